@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
+	_ "time/tzdata" // schedules use IANA time zones, which hosts may lack
 
 	"github.com/PranavJoshi2893/med-portal/internal/config"
 	"github.com/PranavJoshi2893/med-portal/internal/database"
 	"github.com/PranavJoshi2893/med-portal/internal/handler"
+	"github.com/PranavJoshi2893/med-portal/internal/jobs"
 	"github.com/PranavJoshi2893/med-portal/internal/repository"
 	"github.com/PranavJoshi2893/med-portal/internal/server"
 	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/blob"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/PranavJoshi2893/med-portal/pkg/mllp"
 )
 
 func main() {
@@ -26,13 +33,128 @@ func main() {
 	authService := service.NewAuthService(authRepo, cfg.Pepper, cfg.AccessTokenKey, cfg.RefreshTokenKey)
 	authHandler := handler.NewAuthHandler(authService)
 
+	var mail mailer.Mailer = mailer.NewLogMailer(cfg.MailLogBodies)
+	if cfg.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+
+	blobStore, err := blob.NewLocalStore(cfg.BlobDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	auditRepo := repository.NewAuditRepository(db)
+
 	userRepo := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepo)
+	userService := service.NewUserService(userRepo, auditRepo, blobStore, cfg.AvatarMaxBytes)
 	userHandler := handler.NewUserHandler(userService)
 
-	routes := server.Routes(authHandler, userHandler, cfg)
+	erasureRepo := repository.NewErasureRepository(db)
+	erasureService := service.NewErasureService(erasureRepo, auditRepo, blobStore, cfg.ErasureSigningKey)
+	erasureHandler := handler.NewErasureHandler(erasureService)
+
+	exportRepo := repository.NewExportRepository(db)
+	exportService := service.NewExportService(exportRepo, cfg.ExportDir, cfg.ExportLinkTTL, cfg.ExportDailyLimit)
+	exportHandler := handler.NewExportHandler(exportService)
+
+	emailChangeRepo := repository.NewEmailChangeRepository(db)
+	emailChangeService := service.NewEmailChangeService(emailChangeRepo, auditRepo, cfg.Pepper, mail, cfg.AppBaseURL, cfg.EmailChangeTokenTTL, cfg.EmailChangeRevertWindow)
+	emailChangeHandler := handler.NewEmailChangeHandler(emailChangeService)
+
+	invitationRepo := repository.NewInvitationRepository(db)
+	invitationService := service.NewInvitationService(invitationRepo, auditRepo, cfg.Pepper, mail, cfg.AppBaseURL, cfg.InvitationTTL)
+	invitationHandler := handler.NewInvitationHandler(invitationService)
+
+	organisationRepo := repository.NewOrganisationRepository(db)
+	organisationService := service.NewOrganisationService(organisationRepo, auditRepo)
+	organisationHandler := handler.NewOrganisationHandler(organisationService)
+
+	importRepo := repository.NewImportRepository(db)
+	importService := service.NewImportService(importRepo, auditRepo, cfg.Pepper, cfg.ImportBatchSize, cfg.ImportMaxRows)
+	importHandler := handler.NewImportHandler(importService)
+
+	patientRepo := repository.NewPatientRepository(db)
+	practitionerRepo := repository.NewPractitionerRepository(db)
+	patientService := service.NewPatientService(patientRepo, practitionerRepo, auditRepo)
+	patientHandler := handler.NewPatientHandler(patientService)
+
+	practitionerService := service.NewPractitionerService(practitionerRepo, auditRepo, mail)
+	practitionerHandler := handler.NewPractitionerHandler(practitionerService)
+
+	appointmentRepo := repository.NewAppointmentRepository(db)
+	waitlistRepo := repository.NewWaitlistRepository(db)
+	waitlistService := service.NewWaitlistService(waitlistRepo, appointmentRepo, patientRepo, auditRepo, mail, cfg.AppBaseURL, cfg.WaitlistOfferHold, time.Now)
+	waitlistHandler := handler.NewWaitlistHandler(waitlistService)
+
+	appointmentService := service.NewAppointmentService(appointmentRepo, patientRepo, practitionerRepo, auditRepo, mail, waitlistService)
+	appointmentHandler := handler.NewAppointmentHandler(appointmentService)
+
+	calendarRepo := repository.NewCalendarRepository(db)
+	calendarService := service.NewCalendarService(calendarRepo, appointmentRepo, auditRepo)
+	calendarHandler := handler.NewCalendarHandler(calendarService)
+
+	medicationRepo := repository.NewMedicationRepository(db)
+	medicationService := service.NewMedicationService(medicationRepo, auditRepo, cfg.MedicationBatchSize)
+	medicationHandler := handler.NewMedicationHandler(medicationService)
+
+	allergyRepo := repository.NewAllergyRepository(db)
+	allergyService := service.NewAllergyService(allergyRepo, patientRepo, practitionerRepo, medicationRepo, auditRepo)
+	allergyHandler := handler.NewAllergyHandler(allergyService)
+
+	interactionRepo := repository.NewInteractionRepository(db)
+	prescriptionRepo := repository.NewPrescriptionRepository(db)
+	prescriptionService := service.NewPrescriptionService(prescriptionRepo, medicationRepo, interactionRepo, allergyRepo, patientRepo, auditRepo, mail)
+	prescriptionHandler := handler.NewPrescriptionHandler(prescriptionService)
+
+	labRepo := repository.NewLabRepository(db)
+	referenceRanges, err := service.LoadReferenceRanges(cfg.LabReferenceRangesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	labService := service.NewLabService(labRepo, patientRepo, practitionerRepo, auditRepo, mail, referenceRanges, cfg.LabTimezone)
+	labHandler := handler.NewLabHandler(labService)
+
+	fhirHandler := handler.NewFHIRHandler(patientService, practitionerService, appointmentService)
+
+	routes := server.Routes(server.Handlers{
+		Auth:         authHandler,
+		User:         userHandler,
+		Erasure:      erasureHandler,
+		Export:       exportHandler,
+		EmailChange:  emailChangeHandler,
+		Invitation:   invitationHandler,
+		Organisation: organisationHandler,
+		Import:       importHandler,
+		Patient:      patientHandler,
+		Practitioner: practitionerHandler,
+		Appointment:  appointmentHandler,
+		Waitlist:     waitlistHandler,
+		Calendar:     calendarHandler,
+		Medication:   medicationHandler,
+		Prescription: prescriptionHandler,
+		Allergy:      allergyHandler,
+		Lab:          labHandler,
+		FHIR:         fhirHandler,
+	}, authService, cfg)
+
+	var mllpHandler mllp.Handler
+	if cfg.MLLPAddr != "" {
+		mllpHandler = labService.MLLPHandler(cfg.MLLPOrganisationID)
+	}
+
+	srv := server.NewServer(cfg, db, routes, mllpHandler)
+
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
 
-	srv := server.NewServer(cfg, db, routes)
+	go jobs.RunPeriodic(jobCtx, "purge-deleted-users", cfg.PurgeInterval,
+		jobs.PurgeDeletedUsers(userService, cfg.DeletedUserRetention, cfg.PurgeMode))
+	go jobs.RunPeriodic(jobCtx, "data-exports", cfg.ExportPollInterval,
+		jobs.ProcessDataExports(exportService))
+	go jobs.RunPeriodic(jobCtx, "licence-expiry", cfg.LicenceCheckInterval,
+		jobs.FlagExpiringLicences(practitionerService, cfg.LicenceExpiryWarning))
+	go jobs.RunPeriodic(jobCtx, "waitlist-offers", cfg.WaitlistCheckInterval,
+		jobs.LapseWaitlistOffers(waitlistService))
 
 	log.Println("server is running on port", cfg.ServerPort)
 	err = srv.Run()
@@ -0,0 +1,66 @@
+// Command interactions loads the drug interaction dataset from a CSV file
+// with the columns substance_a, substance_b, severity and description.
+//
+//	interactions -file data/interactions.csv
+//
+// The file is read from standard input when -file is not given. Entries are
+// upserted by their pair of substances, so a newer release can be loaded
+// over an older one. The report is printed as JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/PranavJoshi2893/med-portal/internal/config"
+	"github.com/PranavJoshi2893/med-portal/internal/database"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+)
+
+func main() {
+	file := flag.String("file", "", "interaction CSV file to load (default: standard input)")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	db, err := database.NewPostgres(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	interactionService := service.NewInteractionService(
+		repository.NewInteractionRepository(db),
+		repository.NewAuditRepository(db),
+		cfg.MedicationBatchSize,
+	)
+
+	report, err := interactionService.LoadDataset(context.Background(), in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+// Command medications loads the medication catalogue from an RxNorm concept
+// file, RXNCONSO.RRF, or a file laid out like it.
+//
+//	medications -file rrf/RXNCONSO.RRF
+//
+// The file is read from standard input when -file is not given. Entries are
+// upserted by RxCUI, so a newer release can be loaded over an older one. The
+// report is printed as JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/PranavJoshi2893/med-portal/internal/config"
+	"github.com/PranavJoshi2893/med-portal/internal/database"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+)
+
+func main() {
+	file := flag.String("file", "", "RxNorm concept file to load (default: standard input)")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	db, err := database.NewPostgres(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	medicationService := service.NewMedicationService(
+		repository.NewMedicationRepository(db),
+		repository.NewAuditRepository(db),
+		cfg.MedicationBatchSize,
+	)
+
+	report, err := medicationService.LoadCatalogue(context.Background(), in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 )
 
@@ -18,6 +21,81 @@ type Config struct {
 	Pepper          string
 	AccessTokenKey  string
 	RefreshTokenKey string
+
+	// ErasureSigningKey keys the erasure pseudonyms and signs erasure
+	// certificates. Changing it invalidates existing certificates.
+	ErasureSigningKey string
+
+	// Soft-deleted accounts are purged once they have been deleted for
+	// longer than DeletedUserRetention.
+	DeletedUserRetention time.Duration
+	PurgeMode            string
+	PurgeInterval        time.Duration
+
+	// Personal data exports are written to ExportDir. Download links stay
+	// valid for ExportLinkTTL and each user may request ExportDailyLimit
+	// exports per day.
+	ExportDir          string
+	ExportLinkTTL      time.Duration
+	ExportDailyLimit   int
+	ExportPollInterval time.Duration
+
+	// Uploaded files such as avatars are stored below BlobDir. Avatars may
+	// be at most AvatarMaxBytes.
+	BlobDir        string
+	AvatarMaxBytes int64
+
+	// AppBaseURL is the front end origin used in emailed links.
+	AppBaseURL string
+
+	// Outgoing mail. Messages are logged instead of sent when SMTPHost is
+	// empty; their bodies only with MailLogBodies, meant for development.
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+	MailFrom      string
+	MailLogBodies bool
+
+	EmailChangeTokenTTL     time.Duration
+	EmailChangeRevertWindow time.Duration
+
+	// Invitation links stay valid for InvitationTTL after they are sent.
+	InvitationTTL time.Duration
+
+	// User imports are written in transactions of ImportBatchSize rows. An
+	// import through the API may have at most ImportMaxRows rows.
+	ImportBatchSize int
+	ImportMaxRows   int
+
+	// Practitioner licences expiring within LicenceExpiryWarning are flagged
+	// by a check that runs every LicenceCheckInterval.
+	LicenceExpiryWarning time.Duration
+	LicenceCheckInterval time.Duration
+
+	// A slot offered from the waitlist is held for WaitlistOfferHold. Lapsed
+	// offers are passed on by a check that runs every WaitlistCheckInterval.
+	WaitlistOfferHold     time.Duration
+	WaitlistCheckInterval time.Duration
+
+	// The medication catalogue and the interaction dataset are loaded in
+	// statements of MedicationBatchSize entries.
+	MedicationBatchSize int
+
+	// Times in lab result messages that carry no UTC offset are read in
+	// LabTimezone.
+	LabTimezone *time.Location
+
+	// Lab results are interpreted against the reference ranges in the JSON
+	// file at LabReferenceRangesFile, if set.
+	LabReferenceRangesFile string
+
+	// When MLLPAddr is set, lab messages are also received over MLLP on that
+	// address and filed for MLLPOrganisationID. Connections that send
+	// nothing for MLLPIdleTimeout are closed.
+	MLLPAddr           string
+	MLLPOrganisationID uuid.UUID
+	MLLPIdleTimeout    time.Duration
 }
 
 func Load() (*Config, error) {
@@ -37,6 +115,110 @@ func Load() (*Config, error) {
 		Pepper:          os.Getenv("PEPPER"),
 		AccessTokenKey:  os.Getenv("ACCESS_TOKEN_KEY"),
 		RefreshTokenKey: os.Getenv("REFRESH_TOKEN_KEY"),
+
+		ErasureSigningKey: os.Getenv("ERASURE_SIGNING_KEY"),
+	}
+
+	retentionDays, err := getEnvInt("DELETED_USER_RETENTION_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DeletedUserRetention = time.Duration(retentionDays) * 24 * time.Hour
+
+	cfg.PurgeMode = getEnv("PURGE_MODE", "anonymise")
+	if cfg.PurgeMode != "anonymise" && cfg.PurgeMode != "delete" {
+		return nil, fmt.Errorf("PURGE_MODE must be anonymise or delete")
+	}
+
+	if cfg.PurgeInterval, err = getEnvDuration("PURGE_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+
+	cfg.ExportDir = getEnv("EXPORT_DIR", "./data/exports")
+	if cfg.ExportLinkTTL, err = getEnvDuration("EXPORT_LINK_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.ExportDailyLimit, err = getEnvInt("EXPORT_DAILY_LIMIT", 3); err != nil {
+		return nil, err
+	}
+	if cfg.ExportPollInterval, err = getEnvDuration("EXPORT_POLL_INTERVAL", 10*time.Second); err != nil {
+		return nil, err
+	}
+
+	cfg.BlobDir = getEnv("BLOB_DIR", "./data/blobs")
+	avatarMaxBytes, err := getEnvInt("AVATAR_MAX_BYTES", 2<<20)
+	if err != nil {
+		return nil, err
+	}
+	cfg.AvatarMaxBytes = int64(avatarMaxBytes)
+
+	cfg.AppBaseURL = getEnv("APP_BASE_URL", "http://localhost:4200")
+	cfg.SMTPHost = os.Getenv("SMTP_HOST")
+	cfg.SMTPPort = getEnv("SMTP_PORT", "587")
+	cfg.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	cfg.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	cfg.MailFrom = getEnv("MAIL_FROM", "no-reply@med-portal.local")
+	if cfg.MailLogBodies, err = getEnvBool("MAIL_LOG_BODIES", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.EmailChangeTokenTTL, err = getEnvDuration("EMAIL_CHANGE_TOKEN_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.EmailChangeRevertWindow, err = getEnvDuration("EMAIL_CHANGE_REVERT_WINDOW", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.InvitationTTL, err = getEnvDuration("INVITATION_TTL", 72*time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.ImportBatchSize, err = getEnvInt("IMPORT_BATCH_SIZE", 100); err != nil {
+		return nil, err
+	}
+	if cfg.ImportBatchSize == 0 {
+		return nil, fmt.Errorf("IMPORT_BATCH_SIZE must be positive")
+	}
+	if cfg.ImportMaxRows, err = getEnvInt("IMPORT_MAX_ROWS", 500); err != nil {
+		return nil, err
+	}
+
+	warningDays, err := getEnvInt("LICENCE_EXPIRY_WARNING_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LicenceExpiryWarning = time.Duration(warningDays) * 24 * time.Hour
+	if cfg.LicenceCheckInterval, err = getEnvDuration("LICENCE_CHECK_INTERVAL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.WaitlistOfferHold, err = getEnvDuration("WAITLIST_OFFER_HOLD", 2*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.WaitlistCheckInterval, err = getEnvDuration("WAITLIST_CHECK_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+
+	if cfg.MedicationBatchSize, err = getEnvInt("MEDICATION_BATCH_SIZE", 1000); err != nil {
+		return nil, err
+	}
+	if cfg.MedicationBatchSize <= 0 {
+		return nil, fmt.Errorf("MEDICATION_BATCH_SIZE must be positive")
+	}
+
+	if cfg.LabTimezone, err = time.LoadLocation(getEnv("LAB_TIMEZONE", "UTC")); err != nil {
+		return nil, fmt.Errorf("LAB_TIMEZONE must be an IANA time zone name")
+	}
+	cfg.LabReferenceRangesFile = os.Getenv("LAB_REFERENCE_RANGES_FILE")
+
+	cfg.MLLPAddr = os.Getenv("MLLP_ADDR")
+	if cfg.MLLPAddr != "" {
+		if cfg.MLLPOrganisationID, err = uuid.Parse(os.Getenv("MLLP_ORGANISATION_ID")); err != nil {
+			return nil, fmt.Errorf("MLLP_ORGANISATION_ID must be an organisation ID when MLLP_ADDR is set")
+		}
+	}
+	if cfg.MLLPIdleTimeout, err = getEnvDuration("MLLP_IDLE_TIMEOUT", 5*time.Minute); err != nil {
+		return nil, err
 	}
 
 	if cfg.Pepper == "" {
@@ -48,6 +230,52 @@ func Load() (*Config, error) {
 	if cfg.RefreshTokenKey == "" {
 		return nil, fmt.Errorf("REFRESH_TOKEN_KEY is required")
 	}
+	if cfg.ErasureSigningKey == "" {
+		return nil, fmt.Errorf("ERASURE_SIGNING_KEY is required")
+	}
 
 	return cfg, nil
 }
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, nil
+}
+
+func getEnvBool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", key)
+	}
+	return b, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration", key)
+	}
+	return d, nil
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type AllergyHandler struct {
+	service *service.AllergyService
+}
+
+func NewAllergyHandler(service *service.AllergyService) *AllergyHandler {
+	return &AllergyHandler{
+		service: service,
+	}
+}
+
+func (h *AllergyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.CreateAllergy
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	allergy, err := h.service.Create(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "allergy recorded successfully", allergy)
+}
+
+func (h *AllergyHandler) List(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	allergies, err := h.service.List(ctx, id, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", allergies)
+}
+
+func (h *AllergyHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	allergyID, err := uuid.Parse(r.PathValue("allergyID"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Allergy ID",
+		})
+		return
+	}
+
+	if !isMergePatch(r) {
+		w.Header().Set("Accept-Patch", model.MergePatchContentType)
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Status:  "UNSUPPORTED_MEDIA_TYPE",
+			Message: "Content-Type must be " + model.MergePatchContentType + " or application/json",
+		})
+		return
+	}
+
+	var data *model.UpdateAllergy
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	allergy, err := h.service.UpdateByID(ctx, id, allergyID, data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "allergy updated successfully", allergy)
+}
@@ -0,0 +1,469 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type AppointmentHandler struct {
+	service *service.AppointmentService
+}
+
+func NewAppointmentHandler(service *service.AppointmentService) *AppointmentHandler {
+	return &AppointmentHandler{
+		service: service,
+	}
+}
+
+func parseAppointmentFilter(r *http.Request) (model.AppointmentFilter, error) {
+	query := r.URL.Query()
+	var errs model.ValidationErrors
+
+	filter := model.AppointmentFilter{
+		Status: query.Get("status"),
+	}
+
+	for field, dst := range map[string]**uuid.UUID{"practitioner_id": &filter.PractitionerID, "patient_id": &filter.PatientID} {
+		raw := query.Get(field)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			errs = append(errs, model.FieldError{Field: field, Message: "invalid id"})
+			continue
+		}
+		*dst = &id
+	}
+
+	var ok bool
+	if filter.From, ok = parseTimeParam(query.Get("from"), false); !ok {
+		errs = append(errs, model.FieldError{Field: "from", Message: "invalid date"})
+	}
+	if filter.To, ok = parseTimeParam(query.Get("to"), true); !ok {
+		errs = append(errs, model.FieldError{Field: "to", Message: "invalid date"})
+	}
+
+	if len(errs) > 0 {
+		return filter, errs
+	}
+
+	return filter, filter.Validate()
+}
+
+// appointmentID parses the appointment ID in the path, writing the error
+// response if it is invalid.
+func appointmentID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Appointment ID",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *AppointmentHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	schedule, err := h.service.GetSchedule(ctx, id, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", schedule)
+}
+
+func (h *AppointmentHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.SetSchedule
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	schedule, err := h.service.SetSchedule(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "schedule updated successfully", schedule)
+}
+
+func (h *AppointmentHandler) ListExceptions(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	exceptions, err := h.service.ListExceptions(ctx, id, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", exceptions)
+}
+
+func (h *AppointmentHandler) AddException(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.CreateAvailabilityException
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	exception, err := h.service.AddException(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "exception added successfully", exception)
+}
+
+func (h *AppointmentHandler) RemoveException(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	exceptionID, err := uuid.Parse(r.PathValue("exceptionID"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Exception ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.RemoveException(ctx, id, exceptionID, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "exception removed successfully", nil)
+}
+
+func (h *AppointmentHandler) Slots(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	rng := model.SlotRange{From: r.URL.Query().Get("from"), To: r.URL.Query().Get("to")}
+	if err := rng.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	slots, err := h.service.Slots(ctx, id, rng, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", slots)
+}
+
+func (h *AppointmentHandler) Book(w http.ResponseWriter, r *http.Request) {
+	var data model.CreateAppointment
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	appointment, err := h.service.Book(ctx, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "appointment booked successfully", appointment)
+}
+
+func (h *AppointmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	filter, err := parseAppointmentFilter(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	result, err := h.service.List(ctx, filter, parsePagination(r), *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *AppointmentHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := appointmentID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	appointment, err := h.service.GetByID(ctx, id, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", appointment)
+}
+
+func (h *AppointmentHandler) Reschedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := appointmentID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.RescheduleAppointment
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	appointment, err := h.service.Reschedule(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "appointment rescheduled successfully", appointment)
+}
+
+func (h *AppointmentHandler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := appointmentID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.ChangeAppointmentStatus
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	appointment, err := h.service.SetStatus(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "appointment status updated successfully", appointment)
+}
@@ -2,11 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/PranavJoshi2893/med-portal/internal/model"
 	"github.com/PranavJoshi2893/med-portal/internal/service"
 	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
@@ -137,10 +139,35 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	responses.WriteSuccess(w, http.StatusOK, "logout successful", nil)
 }
 
+// Refresh takes an optional body naming the organisation to switch to.
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var switchTo *uuid.UUID
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	var data model.SwitchOrganisation
+	switch err := dec.Decode(&data); err {
+	case io.EOF:
+	case nil:
+		if err := data.Validate(); err != nil {
+			responses.WriteError(w, responses.FromModelError(err, ""))
+			return
+		}
+		switchTo = &data.OrganisationID
+	default:
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
 	ctx := r.Context()
 
-	data, err := h.service.Refresh(ctx)
+	tokens, err := h.service.Refresh(ctx, switchTo)
 	if err != nil {
 		responses.WriteError(w, responses.FromModelError(err, err.Error()))
 		return
@@ -148,7 +175,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     "refresh_token",
-		Value:    data.RefreshToken,
+		Value:    tokens.RefreshToken,
 		HttpOnly: true,
 		Secure:   false,
 		SameSite: http.SameSiteLaxMode,
@@ -162,6 +189,6 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		"refresh successful",
 		struct {
 			AccessToken string `json:"access_token"`
-		}{AccessToken: data.AccessToken},
+		}{AccessToken: tokens.AccessToken},
 	)
 }
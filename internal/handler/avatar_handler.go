@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+// avatarField is the multipart form field carrying the image.
+const avatarField = "avatar"
+
+// UploadAvatar streams the "avatar" part of a multipart/form-data request to
+// the blob store without buffering the whole request. Size and content type
+// are checked by the service.
+func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid User ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_MULTIPART",
+			Message: "Request must be multipart/form-data",
+		})
+		return
+	}
+	defer r.Body.Close()
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			responses.WriteError(w, responses.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Status:  "INVALID_MULTIPART",
+				Message: "Malformed multipart body",
+			})
+			return
+		}
+
+		if part.FormName() != avatarField {
+			part.Close()
+			continue
+		}
+
+		err = h.service.SetAvatar(ctx, id, part, *callerID, callerRole)
+		part.Close()
+		if err != nil {
+			responses.WriteError(w, responses.FromModelError(err, err.Error()))
+			return
+		}
+
+		responses.WriteSuccess(w, http.StatusOK, "avatar updated successfully", nil)
+		return
+	}
+
+	responses.WriteError(w, responses.ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Status:  "MISSING_FILE",
+		Message: `Missing "avatar" file field`,
+	})
+}
+
+func (h *UserHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid User ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	avatar, file, err := h.service.GetAvatar(ctx, id, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("error writing avatar of %s: %v", id, err)
+	}
+}
+
+func (h *UserHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid User ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.DeleteAvatar(ctx, id, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "avatar deleted successfully", nil)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/ical"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+)
+
+type CalendarHandler struct {
+	service *service.CalendarService
+}
+
+func NewCalendarHandler(service *service.CalendarService) *CalendarHandler {
+	return &CalendarHandler{
+		service: service,
+	}
+}
+
+func (h *CalendarHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	result, err := h.service.CreateFeed(ctx, *callerID)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "calendar feed created", result)
+}
+
+func (h *CalendarHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	feed, err := h.service.GetFeed(ctx, *callerID)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", feed)
+}
+
+func (h *CalendarHandler) RevokeFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.RevokeFeed(ctx, *callerID); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "calendar feed revoked", nil)
+}
+
+// Feed serves the iCalendar feed. It is authorised by the token in the URL
+// rather than an access token, since calendar apps cannot sign in.
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+	if token == "" {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	body, err := h.service.Feed(r.Context(), token)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="med-portal.ics"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(body); err != nil {
+		log.Printf("error writing calendar feed: %v", err)
+	}
+}
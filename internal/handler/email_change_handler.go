@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type EmailChangeHandler struct {
+	service *service.EmailChangeService
+}
+
+func NewEmailChangeHandler(service *service.EmailChangeService) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		service: service,
+	}
+}
+
+func (h *EmailChangeHandler) Request(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid User ID",
+		})
+		return
+	}
+
+	var data model.ChangeEmail
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.Request(ctx, id, &data, *callerID); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusAccepted, "confirmation sent to the new email address", nil)
+}
+
+func (h *EmailChangeHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	data, ok := decodeEmailToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Confirm(r.Context(), data.Token); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "email changed successfully", nil)
+}
+
+func (h *EmailChangeHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	data, ok := decodeEmailToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Revert(r.Context(), data.Token); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "email change reverted", nil)
+}
+
+func decodeEmailToken(w http.ResponseWriter, r *http.Request) (*model.EmailToken, bool) {
+	var data model.EmailToken
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return nil, false
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return nil, false
+	}
+
+	return &data, true
+}
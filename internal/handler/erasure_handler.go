@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type ErasureHandler struct {
+	service *service.ErasureService
+}
+
+func NewErasureHandler(service *service.ErasureService) *ErasureHandler {
+	return &ErasureHandler{
+		service: service,
+	}
+}
+
+func (h *ErasureHandler) Erase(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid User ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	result, created, err := h.service.Erase(ctx, id, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	if !created {
+		responses.WriteSuccess(w, http.StatusOK, "user already erased", result)
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "user erased successfully", result)
+}
+
+func (h *ErasureHandler) GetCertificate(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid User ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	result, err := h.service.GetCertificate(ctx, id, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
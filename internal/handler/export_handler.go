@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type ExportHandler struct {
+	service *service.ExportService
+}
+
+func NewExportHandler(service *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		service: service,
+	}
+}
+
+func (h *ExportHandler) Request(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	result, err := h.service.Request(ctx, *callerID)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusAccepted, "export requested", result)
+}
+
+func (h *ExportHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	exports, err := h.service.List(ctx, *callerID)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", exports)
+}
+
+func (h *ExportHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Export ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	export, err := h.service.GetByID(ctx, id, *callerID)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", export)
+}
+
+// Download serves the archive. It is authorised by the token in the link
+// rather than an access token so the link can be opened directly.
+func (h *ExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Export ID",
+		})
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	file, export, err := h.service.Open(r.Context(), id, token)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="med-portal-export-`+export.ID.String()+`.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("error writing export %s: %v", export.ID, err)
+	}
+}
@@ -0,0 +1,568 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/fhir"
+	"github.com/google/uuid"
+)
+
+// fhirMaxCount caps the _count of a search.
+const fhirMaxCount = 100
+
+// FHIRHandler serves patients, practitioners and appointments as FHIR R4
+// resources for partner systems. Its responses are resources rather than
+// the SuccessResponse envelope, and its errors are OperationOutcomes.
+type FHIRHandler struct {
+	patients      *service.PatientService
+	practitioners *service.PractitionerService
+	appointments  *service.AppointmentService
+	started       time.Time
+}
+
+func NewFHIRHandler(patients *service.PatientService, practitioners *service.PractitionerService, appointments *service.AppointmentService) *FHIRHandler {
+	return &FHIRHandler{
+		patients:      patients,
+		practitioners: practitioners,
+		appointments:  appointments,
+		started:       time.Now().UTC(),
+	}
+}
+
+func writeFHIR(w http.ResponseWriter, status int, resource any) {
+	w.Header().Set("Content-Type", fhir.ContentType)
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(resource); err != nil {
+		log.Printf("error encoding FHIR resource: %v", err)
+	}
+}
+
+// writeOutcome writes err as an OperationOutcome, locating validation errors
+// with paths.
+func writeOutcome(w http.ResponseWriter, err error, paths map[string]string) {
+	status, outcome := fhir.FromError(err, paths)
+	writeFHIR(w, status, outcome)
+}
+
+// writeSearchOutcome writes the errors of search parameters, which FHIR
+// reports as a bad request rather than an unprocessable resource.
+func writeSearchOutcome(w http.ResponseWriter, err error, params map[string]string) {
+	status, outcome := fhir.FromError(err, params)
+	if status == http.StatusUnprocessableEntity {
+		status = http.StatusBadRequest
+	}
+	writeFHIR(w, status, outcome)
+}
+
+// fhirBase returns the base URL of the FHIR API as the client reached it.
+func fhirBase(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/fhir/R4"
+}
+
+// fhirID parses the logical ID in the path, writing the OperationOutcome if
+// it is invalid.
+func fhirID(w http.ResponseWriter, r *http.Request, resourceType string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeFHIR(w, http.StatusBadRequest, fhir.NewOutcome(fhir.IssueInvalid, "Invalid "+resourceType+" ID"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// decodeFHIR reads the resource in the body into v, writing the
+// OperationOutcome if it is not FHIR JSON. Unknown elements are rejected
+// rather than silently dropped.
+func decodeFHIR(w http.ResponseWriter, r *http.Request, v any) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != fhir.ContentType && mediaType != "application/json" {
+		writeFHIR(w, http.StatusUnsupportedMediaType, fhir.NewOutcome(fhir.IssueNotSupported, "Content-Type must be "+fhir.ContentType))
+		return false
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(v); err != nil {
+		writeFHIR(w, http.StatusBadRequest, fhir.NewOutcome(fhir.IssueStructure, "Invalid JSON payload: "+err.Error()))
+		return false
+	}
+	return true
+}
+
+// setVersion writes the ETag of a resource version, which FHIR gives as a
+// weak tag.
+func setVersion(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", `W/"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// fhirPagination reads _count, which defaults to 10 and is capped at
+// fhirMaxCount, and _page.
+func fhirPagination(r *http.Request) model.PaginationParams {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("_page"))
+	if page < 1 {
+		page = 1
+	}
+	count, err := strconv.Atoi(query.Get("_count"))
+	if err != nil || count < 1 {
+		count = 10
+	}
+	return model.PaginationParams{Page: page, Limit: min(count, fhirMaxCount)}
+}
+
+// searchset returns a page of search results with links to the other pages
+// of the search.
+func searchset(r *http.Request, resourceType string, meta model.PaginationMeta, entries []fhir.BundleEntry) *fhir.Bundle {
+	base := fhirBase(r)
+	link := func(relation string, page int) fhir.BundleLink {
+		query := r.URL.Query()
+		query.Set("_page", strconv.Itoa(page))
+		query.Set("_count", strconv.Itoa(meta.Limit))
+		return fhir.BundleLink{Relation: relation, URL: base + "/" + resourceType + "?" + query.Encode()}
+	}
+
+	total := meta.Total
+	bundle := &fhir.Bundle{
+		ResourceType: "Bundle",
+		Type:         fhir.BundleSearchset,
+		Total:        &total,
+		Link:         []fhir.BundleLink{link("self", meta.Page), link("first", 1)},
+		Entry:        entries,
+	}
+	if meta.Page > 1 {
+		bundle.Link = append(bundle.Link, link("previous", min(meta.Page-1, max(meta.TotalPages, 1))))
+	}
+	if meta.Page < meta.TotalPages {
+		bundle.Link = append(bundle.Link, link("next", meta.Page+1))
+	}
+	bundle.Link = append(bundle.Link, link("last", max(meta.TotalPages, 1)))
+	return bundle
+}
+
+func searchEntry(r *http.Request, resourceType string, id uuid.UUID, resource any) fhir.BundleEntry {
+	return fhir.BundleEntry{
+		FullURL:  fhirBase(r) + "/" + fhir.Ref(resourceType, id),
+		Resource: resource,
+		Search:   &fhir.BundleSearch{Mode: "match"},
+	}
+}
+
+// created writes a created resource with its location.
+func created(w http.ResponseWriter, r *http.Request, resourceType string, id uuid.UUID, resource any) {
+	w.Header().Set("Location", fhirBase(r)+"/"+fhir.Ref(resourceType, id))
+	writeFHIR(w, http.StatusCreated, resource)
+}
+
+func (h *FHIRHandler) unauthorized(w http.ResponseWriter) {
+	writeFHIR(w, http.StatusUnauthorized, fhir.NewOutcome(fhir.IssueLogin, "Unauthorized"))
+}
+
+// Metadata serves the CapabilityStatement of the API.
+func (h *FHIRHandler) Metadata(w http.ResponseWriter, r *http.Request) {
+	interactions := func(codes ...string) []fhir.CapabilityInteraction {
+		list := make([]fhir.CapabilityInteraction, len(codes))
+		for i, c := range codes {
+			list[i] = fhir.CapabilityInteraction{Code: c}
+		}
+		return list
+	}
+	paging := []fhir.CapabilitySearchParam{
+		{Name: "_count", Type: "number", Documentation: "Results per page, from 1 to 100; defaults to 10"},
+		{Name: "_page", Type: "number", Documentation: "Page of the results, from 1"},
+	}
+
+	writeFHIR(w, http.StatusOK, &fhir.CapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         h.started.Format(time.RFC3339),
+		Kind:         "instance",
+		Software:     &fhir.CapabilitySoftware{Name: "med-portal"},
+		Implementation: &fhir.CapabilityImplementing{
+			Description: "med-portal FHIR R4 API",
+			URL:         fhirBase(r),
+		},
+		FHIRVersion: fhir.Version,
+		Format:      []string{fhir.ContentType},
+		Rest: []fhir.CapabilityRest{{
+			Mode: "server",
+			Security: &fhir.CapabilitySecurity{
+				Description: "Requests other than metadata need the bearer access token returned by /api/v1/auth/login.",
+			},
+			Resource: []fhir.CapabilityResource{
+				{
+					Type:        "Patient",
+					Interaction: interactions("read", "search-type", "create"),
+					SearchParam: append([]fhir.CapabilitySearchParam{
+						{Name: "name", Type: "string", Documentation: "Matches the name or MRN"},
+						{Name: "birthdate", Type: "date"},
+					}, paging...),
+				},
+				{
+					Type:        "Practitioner",
+					Interaction: interactions("read", "search-type", "create"),
+					SearchParam: append([]fhir.CapabilitySearchParam{
+						{Name: "name", Type: "string"},
+					}, paging...),
+				},
+				{
+					Type:        "Appointment",
+					Interaction: interactions("read", "search-type", "create"),
+					SearchParam: append([]fhir.CapabilitySearchParam{
+						{Name: "patient", Type: "reference"},
+						{Name: "practitioner", Type: "reference"},
+						{Name: "status", Type: "token"},
+						{Name: "date", Type: "date", Documentation: "Supports the eq, ge, gt, le and lt prefixes"},
+					}, paging...),
+				},
+			},
+		}},
+	})
+}
+
+// NotFound reports a path the API does not serve.
+func (h *FHIRHandler) NotFound(w http.ResponseWriter, r *http.Request) {
+	writeFHIR(w, http.StatusNotFound, fhir.NewOutcome(fhir.IssueNotSupported, "Unknown resource type or interaction"))
+}
+
+// MethodNotAllowed reports an interaction the API does not support.
+func (h *FHIRHandler) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeFHIR(w, http.StatusMethodNotAllowed, fhir.NewOutcome(fhir.IssueNotSupported, "Interaction not supported"))
+}
+
+func (h *FHIRHandler) ReadPatient(w http.ResponseWriter, r *http.Request) {
+	id, ok := fhirID(w, r, "Patient")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	patient, err := h.patients.GetByID(ctx, id, *callerID, callerRole)
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+
+	setVersion(w, patient.Version)
+	writeFHIR(w, http.StatusOK, fhir.FromPatient(patient))
+}
+
+func (h *FHIRHandler) SearchPatients(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := model.PatientFilter{
+		Search:      query.Get("name"),
+		DateOfBirth: strings.TrimPrefix(query.Get("birthdate"), "eq"),
+	}
+	if err := filter.Validate(); err != nil {
+		writeSearchOutcome(w, err, map[string]string{"q": "name", "date_of_birth": "birthdate"})
+		return
+	}
+
+	result, err := h.patients.List(ctx, *callerID, callerRole, filter, fhirPagination(r))
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+
+	entries := make([]fhir.BundleEntry, len(result.Items))
+	for i, p := range result.Items {
+		entries[i] = searchEntry(r, "Patient", p.ID, fhir.FromPatientSummary(p))
+	}
+	writeFHIR(w, http.StatusOK, searchset(r, "Patient", result.Meta, entries))
+}
+
+func (h *FHIRHandler) CreatePatient(w http.ResponseWriter, r *http.Request) {
+	var resource fhir.Patient
+	if !decodeFHIR(w, r, &resource) {
+		return
+	}
+
+	data, err := resource.CreatePatient()
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+	if err := data.Validate(); err != nil {
+		writeOutcome(w, err, fhir.PatientPaths)
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	patient, err := h.patients.Create(ctx, data, *callerID, callerRole)
+	if err != nil {
+		writeOutcome(w, err, fhir.PatientPaths)
+		return
+	}
+
+	setVersion(w, patient.Version)
+	created(w, r, "Patient", patient.ID, fhir.FromPatient(patient))
+}
+
+func (h *FHIRHandler) ReadPractitioner(w http.ResponseWriter, r *http.Request) {
+	id, ok := fhirID(w, r, "Practitioner")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	practitioner, err := h.practitioners.GetByID(ctx, id, callerRole)
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+
+	setVersion(w, practitioner.Version)
+	writeFHIR(w, http.StatusOK, fhir.FromPractitioner(practitioner))
+}
+
+func (h *FHIRHandler) SearchPractitioners(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	filter := model.PractitionerFilter{Search: r.URL.Query().Get("name")}
+	if err := filter.Validate(); err != nil {
+		writeSearchOutcome(w, err, map[string]string{"q": "name"})
+		return
+	}
+
+	result, err := h.practitioners.List(ctx, callerRole, filter, fhirPagination(r))
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+
+	entries := make([]fhir.BundleEntry, len(result.Items))
+	for i, p := range result.Items {
+		entries[i] = searchEntry(r, "Practitioner", p.ID, fhir.FromPractitionerSummary(p))
+	}
+	writeFHIR(w, http.StatusOK, searchset(r, "Practitioner", result.Meta, entries))
+}
+
+func (h *FHIRHandler) CreatePractitioner(w http.ResponseWriter, r *http.Request) {
+	var resource fhir.Practitioner
+	if !decodeFHIR(w, r, &resource) {
+		return
+	}
+
+	data, err := resource.CreatePractitioner()
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+	if err := data.Validate(); err != nil {
+		writeOutcome(w, err, fhir.PractitionerPaths)
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	practitioner, err := h.practitioners.Create(ctx, data, *callerID, callerRole)
+	if err != nil {
+		writeOutcome(w, err, fhir.PractitionerPaths)
+		return
+	}
+
+	setVersion(w, practitioner.Version)
+	created(w, r, "Practitioner", practitioner.ID, fhir.FromPractitioner(practitioner))
+}
+
+func (h *FHIRHandler) ReadAppointment(w http.ResponseWriter, r *http.Request) {
+	id, ok := fhirID(w, r, "Appointment")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	appointment, err := h.appointments.GetByID(ctx, id, *callerID, callerRole)
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+
+	writeFHIR(w, http.StatusOK, fhir.FromAppointment(appointment))
+}
+
+// parseFHIRAppointmentFilter reads the appointment search parameters. The
+// date parameter may be repeated with the ge, gt, le and lt prefixes to
+// bound the start of the appointments; without a prefix it matches a day
+// or an instant.
+func parseFHIRAppointmentFilter(r *http.Request) (model.AppointmentFilter, error) {
+	query := r.URL.Query()
+	var errs model.ValidationErrors
+	var filter model.AppointmentFilter
+
+	for param, ref := range map[string]struct {
+		resourceType string
+		dst          **uuid.UUID
+	}{
+		"patient":      {"Patient", &filter.PatientID},
+		"practitioner": {"Practitioner", &filter.PractitionerID},
+	} {
+		raw := query.Get(param)
+		if raw == "" {
+			continue
+		}
+		id, ok := fhir.ParseRef(ref.resourceType, raw)
+		if !ok {
+			errs = append(errs, model.FieldError{Field: param, Message: "invalid reference"})
+			continue
+		}
+		*ref.dst = &id
+	}
+
+	if raw := query.Get("status"); raw != "" {
+		status, ok := fhir.AppointmentStatus(raw)
+		if !ok {
+			errs = append(errs, model.FieldError{Field: "status", Message: "unknown status"})
+		}
+		filter.Status = status
+	}
+
+	for _, raw := range query["date"] {
+		prefix := "eq"
+		if len(raw) > 2 && raw[0] >= 'a' && raw[0] <= 'z' {
+			prefix, raw = raw[:2], raw[2:]
+		}
+
+		var ok bool
+		switch prefix {
+		case "ge":
+			filter.From, ok = parseTimeParam(raw, false)
+		case "gt":
+			filter.From, ok = parseTimeParam(raw, true)
+		case "le":
+			filter.To, ok = parseTimeParam(raw, true)
+		case "lt":
+			filter.To, ok = parseTimeParam(raw, false)
+		case "eq":
+			if filter.From, ok = parseTimeParam(raw, false); ok {
+				filter.To, _ = parseTimeParam(raw, true)
+				if filter.To.Equal(*filter.From) {
+					end := filter.To.Add(time.Nanosecond)
+					filter.To = &end
+				}
+			}
+		}
+		if !ok || raw == "" {
+			errs = append(errs, model.FieldError{Field: "date", Message: "date must be a date or dateTime with an eq, ge, gt, le or lt prefix"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return filter, errs
+	}
+	return filter, filter.Validate()
+}
+
+func (h *FHIRHandler) SearchAppointments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	filter, err := parseFHIRAppointmentFilter(r)
+	if err != nil {
+		writeSearchOutcome(w, err, map[string]string{"from": "date", "to": "date"})
+		return
+	}
+
+	result, err := h.appointments.List(ctx, filter, fhirPagination(r), *callerID, callerRole)
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+
+	entries := make([]fhir.BundleEntry, len(result.Items))
+	for i := range result.Items {
+		entries[i] = searchEntry(r, "Appointment", result.Items[i].ID, fhir.FromAppointment(&result.Items[i]))
+	}
+	writeFHIR(w, http.StatusOK, searchset(r, "Appointment", result.Meta, entries))
+}
+
+func (h *FHIRHandler) CreateAppointment(w http.ResponseWriter, r *http.Request) {
+	var resource fhir.Appointment
+	if !decodeFHIR(w, r, &resource) {
+		return
+	}
+
+	data, err := resource.CreateAppointment()
+	if err != nil {
+		writeOutcome(w, err, nil)
+		return
+	}
+	if err := data.Validate(); err != nil {
+		writeOutcome(w, err, fhir.AppointmentPaths)
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		h.unauthorized(w)
+		return
+	}
+
+	appointment, err := h.appointments.Book(ctx, data, *callerID, callerRole)
+	if err != nil {
+		writeOutcome(w, err, fhir.AppointmentPaths)
+		return
+	}
+
+	created(w, r, "Appointment", appointment.ID, fhir.FromAppointment(appointment))
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+)
+
+// importMaxBytes caps the size of an uploaded import file.
+const importMaxBytes = 10 << 20
+
+type ImportHandler struct {
+	service *service.ImportService
+}
+
+func NewImportHandler(service *service.ImportService) *ImportHandler {
+	return &ImportHandler{
+		service: service,
+	}
+}
+
+// Import reads a text/csv request body. With dry_run=true every row is
+// validated and the report says what would happen, but nothing is written.
+func (h *ImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "text/csv" {
+		responses.WriteError(w, responses.FromModelError(model.ErrUnsupportedMedia, "Content-Type must be text/csv"))
+		return
+	}
+
+	if r.ContentLength > importMaxBytes {
+		responses.WriteError(w, responses.FromModelError(model.ErrPayloadTooLarge, "import file is too large"))
+		return
+	}
+
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			responses.WriteError(w, responses.FromModelError(
+				model.ValidationErrors{model.FieldError{Field: "dry_run", Message: "dry_run must be true or false"}}, "",
+			))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, importMaxBytes)
+	defer body.Close()
+
+	report, err := h.service.Import(ctx, body, dryRun, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	message := "import completed"
+	if dryRun {
+		message = "dry run completed"
+	}
+	responses.WriteSuccess(w, http.StatusOK, message, report)
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type InvitationHandler struct {
+	service *service.InvitationService
+}
+
+func NewInvitationHandler(service *service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		service: service,
+	}
+}
+
+func (h *InvitationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var data model.CreateInvitation
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	inv, err := h.service.Create(ctx, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "invitation sent", inv)
+}
+
+func (h *InvitationHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter := model.InvitationFilter{Status: r.URL.Query().Get("status")}
+	if err := filter.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	result, err := h.service.List(ctx, callerRole, filter, parsePagination(r))
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *InvitationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, _, callerRole, ok := invitationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	inv, err := h.service.GetByID(r.Context(), id, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", inv)
+}
+
+func (h *InvitationHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	id, callerID, callerRole, ok := invitationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	inv, err := h.service.Resend(r.Context(), id, callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "invitation resent", inv)
+}
+
+func (h *InvitationHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, callerID, callerRole, ok := invitationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), id, callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "invitation revoked", nil)
+}
+
+// Accept is public: the token in the invitation link identifies the
+// invitee.
+func (h *InvitationHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	var data model.AcceptInvitation
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	if err := h.service.Accept(r.Context(), &data); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "account created successfully", nil)
+}
+
+// invitationRequest reads the invitation id and the caller, writing the
+// error response itself when either is missing.
+func invitationRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, string, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Invitation ID",
+		})
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	callerID, callerRole := getCallerFromContext(r.Context())
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	return id, *callerID, callerRole, true
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/hl7"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+// labMessageMaxBytes caps the size of a submitted lab message.
+const labMessageMaxBytes = 1 << 20
+
+type LabHandler struct {
+	service *service.LabService
+}
+
+func NewLabHandler(service *service.LabService) *LabHandler {
+	return &LabHandler{
+		service: service,
+	}
+}
+
+func reconciliationID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Reconciliation ID",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func observationID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Lab Result ID",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// Receive reads an HL7 v2 message in ER7 encoding and replies with its
+// acknowledgment in the same encoding. Whether the message was accepted is
+// told by the acknowledgment, not the HTTP status.
+func (h *LabHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != hl7.ContentType && mediaType != "text/plain" {
+		responses.WriteError(w, responses.FromModelError(model.ErrUnsupportedMedia, "Content-Type must be "+hl7.ContentType))
+		return
+	}
+
+	if r.ContentLength > labMessageMaxBytes {
+		responses.WriteError(w, responses.FromModelError(model.ErrPayloadTooLarge, "lab message is too large"))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, labMessageMaxBytes)
+	defer body.Close()
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			responses.WriteError(w, responses.FromModelError(model.ErrPayloadTooLarge, "lab message is too large"))
+			return
+		}
+		responses.WriteError(w, responses.FromModelError(model.ErrBadRequest, "could not read lab message"))
+		return
+	}
+
+	ack, err := h.service.Receive(ctx, raw, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", hl7.ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(ack); err != nil {
+		log.Printf("error writing lab acknowledgment: %v", err)
+	}
+}
+
+func (h *LabHandler) ListObservations(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	result, err := h.service.ListObservations(ctx, id, parsePagination(r), *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *LabHandler) ListReconciliation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	filter := model.ReconciliationFilter{Status: r.URL.Query().Get("status")}
+	if err := filter.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	result, err := h.service.ListReconciliation(ctx, filter, parsePagination(r), callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *LabHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	id, ok := reconciliationID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.ResolveReconciliation
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	item, err := h.service.Resolve(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "lab results filed successfully", item)
+}
+
+func (h *LabHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	id, ok := reconciliationID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.DiscardReconciliation
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	item, err := h.service.Discard(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "lab results discarded successfully", item)
+}
+
+func (h *LabHandler) ListCritical(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	result, err := h.service.ListCritical(ctx, parsePagination(r), *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *LabHandler) AcknowledgeCritical(w http.ResponseWriter, r *http.Request) {
+	id, ok := observationID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	o, err := h.service.AcknowledgeCritical(ctx, id, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "critical lab result acknowledged successfully", o)
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+)
+
+type MedicationHandler struct {
+	service *service.MedicationService
+}
+
+func NewMedicationHandler(service *service.MedicationService) *MedicationHandler {
+	return &MedicationHandler{
+		service: service,
+	}
+}
+
+func (h *MedicationHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	filter := model.MedicationFilter{
+		Search:   r.URL.Query().Get("q"),
+		TermType: r.URL.Query().Get("term_type"),
+	}
+	if err := filter.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	result, err := h.service.List(ctx, filter, parsePagination(r))
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *MedicationHandler) GetByRxCUI(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	medication, err := h.service.GetByRxCUI(ctx, r.PathValue("rxcui"))
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", medication)
+}
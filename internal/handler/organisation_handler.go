@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type OrganisationHandler struct {
+	service *service.OrganisationService
+}
+
+func NewOrganisationHandler(service *service.OrganisationService) *OrganisationHandler {
+	return &OrganisationHandler{
+		service: service,
+	}
+}
+
+func (h *OrganisationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var data model.CreateOrganisation
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	org, err := h.service.Create(ctx, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "organisation created successfully", org)
+}
+
+func (h *OrganisationHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	orgs, err := h.service.List(ctx, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", orgs)
+}
+
+// memberPath parses the organisation and, if the route has one, the user in
+// the path. It writes the error response and returns false if either is
+// invalid.
+func memberPath(w http.ResponseWriter, r *http.Request, withUser bool) (orgID, userID uuid.UUID, ok bool) {
+	orgID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Organisation ID",
+		})
+		return orgID, userID, false
+	}
+
+	if withUser {
+		if userID, err = uuid.Parse(r.PathValue("userID")); err != nil {
+			responses.WriteError(w, responses.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Status:  "INVALID_ID",
+				Message: "Invalid User ID",
+			})
+			return orgID, userID, false
+		}
+	}
+
+	return orgID, userID, true
+}
+
+func (h *OrganisationHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	orgID, _, ok := memberPath(w, r, false)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	members, err := h.service.ListMembers(ctx, orgID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", members)
+}
+
+func (h *OrganisationHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	orgID, userID, ok := memberPath(w, r, true)
+	if !ok {
+		return
+	}
+
+	var data model.SetMember
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	member, err := h.service.SetMember(ctx, orgID, userID, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "membership updated successfully", member)
+}
+
+func (h *OrganisationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	orgID, userID, ok := memberPath(w, r, true)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.RemoveMember(ctx, orgID, userID, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "member removed successfully", nil)
+}
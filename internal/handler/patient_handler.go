@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type PatientHandler struct {
+	service *service.PatientService
+}
+
+func NewPatientHandler(service *service.PatientService) *PatientHandler {
+	return &PatientHandler{
+		service: service,
+	}
+}
+
+func parsePatientFilter(r *http.Request) (model.PatientFilter, error) {
+	query := r.URL.Query()
+
+	filter := model.PatientFilter{
+		Search:      query.Get("q"),
+		DateOfBirth: query.Get("date_of_birth"),
+	}
+
+	sort, err := model.ParseSort(query.Get("sort"), model.PatientSortFields)
+	if err != nil {
+		return filter, err
+	}
+	filter.Sort = sort
+
+	return filter, filter.Validate()
+}
+
+// patientID parses the patient ID in the path, writing the error response if
+// it is invalid.
+func patientID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Patient ID",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *PatientHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var data model.CreatePatient
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	patient, err := h.service.Create(ctx, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.SetETag(w, patient.Version)
+	responses.WriteSuccess(w, http.StatusCreated, "patient created successfully", patient)
+}
+
+func (h *PatientHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	filter, err := parsePatientFilter(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	result, err := h.service.List(ctx, *callerID, callerRole, filter, parsePagination(r))
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+// ListOwn returns the patient records linked to the caller.
+func (h *PatientHandler) ListOwn(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, _ := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	patients, err := h.service.ListOwn(ctx, *callerID)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", patients)
+}
+
+func (h *PatientHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	patient, err := h.service.GetByID(ctx, id, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.SetETag(w, patient.Version)
+	if responses.NoneMatch(r, patient.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", patient)
+}
+
+func (h *PatientHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	if !isMergePatch(r) {
+		w.Header().Set("Accept-Patch", model.MergePatchContentType)
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Status:  "UNSUPPORTED_MEDIA_TYPE",
+			Message: "Content-Type must be " + model.MergePatchContentType + " or application/json",
+		})
+		return
+	}
+
+	var data *model.UpdatePatient
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	cond, err := responses.ParseIfMatch(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, "If-Match header is required"))
+		return
+	}
+
+	version, err := h.service.UpdateByID(ctx, id, data, cond, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.SetETag(w, version)
+	responses.WriteSuccess(w, http.StatusOK, "patient updated successfully", nil)
+}
+
+func (h *PatientHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	cond, err := responses.ParseIfMatch(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, "If-Match header is required"))
+		return
+	}
+
+	if err := h.service.DeleteByID(ctx, id, cond, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "patient deleted successfully", nil)
+}
+
+func (h *PatientHandler) AddIdentifier(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.CreatePatientIdentifier
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	identifier, err := h.service.AddIdentifier(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "identifier added successfully", identifier)
+}
+
+func (h *PatientHandler) RemoveIdentifier(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	identifierID, err := uuid.Parse(r.PathValue("identifierID"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Identifier ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.RemoveIdentifier(ctx, id, identifierID, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "identifier removed successfully", nil)
+}
+
+func (h *PatientHandler) LinkUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.LinkPatientUser
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.LinkUser(ctx, id, &data, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "user linked successfully", nil)
+}
+
+func (h *PatientHandler) UnlinkUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := patientID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.UnlinkUser(ctx, id, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "user unlinked successfully", nil)
+}
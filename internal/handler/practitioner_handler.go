@@ -0,0 +1,395 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type PractitionerHandler struct {
+	service *service.PractitionerService
+}
+
+func NewPractitionerHandler(service *service.PractitionerService) *PractitionerHandler {
+	return &PractitionerHandler{
+		service: service,
+	}
+}
+
+func parsePractitionerFilter(r *http.Request) (model.PractitionerFilter, error) {
+	query := r.URL.Query()
+
+	filter := model.PractitionerFilter{
+		Search:     query.Get("q"),
+		Profession: query.Get("profession"),
+		Specialty:  query.Get("specialty"),
+		Location:   query.Get("location"),
+	}
+
+	if raw := query.Get("facility_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return filter, model.ValidationErrors{model.FieldError{Field: "facility_id", Message: "invalid facility id"}}
+		}
+		filter.FacilityID = &id
+	}
+
+	return filter, filter.Validate()
+}
+
+// practitionerID parses the practitioner ID in the path, writing the error
+// response if it is invalid.
+func practitionerID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Practitioner ID",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *PractitionerHandler) CreateFacility(w http.ResponseWriter, r *http.Request) {
+	var data model.CreateFacility
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	facility, err := h.service.CreateFacility(ctx, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "facility created successfully", facility)
+}
+
+func (h *PractitionerHandler) ListFacilities(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	facilities, err := h.service.ListFacilities(ctx, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", facilities)
+}
+
+func (h *PractitionerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var data model.CreatePractitioner
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	practitioner, err := h.service.Create(ctx, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.SetETag(w, practitioner.Version)
+	responses.WriteSuccess(w, http.StatusCreated, "practitioner created successfully", practitioner)
+}
+
+func (h *PractitionerHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	filter, err := parsePractitionerFilter(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	result, err := h.service.List(ctx, callerRole, filter, parsePagination(r))
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *PractitionerHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	practitioner, err := h.service.GetByID(ctx, id, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.SetETag(w, practitioner.Version)
+	if responses.NoneMatch(r, practitioner.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", practitioner)
+}
+
+func (h *PractitionerHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	if !isMergePatch(r) {
+		w.Header().Set("Accept-Patch", model.MergePatchContentType)
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Status:  "UNSUPPORTED_MEDIA_TYPE",
+			Message: "Content-Type must be " + model.MergePatchContentType + " or application/json",
+		})
+		return
+	}
+
+	var data *model.UpdatePractitioner
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	cond, err := responses.ParseIfMatch(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, "If-Match header is required"))
+		return
+	}
+
+	version, err := h.service.UpdateByID(ctx, id, data, cond, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.SetETag(w, version)
+	responses.WriteSuccess(w, http.StatusOK, "practitioner updated successfully", nil)
+}
+
+func (h *PractitionerHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	cond, err := responses.ParseIfMatch(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, "If-Match header is required"))
+		return
+	}
+
+	if err := h.service.DeleteByID(ctx, id, cond, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "practitioner deleted successfully", nil)
+}
+
+func (h *PractitionerHandler) AddLicence(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	var data model.CreateLicence
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	licence, err := h.service.AddLicence(ctx, id, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "licence added successfully", licence)
+}
+
+func (h *PractitionerHandler) RemoveLicence(w http.ResponseWriter, r *http.Request) {
+	id, ok := practitionerID(w, r)
+	if !ok {
+		return
+	}
+
+	licenceID, err := uuid.Parse(r.PathValue("licenceID"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid Licence ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.RemoveLicence(ctx, id, licenceID, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "licence removed successfully", nil)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+)
+
+// exportWriteTimeout bounds how long a single write of a streamed export
+// may take. The deadline is pushed back as the export makes progress, so
+// large exports are not cut off by the server's WriteTimeout.
+const exportWriteTimeout = 30 * time.Second
+
+// streamWriter sets the response headers on the first write and extends the
+// write deadline as data goes out.
+type streamWriter struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	contentType string
+	filename    string
+	started     bool
+	deadline    time.Time
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", s.contentType)
+		s.w.Header().Set("Content-Disposition", `attachment; filename="`+s.filename+`"`)
+		s.w.Header().Set("Cache-Control", "no-store")
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	if now := time.Now(); s.deadline.Sub(now) < exportWriteTimeout/2 {
+		s.deadline = now.Add(exportWriteTimeout)
+		// Not every ResponseWriter supports deadlines; the server's
+		// WriteTimeout then applies.
+		_ = s.rc.SetWriteDeadline(s.deadline)
+	}
+
+	return s.w.Write(p)
+}
+
+// Export streams the users matching the listing filters as CSV or NDJSON.
+// format selects the encoding and fields the columns.
+func (h *UserHandler) Export(w http.ResponseWriter, r *http.Request) {
+	callerID, callerRole := getCallerFromContext(r.Context())
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	filter, err := parseUserFilter(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	format, err := model.ParseUserExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	fields, err := model.ParseUserExportFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	out := &streamWriter{
+		w:           w,
+		rc:          http.NewResponseController(w),
+		contentType: "text/csv; charset=utf-8",
+		filename:    "users.csv",
+	}
+	if format == model.UserExportNDJSON {
+		out.contentType = "application/x-ndjson"
+		out.filename = "users.ndjson"
+	}
+
+	// The request context carries the router's 30 second timeout, which a
+	// long export would exceed. A client that goes away is noticed when a
+	// write fails instead.
+	ctx := context.WithoutCancel(r.Context())
+
+	if err := h.service.ExportUsers(ctx, out, format, fields, filter, callerRole); err != nil {
+		if !out.started {
+			responses.WriteError(w, responses.FromModelError(err, err.Error()))
+			return
+		}
+		log.Printf("user export: %v", err)
+	}
+}
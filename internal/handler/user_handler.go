@@ -2,8 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/PranavJoshi2893/med-portal/internal/model"
 	"github.com/PranavJoshi2893/med-portal/internal/service"
@@ -11,6 +13,18 @@ import (
 	"github.com/google/uuid"
 )
 
+// isMergePatch reports whether the request body is a JSON Merge Patch.
+// Plain application/json is accepted too, and a missing Content-Type is
+// assumed to be JSON.
+func isMergePatch(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	return err == nil && (mt == model.MergePatchContentType || mt == "application/json")
+}
+
 func getCallerFromContext(ctx interface{ Value(any) any }) (*uuid.UUID, string) {
 	userID, ok := ctx.Value("user_id").(uuid.UUID)
 	if !ok {
@@ -48,6 +62,88 @@ func parsePagination(r *http.Request) model.PaginationParams {
 	return model.PaginationParams{Page: page, Limit: limit}
 }
 
+// parseCursorPagination reads the keyset pagination parameters. Unlike the
+// page/limit mode any limit from 1 to 100 is accepted.
+func parseCursorPagination(r *http.Request) (model.CursorParams, error) {
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+	params := model.CursorParams{Limit: limit}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := model.DecodeCursor(raw)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = cursor
+	}
+
+	return params, nil
+}
+
+// cursorLink returns the URL of the current request with its cursor and
+// limit replaced.
+func cursorLink(r *http.Request, cursor string, limit int) string {
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(limit))
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// parseTimeParam accepts either an RFC 3339 timestamp or a YYYY-MM-DD date.
+// A bare date used as an upper bound covers the whole day.
+func parseTimeParam(raw string, endOfDay bool) (*time.Time, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, true
+	}
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return nil, false
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, true
+}
+
+func parseUserFilter(r *http.Request) (model.UserFilter, error) {
+	query := r.URL.Query()
+	var errs model.ValidationErrors
+
+	filter := model.UserFilter{
+		Search:  query.Get("q"),
+		Role:    query.Get("role"),
+		Status:  query.Get("status"),
+		Deleted: query.Get("deleted"),
+	}
+
+	var ok bool
+	if filter.CreatedFrom, ok = parseTimeParam(query.Get("created_from"), false); !ok {
+		errs = append(errs, model.FieldError{Field: "created_from", Message: "invalid date"})
+	}
+	if filter.CreatedTo, ok = parseTimeParam(query.Get("created_to"), true); !ok {
+		errs = append(errs, model.FieldError{Field: "created_to", Message: "invalid date"})
+	}
+
+	sort, err := model.ParseSort(query.Get("sort"), model.UserSortFields)
+	if err != nil {
+		return filter, err
+	}
+	filter.Sort = sort
+
+	if len(errs) > 0 {
+		return filter, errs
+	}
+
+	return filter, filter.Validate()
+}
+
 func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	callerID, callerRole := getCallerFromContext(ctx)
@@ -59,8 +155,21 @@ func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	filter, err := parseUserFilter(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	// The presence of a cursor parameter, even an empty one, selects keyset
+	// pagination. Without it the page/limit mode is used.
+	if r.URL.Query().Has("cursor") {
+		h.getAllByCursor(w, r, *callerID, callerRole, filter)
+		return
+	}
+
 	params := parsePagination(r)
-	result, err := h.service.GetAll(ctx, *callerID, callerRole, params)
+	result, err := h.service.GetAll(ctx, *callerID, callerRole, filter, params)
 	if err != nil {
 		responses.WriteError(w, responses.FromModelError(err, err.Error()))
 		return
@@ -74,6 +183,38 @@ func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+func (h *UserHandler) getAllByCursor(w http.ResponseWriter, r *http.Request, callerID uuid.UUID, callerRole string, filter model.UserFilter) {
+	if len(filter.Sort) > 0 {
+		responses.WriteError(w, responses.FromModelError(model.ValidationErrors{
+			model.FieldError{Field: "sort", Message: "sort is not supported with cursor pagination"},
+		}, ""))
+		return
+	}
+
+	params, err := parseCursorPagination(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	result, err := h.service.GetAllByCursor(r.Context(), callerID, callerRole, filter, params)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	var links []responses.Link
+	if result.Meta.PrevCursor != "" {
+		links = append(links, responses.Link{URL: cursorLink(r, result.Meta.PrevCursor, params.Limit), Rel: "prev"})
+	}
+	if result.Meta.NextCursor != "" {
+		links = append(links, responses.Link{URL: cursorLink(r, result.Meta.NextCursor, params.Limit), Rel: "next"})
+	}
+	responses.SetLinkHeader(w, links...)
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
 func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
@@ -102,6 +243,12 @@ func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	responses.SetETag(w, user.Version)
+	if responses.NoneMatch(r, user.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	responses.WriteSuccess(w, http.StatusOK, "success", user)
 }
 
@@ -127,7 +274,13 @@ func (h *UserHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteByID(ctx, id, *callerID, callerRole); err != nil {
+	cond, err := responses.ParseIfMatch(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, "If-Match header is required"))
+		return
+	}
+
+	if err := h.service.DeleteByID(ctx, id, cond, *callerID, callerRole); err != nil {
 		responses.WriteError(w, responses.FromModelError(err, err.Error()))
 		return
 	}
@@ -153,6 +306,16 @@ func (h *UserHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !isMergePatch(r) {
+		w.Header().Set("Accept-Patch", model.MergePatchContentType)
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Status:  "UNSUPPORTED_MEDIA_TYPE",
+			Message: "Content-Type must be " + model.MergePatchContentType + " or application/json",
+		})
+		return
+	}
+
 	var user *model.UpdateUser
 
 	dec := json.NewDecoder(r.Body)
@@ -183,12 +346,20 @@ func (h *UserHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	err = h.service.UpdateByID(ctx, id, user, *callerID, callerRole)
+	cond, err := responses.ParseIfMatch(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, "If-Match header is required"))
+		return
+	}
+
+	version, err := h.service.UpdateByID(ctx, id, user, cond, *callerID, callerRole)
 	if err != nil {
 		responses.WriteError(w, responses.FromModelError(err, err.Error()))
 		return
 	}
 
+	responses.SetETag(w, version)
+
 	responses.WriteSuccess(
 		w,
 		http.StatusOK,
@@ -196,3 +367,60 @@ func (h *UserHandler) UpdateByID(w http.ResponseWriter, r *http.Request) {
 		nil,
 	)
 }
+
+func (h *UserHandler) GetDeleted(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	filter, err := parseUserFilter(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	result, err := h.service.GetDeleted(ctx, callerRole, filter, parsePagination(r))
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *UserHandler) RestoreByID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid User ID",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.RestoreByID(ctx, id, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "user restored successfully", nil)
+}
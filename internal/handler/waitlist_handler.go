@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
+)
+
+type WaitlistHandler struct {
+	service *service.WaitlistService
+}
+
+func NewWaitlistHandler(service *service.WaitlistService) *WaitlistHandler {
+	return &WaitlistHandler{
+		service: service,
+	}
+}
+
+// parseWaitlistFilter reads the waitlist filters from the query string.
+func parseWaitlistFilter(r *http.Request) (model.WaitlistFilter, error) {
+	query := r.URL.Query()
+	var errs model.ValidationErrors
+
+	filter := model.WaitlistFilter{
+		Status: query.Get("status"),
+	}
+
+	for field, dst := range map[string]**uuid.UUID{"practitioner_id": &filter.PractitionerID, "patient_id": &filter.PatientID} {
+		raw := query.Get(field)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			errs = append(errs, model.FieldError{Field: field, Message: "invalid id"})
+			continue
+		}
+		*dst = &id
+	}
+
+	if len(errs) > 0 {
+		return filter, errs
+	}
+
+	return filter, filter.Validate()
+}
+
+// waitlistID parses the ID in the path, writing the error response if it is
+// invalid.
+func waitlistID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_ID",
+			Message: "Invalid " + name + " ID",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *WaitlistHandler) Join(w http.ResponseWriter, r *http.Request) {
+	var data model.JoinWaitlist
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	defer r.Body.Close()
+
+	if err := dec.Decode(&data); err != nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Status:  "INVALID_JSON",
+			Message: "Invalid JSON payload",
+		})
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	entry, err := h.service.Join(ctx, &data, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "added to waitlist", entry)
+}
+
+func (h *WaitlistHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	filter, err := parseWaitlistFilter(r)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, ""))
+		return
+	}
+
+	result, err := h.service.List(ctx, filter, parsePagination(r), *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", result)
+}
+
+func (h *WaitlistHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := waitlistID(w, r, "Waitlist Entry")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	entry, err := h.service.GetByID(ctx, id, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "success", entry)
+}
+
+func (h *WaitlistHandler) Leave(w http.ResponseWriter, r *http.Request) {
+	id, ok := waitlistID(w, r, "Waitlist Entry")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.Leave(ctx, id, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "removed from waitlist", nil)
+}
+
+func (h *WaitlistHandler) AcceptOffer(w http.ResponseWriter, r *http.Request) {
+	id, ok := waitlistID(w, r, "Offer")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	appointment, err := h.service.Accept(ctx, id, *callerID, callerRole)
+	if err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusCreated, "appointment booked successfully", appointment)
+}
+
+func (h *WaitlistHandler) DeclineOffer(w http.ResponseWriter, r *http.Request) {
+	id, ok := waitlistID(w, r, "Offer")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	callerID, callerRole := getCallerFromContext(ctx)
+	if callerID == nil {
+		responses.WriteError(w, responses.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Status:  "UNAUTHORIZED",
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.Decline(ctx, id, *callerID, callerRole); err != nil {
+		responses.WriteError(w, responses.FromModelError(err, err.Error()))
+		return
+	}
+
+	responses.WriteSuccess(w, http.StatusOK, "offer declined", nil)
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+)
+
+// ProcessDataExports returns a job that builds queued personal data exports
+// and removes archives whose download link has expired.
+func ProcessDataExports(exports *service.ExportService) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := exports.ProcessPending(ctx)
+		if n > 0 {
+			log.Printf("completed %d data exports", n)
+		}
+		if err != nil {
+			return err
+		}
+
+		n, err = exports.CleanupExpired(ctx)
+		if n > 0 {
+			log.Printf("expired %d data exports", n)
+		}
+		return err
+	}
+}
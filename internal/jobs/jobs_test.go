@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunPeriodic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls atomic.Int32
+	done := make(chan struct{})
+
+	go func() {
+		RunPeriodic(ctx, "test", 5*time.Millisecond, func(ctx context.Context) error {
+			if calls.Add(1) == 3 {
+				cancel()
+			}
+			return errors.New("keeps running")
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPeriodic did not stop after cancel")
+	}
+
+	if got := calls.Load(); got < 3 {
+		t.Errorf("got %d calls want at least 3", got)
+	}
+}
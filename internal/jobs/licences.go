@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+)
+
+// FlagExpiringLicences returns a job that flags practitioner licences
+// expiring within the warning period and notifies their holders.
+func FlagExpiringLicences(practitioners *service.PractitionerService, warning time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := practitioners.FlagExpiringLicences(ctx, warning)
+		if n > 0 {
+			log.Printf("flagged %d expiring licences", n)
+		}
+		return err
+	}
+}
@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+)
+
+const purgeBatchSize = 100
+
+// PurgeDeletedUsers returns a job that purges accounts soft-deleted for
+// longer than retention.
+func PurgeDeletedUsers(users *service.UserService, retention time.Duration, mode string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := users.PurgeDeleted(ctx, retention, mode, purgeBatchSize)
+		if n > 0 {
+			log.Printf("purged %d deleted users (%s)", n, mode)
+		}
+		return err
+	}
+}
@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"github.com/PranavJoshi2893/med-portal/internal/service"
+)
+
+// LapseWaitlistOffers returns a job that closes waitlist offers whose hold has
+// expired and passes their slots to the next patient.
+func LapseWaitlistOffers(waitlist *service.WaitlistService) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := waitlist.LapseOffers(ctx)
+		if n > 0 {
+			log.Printf("passed on %d lapsed waitlist offers", n)
+		}
+		return err
+	}
+}
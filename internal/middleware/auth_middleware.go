@@ -2,53 +2,76 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/PranavJoshi2893/med-portal/internal/config"
+	"github.com/PranavJoshi2893/med-portal/internal/model"
 	"github.com/PranavJoshi2893/med-portal/pkg/auth"
+	"github.com/PranavJoshi2893/med-portal/pkg/fhir"
 	"github.com/PranavJoshi2893/med-portal/pkg/responses"
+	"github.com/google/uuid"
 )
 
-func AccessTokenMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+// AccountChecker reports whether a user may still use tokens issued to them
+// for an organisation, for example because the account has since been
+// suspended or removed from it, and returns the role they now hold there.
+type AccountChecker interface {
+	CheckAccount(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (string, error)
+}
+
+// AccessTokenMiddleware authenticates the request with the bearer token and
+// then checks that the account is still active and a member of the token's
+// organisation, so a suspension or a change of role takes effect immediately
+// rather than when the token expires. The organisation is stored in the
+// context, where the repositories use it to scope their queries.
+func AccessTokenMiddleware(cfg *config.Config, accounts AccountChecker) func(http.Handler) http.Handler {
+	return accessToken(cfg, accounts, func(w http.ResponseWriter, err error, message string) {
+		responses.WriteError(w, responses.FromModelError(err, message))
+	})
+}
+
+// FHIRAccessTokenMiddleware is AccessTokenMiddleware for the FHIR API, which
+// reports errors as OperationOutcome resources.
+func FHIRAccessTokenMiddleware(cfg *config.Config, accounts AccountChecker) func(http.Handler) http.Handler {
+	return accessToken(cfg, accounts, func(w http.ResponseWriter, err error, _ string) {
+		status, outcome := fhir.FromError(err, nil)
+		w.Header().Set("Content-Type", fhir.ContentType)
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(outcome); err != nil {
+			log.Printf("error encoding operation outcome: %v", err)
+		}
+	})
+}
+
+func accessToken(cfg *config.Config, accounts AccountChecker, fail func(w http.ResponseWriter, err error, message string)) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := r.Header.Get("Authorization")
-
+			token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
 			if token == "" {
-				responses.WriteError(w, responses.ErrorResponse{
-					Code:    http.StatusUnauthorized,
-					Status:  "UNAUTHORIZED",
-					Message: "Unauthorized",
-				})
+				fail(w, model.ErrUnauthorized, "Unauthorized")
 				return
 			}
 
-			token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
-			if token == "" {
-				responses.WriteError(w, responses.ErrorResponse{
-					Code:    http.StatusUnauthorized,
-					Status:  "UNAUTHORIZED",
-					Message: "Unauthorized",
-				})
+			claims, err := auth.VerifyAccessToken(cfg.AccessTokenKey, token)
+			if err != nil {
+				fail(w, model.ErrUnauthorized, "Unauthorized")
 				return
 			}
 
-			claims, err := auth.VerifyAccessToken(cfg.AccessTokenKey, token)
+			role, err := accounts.CheckAccount(r.Context(), claims.UserID, claims.OrganisationID)
 			if err != nil {
-				responses.WriteError(w, responses.ErrorResponse{
-					Code:    http.StatusUnauthorized,
-					Status:  "UNAUTHORIZED",
-					Message: "Unauthorized",
-				})
+				fail(w, err, err.Error())
 				return
 			}
-			role := claims.Role
-			if role == "" {
-				role = "user"
-			}
+
 			ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
 			ctx = context.WithValue(ctx, "role", role)
+			if claims.OrganisationID != nil {
+				ctx = context.WithValue(ctx, "organisation_id", *claims.OrganisationID)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -80,6 +103,9 @@ func RefreshTokenMiddleware(cfg *config.Config) func(http.Handler) http.Handler
 			ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
 			ctx = context.WithValue(ctx, "role", claims.Role)
 			ctx = context.WithValue(ctx, "refresh_token", token.Value)
+			if claims.OrganisationID != nil {
+				ctx = context.WithValue(ctx, "organisation_id", *claims.OrganisationID)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
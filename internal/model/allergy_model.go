@@ -0,0 +1,169 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Allergy severities.
+const (
+	AllergyMild     = "mild"
+	AllergyModerate = "moderate"
+	AllergySevere   = "severe"
+)
+
+var allergySeverities = map[string]bool{
+	AllergyMild:     true,
+	AllergyModerate: true,
+	AllergySevere:   true,
+}
+
+// Allergy verification statuses. A refuted allergy, or one entered in
+// error, is kept on the record but no longer checked against prescriptions.
+const (
+	AllergyUnconfirmed    = "unconfirmed"
+	AllergyConfirmed      = "confirmed"
+	AllergyRefuted        = "refuted"
+	AllergyEnteredInError = "entered_in_error"
+)
+
+var allergyVerifications = map[string]bool{
+	AllergyUnconfirmed:    true,
+	AllergyConfirmed:      true,
+	AllergyRefuted:        true,
+	AllergyEnteredInError: true,
+}
+
+// Allergy is an allergy recorded for a patient. RxCUI links the allergen to
+// the medication catalogue, when it was chosen from it.
+type Allergy struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganisationID uuid.UUID  `json:"organisation_id"`
+	PatientID      uuid.UUID  `json:"patient_id"`
+	Allergen       string     `json:"allergen"`
+	RxCUI          *string    `json:"rxcui"`
+	Reaction       *string    `json:"reaction"`
+	Severity       string     `json:"severity"`
+	Verification   string     `json:"verification"`
+	RecordedBy     *uuid.UUID `json:"recorded_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the allergy is still checked against
+// prescriptions.
+func (a *Allergy) Active() bool {
+	return a.Verification != AllergyRefuted && a.Verification != AllergyEnteredInError
+}
+
+// ConflictSeverity is the severity of a conflict between the allergy and a
+// drug containing the allergen.
+func (a *Allergy) ConflictSeverity() string {
+	switch a.Severity {
+	case AllergySevere:
+		return SeverityHigh
+	case AllergyModerate:
+		return SeverityModerate
+	default:
+		return SeverityLow
+	}
+}
+
+// CreateAllergy records an allergy. Allergen may be left out when RxCUI is
+// given, and is then the catalogue name. Verification defaults to
+// unconfirmed.
+type CreateAllergy struct {
+	Allergen     string `json:"allergen"`
+	RxCUI        string `json:"rxcui"`
+	Reaction     string `json:"reaction"`
+	Severity     string `json:"severity"`
+	Verification string `json:"verification"`
+}
+
+func (m *CreateAllergy) Validate() error {
+	var errs ValidationErrors
+
+	m.RxCUI = strings.TrimSpace(m.RxCUI)
+	if len(m.RxCUI) > 20 {
+		errs = append(errs, FieldError{Field: "rxcui", Message: "rxcui must be at most 20 characters"})
+	}
+
+	m.Allergen = strings.TrimSpace(m.Allergen)
+	if m.Allergen == "" && m.RxCUI == "" {
+		errs = append(errs, FieldError{Field: "allergen", Message: "allergen or rxcui is required"})
+	} else if len(m.Allergen) > 200 {
+		errs = append(errs, FieldError{Field: "allergen", Message: "allergen must be at most 200 characters"})
+	}
+
+	m.Reaction = strings.TrimSpace(m.Reaction)
+	if len(m.Reaction) > 500 {
+		errs = append(errs, FieldError{Field: "reaction", Message: "reaction must be at most 500 characters"})
+	}
+
+	m.Severity = strings.ToLower(strings.TrimSpace(m.Severity))
+	if m.Severity == "" {
+		errs = append(errs, FieldError{Field: "severity", Message: "severity is required"})
+	} else if !allergySeverities[m.Severity] {
+		errs = append(errs, FieldError{Field: "severity", Message: "severity must be mild, moderate or severe"})
+	}
+
+	m.Verification = strings.ToLower(strings.TrimSpace(m.Verification))
+	if m.Verification == "" {
+		m.Verification = AllergyUnconfirmed
+	} else if !allergyVerifications[m.Verification] {
+		errs = append(errs, FieldError{Field: "verification", Message: "verification must be unconfirmed, confirmed, refuted or entered_in_error"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// UpdateAllergy is a merge patch of an allergy. The allergen cannot be
+// changed; an allergy recorded against the wrong allergen is marked
+// entered_in_error and recorded again.
+type UpdateAllergy struct {
+	Reaction     Optional[string] `json:"reaction"`
+	Severity     Optional[string] `json:"severity"`
+	Verification Optional[string] `json:"verification"`
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (m *UpdateAllergy) IsEmpty() bool {
+	return !m.Reaction.Set && !m.Severity.Set && !m.Verification.Set
+}
+
+func (m *UpdateAllergy) Validate() error {
+	if m == nil {
+		return ValidationErrors{FieldError{Field: "body", Message: "patch must be a JSON object"}}
+	}
+
+	var errs ValidationErrors
+
+	normalizeOptional(&m.Reaction)
+	if m.Reaction.HasValue() && len(m.Reaction.Value) > 500 {
+		errs = append(errs, FieldError{Field: "reaction", Message: "reaction must be at most 500 characters"})
+	}
+
+	if m.Severity.Set {
+		m.Severity.Value = strings.ToLower(strings.TrimSpace(m.Severity.Value))
+		if m.Severity.Null || !allergySeverities[m.Severity.Value] {
+			errs = append(errs, FieldError{Field: "severity", Message: "severity must be mild, moderate or severe"})
+		}
+	}
+
+	if m.Verification.Set {
+		m.Verification.Value = strings.ToLower(strings.TrimSpace(m.Verification.Value))
+		if m.Verification.Null || !allergyVerifications[m.Verification.Value] {
+			errs = append(errs, FieldError{Field: "verification", Message: "verification must be unconfirmed, confirmed, refuted or entered_in_error"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreateAllergy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    CreateAllergy
+		wantErr bool
+	}{
+		{name: "valid", data: CreateAllergy{Allergen: "penicillin", Severity: "Severe"}},
+		{name: "catalogue allergen", data: CreateAllergy{RxCUI: "7980", Severity: "mild", Verification: "confirmed"}},
+		{name: "no allergen", data: CreateAllergy{Severity: "mild"}, wantErr: true},
+		{name: "no severity", data: CreateAllergy{Allergen: "peanuts"}, wantErr: true},
+		{name: "unknown severity", data: CreateAllergy{Allergen: "peanuts", Severity: "fatal"}, wantErr: true},
+		{name: "unknown verification", data: CreateAllergy{Allergen: "peanuts", Severity: "mild", Verification: "maybe"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.data.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (tt.data.Verification == "" || tt.data.Severity != strings.ToLower(tt.data.Severity)) {
+				t.Errorf("data = %+v, want a normalised severity and verification", tt.data)
+			}
+		})
+	}
+}
+
+func TestAllergy_ConflictSeverity(t *testing.T) {
+	for severity, want := range map[string]string{AllergyMild: SeverityLow, AllergyModerate: SeverityModerate, AllergySevere: SeverityHigh} {
+		a := Allergy{Severity: severity}
+		if got := a.ConflictSeverity(); got != want {
+			t.Errorf("ConflictSeverity(%s) = %s, want %s", severity, got, want)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Appointment statuses. A booked appointment is checked in when the patient
+// arrives and completed after the visit, or marked as a no-show. Cancelled
+// appointments free their slot.
+const (
+	AppointmentBooked    = "booked"
+	AppointmentCheckedIn = "checked_in"
+	AppointmentCompleted = "completed"
+	AppointmentNoShow    = "no_show"
+	AppointmentCancelled = "cancelled"
+)
+
+// appointmentTransitions lists the statuses each status can move to.
+var appointmentTransitions = map[string][]string{
+	AppointmentBooked:    {AppointmentCheckedIn, AppointmentNoShow, AppointmentCancelled},
+	AppointmentCheckedIn: {AppointmentCompleted},
+}
+
+var appointmentStatuses = map[string]bool{
+	AppointmentBooked:    true,
+	AppointmentCheckedIn: true,
+	AppointmentCompleted: true,
+	AppointmentNoShow:    true,
+	AppointmentCancelled: true,
+}
+
+// CanTransition reports whether an appointment may move from one status to
+// another.
+func CanTransition(from, to string) bool {
+	for _, s := range appointmentTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TimeOfDayLayout is the layout of the start and end of a schedule window.
+const TimeOfDayLayout = "15:04"
+
+const (
+	minSlotMinutes = 5
+	maxSlotMinutes = 480
+	maxWindows     = 50
+
+	// MaxSlotRangeDays caps the days of open slots returned at once.
+	MaxSlotRangeDays = 31
+)
+
+// ScheduleWindow is a span of a weekday when a practitioner sees patients.
+// Weekday counts from Sunday (0) to Saturday (6); Start and End are local
+// times such as 09:00.
+type ScheduleWindow struct {
+	Weekday int    `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// Schedule is the weekly availability template of a practitioner. Its
+// windows are cut into slots of SlotMinutes in Timezone.
+type Schedule struct {
+	PractitionerID uuid.UUID        `json:"practitioner_id"`
+	Timezone       string           `json:"timezone"`
+	SlotMinutes    int              `json:"slot_minutes"`
+	Windows        []ScheduleWindow `json:"windows"`
+	UpdatedAt      *time.Time       `json:"updated_at"`
+}
+
+// SetSchedule replaces the weekly schedule of a practitioner.
+type SetSchedule struct {
+	Timezone    string           `json:"timezone"`
+	SlotMinutes int              `json:"slot_minutes"`
+	Windows     []ScheduleWindow `json:"windows"`
+}
+
+func (m *SetSchedule) Validate() error {
+	var errs ValidationErrors
+
+	m.Timezone = strings.TrimSpace(m.Timezone)
+	if m.Timezone == "" {
+		errs = append(errs, FieldError{Field: "timezone", Message: "timezone is required"})
+	} else if _, err := time.LoadLocation(m.Timezone); err != nil {
+		errs = append(errs, FieldError{Field: "timezone", Message: "unknown timezone"})
+	}
+
+	if m.SlotMinutes < minSlotMinutes || m.SlotMinutes > maxSlotMinutes {
+		errs = append(errs, FieldError{Field: "slot_minutes", Message: fmt.Sprintf("slot_minutes must be between %d and %d", minSlotMinutes, maxSlotMinutes)})
+	}
+
+	if len(m.Windows) > maxWindows {
+		errs = append(errs, FieldError{Field: "windows", Message: fmt.Sprintf("at most %d windows", maxWindows)})
+	}
+
+	valid := true
+	for i := range m.Windows {
+		w := &m.Windows[i]
+		prefix := fmt.Sprintf("windows[%d].", i)
+		w.Start = strings.TrimSpace(w.Start)
+		w.End = strings.TrimSpace(w.End)
+
+		if w.Weekday < 0 || w.Weekday > 6 {
+			errs = append(errs, FieldError{Field: prefix + "weekday", Message: "weekday must be between 0 (Sunday) and 6 (Saturday)"})
+			valid = false
+		}
+		start, okStart := parseTimeOfDay(w.Start)
+		if !okStart {
+			errs = append(errs, FieldError{Field: prefix + "start", Message: "start must be HH:MM"})
+		}
+		end, okEnd := parseTimeOfDay(w.End)
+		if !okEnd {
+			errs = append(errs, FieldError{Field: prefix + "end", Message: "end must be HH:MM"})
+		}
+		if okStart && okEnd && end <= start {
+			errs = append(errs, FieldError{Field: prefix + "end", Message: "end must be after start"})
+		}
+		valid = valid && okStart && okEnd && end > start
+	}
+
+	if valid {
+		sort.Slice(m.Windows, func(i, j int) bool {
+			if m.Windows[i].Weekday != m.Windows[j].Weekday {
+				return m.Windows[i].Weekday < m.Windows[j].Weekday
+			}
+			return m.Windows[i].Start < m.Windows[j].Start
+		})
+		for i := 1; i < len(m.Windows); i++ {
+			prev, cur := m.Windows[i-1], m.Windows[i]
+			if prev.Weekday == cur.Weekday && cur.Start < prev.End {
+				errs = append(errs, FieldError{Field: "windows", Message: "windows on the same weekday must not overlap"})
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// parseTimeOfDay returns the minutes since midnight of an HH:MM time.
+func parseTimeOfDay(s string) (int, bool) {
+	t, err := time.Parse(TimeOfDayLayout, s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// TimeRange is a span of time, including Start and excluding End.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Overlaps reports whether r and o share any time.
+func (r TimeRange) Overlaps(o TimeRange) bool {
+	return r.Start.Before(o.End) && o.Start.Before(r.End)
+}
+
+// Slot is a bookable span of a practitioner's schedule.
+type Slot struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// Slots returns the slots of s that start in [from, to) and not before now,
+// leaving out those that overlap busy. Slots are laid out from the start of
+// each window, and a slot that would run past the end of its window is left
+// out. Days are taken in the schedule's time zone, so windows keep their
+// local times across daylight saving changes.
+func (s *Schedule) Slots(from, to, now time.Time, busy []TimeRange) ([]Slot, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	if s.SlotMinutes <= 0 {
+		return nil, fmt.Errorf("invalid slot length %d", s.SlotMinutes)
+	}
+	length := time.Duration(s.SlotMinutes) * time.Minute
+
+	slots := []Slot{}
+	first := from.In(loc)
+	last := to.In(loc)
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc); day.Before(last); day = day.AddDate(0, 0, 1) {
+		for _, w := range s.Windows {
+			if w.Weekday != int(day.Weekday()) {
+				continue
+			}
+			start, ok := parseTimeOfDay(w.Start)
+			end, ok2 := parseTimeOfDay(w.End)
+			if !ok || !ok2 {
+				continue
+			}
+
+			windowEnd := time.Date(day.Year(), day.Month(), day.Day(), end/60, end%60, 0, 0, loc)
+			for t := time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, loc); !t.Add(length).After(windowEnd); t = t.Add(length) {
+				if t.Before(from) || !t.Before(to) || t.Before(now) {
+					continue
+				}
+				slot := TimeRange{Start: t, End: t.Add(length)}
+				free := true
+				for _, b := range busy {
+					if slot.Overlaps(b) {
+						free = false
+						break
+					}
+				}
+				if free {
+					slots = append(slots, Slot{StartsAt: slot.Start, EndsAt: slot.End})
+				}
+			}
+		}
+	}
+
+	return slots, nil
+}
+
+// SlotRange is a request for open slots from From to To, both dates in the
+// practitioner's time zone and both included. From defaults to today and To
+// to a week after From.
+type SlotRange struct {
+	From string
+	To   string
+}
+
+func (m *SlotRange) Validate() error {
+	var errs ValidationErrors
+
+	m.From = strings.TrimSpace(m.From)
+	m.To = strings.TrimSpace(m.To)
+	if m.From == "" {
+		m.From = time.Now().Format(DateLayout)
+	}
+
+	from, err := time.Parse(DateLayout, m.From)
+	if err != nil {
+		errs = append(errs, FieldError{Field: "from", Message: "from must be YYYY-MM-DD"})
+	} else if m.To == "" {
+		m.To = from.AddDate(0, 0, 6).Format(DateLayout)
+	}
+
+	to, err := time.Parse(DateLayout, m.To)
+	if err != nil {
+		errs = append(errs, FieldError{Field: "to", Message: "to must be YYYY-MM-DD"})
+	} else if len(errs) == 0 {
+		if to.Before(from) {
+			errs = append(errs, FieldError{Field: "to", Message: "to must not be before from"})
+		} else if to.Sub(from) >= MaxSlotRangeDays*24*time.Hour {
+			errs = append(errs, FieldError{Field: "to", Message: fmt.Sprintf("at most %d days at a time", MaxSlotRangeDays)})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// AvailabilityException takes a practitioner out of their schedule, for
+// example for a holiday or leave.
+type AvailabilityException struct {
+	ID             uuid.UUID  `json:"id"`
+	PractitionerID uuid.UUID  `json:"practitioner_id"`
+	StartsAt       time.Time  `json:"starts_at"`
+	EndsAt         time.Time  `json:"ends_at"`
+	Reason         string     `json:"reason"`
+	CreatedBy      *uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type CreateAvailabilityException struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason"`
+}
+
+func (m *CreateAvailabilityException) Validate() error {
+	var errs ValidationErrors
+
+	if m.StartsAt.IsZero() {
+		errs = append(errs, FieldError{Field: "starts_at", Message: "starts_at is required"})
+	}
+	if m.EndsAt.IsZero() {
+		errs = append(errs, FieldError{Field: "ends_at", Message: "ends_at is required"})
+	} else if !m.EndsAt.After(m.StartsAt) {
+		errs = append(errs, FieldError{Field: "ends_at", Message: "ends_at must be after starts_at"})
+	} else if !m.EndsAt.After(time.Now()) {
+		errs = append(errs, FieldError{Field: "ends_at", Message: "ends_at must be in the future"})
+	}
+
+	m.Reason = strings.TrimSpace(m.Reason)
+	if m.Reason == "" {
+		errs = append(errs, FieldError{Field: "reason", Message: "reason is required"})
+	} else if len(m.Reason) > 200 {
+		errs = append(errs, FieldError{Field: "reason", Message: "reason must be at most 200 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Appointment is a booking of a patient into a practitioner's slot.
+type Appointment struct {
+	ID               uuid.UUID  `json:"id"`
+	OrganisationID   uuid.UUID  `json:"organisation_id"`
+	PractitionerID   uuid.UUID  `json:"practitioner_id"`
+	PractitionerName string     `json:"practitioner_name"`
+	PatientID        uuid.UUID  `json:"patient_id"`
+	PatientName      string     `json:"patient_name"`
+	StartsAt         time.Time  `json:"starts_at"`
+	EndsAt           time.Time  `json:"ends_at"`
+	Status           string     `json:"status"`
+	Service          *string    `json:"service"`
+	Reason           *string    `json:"reason"`
+	CancelReason     *string    `json:"cancel_reason"`
+	CancelledAt      *time.Time `json:"cancelled_at"`
+	RescheduleReason *string    `json:"reschedule_reason"`
+	RescheduledAt    *time.Time `json:"rescheduled_at"`
+	CheckedInAt      *time.Time `json:"checked_in_at"`
+	CompletedAt      *time.Time `json:"completed_at"`
+	CreatedBy        *uuid.UUID `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Timezone is the practitioner's schedule time zone, in which calendars
+	// show the appointment.
+	Timezone string `json:"timezone"`
+
+	// The logins of the practitioner and of the patient, if it has one,
+	// which decide who else may see the appointment.
+	PractitionerUserID uuid.UUID  `json:"-"`
+	PatientUserID      *uuid.UUID `json:"-"`
+}
+
+// PaginatedAppointmentsResponse wraps an appointment list with pagination
+// metadata.
+type PaginatedAppointmentsResponse struct {
+	Items []Appointment  `json:"items"`
+	Meta  PaginationMeta `json:"meta"`
+}
+
+// CreateAppointment books a patient into an open slot starting at StartsAt.
+// The slot length comes from the practitioner's schedule. Service optionally
+// names the kind of visit, which waitlist entries can ask for.
+type CreateAppointment struct {
+	PractitionerID uuid.UUID `json:"practitioner_id"`
+	PatientID      uuid.UUID `json:"patient_id"`
+	StartsAt       time.Time `json:"starts_at"`
+	Service        string    `json:"service"`
+	Reason         string    `json:"reason"`
+}
+
+func (m *CreateAppointment) Validate() error {
+	var errs ValidationErrors
+
+	if m.PractitionerID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "practitioner_id", Message: "practitioner is required"})
+	}
+	if m.PatientID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "patient_id", Message: "patient is required"})
+	}
+	if m.StartsAt.IsZero() {
+		errs = append(errs, FieldError{Field: "starts_at", Message: "starts_at is required"})
+	}
+
+	m.Service = normaliseService(m.Service)
+	if len(m.Service) > 100 {
+		errs = append(errs, FieldError{Field: "service", Message: "service must be at most 100 characters"})
+	}
+
+	m.Reason = strings.TrimSpace(m.Reason)
+	if len(m.Reason) > 500 {
+		errs = append(errs, FieldError{Field: "reason", Message: "reason must be at most 500 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// RescheduleAppointment moves a booked appointment to another open slot of
+// the same practitioner.
+type RescheduleAppointment struct {
+	StartsAt time.Time `json:"starts_at"`
+	Reason   string    `json:"reason"`
+}
+
+func (m *RescheduleAppointment) Validate() error {
+	var errs ValidationErrors
+
+	if m.StartsAt.IsZero() {
+		errs = append(errs, FieldError{Field: "starts_at", Message: "starts_at is required"})
+	}
+
+	m.Reason = strings.TrimSpace(m.Reason)
+	if m.Reason == "" {
+		errs = append(errs, FieldError{Field: "reason", Message: "reason is required"})
+	} else if len(m.Reason) > 500 {
+		errs = append(errs, FieldError{Field: "reason", Message: "reason must be at most 500 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ChangeAppointmentStatus moves an appointment on. Cancelling requires a
+// reason.
+type ChangeAppointmentStatus struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+func (m *ChangeAppointmentStatus) Validate() error {
+	var errs ValidationErrors
+
+	m.Status = strings.ToLower(strings.TrimSpace(m.Status))
+	m.Reason = strings.TrimSpace(m.Reason)
+
+	switch m.Status {
+	case AppointmentCancelled:
+		if m.Reason == "" {
+			errs = append(errs, FieldError{Field: "reason", Message: "reason is required"})
+		}
+	case AppointmentCheckedIn, AppointmentCompleted, AppointmentNoShow:
+	case "":
+		errs = append(errs, FieldError{Field: "status", Message: "status is required"})
+	default:
+		errs = append(errs, FieldError{Field: "status", Message: "status must be checked_in, completed, no_show or cancelled"})
+	}
+
+	if len(m.Reason) > 500 {
+		errs = append(errs, FieldError{Field: "reason", Message: "reason must be at most 500 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// AppointmentFilter narrows an appointment list. From and To select
+// appointments that start in [From, To). ParticipantID is set by the service
+// to limit the list to the appointments of a practitioner's or patient's
+// login.
+type AppointmentFilter struct {
+	PractitionerID *uuid.UUID
+	PatientID      *uuid.UUID
+	Status         string
+	From           *time.Time
+	To             *time.Time
+	ParticipantID  *uuid.UUID
+}
+
+func (f *AppointmentFilter) Validate() error {
+	var errs ValidationErrors
+
+	f.Status = strings.ToLower(strings.TrimSpace(f.Status))
+	if f.Status != "" && !appointmentStatuses[f.Status] {
+		errs = append(errs, FieldError{Field: "status", Message: "unknown status"})
+	}
+
+	if f.From != nil && f.To != nil && !f.To.After(*f.From) {
+		errs = append(errs, FieldError{Field: "to", Message: "to must be after from"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{AppointmentBooked, AppointmentCheckedIn, true},
+		{AppointmentBooked, AppointmentNoShow, true},
+		{AppointmentBooked, AppointmentCancelled, true},
+		{AppointmentBooked, AppointmentCompleted, false},
+		{AppointmentCheckedIn, AppointmentCompleted, true},
+		{AppointmentCheckedIn, AppointmentCancelled, false},
+		{AppointmentCompleted, AppointmentBooked, false},
+		{AppointmentCancelled, AppointmentBooked, false},
+		{AppointmentNoShow, AppointmentCheckedIn, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestSetSchedule_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        SetSchedule
+		expectField string
+	}{
+		{
+			name: "valid",
+			data: SetSchedule{Timezone: "Europe/London", SlotMinutes: 15, Windows: []ScheduleWindow{
+				{Weekday: 1, Start: "13:00", End: "17:00"},
+				{Weekday: 1, Start: "09:00", End: "12:00"},
+			}},
+		},
+		{
+			name:        "unknown timezone",
+			data:        SetSchedule{Timezone: "Mars/Olympus", SlotMinutes: 15},
+			expectField: "timezone",
+		},
+		{
+			name:        "slot too short",
+			data:        SetSchedule{Timezone: "UTC", SlotMinutes: 1},
+			expectField: "slot_minutes",
+		},
+		{
+			name:        "bad weekday",
+			data:        SetSchedule{Timezone: "UTC", SlotMinutes: 15, Windows: []ScheduleWindow{{Weekday: 7, Start: "09:00", End: "10:00"}}},
+			expectField: "windows[0].weekday",
+		},
+		{
+			name:        "end before start",
+			data:        SetSchedule{Timezone: "UTC", SlotMinutes: 15, Windows: []ScheduleWindow{{Weekday: 1, Start: "10:00", End: "09:00"}}},
+			expectField: "windows[0].end",
+		},
+		{
+			name: "overlapping windows",
+			data: SetSchedule{Timezone: "UTC", SlotMinutes: 15, Windows: []ScheduleWindow{
+				{Weekday: 2, Start: "09:00", End: "12:00"},
+				{Weekday: 2, Start: "11:00", End: "13:00"},
+			}},
+			expectField: "windows",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.data.Validate()
+			if tt.expectField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tt.data.Windows[0].Start != "09:00" {
+					t.Errorf("windows not sorted: %+v", tt.data.Windows)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			for _, fe := range verrs {
+				if fe.Field == tt.expectField {
+					return
+				}
+			}
+			t.Errorf("expected an error on %s, got %v", tt.expectField, verrs)
+		})
+	}
+}
+
+func TestSchedule_Slots(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+
+	// Mondays 09:00-10:10 in 30 minute slots: the last 10 minutes do not fit
+	// a slot.
+	s := Schedule{Timezone: "Europe/London", SlotMinutes: 30, Windows: []ScheduleWindow{{Weekday: 1, Start: "09:00", End: "10:10"}}}
+	at := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, london)
+	}
+
+	t.Run("slots in local time across daylight saving", func(t *testing.T) {
+		// Clocks go back on 25 October 2026, between the two Mondays.
+		from := at(2026, time.October, 19, 0, 0)
+		to := at(2026, time.October, 27, 0, 0)
+
+		slots, err := s.Slots(from, to, time.Time{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []time.Time{
+			at(2026, time.October, 19, 9, 0), at(2026, time.October, 19, 9, 30),
+			at(2026, time.October, 26, 9, 0), at(2026, time.October, 26, 9, 30),
+		}
+		if len(slots) != len(want) {
+			t.Fatalf("got %d slots, want %d: %+v", len(slots), len(want), slots)
+		}
+		for i, slot := range slots {
+			if !slot.StartsAt.Equal(want[i]) || slot.EndsAt.Sub(slot.StartsAt) != 30*time.Minute {
+				t.Errorf("slot %d = %v-%v, want start %v", i, slot.StartsAt, slot.EndsAt, want[i])
+			}
+		}
+		if slots[0].StartsAt.UTC().Hour() != 8 || slots[2].StartsAt.UTC().Hour() != 9 {
+			t.Errorf("slots do not keep local time: %v, %v", slots[0].StartsAt.UTC(), slots[2].StartsAt.UTC())
+		}
+	})
+
+	t.Run("busy and past slots are left out", func(t *testing.T) {
+		from := at(2026, time.October, 19, 0, 0)
+		to := at(2026, time.October, 20, 0, 0)
+		busy := []TimeRange{{Start: at(2026, time.October, 19, 9, 20), End: at(2026, time.October, 19, 9, 40)}}
+
+		slots, err := s.Slots(from, to, time.Time{}, busy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(slots) != 0 {
+			t.Errorf("expected every slot to overlap the busy time, got %+v", slots)
+		}
+
+		slots, err = s.Slots(from, to, at(2026, time.October, 19, 9, 10), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(slots) != 1 || !slots[0].StartsAt.Equal(at(2026, time.October, 19, 9, 30)) {
+			t.Errorf("expected only the 09:30 slot after 09:10, got %+v", slots)
+		}
+	})
+
+	t.Run("a single start", func(t *testing.T) {
+		start := at(2026, time.October, 19, 9, 30)
+		slots, err := s.Slots(start, start.Add(time.Nanosecond), time.Time{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(slots) != 1 || !slots[0].StartsAt.Equal(start) {
+			t.Errorf("expected the 09:30 slot, got %+v", slots)
+		}
+
+		off := at(2026, time.October, 19, 9, 15)
+		if slots, _ := s.Slots(off, off.Add(time.Nanosecond), time.Time{}, nil); len(slots) != 0 {
+			t.Errorf("expected no slot at 09:15, got %+v", slots)
+		}
+	})
+}
+
+func TestSlotRange_Validate(t *testing.T) {
+	r := SlotRange{From: "2026-11-02"}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.To != "2026-11-08" {
+		t.Errorf("to = %q, want 2026-11-08", r.To)
+	}
+
+	for _, r := range []SlotRange{
+		{From: "2026-11-02", To: "2026-11-01"},
+		{From: "2026-11-02", To: "2026-12-03"},
+		{From: "02/11/2026"},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", r)
+		}
+	}
+}
+
+func TestChangeAppointmentStatus_Validate(t *testing.T) {
+	tests := []struct {
+		data    ChangeAppointmentStatus
+		wantErr bool
+	}{
+		{ChangeAppointmentStatus{Status: "Checked_In"}, false},
+		{ChangeAppointmentStatus{Status: "cancelled", Reason: "patient unwell"}, false},
+		{ChangeAppointmentStatus{Status: "cancelled"}, true},
+		{ChangeAppointmentStatus{Status: "booked"}, true},
+		{ChangeAppointmentStatus{}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.data.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, want error %v", tt.data, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions.
+const (
+	AuditUserRestore = "user.restore"
+	AuditUserPurge   = "user.purge"
+	AuditUserErase   = "user.erase"
+	AuditUserStatus  = "user.status_changed"
+	AuditUserImport  = "user.import"
+
+	AuditEmailChangeRequested = "user.email_change_requested"
+	AuditEmailChanged         = "user.email_changed"
+	AuditEmailReverted        = "user.email_reverted"
+
+	AuditInvitationCreated  = "invitation.created"
+	AuditInvitationResent   = "invitation.resent"
+	AuditInvitationRevoked  = "invitation.revoked"
+	AuditInvitationAccepted = "invitation.accepted"
+
+	AuditOrganisationCreated = "organisation.created"
+	AuditMemberSet           = "organisation.member_set"
+	AuditMemberRemoved       = "organisation.member_removed"
+
+	AuditPatientCreated           = "patient.created"
+	AuditPatientUpdated           = "patient.updated"
+	AuditPatientDeleted           = "patient.deleted"
+	AuditPatientIdentifierAdded   = "patient.identifier_added"
+	AuditPatientIdentifierRemoved = "patient.identifier_removed"
+	AuditPatientUserLinked        = "patient.user_linked"
+	AuditPatientUserUnlinked      = "patient.user_unlinked"
+
+	AuditFacilityCreated      = "facility.created"
+	AuditPractitionerCreated  = "practitioner.created"
+	AuditPractitionerUpdated  = "practitioner.updated"
+	AuditPractitionerDeleted  = "practitioner.deleted"
+	AuditLicenceAdded         = "practitioner.licence_added"
+	AuditLicenceRemoved       = "practitioner.licence_removed"
+	AuditLicenceExpiryFlagged = "practitioner.licence_expiry_flagged"
+
+	AuditScheduleSet                  = "practitioner.schedule_set"
+	AuditAvailabilityExceptionAdded   = "practitioner.exception_added"
+	AuditAvailabilityExceptionRemoved = "practitioner.exception_removed"
+	AuditAppointmentBooked            = "appointment.booked"
+	AuditAppointmentRescheduled       = "appointment.rescheduled"
+	AuditAppointmentStatusChanged     = "appointment.status_changed"
+
+	AuditCalendarFeedCreated = "calendar.feed_created"
+	AuditCalendarFeedRevoked = "calendar.feed_revoked"
+
+	AuditWaitlistJoined        = "waitlist.joined"
+	AuditWaitlistLeft          = "waitlist.left"
+	AuditWaitlistOffered       = "waitlist.offered"
+	AuditWaitlistOfferAccepted = "waitlist.offer_accepted"
+	AuditWaitlistOfferDeclined = "waitlist.offer_declined"
+	AuditWaitlistOfferLapsed   = "waitlist.offer_lapsed"
+
+	AuditMedicationCatalogueLoaded = "medication.catalogue_loaded"
+	AuditPrescriptionCreated       = "prescription.created"
+	AuditPrescriptionStatusChanged = "prescription.status_changed"
+	AuditRefillRequested           = "prescription.refill_requested"
+	AuditRefillApproved            = "prescription.refill_approved"
+	AuditRefillDenied              = "prescription.refill_denied"
+
+	AuditAllergyRecorded        = "allergy.recorded"
+	AuditAllergyUpdated         = "allergy.updated"
+	AuditInteractionsLoaded     = "medication.interactions_loaded"
+	AuditPrescriptionOverridden = "prescription.conflict_overridden"
+
+	AuditLabMessageAccepted         = "lab.message_accepted"
+	AuditLabMessageRejected         = "lab.message_rejected"
+	AuditLabReconciliationResolved  = "lab.reconciliation_resolved"
+	AuditLabReconciliationDiscarded = "lab.reconciliation_discarded"
+	AuditLabCriticalResult          = "lab.critical_result"
+	AuditLabCriticalAcknowledged    = "lab.critical_acknowledged"
+)
+
+// AuditEntry records an action taken on a subject. ActorID is nil for
+// actions performed by the system, such as scheduled jobs.
+type AuditEntry struct {
+	ID          uuid.UUID      `json:"id"`
+	ActorID     *uuid.UUID     `json:"actor_id"`
+	Action      string         `json:"action"`
+	SubjectType string         `json:"subject_type"`
+	SubjectID   *uuid.UUID     `json:"subject_id"`
+	Details     map[string]any `json:"details,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
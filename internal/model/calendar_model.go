@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalendarFeed is a secret link through which a user's calendar app reads
+// their appointments as iCalendar.
+type CalendarFeed struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	TokenHash  string     `json:"-"`
+
+	// OwnerStatus is the effective account status of the user, filled in
+	// when the feed is looked up by token.
+	OwnerStatus string `json:"-"`
+}
+
+// CalendarFeedCreated is returned when a feed is created. URL carries the
+// only copy of the feed token.
+type CalendarFeedCreated struct {
+	Feed CalendarFeed `json:"feed"`
+	URL  string       `json:"url"`
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChangeEmail struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+func (m *ChangeEmail) Validate() error {
+	var errs ValidationErrors
+
+	m.NewEmail = strings.ToLower(strings.TrimSpace(m.NewEmail))
+
+	if m.NewEmail == "" {
+		errs = append(errs, FieldError{
+			Field:   "new_email",
+			Message: "new email is required",
+		})
+	} else {
+		addr, err := mail.ParseAddress(m.NewEmail)
+		if err != nil || addr.Address != m.NewEmail {
+			errs = append(errs, FieldError{
+				Field:   "new_email",
+				Message: "invalid email",
+			})
+		}
+	}
+
+	if m.Password == "" {
+		errs = append(errs, FieldError{
+			Field:   "password",
+			Message: "password is required",
+		})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// EmailToken carries a single-use token from an emailed link.
+type EmailToken struct {
+	Token string `json:"token"`
+}
+
+func (m *EmailToken) Validate() error {
+	m.Token = strings.TrimSpace(m.Token)
+	if m.Token == "" {
+		return ValidationErrors{FieldError{Field: "token", Message: "token is required"}}
+	}
+	return nil
+}
+
+// EmailChangeRequest tracks an email change from request through
+// confirmation to the end of the window in which the old address can revert
+// it.
+type EmailChangeRequest struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	OldEmail        string
+	NewEmail        string
+	TokenHash       string
+	ExpiresAt       time.Time
+	ConfirmedAt     *time.Time
+	RevertTokenHash string
+	RevertExpiresAt *time.Time
+	RevertedAt      *time.Time
+}
+
+// EmailCredentials is the current email and password hash of a user.
+type EmailCredentials struct {
+	Email    string
+	Password string
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErasedUserFields lists the user columns overwritten by an erasure.
+var ErasedUserFields = []string{
+	"first_name", "last_name", "email", "password",
+	"phone", "date_of_birth", "sex", "gender", "preferred_language", "address", "avatar",
+}
+
+// ErasedPatientFields lists the columns of a linked patient record that an
+// erasure overwrites. The date of birth is cut to the year, so age-based
+// reference ranges still apply to the patient's lab results.
+var ErasedPatientFields = []string{
+	"patient.first_name", "patient.last_name", "patient.date_of_birth", "patient.gender", "patient.phone",
+	"patient.email", "patient.preferred_language", "patient.address", "patient.identifiers",
+}
+
+// ErasureCertificate is the signed record that a user's personal data was
+// erased. The pseudonym is derived from the user id with a keyed hash, so it
+// is stable across retries but cannot be reversed to the original data.
+type ErasureCertificate struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Pseudonym    string    `json:"pseudonym"`
+	ErasedFields []string  `json:"erased_fields"`
+	RequestedBy  uuid.UUID `json:"requested_by"`
+	ErasedAt     time.Time `json:"erased_at"`
+	Signature    string    `json:"signature"`
+}
+
+// SigningPayload returns the canonical text covered by the signature.
+func (c *ErasureCertificate) SigningPayload() string {
+	return strings.Join([]string{
+		"erasure-certificate/v1",
+		c.ID.String(),
+		c.UserID.String(),
+		c.Pseudonym,
+		strings.Join(c.ErasedFields, ","),
+		c.RequestedBy.String(),
+		c.ErasedAt.UTC().Format(time.RFC3339Nano),
+	}, "|")
+}
+
+// ErasureResult is returned by the erasure endpoints. Valid reports whether
+// the stored signature still matches the certificate.
+type ErasureResult struct {
+	Certificate ErasureCertificate `json:"certificate"`
+	Valid       bool               `json:"valid"`
+}
@@ -3,15 +3,20 @@ package model
 import "errors"
 
 var (
-	ErrAlreadyExists    = errors.New("already exists")        // 409
-	ErrNotFound         = errors.New("not found")             // 404
-	ErrAlreadyDeleted   = errors.New("already deleted")       // 410
-	ErrUnauthorized     = errors.New("unauthorized")          // 401
-	ErrForbidden        = errors.New("forbidden")             // 403
-	ErrBadRequest       = errors.New("bad request")           // 400
-	ErrInternal         = errors.New("internal server error") // 500
-	ErrConflict         = errors.New("conflict")              // 409
-	ErrValidationFailed = errors.New("validation failed")     // 422
+	ErrAlreadyExists        = errors.New("already exists")         // 409
+	ErrNotFound             = errors.New("not found")              // 404
+	ErrAlreadyDeleted       = errors.New("already deleted")        // 410
+	ErrUnauthorized         = errors.New("unauthorized")           // 401
+	ErrForbidden            = errors.New("forbidden")              // 403
+	ErrBadRequest           = errors.New("bad request")            // 400
+	ErrInternal             = errors.New("internal server error")  // 500
+	ErrConflict             = errors.New("conflict")               // 409
+	ErrValidationFailed     = errors.New("validation failed")      // 422
+	ErrTooManyRequests      = errors.New("too many requests")      // 429
+	ErrPayloadTooLarge      = errors.New("payload too large")      // 413
+	ErrUnsupportedMedia     = errors.New("unsupported media type") // 415
+	ErrPreconditionFailed   = errors.New("precondition failed")    // 412
+	ErrPreconditionRequired = errors.New("precondition required")  // 428
 )
 
 type FieldError struct {
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Data export statuses.
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// DataExport is a request for a copy of everything held about a user.
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Status      string     `json:"status"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	TokenHash   string     `json:"-"`
+	FilePath    string     `json:"-"`
+}
+
+// DataExportRequested is returned when an export is requested. DownloadURL
+// carries the only copy of the download token and starts working once the
+// export is ready.
+type DataExportRequested struct {
+	Export      DataExport `json:"export"`
+	DownloadURL string     `json:"download_url"`
+}
+
+// PersonalData is everything gathered into a data export.
+type PersonalData struct {
+	Profile     ExportProfile   `json:"profile"`
+	Sessions    []ExportSession `json:"sessions"`
+	AuditLog    []AuditEntry    `json:"audit_log"`
+	Patients    []ExportPatient `json:"patients"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
+
+// ExportPatient is a patient record linked to the user, with the clinical
+// records filed against it.
+type ExportPatient struct {
+	Patient       Patient          `json:"patient"`
+	Appointments  []Appointment    `json:"appointments"`
+	Prescriptions []Prescription   `json:"prescriptions"`
+	Allergies     []Allergy        `json:"allergies"`
+	LabResults    []LabObservation `json:"lab_results"`
+}
+
+type ExportProfile struct {
+	ID                uuid.UUID `json:"id"`
+	FirstName         string    `json:"first_name"`
+	LastName          string    `json:"last_name"`
+	Email             string    `json:"email"`
+	Phone             string    `json:"phone,omitempty"`
+	DateOfBirth       string    `json:"date_of_birth,omitempty"`
+	Sex               string    `json:"sex,omitempty"`
+	Gender            string    `json:"gender,omitempty"`
+	PreferredLanguage string    `json:"preferred_language,omitempty"`
+	Address           Address   `json:"address"`
+	Role              string    `json:"role"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ExportSession describes a refresh token without the token hash.
+type ExportSession struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Revoked   bool      `json:"revoked"`
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Outcomes of an imported row. In a dry run they describe what a real run
+// would do.
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped" // the address belongs to a deleted account
+	ImportInvalid = "invalid"
+)
+
+// importColumns are the CSV columns of a user import, all required.
+var importColumns = []string{"first_name", "last_name", "email", "password"}
+
+// ImportRow is a data row of an import file. Line is the line number in the
+// file, counting the header as line 1.
+type ImportRow struct {
+	Line int
+	User CreateUser
+}
+
+// ParseImportCSV reads a user import file. The header must name the columns
+// first_name, last_name, email and password in any order. Rows are not
+// validated here.
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ValidationErrors{FieldError{Field: "file", Message: "file is empty"}}
+	}
+	if err != nil {
+		return nil, ValidationErrors{FieldError{Field: "file", Message: "invalid CSV: " + err.Error()}}
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, dup := index[name]; dup {
+			return nil, ValidationErrors{FieldError{Field: "header", Message: "duplicate column " + name}}
+		}
+		index[name] = i
+	}
+
+	var errs ValidationErrors
+	for _, col := range importColumns {
+		if _, ok := index[col]; !ok {
+			errs = append(errs, FieldError{Field: "header", Message: "missing column " + col})
+		}
+	}
+	if len(index) > len(importColumns) {
+		errs = append(errs, FieldError{Field: "header", Message: "unexpected columns; expected " + strings.Join(importColumns, ", ")})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, ValidationErrors{FieldError{Field: "file", Message: "invalid CSV: " + err.Error()}}
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, ImportRow{
+			Line: line,
+			User: CreateUser{
+				FirstName: record[index["first_name"]],
+				LastName:  record[index["last_name"]],
+				Email:     record[index["email"]],
+				Password:  record[index["password"]],
+			},
+		})
+	}
+
+	return rows, nil
+}
+
+// ValidateImportRow checks a row with the registration rules plus the
+// column lengths of the users table, so that one long value cannot fail a
+// whole batch.
+func ValidateImportRow(u *CreateUser) ValidationErrors {
+	var errs ValidationErrors
+	if err := u.Validate(); err != nil {
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) {
+			return ValidationErrors{FieldError{Field: "row", Message: err.Error()}}
+		}
+		errs = append(errs, verrs...)
+	}
+
+	for _, f := range []struct {
+		field, value string
+		max          int
+	}{
+		{"first_name", u.FirstName, 50},
+		{"last_name", u.LastName, 50},
+		{"email", u.Email, 150},
+	} {
+		if len(f.value) > f.max {
+			errs = append(errs, FieldError{Field: f.field, Message: fmt.Sprintf("%s must be at most %d characters", f.field, f.max)})
+		}
+	}
+
+	return errs
+}
+
+// ImportUser is a validated row ready to be written. PasswordHash is empty
+// for addresses that already have an account, whose password is kept.
+type ImportUser struct {
+	ID           uuid.UUID
+	FirstName    string
+	LastName     string
+	Email        string
+	PasswordHash string
+}
+
+// ImportRowResult reports what happened to one row.
+type ImportRowResult struct {
+	Line    int              `json:"line"`
+	Email   string           `json:"email"`
+	Outcome string           `json:"outcome"`
+	Errors  ValidationErrors `json:"errors,omitempty"`
+}
+
+// ImportReport summarises an import. Rows lists every row in file order.
+type ImportReport struct {
+	ID      uuid.UUID         `json:"id"`
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Invalid int               `json:"invalid"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// Count adds outcome to the totals.
+func (r *ImportReport) Count(outcome string) {
+	switch outcome {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportSkipped:
+		r.Skipped++
+	case ImportInvalid:
+		r.Invalid++
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		rows      int
+		firstLine int
+		errField  string
+	}{
+		{
+			name:      "columns in any order",
+			input:     "Email,password,first_name,last_name\njane@example.com,Password1!,Jane,Doe\njohn@example.com,Password1!,John,Doe\n",
+			rows:      2,
+			firstLine: 2,
+		},
+		{
+			name:      "byte order mark",
+			input:     "\ufefffirst_name,last_name,email,password\nJane,Doe,jane@example.com,Password1!\n",
+			rows:      1,
+			firstLine: 2,
+		},
+		{
+			name:      "quoted newline keeps line numbers",
+			input:     "first_name,last_name,email,password\n\"Ja\nne\",Doe,a@example.com,x\nJohn,Doe,b@example.com,y\n",
+			rows:      2,
+			firstLine: 2,
+		},
+		{name: "header only", input: "first_name,last_name,email,password\n"},
+		{name: "empty", input: "", errField: "file"},
+		{name: "missing column", input: "first_name,last_name,email\n", errField: "header"},
+		{name: "unexpected column", input: "first_name,last_name,email,password,role\n", errField: "header"},
+		{name: "duplicate column", input: "email,email,first_name,last_name\n", errField: "header"},
+		{name: "ragged row", input: "first_name,last_name,email,password\nJane,Doe\n", errField: "file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseImportCSV(strings.NewReader(tt.input))
+
+			if tt.errField != "" {
+				var verrs ValidationErrors
+				if !errors.As(err, &verrs) || verrs[0].Field != tt.errField {
+					t.Fatalf("expected %s error, got %v", tt.errField, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(rows) != tt.rows {
+				t.Fatalf("expected %d rows, got %d", tt.rows, len(rows))
+			}
+			if tt.rows > 0 && rows[0].Line != tt.firstLine {
+				t.Errorf("expected first row on line %d, got %d", tt.firstLine, rows[0].Line)
+			}
+		})
+	}
+}
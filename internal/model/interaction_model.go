@@ -0,0 +1,263 @@
+package model
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Conflict severities, from the interaction dataset or from the severity
+// of an allergy. High-severity conflicts need an override reason.
+const (
+	SeverityLow      = "low"
+	SeverityModerate = "moderate"
+	SeverityHigh     = "high"
+)
+
+var severityRanks = map[string]int{
+	SeverityLow:      1,
+	SeverityModerate: 2,
+	SeverityHigh:     3,
+}
+
+// Conflict types.
+const (
+	ConflictDrugDrug    = "drug_drug"
+	ConflictDrugAllergy = "drug_allergy"
+)
+
+// Interaction is an entry of the interaction dataset: two substances, such
+// as ingredient or drug class names, that should not be taken together or
+// by a patient allergic to the other.
+type Interaction struct {
+	SubstanceA  string `json:"substance_a"`
+	SubstanceB  string `json:"substance_b"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// Normalize lower-cases the substances and puts them in name order, as
+// they are stored, and checks the entry.
+func (i *Interaction) Normalize() error {
+	i.SubstanceA = strings.ToLower(strings.TrimSpace(i.SubstanceA))
+	i.SubstanceB = strings.ToLower(strings.TrimSpace(i.SubstanceB))
+	i.Severity = strings.ToLower(strings.TrimSpace(i.Severity))
+	i.Description = strings.TrimSpace(i.Description)
+
+	switch {
+	case i.SubstanceA == "" || i.SubstanceB == "":
+		return errors.New("both substances are required")
+	case len(i.SubstanceA) > 100 || len(i.SubstanceB) > 100:
+		return errors.New("substances must be at most 100 characters")
+	case i.SubstanceA == i.SubstanceB:
+		return errors.New("substances must differ")
+	case severityRanks[i.Severity] == 0:
+		return fmt.Errorf("severity must be low, moderate or high, not %q", i.Severity)
+	case i.Description == "":
+		return errors.New("description is required")
+	case len(i.Description) > 500:
+		return errors.New("description must be at most 500 characters")
+	}
+
+	if i.SubstanceB < i.SubstanceA {
+		i.SubstanceA, i.SubstanceB = i.SubstanceB, i.SubstanceA
+	}
+	return nil
+}
+
+// interactionColumns are the CSV columns of an interaction dataset, all
+// required.
+var interactionColumns = []string{"substance_a", "substance_b", "severity", "description"}
+
+// InteractionRow is a data row of an interaction dataset. Line is the line
+// number in the file, counting the header as line 1.
+type InteractionRow struct {
+	Line        int
+	Interaction Interaction
+}
+
+// ParseInteractionCSV reads an interaction dataset. The header must name the
+// columns substance_a, substance_b, severity and description in any order.
+// Rows are not checked here.
+func ParseInteractionCSV(r io.Reader) ([]InteractionRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ValidationErrors{FieldError{Field: "file", Message: "file is empty"}}
+	}
+	if err != nil {
+		return nil, ValidationErrors{FieldError{Field: "file", Message: "invalid CSV: " + err.Error()}}
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, dup := index[name]; dup {
+			return nil, ValidationErrors{FieldError{Field: "header", Message: "duplicate column " + name}}
+		}
+		index[name] = i
+	}
+
+	var errs ValidationErrors
+	for _, col := range interactionColumns {
+		if _, ok := index[col]; !ok {
+			errs = append(errs, FieldError{Field: "header", Message: "missing column " + col})
+		}
+	}
+	if len(index) > len(interactionColumns) {
+		errs = append(errs, FieldError{Field: "header", Message: "unexpected columns; expected " + strings.Join(interactionColumns, ", ")})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var rows []InteractionRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, ValidationErrors{FieldError{Field: "file", Message: "invalid CSV: " + err.Error()}}
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, InteractionRow{
+			Line: line,
+			Interaction: Interaction{
+				SubstanceA:  record[index["substance_a"]],
+				SubstanceB:  record[index["substance_b"]],
+				Severity:    record[index["severity"]],
+				Description: record[index["description"]],
+			},
+		})
+	}
+
+	return rows, nil
+}
+
+// InteractionReport summarises a dataset load. Read counts the data rows of
+// the file; Loaded the entries written, new or changed; Unchanged those
+// already in the dataset as they are.
+type InteractionReport struct {
+	ID        uuid.UUID `json:"id"`
+	Read      int       `json:"read"`
+	Loaded    int       `json:"loaded"`
+	Unchanged int       `json:"unchanged"`
+}
+
+// words splits text into lower-case words of letters and digits.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Mentions reports whether text names a substance: whether the words of the
+// substance appear in text, in order and whole. "amoxicillin 500 MG Oral
+// Capsule" mentions amoxicillin but "amoxicillin" does not mention
+// cillin.
+func Mentions(text, substance string) bool {
+	want := words(substance)
+	if len(want) == 0 {
+		return false
+	}
+	have := words(text)
+
+outer:
+	for i := 0; i+len(want) <= len(have); i++ {
+		for j, w := range want {
+			if have[i+j] != w {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// DrugTerm is a drug or allergen as conflict checks match it. One coded with
+// an RxCUI is the same as any other with that RxCUI, and otherwise names
+// what its catalogue name names: in RxNorm's normalised form that is each
+// ingredient, whatever brand it was entered as. One without an RxCUI names
+// only what its own name names.
+type DrugTerm struct {
+	RxCUI string
+	Name  string
+}
+
+// Same reports whether t and other are the same catalogue concept.
+func (t DrugTerm) Same(other DrugTerm) bool {
+	return t.RxCUI != "" && t.RxCUI == other.RxCUI
+}
+
+// Mentions reports whether t names substance, as Mentions does for text.
+func (t DrugTerm) Mentions(substance string) bool {
+	return Mentions(t.Name, substance)
+}
+
+// Conflict is a problem with prescribing a drug to a patient: an
+// interaction with another of their current prescriptions, or their
+// allergy to the drug or to a substance it cross-reacts with. With names
+// the other drug or the allergen.
+type Conflict struct {
+	Type           string     `json:"type"`
+	Severity       string     `json:"severity"`
+	With           string     `json:"with"`
+	PrescriptionID *uuid.UUID `json:"prescription_id,omitempty"`
+	AllergyID      *uuid.UUID `json:"allergy_id,omitempty"`
+	Description    string     `json:"description"`
+}
+
+// SortConflicts orders conflicts from the most severe.
+func SortConflicts(conflicts []Conflict) {
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return severityRanks[conflicts[i].Severity] > severityRanks[conflicts[j].Severity]
+	})
+}
+
+// MoreSevere reports whether severity a is higher than b.
+func MoreSevere(a, b string) bool {
+	return severityRanks[a] > severityRanks[b]
+}
+
+// CheckPrescription asks which conflicts prescribing a drug to a patient
+// would raise, without prescribing it.
+type CheckPrescription struct {
+	PatientID uuid.UUID `json:"patient_id"`
+	RxCUI     string    `json:"rxcui"`
+	Drug      string    `json:"drug"`
+}
+
+func (m *CheckPrescription) Validate() error {
+	var errs ValidationErrors
+
+	if m.PatientID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "patient_id", Message: "patient is required"})
+	}
+
+	m.RxCUI = strings.TrimSpace(m.RxCUI)
+	if len(m.RxCUI) > 20 {
+		errs = append(errs, FieldError{Field: "rxcui", Message: "rxcui must be at most 20 characters"})
+	}
+
+	m.Drug = strings.TrimSpace(m.Drug)
+	if m.Drug == "" && m.RxCUI == "" {
+		errs = append(errs, FieldError{Field: "drug", Message: "drug or rxcui is required"})
+	} else if len(m.Drug) > 200 {
+		errs = append(errs, FieldError{Field: "drug", Message: "drug must be at most 200 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package model
+
+import "testing"
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		text, substance string
+		want            bool
+	}{
+		{"amoxicillin 500 MG Oral Capsule", "amoxicillin", true},
+		{"Amoxicillin 500 MG Oral Capsule", "AMOXICILLIN", true},
+		{"amoxicillin / clavulanate Oral Suspension", "clavulanate", true},
+		{"acetylsalicylic acid 75 MG", "acetylsalicylic acid", true},
+		{"acetylsalicylic 75 MG acid", "acetylsalicylic acid", false},
+		{"amoxicillin", "cillin", false},
+		{"penicillin G", "penicillin", true},
+		{"penicillin", "penicillin G", false},
+		{"warfarin", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := Mentions(tt.text, tt.substance); got != tt.want {
+			t.Errorf("Mentions(%q, %q) = %v, want %v", tt.text, tt.substance, got, tt.want)
+		}
+	}
+}
+
+func TestInteraction_Normalize(t *testing.T) {
+	in := Interaction{SubstanceA: " Warfarin ", SubstanceB: "Aspirin", Severity: "HIGH", Description: " bleeding "}
+	if err := in.Normalize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Interaction{SubstanceA: "aspirin", SubstanceB: "warfarin", Severity: "high", Description: "bleeding"}
+	if in != want {
+		t.Errorf("Normalize() = %+v, want %+v", in, want)
+	}
+
+	for _, bad := range []Interaction{
+		{SubstanceA: "warfarin", Severity: "high", Description: "bleeding"},
+		{SubstanceA: "warfarin", SubstanceB: "Warfarin", Severity: "high", Description: "bleeding"},
+		{SubstanceA: "warfarin", SubstanceB: "aspirin", Severity: "severe", Description: "bleeding"},
+		{SubstanceA: "warfarin", SubstanceB: "aspirin", Severity: "high"},
+	} {
+		if err := bad.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) = nil, want an error", bad)
+		}
+	}
+}
+
+func TestSortConflicts(t *testing.T) {
+	conflicts := []Conflict{
+		{With: "a", Severity: SeverityLow},
+		{With: "b", Severity: SeverityHigh},
+		{With: "c", Severity: SeverityModerate},
+		{With: "d", Severity: SeverityHigh},
+	}
+	SortConflicts(conflicts)
+
+	var got string
+	for _, c := range conflicts {
+		got += c.With
+	}
+	if got != "bdca" {
+		t.Errorf("order = %s, want bdca", got)
+	}
+}
+
+func TestDrugTerm_Same(t *testing.T) {
+	tests := []struct {
+		a, b DrugTerm
+		want bool
+	}{
+		{DrugTerm{RxCUI: "308191", Name: "Amoxil"}, DrugTerm{RxCUI: "308191", Name: "amoxicillin"}, true},
+		{DrugTerm{RxCUI: "308191"}, DrugTerm{RxCUI: "617296"}, false},
+		{DrugTerm{Name: "amoxicillin"}, DrugTerm{Name: "amoxicillin"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Same(tt.b); got != tt.want {
+			t.Errorf("%+v.Same(%+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
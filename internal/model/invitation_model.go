@@ -0,0 +1,159 @@
+package model
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invitation statuses. Expired is derived from expires_at rather than stored.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+var invitationStatuses = map[string]bool{
+	InvitationPending:  true,
+	InvitationAccepted: true,
+	InvitationRevoked:  true,
+	InvitationExpired:  true,
+}
+
+// Organisation is a clinic or practice that staff accounts belong to.
+type Organisation struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateOrganisation struct {
+	Name string `json:"name"`
+}
+
+func (m *CreateOrganisation) Validate() error {
+	m.Name = strings.TrimSpace(m.Name)
+
+	if m.Name == "" {
+		return ValidationErrors{FieldError{Field: "name", Message: "name is required"}}
+	}
+	if len(m.Name) > 150 {
+		return ValidationErrors{FieldError{Field: "name", Message: "name must be at most 150 characters"}}
+	}
+	return nil
+}
+
+// Invitation asks someone to create an account with a role and organisation
+// chosen by an admin. The account is only created when it is accepted.
+type Invitation struct {
+	ID             uuid.UUID  `json:"id"`
+	Email          string     `json:"email"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	Role           string     `json:"role"`
+	OrganisationID uuid.UUID  `json:"organisation_id"`
+	Status         string     `json:"status"`
+	InvitedBy      *uuid.UUID `json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	SentAt         time.Time  `json:"sent_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	AcceptedUserID *uuid.UUID `json:"accepted_user_id"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	TokenHash      string     `json:"-"`
+}
+
+type CreateInvitation struct {
+	Email          string    `json:"email"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Role           string    `json:"role"`
+	OrganisationID uuid.UUID `json:"organisation_id"`
+}
+
+func (m *CreateInvitation) Validate() error {
+	var errs ValidationErrors
+
+	m.Email = strings.ToLower(strings.TrimSpace(m.Email))
+	m.FirstName = strings.TrimSpace(m.FirstName)
+	m.LastName = strings.TrimSpace(m.LastName)
+	m.Role = strings.ToLower(strings.TrimSpace(m.Role))
+
+	if m.Email == "" {
+		errs = append(errs, FieldError{Field: "email", Message: "email is required"})
+	} else if addr, err := mail.ParseAddress(m.Email); err != nil || addr.Address != m.Email {
+		errs = append(errs, FieldError{Field: "email", Message: "invalid email"})
+	}
+
+	if m.FirstName == "" {
+		errs = append(errs, FieldError{Field: "first_name", Message: "first name is required"})
+	} else if !isValidName(m.FirstName) {
+		errs = append(errs, FieldError{Field: "first_name", Message: "invalid first name"})
+	}
+
+	if m.LastName == "" {
+		errs = append(errs, FieldError{Field: "last_name", Message: "last name is required"})
+	} else if !isValidName(m.LastName) {
+		errs = append(errs, FieldError{Field: "last_name", Message: "invalid last name"})
+	}
+
+	if m.Role == "" {
+		m.Role = "user"
+	} else if !userRoles[m.Role] {
+		errs = append(errs, FieldError{Field: "role", Message: "invalid role"})
+	}
+
+	if m.OrganisationID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "organisation_id", Message: "organisation is required"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// AcceptInvitation sets the password of the account created from an
+// invitation.
+type AcceptInvitation struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (m *AcceptInvitation) Validate() error {
+	var errs ValidationErrors
+
+	m.Token = strings.TrimSpace(m.Token)
+	if m.Token == "" {
+		errs = append(errs, FieldError{Field: "token", Message: "token is required"})
+	}
+
+	errs = append(errs, validateNewPassword(m.Password)...)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// InvitationFilter narrows the invitation listing.
+type InvitationFilter struct {
+	Status string
+}
+
+func (f *InvitationFilter) Validate() error {
+	f.Status = strings.ToLower(strings.TrimSpace(f.Status))
+	if f.Status != "" && !invitationStatuses[f.Status] {
+		return ValidationErrors{FieldError{Field: "status", Message: "invalid status"}}
+	}
+	return nil
+}
+
+// PaginatedInvitationsResponse wraps the invitation list with pagination metadata.
+type PaginatedInvitationsResponse struct {
+	Items []Invitation   `json:"items"`
+	Meta  PaginationMeta `json:"meta"`
+}
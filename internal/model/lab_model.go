@@ -0,0 +1,337 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reconciliation statuses. A pending item is resolved by matching it to a
+// patient, or discarded.
+const (
+	ReconciliationPending   = "pending"
+	ReconciliationResolved  = "resolved"
+	ReconciliationDiscarded = "discarded"
+)
+
+var reconciliationStatuses = map[string]bool{
+	ReconciliationPending:   true,
+	ReconciliationResolved:  true,
+	ReconciliationDiscarded: true,
+}
+
+// Reasons a patient in a lab message could not be matched.
+const (
+	ReconcileNoMatch       = "no patient matches the identifiers"
+	ReconcileSeveral       = "the identifiers match more than one patient"
+	ReconcileDemographics  = "the date of birth or family name does not match the patient"
+	ReconcileNoIdentifiers = "the message has no patient identifiers"
+)
+
+// Interpretations of a numeric lab result against its reference range.
+const (
+	InterpretationLow      = "low"
+	InterpretationNormal   = "normal"
+	InterpretationHigh     = "high"
+	InterpretationCritical = "critical"
+)
+
+// LabMessage is a lab result message that was accepted, kept as received.
+type LabMessage struct {
+	ID                 uuid.UUID
+	OrganisationID     uuid.UUID
+	ControlID          string
+	SendingApplication string
+	SendingFacility    string
+	MessageType        string
+	Raw                string
+	ReceivedBy         *uuid.UUID
+	ReceivedAt         time.Time
+}
+
+// LabIdentifier is a patient identifier from a message: its value, the
+// authority that assigned it and its type, such as MR for a medical record
+// number.
+type LabIdentifier struct {
+	Value     string `json:"value"`
+	Authority string `json:"authority,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+// LabPatient is the patient a message names.
+type LabPatient struct {
+	Identifiers []LabIdentifier `json:"identifiers"`
+	FamilyName  string          `json:"family_name"`
+	GivenName   string          `json:"given_name"`
+	DateOfBirth *string         `json:"date_of_birth"`
+}
+
+// LabObservation is a single lab result, reported under an order for a
+// service such as a lipid panel. ReferenceRange and AbnormalFlags are as
+// the lab reported them; Interpretation compares the value with the
+// configured reference range for the patient, and is nil when no range
+// applies. A critical result is acknowledged by the practitioner who
+// ordered it or by an admin.
+type LabObservation struct {
+	ID                     uuid.UUID  `json:"id"`
+	OrganisationID         uuid.UUID  `json:"organisation_id"`
+	MessageID              uuid.UUID  `json:"message_id"`
+	PatientID              *uuid.UUID `json:"patient_id"`
+	ReconciliationID       *uuid.UUID `json:"reconciliation_id,omitempty"`
+	PlacerOrderNumber      *string    `json:"placer_order_number"`
+	FillerOrderNumber      *string    `json:"filler_order_number"`
+	OrderingProvider       *string    `json:"ordering_provider"`
+	OrderingPractitionerID *uuid.UUID `json:"ordering_practitioner_id"`
+	ServiceCode            string     `json:"service_code"`
+	ServiceName            *string    `json:"service_name"`
+	Code                   string     `json:"code"`
+	CodeSystem             *string    `json:"code_system"`
+	Name                   string     `json:"name"`
+	ValueType              string     `json:"value_type"`
+	Value                  *string    `json:"value"`
+	Units                  *string    `json:"units"`
+	ReferenceRange         *string    `json:"reference_range"`
+	AbnormalFlags          []string   `json:"abnormal_flags"`
+	Interpretation         *string    `json:"interpretation"`
+	ResultStatus           string     `json:"result_status"`
+	ObservedAt             *time.Time `json:"observed_at"`
+	Note                   *string    `json:"note"`
+	AcknowledgedBy         *uuid.UUID `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt         *time.Time `json:"acknowledged_at,omitempty"`
+	CreatedAt              time.Time  `json:"created_at"`
+}
+
+// PaginatedLabObservationsResponse wraps a list of lab results with
+// pagination metadata.
+type PaginatedLabObservationsResponse struct {
+	Items []LabObservation `json:"items"`
+	Meta  PaginationMeta   `json:"meta"`
+}
+
+// LabReconciliation is a patient named in a lab message who could not be
+// matched to a patient record. Their results are held until the item is
+// resolved to a patient or discarded.
+type LabReconciliation struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganisationID uuid.UUID  `json:"organisation_id"`
+	MessageID      uuid.UUID  `json:"message_id"`
+	Patient        LabPatient `json:"patient"`
+	Reason         string     `json:"reason"`
+	Status         string     `json:"status"`
+	Observations   int        `json:"observations"`
+	PatientID      *uuid.UUID `json:"patient_id"`
+	ResolvedBy     *uuid.UUID `json:"resolved_by"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	Note           *string    `json:"note"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// PaginatedLabReconciliationResponse wraps the reconciliation queue with
+// pagination metadata.
+type PaginatedLabReconciliationResponse struct {
+	Items []LabReconciliation `json:"items"`
+	Meta  PaginationMeta      `json:"meta"`
+}
+
+// ReconciliationFilter narrows the reconciliation queue to a status,
+// pending unless another is asked for.
+type ReconciliationFilter struct {
+	Status string
+}
+
+func (f *ReconciliationFilter) Validate() error {
+	f.Status = strings.TrimSpace(f.Status)
+	if f.Status == "" {
+		f.Status = ReconciliationPending
+	}
+	if !reconciliationStatuses[f.Status] {
+		return ValidationErrors{FieldError{Field: "status", Message: "status must be pending, resolved or discarded"}}
+	}
+	return nil
+}
+
+// ResolveReconciliation files the results of a reconciliation item under a
+// patient.
+type ResolveReconciliation struct {
+	PatientID uuid.UUID `json:"patient_id"`
+	Note      string    `json:"note"`
+}
+
+func (m *ResolveReconciliation) Validate() error {
+	var errs ValidationErrors
+
+	if m.PatientID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "patient_id", Message: "patient id is required"})
+	}
+	m.Note = strings.TrimSpace(m.Note)
+	if len(m.Note) > 500 {
+		errs = append(errs, FieldError{Field: "note", Message: "note must be at most 500 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// DiscardReconciliation drops the results of a reconciliation item, such as
+// results sent to the wrong organisation.
+type DiscardReconciliation struct {
+	Reason string `json:"reason"`
+}
+
+func (m *DiscardReconciliation) Validate() error {
+	m.Reason = strings.TrimSpace(m.Reason)
+	if m.Reason == "" {
+		return ValidationErrors{FieldError{Field: "reason", Message: "reason is required"}}
+	}
+	if len(m.Reason) > 500 {
+		return ValidationErrors{FieldError{Field: "reason", Message: "reason must be at most 500 characters"}}
+	}
+	return nil
+}
+
+// Age is a patient's age, written as a number of years, months, weeks or
+// days such as 18y, 6m, 2w or 28d.
+type Age struct {
+	years, months, days int
+}
+
+// ParseAge reads an age such as 18y, 6m, 2w or 28d.
+func ParseAge(s string) (Age, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return Age{}, fmt.Errorf("age %q must be a number followed by y, m, w or d", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return Age{}, fmt.Errorf("age %q must be a number followed by y, m, w or d", s)
+	}
+	switch s[len(s)-1] {
+	case 'y':
+		return Age{years: n}, nil
+	case 'm':
+		return Age{months: n}, nil
+	case 'w':
+		return Age{days: 7 * n}, nil
+	case 'd':
+		return Age{days: n}, nil
+	}
+	return Age{}, fmt.Errorf("age %q must be a number followed by y, m, w or d", s)
+}
+
+func (a *Age) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("age must be a string such as 18y")
+	}
+	age, err := ParseAge(s)
+	if err != nil {
+		return err
+	}
+	*a = age
+	return nil
+}
+
+// ReachedBy reports whether someone born on dob is at least this age at t.
+func (a Age) ReachedBy(dob, t time.Time) bool {
+	return !dob.AddDate(a.years, a.months, a.days).After(t)
+}
+
+// ReferenceRange is the expected range of a numeric lab result for a test
+// code, optionally limited to a sex and to ages from MinAge up to, but not
+// including, MaxAge. A value below Low or above High is abnormal, and below
+// CriticalLow or above CriticalHigh is critical. Units, when set, must match
+// the units the lab reports.
+type ReferenceRange struct {
+	Code         string   `json:"code"`
+	System       string   `json:"system"`
+	Units        string   `json:"units"`
+	Sex          string   `json:"sex"`
+	MinAge       *Age     `json:"min_age"`
+	MaxAge       *Age     `json:"max_age"`
+	Low          *float64 `json:"low"`
+	High         *float64 `json:"high"`
+	CriticalLow  *float64 `json:"critical_low"`
+	CriticalHigh *float64 `json:"critical_high"`
+}
+
+func (m *ReferenceRange) Validate() error {
+	var errs ValidationErrors
+
+	m.Code = strings.TrimSpace(m.Code)
+	if m.Code == "" {
+		errs = append(errs, FieldError{Field: "code", Message: "code is required"})
+	}
+	m.System = strings.TrimSpace(m.System)
+	m.Units = strings.TrimSpace(m.Units)
+
+	m.Sex = strings.ToLower(strings.TrimSpace(m.Sex))
+	if m.Sex != "" && !sexValues[m.Sex] {
+		errs = append(errs, FieldError{Field: "sex", Message: "sex must be male, female, other or unknown"})
+	}
+
+	if m.Low == nil && m.High == nil && m.CriticalLow == nil && m.CriticalHigh == nil {
+		errs = append(errs, FieldError{Field: "low", Message: "at least one limit is required"})
+	}
+	if m.Low != nil && m.High != nil && *m.Low > *m.High {
+		errs = append(errs, FieldError{Field: "high", Message: "high must not be below low"})
+	}
+	if m.CriticalLow != nil && m.Low != nil && *m.CriticalLow > *m.Low {
+		errs = append(errs, FieldError{Field: "critical_low", Message: "critical_low must not be above low"})
+	}
+	if m.CriticalHigh != nil && m.High != nil && *m.CriticalHigh < *m.High {
+		errs = append(errs, FieldError{Field: "critical_high", Message: "critical_high must not be below high"})
+	}
+	if m.MinAge != nil && m.MaxAge != nil {
+		// Compare the ages from a fixed date of birth.
+		dob := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if m.MaxAge.ReachedBy(dob, dob.AddDate(m.MinAge.years, m.MinAge.months, m.MinAge.days)) {
+			errs = append(errs, FieldError{Field: "max_age", Message: "max_age must be above min_age"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Applies reports whether the range is for a patient of sex who was born on
+// dob, at time t. A range limited by age never applies to a patient whose
+// date of birth is unknown.
+func (m *ReferenceRange) Applies(sex string, dob *time.Time, t time.Time) bool {
+	if m.Sex != "" && m.Sex != sex {
+		return false
+	}
+	if m.MinAge == nil && m.MaxAge == nil {
+		return true
+	}
+	if dob == nil {
+		return false
+	}
+	if m.MinAge != nil && !m.MinAge.ReachedBy(*dob, t) {
+		return false
+	}
+	if m.MaxAge != nil && m.MaxAge.ReachedBy(*dob, t) {
+		return false
+	}
+	return true
+}
+
+// Interpret compares a value with the range.
+func (m *ReferenceRange) Interpret(v float64) string {
+	switch {
+	case m.CriticalLow != nil && v < *m.CriticalLow, m.CriticalHigh != nil && v > *m.CriticalHigh:
+		return InterpretationCritical
+	case m.Low != nil && v < *m.Low:
+		return InterpretationLow
+	case m.High != nil && v > *m.High:
+		return InterpretationHigh
+	}
+	return InterpretationNormal
+}
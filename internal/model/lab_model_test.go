@@ -0,0 +1,170 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReconciliationFilter_Validate(t *testing.T) {
+	f := ReconciliationFilter{}
+	if err := f.Validate(); err != nil || f.Status != ReconciliationPending {
+		t.Errorf("Validate() = %v with status %q, want pending by default", err, f.Status)
+	}
+
+	f = ReconciliationFilter{Status: " resolved "}
+	if err := f.Validate(); err != nil || f.Status != ReconciliationResolved {
+		t.Errorf("Validate() = %v with status %q, want resolved", err, f.Status)
+	}
+
+	f = ReconciliationFilter{Status: "open"}
+	if err := f.Validate(); err == nil {
+		t.Error("expected an unknown status to be rejected")
+	}
+}
+
+func TestResolveReconciliation_Validate(t *testing.T) {
+	id, _ := uuid.NewV7()
+
+	tests := []struct {
+		name    string
+		data    ResolveReconciliation
+		wantErr bool
+	}{
+		{name: "valid", data: ResolveReconciliation{PatientID: id, Note: "MRN typo"}},
+		{name: "no patient", data: ResolveReconciliation{Note: "MRN typo"}, wantErr: true},
+		{name: "long note", data: ResolveReconciliation{PatientID: id, Note: strings.Repeat("x", 501)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.data.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiscardReconciliation_Validate(t *testing.T) {
+	for reason, wantErr := range map[string]bool{
+		"sent to the wrong clinic": false,
+		"   ":                      true,
+		strings.Repeat("x", 501):   true,
+	} {
+		data := DiscardReconciliation{Reason: reason}
+		if err := data.Validate(); (err != nil) != wantErr {
+			t.Errorf("Validate(%q) = %v, want error %v", reason, err, wantErr)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	dob := time.Date(2020, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		age     string
+		reached time.Time
+	}{
+		{"18y", time.Date(2038, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"1m", time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		{"2w", time.Date(2020, time.February, 14, 0, 0, 0, 0, time.UTC)},
+		{"28d", time.Date(2020, time.February, 28, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		age, err := ParseAge(tt.age)
+		if err != nil {
+			t.Fatalf("ParseAge(%q) = %v", tt.age, err)
+		}
+		if !age.ReachedBy(dob, tt.reached) || age.ReachedBy(dob, tt.reached.Add(-time.Second)) {
+			t.Errorf("%s is not reached on %s", tt.age, tt.reached.Format(DateLayout))
+		}
+	}
+
+	for _, bad := range []string{"", "y", "18", "-1y", "18x", "1.5y"} {
+		if _, err := ParseAge(bad); err == nil {
+			t.Errorf("expected ParseAge(%q) to fail", bad)
+		}
+	}
+}
+
+func TestReferenceRange_Validate(t *testing.T) {
+	parse := func(s string) ReferenceRange {
+		var r ReferenceRange
+		if err := json.Unmarshal([]byte(s), &r); err != nil {
+			t.Fatalf("invalid range %s: %v", s, err)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `{"code": "2823-3", "units": "mmol/L", "sex": "Female", "min_age": "18y", "low": 3.5, "high": 5.1, "critical_low": 2.5, "critical_high": 6.5}`},
+		{name: "critical only", data: `{"code": "2823-3", "critical_high": 6.5}`},
+		{name: "no code", data: `{"low": 3.5}`, wantErr: true},
+		{name: "no limits", data: `{"code": "2823-3"}`, wantErr: true},
+		{name: "unknown sex", data: `{"code": "2823-3", "sex": "f", "low": 3.5}`, wantErr: true},
+		{name: "low above high", data: `{"code": "2823-3", "low": 5.1, "high": 3.5}`, wantErr: true},
+		{name: "critical inside range", data: `{"code": "2823-3", "low": 3.5, "high": 5.1, "critical_high": 5}`, wantErr: true},
+		{name: "empty age band", data: `{"code": "2823-3", "min_age": "1y", "max_age": "12m", "low": 3.5}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parse(tt.data)
+			if err := r.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var r ReferenceRange
+	if err := json.Unmarshal([]byte(`{"code": "2823-3", "min_age": "adult"}`), &r); err == nil {
+		t.Error("expected an invalid age to be rejected")
+	}
+}
+
+func TestReferenceRange_Interpret(t *testing.T) {
+	low, high, criticalLow, criticalHigh := 3.5, 5.1, 2.5, 6.5
+	r := ReferenceRange{Code: "2823-3", Low: &low, High: &high, CriticalLow: &criticalLow, CriticalHigh: &criticalHigh}
+
+	for v, want := range map[float64]string{
+		2.4: InterpretationCritical,
+		2.5: InterpretationLow,
+		3.5: InterpretationNormal,
+		5.1: InterpretationNormal,
+		5.2: InterpretationHigh,
+		6.6: InterpretationCritical,
+	} {
+		if got := r.Interpret(v); got != want {
+			t.Errorf("Interpret(%v) = %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestReferenceRange_Applies(t *testing.T) {
+	adult := Age{years: 18}
+	r := ReferenceRange{Code: "2823-3", Sex: "female", MinAge: &adult}
+	paediatric := ReferenceRange{Code: "2823-3", MaxAge: &adult}
+
+	dob := time.Date(2000, time.June, 1, 0, 0, 0, 0, time.UTC)
+	birthday := time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	if !r.Applies("female", &dob, birthday) || r.Applies("female", &dob, birthday.Add(-time.Hour)) {
+		t.Error("expected the adult range to apply from the 18th birthday")
+	}
+	if paediatric.Applies("male", &dob, birthday) || !paediatric.Applies("male", &dob, birthday.Add(-time.Hour)) {
+		t.Error("expected the paediatric range to apply until the 18th birthday")
+	}
+	if r.Applies("male", &dob, birthday) {
+		t.Error("expected a range for women not to apply to men")
+	}
+	if r.Applies("female", nil, birthday) {
+		t.Error("expected an age band not to apply without a date of birth")
+	}
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CatalogueSource is the RxNorm source whose names make up the catalogue.
+const CatalogueSource = "RXNORM"
+
+// catalogueTermTypes are the RxNorm term types kept in the catalogue:
+// ingredients and the drugs and packs that can be prescribed.
+var catalogueTermTypes = map[string]bool{
+	"IN":   true, // ingredient, e.g. amoxicillin
+	"MIN":  true, // multiple ingredients, e.g. amoxicillin / clavulanate
+	"SCD":  true, // clinical drug, e.g. amoxicillin 500 MG Oral Capsule
+	"SBD":  true, // branded drug
+	"GPCK": true, // generic pack
+	"BPCK": true, // branded pack
+}
+
+// InCatalogue reports whether names of an RxNorm term type are kept in the
+// catalogue.
+func InCatalogue(termType string) bool {
+	return catalogueTermTypes[termType]
+}
+
+// Medication is an entry of the medication catalogue, identified by its
+// RxNorm concept ID.
+type Medication struct {
+	RxCUI     string    `json:"rxcui"`
+	Name      string    `json:"name"`
+	TermType  string    `json:"term_type"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PaginatedMedicationsResponse wraps a catalogue search with pagination
+// metadata.
+type PaginatedMedicationsResponse struct {
+	Items []Medication   `json:"items"`
+	Meta  PaginationMeta `json:"meta"`
+}
+
+// MedicationFilter narrows a catalogue search. Search matches part of the
+// name, case-insensitively.
+type MedicationFilter struct {
+	Search   string
+	TermType string
+}
+
+func (f *MedicationFilter) Validate() error {
+	var errs ValidationErrors
+
+	f.Search = strings.TrimSpace(f.Search)
+	if len(f.Search) > 100 {
+		errs = append(errs, FieldError{Field: "q", Message: "q must be at most 100 characters"})
+	}
+
+	f.TermType = strings.ToUpper(strings.TrimSpace(f.TermType))
+	if f.TermType != "" && !catalogueTermTypes[f.TermType] {
+		errs = append(errs, FieldError{Field: "term_type", Message: "term_type must be IN, MIN, SCD, SBD, GPCK or BPCK"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CatalogueReport summarises a catalogue load. Read counts the lines of the
+// file; Loaded the names written, new or changed; Unchanged those already
+// in the catalogue as they are; Skipped the names of other sources,
+// languages or term types, suppressed names and repeats of a concept.
+type CatalogueReport struct {
+	ID        uuid.UUID `json:"id"`
+	Read      int       `json:"read"`
+	Loaded    int       `json:"loaded"`
+	Unchanged int       `json:"unchanged"`
+	Skipped   int       `json:"skipped"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles a user can hold within an organisation. Organisation admins act as
+// "admin" while signed in to that organisation and members as "user".
+const (
+	MemberRoleAdmin  = "admin"
+	MemberRoleMember = "member"
+)
+
+var memberRoles = map[string]bool{MemberRoleAdmin: true, MemberRoleMember: true}
+
+// Member is a user's membership of an organisation.
+type Member struct {
+	UserID    uuid.UUID `json:"user_id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SetMember struct {
+	Role string `json:"role"`
+}
+
+func (m *SetMember) Validate() error {
+	m.Role = strings.ToLower(strings.TrimSpace(m.Role))
+
+	if m.Role == "" {
+		return ValidationErrors{FieldError{Field: "role", Message: "role is required"}}
+	}
+	if !memberRoles[m.Role] {
+		return ValidationErrors{FieldError{Field: "role", Message: "role must be admin or member"}}
+	}
+	return nil
+}
+
+// Access is what decides the role a user acts with in an organisation.
+type Access struct {
+	Status string
+	// Role is the account's own role; only super_admin applies across
+	// organisations.
+	Role string
+	// MemberRole is empty when the user is not a member of the organisation.
+	MemberRole         string
+	OrganisationExists bool
+}
+
+// SwitchOrganisation asks for new tokens issued for another organisation.
+type SwitchOrganisation struct {
+	OrganisationID uuid.UUID `json:"organisation_id"`
+}
+
+func (m *SwitchOrganisation) Validate() error {
+	if m.OrganisationID == uuid.Nil {
+		return ValidationErrors{FieldError{Field: "organisation_id", Message: "organisation is required"}}
+	}
+	return nil
+}
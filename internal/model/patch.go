@@ -0,0 +1,32 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Optional is a member of a JSON Merge Patch (RFC 7396) document. It
+// distinguishes an absent member (Set is false) from an explicit null (Set
+// and Null are true) and from a value.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+	Null  bool
+}
+
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(b, &o.Value)
+}
+
+// HasValue reports whether the member is present and not null.
+func (o Optional[T]) HasValue() bool {
+	return o.Set && !o.Null
+}
+
+// MergePatchContentType is the media type of RFC 7396 patch documents.
+const MergePatchContentType = "application/merge-patch+json"
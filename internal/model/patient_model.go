@@ -0,0 +1,343 @@
+package model
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Patient is a person receiving care from an organisation. Patients are not
+// users; a patient may optionally be linked to a user account they sign in
+// with.
+type Patient struct {
+	ID                uuid.UUID           `json:"id"`
+	OrganisationID    uuid.UUID           `json:"organisation_id"`
+	MRN               string              `json:"mrn"`
+	FirstName         string              `json:"first_name"`
+	LastName          string              `json:"last_name"`
+	DateOfBirth       string              `json:"date_of_birth"`
+	Sex               string              `json:"sex"`
+	Gender            *string             `json:"gender"`
+	Phone             *string             `json:"phone"`
+	Email             *string             `json:"email"`
+	PreferredLanguage *string             `json:"preferred_language"`
+	Address           *Address            `json:"address"`
+	UserID            *uuid.UUID          `json:"user_id"`
+	Identifiers       []PatientIdentifier `json:"identifiers"`
+	CreatedBy         *uuid.UUID          `json:"created_by"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+
+	Version int64 `json:"-"`
+}
+
+// PatientSummary is a patient as shown in listings.
+type PatientSummary struct {
+	ID          uuid.UUID `json:"id"`
+	MRN         string    `json:"mrn"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	DateOfBirth string    `json:"date_of_birth"`
+	Sex         string    `json:"sex"`
+}
+
+// PaginatedPatientsResponse wraps the patient list with pagination metadata.
+type PaginatedPatientsResponse struct {
+	Items []PatientSummary `json:"items"`
+	Meta  PaginationMeta   `json:"meta"`
+}
+
+// PatientIdentifier is an identifier issued to a patient by another system,
+// such as a national health number or an insurance member number. System
+// names the issuer, ideally as a URI.
+type PatientIdentifier struct {
+	ID        uuid.UUID `json:"id"`
+	System    string    `json:"system"`
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreatePatientIdentifier struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+func (m *CreatePatientIdentifier) Validate() error {
+	if errs := m.validate("system", "value"); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (m *CreatePatientIdentifier) validate(systemField, valueField string) ValidationErrors {
+	var errs ValidationErrors
+
+	m.System = strings.TrimSpace(m.System)
+	m.Value = strings.TrimSpace(m.Value)
+
+	if m.System == "" {
+		errs = append(errs, FieldError{Field: systemField, Message: "system is required"})
+	} else if len(m.System) > 100 {
+		errs = append(errs, FieldError{Field: systemField, Message: "system must be at most 100 characters"})
+	}
+
+	if m.Value == "" {
+		errs = append(errs, FieldError{Field: valueField, Message: "value is required"})
+	} else if len(m.Value) > 100 {
+		errs = append(errs, FieldError{Field: valueField, Message: "value must be at most 100 characters"})
+	}
+
+	return errs
+}
+
+// CreatePatient registers a patient. OrganisationID defaults to the
+// organisation the caller is signed in to. The MRN is generated.
+type CreatePatient struct {
+	OrganisationID    *uuid.UUID                `json:"organisation_id"`
+	FirstName         string                    `json:"first_name"`
+	LastName          string                    `json:"last_name"`
+	DateOfBirth       string                    `json:"date_of_birth"`
+	Sex               string                    `json:"sex"`
+	Gender            string                    `json:"gender"`
+	Phone             string                    `json:"phone"`
+	Email             string                    `json:"email"`
+	PreferredLanguage string                    `json:"preferred_language"`
+	Address           *Address                  `json:"address"`
+	UserID            *uuid.UUID                `json:"user_id"`
+	Identifiers       []CreatePatientIdentifier `json:"identifiers"`
+}
+
+func (m *CreatePatient) Validate() error {
+	var errs ValidationErrors
+
+	m.FirstName = strings.TrimSpace(m.FirstName)
+	m.LastName = strings.TrimSpace(m.LastName)
+
+	errs = append(errs, validateName(m.FirstName, "first_name", "first name")...)
+	errs = append(errs, validateName(m.LastName, "last_name", "last name")...)
+
+	fields := []*string{&m.Phone, &m.DateOfBirth, &m.Sex, &m.Gender, &m.PreferredLanguage}
+	opts := make([]Optional[string], len(fields))
+	for i, f := range fields {
+		opts[i] = Optional[string]{Value: *f, Set: true}
+	}
+	errs = append(errs, demographics{
+		Phone:             &opts[0],
+		DateOfBirth:       &opts[1],
+		Sex:               &opts[2],
+		Gender:            &opts[3],
+		PreferredLanguage: &opts[4],
+	}.validate()...)
+	for i, f := range fields {
+		*f = opts[i].Value
+	}
+
+	if m.DateOfBirth == "" {
+		errs = append(errs, FieldError{Field: "date_of_birth", Message: "date of birth is required"})
+	}
+	if m.Sex == "" {
+		errs = append(errs, FieldError{Field: "sex", Message: "sex is required"})
+	}
+
+	m.Email = strings.ToLower(strings.TrimSpace(m.Email))
+	if m.Email != "" {
+		if addr, err := mail.ParseAddress(m.Email); err != nil || addr.Address != m.Email || len(m.Email) > 150 {
+			errs = append(errs, FieldError{Field: "email", Message: "invalid email"})
+		}
+	}
+
+	if m.Address != nil {
+		errs = append(errs, m.Address.check()...)
+		if m.Address.IsZero() {
+			m.Address = nil
+		}
+	}
+
+	seen := map[string]bool{}
+	for i := range m.Identifiers {
+		id := &m.Identifiers[i]
+		prefix := fmt.Sprintf("identifiers[%d].", i)
+		errs = append(errs, id.validate(prefix+"system", prefix+"value")...)
+		if seen[id.System] {
+			errs = append(errs, FieldError{Field: prefix + "system", Message: "only one identifier per system"})
+		}
+		seen[id.System] = true
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateName(name, field, label string) ValidationErrors {
+	if name == "" {
+		return ValidationErrors{FieldError{Field: field, Message: label + " is required"}}
+	}
+	if !isValidName(name) || len(name) > 50 {
+		return ValidationErrors{FieldError{Field: field, Message: "invalid " + label}}
+	}
+	return nil
+}
+
+// UpdatePatient is a JSON Merge Patch of a patient's demographics. Names,
+// date of birth and sex cannot be cleared.
+type UpdatePatient struct {
+	FirstName         Optional[string]       `json:"first_name"`
+	LastName          Optional[string]       `json:"last_name"`
+	DateOfBirth       Optional[string]       `json:"date_of_birth"`
+	Sex               Optional[string]       `json:"sex"`
+	Gender            Optional[string]       `json:"gender"`
+	Phone             Optional[string]       `json:"phone"`
+	Email             Optional[string]       `json:"email"`
+	PreferredLanguage Optional[string]       `json:"preferred_language"`
+	Address           Optional[AddressPatch] `json:"address"`
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (m *UpdatePatient) IsEmpty() bool {
+	return !m.FirstName.Set && !m.LastName.Set && !m.DateOfBirth.Set && !m.Sex.Set && !m.Gender.Set &&
+		!m.Phone.Set && !m.Email.Set && !m.PreferredLanguage.Set && !m.Address.Set
+}
+
+func (m *UpdatePatient) Validate() error {
+	if m == nil {
+		return ValidationErrors{FieldError{Field: "body", Message: "patch must be a JSON object"}}
+	}
+
+	var errs ValidationErrors
+
+	errs = append(errs, validatePatchName(&m.FirstName, "first_name", "first name")...)
+	errs = append(errs, validatePatchName(&m.LastName, "last_name", "last name")...)
+
+	errs = append(errs, demographics{
+		Phone:             &m.Phone,
+		DateOfBirth:       &m.DateOfBirth,
+		Sex:               &m.Sex,
+		Gender:            &m.Gender,
+		PreferredLanguage: &m.PreferredLanguage,
+	}.validate()...)
+
+	if m.DateOfBirth.Set && m.DateOfBirth.Null {
+		errs = append(errs, FieldError{Field: "date_of_birth", Message: "date of birth cannot be removed"})
+	}
+	if m.Sex.Set && m.Sex.Null {
+		errs = append(errs, FieldError{Field: "sex", Message: "sex cannot be removed"})
+	}
+
+	normalizeOptional(&m.Email)
+	m.Email.Value = strings.ToLower(m.Email.Value)
+	if m.Email.HasValue() {
+		if addr, err := mail.ParseAddress(m.Email.Value); err != nil || addr.Address != m.Email.Value || len(m.Email.Value) > 150 {
+			errs = append(errs, FieldError{Field: "email", Message: "invalid email"})
+		}
+	}
+
+	if m.Address.HasValue() {
+		errs = append(errs, m.Address.Value.validate()...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// LinkPatientUser links a patient to the user account they sign in with.
+type LinkPatientUser struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (m *LinkPatientUser) Validate() error {
+	if m.UserID == uuid.Nil {
+		return ValidationErrors{FieldError{Field: "user_id", Message: "user is required"}}
+	}
+	return nil
+}
+
+// PatientSortFields lists the fields the patient listing can be sorted by.
+var PatientSortFields = map[string]bool{
+	"mrn":           true,
+	"first_name":    true,
+	"last_name":     true,
+	"date_of_birth": true,
+	"created_at":    true,
+}
+
+// PatientFilter narrows and orders the patient listing. Search matches the
+// full name, or an MRN or identifier value exactly.
+type PatientFilter struct {
+	Search      string
+	DateOfBirth string
+	Sort        []SortField
+}
+
+func (f *PatientFilter) Validate() error {
+	var errs ValidationErrors
+
+	f.Search = strings.TrimSpace(f.Search)
+	if len(f.Search) > 100 {
+		errs = append(errs, FieldError{Field: "q", Message: "search must be at most 100 characters"})
+	}
+
+	f.DateOfBirth = strings.TrimSpace(f.DateOfBirth)
+	if f.DateOfBirth != "" {
+		if _, err := time.Parse(DateLayout, f.DateOfBirth); err != nil {
+			errs = append(errs, FieldError{Field: "date_of_birth", Message: "date of birth must be YYYY-MM-DD"})
+		}
+	}
+
+	for _, s := range f.Sort {
+		if !PatientSortFields[s.Field] {
+			errs = append(errs, FieldError{Field: "sort", Message: "unsupported sort field"})
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// FormatMRN formats the n-th medical record number of an organisation as
+// eight digits followed by a Luhn check digit, so that most typing mistakes
+// give an invalid number rather than another patient's.
+func FormatMRN(n int64) string {
+	digits := fmt.Sprintf("%08d", n)
+	return digits + string(rune('0'+luhnCheckDigit(digits)))
+}
+
+// ValidMRN reports whether s is a well-formed MRN.
+func ValidMRN(s string) bool {
+	if len(s) < 9 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return luhnCheckDigit(s[:len(s)-1]) == int(s[len(s)-1]-'0')
+}
+
+// luhnCheckDigit returns the Luhn check digit for a string of digits.
+func luhnCheckDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatMRN(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{1, "000000018"},
+		{42, "000000422"},
+		{12345678, "123456782"},
+	}
+
+	for _, tt := range tests {
+		got := FormatMRN(tt.n)
+		if got != tt.want {
+			t.Errorf("FormatMRN(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+		if !ValidMRN(got) {
+			t.Errorf("ValidMRN(%q) = false", got)
+		}
+	}
+}
+
+func TestValidMRN(t *testing.T) {
+	tests := []struct {
+		mrn  string
+		want bool
+	}{
+		{"000000018", true},
+		{"000000019", false},
+		{"000000081", false},
+		{"00000001", false},
+		{"00000001a", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidMRN(tt.mrn); got != tt.want {
+			t.Errorf("ValidMRN(%q) = %v, want %v", tt.mrn, got, tt.want)
+		}
+	}
+}
+
+func TestCreatePatient_Validate(t *testing.T) {
+	valid := func() CreatePatient {
+		return CreatePatient{
+			FirstName:   " Jane ",
+			LastName:    "Doe",
+			DateOfBirth: "1990-04-01",
+			Sex:         "Female",
+			Phone:       "+44 20 7946 0958",
+			Email:       "Jane@Example.com",
+			Address:     &Address{Line1: "1 High Street", City: "London", Country: "gb"},
+		}
+	}
+
+	m := valid()
+	if err := m.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.FirstName != "Jane" || m.Sex != "female" || m.Phone != "+442079460958" || m.Email != "jane@example.com" || m.Address.Country != "GB" {
+		t.Errorf("not normalized: %+v %+v", m, m.Address)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *CreatePatient)
+		field  string
+	}{
+		{"missing date of birth", func(m *CreatePatient) { m.DateOfBirth = "" }, "date_of_birth"},
+		{"missing sex", func(m *CreatePatient) { m.Sex = " " }, "sex"},
+		{"invalid email", func(m *CreatePatient) { m.Email = "jane" }, "email"},
+		{"incomplete address", func(m *CreatePatient) { m.Address.City = "" }, "address.city"},
+		{"blank identifier value", func(m *CreatePatient) {
+			m.Identifiers = []CreatePatientIdentifier{{System: "urn:nhs"}}
+		}, "identifiers[0].value"},
+		{"duplicate identifier system", func(m *CreatePatient) {
+			m.Identifiers = []CreatePatientIdentifier{{System: "urn:nhs", Value: "1"}, {System: "urn:nhs", Value: "2"}}
+		}, "identifiers[1].system"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid()
+			tt.modify(&m)
+
+			var errs ValidationErrors
+			if err := m.Validate(); !errors.As(err, &errs) {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			for _, e := range errs {
+				if e.Field == tt.field {
+					return
+				}
+			}
+			t.Errorf("expected an error on %s, got %+v", tt.field, errs)
+		})
+	}
+}
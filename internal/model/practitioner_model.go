@@ -0,0 +1,353 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Professions a practitioner can be registered with.
+var practitionerProfessions = map[string]bool{
+	"doctor":     true,
+	"nurse":      true,
+	"midwife":    true,
+	"pharmacist": true,
+	"therapist":  true,
+	"other":      true,
+}
+
+// jurisdictionPattern matches an ISO 3166-1 country or ISO 3166-2
+// subdivision code, such as GB or US-CA.
+var jurisdictionPattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// maxSpecialties caps the specialties of a practitioner.
+const maxSpecialties = 20
+
+// Facility is a site of an organisation where practitioners work.
+type Facility struct {
+	ID             uuid.UUID `json:"id"`
+	OrganisationID uuid.UUID `json:"organisation_id"`
+	Name           string    `json:"name"`
+	Address        Address   `json:"address"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CreateFacility adds a facility. OrganisationID defaults to the
+// organisation the caller is signed in to.
+type CreateFacility struct {
+	OrganisationID *uuid.UUID `json:"organisation_id"`
+	Name           string     `json:"name"`
+	Address        Address    `json:"address"`
+}
+
+func (m *CreateFacility) Validate() error {
+	var errs ValidationErrors
+
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		errs = append(errs, FieldError{Field: "name", Message: "name is required"})
+	} else if len(m.Name) > 100 {
+		errs = append(errs, FieldError{Field: "name", Message: "name must be at most 100 characters"})
+	}
+
+	errs = append(errs, m.Address.check()...)
+	if m.Address.IsZero() {
+		errs = append(errs, FieldError{Field: "address", Message: "address is required"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Practitioner is a doctor, nurse or other provider working for an
+// organisation. Each practitioner signs in with a user account, whose name
+// and email are shown with the record.
+type Practitioner struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganisationID uuid.UUID  `json:"organisation_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	Email          string     `json:"email"`
+	Profession     string     `json:"profession"`
+	NPI            *string    `json:"npi"`
+	Specialties    []string   `json:"specialties"`
+	Licences       []Licence  `json:"licences"`
+	Facilities     []Facility `json:"facilities"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Version int64 `json:"-"`
+}
+
+// PractitionerSummary is a practitioner as shown in the directory.
+type PractitionerSummary struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	Profession  string    `json:"profession"`
+	Specialties []string  `json:"specialties"`
+}
+
+// PaginatedPractitionersResponse wraps the directory with pagination
+// metadata.
+type PaginatedPractitionersResponse struct {
+	Items []PractitionerSummary `json:"items"`
+	Meta  PaginationMeta        `json:"meta"`
+}
+
+// Licence is a licence to practise issued in a jurisdiction.
+// ExpiryFlaggedAt is set when the licence was reported as about to expire.
+type Licence struct {
+	ID              uuid.UUID  `json:"id"`
+	Number          string     `json:"number"`
+	Jurisdiction    string     `json:"jurisdiction"`
+	ExpiresOn       string     `json:"expires_on"`
+	ExpiryFlaggedAt *time.Time `json:"expiry_flagged_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type CreateLicence struct {
+	Number       string `json:"number"`
+	Jurisdiction string `json:"jurisdiction"`
+	ExpiresOn    string `json:"expires_on"`
+}
+
+func (m *CreateLicence) Validate() error {
+	if errs := m.validate(""); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (m *CreateLicence) validate(prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	m.Number = strings.TrimSpace(m.Number)
+	if m.Number == "" {
+		errs = append(errs, FieldError{Field: prefix + "number", Message: "licence number is required"})
+	} else if len(m.Number) > 50 {
+		errs = append(errs, FieldError{Field: prefix + "number", Message: "licence number must be at most 50 characters"})
+	}
+
+	m.Jurisdiction = strings.ToUpper(strings.TrimSpace(m.Jurisdiction))
+	if !jurisdictionPattern.MatchString(m.Jurisdiction) {
+		errs = append(errs, FieldError{Field: prefix + "jurisdiction", Message: "jurisdiction must be an ISO 3166 country or subdivision code, e.g. US-CA"})
+	}
+
+	m.ExpiresOn = strings.TrimSpace(m.ExpiresOn)
+	if t, err := time.Parse(DateLayout, m.ExpiresOn); err != nil {
+		errs = append(errs, FieldError{Field: prefix + "expires_on", Message: "expiry must be YYYY-MM-DD"})
+	} else if t.Before(time.Now().Truncate(24 * time.Hour)) {
+		errs = append(errs, FieldError{Field: prefix + "expires_on", Message: "expiry must not be in the past"})
+	}
+
+	return errs
+}
+
+// CreatePractitioner registers a member of an organisation as a
+// practitioner. OrganisationID defaults to the organisation the caller is
+// signed in to.
+type CreatePractitioner struct {
+	OrganisationID *uuid.UUID      `json:"organisation_id"`
+	UserID         uuid.UUID       `json:"user_id"`
+	Profession     string          `json:"profession"`
+	NPI            string          `json:"npi"`
+	Specialties    []string        `json:"specialties"`
+	FacilityIDs    []uuid.UUID     `json:"facility_ids"`
+	Licences       []CreateLicence `json:"licences"`
+}
+
+func (m *CreatePractitioner) Validate() error {
+	var errs ValidationErrors
+
+	if m.UserID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "user_id", Message: "user is required"})
+	}
+
+	m.Profession = strings.ToLower(strings.TrimSpace(m.Profession))
+	if m.Profession == "" {
+		errs = append(errs, FieldError{Field: "profession", Message: "profession is required"})
+	} else if !practitionerProfessions[m.Profession] {
+		errs = append(errs, FieldError{Field: "profession", Message: "unknown profession"})
+	}
+
+	m.NPI = strings.TrimSpace(m.NPI)
+	if m.NPI != "" && !ValidNPI(m.NPI) {
+		errs = append(errs, FieldError{Field: "npi", Message: "npi must be 10 digits with a valid check digit"})
+	}
+
+	var specErrs ValidationErrors
+	m.Specialties, specErrs = normalizeSpecialties(m.Specialties)
+	errs = append(errs, specErrs...)
+
+	m.FacilityIDs = uniqueIDs(m.FacilityIDs)
+
+	for i := range m.Licences {
+		errs = append(errs, m.Licences[i].validate(fmt.Sprintf("licences[%d].", i))...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// UpdatePractitioner is a JSON Merge Patch of a practitioner. The profession
+// cannot be cleared; a null list clears it.
+type UpdatePractitioner struct {
+	Profession  Optional[string]      `json:"profession"`
+	NPI         Optional[string]      `json:"npi"`
+	Specialties Optional[[]string]    `json:"specialties"`
+	FacilityIDs Optional[[]uuid.UUID] `json:"facility_ids"`
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (m *UpdatePractitioner) IsEmpty() bool {
+	return !m.Profession.Set && !m.NPI.Set && !m.Specialties.Set && !m.FacilityIDs.Set
+}
+
+func (m *UpdatePractitioner) Validate() error {
+	if m == nil {
+		return ValidationErrors{FieldError{Field: "body", Message: "patch must be a JSON object"}}
+	}
+
+	var errs ValidationErrors
+
+	if m.Profession.Set {
+		m.Profession.Value = strings.ToLower(strings.TrimSpace(m.Profession.Value))
+		if m.Profession.Null || m.Profession.Value == "" {
+			errs = append(errs, FieldError{Field: "profession", Message: "profession cannot be removed"})
+		} else if !practitionerProfessions[m.Profession.Value] {
+			errs = append(errs, FieldError{Field: "profession", Message: "unknown profession"})
+		}
+	}
+
+	normalizeOptional(&m.NPI)
+	if m.NPI.HasValue() && !ValidNPI(m.NPI.Value) {
+		errs = append(errs, FieldError{Field: "npi", Message: "npi must be 10 digits with a valid check digit"})
+	}
+
+	if m.Specialties.HasValue() {
+		var specErrs ValidationErrors
+		m.Specialties.Value, specErrs = normalizeSpecialties(m.Specialties.Value)
+		errs = append(errs, specErrs...)
+	}
+
+	if m.FacilityIDs.HasValue() {
+		m.FacilityIDs.Value = uniqueIDs(m.FacilityIDs.Value)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// normalizeSpecialties trims the specialties and drops blanks and
+// case-insensitive duplicates, keeping the first spelling.
+func normalizeSpecialties(specialties []string) ([]string, ValidationErrors) {
+	var errs ValidationErrors
+	out := []string{}
+	seen := map[string]bool{}
+
+	for _, s := range specialties {
+		s = strings.TrimSpace(s)
+		key := strings.ToLower(s)
+		if s == "" || seen[key] {
+			continue
+		}
+		if len(s) > 100 {
+			errs = append(errs, FieldError{Field: "specialties", Message: "specialties must be at most 100 characters"})
+			continue
+		}
+		seen[key] = true
+		out = append(out, s)
+	}
+
+	if len(out) > maxSpecialties {
+		errs = append(errs, FieldError{Field: "specialties", Message: fmt.Sprintf("at most %d specialties", maxSpecialties)})
+	}
+
+	return out, errs
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	out := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if id == uuid.Nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// PractitionerFilter narrows the practitioner directory. Specialty matches a
+// specialty case-insensitively; Location matches the name, city, region or
+// postal code of a facility the practitioner works at.
+type PractitionerFilter struct {
+	Search     string
+	Profession string
+	Specialty  string
+	Location   string
+	FacilityID *uuid.UUID
+}
+
+func (f *PractitionerFilter) Validate() error {
+	var errs ValidationErrors
+
+	f.Search = strings.TrimSpace(f.Search)
+	f.Specialty = strings.TrimSpace(f.Specialty)
+	f.Location = strings.TrimSpace(f.Location)
+	for field, v := range map[string]string{"q": f.Search, "specialty": f.Specialty, "location": f.Location} {
+		if len(v) > 100 {
+			errs = append(errs, FieldError{Field: field, Message: field + " must be at most 100 characters"})
+		}
+	}
+
+	f.Profession = strings.ToLower(strings.TrimSpace(f.Profession))
+	if f.Profession != "" && !practitionerProfessions[f.Profession] {
+		errs = append(errs, FieldError{Field: "profession", Message: "unknown profession"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ExpiringLicence is a licence reported by the expiry check, with the
+// contact details of its practitioner.
+type ExpiringLicence struct {
+	LicenceID      uuid.UUID
+	PractitionerID uuid.UUID
+	OrganisationID uuid.UUID
+	Number         string
+	Jurisdiction   string
+	ExpiresOn      string
+	FirstName      string
+	Email          string
+}
+
+// ValidNPI reports whether s is a National Provider Identifier: ten digits
+// whose last is the Luhn check digit of the first nine prefixed with 80840.
+func ValidNPI(s string) bool {
+	if len(s) != 10 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return luhnCheckDigit("80840"+s[:9]) == int(s[9]-'0')
+}
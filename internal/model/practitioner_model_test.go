@@ -0,0 +1,101 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidNPI(t *testing.T) {
+	tests := []struct {
+		npi  string
+		want bool
+	}{
+		{"1234567893", true},
+		{"1234567890", false},
+		{"123456789", false},
+		{"12345678930", false},
+		{"12345678a3", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidNPI(tt.npi); got != tt.want {
+			t.Errorf("ValidNPI(%q) = %v, want %v", tt.npi, got, tt.want)
+		}
+	}
+}
+
+func TestCreatePractitioner_Validate(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	facilityID, _ := uuid.NewV7()
+	nextYear := time.Now().AddDate(1, 0, 0).Format(DateLayout)
+	lastYear := time.Now().AddDate(-1, 0, 0).Format(DateLayout)
+
+	valid := func() CreatePractitioner {
+		return CreatePractitioner{
+			UserID:      userID,
+			Profession:  " Doctor ",
+			NPI:         "1234567893",
+			Specialties: []string{"Cardiology", " cardiology ", ""},
+			FacilityIDs: []uuid.UUID{facilityID, facilityID},
+			Licences:    []CreateLicence{{Number: "GMC123", Jurisdiction: "gb", ExpiresOn: nextYear}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(m *CreatePractitioner)
+		expectField string
+	}{
+		{name: "valid"},
+		{name: "missing user", modify: func(m *CreatePractitioner) { m.UserID = uuid.Nil }, expectField: "user_id"},
+		{name: "unknown profession", modify: func(m *CreatePractitioner) { m.Profession = "wizard" }, expectField: "profession"},
+		{name: "bad npi", modify: func(m *CreatePractitioner) { m.NPI = "1234567890" }, expectField: "npi"},
+		{name: "bad jurisdiction", modify: func(m *CreatePractitioner) { m.Licences[0].Jurisdiction = "Britain" }, expectField: "licences[0].jurisdiction"},
+		{name: "expired licence", modify: func(m *CreatePractitioner) { m.Licences[0].ExpiresOn = lastYear }, expectField: "licences[0].expires_on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid()
+			if tt.modify != nil {
+				tt.modify(&m)
+			}
+			err := m.Validate()
+
+			if tt.expectField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if m.Profession != "doctor" {
+					t.Errorf("profession = %q, want doctor", m.Profession)
+				}
+				if !reflect.DeepEqual(m.Specialties, []string{"Cardiology"}) {
+					t.Errorf("specialties = %q, want [Cardiology]", m.Specialties)
+				}
+				if len(m.FacilityIDs) != 1 {
+					t.Errorf("facility ids = %v, want one", m.FacilityIDs)
+				}
+				if m.Licences[0].Jurisdiction != "GB" {
+					t.Errorf("jurisdiction = %q, want GB", m.Licences[0].Jurisdiction)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			for _, fe := range verrs {
+				if fe.Field == tt.expectField {
+					return
+				}
+			}
+			t.Errorf("expected an error on %s, got %v", tt.expectField, verrs)
+		})
+	}
+}
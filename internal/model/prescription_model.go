@@ -0,0 +1,343 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Prescription statuses. An active prescription can be put on hold and
+// resumed; it ends when it is completed or cancelled.
+const (
+	PrescriptionActive    = "active"
+	PrescriptionOnHold    = "on_hold"
+	PrescriptionCompleted = "completed"
+	PrescriptionCancelled = "cancelled"
+)
+
+// prescriptionTransitions lists the statuses each status can move to.
+var prescriptionTransitions = map[string][]string{
+	PrescriptionActive: {PrescriptionOnHold, PrescriptionCompleted, PrescriptionCancelled},
+	PrescriptionOnHold: {PrescriptionActive, PrescriptionCancelled},
+}
+
+var prescriptionStatuses = map[string]bool{
+	PrescriptionActive:    true,
+	PrescriptionOnHold:    true,
+	PrescriptionCompleted: true,
+	PrescriptionCancelled: true,
+}
+
+// CanTransitionPrescription reports whether a prescription may move from one
+// status to another.
+func CanTransitionPrescription(from, to string) bool {
+	for _, s := range prescriptionTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Refill request statuses.
+const (
+	RefillPending  = "pending"
+	RefillApproved = "approved"
+	RefillDenied   = "denied"
+)
+
+// Routes a medication can be given by.
+var medicationRoutes = map[string]bool{
+	"oral":          true,
+	"sublingual":    true,
+	"buccal":        true,
+	"topical":       true,
+	"transdermal":   true,
+	"inhaled":       true,
+	"nasal":         true,
+	"ophthalmic":    true,
+	"otic":          true,
+	"rectal":        true,
+	"vaginal":       true,
+	"subcutaneous":  true,
+	"intramuscular": true,
+	"intravenous":   true,
+	"other":         true,
+}
+
+// prescribingProfessions are the professions whose practitioners may
+// prescribe.
+var prescribingProfessions = map[string]bool{
+	"doctor":     true,
+	"nurse":      true,
+	"midwife":    true,
+	"pharmacist": true,
+}
+
+// CanPrescribe reports whether practitioners of a profession may prescribe.
+func CanPrescribe(profession string) bool {
+	return prescribingProfessions[profession]
+}
+
+const (
+	// MaxRefills caps the refills of a prescription.
+	MaxRefills = 12
+
+	maxQuantity     = 10000
+	maxDurationDays = 365
+)
+
+// Prescriber is the practitioner record of a user in the organisation they
+// are signed in to. LicensedUntil is the last expiry date of their
+// licences, if they have any.
+type Prescriber struct {
+	PractitionerID uuid.UUID
+	Profession     string
+	LicensedUntil  *string
+}
+
+// Prescription is a medication prescribed to a patient. RxCUI links it to
+// the medication catalogue, when the drug was chosen from it.
+type Prescription struct {
+	ID               uuid.UUID  `json:"id"`
+	OrganisationID   uuid.UUID  `json:"organisation_id"`
+	PatientID        uuid.UUID  `json:"patient_id"`
+	PatientName      string     `json:"patient_name"`
+	PrescriberID     uuid.UUID  `json:"prescriber_id"`
+	PrescriberName   string     `json:"prescriber_name"`
+	RxCUI            *string    `json:"rxcui"`
+	Drug             string     `json:"drug"`
+	Strength         *string    `json:"strength"`
+	Dose             string     `json:"dose"`
+	Route            string     `json:"route"`
+	Frequency        string     `json:"frequency"`
+	DurationDays     *int       `json:"duration_days"`
+	Quantity         int        `json:"quantity"`
+	Refills          int        `json:"refills"`
+	RefillsRemaining int        `json:"refills_remaining"`
+	Instructions     *string    `json:"instructions"`
+	OverrideReason   *string    `json:"override_reason"`
+	Status           string     `json:"status"`
+	StatusReason     *string    `json:"status_reason"`
+	StatusChangedAt  *time.Time `json:"status_changed_at"`
+	CreatedBy        *uuid.UUID `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Conflicts are those found when the prescription was created, and are
+	// only returned then.
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+
+	// The logins of the prescriber and of the patient, if it has one, which
+	// decide who else may see the prescription.
+	PrescriberUserID uuid.UUID  `json:"-"`
+	PatientUserID    *uuid.UUID `json:"-"`
+}
+
+// PaginatedPrescriptionsResponse wraps a prescription list with pagination
+// metadata.
+type PaginatedPrescriptionsResponse struct {
+	Items []Prescription `json:"items"`
+	Meta  PaginationMeta `json:"meta"`
+}
+
+// CreatePrescription prescribes a medication to a patient. Drug may be left
+// out when RxCUI is given, and is then the catalogue name. OverrideReason is
+// required to prescribe despite a high-severity conflict.
+type CreatePrescription struct {
+	PatientID      uuid.UUID `json:"patient_id"`
+	RxCUI          string    `json:"rxcui"`
+	Drug           string    `json:"drug"`
+	Strength       string    `json:"strength"`
+	Dose           string    `json:"dose"`
+	Route          string    `json:"route"`
+	Frequency      string    `json:"frequency"`
+	DurationDays   *int      `json:"duration_days"`
+	Quantity       int       `json:"quantity"`
+	Refills        int       `json:"refills"`
+	Instructions   string    `json:"instructions"`
+	OverrideReason string    `json:"override_reason"`
+}
+
+func (m *CreatePrescription) Validate() error {
+	var errs ValidationErrors
+
+	if m.PatientID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "patient_id", Message: "patient is required"})
+	}
+
+	m.RxCUI = strings.TrimSpace(m.RxCUI)
+	if len(m.RxCUI) > 20 {
+		errs = append(errs, FieldError{Field: "rxcui", Message: "rxcui must be at most 20 characters"})
+	}
+
+	m.Drug = strings.TrimSpace(m.Drug)
+	if m.Drug == "" && m.RxCUI == "" {
+		errs = append(errs, FieldError{Field: "drug", Message: "drug or rxcui is required"})
+	} else if len(m.Drug) > 200 {
+		errs = append(errs, FieldError{Field: "drug", Message: "drug must be at most 200 characters"})
+	}
+
+	m.Strength = strings.TrimSpace(m.Strength)
+	if len(m.Strength) > 50 {
+		errs = append(errs, FieldError{Field: "strength", Message: "strength must be at most 50 characters"})
+	}
+
+	m.Dose = strings.TrimSpace(m.Dose)
+	if m.Dose == "" {
+		errs = append(errs, FieldError{Field: "dose", Message: "dose is required"})
+	} else if len(m.Dose) > 100 {
+		errs = append(errs, FieldError{Field: "dose", Message: "dose must be at most 100 characters"})
+	}
+
+	m.Route = strings.ToLower(strings.TrimSpace(m.Route))
+	if m.Route == "" {
+		errs = append(errs, FieldError{Field: "route", Message: "route is required"})
+	} else if !medicationRoutes[m.Route] {
+		errs = append(errs, FieldError{Field: "route", Message: "unknown route"})
+	}
+
+	m.Frequency = strings.TrimSpace(m.Frequency)
+	if m.Frequency == "" {
+		errs = append(errs, FieldError{Field: "frequency", Message: "frequency is required"})
+	} else if len(m.Frequency) > 100 {
+		errs = append(errs, FieldError{Field: "frequency", Message: "frequency must be at most 100 characters"})
+	}
+
+	if m.DurationDays != nil && (*m.DurationDays < 1 || *m.DurationDays > maxDurationDays) {
+		errs = append(errs, FieldError{Field: "duration_days", Message: fmt.Sprintf("duration_days must be between 1 and %d", maxDurationDays)})
+	}
+
+	if m.Quantity < 1 || m.Quantity > maxQuantity {
+		errs = append(errs, FieldError{Field: "quantity", Message: fmt.Sprintf("quantity must be between 1 and %d", maxQuantity)})
+	}
+
+	if m.Refills < 0 || m.Refills > MaxRefills {
+		errs = append(errs, FieldError{Field: "refills", Message: fmt.Sprintf("refills must be between 0 and %d", MaxRefills)})
+	}
+
+	m.Instructions = strings.TrimSpace(m.Instructions)
+	if len(m.Instructions) > 500 {
+		errs = append(errs, FieldError{Field: "instructions", Message: "instructions must be at most 500 characters"})
+	}
+
+	m.OverrideReason = strings.TrimSpace(m.OverrideReason)
+	if len(m.OverrideReason) > 500 {
+		errs = append(errs, FieldError{Field: "override_reason", Message: "override_reason must be at most 500 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ChangePrescriptionStatus moves a prescription on. Putting it on hold and
+// cancelling require a reason.
+type ChangePrescriptionStatus struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+func (m *ChangePrescriptionStatus) Validate() error {
+	var errs ValidationErrors
+
+	m.Status = strings.ToLower(strings.TrimSpace(m.Status))
+	m.Reason = strings.TrimSpace(m.Reason)
+
+	switch m.Status {
+	case PrescriptionOnHold, PrescriptionCancelled:
+		if m.Reason == "" {
+			errs = append(errs, FieldError{Field: "reason", Message: "reason is required"})
+		}
+	case PrescriptionActive, PrescriptionCompleted:
+	case "":
+		errs = append(errs, FieldError{Field: "status", Message: "status is required"})
+	default:
+		errs = append(errs, FieldError{Field: "status", Message: "status must be active, on_hold, completed or cancelled"})
+	}
+
+	if len(m.Reason) > 500 {
+		errs = append(errs, FieldError{Field: "reason", Message: "reason must be at most 500 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// PrescriptionFilter narrows a prescription list. ParticipantID is set by
+// the service to limit the list to the prescriptions of a prescriber's or
+// patient's login.
+type PrescriptionFilter struct {
+	PatientID     *uuid.UUID
+	PrescriberID  *uuid.UUID
+	Status        string
+	ParticipantID *uuid.UUID
+}
+
+func (f *PrescriptionFilter) Validate() error {
+	f.Status = strings.ToLower(strings.TrimSpace(f.Status))
+	if f.Status != "" && !prescriptionStatuses[f.Status] {
+		return ValidationErrors{FieldError{Field: "status", Message: "unknown status"}}
+	}
+	return nil
+}
+
+// RefillRequest is a patient's request for a refill of a prescription.
+// DecidedBy is the prescriber who approved or denied it.
+type RefillRequest struct {
+	ID             uuid.UUID  `json:"id"`
+	PrescriptionID uuid.UUID  `json:"prescription_id"`
+	Status         string     `json:"status"`
+	Note           *string    `json:"note"`
+	RequestedBy    *uuid.UUID `json:"requested_by"`
+	DecidedBy      *uuid.UUID `json:"decided_by"`
+	DecisionNote   *string    `json:"decision_note"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DecidedAt      *time.Time `json:"decided_at"`
+}
+
+// CreateRefillRequest asks for a refill, with an optional note for the
+// prescriber.
+type CreateRefillRequest struct {
+	Note string `json:"note"`
+}
+
+func (m *CreateRefillRequest) Validate() error {
+	m.Note = strings.TrimSpace(m.Note)
+	if len(m.Note) > 500 {
+		return ValidationErrors{FieldError{Field: "note", Message: "note must be at most 500 characters"}}
+	}
+	return nil
+}
+
+// DecideRefillRequest approves or denies a refill request. Denying requires
+// a note for the patient.
+type DecideRefillRequest struct {
+	Approve *bool  `json:"approve"`
+	Note    string `json:"note"`
+}
+
+func (m *DecideRefillRequest) Validate() error {
+	var errs ValidationErrors
+
+	if m.Approve == nil {
+		errs = append(errs, FieldError{Field: "approve", Message: "approve is required"})
+	}
+
+	m.Note = strings.TrimSpace(m.Note)
+	if m.Approve != nil && !*m.Approve && m.Note == "" {
+		errs = append(errs, FieldError{Field: "note", Message: "note is required when denying"})
+	} else if len(m.Note) > 500 {
+		errs = append(errs, FieldError{Field: "note", Message: "note must be at most 500 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
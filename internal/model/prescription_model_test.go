@@ -0,0 +1,136 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCanTransitionPrescription(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{PrescriptionActive, PrescriptionOnHold, true},
+		{PrescriptionActive, PrescriptionCompleted, true},
+		{PrescriptionActive, PrescriptionCancelled, true},
+		{PrescriptionOnHold, PrescriptionActive, true},
+		{PrescriptionOnHold, PrescriptionCancelled, true},
+		{PrescriptionOnHold, PrescriptionCompleted, false},
+		{PrescriptionCompleted, PrescriptionActive, false},
+		{PrescriptionCancelled, PrescriptionActive, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransitionPrescription(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransitionPrescription(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCreatePrescription_Validate(t *testing.T) {
+	patientID, _ := uuid.NewV7()
+	valid := func() CreatePrescription {
+		return CreatePrescription{
+			PatientID: patientID,
+			Drug:      " Amoxicillin ",
+			Strength:  "500 mg",
+			Dose:      "1 capsule",
+			Route:     "Oral",
+			Frequency: "three times daily",
+			Quantity:  21,
+			Refills:   1,
+		}
+	}
+	days := func(n int) *int { return &n }
+
+	tests := []struct {
+		name        string
+		edit        func(m *CreatePrescription)
+		expectField string
+	}{
+		{name: "valid", edit: func(m *CreatePrescription) {}},
+		{name: "catalogue drug without a name", edit: func(m *CreatePrescription) { m.Drug = ""; m.RxCUI = "308191" }},
+		{name: "no drug", edit: func(m *CreatePrescription) { m.Drug = " " }, expectField: "drug"},
+		{name: "no patient", edit: func(m *CreatePrescription) { m.PatientID = uuid.Nil }, expectField: "patient_id"},
+		{name: "no dose", edit: func(m *CreatePrescription) { m.Dose = "" }, expectField: "dose"},
+		{name: "unknown route", edit: func(m *CreatePrescription) { m.Route = "by mouth" }, expectField: "route"},
+		{name: "no frequency", edit: func(m *CreatePrescription) { m.Frequency = "" }, expectField: "frequency"},
+		{name: "no quantity", edit: func(m *CreatePrescription) { m.Quantity = 0 }, expectField: "quantity"},
+		{name: "negative refills", edit: func(m *CreatePrescription) { m.Refills = -1 }, expectField: "refills"},
+		{name: "too many refills", edit: func(m *CreatePrescription) { m.Refills = MaxRefills + 1 }, expectField: "refills"},
+		{name: "zero duration", edit: func(m *CreatePrescription) { m.DurationDays = days(0) }, expectField: "duration_days"},
+		{name: "duration", edit: func(m *CreatePrescription) { m.DurationDays = days(7) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := valid()
+			tt.edit(&data)
+			err := data.Validate()
+
+			if tt.expectField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if data.Route != "oral" {
+					t.Errorf("route = %q, want it lower-cased", data.Route)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			for _, fe := range verrs {
+				if fe.Field == tt.expectField {
+					return
+				}
+			}
+			t.Errorf("expected an error on %s, got %v", tt.expectField, verrs)
+		})
+	}
+}
+
+func TestChangePrescriptionStatus_Validate(t *testing.T) {
+	tests := []struct {
+		data    ChangePrescriptionStatus
+		wantErr bool
+	}{
+		{ChangePrescriptionStatus{Status: "completed"}, false},
+		{ChangePrescriptionStatus{Status: "Active"}, false},
+		{ChangePrescriptionStatus{Status: "on_hold"}, true},
+		{ChangePrescriptionStatus{Status: "on_hold", Reason: "awaiting blood results"}, false},
+		{ChangePrescriptionStatus{Status: "cancelled"}, true},
+		{ChangePrescriptionStatus{Status: "dispensed"}, true},
+		{ChangePrescriptionStatus{}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.data.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, want error %v", tt.data, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDecideRefillRequest_Validate(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		data    DecideRefillRequest
+		wantErr bool
+	}{
+		{DecideRefillRequest{Approve: &yes}, false},
+		{DecideRefillRequest{Approve: &no}, true},
+		{DecideRefillRequest{Approve: &no, Note: "please book a review"}, false},
+		{DecideRefillRequest{Note: "ok"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.data.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, want error %v", tt.data, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Profile field limits and formats.
+var (
+	e164Pattern        = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+	languageTagPattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	countryPattern     = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// DateLayout is the wire format of calendar dates such as date_of_birth.
+const DateLayout = "2006-01-02"
+
+// Administrative sex values, as used by HL7 FHIR.
+var sexValues = map[string]bool{"male": true, "female": true, "other": true, "unknown": true}
+
+// Address is a postal address. Country is an ISO 3166-1 alpha-2 code.
+type Address struct {
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country"`
+}
+
+// IsZero reports whether no field of a is set.
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
+
+// Validate checks a merged address. Line 1, city and country are required
+// unless the address is empty, which clears it.
+func (a Address) Validate() error {
+	if a.IsZero() {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if a.Line1 == "" {
+		errs = append(errs, FieldError{Field: "address.line1", Message: "address line 1 is required"})
+	}
+	if a.City == "" {
+		errs = append(errs, FieldError{Field: "address.city", Message: "city is required"})
+	}
+	if a.Country == "" {
+		errs = append(errs, FieldError{Field: "address.country", Message: "country is required"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// check normalizes a complete address and checks it like a patch that sets
+// every field.
+func (a *Address) check() ValidationErrors {
+	present := func(v string) Optional[string] { return Optional[string]{Value: v, Set: true} }
+	p := AddressPatch{
+		Line1:      present(a.Line1),
+		Line2:      present(a.Line2),
+		City:       present(a.City),
+		Region:     present(a.Region),
+		PostalCode: present(a.PostalCode),
+		Country:    present(a.Country),
+	}
+
+	errs := p.validate()
+	*a = p.Apply(nil)
+	if len(errs) > 0 {
+		return errs
+	}
+	if err := a.Validate(); err != nil {
+		return err.(ValidationErrors)
+	}
+	return nil
+}
+
+// AddressPatch is the merge patch form of Address.
+type AddressPatch struct {
+	Line1      Optional[string] `json:"line1"`
+	Line2      Optional[string] `json:"line2"`
+	City       Optional[string] `json:"city"`
+	Region     Optional[string] `json:"region"`
+	PostalCode Optional[string] `json:"postal_code"`
+	Country    Optional[string] `json:"country"`
+}
+
+// Apply returns current with the patch merged in. A nil current is an empty
+// address.
+func (p *AddressPatch) Apply(current *Address) Address {
+	var a Address
+	if current != nil {
+		a = *current
+	}
+
+	for _, f := range []struct {
+		patch Optional[string]
+		dst   *string
+	}{
+		{p.Line1, &a.Line1},
+		{p.Line2, &a.Line2},
+		{p.City, &a.City},
+		{p.Region, &a.Region},
+		{p.PostalCode, &a.PostalCode},
+		{p.Country, &a.Country},
+	} {
+		if f.patch.Set {
+			*f.dst = f.patch.Value
+		}
+	}
+
+	return a
+}
+
+func (p *AddressPatch) validate() ValidationErrors {
+	var errs ValidationErrors
+
+	for _, f := range []struct {
+		field string
+		value *Optional[string]
+		max   int
+	}{
+		{"address.line1", &p.Line1, 100},
+		{"address.line2", &p.Line2, 100},
+		{"address.city", &p.City, 100},
+		{"address.region", &p.Region, 100},
+		{"address.postal_code", &p.PostalCode, 20},
+	} {
+		normalizeOptional(f.value)
+		if len(f.value.Value) > f.max {
+			errs = append(errs, FieldError{Field: f.field, Message: "must be at most " + strconv.Itoa(f.max) + " characters"})
+		}
+	}
+
+	normalizeOptional(&p.Country)
+	p.Country.Value = strings.ToUpper(p.Country.Value)
+	if p.Country.HasValue() && !countryPattern.MatchString(p.Country.Value) {
+		errs = append(errs, FieldError{Field: "address.country", Message: "country must be an ISO 3166-1 alpha-2 code"})
+	}
+
+	return errs
+}
+
+// normalizeOptional trims a present string and turns an empty one into null.
+func normalizeOptional(o *Optional[string]) {
+	if !o.Set || o.Null {
+		return
+	}
+	o.Value = strings.TrimSpace(o.Value)
+	if o.Value == "" {
+		o.Null = true
+	}
+}
+
+// validateProfile checks the optional contact and demographic members of a
+// patch.
+func (m *UpdateUser) validateProfile() ValidationErrors {
+	errs := demographics{
+		Phone:             &m.Phone,
+		DateOfBirth:       &m.DateOfBirth,
+		Sex:               &m.Sex,
+		Gender:            &m.Gender,
+		PreferredLanguage: &m.PreferredLanguage,
+	}.validate()
+
+	if m.Address.HasValue() {
+		errs = append(errs, m.Address.Value.validate()...)
+	}
+
+	return errs
+}
+
+// demographics are the contact and demographic fields shared by users and
+// patients.
+type demographics struct {
+	Phone             *Optional[string]
+	DateOfBirth       *Optional[string]
+	Sex               *Optional[string]
+	Gender            *Optional[string]
+	PreferredLanguage *Optional[string]
+}
+
+// ValidPhone reports whether s is a phone number in E.164 format, as profiles
+// store them.
+func ValidPhone(s string) bool {
+	return e164Pattern.MatchString(s)
+}
+
+// validate normalizes and checks the fields that are present.
+func (d demographics) validate() ValidationErrors {
+	var errs ValidationErrors
+
+	if d.Phone.Set {
+		d.Phone.Value = strings.ReplaceAll(d.Phone.Value, " ", "")
+		normalizeOptional(d.Phone)
+		if d.Phone.HasValue() && !ValidPhone(d.Phone.Value) {
+			errs = append(errs, FieldError{Field: "phone", Message: "phone must be in E.164 format, e.g. +14155552671"})
+		}
+	}
+
+	normalizeOptional(d.DateOfBirth)
+	if d.DateOfBirth.HasValue() {
+		t, err := time.Parse(DateLayout, d.DateOfBirth.Value)
+		switch {
+		case err != nil:
+			errs = append(errs, FieldError{Field: "date_of_birth", Message: "date of birth must be YYYY-MM-DD"})
+		case t.After(time.Now()):
+			errs = append(errs, FieldError{Field: "date_of_birth", Message: "date of birth must not be in the future"})
+		case t.Year() < 1900:
+			errs = append(errs, FieldError{Field: "date_of_birth", Message: "date of birth must not be before 1900"})
+		}
+	}
+
+	normalizeOptional(d.Sex)
+	d.Sex.Value = strings.ToLower(d.Sex.Value)
+	if d.Sex.HasValue() && !sexValues[d.Sex.Value] {
+		errs = append(errs, FieldError{Field: "sex", Message: "sex must be male, female, other or unknown"})
+	}
+
+	normalizeOptional(d.Gender)
+	if len(d.Gender.Value) > 50 {
+		errs = append(errs, FieldError{Field: "gender", Message: "gender must be at most 50 characters"})
+	}
+
+	normalizeOptional(d.PreferredLanguage)
+	if lang := d.PreferredLanguage.Value; d.PreferredLanguage.HasValue() && (len(lang) > 35 || !languageTagPattern.MatchString(lang)) {
+		errs = append(errs, FieldError{Field: "preferred_language", Message: "preferred language must be a BCP 47 tag, e.g. en-GB"})
+	}
+
+	return errs
+}
+
+// AvatarContentTypes lists the image types accepted as avatars. The type is
+// sniffed from the uploaded bytes, not taken from the request.
+var AvatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// AvatarKey is the blob key of a user's avatar. Each user has at most one,
+// so uploads replace it in place.
+func AvatarKey(userID uuid.UUID) string {
+	return "avatars/" + userID.String()
+}
+
+// Avatar describes a stored avatar image.
+type Avatar struct {
+	ContentType string
+	UpdatedAt   time.Time
+}
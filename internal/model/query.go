@@ -0,0 +1,117 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SortField is one key of a multi-field sort. Desc selects descending order.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma separated sort spec such as "last_name,-created_at".
+// A leading "-" sorts descending, an optional leading "+" ascending. Every
+// field must be present in allowed and may appear only once.
+func ParseSort(raw string, allowed map[string]bool) ([]SortField, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		desc := false
+		if strings.HasPrefix(part, "-") {
+			desc = true
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+
+		if part == "" || !allowed[part] {
+			return nil, ValidationErrors{FieldError{Field: "sort", Message: "unsupported sort field"}}
+		}
+		if seen[part] {
+			return nil, ValidationErrors{FieldError{Field: "sort", Message: "duplicate sort field"}}
+		}
+		seen[part] = true
+
+		fields = append(fields, SortField{Field: part, Desc: desc})
+	}
+
+	return fields, nil
+}
+
+// Cursor marks a position in a keyset paginated listing ordered by id. Since
+// ids are UUIDv7 this is also creation order. Before selects the page that
+// ends just before ID instead of the one that starts just after it.
+// Inclusive puts ID itself on the page.
+type Cursor struct {
+	ID        uuid.UUID `json:"id"`
+	Before    bool      `json:"before,omitempty"`
+	Inclusive bool      `json:"inclusive,omitempty"`
+}
+
+// Encode returns the opaque form of c handed out to clients.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	invalid := ValidationErrors{FieldError{Field: "cursor", Message: "invalid cursor"}}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == uuid.Nil {
+		return nil, invalid
+	}
+
+	return &c, nil
+}
+
+// CursorParams holds the position and page size for keyset pagination. A nil
+// Cursor requests the first page.
+type CursorParams struct {
+	Cursor *Cursor
+	Limit  int
+}
+
+type CursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Precondition is a parsed If-Match header. Any is set for "*", which
+// matches every version of an existing resource.
+type Precondition struct {
+	Any      bool
+	Versions []int64
+}
+
+// Matches reports whether a resource at version satisfies p.
+func (p Precondition) Matches(version int64) bool {
+	if p.Any {
+		return true
+	}
+	for _, v := range p.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
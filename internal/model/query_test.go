@@ -0,0 +1,89 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseSort(t *testing.T) {
+	allowed := map[string]bool{"last_name": true, "created_at": true}
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    []SortField
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			raw:  "",
+			want: nil,
+		},
+		{
+			name: "single ascending",
+			raw:  "last_name",
+			want: []SortField{{Field: "last_name"}},
+		},
+		{
+			name: "multiple with direction",
+			raw:  "-created_at, +last_name",
+			want: []SortField{{Field: "created_at", Desc: true}, {Field: "last_name"}},
+		},
+		{
+			name:    "unknown field",
+			raw:     "password",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate field",
+			raw:     "last_name,-last_name",
+			wantErr: true,
+		},
+		{
+			name:    "empty segment",
+			raw:     "last_name,,created_at",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.raw, allowed)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	id, _ := uuid.NewV7()
+
+	for _, c := range []Cursor{{ID: id}, {ID: id, Before: true}} {
+		got, err := DecodeCursor(c.Encode())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if *got != c {
+			t.Errorf("got %+v want %+v", *got, c)
+		}
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, raw := range []string{"not base64!", "bm90IGpzb24", Cursor{}.Encode()} {
+		if _, err := DecodeCursor(raw); err == nil {
+			t.Errorf("expected error for %q, got nil", raw)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Account statuses. Only active accounts can sign in or use their tokens.
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended" // held by an admin, e.g. during an investigation
+	StatusLocked    = "locked"    // blocked for security reasons
+	StatusPending   = "pending"   // created but not yet activated
+)
+
+var accountStatuses = map[string]bool{StatusActive: true, StatusSuspended: true, StatusLocked: true, StatusPending: true}
+
+// AccountStatus is the effective status of an account. A suspension or lock
+// with an Until in the past has lapsed and reads as active.
+type AccountStatus struct {
+	Status    string     `json:"status"`
+	Reason    *string    `json:"reason"`
+	Until     *time.Time `json:"until"`
+	ChangedAt *time.Time `json:"changed_at"`
+	ChangedBy *uuid.UUID `json:"changed_by"`
+}
+
+// ChangeStatus is an admin request to change an account's status. Until is
+// optional and ends a suspension or lock automatically.
+type ChangeStatus struct {
+	Status string     `json:"status"`
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
+func (m *ChangeStatus) Validate() error {
+	var errs ValidationErrors
+
+	m.Status = strings.ToLower(strings.TrimSpace(m.Status))
+	m.Reason = strings.TrimSpace(m.Reason)
+
+	switch m.Status {
+	case StatusSuspended, StatusLocked:
+		if m.Reason == "" {
+			errs = append(errs, FieldError{Field: "reason", Message: "reason is required"})
+		}
+		if m.Until != nil && !m.Until.After(time.Now()) {
+			errs = append(errs, FieldError{Field: "until", Message: "until must be in the future"})
+		}
+	case StatusActive:
+		if m.Until != nil {
+			errs = append(errs, FieldError{Field: "until", Message: "until is only allowed when suspending or locking"})
+		}
+	case "":
+		errs = append(errs, FieldError{Field: "status", Message: "status is required"})
+	default:
+		errs = append(errs, FieldError{Field: "status", Message: "status must be active, suspended or locked"})
+	}
+
+	if len(m.Reason) > 500 {
+		errs = append(errs, FieldError{Field: "reason", Message: "reason must be at most 500 characters"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
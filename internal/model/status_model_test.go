@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeStatus_Validate(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		change   ChangeStatus
+		errField string
+	}{
+		{name: "suspend", change: ChangeStatus{Status: "Suspended", Reason: "investigation"}},
+		{name: "lock until", change: ChangeStatus{Status: "locked", Reason: "too many failed logins", Until: &future}},
+		{name: "reactivate", change: ChangeStatus{Status: "active"}},
+		{name: "missing status", change: ChangeStatus{}, errField: "status"},
+		{name: "pending not allowed", change: ChangeStatus{Status: "pending"}, errField: "status"},
+		{name: "unknown status", change: ChangeStatus{Status: "banned", Reason: "x"}, errField: "status"},
+		{name: "suspend without reason", change: ChangeStatus{Status: "suspended", Reason: "  "}, errField: "reason"},
+		{name: "until in the past", change: ChangeStatus{Status: "suspended", Reason: "x", Until: &past}, errField: "until"},
+		{name: "until on active", change: ChangeStatus{Status: "active", Until: &future}, errField: "until"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.change.Validate()
+			if tt.errField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			verrs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			for _, fe := range verrs {
+				if fe.Field == tt.errField {
+					return
+				}
+			}
+			t.Errorf("expected field %q in errors, got %v", tt.errField, verrs)
+		})
+	}
+}
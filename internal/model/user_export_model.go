@@ -0,0 +1,78 @@
+package model
+
+import "strings"
+
+// Formats of the streamed user export.
+const (
+	UserExportCSV    = "csv"
+	UserExportNDJSON = "ndjson"
+)
+
+// UserExportFields lists the fields the user export can include, in the
+// order they are offered.
+var UserExportFields = []string{
+	"id",
+	"first_name",
+	"last_name",
+	"email",
+	"role",
+	"status",
+	"organisation_id",
+	"phone",
+	"date_of_birth",
+	"sex",
+	"gender",
+	"preferred_language",
+	"address_line1",
+	"address_line2",
+	"address_city",
+	"address_region",
+	"address_postal_code",
+	"address_country",
+	"created_at",
+	"updated_at",
+	"deleted_at",
+}
+
+// DefaultUserExportFields are exported when no fields are requested.
+var DefaultUserExportFields = []string{"id", "first_name", "last_name", "email", "role", "status", "created_at"}
+
+// ParseUserExportFields parses a comma separated field list. Fields are
+// exported in the order given; an empty list selects the defaults.
+func ParseUserExportFields(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return DefaultUserExportFields, nil
+	}
+
+	allowed := map[string]bool{}
+	for _, f := range UserExportFields {
+		allowed[f] = true
+	}
+
+	var fields []string
+	seen := map[string]bool{}
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !allowed[f] {
+			return nil, ValidationErrors{FieldError{Field: "fields", Message: "unsupported field " + f}}
+		}
+		if seen[f] {
+			return nil, ValidationErrors{FieldError{Field: "fields", Message: "duplicate field " + f}}
+		}
+		seen[f] = true
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// ParseUserExportFormat returns the export format, csv by default.
+func ParseUserExportFormat(raw string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", UserExportCSV:
+		return UserExportCSV, nil
+	case UserExportNDJSON:
+		return UserExportNDJSON, nil
+	}
+	return "", ValidationErrors{FieldError{Field: "format", Message: "format must be csv or ndjson"}}
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"net/mail"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/google/uuid"
@@ -23,49 +24,66 @@ type CreateUser struct {
 	Password  string `json:"password"`
 }
 
+// LoginUser signs in to OrganisationID, or to the user's default
+// organisation when it is omitted. Super admins without one act across all
+// organisations.
 type LoginUser struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email          string     `json:"email"`
+	Password       string     `json:"password"`
+	OrganisationID *uuid.UUID `json:"organisation_id"`
 }
 
+// UpdateUser is a JSON Merge Patch (RFC 7396) of a user's profile. Absent
+// members are left unchanged and null clears an optional field. Empty strings
+// are treated like null. first_name and last_name cannot be cleared.
 type UpdateUser struct {
-	FirstName *string `json:"first_name"`
-	LastName  *string `json:"last_name"`
+	FirstName         Optional[string]       `json:"first_name"`
+	LastName          Optional[string]       `json:"last_name"`
+	Phone             Optional[string]       `json:"phone"`
+	DateOfBirth       Optional[string]       `json:"date_of_birth"`
+	Sex               Optional[string]       `json:"sex"`
+	Gender            Optional[string]       `json:"gender"`
+	PreferredLanguage Optional[string]       `json:"preferred_language"`
+	Address           Optional[AddressPatch] `json:"address"`
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (m *UpdateUser) IsEmpty() bool {
+	return !m.FirstName.Set && !m.LastName.Set && !m.Phone.Set && !m.DateOfBirth.Set &&
+		!m.Sex.Set && !m.Gender.Set && !m.PreferredLanguage.Set && !m.Address.Set
 }
 
 func (m *UpdateUser) Validate() error {
 	var errs ValidationErrors
 
 	if m == nil {
-		return ValidationErrors{FieldError{Field: "first_name", Message: "first name is required"}, FieldError{Field: "last_name", Message: "last name is required"}}
+		return ValidationErrors{FieldError{Field: "body", Message: "patch must be a JSON object"}}
 	}
 
-	if m.FirstName == nil {
-		errs = append(errs, FieldError{Field: "first_name", Message: "first name is required"})
-	} else {
-		trimmed := strings.TrimSpace(*m.FirstName)
-		m.FirstName = &trimmed
-		if trimmed == "" {
-			errs = append(errs, FieldError{Field: "first_name", Message: "first name is required"})
-		} else if !isValidName(trimmed) {
-			errs = append(errs, FieldError{Field: "first_name", Message: "invalid first name"})
-		}
+	errs = append(errs, validatePatchName(&m.FirstName, "first_name", "first name")...)
+	errs = append(errs, validatePatchName(&m.LastName, "last_name", "last name")...)
+	errs = append(errs, m.validateProfile()...)
+
+	if len(errs) > 0 {
+		return errs
 	}
+	return nil
+}
 
-	if m.LastName == nil {
-		errs = append(errs, FieldError{Field: "last_name", Message: "last name is required"})
-	} else {
-		trimmed := strings.TrimSpace(*m.LastName)
-		m.LastName = &trimmed
-		if trimmed == "" {
-			errs = append(errs, FieldError{Field: "last_name", Message: "last name is required"})
-		} else if !isValidName(trimmed) {
-			errs = append(errs, FieldError{Field: "last_name", Message: "invalid last name"})
-		}
+func validatePatchName(name *Optional[string], field, label string) ValidationErrors {
+	if !name.Set {
+		return nil
+	}
+	if name.Null {
+		return ValidationErrors{FieldError{Field: field, Message: label + " cannot be removed"}}
 	}
 
-	if len(errs) > 0 {
-		return errs
+	name.Value = strings.TrimSpace(name.Value)
+	if name.Value == "" {
+		return ValidationErrors{FieldError{Field: field, Message: label + " is required"}}
+	}
+	if !isValidName(name.Value) {
+		return ValidationErrors{FieldError{Field: field, Message: "invalid " + label}}
 	}
 	return nil
 }
@@ -84,12 +102,94 @@ type PaginationParams struct {
 	Limit int
 }
 
+// Deleted-state filters for the user listing.
+const (
+	DeletedExclude = "false" // active users only (default)
+	DeletedOnly    = "true"  // soft-deleted users only
+	DeletedInclude = "all"   // active and soft-deleted users
+)
+
+// Roles a user can hold.
+var userRoles = map[string]bool{"super_admin": true, "admin": true, "user": true}
+
+// UserSortFields lists the fields the user listing can be sorted by.
+var UserSortFields = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"email":      true,
+	"role":       true,
+	"created_at": true,
+	"deleted_at": true,
+}
+
+// UserFilter narrows and orders the user listing. The same filter is applied
+// to the item query and the total count.
+type UserFilter struct {
+	Search      string
+	Role        string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Deleted     string
+	Status      string
+	Sort        []SortField
+}
+
+func (f *UserFilter) Validate() error {
+	var errs ValidationErrors
+
+	f.Search = strings.TrimSpace(f.Search)
+	f.Role = strings.ToLower(strings.TrimSpace(f.Role))
+
+	if len(f.Search) > 100 {
+		errs = append(errs, FieldError{Field: "q", Message: "search must be at most 100 characters"})
+	}
+
+	if f.Role != "" && !userRoles[f.Role] {
+		errs = append(errs, FieldError{Field: "role", Message: "invalid role"})
+	}
+
+	if f.CreatedFrom != nil && f.CreatedTo != nil && f.CreatedFrom.After(*f.CreatedTo) {
+		errs = append(errs, FieldError{Field: "created_from", Message: "created_from must not be after created_to"})
+	}
+
+	f.Status = strings.ToLower(strings.TrimSpace(f.Status))
+	if f.Status != "" && !accountStatuses[f.Status] {
+		errs = append(errs, FieldError{Field: "status", Message: "invalid status"})
+	}
+
+	switch f.Deleted {
+	case "":
+		f.Deleted = DeletedExclude
+	case DeletedExclude, DeletedOnly, DeletedInclude:
+	default:
+		errs = append(errs, FieldError{Field: "deleted", Message: "deleted must be true, false or all"})
+	}
+
+	for _, s := range f.Sort {
+		if !UserSortFields[s.Field] {
+			errs = append(errs, FieldError{Field: "sort", Message: "unsupported sort field"})
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 // PaginatedUsersResponse wraps user list with pagination metadata.
 type PaginatedUsersResponse struct {
 	Items []GetAll       `json:"items"`
 	Meta  PaginationMeta `json:"meta"`
 }
 
+// CursorUsersResponse wraps a keyset paginated user list.
+type CursorUsersResponse struct {
+	Items []GetAll   `json:"items"`
+	Meta  CursorMeta `json:"meta"`
+}
+
 type PaginationMeta struct {
 	Page       int `json:"page"`
 	Limit      int `json:"limit"`
@@ -97,17 +197,60 @@ type PaginationMeta struct {
 	TotalPages int `json:"total_pages"`
 }
 
-type GetByID struct {
+// DeletedUser is a soft-deleted account awaiting restore or purge.
+type DeletedUser struct {
 	ID        uuid.UUID `json:"id"`
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
 	Email     string    `json:"email"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// PaginatedDeletedUsersResponse wraps the deleted user list with pagination metadata.
+type PaginatedDeletedUsersResponse struct {
+	Items []DeletedUser  `json:"items"`
+	Meta  PaginationMeta `json:"meta"`
+}
+
+// Purge modes for soft-deleted accounts past their retention period.
+const (
+	PurgeModeAnonymise = "anonymise" // keep the row, overwrite personal data
+	PurgeModeDelete    = "delete"    // remove the row
+)
+
+// PurgedUser is an account removed by the purge job and the mode it was
+// purged with. Accounts that clinical records still point to, such as those
+// of practitioners, are anonymised even in delete mode.
+type PurgedUser struct {
+	ID   uuid.UUID
+	Mode string
+}
+
+type GetByID struct {
+	ID                uuid.UUID `json:"id"`
+	FirstName         string    `json:"first_name"`
+	LastName          string    `json:"last_name"`
+	Email             string    `json:"email"`
+	Status            string    `json:"status"`
+	Phone             *string   `json:"phone"`
+	DateOfBirth       *string   `json:"date_of_birth"`
+	Sex               *string   `json:"sex"`
+	Gender            *string   `json:"gender"`
+	PreferredLanguage *string   `json:"preferred_language"`
+	Address           *Address  `json:"address"`
+	AvatarURL         *string   `json:"avatar_url"`
+
+	AvatarUpdatedAt *time.Time `json:"-"`
+	// Version is carried in the ETag. It changes with the row and when a
+	// suspension or lock lapses.
+	Version int64 `json:"-"`
 }
 
 type GetByEmail struct {
 	ID       uuid.UUID
 	Password string
 	Role     string
+	Status   string
 }
 
 type DeleteUser struct {
@@ -166,20 +309,33 @@ func (m *CreateUser) Validate() error {
 		}
 	}
 
-	if m.Password == "" {
+	errs = append(errs, validateNewPassword(m.Password)...)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateNewPassword applies the password rules for new accounts.
+func validateNewPassword(password string) ValidationErrors {
+	var errs ValidationErrors
+
+	if password == "" {
 		errs = append(errs, FieldError{
 			Field:   "password",
 			Message: "password is required",
 		})
 	} else {
-		if len(m.Password) < 8 {
+		if len(password) < 8 {
 			errs = append(errs, FieldError{
 				Field:   "password",
 				Message: "password must be at least 8 characters",
 			})
 		}
 
-		if !isValidPassword(m.Password) {
+		if !isValidPassword(password) {
 			errs = append(errs, FieldError{
 				Field:   "password",
 				Message: "password must contain upper, lower, digit and special character",
@@ -187,11 +343,7 @@ func (m *CreateUser) Validate() error {
 		}
 	}
 
-	if len(errs) > 0 {
-		return errs
-	}
-
-	return nil
+	return errs
 }
 
 func (m *LoginUser) Validate() error {
@@ -1,8 +1,11 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCreateUser_Validate(t *testing.T) {
@@ -234,10 +237,11 @@ func TestLoginUser_Validate(t *testing.T) {
 }
 
 func TestUpdateUser_Validate(t *testing.T) {
-	validFirst := "John"
-	validLast := "Doe"
-	invalidName := "John123"
-	empty := ""
+	validFirst := set("John")
+	validLast := set("Doe")
+	invalidName := set("John123")
+	empty := set("")
+	null := Optional[string]{Set: true, Null: true}
 
 	tests := []struct {
 		name     string
@@ -248,8 +252,8 @@ func TestUpdateUser_Validate(t *testing.T) {
 		{
 			name: "valid",
 			user: &UpdateUser{
-				FirstName: &validFirst,
-				LastName:  &validLast,
+				FirstName: validFirst,
+				LastName:  validLast,
 			},
 			wantErr: false,
 		},
@@ -257,22 +261,20 @@ func TestUpdateUser_Validate(t *testing.T) {
 			name:     "nil",
 			user:     nil,
 			wantErr:  true,
-			errField: "first_name",
+			errField: "body",
 		},
 		{
-			name: "nil first name",
+			name: "absent first name",
 			user: &UpdateUser{
-				FirstName: nil,
-				LastName:  &validLast,
+				LastName: validLast,
 			},
-			wantErr:  true,
-			errField: "first_name",
+			wantErr: false,
 		},
 		{
-			name: "nil last name",
+			name: "null last name",
 			user: &UpdateUser{
-				FirstName: &validFirst,
-				LastName:  nil,
+				FirstName: validFirst,
+				LastName:  null,
 			},
 			wantErr:  true,
 			errField: "last_name",
@@ -280,8 +282,8 @@ func TestUpdateUser_Validate(t *testing.T) {
 		{
 			name: "empty first name",
 			user: &UpdateUser{
-				FirstName: &empty,
-				LastName:  &validLast,
+				FirstName: empty,
+				LastName:  validLast,
 			},
 			wantErr:  true,
 			errField: "first_name",
@@ -289,8 +291,8 @@ func TestUpdateUser_Validate(t *testing.T) {
 		{
 			name: "whitespace first name",
 			user: &UpdateUser{
-				FirstName: strPtr("   "),
-				LastName:  &validLast,
+				FirstName: set("   "),
+				LastName:  validLast,
 			},
 			wantErr:  true,
 			errField: "first_name",
@@ -298,12 +300,103 @@ func TestUpdateUser_Validate(t *testing.T) {
 		{
 			name: "invalid first name",
 			user: &UpdateUser{
-				FirstName: &invalidName,
-				LastName:  &validLast,
+				FirstName: invalidName,
+				LastName:  validLast,
 			},
 			wantErr:  true,
 			errField: "first_name",
 		},
+		{
+			name: "full profile",
+			user: &UpdateUser{
+				FirstName:         validFirst,
+				LastName:          validLast,
+				Phone:             set("+44 20 7946 0958"),
+				DateOfBirth:       set("1980-04-12"),
+				Sex:               set("Female"),
+				Gender:            set("woman"),
+				PreferredLanguage: set("en-GB"),
+				Address:           Optional[AddressPatch]{Set: true, Value: AddressPatch{Line1: set("1 High St"), City: set("London"), Country: set("gb")}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "cleared profile fields",
+			user: &UpdateUser{
+				FirstName:   validFirst,
+				LastName:    validLast,
+				Phone:       null,
+				DateOfBirth: empty,
+				Sex:         null,
+				Address:     Optional[AddressPatch]{Set: true, Null: true},
+			},
+			wantErr: false,
+		},
+		{
+			name: "phone not e164",
+			user: &UpdateUser{
+				FirstName: validFirst,
+				LastName:  validLast,
+				Phone:     set("020 7946 0958"),
+			},
+			wantErr:  true,
+			errField: "phone",
+		},
+		{
+			name: "date of birth in the future",
+			user: &UpdateUser{
+				FirstName:   validFirst,
+				LastName:    validLast,
+				DateOfBirth: set(time.Now().AddDate(1, 0, 0).Format(DateLayout)),
+			},
+			wantErr:  true,
+			errField: "date_of_birth",
+		},
+		{
+			name: "date of birth malformed",
+			user: &UpdateUser{
+				FirstName:   validFirst,
+				LastName:    validLast,
+				DateOfBirth: set("12/04/1980"),
+			},
+			wantErr:  true,
+			errField: "date_of_birth",
+		},
+		{
+			name: "invalid sex",
+			user: &UpdateUser{
+				FirstName: validFirst,
+				LastName:  validLast,
+				Sex:       set("x"),
+			},
+			wantErr:  true,
+			errField: "sex",
+		},
+		{
+			name: "invalid language",
+			user: &UpdateUser{
+				FirstName:         validFirst,
+				LastName:          validLast,
+				PreferredLanguage: set("english"),
+			},
+			wantErr:  true,
+			errField: "preferred_language",
+		},
+		{
+			name: "partial address",
+			user: &UpdateUser{
+				Address: Optional[AddressPatch]{Set: true, Value: AddressPatch{PostalCode: set("SW1A 1AA")}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid country",
+			user: &UpdateUser{
+				Address: Optional[AddressPatch]{Set: true, Value: AddressPatch{Country: set("GBR")}},
+			},
+			wantErr:  true,
+			errField: "address.country",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -342,3 +435,198 @@ func TestUpdateUser_Validate(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+func set(s string) Optional[string] {
+	return Optional[string]{Value: s, Set: true}
+}
+
+func TestUserFilter_Validate(t *testing.T) {
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		filter   UserFilter
+		wantErr  bool
+		errField string
+	}{
+		{
+			name:    "empty defaults to active users",
+			filter:  UserFilter{},
+			wantErr: false,
+		},
+		{
+			name: "valid",
+			filter: UserFilter{
+				Search:      " doe ",
+				Role:        "Admin",
+				CreatedFrom: &jan,
+				CreatedTo:   &feb,
+				Deleted:     DeletedInclude,
+				Sort:        []SortField{{Field: "last_name"}, {Field: "created_at", Desc: true}},
+			},
+			wantErr: false,
+		},
+		{
+			name:     "invalid role",
+			filter:   UserFilter{Role: "root"},
+			wantErr:  true,
+			errField: "role",
+		},
+		{
+			name:     "inverted created range",
+			filter:   UserFilter{CreatedFrom: &feb, CreatedTo: &jan},
+			wantErr:  true,
+			errField: "created_from",
+		},
+		{
+			name:     "invalid deleted",
+			filter:   UserFilter{Deleted: "maybe"},
+			wantErr:  true,
+			errField: "deleted",
+		},
+		{
+			name:     "unsupported sort",
+			filter:   UserFilter{Sort: []SortField{{Field: "password"}}},
+			wantErr:  true,
+			errField: "sort",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				var vErrs ValidationErrors
+				if errors.As(err, &vErrs) && tt.errField != "" {
+					found := false
+					for _, fe := range vErrs {
+						if fe.Field == tt.errField {
+							found = true
+							break
+						}
+					}
+					if !found {
+						t.Errorf("expected field %q in errors, got %v", tt.errField, vErrs)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.filter.Deleted == "" {
+				t.Error("expected deleted filter to be defaulted")
+			}
+		})
+	}
+}
+
+func TestUpdateUser_ValidateNormalizesProfile(t *testing.T) {
+	u := &UpdateUser{
+		FirstName: set("John"),
+		LastName:  set("Doe"),
+		Phone:     set(" +44 20 7946 0958 "),
+		Sex:       set(" MALE "),
+		Address:   Optional[AddressPatch]{Set: true, Value: AddressPatch{Line1: set(" 1 High St "), City: set("London"), Country: set(" gb ")}},
+	}
+	if err := u.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Phone.Value != "+442079460958" {
+		t.Errorf("phone = %q", u.Phone.Value)
+	}
+	if u.Sex.Value != "male" {
+		t.Errorf("sex = %q", u.Sex.Value)
+	}
+	if u.Address.Value.Line1.Value != "1 High St" || u.Address.Value.Country.Value != "GB" {
+		t.Errorf("address = %+v", u.Address.Value)
+	}
+}
+
+func TestUpdateUser_MergePatchSemantics(t *testing.T) {
+	var patch UpdateUser
+	if err := json.Unmarshal([]byte(`{"first_name":" John ","phone":null,"gender":"","address":{"line2":null,"country":" gb "}}`), &patch); err != nil {
+		t.Fatal(err)
+	}
+	if err := patch.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !patch.FirstName.HasValue() || patch.FirstName.Value != "John" {
+		t.Errorf("first_name = %+v", patch.FirstName)
+	}
+	if patch.LastName.Set {
+		t.Error("absent last_name must not be set")
+	}
+	if !patch.Phone.Set || !patch.Phone.Null {
+		t.Errorf("phone should be an explicit null, got %+v", patch.Phone)
+	}
+	if !patch.Gender.Null {
+		t.Error("empty gender should be treated as null")
+	}
+	if patch.DateOfBirth.Set {
+		t.Error("absent date_of_birth must not be set")
+	}
+	if patch.IsEmpty() {
+		t.Error("patch should not be empty")
+	}
+
+	current := &Address{Line1: "1 High St", Line2: "Flat 2", City: "London", Country: "FR"}
+	merged := patch.Address.Value.Apply(current)
+	want := Address{Line1: "1 High St", City: "London", Country: "GB"}
+	if merged != want {
+		t.Errorf("merged address = %+v, want %+v", merged, want)
+	}
+	if current.Line2 != "Flat 2" {
+		t.Error("Apply must not modify the current address")
+	}
+}
+
+func TestAddress_Validate(t *testing.T) {
+	if err := (Address{}).Validate(); err != nil {
+		t.Errorf("empty address should be valid, got %v", err)
+	}
+	if err := (Address{Line1: "1 High St", City: "London", Country: "GB"}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (Address{PostalCode: "SW1A 1AA"}).Validate(); err == nil {
+		t.Error("expected error for incomplete address")
+	}
+}
+
+func TestParseUserExportFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected []string
+		errMsg   string
+	}{
+		{name: "default", raw: "", expected: DefaultUserExportFields},
+		{name: "order kept", raw: "email, ID,status", expected: []string{"email", "id", "status"}},
+		{name: "unknown field", raw: "id,password", errMsg: "unsupported field password"},
+		{name: "duplicate field", raw: "id,id", errMsg: "duplicate field id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseUserExportFields(tt.raw)
+
+			if tt.errMsg != "" {
+				verrs, ok := err.(ValidationErrors)
+				if !ok || verrs[0].Message != tt.errMsg {
+					t.Fatalf("expected %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Waitlist entry statuses. An entry is offered while one of its offers is
+// pending, and goes back to waiting if the offer is declined or lapses.
+const (
+	WaitlistWaiting   = "waiting"
+	WaitlistOffered   = "offered"
+	WaitlistBooked    = "booked"
+	WaitlistCancelled = "cancelled"
+)
+
+var waitlistStatuses = map[string]bool{
+	WaitlistWaiting:   true,
+	WaitlistOffered:   true,
+	WaitlistBooked:    true,
+	WaitlistCancelled: true,
+}
+
+// Waitlist offer statuses.
+const (
+	OfferPending  = "pending"
+	OfferAccepted = "accepted"
+	OfferDeclined = "declined"
+	OfferLapsed   = "lapsed"
+)
+
+// maxWaitlistDays is the longest span of dates a waitlist entry may cover.
+const maxWaitlistDays = 90
+
+// normaliseService trims and lower-cases a service name so that entries and
+// appointments compare equal regardless of how they were typed.
+func normaliseService(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// WaitlistEntry is a patient waiting for a slot with a practitioner that
+// starts between two dates of the practitioner's time zone. An entry with a
+// service only matches slots freed by appointments for that service.
+type WaitlistEntry struct {
+	ID               uuid.UUID  `json:"id"`
+	OrganisationID   uuid.UUID  `json:"organisation_id"`
+	PractitionerID   uuid.UUID  `json:"practitioner_id"`
+	PractitionerName string     `json:"practitioner_name"`
+	PatientID        uuid.UUID  `json:"patient_id"`
+	PatientName      string     `json:"patient_name"`
+	Service          *string    `json:"service"`
+	From             string     `json:"from"`
+	To               string     `json:"to"`
+	Status           string     `json:"status"`
+	CreatedBy        *uuid.UUID `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Offer is the pending offer of an entry that is offered a slot.
+	Offer *WaitlistOffer `json:"offer,omitempty"`
+
+	// The logins of the practitioner and of the patient, as for
+	// appointments.
+	PractitionerUserID uuid.UUID  `json:"-"`
+	PatientUserID      *uuid.UUID `json:"-"`
+}
+
+// WaitlistOffer is a freed slot offered to a waitlist entry. The slot is held
+// for the patient until ExpiresAt.
+type WaitlistOffer struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganisationID uuid.UUID  `json:"organisation_id"`
+	EntryID        uuid.UUID  `json:"entry_id"`
+	PractitionerID uuid.UUID  `json:"practitioner_id"`
+	PatientID      uuid.UUID  `json:"patient_id"`
+	StartsAt       time.Time  `json:"starts_at"`
+	EndsAt         time.Time  `json:"ends_at"`
+	Service        *string    `json:"service"`
+	Status         string     `json:"status"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AppointmentID  *uuid.UUID `json:"appointment_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	RespondedAt    *time.Time `json:"responded_at"`
+
+	PatientUserID *uuid.UUID `json:"-"`
+}
+
+// FreedSlot is a slot of a practitioner given up by an appointment, which
+// can be offered to the waitlist.
+type FreedSlot struct {
+	PractitionerID uuid.UUID
+	StartsAt       time.Time
+	EndsAt         time.Time
+	Service        *string
+}
+
+// PaginatedWaitlistResponse wraps a waitlist with pagination metadata.
+type PaginatedWaitlistResponse struct {
+	Items []WaitlistEntry `json:"items"`
+	Meta  PaginationMeta  `json:"meta"`
+}
+
+// JoinWaitlist puts a patient on the waitlist of a practitioner. From and To
+// are dates; To defaults to From.
+type JoinWaitlist struct {
+	PractitionerID uuid.UUID `json:"practitioner_id"`
+	PatientID      uuid.UUID `json:"patient_id"`
+	Service        string    `json:"service"`
+	From           string    `json:"from"`
+	To             string    `json:"to"`
+}
+
+func (m *JoinWaitlist) Validate() error {
+	var errs ValidationErrors
+
+	if m.PractitionerID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "practitioner_id", Message: "practitioner is required"})
+	}
+	if m.PatientID == uuid.Nil {
+		errs = append(errs, FieldError{Field: "patient_id", Message: "patient is required"})
+	}
+
+	m.Service = normaliseService(m.Service)
+	if len(m.Service) > 100 {
+		errs = append(errs, FieldError{Field: "service", Message: "service must be at most 100 characters"})
+	}
+
+	m.From = strings.TrimSpace(m.From)
+	m.To = strings.TrimSpace(m.To)
+	if m.To == "" {
+		m.To = m.From
+	}
+	from, fromErr := time.Parse(DateLayout, m.From)
+	to, toErr := time.Parse(DateLayout, m.To)
+	switch {
+	case m.From == "":
+		errs = append(errs, FieldError{Field: "from", Message: "from is required"})
+	case fromErr != nil:
+		errs = append(errs, FieldError{Field: "from", Message: "from must be a date (YYYY-MM-DD)"})
+	case toErr != nil:
+		errs = append(errs, FieldError{Field: "to", Message: "to must be a date (YYYY-MM-DD)"})
+	case to.Before(from):
+		errs = append(errs, FieldError{Field: "to", Message: "to must not be before from"})
+	case to.Sub(from) >= maxWaitlistDays*24*time.Hour:
+		errs = append(errs, FieldError{Field: "to", Message: "the waitlist covers at most 90 days"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// WaitlistFilter narrows a waitlist. ParticipantID is set by the service to
+// limit the list to the entries of a practitioner's or patient's login.
+type WaitlistFilter struct {
+	PractitionerID *uuid.UUID
+	PatientID      *uuid.UUID
+	Status         string
+	ParticipantID  *uuid.UUID
+}
+
+func (f *WaitlistFilter) Validate() error {
+	f.Status = strings.ToLower(strings.TrimSpace(f.Status))
+	if f.Status != "" && !waitlistStatuses[f.Status] {
+		return ValidationErrors{FieldError{Field: "status", Message: "unknown status"}}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestJoinWaitlist_Validate(t *testing.T) {
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name        string
+		data        JoinWaitlist
+		expectField string
+	}{
+		{name: "valid", data: JoinWaitlist{PractitionerID: practitionerID, PatientID: patientID, Service: " Physiotherapy ", From: "2026-11-02", To: "2026-11-20"}},
+		{name: "single day", data: JoinWaitlist{PractitionerID: practitionerID, PatientID: patientID, From: "2026-11-02"}},
+		{name: "missing practitioner", data: JoinWaitlist{PatientID: patientID, From: "2026-11-02"}, expectField: "practitioner_id"},
+		{name: "missing from", data: JoinWaitlist{PractitionerID: practitionerID, PatientID: patientID}, expectField: "from"},
+		{name: "bad date", data: JoinWaitlist{PractitionerID: practitionerID, PatientID: patientID, From: "02/11/2026"}, expectField: "from"},
+		{name: "to before from", data: JoinWaitlist{PractitionerID: practitionerID, PatientID: patientID, From: "2026-11-02", To: "2026-11-01"}, expectField: "to"},
+		{name: "too long", data: JoinWaitlist{PractitionerID: practitionerID, PatientID: patientID, From: "2026-11-02", To: "2027-01-31"}, expectField: "to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.data.Validate()
+			if tt.expectField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tt.data.To == "" || tt.data.Service != "physiotherapy" && tt.data.Service != "" {
+					t.Errorf("not normalised: %+v", tt.data)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			for _, fe := range verrs {
+				if fe.Field == tt.expectField {
+					return
+				}
+			}
+			t.Errorf("expected an error on %s, got %v", tt.expectField, verrs)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+type AllergyRepository interface {
+	Create(ctx context.Context, allergy model.Allergy) error
+	GetByID(ctx context.Context, patientID, id uuid.UUID) (*model.Allergy, error)
+	ListByPatient(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error)
+	UpdateByID(ctx context.Context, patientID, id uuid.UUID, data *model.UpdateAllergy) error
+}
+
+type AllergyRepo struct {
+	db *sql.DB
+}
+
+func NewAllergyRepository(db *sql.DB) *AllergyRepo {
+	return &AllergyRepo{
+		db: db,
+	}
+}
+
+// Create records an allergy of a live patient, or returns ErrNotFound.
+func (r *AllergyRepo) Create(ctx context.Context, allergy model.Allergy) error {
+	q := `INSERT INTO patient_allergies(id, organisation_id, patient_id, allergen, rxcui, reaction, severity, verification, recorded_by)
+		SELECT $1, organisation_id, id, $3, $4, $5, $6, $7, $8
+		FROM patients WHERE id = $2 AND is_deleted = false AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q,
+			allergy.ID,
+			allergy.PatientID,
+			allergy.Allergen,
+			allergy.RxCUI,
+			allergy.Reaction,
+			allergy.Severity,
+			allergy.Verification,
+			allergy.RecordedBy,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
+
+const allergyColumns = `id, organisation_id, patient_id, allergen, rxcui, reaction, severity, verification,
+	recorded_by, created_at, updated_at`
+
+func scanAllergy(row rowScanner) (*model.Allergy, error) {
+	var a model.Allergy
+	if err := row.Scan(
+		&a.ID,
+		&a.OrganisationID,
+		&a.PatientID,
+		&a.Allergen,
+		&a.RxCUI,
+		&a.Reaction,
+		&a.Severity,
+		&a.Verification,
+		&a.RecordedBy,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AllergyRepo) GetByID(ctx context.Context, patientID, id uuid.UUID) (*model.Allergy, error) {
+	q := `SELECT ` + allergyColumns + ` FROM patient_allergies WHERE id = $1 AND patient_id = $2 AND ` + tenantOrganisation
+
+	var a *model.Allergy
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		a, err = scanAllergy(db.QueryRowContext(ctx, q, id, patientID))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ListByPatient returns every allergy recorded for a patient, including
+// refuted ones and those entered in error, oldest first.
+func (r *AllergyRepo) ListByPatient(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error) {
+	q := `SELECT ` + allergyColumns + ` FROM patient_allergies WHERE patient_id = $1 AND ` + tenantOrganisation + `
+		ORDER BY created_at, id`
+
+	var allergies []model.Allergy
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, patientID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			a, err := scanAllergy(rows)
+			if err != nil {
+				return err
+			}
+			allergies = append(allergies, *a)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allergies, nil
+}
+
+// UpdateByID applies a merge patch to an allergy. Null members are stored
+// as NULL.
+func (r *AllergyRepo) UpdateByID(ctx context.Context, patientID, id uuid.UUID, data *model.UpdateAllergy) error {
+	var sets []string
+	var args []any
+
+	set := func(col string, o model.Optional[string]) {
+		if !o.Set {
+			return
+		}
+		if o.Null {
+			sets = append(sets, col+" = NULL")
+			return
+		}
+		args = append(args, o.Value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", col, len(args)))
+	}
+
+	set("reaction", data.Reaction)
+	set("severity", data.Severity)
+	set("verification", data.Verification)
+	if len(sets) == 0 {
+		return model.ErrBadRequest
+	}
+
+	args = append(args, id, patientID)
+	q := fmt.Sprintf(`UPDATE patient_allergies SET %s WHERE id = $%d AND patient_id = $%d AND `+tenantOrganisation,
+		strings.Join(sets, ", "), len(args)-1, len(args))
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,476 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type AppointmentRepository interface {
+	GetSchedule(ctx context.Context, practitionerID uuid.UUID) (*model.Schedule, error)
+	SetSchedule(ctx context.Context, schedule model.Schedule) error
+	ListExceptions(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.AvailabilityException, error)
+	AddException(ctx context.Context, exception model.AvailabilityException) (*model.AvailabilityException, error)
+	RemoveException(ctx context.Context, practitionerID, exceptionID uuid.UUID) error
+	ListBusy(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.TimeRange, error)
+	Create(ctx context.Context, appointment model.Appointment) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Appointment, error)
+	List(ctx context.Context, filter model.AppointmentFilter, limit, offset int) ([]model.Appointment, error)
+	Count(ctx context.Context, filter model.AppointmentFilter) (int, error)
+	Reschedule(ctx context.Context, id uuid.UUID, slot model.Slot, reason string) error
+	SetStatus(ctx context.Context, id uuid.UUID, from, to string, reason *string) error
+}
+
+type AppointmentRepo struct {
+	db *sql.DB
+}
+
+func NewAppointmentRepository(db *sql.DB) *AppointmentRepo {
+	return &AppointmentRepo{
+		db: db,
+	}
+}
+
+// isOverlap reports whether err is a violation of an exclusion constraint,
+// which is how the database refuses overlapping appointments.
+func isOverlap(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23P01"
+}
+
+// livePractitionerOrg returns the organisation of a live practitioner of the
+// request's organisation.
+func livePractitionerOrg(ctx context.Context, db querier, id uuid.UUID) (uuid.UUID, error) {
+	q := `SELECT organisation_id FROM practitioners WHERE id = $1 AND is_deleted = false AND ` + tenantOrganisation
+
+	var orgID uuid.UUID
+	if err := db.QueryRowContext(ctx, q, id).Scan(&orgID); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, model.ErrNotFound
+		}
+		return uuid.Nil, err
+	}
+	return orgID, nil
+}
+
+// GetSchedule returns the schedule of a practitioner. A practitioner without
+// one gets an empty schedule with no time zone or slot length.
+func (r *AppointmentRepo) GetSchedule(ctx context.Context, practitionerID uuid.UUID) (*model.Schedule, error) {
+	s := model.Schedule{PractitionerID: practitionerID, Windows: []model.ScheduleWindow{}}
+
+	err := inTenant(ctx, r.db, func(db querier) error {
+		if _, err := livePractitionerOrg(ctx, db, practitionerID); err != nil {
+			return err
+		}
+
+		q := `SELECT timezone, slot_minutes, updated_at FROM practitioner_schedules WHERE practitioner_id = $1`
+		var updatedAt time.Time
+		err := db.QueryRowContext(ctx, q, practitionerID).Scan(&s.Timezone, &s.SlotMinutes, &updatedAt)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.UpdatedAt = &updatedAt
+
+		rows, err := db.QueryContext(ctx, `SELECT weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+			FROM schedule_windows WHERE practitioner_id = $1 ORDER BY weekday, start_time`, practitionerID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var w model.ScheduleWindow
+			if err := rows.Scan(&w.Weekday, &w.Start, &w.End); err != nil {
+				return err
+			}
+			s.Windows = append(s.Windows, w)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// SetSchedule replaces the schedule of a practitioner. Booked appointments
+// are kept even if they no longer fit it.
+func (r *AppointmentRepo) SetSchedule(ctx context.Context, schedule model.Schedule) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	orgID, err := livePractitionerOrg(ctx, tx, schedule.PractitionerID)
+	if err != nil {
+		return err
+	}
+
+	q := `INSERT INTO practitioner_schedules(practitioner_id, organisation_id, timezone, slot_minutes)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (practitioner_id) DO UPDATE SET timezone = EXCLUDED.timezone, slot_minutes = EXCLUDED.slot_minutes, updated_at = now()`
+
+	if _, err := tx.ExecContext(ctx, q, schedule.PractitionerID, orgID, schedule.Timezone, schedule.SlotMinutes); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schedule_windows WHERE practitioner_id = $1`, schedule.PractitionerID); err != nil {
+		return err
+	}
+
+	for _, w := range schedule.Windows {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO schedule_windows(practitioner_id, organisation_id, weekday, start_time, end_time) VALUES($1, $2, $3, $4, $5)`,
+			schedule.PractitionerID, orgID, w.Weekday, w.Start, w.End,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListExceptions returns the exceptions of a practitioner that overlap
+// [from, to).
+func (r *AppointmentRepo) ListExceptions(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.AvailabilityException, error) {
+	q := `SELECT id, practitioner_id, starts_at, ends_at, reason, created_by, created_at
+		FROM availability_exceptions
+		WHERE practitioner_id = $1 AND tstzrange(starts_at, ends_at) && tstzrange($2, $3) AND ` + tenantOrganisation + `
+		ORDER BY starts_at, id`
+
+	var exceptions []model.AvailabilityException
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, practitionerID, from, to)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e model.AvailabilityException
+			if err := rows.Scan(&e.ID, &e.PractitionerID, &e.StartsAt, &e.EndsAt, &e.Reason, &e.CreatedBy, &e.CreatedAt); err != nil {
+				return err
+			}
+			exceptions = append(exceptions, e)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return exceptions, nil
+}
+
+func (r *AppointmentRepo) AddException(ctx context.Context, exception model.AvailabilityException) (*model.AvailabilityException, error) {
+	q := `INSERT INTO availability_exceptions(id, practitioner_id, organisation_id, starts_at, ends_at, reason, created_by)
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`
+
+	err := inTenant(ctx, r.db, func(db querier) error {
+		orgID, err := livePractitionerOrg(ctx, db, exception.PractitionerID)
+		if err != nil {
+			return err
+		}
+		return db.QueryRowContext(ctx, q,
+			exception.ID,
+			exception.PractitionerID,
+			orgID,
+			exception.StartsAt,
+			exception.EndsAt,
+			exception.Reason,
+			exception.CreatedBy,
+		).Scan(&exception.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &exception, nil
+}
+
+func (r *AppointmentRepo) RemoveException(ctx context.Context, practitionerID, exceptionID uuid.UUID) error {
+	q := `DELETE FROM availability_exceptions WHERE id = $1 AND practitioner_id = $2 AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, exceptionID, practitionerID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// ListBusy returns the times of the appointments of a practitioner that
+// overlap [from, to) and have not been cancelled.
+func (r *AppointmentRepo) ListBusy(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.TimeRange, error) {
+	q := `SELECT starts_at, ends_at FROM appointments
+		WHERE practitioner_id = $1 AND status <> 'cancelled' AND tstzrange(starts_at, ends_at) && tstzrange($2, $3) AND ` + tenantOrganisation + `
+		ORDER BY starts_at`
+
+	var busy []model.TimeRange
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, practitionerID, from, to)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var b model.TimeRange
+			if err := rows.Scan(&b.Start, &b.End); err != nil {
+				return err
+			}
+			busy = append(busy, b)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return busy, nil
+}
+
+// Create books an appointment. The practitioner and the patient must be live
+// and belong to the same organisation, or it is ErrNotFound. A time that
+// overlaps another appointment of either is ErrConflict.
+func (r *AppointmentRepo) Create(ctx context.Context, appointment model.Appointment) error {
+	return inTenant(ctx, r.db, func(db querier) error {
+		return insertAppointment(ctx, db, appointment)
+	})
+}
+
+// insertAppointment is Create against db, for callers that book as part of
+// a larger transaction.
+func insertAppointment(ctx context.Context, db querier, appointment model.Appointment) error {
+	q := `INSERT INTO appointments(id, organisation_id, practitioner_id, patient_id, starts_at, ends_at, service, reason, created_by)
+		SELECT $1, p.organisation_id, p.id, pt.id, $4, $5, $6, $7, $8
+		FROM practitioners p JOIN patients pt ON pt.organisation_id = p.organisation_id
+		WHERE p.id = $2 AND pt.id = $3 AND p.is_deleted = false AND pt.is_deleted = false AND ` + tenantOrganisationOf("p")
+
+	res, err := db.ExecContext(ctx, q,
+		appointment.ID,
+		appointment.PractitionerID,
+		appointment.PatientID,
+		appointment.StartsAt,
+		appointment.EndsAt,
+		appointment.Service,
+		appointment.Reason,
+		appointment.CreatedBy,
+	)
+	if err != nil {
+		if isOverlap(err) {
+			return model.ErrConflict
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+const appointmentColumns = `a.id, a.organisation_id, a.practitioner_id, u.first_name || ' ' || u.last_name,
+	a.patient_id, pt.first_name || ' ' || pt.last_name, a.starts_at, a.ends_at, a.status, a.service, a.reason,
+	a.cancel_reason, a.cancelled_at, a.reschedule_reason, a.rescheduled_at, a.checked_in_at, a.completed_at,
+	a.created_by, a.created_at, a.updated_at, COALESCE(ps.timezone, 'UTC'), p.user_id, pt.user_id`
+
+const appointmentJoins = `appointments a
+	JOIN practitioners p ON p.id = a.practitioner_id
+	JOIN users u ON u.id = p.user_id
+	JOIN patients pt ON pt.id = a.patient_id
+	LEFT JOIN practitioner_schedules ps ON ps.practitioner_id = a.practitioner_id`
+
+func scanAppointment(row rowScanner) (*model.Appointment, error) {
+	var a model.Appointment
+	if err := row.Scan(
+		&a.ID,
+		&a.OrganisationID,
+		&a.PractitionerID,
+		&a.PractitionerName,
+		&a.PatientID,
+		&a.PatientName,
+		&a.StartsAt,
+		&a.EndsAt,
+		&a.Status,
+		&a.Service,
+		&a.Reason,
+		&a.CancelReason,
+		&a.CancelledAt,
+		&a.RescheduleReason,
+		&a.RescheduledAt,
+		&a.CheckedInAt,
+		&a.CompletedAt,
+		&a.CreatedBy,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+		&a.Timezone,
+		&a.PractitionerUserID,
+		&a.PatientUserID,
+	); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AppointmentRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Appointment, error) {
+	q := `SELECT ` + appointmentColumns + ` FROM ` + appointmentJoins + ` WHERE a.id = $1 AND ` + tenantOrganisationOf("a")
+
+	var a *model.Appointment
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		a, err = scanAppointment(db.QueryRowContext(ctx, q, id))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// appointmentFilterConds builds the WHERE conditions for filter, limited to
+// the request's organisation.
+func appointmentFilterConds(filter model.AppointmentFilter) ([]string, []any) {
+	conds := []string{tenantOrganisationOf("a")}
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.PractitionerID != nil {
+		conds = append(conds, "a.practitioner_id = "+arg(*filter.PractitionerID))
+	}
+	if filter.PatientID != nil {
+		conds = append(conds, "a.patient_id = "+arg(*filter.PatientID))
+	}
+	if filter.Status != "" {
+		conds = append(conds, "a.status = "+arg(filter.Status))
+	}
+	if filter.From != nil {
+		conds = append(conds, "a.starts_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conds = append(conds, "a.starts_at < "+arg(*filter.To))
+	}
+	if filter.ParticipantID != nil {
+		p := arg(*filter.ParticipantID)
+		conds = append(conds, fmt.Sprintf("(p.user_id = %s OR pt.user_id = %s)", p, p))
+	}
+
+	return conds, args
+}
+
+// List returns a page of appointments in the order they start.
+func (r *AppointmentRepo) List(ctx context.Context, filter model.AppointmentFilter, limit, offset int) ([]model.Appointment, error) {
+	conds, args := appointmentFilterConds(filter)
+	q := fmt.Sprintf(`SELECT %s FROM %s %s ORDER BY a.starts_at, a.id LIMIT $%d OFFSET $%d`,
+		appointmentColumns, appointmentJoins, whereClause(conds), len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	var appointments []model.Appointment
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			a, err := scanAppointment(rows)
+			if err != nil {
+				return err
+			}
+			appointments = append(appointments, *a)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return appointments, nil
+}
+
+func (r *AppointmentRepo) Count(ctx context.Context, filter model.AppointmentFilter) (int, error) {
+	conds, args := appointmentFilterConds(filter)
+	q := `SELECT COUNT(*) FROM ` + appointmentJoins + ` ` + whereClause(conds)
+
+	var count int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, args...).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Reschedule moves a booked appointment to slot. An appointment that is no
+// longer booked, or a slot that overlaps another appointment, is
+// ErrConflict.
+func (r *AppointmentRepo) Reschedule(ctx context.Context, id uuid.UUID, slot model.Slot, reason string) error {
+	q := `UPDATE appointments SET starts_at = $2, ends_at = $3, reschedule_reason = $4, rescheduled_at = now()
+		WHERE id = $1 AND status = 'booked' AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, id, slot.StartsAt, slot.EndsAt, reason)
+		if err != nil {
+			if isOverlap(err) {
+				return model.ErrConflict
+			}
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrConflict
+		}
+		return nil
+	})
+}
+
+// SetStatus moves an appointment from one status to another and stamps the
+// time of the change. An appointment whose status is no longer from is
+// ErrConflict.
+func (r *AppointmentRepo) SetStatus(ctx context.Context, id uuid.UUID, from, to string, reason *string) error {
+	q := `UPDATE appointments SET status = $3,
+			checked_in_at = CASE WHEN $3 = 'checked_in' THEN now() ELSE checked_in_at END,
+			completed_at = CASE WHEN $3 = 'completed' THEN now() ELSE completed_at END,
+			cancelled_at = CASE WHEN $3 = 'cancelled' THEN now() ELSE cancelled_at END,
+			cancel_reason = CASE WHEN $3 = 'cancelled' THEN $4 ELSE cancel_reason END
+		WHERE id = $1 AND status = $2 AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, id, from, to, reason)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrConflict
+		}
+		return nil
+	})
+}
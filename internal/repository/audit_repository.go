@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+type AuditRepository interface {
+	Record(ctx context.Context, entry model.AuditEntry) error
+}
+
+type AuditRepo struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepo {
+	return &AuditRepo{
+		db: db,
+	}
+}
+
+func (r *AuditRepo) Record(ctx context.Context, entry model.AuditEntry) error {
+	details, err := json.Marshal(entry.Details)
+	if err != nil {
+		return err
+	}
+	if entry.Details == nil {
+		details = []byte("{}")
+	}
+
+	q := `INSERT INTO audit_log(id, actor_id, action, subject_type, subject_id, details) VALUES($1, $2, $3, $4, $5, $6)`
+
+	_, err = r.db.ExecContext(ctx, q, entry.ID, entry.ActorID, entry.Action, entry.SubjectType, entry.SubjectID, details)
+	return err
+}
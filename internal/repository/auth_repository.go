@@ -16,6 +16,8 @@ type AuthRepository interface {
 	Login(ctx context.Context, email string) (*model.GetByEmail, error)
 	StoreRefreshToken(ctx context.Context, id uuid.UUID, userID uuid.UUID, token string, expiresAt time.Time) error
 	RevokeRefreshToken(ctx context.Context, token string) error
+	GetAccess(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error)
+	DefaultOrganisation(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error)
 }
 
 type AuthRepo struct {
@@ -51,13 +53,14 @@ func (r *AuthRepo) Register(ctx context.Context, user model.User) error {
 }
 
 func (r *AuthRepo) Login(ctx context.Context, email string) (*model.GetByEmail, error) {
-	q := `SELECT id, password, role FROM users WHERE email=$1 AND is_deleted = false`
+	q := `SELECT id, password, role, ` + effectiveStatus + ` FROM users WHERE email=$1 AND is_deleted = false`
 
 	var user model.GetByEmail
 	if err := r.db.QueryRowContext(ctx, q, email).Scan(
 		&user.ID,
 		&user.Password,
 		&user.Role,
+		&user.Status,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, model.ErrNotFound
@@ -94,3 +97,43 @@ func (r *AuthRepo) RevokeRefreshToken(ctx context.Context, token string) error {
 
 	return nil
 }
+
+// GetAccess returns the effective status and role of a live user together
+// with their membership of orgID, if any.
+func (r *AuthRepo) GetAccess(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error) {
+	q := `SELECT ` + effectiveStatus + `, role,
+		(SELECT role::text FROM organisation_members WHERE user_id = users.id AND organisation_id = $2::uuid),
+		EXISTS (SELECT 1 FROM organisations WHERE id = $2::uuid)
+	FROM users WHERE id = $1 AND is_deleted = false`
+
+	var a model.Access
+	var memberRole sql.NullString
+	if err := r.db.QueryRowContext(ctx, q, userID, orgID).Scan(&a.Status, &a.Role, &memberRole, &a.OrganisationExists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	a.MemberRole = memberRole.String
+
+	return &a, nil
+}
+
+// DefaultOrganisation returns the organisation a user signs in to when they
+// do not name one: the one recorded on the account if they are still a member
+// of it, otherwise the one they joined first. It is nil if they belong to none.
+func (r *AuthRepo) DefaultOrganisation(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	q := `SELECT m.organisation_id FROM organisation_members m JOIN users u ON u.id = m.user_id
+		WHERE m.user_id = $1
+		ORDER BY m.organisation_id = u.organisation_id DESC NULLS LAST, m.created_at, m.organisation_id
+		LIMIT 1`
+
+	var orgID uuid.UUID
+	if err := r.db.QueryRowContext(ctx, q, userID).Scan(&orgID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &orgID, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+type CalendarRepository interface {
+	CreateFeed(ctx context.Context, feed model.CalendarFeed) error
+	GetFeed(ctx context.Context, userID uuid.UUID) (*model.CalendarFeed, error)
+	RevokeFeed(ctx context.Context, userID uuid.UUID) error
+	GetFeedByTokenHash(ctx context.Context, tokenHash string) (*model.CalendarFeed, error)
+	TouchFeed(ctx context.Context, id uuid.UUID) error
+}
+
+type CalendarRepo struct {
+	db *sql.DB
+}
+
+func NewCalendarRepository(db *sql.DB) *CalendarRepo {
+	return &CalendarRepo{
+		db: db,
+	}
+}
+
+// CreateFeed stores a new feed for the user, revoking the one they had.
+func (r *CalendarRepo) CreateFeed(ctx context.Context, feed model.CalendarFeed) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := `UPDATE calendar_feeds SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, q, feed.UserID); err != nil {
+		return err
+	}
+
+	q = `INSERT INTO calendar_feeds(id, user_id, token_hash) VALUES($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, q, feed.ID, feed.UserID, feed.TokenHash); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetFeed returns the user's working feed.
+func (r *CalendarRepo) GetFeed(ctx context.Context, userID uuid.UUID) (*model.CalendarFeed, error) {
+	q := `SELECT id, user_id, created_at, last_used_at, token_hash FROM calendar_feeds
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	var f model.CalendarFeed
+	if err := r.db.QueryRowContext(ctx, q, userID).Scan(&f.ID, &f.UserID, &f.CreatedAt, &f.LastUsedAt, &f.TokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *CalendarRepo) RevokeFeed(ctx context.Context, userID uuid.UUID) error {
+	q := `UPDATE calendar_feeds SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	res, err := r.db.ExecContext(ctx, q, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// GetFeedByTokenHash returns the working feed with the token, along with the
+// owner's account status. Feeds of deleted users are not found.
+func (r *CalendarRepo) GetFeedByTokenHash(ctx context.Context, tokenHash string) (*model.CalendarFeed, error) {
+	q := `SELECT f.id, f.user_id, f.created_at, f.last_used_at, f.token_hash, ` + effectiveStatus + `
+		FROM calendar_feeds f JOIN users ON users.id = f.user_id
+		WHERE f.token_hash = $1 AND f.revoked_at IS NULL AND users.is_deleted = false`
+
+	var f model.CalendarFeed
+	if err := r.db.QueryRowContext(ctx, q, tokenHash).Scan(&f.ID, &f.UserID, &f.CreatedAt, &f.LastUsedAt, &f.TokenHash, &f.OwnerStatus); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *CalendarRepo) TouchFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE calendar_feeds SET last_used_at = now() WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type EmailChangeRepository interface {
+	GetCredentials(ctx context.Context, userID uuid.UUID) (*model.EmailCredentials, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	Create(ctx context.Context, req model.EmailChangeRequest) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error)
+	GetByRevertTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error)
+	Confirm(ctx context.Context, id uuid.UUID, revertTokenHash string, revertExpiresAt time.Time) error
+	Revert(ctx context.Context, id uuid.UUID) error
+}
+
+type EmailChangeRepo struct {
+	db *sql.DB
+}
+
+func NewEmailChangeRepository(db *sql.DB) *EmailChangeRepo {
+	return &EmailChangeRepo{
+		db: db,
+	}
+}
+
+func (r *EmailChangeRepo) GetCredentials(ctx context.Context, userID uuid.UUID) (*model.EmailCredentials, error) {
+	q := `SELECT email, password FROM users WHERE id = $1 AND is_deleted = false`
+
+	var creds model.EmailCredentials
+	if err := r.db.QueryRowContext(ctx, q, userID).Scan(&creds.Email, &creds.Password); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &creds, nil
+}
+
+func (r *EmailChangeRepo) EmailExists(ctx context.Context, email string) (bool, error) {
+	q := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, q, email).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// Create stores req and cancels any other unconfirmed request of the same
+// user, so only the most recent link works.
+func (r *EmailChangeRepo) Create(ctx context.Context, req model.EmailChangeRequest) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := `UPDATE email_change_requests SET cancelled_at = now()
+		WHERE user_id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL`
+	if _, err := tx.ExecContext(ctx, q, req.UserID); err != nil {
+		return err
+	}
+
+	q = `INSERT INTO email_change_requests(id, user_id, old_email, new_email, token_hash, expires_at)
+		VALUES($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.ExecContext(ctx, q, req.ID, req.UserID, req.OldEmail, req.NewEmail, req.TokenHash, req.ExpiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const emailChangeColumns = `id, user_id, old_email, new_email, token_hash, expires_at, confirmed_at,
+	COALESCE(revert_token_hash, ''), revert_expires_at, reverted_at`
+
+func scanEmailChange(row rowScanner) (*model.EmailChangeRequest, error) {
+	var req model.EmailChangeRequest
+	if err := row.Scan(
+		&req.ID,
+		&req.UserID,
+		&req.OldEmail,
+		&req.NewEmail,
+		&req.TokenHash,
+		&req.ExpiresAt,
+		&req.ConfirmedAt,
+		&req.RevertTokenHash,
+		&req.RevertExpiresAt,
+		&req.RevertedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// GetByTokenHash returns the request with the given confirmation token.
+// Cancelled requests are not found.
+func (r *EmailChangeRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error) {
+	q := `SELECT ` + emailChangeColumns + ` FROM email_change_requests WHERE token_hash = $1 AND cancelled_at IS NULL`
+	return scanEmailChange(r.db.QueryRowContext(ctx, q, tokenHash))
+}
+
+func (r *EmailChangeRepo) GetByRevertTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error) {
+	q := `SELECT ` + emailChangeColumns + ` FROM email_change_requests WHERE revert_token_hash = $1`
+	return scanEmailChange(r.db.QueryRowContext(ctx, q, tokenHash))
+}
+
+// Confirm swaps the user's email to the new address and opens the revert
+// window in one transaction. The swap only applies while the user still has
+// the old address; the unique constraint on users.email reports an address
+// taken in the meantime as model.ErrAlreadyExists.
+func (r *EmailChangeRepo) Confirm(ctx context.Context, id uuid.UUID, revertTokenHash string, revertExpiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := `SELECT ` + emailChangeColumns + ` FROM email_change_requests
+		WHERE id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL FOR UPDATE`
+	req, err := scanEmailChange(tx.QueryRowContext(ctx, q, id))
+	if err != nil {
+		return err
+	}
+
+	q = `UPDATE users SET email = $1 WHERE id = $2 AND email = $3 AND is_deleted = false`
+	res, err := tx.ExecContext(ctx, q, req.NewEmail, req.UserID, req.OldEmail)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return model.ErrAlreadyExists
+		}
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return model.ErrConflict
+	}
+
+	q = `UPDATE email_change_requests SET confirmed_at = now(), revert_token_hash = $2, revert_expires_at = $3 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, q, id, revertTokenHash, revertExpiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Revert puts the old address back and revokes every refresh token of the
+// user, since a revert usually means the change was not theirs.
+func (r *EmailChangeRepo) Revert(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := `SELECT ` + emailChangeColumns + ` FROM email_change_requests
+		WHERE id = $1 AND confirmed_at IS NOT NULL AND reverted_at IS NULL FOR UPDATE`
+	req, err := scanEmailChange(tx.QueryRowContext(ctx, q, id))
+	if err != nil {
+		return err
+	}
+
+	q = `UPDATE users SET email = $1 WHERE id = $2 AND email = $3`
+	res, err := tx.ExecContext(ctx, q, req.OldEmail, req.UserID, req.NewEmail)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return model.ErrAlreadyExists
+		}
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return model.ErrConflict
+	}
+
+	q = `UPDATE refresh_tokens SET revoked = true WHERE user_id = $1 AND revoked = false`
+	if _, err := tx.ExecContext(ctx, q, req.UserID); err != nil {
+		return err
+	}
+
+	q = `UPDATE email_change_requests SET reverted_at = now() WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, q, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
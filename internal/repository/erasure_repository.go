@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ErasureRepository interface {
+	Erase(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error)
+	LinkedPatients(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	GetCertificate(ctx context.Context, userID uuid.UUID) (*model.ErasureCertificate, error)
+	ExportFiles(ctx context.Context, userID uuid.UUID) ([]string, error)
+	ClearExportFiles(ctx context.Context, userID uuid.UUID) error
+}
+
+type ErasureRepo struct {
+	db *sql.DB
+}
+
+func NewErasureRepository(db *sql.DB) *ErasureRepo {
+	return &ErasureRepo{
+		db: db,
+	}
+}
+
+// Erase anonymises the user named by cert and the patient records linked to
+// them, revokes their refresh tokens, expires their data exports and stores
+// cert, all in one transaction.
+// The user and patient rows are kept so records that reference them stay
+// valid; patients keep their MRN and sex, and their date of birth is cut to
+// the year. Within an organisation, accounts other organisations share are
+// forbidden. If the user already has a certificate nothing is
+// changed and the existing one is returned with created set to false, which
+// makes a retry after a failure or a duplicate request safe. Users outside the
+// request's organisation are not found.
+func (r *ErasureRepo) Erase(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	shared, err := lockShared(ctx, tx, cert.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := setTenant(ctx, tx); err != nil {
+		return nil, false, err
+	}
+
+	q := `SELECT id FROM users WHERE id = $1 AND ` + tenantUsers + ` FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, q, cert.UserID).Scan(new(uuid.UUID)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, model.ErrNotFound
+		}
+		return nil, false, err
+	}
+
+	existing, err := scanCertificate(tx.QueryRowContext(ctx, certificateQuery, cert.UserID))
+	if err == nil {
+		return existing, false, nil
+	}
+	if err != model.ErrNotFound {
+		return nil, false, err
+	}
+	if shared {
+		return nil, false, errSharedUser
+	}
+
+	q = `UPDATE users SET
+		first_name = 'Erased',
+		last_name = $2,
+		email = $3,
+		password = '',
+		` + clearedProfileColumns + `,
+		is_deleted = true,
+		deleted_at = COALESCE(deleted_at, now()),
+		purged_at = COALESCE(purged_at, now())
+	WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, q, cert.UserID, cert.Pseudonym, email); err != nil {
+		return nil, false, err
+	}
+
+	q = `UPDATE patients SET
+		first_name = 'Erased',
+		last_name = $2,
+		date_of_birth = date_trunc('year', date_of_birth)::date,
+		gender = NULL,
+		phone = NULL,
+		email = NULL,
+		preferred_language = NULL,
+		address_line1 = NULL,
+		address_line2 = NULL,
+		address_city = NULL,
+		address_region = NULL,
+		address_postal_code = NULL,
+		address_country = NULL
+	WHERE user_id = $1 AND ` + tenantOrganisation
+	if _, err := tx.ExecContext(ctx, q, cert.UserID, cert.Pseudonym); err != nil {
+		return nil, false, err
+	}
+
+	q = `DELETE FROM patient_identifiers WHERE patient_id IN (
+		SELECT id FROM patients WHERE user_id = $1 AND ` + tenantOrganisation + `)`
+	if _, err := tx.ExecContext(ctx, q, cert.UserID); err != nil {
+		return nil, false, err
+	}
+
+	// Lab results matched by hand keep the name, date of birth and
+	// identifiers the lab sent, which are the patient's too.
+	q = `UPDATE lab_reconciliation SET identifiers = '[]', family_name = '', given_name = '', date_of_birth = NULL
+	WHERE patient_id IN (SELECT id FROM patients WHERE user_id = $1 AND ` + tenantOrganisation + `)`
+	if _, err := tx.ExecContext(ctx, q, cert.UserID); err != nil {
+		return nil, false, err
+	}
+
+	q = `UPDATE refresh_tokens SET revoked = true WHERE user_id = $1 AND revoked = false`
+	if _, err := tx.ExecContext(ctx, q, cert.UserID); err != nil {
+		return nil, false, err
+	}
+
+	q = `DELETE FROM email_change_requests WHERE user_id = $1`
+	if _, err := tx.ExecContext(ctx, q, cert.UserID); err != nil {
+		return nil, false, err
+	}
+
+	q = `DELETE FROM invitations WHERE accepted_user_id = $1`
+	if _, err := tx.ExecContext(ctx, q, cert.UserID); err != nil {
+		return nil, false, err
+	}
+
+	// Exports are copies of what is erased here. Expiring them stops their
+	// download links; their archives are named by ExportFiles.
+	q = `UPDATE data_exports SET status = 'expired', expires_at = now()
+		WHERE user_id = $1 AND status IN ('pending', 'running', 'ready')`
+	if _, err := tx.ExecContext(ctx, q, cert.UserID); err != nil {
+		return nil, false, err
+	}
+
+	q = `INSERT INTO erasure_certificates(id, user_id, pseudonym, erased_fields, requested_by, erased_at, signature)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := tx.ExecContext(
+		ctx,
+		q,
+		cert.ID,
+		cert.UserID,
+		cert.Pseudonym,
+		pq.Array(cert.ErasedFields),
+		cert.RequestedBy,
+		cert.ErasedAt,
+		cert.Signature,
+	); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return &cert, true, nil
+}
+
+// LinkedPatients returns the ids of the patient records in the request's
+// organisation that are linked to userID, soft-deleted ones included.
+func (r *ErasureRepo) LinkedPatients(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	q := `SELECT id FROM patients WHERE user_id = $1 AND ` + tenantOrganisation + ` ORDER BY created_at, id`
+
+	var ids []uuid.UUID
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *ErasureRepo) GetCertificate(ctx context.Context, userID uuid.UUID) (*model.ErasureCertificate, error) {
+	q := certificateQuery + ` AND EXISTS (SELECT 1 FROM users WHERE users.id = erasure_certificates.user_id AND ` + tenantUsers + `)`
+
+	var cert *model.ErasureCertificate
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		cert, err = scanCertificate(db.QueryRowContext(ctx, q, userID))
+		return err
+	})
+	return cert, err
+}
+
+// ExportFiles returns the archives of the user's expired exports that are
+// still on disk.
+func (r *ErasureRepo) ExportFiles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	q := `SELECT file_path FROM data_exports WHERE user_id = $1 AND status = 'expired' AND file_path IS NOT NULL`
+
+	rows, err := r.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// ClearExportFiles records that the archives of the user's expired exports
+// are deleted.
+func (r *ErasureRepo) ClearExportFiles(ctx context.Context, userID uuid.UUID) error {
+	q := `UPDATE data_exports SET file_path = NULL WHERE user_id = $1 AND status = 'expired'`
+
+	_, err := r.db.ExecContext(ctx, q, userID)
+	return err
+}
+
+const certificateQuery = `SELECT id, user_id, pseudonym, erased_fields, requested_by, erased_at, signature
+	FROM erasure_certificates WHERE user_id = $1`
+
+func scanCertificate(row rowScanner) (*model.ErasureCertificate, error) {
+	var cert model.ErasureCertificate
+	if err := row.Scan(
+		&cert.ID,
+		&cert.UserID,
+		&cert.Pseudonym,
+		pq.Array(&cert.ErasedFields),
+		&cert.RequestedBy,
+		&cert.ErasedAt,
+		&cert.Signature,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &cert, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestErasureRepo_Erase_Exports(t *testing.T) {
+	db := testDB(t)
+	repo := NewErasureRepository(db)
+
+	tests := []struct {
+		name       string
+		status     string
+		filePath   string
+		expectFile bool
+	}{
+		{name: "ready", status: model.ExportReady, filePath: "/exports/ready.zip", expectFile: true},
+		{name: "pending", status: model.ExportPending},
+		{name: "running", status: model.ExportRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := testUser(t, db)
+			t.Cleanup(func() { db.Exec(`DELETE FROM erasure_certificates WHERE user_id = $1`, userID) })
+
+			exportID, _ := uuid.NewV7()
+			var filePath any
+			if tt.filePath != "" {
+				filePath = tt.filePath
+			}
+			q := `INSERT INTO data_exports(id, user_id, status, token_hash, file_path) VALUES($1, $2, $3, $4, $5)`
+			if _, err := db.Exec(q, exportID, userID, tt.status, exportID.String(), filePath); err != nil {
+				t.Fatalf("create export: %v", err)
+			}
+
+			certID, _ := uuid.NewV7()
+			cert := model.ErasureCertificate{ID: certID, UserID: userID, Pseudonym: "x", RequestedBy: userID}
+			if _, _, err := repo.Erase(context.Background(), cert, "erased-"+certID.String()+"@erased.invalid"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var status string
+			if err := db.QueryRow(`SELECT status FROM data_exports WHERE id = $1`, exportID).Scan(&status); err != nil {
+				t.Fatalf("export: %v", err)
+			}
+			if status != model.ExportExpired {
+				t.Errorf("status %s, want %s", status, model.ExportExpired)
+			}
+
+			paths, err := repo.ExportFiles(context.Background(), userID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := len(paths) == 1 && paths[0] == tt.filePath; got != tt.expectFile {
+				t.Errorf("archives %v, want %q listed: %v", paths, tt.filePath, tt.expectFile)
+			}
+			if err := repo.ClearExportFiles(context.Background(), userID); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if paths, _ := repo.ExportFiles(context.Background(), userID); len(paths) != 0 {
+				t.Errorf("archives %v left after clearing", paths)
+			}
+		})
+	}
+}
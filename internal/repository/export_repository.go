@@ -0,0 +1,362 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+type ExportRepository interface {
+	CreateWithinLimit(ctx context.Context, export model.DataExport, since time.Time, limit int) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.DataExport, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]model.DataExport, error)
+	ClaimPending(ctx context.Context, staleAfter time.Duration) (*model.DataExport, error)
+	MarkReady(ctx context.Context, id uuid.UUID, filePath string, expiresAt time.Time) error
+	MarkFailed(ctx context.Context, id uuid.UUID, reason string) error
+	ListExpired(ctx context.Context, now time.Time) ([]model.DataExport, error)
+	MarkExpired(ctx context.Context, id uuid.UUID) error
+	GetPersonalData(ctx context.Context, userID uuid.UUID) (*model.PersonalData, error)
+}
+
+type ExportRepo struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) *ExportRepo {
+	return &ExportRepo{
+		db: db,
+	}
+}
+
+const exportColumns = `id, user_id, status, expires_at, completed_at, created_at, token_hash, COALESCE(file_path, '')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanExport(row rowScanner) (*model.DataExport, error) {
+	var e model.DataExport
+	if err := row.Scan(
+		&e.ID,
+		&e.UserID,
+		&e.Status,
+		&e.ExpiresAt,
+		&e.CompletedAt,
+		&e.CreatedAt,
+		&e.TokenHash,
+		&e.FilePath,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CreateWithinLimit inserts export unless its user has already requested
+// limit exports since since, in which case it returns
+// model.ErrTooManyRequests. The user's row stays locked from the count to the
+// insert, so concurrent requests cannot both pass the check.
+func (r *ExportRepo) CreateWithinLimit(ctx context.Context, export model.DataExport, since time.Time, limit int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := `SELECT id FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, q, export.UserID).Scan(new(uuid.UUID)); err != nil {
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	}
+
+	var count int
+	q = `SELECT COUNT(*) FROM data_exports WHERE user_id = $1 AND created_at >= $2`
+	if err := tx.QueryRowContext(ctx, q, export.UserID, since).Scan(&count); err != nil {
+		return err
+	}
+	if count >= limit {
+		return model.ErrTooManyRequests
+	}
+
+	q = `INSERT INTO data_exports(id, user_id, status, token_hash) VALUES($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, q, export.ID, export.UserID, export.Status, export.TokenHash); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ExportRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.DataExport, error) {
+	q := `SELECT ` + exportColumns + ` FROM data_exports WHERE id = $1`
+	return scanExport(r.db.QueryRowContext(ctx, q, id))
+}
+
+func (r *ExportRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.DataExport, error) {
+	q := `SELECT ` + exportColumns + ` FROM data_exports WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []model.DataExport
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// ClaimPending marks the oldest pending export as running and returns it.
+// Exports left running for longer than staleAfter, for example by a crashed
+// worker, are claimed again. It returns model.ErrNotFound when there is no
+// work.
+func (r *ExportRepo) ClaimPending(ctx context.Context, staleAfter time.Duration) (*model.DataExport, error) {
+	q := `UPDATE data_exports SET status = 'running', started_at = now()
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'pending' OR (status = 'running' AND started_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportColumns
+
+	return scanExport(r.db.QueryRowContext(ctx, q, time.Now().Add(-staleAfter)))
+}
+
+// MarkReady records the archive of a running export. It returns
+// model.ErrNotFound when the export is no longer running, as when it was
+// expired by an erasure while it was built.
+func (r *ExportRepo) MarkReady(ctx context.Context, id uuid.UUID, filePath string, expiresAt time.Time) error {
+	q := `UPDATE data_exports SET status = 'ready', file_path = $2, expires_at = $3, completed_at = now()
+		WHERE id = $1 AND status = 'running'`
+
+	res, err := r.db.ExecContext(ctx, q, id, filePath, expiresAt)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ExportRepo) MarkFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	q := `UPDATE data_exports SET status = 'failed', error = $2, completed_at = now() WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, q, id, reason)
+	return err
+}
+
+func (r *ExportRepo) ListExpired(ctx context.Context, now time.Time) ([]model.DataExport, error) {
+	q := `SELECT ` + exportColumns + ` FROM data_exports WHERE status = 'ready' AND expires_at < $1`
+
+	rows, err := r.db.QueryContext(ctx, q, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []model.DataExport
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+func (r *ExportRepo) MarkExpired(ctx context.Context, id uuid.UUID) error {
+	q := `UPDATE data_exports SET status = 'expired', file_path = NULL WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, q, id)
+	return err
+}
+
+// GetPersonalData gathers everything stored about a user, including the
+// patient records linked to their login in any organisation and the clinical
+// records filed against them. Refresh token hashes are left out; they are
+// credentials rather than personal data.
+func (r *ExportRepo) GetPersonalData(ctx context.Context, userID uuid.UUID) (*model.PersonalData, error) {
+	data := model.PersonalData{
+		Sessions: []model.ExportSession{},
+		AuditLog: []model.AuditEntry{},
+	}
+
+	q := `SELECT id, first_name, last_name, email,
+		COALESCE(phone, ''), COALESCE(to_char(date_of_birth, 'YYYY-MM-DD'), ''), COALESCE(sex, ''), COALESCE(gender, ''),
+		COALESCE(preferred_language, ''), COALESCE(address_line1, ''), COALESCE(address_line2, ''), COALESCE(address_city, ''),
+		COALESCE(address_region, ''), COALESCE(address_postal_code, ''), COALESCE(address_country, ''),
+		role, created_at, updated_at
+	FROM users WHERE id = $1 AND purged_at IS NULL`
+	p := &data.Profile
+	if err := r.db.QueryRowContext(ctx, q, userID).Scan(
+		&p.ID,
+		&p.FirstName,
+		&p.LastName,
+		&p.Email,
+		&p.Phone,
+		&p.DateOfBirth,
+		&p.Sex,
+		&p.Gender,
+		&p.PreferredLanguage,
+		&p.Address.Line1,
+		&p.Address.Line2,
+		&p.Address.City,
+		&p.Address.Region,
+		&p.Address.PostalCode,
+		&p.Address.Country,
+		&p.Role,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+
+	q = `SELECT id, created_at, expires_at, revoked FROM refresh_tokens WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var s model.ExportSession
+		if err := rows.Scan(&s.ID, &s.CreatedAt, &s.ExpiresAt, &s.Revoked); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		data.Sessions = append(data.Sessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	q = `SELECT id, actor_id, action, subject_type, subject_id, details, created_at FROM audit_log
+		WHERE subject_id = $1 OR actor_id = $1
+		ORDER BY created_at`
+	rows, err = r.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e model.AuditEntry
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.SubjectType, &e.SubjectID, &details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(details, &e.Details); err != nil {
+			return nil, err
+		}
+		data.AuditLog = append(data.AuditLog, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if data.Patients, err = r.linkedPatients(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// linkedPatients returns the patient records linked to userID, soft-deleted
+// ones included since their data is still held.
+func (r *ExportRepo) linkedPatients(ctx context.Context, userID uuid.UUID) ([]model.ExportPatient, error) {
+	q := `SELECT ` + patientColumns + ` FROM patients WHERE user_id = $1 ORDER BY created_at, id`
+	patients, err := queryAll(ctx, r.db, scanPatient, q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := make([]model.ExportPatient, 0, len(patients))
+	for _, p := range patients {
+		e := model.ExportPatient{Patient: p}
+
+		q = `SELECT id, system, value, created_at FROM patient_identifiers WHERE patient_id = $1 ORDER BY system`
+		e.Patient.Identifiers, err = queryAll(ctx, r.db, func(row rowScanner) (*model.PatientIdentifier, error) {
+			var identifier model.PatientIdentifier
+			return &identifier, row.Scan(&identifier.ID, &identifier.System, &identifier.Value, &identifier.CreatedAt)
+		}, q, p.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		q = `SELECT ` + appointmentColumns + ` FROM ` + appointmentJoins + ` WHERE a.patient_id = $1 ORDER BY a.starts_at, a.id`
+		if e.Appointments, err = queryAll(ctx, r.db, scanAppointment, q, p.ID); err != nil {
+			return nil, err
+		}
+
+		q = `SELECT ` + prescriptionColumns + ` FROM ` + prescriptionJoins + ` WHERE rx.patient_id = $1 ORDER BY rx.created_at, rx.id`
+		if e.Prescriptions, err = queryAll(ctx, r.db, scanPrescription, q, p.ID); err != nil {
+			return nil, err
+		}
+
+		q = `SELECT ` + allergyColumns + ` FROM patient_allergies WHERE patient_id = $1 ORDER BY created_at, id`
+		if e.Allergies, err = queryAll(ctx, r.db, scanAllergy, q, p.ID); err != nil {
+			return nil, err
+		}
+
+		q = `SELECT ` + labObservationColumns + ` FROM lab_observations
+			WHERE patient_id = $1 ORDER BY observed_at NULLS LAST, created_at, id`
+		if e.LabResults, err = queryAll(ctx, r.db, scanLabObservation, q, p.ID); err != nil {
+			return nil, err
+		}
+
+		linked = append(linked, e)
+	}
+
+	return linked, nil
+}
+
+// queryAll runs q and scans every row with scan. It returns an empty slice
+// rather than nil when there are no rows, so exports show empty sections.
+func queryAll[T any](ctx context.Context, db querier, scan func(rowScanner) (*T, error), q string, args ...any) ([]T, error) {
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
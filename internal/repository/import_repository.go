@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/lib/pq"
+)
+
+type ImportRepository interface {
+	ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error)
+	UpsertBatch(ctx context.Context, users []model.ImportUser) ([]string, error)
+}
+
+type ImportRepo struct {
+	db *sql.DB
+}
+
+func NewImportRepository(db *sql.DB) *ImportRepo {
+	return &ImportRepo{
+		db: db,
+	}
+}
+
+// ExistingEmails returns which of emails already have an account, mapped to
+// whether the import must leave that account alone because it is deleted or
+// belongs to another organisation than the request's. Addresses are unique
+// across organisations, so the lookup itself is not scoped.
+func (r *ImportRepo) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	q := `SELECT email, is_deleted OR ($2::uuid IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM organisation_members m WHERE m.user_id = users.id AND m.organisation_id = $2::uuid))
+		FROM users WHERE email = ANY($1)`
+
+	rows, err := r.db.QueryContext(ctx, q, pq.Array(emails), TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var email string
+		var skip bool
+		if err := rows.Scan(&email, &skip); err != nil {
+			return nil, err
+		}
+		existing[email] = skip
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// UpsertBatch writes users in one transaction, keyed by email, and returns
+// the outcome of each. Users with a password hash are inserted, or have
+// their names updated if the address is already registered; users without
+// one only have their names updated. Deleted accounts are skipped rather
+// than revived. Within an organisation, created users become its members and
+// only its members are updated.
+//
+// The insert has no RETURNING or DO UPDATE clause: either would check the new
+// row against the users select policy, which it only passes once its
+// membership is added.
+func (r *ImportRepo) UpsertBatch(ctx context.Context, users []model.ImportUser) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return nil, err
+	}
+	orgID := TenantFromContext(ctx)
+
+	insert := `INSERT INTO users(id, first_name, last_name, email, password, organisation_id) VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (email) DO NOTHING`
+	update := `UPDATE users SET first_name = $2, last_name = $3 WHERE email = $1 AND is_deleted = false AND ` + tenantUsers
+	member := `INSERT INTO organisation_members(organisation_id, user_id) VALUES($1, $2)`
+
+	outcomes := make([]string, len(users))
+	for i, u := range users {
+		if u.PasswordHash != "" {
+			res, err := tx.ExecContext(ctx, insert, u.ID, u.FirstName, u.LastName, u.Email, u.PasswordHash, orgID)
+			if err != nil {
+				return nil, err
+			}
+			if rows, _ := res.RowsAffected(); rows == 1 {
+				outcomes[i] = model.ImportCreated
+				if orgID != nil {
+					if _, err := tx.ExecContext(ctx, member, *orgID, u.ID); err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+		}
+
+		// The address is registered, or was in the meantime.
+		res, err := tx.ExecContext(ctx, update, u.Email, u.FirstName, u.LastName)
+		if err != nil {
+			return nil, err
+		}
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			outcomes[i] = model.ImportSkipped
+		} else {
+			outcomes[i] = model.ImportUpdated
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return outcomes, nil
+}
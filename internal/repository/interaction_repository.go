@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/lib/pq"
+)
+
+// InteractionRepository stores the interaction dataset. Like the medication
+// catalogue it is shared by every organisation, so its queries are not
+// scoped.
+type InteractionRepository interface {
+	UpsertBatch(ctx context.Context, interactions []model.Interaction) (int, error)
+	ListMentioned(ctx context.Context, drug string) ([]model.Interaction, error)
+}
+
+type InteractionRepo struct {
+	db *sql.DB
+}
+
+func NewInteractionRepository(db *sql.DB) *InteractionRepo {
+	return &InteractionRepo{
+		db: db,
+	}
+}
+
+// UpsertBatch writes interactions in one statement, keyed by their pair of
+// substances, and returns how many were new or changed. The substances must
+// already be normalised.
+func (r *InteractionRepo) UpsertBatch(ctx context.Context, interactions []model.Interaction) (int, error) {
+	q := `INSERT INTO drug_interactions(substance_a, substance_b, severity, description)
+		SELECT * FROM unnest($1::varchar[], $2::varchar[], $3::varchar[], $4::varchar[])
+		ON CONFLICT (substance_a, substance_b) DO UPDATE
+			SET severity = EXCLUDED.severity, description = EXCLUDED.description, updated_at = now()
+		WHERE (drug_interactions.severity, drug_interactions.description) IS DISTINCT FROM (EXCLUDED.severity, EXCLUDED.description)`
+
+	as := make([]string, len(interactions))
+	bs := make([]string, len(interactions))
+	severities := make([]string, len(interactions))
+	descriptions := make([]string, len(interactions))
+	for i, in := range interactions {
+		as[i] = in.SubstanceA
+		bs[i] = in.SubstanceB
+		severities[i] = in.Severity
+		descriptions[i] = in.Description
+	}
+
+	res, err := r.db.ExecContext(ctx, q, pq.Array(as), pq.Array(bs), pq.Array(severities), pq.Array(descriptions))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// ListMentioned returns the interactions one of whose substances appears in
+// the name of drug. For a drug coded with an RxCUI callers pass its
+// catalogue name, which names each ingredient, rather than the name it was
+// entered as. The match is on the text alone, so callers check with
+// model.Mentions that the substance is named as a whole word.
+func (r *InteractionRepo) ListMentioned(ctx context.Context, drug string) ([]model.Interaction, error) {
+	q := `SELECT substance_a, substance_b, severity, description FROM drug_interactions
+		WHERE strpos(lower($1), substance_a) > 0 OR strpos(lower($1), substance_b) > 0
+		ORDER BY substance_a, substance_b`
+
+	rows, err := r.db.QueryContext(ctx, q, drug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interactions []model.Interaction
+	for rows.Next() {
+		var in model.Interaction
+		if err := rows.Scan(&in.SubstanceA, &in.SubstanceB, &in.Severity, &in.Description); err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, in)
+	}
+	return interactions, rows.Err()
+}
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type InvitationRepository interface {
+	EmailRegistered(ctx context.Context, email string) (bool, error)
+	Create(ctx context.Context, inv model.Invitation) (*model.Invitation, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Invitation, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error)
+	List(ctx context.Context, filter model.InvitationFilter, limit, offset int) ([]model.Invitation, error)
+	Count(ctx context.Context, filter model.InvitationFilter) (int, error)
+	Resend(ctx context.Context, id uuid.UUID, tokenHash string, expiresAt time.Time) (*model.Invitation, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	Accept(ctx context.Context, id uuid.UUID, userID uuid.UUID, passwordHash string) error
+}
+
+type InvitationRepo struct {
+	db *sql.DB
+}
+
+func NewInvitationRepository(db *sql.DB) *InvitationRepo {
+	return &InvitationRepo{
+		db: db,
+	}
+}
+
+// invitationStatus derives the status of an invitation row.
+const invitationStatus = `CASE
+		WHEN accepted_at IS NOT NULL THEN 'accepted'
+		WHEN revoked_at IS NOT NULL THEN 'revoked'
+		WHEN expires_at <= now() THEN 'expired'
+		ELSE 'pending'
+	END`
+
+// openInvitation matches invitations that have been neither accepted nor
+// revoked, whether or not they have expired.
+const openInvitation = `accepted_at IS NULL AND revoked_at IS NULL`
+
+const invitationColumns = `id, email, first_name, last_name, role, organisation_id, ` + invitationStatus + `,
+	invited_by, expires_at, sent_at, accepted_at, accepted_user_id, revoked_at, created_at, token_hash`
+
+func scanInvitation(row rowScanner) (*model.Invitation, error) {
+	var inv model.Invitation
+	if err := row.Scan(
+		&inv.ID,
+		&inv.Email,
+		&inv.FirstName,
+		&inv.LastName,
+		&inv.Role,
+		&inv.OrganisationID,
+		&inv.Status,
+		&inv.InvitedBy,
+		&inv.ExpiresAt,
+		&inv.SentAt,
+		&inv.AcceptedAt,
+		&inv.AcceptedUserID,
+		&inv.RevokedAt,
+		&inv.CreatedAt,
+		&inv.TokenHash,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *InvitationRepo) EmailRegistered(ctx context.Context, email string) (bool, error) {
+	q := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, q, email).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// Create stores inv. Expired invitations to the same address are revoked
+// first so that they do not block a new one; a pending one is reported as
+// model.ErrAlreadyExists. An unknown organisation is model.ErrNotFound.
+func (r *InvitationRepo) Create(ctx context.Context, inv model.Invitation) (*model.Invitation, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Addresses are unique across organisations, so expired invitations are
+	// revoked before the transaction is scoped to the request's organisation.
+	q := `UPDATE invitations SET revoked_at = now() WHERE email = $1 AND ` + openInvitation + ` AND expires_at <= now()`
+	if _, err := tx.ExecContext(ctx, q, inv.Email); err != nil {
+		return nil, err
+	}
+
+	if err := setTenant(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	q = `INSERT INTO invitations(id, email, first_name, last_name, role, organisation_id, token_hash, expires_at, invited_by)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING ` + invitationColumns
+	created, err := scanInvitation(tx.QueryRowContext(
+		ctx,
+		q,
+		inv.ID,
+		inv.Email,
+		inv.FirstName,
+		inv.LastName,
+		inv.Role,
+		inv.OrganisationID,
+		inv.TokenHash,
+		inv.ExpiresAt,
+		inv.InvitedBy,
+	))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return nil, model.ErrAlreadyExists
+			case "23503":
+				return nil, model.ErrNotFound
+			}
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *InvitationRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Invitation, error) {
+	q := `SELECT ` + invitationColumns + ` FROM invitations WHERE id = $1 AND ` + tenantOrganisation
+	return r.queryInvitation(ctx, q, id)
+}
+
+// queryInvitation runs a query returning one invitation within the request's
+// organisation.
+func (r *InvitationRepo) queryInvitation(ctx context.Context, q string, args ...any) (*model.Invitation, error) {
+	var inv *model.Invitation
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		inv, err = scanInvitation(db.QueryRowContext(ctx, q, args...))
+		return err
+	})
+	return inv, err
+}
+
+// GetByTokenHash looks an invitation up by the token of its link. It is used
+// before the invitee has signed in, so it is not scoped to an organisation.
+func (r *InvitationRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	q := `SELECT ` + invitationColumns + ` FROM invitations WHERE token_hash = $1`
+	return scanInvitation(r.db.QueryRowContext(ctx, q, tokenHash))
+}
+
+func invitationFilterConds(filter model.InvitationFilter) ([]string, []any) {
+	conds := []string{tenantOrganisation}
+
+	switch filter.Status {
+	case model.InvitationAccepted:
+		conds = append(conds, "accepted_at IS NOT NULL")
+	case model.InvitationRevoked:
+		conds = append(conds, "accepted_at IS NULL", "revoked_at IS NOT NULL")
+	case model.InvitationExpired:
+		conds = append(conds, openInvitation, "expires_at <= now()")
+	case model.InvitationPending:
+		conds = append(conds, openInvitation, "expires_at > now()")
+	}
+	return conds, nil
+}
+
+// List returns invitations matching filter, newest first.
+func (r *InvitationRepo) List(ctx context.Context, filter model.InvitationFilter, limit, offset int) ([]model.Invitation, error) {
+	conds, args := invitationFilterConds(filter)
+	q := fmt.Sprintf(
+		`SELECT %s FROM invitations %s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+		invitationColumns, whereClause(conds), len(args)+1, len(args)+2,
+	)
+	args = append(args, limit, offset)
+
+	var invs []model.Invitation
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			inv, err := scanInvitation(rows)
+			if err != nil {
+				return err
+			}
+			invs = append(invs, *inv)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invs, nil
+}
+
+func (r *InvitationRepo) Count(ctx context.Context, filter model.InvitationFilter) (int, error) {
+	conds, args := invitationFilterConds(filter)
+	q := `SELECT COUNT(*) FROM invitations ` + whereClause(conds)
+
+	var total int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, args...).Scan(&total)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Resend replaces the token of an open invitation, which invalidates the
+// link sent before, and restarts its expiry. Invitations that have been
+// accepted or revoked in the meantime are model.ErrConflict.
+func (r *InvitationRepo) Resend(ctx context.Context, id uuid.UUID, tokenHash string, expiresAt time.Time) (*model.Invitation, error) {
+	q := `UPDATE invitations SET token_hash = $2, expires_at = $3, sent_at = now()
+		WHERE id = $1 AND ` + openInvitation + ` AND ` + tenantOrganisation + `
+		RETURNING ` + invitationColumns
+
+	inv, err := r.queryInvitation(ctx, q, id, tokenHash, expiresAt)
+	if err != nil {
+		if err == model.ErrNotFound {
+			return nil, model.ErrConflict
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (r *InvitationRepo) Revoke(ctx context.Context, id uuid.UUID) error {
+	q := `UPDATE invitations SET revoked_at = now() WHERE id = $1 AND ` + openInvitation + ` AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			return model.ErrConflict
+		}
+		return nil
+	})
+}
+
+// Accept creates the invited user as a member of the invitation's
+// organisation and marks the invitation accepted, in one transaction. Invited
+// admins become admins of the organisation; only super_admin is kept as the
+// account's own role. An
+// invitation that is no longer pending is model.ErrConflict and an address
+// registered in the meantime is model.ErrAlreadyExists.
+func (r *InvitationRepo) Accept(ctx context.Context, id uuid.UUID, userID uuid.UUID, passwordHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := `SELECT ` + invitationColumns + ` FROM invitations
+		WHERE id = $1 AND ` + openInvitation + ` AND expires_at > now() FOR UPDATE`
+	inv, err := scanInvitation(tx.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if err == model.ErrNotFound {
+			return model.ErrConflict
+		}
+		return err
+	}
+
+	role, membership := "user", model.MemberRoleMember
+	switch inv.Role {
+	case "super_admin":
+		role, membership = "super_admin", model.MemberRoleAdmin
+	case "admin":
+		membership = model.MemberRoleAdmin
+	}
+
+	q = `INSERT INTO users(id, first_name, last_name, email, password, role, organisation_id)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := tx.ExecContext(ctx, q, userID, inv.FirstName, inv.LastName, inv.Email, passwordHash, role, inv.OrganisationID); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return model.ErrAlreadyExists
+		}
+		return err
+	}
+
+	q = `INSERT INTO organisation_members(organisation_id, user_id, role) VALUES($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, q, inv.OrganisationID, userID, membership); err != nil {
+		return err
+	}
+
+	q = `UPDATE invitations SET accepted_at = now(), accepted_user_id = $2 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, q, id, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,455 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type LabRepository interface {
+	Store(ctx context.Context, msg model.LabMessage, items []model.LabReconciliation, observations []model.LabObservation) error
+	ListObservations(ctx context.Context, patientID uuid.UUID, limit, offset int) ([]model.LabObservation, error)
+	CountObservations(ctx context.Context, patientID uuid.UUID) (int, error)
+	GetReconciliation(ctx context.Context, id uuid.UUID) (*model.LabReconciliation, error)
+	ListReconciliation(ctx context.Context, filter model.ReconciliationFilter, limit, offset int) ([]model.LabReconciliation, error)
+	CountReconciliation(ctx context.Context, filter model.ReconciliationFilter) (int, error)
+	ReconciliationObservations(ctx context.Context, id uuid.UUID) ([]model.LabObservation, error)
+	Resolve(ctx context.Context, id, patientID, resolvedBy uuid.UUID, note *string, interpretations map[uuid.UUID]string) error
+	Discard(ctx context.Context, id, resolvedBy uuid.UUID, reason string) error
+	GetObservation(ctx context.Context, id uuid.UUID) (*model.LabObservation, error)
+	ListCritical(ctx context.Context, orderedBy *uuid.UUID, limit, offset int) ([]model.LabObservation, error)
+	CountCritical(ctx context.Context, orderedBy *uuid.UUID) (int, error)
+	AcknowledgeCritical(ctx context.Context, id, acknowledgedBy uuid.UUID) error
+}
+
+type LabRepo struct {
+	db *sql.DB
+}
+
+func NewLabRepository(db *sql.DB) *LabRepo {
+	return &LabRepo{
+		db: db,
+	}
+}
+
+// Store records a message with its reconciliation items and results in one
+// transaction. A message already received from the same sender with the
+// same control ID is ErrConflict, and nothing is stored.
+func (r *LabRepo) Store(ctx context.Context, msg model.LabMessage, items []model.LabReconciliation, observations []model.LabObservation) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO lab_messages(id, organisation_id, control_id, sending_application,
+			sending_facility, message_type, raw, received_by)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (organisation_id, sending_application, sending_facility, control_id) DO NOTHING`,
+		msg.ID,
+		msg.OrganisationID,
+		msg.ControlID,
+		msg.SendingApplication,
+		msg.SendingFacility,
+		msg.MessageType,
+		msg.Raw,
+		msg.ReceivedBy,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrConflict
+	}
+
+	for _, item := range items {
+		identifiers, err := json.Marshal(item.Patient.Identifiers)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO lab_reconciliation(id, organisation_id, message_id, identifiers,
+				family_name, given_name, date_of_birth, reason)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
+			item.ID,
+			item.OrganisationID,
+			item.MessageID,
+			identifiers,
+			item.Patient.FamilyName,
+			item.Patient.GivenName,
+			item.Patient.DateOfBirth,
+			item.Reason,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	insert := `INSERT INTO lab_observations(id, organisation_id, message_id, patient_id, reconciliation_id,
+			placer_order_number, filler_order_number, ordering_provider, ordering_practitioner_id, service_code,
+			service_name, code, code_system, name, value_type, value, units, reference_range, abnormal_flags,
+			interpretation, result_status, observed_at, note)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)`
+	for _, o := range observations {
+		_, err := tx.ExecContext(ctx, insert,
+			o.ID,
+			o.OrganisationID,
+			o.MessageID,
+			o.PatientID,
+			o.ReconciliationID,
+			o.PlacerOrderNumber,
+			o.FillerOrderNumber,
+			o.OrderingProvider,
+			o.OrderingPractitionerID,
+			o.ServiceCode,
+			o.ServiceName,
+			o.Code,
+			o.CodeSystem,
+			o.Name,
+			o.ValueType,
+			o.Value,
+			o.Units,
+			o.ReferenceRange,
+			pq.Array(o.AbnormalFlags),
+			o.Interpretation,
+			o.ResultStatus,
+			o.ObservedAt,
+			o.Note,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+const labObservationColumns = `id, organisation_id, message_id, patient_id, reconciliation_id, placer_order_number,
+	filler_order_number, ordering_provider, ordering_practitioner_id, service_code, service_name, code, code_system,
+	name, value_type, value, units, reference_range, abnormal_flags, interpretation, result_status, observed_at, note,
+	acknowledged_by, acknowledged_at, created_at`
+
+func scanLabObservation(row rowScanner) (*model.LabObservation, error) {
+	var o model.LabObservation
+	if err := row.Scan(
+		&o.ID,
+		&o.OrganisationID,
+		&o.MessageID,
+		&o.PatientID,
+		&o.ReconciliationID,
+		&o.PlacerOrderNumber,
+		&o.FillerOrderNumber,
+		&o.OrderingProvider,
+		&o.OrderingPractitionerID,
+		&o.ServiceCode,
+		&o.ServiceName,
+		&o.Code,
+		&o.CodeSystem,
+		&o.Name,
+		&o.ValueType,
+		&o.Value,
+		&o.Units,
+		&o.ReferenceRange,
+		pq.Array(&o.AbnormalFlags),
+		&o.Interpretation,
+		&o.ResultStatus,
+		&o.ObservedAt,
+		&o.Note,
+		&o.AcknowledgedBy,
+		&o.AcknowledgedAt,
+		&o.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if o.AbnormalFlags == nil {
+		o.AbnormalFlags = []string{}
+	}
+	return &o, nil
+}
+
+// ListObservations returns a patient's lab results, most recently observed
+// first.
+func (r *LabRepo) ListObservations(ctx context.Context, patientID uuid.UUID, limit, offset int) ([]model.LabObservation, error) {
+	q := `SELECT ` + labObservationColumns + ` FROM lab_observations
+		WHERE patient_id = $1 AND ` + tenantOrganisation + `
+		ORDER BY observed_at DESC NULLS LAST, created_at DESC, id LIMIT $2 OFFSET $3`
+
+	return r.queryObservations(ctx, q, patientID, limit, offset)
+}
+
+func (r *LabRepo) queryObservations(ctx context.Context, q string, args ...any) ([]model.LabObservation, error) {
+	var observations []model.LabObservation
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			o, err := scanLabObservation(rows)
+			if err != nil {
+				return err
+			}
+			observations = append(observations, *o)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return observations, nil
+}
+
+func (r *LabRepo) CountObservations(ctx context.Context, patientID uuid.UUID) (int, error) {
+	q := `SELECT COUNT(*) FROM lab_observations WHERE patient_id = $1 AND ` + tenantOrganisation
+
+	var count int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, patientID).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+const labReconciliationColumns = `r.id, r.organisation_id, r.message_id, r.identifiers, r.family_name, r.given_name,
+	r.date_of_birth, r.reason, r.status,
+	(SELECT COUNT(*) FROM lab_observations o WHERE o.reconciliation_id = r.id),
+	r.patient_id, r.resolved_by, r.resolved_at, r.note, r.created_at`
+
+func scanLabReconciliation(row rowScanner) (*model.LabReconciliation, error) {
+	var item model.LabReconciliation
+	var identifiers []byte
+	var dob sql.NullTime
+	if err := row.Scan(
+		&item.ID,
+		&item.OrganisationID,
+		&item.MessageID,
+		&identifiers,
+		&item.Patient.FamilyName,
+		&item.Patient.GivenName,
+		&dob,
+		&item.Reason,
+		&item.Status,
+		&item.Observations,
+		&item.PatientID,
+		&item.ResolvedBy,
+		&item.ResolvedAt,
+		&item.Note,
+		&item.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(identifiers, &item.Patient.Identifiers); err != nil {
+		return nil, err
+	}
+	if dob.Valid {
+		s := dob.Time.Format(model.DateLayout)
+		item.Patient.DateOfBirth = &s
+	}
+	return &item, nil
+}
+
+func (r *LabRepo) GetReconciliation(ctx context.Context, id uuid.UUID) (*model.LabReconciliation, error) {
+	q := `SELECT ` + labReconciliationColumns + ` FROM lab_reconciliation r WHERE r.id = $1 AND ` + tenantOrganisationOf("r")
+
+	var item *model.LabReconciliation
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		item, err = scanLabReconciliation(db.QueryRowContext(ctx, q, id))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ListReconciliation returns the reconciliation items with a status, oldest
+// first, so the queue is worked in the order results arrived.
+func (r *LabRepo) ListReconciliation(ctx context.Context, filter model.ReconciliationFilter, limit, offset int) ([]model.LabReconciliation, error) {
+	q := `SELECT ` + labReconciliationColumns + ` FROM lab_reconciliation r
+		WHERE r.status = $1 AND ` + tenantOrganisationOf("r") + `
+		ORDER BY r.created_at, r.id LIMIT $2 OFFSET $3`
+
+	var items []model.LabReconciliation
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, filter.Status, limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			item, err := scanLabReconciliation(rows)
+			if err != nil {
+				return err
+			}
+			items = append(items, *item)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *LabRepo) CountReconciliation(ctx context.Context, filter model.ReconciliationFilter) (int, error) {
+	q := `SELECT COUNT(*) FROM lab_reconciliation WHERE status = $1 AND ` + tenantOrganisation
+
+	var count int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, filter.Status).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ReconciliationObservations returns the results held by a reconciliation
+// item.
+func (r *LabRepo) ReconciliationObservations(ctx context.Context, id uuid.UUID) ([]model.LabObservation, error) {
+	q := `SELECT ` + labObservationColumns + ` FROM lab_observations
+		WHERE reconciliation_id = $1 AND ` + tenantOrganisation + `
+		ORDER BY created_at, id`
+
+	return r.queryObservations(ctx, q, id)
+}
+
+// Resolve files the results of a pending reconciliation item under a live
+// patient of the same organisation, with the interpretations of the
+// results for that patient by result ID. It is ErrNotFound if the item is
+// no longer pending or the patient does not qualify.
+func (r *LabRepo) Resolve(ctx context.Context, id, patientID, resolvedBy uuid.UUID, note *string, interpretations map[uuid.UUID]string) error {
+	resolve := `UPDATE lab_reconciliation r
+		SET status = 'resolved', patient_id = p.id, resolved_by = $3, resolved_at = now(), note = $4
+		FROM patients p
+		WHERE r.id = $1 AND r.status = 'pending' AND p.id = $2 AND p.organisation_id = r.organisation_id
+			AND p.is_deleted = false AND ` + tenantOrganisationOf("r")
+	file := `UPDATE lab_observations SET patient_id = $2 WHERE reconciliation_id = $1`
+	interpret := `UPDATE lab_observations SET interpretation = $3 WHERE id = $1 AND reconciliation_id = $2`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, resolve, id, patientID, resolvedBy, note)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, file, id, patientID); err != nil {
+		return err
+	}
+	for observationID, interpretation := range interpretations {
+		if _, err := tx.ExecContext(ctx, interpret, observationID, id, interpretation); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Discard closes a pending reconciliation item without filing its results,
+// or returns ErrNotFound.
+func (r *LabRepo) Discard(ctx context.Context, id, resolvedBy uuid.UUID, reason string) error {
+	q := `UPDATE lab_reconciliation SET status = 'discarded', resolved_by = $2, resolved_at = now(), note = $3
+		WHERE id = $1 AND status = 'pending' AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, id, resolvedBy, reason)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *LabRepo) GetObservation(ctx context.Context, id uuid.UUID) (*model.LabObservation, error) {
+	q := `SELECT ` + labObservationColumns + ` FROM lab_observations WHERE id = $1 AND ` + tenantOrganisation
+
+	var o *model.LabObservation
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		o, err = scanLabObservation(db.QueryRowContext(ctx, q, id))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// criticalFilter matches the critical results filed under a patient that
+// nobody has acknowledged, ordered by the practitioner signed in as user $1
+// unless $1 is NULL.
+const criticalFilter = `interpretation = 'critical' AND acknowledged_at IS NULL AND patient_id IS NOT NULL
+	AND ($1::uuid IS NULL OR ordering_practitioner_id IN (
+		SELECT id FROM practitioners WHERE user_id = $1 AND is_deleted = false))`
+
+// ListCritical returns unacknowledged critical results, oldest first, so
+// the longest waiting are seen first. orderedBy limits them to those
+// ordered by the practitioner with that user ID.
+func (r *LabRepo) ListCritical(ctx context.Context, orderedBy *uuid.UUID, limit, offset int) ([]model.LabObservation, error) {
+	q := `SELECT ` + labObservationColumns + ` FROM lab_observations
+		WHERE ` + criticalFilter + ` AND ` + tenantOrganisation + `
+		ORDER BY created_at, id LIMIT $2 OFFSET $3`
+
+	return r.queryObservations(ctx, q, orderedBy, limit, offset)
+}
+
+func (r *LabRepo) CountCritical(ctx context.Context, orderedBy *uuid.UUID) (int, error) {
+	q := `SELECT COUNT(*) FROM lab_observations WHERE ` + criticalFilter + ` AND ` + tenantOrganisation
+
+	var count int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, orderedBy).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// AcknowledgeCritical records that a critical result has been seen, or
+// returns ErrNotFound if it is not an unacknowledged critical result.
+func (r *LabRepo) AcknowledgeCritical(ctx context.Context, id, acknowledgedBy uuid.UUID) error {
+	q := `UPDATE lab_observations SET acknowledged_by = $2, acknowledged_at = now()
+		WHERE id = $1 AND interpretation = 'critical' AND acknowledged_at IS NULL AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, id, acknowledgedBy)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
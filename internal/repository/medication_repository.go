@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/lib/pq"
+)
+
+// MedicationRepository stores the medication catalogue. The catalogue is
+// shared by every organisation, so its queries are not scoped.
+type MedicationRepository interface {
+	UpsertBatch(ctx context.Context, medications []model.Medication) (int, error)
+	GetByRxCUI(ctx context.Context, rxcui string) (*model.Medication, error)
+	List(ctx context.Context, filter model.MedicationFilter, limit, offset int) ([]model.Medication, error)
+	Count(ctx context.Context, filter model.MedicationFilter) (int, error)
+}
+
+type MedicationRepo struct {
+	db *sql.DB
+}
+
+func NewMedicationRepository(db *sql.DB) *MedicationRepo {
+	return &MedicationRepo{
+		db: db,
+	}
+}
+
+// UpsertBatch writes medications in one statement, keyed by RxCUI, and
+// returns how many were new or changed. Entries that are already in the
+// catalogue as they are are left alone.
+func (r *MedicationRepo) UpsertBatch(ctx context.Context, medications []model.Medication) (int, error) {
+	q := `INSERT INTO medications(rxcui, name, term_type)
+		SELECT * FROM unnest($1::varchar[], $2::text[], $3::varchar[])
+		ON CONFLICT (rxcui) DO UPDATE SET name = EXCLUDED.name, term_type = EXCLUDED.term_type, updated_at = now()
+		WHERE (medications.name, medications.term_type) IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.term_type)`
+
+	rxcuis := make([]string, len(medications))
+	names := make([]string, len(medications))
+	termTypes := make([]string, len(medications))
+	for i, m := range medications {
+		rxcuis[i] = m.RxCUI
+		names[i] = m.Name
+		termTypes[i] = m.TermType
+	}
+
+	res, err := r.db.ExecContext(ctx, q, pq.Array(rxcuis), pq.Array(names), pq.Array(termTypes))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+func (r *MedicationRepo) GetByRxCUI(ctx context.Context, rxcui string) (*model.Medication, error) {
+	q := `SELECT rxcui, name, term_type, updated_at FROM medications WHERE rxcui = $1`
+
+	var m model.Medication
+	if err := r.db.QueryRowContext(ctx, q, rxcui).Scan(&m.RxCUI, &m.Name, &m.TermType, &m.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// medicationFilterConds builds the WHERE conditions for filter.
+func medicationFilterConds(filter model.MedicationFilter) ([]string, []any) {
+	var conds []string
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Search != "" {
+		conds = append(conds, "name ILIKE "+arg("%"+escapeLike(filter.Search)+"%"))
+	}
+	if filter.TermType != "" {
+		conds = append(conds, "term_type = "+arg(filter.TermType))
+	}
+
+	return conds, args
+}
+
+// List returns a page of the catalogue, shortest names first so that
+// ingredients come before the drugs made from them.
+func (r *MedicationRepo) List(ctx context.Context, filter model.MedicationFilter, limit, offset int) ([]model.Medication, error) {
+	conds, args := medicationFilterConds(filter)
+	q := fmt.Sprintf(`SELECT rxcui, name, term_type, updated_at FROM medications %s
+		ORDER BY length(name), name, rxcui LIMIT $%d OFFSET $%d`,
+		whereClause(conds), len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var medications []model.Medication
+	for rows.Next() {
+		var m model.Medication
+		if err := rows.Scan(&m.RxCUI, &m.Name, &m.TermType, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		medications = append(medications, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return medications, nil
+}
+
+func (r *MedicationRepo) Count(ctx context.Context, filter model.MedicationFilter) (int, error) {
+	conds, args := medicationFilterConds(filter)
+	q := `SELECT COUNT(*) FROM medications ` + whereClause(conds)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, q, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type OrganisationRepository interface {
+	Create(ctx context.Context, org model.Organisation) (*model.Organisation, error)
+	List(ctx context.Context, memberID *uuid.UUID) ([]model.Organisation, error)
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]model.Member, error)
+	GetMember(ctx context.Context, orgID, userID uuid.UUID) (*model.Member, error)
+	SetMember(ctx context.Context, orgID, userID uuid.UUID, role string) error
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+}
+
+type OrganisationRepo struct {
+	db *sql.DB
+}
+
+func NewOrganisationRepository(db *sql.DB) *OrganisationRepo {
+	return &OrganisationRepo{
+		db: db,
+	}
+}
+
+func (r *OrganisationRepo) Create(ctx context.Context, org model.Organisation) (*model.Organisation, error) {
+	q := `INSERT INTO organisations(id, name) VALUES($1, $2) RETURNING created_at`
+
+	if err := r.db.QueryRowContext(ctx, q, org.ID, org.Name).Scan(&org.CreatedAt); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, model.ErrAlreadyExists
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// List returns all organisations, or only those memberID belongs to when it
+// is given.
+func (r *OrganisationRepo) List(ctx context.Context, memberID *uuid.UUID) ([]model.Organisation, error) {
+	q := `SELECT id, name, created_at FROM organisations
+		WHERE $1::uuid IS NULL OR id IN (SELECT organisation_id FROM organisation_members WHERE user_id = $1::uuid)
+		ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, q, memberID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []model.Organisation
+	for rows.Next() {
+		var org model.Organisation
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+const memberColumns = `m.user_id, u.first_name, u.last_name, u.email, m.role, m.created_at`
+
+func scanMember(row rowScanner) (*model.Member, error) {
+	var m model.Member
+	if err := row.Scan(&m.UserID, &m.FirstName, &m.LastName, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ListMembers returns the live members of orgID by name.
+func (r *OrganisationRepo) ListMembers(ctx context.Context, orgID uuid.UUID) ([]model.Member, error) {
+	q := `SELECT ` + memberColumns + ` FROM organisation_members m JOIN users u ON u.id = m.user_id
+		WHERE m.organisation_id = $1 AND u.is_deleted = false
+		ORDER BY u.last_name, u.first_name, u.id`
+
+	var members []model.Member
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			m, err := scanMember(rows)
+			if err != nil {
+				return err
+			}
+			members = append(members, *m)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (r *OrganisationRepo) GetMember(ctx context.Context, orgID, userID uuid.UUID) (*model.Member, error) {
+	q := `SELECT ` + memberColumns + ` FROM organisation_members m JOIN users u ON u.id = m.user_id
+		WHERE m.organisation_id = $1 AND m.user_id = $2 AND u.is_deleted = false`
+
+	var m *model.Member
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		m, err = scanMember(db.QueryRowContext(ctx, q, orgID, userID))
+		return err
+	})
+	return m, err
+}
+
+// SetMember adds userID to orgID with role, or changes the role they already
+// have there. An unknown organisation or user is model.ErrNotFound.
+func (r *OrganisationRepo) SetMember(ctx context.Context, orgID, userID uuid.UUID, role string) error {
+	q := `INSERT INTO organisation_members(organisation_id, user_id, role)
+		SELECT $1, id, $3 FROM users WHERE id = $2 AND is_deleted = false
+		ON CONFLICT (organisation_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, orgID, userID, role)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return model.ErrNotFound
+			}
+			return err
+		}
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// RemoveMember removes userID from orgID. If it was the user's default
+// organisation the default is cleared, so they next sign in to the
+// organisation they joined first.
+func (r *OrganisationRepo) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	// The user is only visible within the organisation while still a member,
+	// so the default is cleared first.
+	q := `UPDATE users SET organisation_id = NULL WHERE id = $2 AND organisation_id = $1`
+	if _, err := tx.ExecContext(ctx, q, orgID, userID); err != nil {
+		return err
+	}
+
+	q = `DELETE FROM organisation_members WHERE organisation_id = $1 AND user_id = $2`
+	res, err := tx.ExecContext(ctx, q, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return model.ErrNotFound
+	}
+
+	return tx.Commit()
+}
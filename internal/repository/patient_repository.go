@@ -0,0 +1,565 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PatientRepository interface {
+	Create(ctx context.Context, patient model.Patient) (*model.Patient, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Patient, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]model.PatientSummary, error)
+	FindByIdentifiers(ctx context.Context, mrns []string, identifiers []model.CreatePatientIdentifier) ([]model.PatientSummary, error)
+	List(ctx context.Context, filter model.PatientFilter, limit, offset int) ([]model.PatientSummary, error)
+	Count(ctx context.Context, filter model.PatientFilter) (int, error)
+	UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdatePatient, cond model.Precondition) (int64, error)
+	DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error
+	AddIdentifier(ctx context.Context, patientID uuid.UUID, identifier model.PatientIdentifier) (*model.PatientIdentifier, error)
+	RemoveIdentifier(ctx context.Context, patientID, identifierID uuid.UUID) error
+	SetUser(ctx context.Context, id uuid.UUID, userID *uuid.UUID) error
+}
+
+type PatientRepo struct {
+	db *sql.DB
+}
+
+func NewPatientRepository(db *sql.DB) *PatientRepo {
+	return &PatientRepo{
+		db: db,
+	}
+}
+
+// patientSortColumns maps the sortable API fields to their columns.
+var patientSortColumns = map[string]string{
+	"mrn":           "mrn",
+	"first_name":    "first_name",
+	"last_name":     "last_name",
+	"date_of_birth": "date_of_birth",
+	"created_at":    "created_at",
+}
+
+// Create registers patient with the next MRN of its organisation, together
+// with its identifiers. A linked user must be a member of the organisation.
+func (r *PatientRepo) Create(ctx context.Context, patient model.Patient) (*model.Patient, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	if patient.UserID != nil {
+		if err := checkMember(ctx, tx, patient.OrganisationID, *patient.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	q := `INSERT INTO patient_mrn_sequences(organisation_id, last_value) VALUES($1, 1)
+		ON CONFLICT (organisation_id) DO UPDATE SET last_value = patient_mrn_sequences.last_value + 1
+		RETURNING last_value`
+
+	var n int64
+	if err := tx.QueryRowContext(ctx, q, patient.OrganisationID).Scan(&n); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	patient.MRN = model.FormatMRN(n)
+
+	a := patient.Address
+	if a == nil {
+		a = &model.Address{}
+	}
+
+	q = `INSERT INTO patients(id, organisation_id, mrn, first_name, last_name, date_of_birth, sex, gender, phone, email, preferred_language,
+			address_line1, address_line2, address_city, address_region, address_postal_code, address_country, user_id, created_by)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING created_at, updated_at, version`
+
+	if err := tx.QueryRowContext(ctx, q,
+		patient.ID,
+		patient.OrganisationID,
+		patient.MRN,
+		patient.FirstName,
+		patient.LastName,
+		patient.DateOfBirth,
+		patient.Sex,
+		patient.Gender,
+		patient.Phone,
+		patient.Email,
+		patient.PreferredLanguage,
+		nullString(a.Line1),
+		nullString(a.Line2),
+		nullString(a.City),
+		nullString(a.Region),
+		nullString(a.PostalCode),
+		nullString(a.Country),
+		patient.UserID,
+		patient.CreatedBy,
+	).Scan(&patient.CreatedAt, &patient.UpdatedAt, &patient.Version); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, model.ErrAlreadyExists
+		}
+		return nil, err
+	}
+
+	for i := range patient.Identifiers {
+		if err := insertIdentifier(ctx, tx, patient.ID, patient.OrganisationID, &patient.Identifiers[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &patient, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// checkMember returns ErrNotFound unless userID is a live member of
+// orgID.
+func checkMember(ctx context.Context, tx *sql.Tx, orgID, userID uuid.UUID) error {
+	q := `SELECT EXISTS (SELECT 1 FROM organisation_members m JOIN users u ON u.id = m.user_id
+		WHERE m.organisation_id = $1 AND m.user_id = $2 AND u.is_deleted = false)`
+
+	var ok bool
+	if err := tx.QueryRowContext(ctx, q, orgID, userID).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// insertIdentifier adds identifier to a patient. An identifier whose system
+// and value are taken in the organisation, or a second one of the same
+// system, is ErrAlreadyExists.
+func insertIdentifier(ctx context.Context, tx *sql.Tx, patientID, orgID uuid.UUID, identifier *model.PatientIdentifier) error {
+	q := `INSERT INTO patient_identifiers(id, patient_id, organisation_id, system, value)
+		VALUES($1, $2, $3, $4, $5) RETURNING created_at`
+
+	if err := tx.QueryRowContext(ctx, q, identifier.ID, patientID, orgID, identifier.System, identifier.Value).Scan(&identifier.CreatedAt); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return model.ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+const patientColumns = `id, organisation_id, mrn, first_name, last_name, date_of_birth, sex, gender, phone, email, preferred_language,
+	address_line1, address_line2, address_city, address_region, address_postal_code, address_country,
+	user_id, created_by, created_at, updated_at, version`
+
+func scanPatient(row rowScanner) (*model.Patient, error) {
+	var p model.Patient
+	var dob sql.NullTime
+	var line1, line2, city, region, postalCode, country sql.NullString
+	if err := row.Scan(
+		&p.ID,
+		&p.OrganisationID,
+		&p.MRN,
+		&p.FirstName,
+		&p.LastName,
+		&dob,
+		&p.Sex,
+		&p.Gender,
+		&p.Phone,
+		&p.Email,
+		&p.PreferredLanguage,
+		&line1,
+		&line2,
+		&city,
+		&region,
+		&postalCode,
+		&country,
+		&p.UserID,
+		&p.CreatedBy,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.Version,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+
+	p.DateOfBirth = dob.Time.Format(model.DateLayout)
+
+	addr := model.Address{
+		Line1:      line1.String,
+		Line2:      line2.String,
+		City:       city.String,
+		Region:     region.String,
+		PostalCode: postalCode.String,
+		Country:    country.String,
+	}
+	if !addr.IsZero() {
+		p.Address = &addr
+	}
+
+	return &p, nil
+}
+
+// GetByID returns a live patient with its identifiers.
+func (r *PatientRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+	q := `SELECT ` + patientColumns + ` FROM patients WHERE id = $1 AND is_deleted = false AND ` + tenantOrganisation
+
+	var p *model.Patient
+	err := inTenant(ctx, r.db, func(db querier) error {
+		var err error
+		if p, err = scanPatient(db.QueryRowContext(ctx, q, id)); err != nil {
+			return err
+		}
+
+		rows, err := db.QueryContext(ctx, `SELECT id, system, value, created_at FROM patient_identifiers
+			WHERE patient_id = $1 ORDER BY system`, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		p.Identifiers = []model.PatientIdentifier{}
+		for rows.Next() {
+			var identifier model.PatientIdentifier
+			if err := rows.Scan(&identifier.ID, &identifier.System, &identifier.Value, &identifier.CreatedAt); err != nil {
+				return err
+			}
+			p.Identifiers = append(p.Identifiers, identifier)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// ListByUser returns the live patient records linked to userID.
+func (r *PatientRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.PatientSummary, error) {
+	q := `SELECT id, mrn, first_name, last_name, date_of_birth, sex FROM patients
+		WHERE user_id = $1 AND is_deleted = false AND ` + tenantOrganisation + ` ORDER BY created_at, id`
+
+	return r.queryPatients(ctx, q, userID)
+}
+
+// FindByIdentifiers returns the live patients with one of the MRNs or one of
+// the identifiers, matched on both system and value.
+func (r *PatientRepo) FindByIdentifiers(ctx context.Context, mrns []string, identifiers []model.CreatePatientIdentifier) ([]model.PatientSummary, error) {
+	systems := make([]string, len(identifiers))
+	values := make([]string, len(identifiers))
+	for i, id := range identifiers {
+		systems[i] = id.System
+		values[i] = id.Value
+	}
+
+	q := `SELECT id, mrn, first_name, last_name, date_of_birth, sex FROM patients
+		WHERE is_deleted = false AND ` + tenantOrganisation + ` AND (mrn = ANY($1) OR EXISTS (
+			SELECT 1 FROM patient_identifiers i JOIN unnest($2::text[], $3::text[]) AS x(system, value)
+				ON i.system = x.system AND i.value = x.value
+			WHERE i.patient_id = patients.id))
+		ORDER BY created_at, id`
+
+	return r.queryPatients(ctx, q, pq.Array(mrns), pq.Array(systems), pq.Array(values))
+}
+
+// patientFilterConds builds the WHERE conditions for filter, limited to the
+// request's organisation.
+func patientFilterConds(filter model.PatientFilter) ([]string, []any) {
+	conds := []string{tenantOrganisation, "is_deleted = false"}
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Search != "" {
+		p := arg("%" + escapeLike(filter.Search) + "%")
+		exact := arg(filter.Search)
+		conds = append(conds, fmt.Sprintf(`((first_name || ' ' || last_name) ILIKE %s OR mrn = %s
+			OR EXISTS (SELECT 1 FROM patient_identifiers i WHERE i.patient_id = patients.id AND i.value = %s))`, p, exact, exact))
+	}
+
+	if filter.DateOfBirth != "" {
+		conds = append(conds, "date_of_birth = "+arg(filter.DateOfBirth))
+	}
+
+	return conds, args
+}
+
+// patientOrderClause builds the ORDER BY clause for sort, by name unless
+// another order is requested.
+func patientOrderClause(sort []model.SortField) string {
+	var keys []string
+	for _, s := range sort {
+		col, ok := patientSortColumns[s.Field]
+		if !ok {
+			continue
+		}
+		if s.Desc {
+			col += " DESC"
+		}
+		keys = append(keys, col)
+	}
+	if len(keys) == 0 {
+		keys = append(keys, "last_name", "first_name")
+	}
+	keys = append(keys, "id")
+	return "ORDER BY " + strings.Join(keys, ", ")
+}
+
+func (r *PatientRepo) List(ctx context.Context, filter model.PatientFilter, limit, offset int) ([]model.PatientSummary, error) {
+	conds, args := patientFilterConds(filter)
+	q := fmt.Sprintf(
+		`SELECT id, mrn, first_name, last_name, date_of_birth, sex FROM patients %s %s LIMIT $%d OFFSET $%d`,
+		whereClause(conds), patientOrderClause(filter.Sort), len(args)+1, len(args)+2,
+	)
+	args = append(args, limit, offset)
+
+	return r.queryPatients(ctx, q, args...)
+}
+
+func (r *PatientRepo) queryPatients(ctx context.Context, q string, args ...any) ([]model.PatientSummary, error) {
+	var patients []model.PatientSummary
+
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p model.PatientSummary
+			var dob sql.NullTime
+			if err := rows.Scan(&p.ID, &p.MRN, &p.FirstName, &p.LastName, &dob, &p.Sex); err != nil {
+				return err
+			}
+			p.DateOfBirth = dob.Time.Format(model.DateLayout)
+			patients = append(patients, p)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return patients, nil
+}
+
+func (r *PatientRepo) Count(ctx context.Context, filter model.PatientFilter) (int, error) {
+	conds, args := patientFilterConds(filter)
+	q := `SELECT COUNT(*) FROM patients ` + whereClause(conds)
+
+	var count int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, args...).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// lockPatient locks a live patient row for the rest of tx, checks cond
+// against its version and returns its organisation.
+func lockPatient(ctx context.Context, tx *sql.Tx, id uuid.UUID, cond model.Precondition) (uuid.UUID, error) {
+	q := `SELECT organisation_id, is_deleted, version FROM patients WHERE id = $1 AND ` + tenantOrganisation + ` FOR UPDATE`
+
+	var orgID uuid.UUID
+	var isDeleted bool
+	var version int64
+	if err := tx.QueryRowContext(ctx, q, id).Scan(&orgID, &isDeleted, &version); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, model.ErrNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	if isDeleted {
+		return uuid.Nil, model.ErrNotFound
+	}
+	if !cond.Matches(version) {
+		return uuid.Nil, model.ErrPreconditionFailed
+	}
+	return orgID, nil
+}
+
+// anyVersion is the precondition of writes that do not take If-Match.
+var anyVersion = model.Precondition{Any: true}
+
+// touchPatient bumps the version of a patient whose identifiers changed, so
+// its ETag changes too.
+func touchPatient(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `UPDATE patients SET updated_at = now() WHERE id = $1`, id)
+	return err
+}
+
+// patientTx runs fn in a transaction scoped to the request's organisation
+// with the patient row locked. fn receives the patient's organisation.
+func (r *PatientRepo) patientTx(ctx context.Context, id uuid.UUID, cond model.Precondition, fn func(tx *sql.Tx, orgID uuid.UUID) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	orgID, err := lockPatient(ctx, tx, id, cond)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx, orgID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateByID applies a merge patch if cond matches the current version and
+// returns the new version.
+func (r *PatientRepo) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdatePatient, cond model.Precondition) (int64, error) {
+	if data == nil {
+		return 0, model.ErrBadRequest
+	}
+
+	sets, args := patientPatchSets(data)
+	if len(sets) == 0 {
+		return 0, model.ErrBadRequest
+	}
+
+	var version int64
+	err := r.patientTx(ctx, id, cond, func(tx *sql.Tx, _ uuid.UUID) error {
+		args = append(args, id)
+		q := fmt.Sprintf(`UPDATE patients SET %s WHERE id = $%d RETURNING version`, strings.Join(sets, ", "), len(args))
+		return tx.QueryRowContext(ctx, q, args...).Scan(&version)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// patientPatchSets builds the SET assignments for the members present in a
+// merge patch. Null members are stored as NULL.
+func patientPatchSets(data *model.UpdatePatient) ([]string, []any) {
+	var sets []string
+	var args []any
+
+	set := func(col string, o model.Optional[string]) {
+		if !o.Set {
+			return
+		}
+		if o.Null {
+			sets = append(sets, col+" = NULL")
+			return
+		}
+		args = append(args, o.Value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", col, len(args)))
+	}
+
+	set("first_name", data.FirstName)
+	set("last_name", data.LastName)
+	set("date_of_birth", data.DateOfBirth)
+	set("sex", data.Sex)
+	set("gender", data.Gender)
+	set("phone", data.Phone)
+	set("email", data.Email)
+	set("preferred_language", data.PreferredLanguage)
+
+	if data.Address.Null {
+		for _, col := range addressColumns {
+			sets = append(sets, col+" = NULL")
+		}
+	} else if data.Address.Set {
+		a := data.Address.Value
+		set("address_line1", a.Line1)
+		set("address_line2", a.Line2)
+		set("address_city", a.City)
+		set("address_region", a.Region)
+		set("address_postal_code", a.PostalCode)
+		set("address_country", a.Country)
+	}
+
+	return sets, args
+}
+
+// DeleteByID soft-deletes a patient if cond matches the current version. The
+// user link is removed so the login can be linked to a new record.
+func (r *PatientRepo) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
+	return r.patientTx(ctx, id, cond, func(tx *sql.Tx, _ uuid.UUID) error {
+		_, err := tx.ExecContext(ctx, `UPDATE patients SET is_deleted = true, deleted_at = now(), user_id = NULL WHERE id = $1`, id)
+		return err
+	})
+}
+
+func (r *PatientRepo) AddIdentifier(ctx context.Context, patientID uuid.UUID, identifier model.PatientIdentifier) (*model.PatientIdentifier, error) {
+	err := r.patientTx(ctx, patientID, anyVersion, func(tx *sql.Tx, orgID uuid.UUID) error {
+		if err := insertIdentifier(ctx, tx, patientID, orgID, &identifier); err != nil {
+			return err
+		}
+		return touchPatient(ctx, tx, patientID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &identifier, nil
+}
+
+func (r *PatientRepo) RemoveIdentifier(ctx context.Context, patientID, identifierID uuid.UUID) error {
+	return r.patientTx(ctx, patientID, anyVersion, func(tx *sql.Tx, _ uuid.UUID) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM patient_identifiers WHERE id = $1 AND patient_id = $2`, identifierID, patientID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		return touchPatient(ctx, tx, patientID)
+	})
+}
+
+// SetUser links the patient to userID, or unlinks it when userID is nil. The
+// user must be a member of the patient's organisation and not already be
+// linked to another of its patients.
+func (r *PatientRepo) SetUser(ctx context.Context, id uuid.UUID, userID *uuid.UUID) error {
+	return r.patientTx(ctx, id, anyVersion, func(tx *sql.Tx, orgID uuid.UUID) error {
+		if userID != nil {
+			if err := checkMember(ctx, tx, orgID, *userID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE patients SET user_id = $1 WHERE id = $2`, userID, id); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return model.ErrAlreadyExists
+			}
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,565 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PractitionerRepository interface {
+	CreateFacility(ctx context.Context, facility model.Facility) (*model.Facility, error)
+	ListFacilities(ctx context.Context) ([]model.Facility, error)
+	Create(ctx context.Context, practitioner model.Practitioner, facilityIDs []uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Practitioner, error)
+	List(ctx context.Context, filter model.PractitionerFilter, limit, offset int) ([]model.PractitionerSummary, error)
+	Count(ctx context.Context, filter model.PractitionerFilter) (int, error)
+	UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdatePractitioner, cond model.Precondition) (int64, error)
+	DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error
+	AddLicence(ctx context.Context, practitionerID uuid.UUID, licence model.Licence) (*model.Licence, error)
+	RemoveLicence(ctx context.Context, practitionerID, licenceID uuid.UUID) error
+	FlagExpiringLicences(ctx context.Context, cutoff time.Time) ([]model.ExpiringLicence, error)
+	IsPractitioner(ctx context.Context, userID uuid.UUID) (bool, error)
+	FindByNPI(ctx context.Context, npi string) (*model.Practitioner, error)
+}
+
+type PractitionerRepo struct {
+	db *sql.DB
+}
+
+func NewPractitionerRepository(db *sql.DB) *PractitionerRepo {
+	return &PractitionerRepo{
+		db: db,
+	}
+}
+
+func (r *PractitionerRepo) CreateFacility(ctx context.Context, facility model.Facility) (*model.Facility, error) {
+	q := `INSERT INTO facilities(id, organisation_id, name, address_line1, address_line2, address_city, address_region, address_postal_code, address_country)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING created_at`
+
+	a := facility.Address
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q,
+			facility.ID,
+			facility.OrganisationID,
+			facility.Name,
+			a.Line1,
+			nullString(a.Line2),
+			a.City,
+			nullString(a.Region),
+			nullString(a.PostalCode),
+			a.Country,
+		).Scan(&facility.CreatedAt)
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return nil, model.ErrAlreadyExists
+			case "23503":
+				return nil, model.ErrNotFound
+			}
+		}
+		return nil, err
+	}
+
+	return &facility, nil
+}
+
+const facilityColumns = `f.id, f.organisation_id, f.name, f.address_line1, f.address_line2, f.address_city,
+	f.address_region, f.address_postal_code, f.address_country, f.created_at`
+
+func scanFacility(row rowScanner) (*model.Facility, error) {
+	var f model.Facility
+	var line2, region, postalCode sql.NullString
+	if err := row.Scan(
+		&f.ID,
+		&f.OrganisationID,
+		&f.Name,
+		&f.Address.Line1,
+		&line2,
+		&f.Address.City,
+		&region,
+		&postalCode,
+		&f.Address.Country,
+		&f.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	f.Address.Line2 = line2.String
+	f.Address.Region = region.String
+	f.Address.PostalCode = postalCode.String
+	return &f, nil
+}
+
+func queryFacilities(ctx context.Context, db querier, q string, args ...any) ([]model.Facility, error) {
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facilities := []model.Facility{}
+	for rows.Next() {
+		f, err := scanFacility(rows)
+		if err != nil {
+			return nil, err
+		}
+		facilities = append(facilities, *f)
+	}
+
+	return facilities, rows.Err()
+}
+
+// ListFacilities returns the facilities of the request's organisation by
+// name.
+func (r *PractitionerRepo) ListFacilities(ctx context.Context) ([]model.Facility, error) {
+	q := `SELECT ` + facilityColumns + ` FROM facilities f WHERE ` + tenantOrganisationOf("f") + ` ORDER BY f.name, f.id`
+
+	var facilities []model.Facility
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		facilities, err = queryFacilities(ctx, db, q)
+		return err
+	})
+	return facilities, err
+}
+
+// Create registers practitioner with its licences and the facilities it
+// works at. The user must be a member of the organisation and the facilities
+// must belong to it.
+func (r *PractitionerRepo) Create(ctx context.Context, practitioner model.Practitioner, facilityIDs []uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := checkMember(ctx, tx, practitioner.OrganisationID, practitioner.UserID); err != nil {
+		return err
+	}
+
+	q := `INSERT INTO practitioners(id, organisation_id, user_id, profession, npi, specialties)
+		VALUES($1, $2, $3, $4, $5, $6)`
+
+	if _, err := tx.ExecContext(ctx, q,
+		practitioner.ID,
+		practitioner.OrganisationID,
+		practitioner.UserID,
+		practitioner.Profession,
+		practitioner.NPI,
+		pq.Array(practitioner.Specialties),
+	); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return model.ErrAlreadyExists
+		}
+		return err
+	}
+
+	if err := setFacilities(ctx, tx, practitioner.ID, practitioner.OrganisationID, facilityIDs); err != nil {
+		return err
+	}
+
+	for i := range practitioner.Licences {
+		if err := insertLicence(ctx, tx, practitioner.ID, practitioner.OrganisationID, &practitioner.Licences[i]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// setFacilities replaces the facilities a practitioner works at. A facility
+// that does not exist in orgID is ErrNotFound.
+func setFacilities(ctx context.Context, tx *sql.Tx, practitionerID, orgID uuid.UUID, facilityIDs []uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM practitioner_facilities WHERE practitioner_id = $1`, practitionerID); err != nil {
+		return err
+	}
+	if len(facilityIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(facilityIDs))
+	for i, id := range facilityIDs {
+		ids[i] = id.String()
+	}
+
+	q := `INSERT INTO practitioner_facilities(practitioner_id, facility_id, organisation_id)
+		SELECT $1, id, organisation_id FROM facilities WHERE id = ANY($2::uuid[]) AND organisation_id = $3`
+
+	res, err := tx.ExecContext(ctx, q, practitionerID, pq.Array(ids), orgID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); int(n) != len(facilityIDs) {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// insertLicence adds a licence to a practitioner. A number already
+// registered in the same jurisdiction is ErrAlreadyExists.
+func insertLicence(ctx context.Context, tx *sql.Tx, practitionerID, orgID uuid.UUID, licence *model.Licence) error {
+	q := `INSERT INTO practitioner_licences(id, practitioner_id, organisation_id, number, jurisdiction, expires_on)
+		VALUES($1, $2, $3, $4, $5, $6) RETURNING created_at`
+
+	if err := tx.QueryRowContext(ctx, q, licence.ID, practitionerID, orgID, licence.Number, licence.Jurisdiction, licence.ExpiresOn).Scan(&licence.CreatedAt); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return model.ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByID returns a live practitioner with its licences and facilities.
+func (r *PractitionerRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Practitioner, error) {
+	q := `SELECT p.id, p.organisation_id, p.user_id, u.first_name, u.last_name, u.email, p.profession, p.npi, p.specialties,
+			p.created_at, p.updated_at, p.version
+		FROM practitioners p JOIN users u ON u.id = p.user_id
+		WHERE p.id = $1 AND p.is_deleted = false AND ` + tenantOrganisationOf("p")
+
+	var p model.Practitioner
+	err := inTenant(ctx, r.db, func(db querier) error {
+		if err := db.QueryRowContext(ctx, q, id).Scan(
+			&p.ID,
+			&p.OrganisationID,
+			&p.UserID,
+			&p.FirstName,
+			&p.LastName,
+			&p.Email,
+			&p.Profession,
+			&p.NPI,
+			pq.Array(&p.Specialties),
+			&p.CreatedAt,
+			&p.UpdatedAt,
+			&p.Version,
+		); err != nil {
+			if err == sql.ErrNoRows {
+				return model.ErrNotFound
+			}
+			return err
+		}
+
+		var err error
+		p.Facilities, err = queryFacilities(ctx, db, `SELECT `+facilityColumns+` FROM facilities f
+			JOIN practitioner_facilities pf ON pf.facility_id = f.id
+			WHERE pf.practitioner_id = $1 ORDER BY f.name, f.id`, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := db.QueryContext(ctx, `SELECT id, number, jurisdiction, expires_on, expiry_flagged_at, created_at
+			FROM practitioner_licences WHERE practitioner_id = $1 ORDER BY expires_on, id`, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		p.Licences = []model.Licence{}
+		for rows.Next() {
+			var l model.Licence
+			var expiresOn time.Time
+			if err := rows.Scan(&l.ID, &l.Number, &l.Jurisdiction, &expiresOn, &l.ExpiryFlaggedAt, &l.CreatedAt); err != nil {
+				return err
+			}
+			l.ExpiresOn = expiresOn.Format(model.DateLayout)
+			p.Licences = append(p.Licences, l)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Specialties == nil {
+		p.Specialties = []string{}
+	}
+	return &p, nil
+}
+
+// practitionerFilterConds builds the WHERE conditions for filter, limited to
+// live practitioners of the request's organisation.
+func practitionerFilterConds(filter model.PractitionerFilter) ([]string, []any) {
+	conds := []string{tenantOrganisationOf("p"), "p.is_deleted = false", "u.is_deleted = false"}
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Search != "" {
+		conds = append(conds, "(u.first_name || ' ' || u.last_name) ILIKE "+arg("%"+escapeLike(filter.Search)+"%"))
+	}
+
+	if filter.Profession != "" {
+		conds = append(conds, "p.profession = "+arg(filter.Profession))
+	}
+
+	if filter.Specialty != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM unnest(p.specialties) s WHERE lower(s) = lower("+arg(filter.Specialty)+"))")
+	}
+
+	if filter.Location != "" {
+		l := arg("%" + escapeLike(filter.Location) + "%")
+		conds = append(conds, fmt.Sprintf(`EXISTS (SELECT 1 FROM practitioner_facilities pf JOIN facilities f ON f.id = pf.facility_id
+			WHERE pf.practitioner_id = p.id
+			AND (f.name ILIKE %s OR f.address_city ILIKE %s OR f.address_region ILIKE %s OR f.address_postal_code ILIKE %s))`, l, l, l, l))
+	}
+
+	if filter.FacilityID != nil {
+		conds = append(conds, "EXISTS (SELECT 1 FROM practitioner_facilities pf WHERE pf.practitioner_id = p.id AND pf.facility_id = "+arg(*filter.FacilityID)+")")
+	}
+
+	return conds, args
+}
+
+func (r *PractitionerRepo) List(ctx context.Context, filter model.PractitionerFilter, limit, offset int) ([]model.PractitionerSummary, error) {
+	conds, args := practitionerFilterConds(filter)
+	q := fmt.Sprintf(
+		`SELECT p.id, p.user_id, u.first_name, u.last_name, p.profession, p.specialties
+		FROM practitioners p JOIN users u ON u.id = p.user_id %s
+		ORDER BY u.last_name, u.first_name, p.id LIMIT $%d OFFSET $%d`,
+		whereClause(conds), len(args)+1, len(args)+2,
+	)
+	args = append(args, limit, offset)
+
+	var practitioners []model.PractitionerSummary
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p model.PractitionerSummary
+			if err := rows.Scan(&p.ID, &p.UserID, &p.FirstName, &p.LastName, &p.Profession, pq.Array(&p.Specialties)); err != nil {
+				return err
+			}
+			if p.Specialties == nil {
+				p.Specialties = []string{}
+			}
+			practitioners = append(practitioners, p)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return practitioners, nil
+}
+
+func (r *PractitionerRepo) Count(ctx context.Context, filter model.PractitionerFilter) (int, error) {
+	conds, args := practitionerFilterConds(filter)
+	q := `SELECT COUNT(*) FROM practitioners p JOIN users u ON u.id = p.user_id ` + whereClause(conds)
+
+	var count int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, args...).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// practitionerTx runs fn in a transaction scoped to the request's
+// organisation with the practitioner row locked, after checking cond against
+// its version. fn receives the practitioner's organisation.
+func (r *PractitionerRepo) practitionerTx(ctx context.Context, id uuid.UUID, cond model.Precondition, fn func(tx *sql.Tx, orgID uuid.UUID) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	q := `SELECT organisation_id, version FROM practitioners
+		WHERE id = $1 AND is_deleted = false AND ` + tenantOrganisation + ` FOR UPDATE`
+
+	var orgID uuid.UUID
+	var version int64
+	if err := tx.QueryRowContext(ctx, q, id).Scan(&orgID, &version); err != nil {
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	}
+	if !cond.Matches(version) {
+		return model.ErrPreconditionFailed
+	}
+
+	if err := fn(tx, orgID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateByID applies a merge patch if cond matches the current version and
+// returns the new version. Replacing the facilities changes the version too.
+func (r *PractitionerRepo) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdatePractitioner, cond model.Precondition) (int64, error) {
+	if data == nil || data.IsEmpty() {
+		return 0, model.ErrBadRequest
+	}
+
+	sets := []string{"updated_at = now()"}
+	var args []any
+	if data.Profession.Set {
+		args = append(args, data.Profession.Value)
+		sets = append(sets, fmt.Sprintf("profession = $%d", len(args)))
+	}
+	if data.NPI.Null {
+		sets = append(sets, "npi = NULL")
+	} else if data.NPI.Set {
+		args = append(args, data.NPI.Value)
+		sets = append(sets, fmt.Sprintf("npi = $%d", len(args)))
+	}
+	if data.Specialties.Set {
+		args = append(args, pq.Array(data.Specialties.Value))
+		sets = append(sets, fmt.Sprintf("specialties = COALESCE($%d, '{}')", len(args)))
+	}
+
+	var version int64
+	err := r.practitionerTx(ctx, id, cond, func(tx *sql.Tx, orgID uuid.UUID) error {
+		if data.FacilityIDs.Set {
+			if err := setFacilities(ctx, tx, id, orgID, data.FacilityIDs.Value); err != nil {
+				return err
+			}
+		}
+
+		q := fmt.Sprintf(`UPDATE practitioners SET %s WHERE id = $%d RETURNING version`, strings.Join(sets, ", "), len(args)+1)
+		if err := tx.QueryRowContext(ctx, q, append(args, id)...).Scan(&version); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return model.ErrAlreadyExists
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// DeleteByID soft-deletes a practitioner if cond matches the current
+// version. Its licences are no longer checked for expiry.
+func (r *PractitionerRepo) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
+	return r.practitionerTx(ctx, id, cond, func(tx *sql.Tx, _ uuid.UUID) error {
+		_, err := tx.ExecContext(ctx, `UPDATE practitioners SET is_deleted = true, deleted_at = now() WHERE id = $1`, id)
+		return err
+	})
+}
+
+func (r *PractitionerRepo) AddLicence(ctx context.Context, practitionerID uuid.UUID, licence model.Licence) (*model.Licence, error) {
+	err := r.practitionerTx(ctx, practitionerID, anyVersion, func(tx *sql.Tx, orgID uuid.UUID) error {
+		if err := insertLicence(ctx, tx, practitionerID, orgID, &licence); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE practitioners SET updated_at = now() WHERE id = $1`, practitionerID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &licence, nil
+}
+
+func (r *PractitionerRepo) RemoveLicence(ctx context.Context, practitionerID, licenceID uuid.UUID) error {
+	return r.practitionerTx(ctx, practitionerID, anyVersion, func(tx *sql.Tx, _ uuid.UUID) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM practitioner_licences WHERE id = $1 AND practitioner_id = $2`, licenceID, practitionerID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE practitioners SET updated_at = now() WHERE id = $1`, practitionerID)
+		return err
+	})
+}
+
+// FlagExpiringLicences marks the licences of live practitioners that expire
+// on or before cutoff and have not been flagged yet, and returns them. Each
+// licence is flagged once; a renewal is added as a new licence. It runs
+// across all organisations.
+func (r *PractitionerRepo) FlagExpiringLicences(ctx context.Context, cutoff time.Time) ([]model.ExpiringLicence, error) {
+	q := `WITH flagged AS (
+			UPDATE practitioner_licences l SET expiry_flagged_at = now()
+			FROM practitioners p
+			WHERE p.id = l.practitioner_id AND p.is_deleted = false
+				AND l.expiry_flagged_at IS NULL AND l.expires_on <= $1::date
+			RETURNING l.id, l.practitioner_id, l.organisation_id, l.number, l.jurisdiction, l.expires_on, p.user_id
+		)
+		SELECT f.id, f.practitioner_id, f.organisation_id, f.number, f.jurisdiction, f.expires_on, u.first_name, u.email
+		FROM flagged f JOIN users u ON u.id = f.user_id
+		ORDER BY f.expires_on, f.id`
+
+	rows, err := r.db.QueryContext(ctx, q, cutoff.Format(model.DateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var licences []model.ExpiringLicence
+	for rows.Next() {
+		var l model.ExpiringLicence
+		var expiresOn time.Time
+		if err := rows.Scan(&l.LicenceID, &l.PractitionerID, &l.OrganisationID, &l.Number, &l.Jurisdiction, &expiresOn, &l.FirstName, &l.Email); err != nil {
+			return nil, err
+		}
+		l.ExpiresOn = expiresOn.Format(model.DateLayout)
+		licences = append(licences, l)
+	}
+
+	return licences, rows.Err()
+}
+
+// IsPractitioner reports whether a user has a live practitioner record in
+// the request's organisation.
+func (r *PractitionerRepo) IsPractitioner(ctx context.Context, userID uuid.UUID) (bool, error) {
+	q := `SELECT EXISTS (SELECT 1 FROM practitioners
+		WHERE user_id = $1 AND is_deleted = false AND ` + tenantOrganisation + `)`
+
+	var ok bool
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, userID).Scan(&ok)
+	})
+	return ok, err
+}
+
+// FindByNPI returns the live practitioner with a National Provider
+// Identifier, or ErrNotFound.
+func (r *PractitionerRepo) FindByNPI(ctx context.Context, npi string) (*model.Practitioner, error) {
+	q := `SELECT id FROM practitioners WHERE npi = $1 AND is_deleted = false AND ` + tenantOrganisation
+
+	var id uuid.UUID
+	err := inTenant(ctx, r.db, func(db querier) error {
+		err := db.QueryRowContext(ctx, q, npi).Scan(&id)
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
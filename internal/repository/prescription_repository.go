@@ -0,0 +1,424 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PrescriptionRepository interface {
+	GetPrescriber(ctx context.Context, userID uuid.UUID) (*model.Prescriber, error)
+	Create(ctx context.Context, prescription model.Prescription) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Prescription, error)
+	List(ctx context.Context, filter model.PrescriptionFilter, limit, offset int) ([]model.Prescription, error)
+	Count(ctx context.Context, filter model.PrescriptionFilter) (int, error)
+	ListCurrent(ctx context.Context, patientID uuid.UUID) ([]model.Prescription, error)
+	SetStatus(ctx context.Context, id uuid.UUID, from, to string, reason *string) error
+	CreateRefill(ctx context.Context, refill model.RefillRequest) error
+	GetRefill(ctx context.Context, prescriptionID, refillID uuid.UUID) (*model.RefillRequest, error)
+	ListRefills(ctx context.Context, prescriptionID uuid.UUID) ([]model.RefillRequest, error)
+	DecideRefill(ctx context.Context, prescriptionID, refillID uuid.UUID, approve bool, decidedBy uuid.UUID, note *string) error
+}
+
+type PrescriptionRepo struct {
+	db *sql.DB
+}
+
+func NewPrescriptionRepository(db *sql.DB) *PrescriptionRepo {
+	return &PrescriptionRepo{
+		db: db,
+	}
+}
+
+// GetPrescriber returns the live practitioner record of a user in the
+// request's organisation, or ErrNotFound if they have none.
+func (r *PrescriptionRepo) GetPrescriber(ctx context.Context, userID uuid.UUID) (*model.Prescriber, error) {
+	q := `SELECT p.id, p.profession, MAX(l.expires_on)
+		FROM practitioners p LEFT JOIN practitioner_licences l ON l.practitioner_id = p.id
+		WHERE p.user_id = $1 AND p.is_deleted = false AND ` + tenantOrganisationOf("p") + `
+		GROUP BY p.id, p.profession`
+
+	var p model.Prescriber
+	var licensedUntil *time.Time
+	err := inTenant(ctx, r.db, func(db querier) error {
+		err := db.QueryRowContext(ctx, q, userID).Scan(&p.PractitionerID, &p.Profession, &licensedUntil)
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if licensedUntil != nil {
+		s := licensedUntil.Format(model.DateLayout)
+		p.LicensedUntil = &s
+	}
+	return &p, nil
+}
+
+// Create records a prescription. The prescriber and the patient must be live
+// and belong to the same organisation, or it is ErrNotFound.
+func (r *PrescriptionRepo) Create(ctx context.Context, prescription model.Prescription) error {
+	q := `INSERT INTO prescriptions(id, organisation_id, patient_id, prescriber_id, rxcui, drug, strength, dose, route,
+			frequency, duration_days, quantity, refills, refills_remaining, instructions, override_reason, created_by)
+		SELECT $1, p.organisation_id, pt.id, p.id, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12, $13, $14, $15
+		FROM practitioners p JOIN patients pt ON pt.organisation_id = p.organisation_id
+		WHERE p.id = $2 AND pt.id = $3 AND p.is_deleted = false AND pt.is_deleted = false AND ` + tenantOrganisationOf("p")
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q,
+			prescription.ID,
+			prescription.PrescriberID,
+			prescription.PatientID,
+			prescription.RxCUI,
+			prescription.Drug,
+			prescription.Strength,
+			prescription.Dose,
+			prescription.Route,
+			prescription.Frequency,
+			prescription.DurationDays,
+			prescription.Quantity,
+			prescription.Refills,
+			prescription.Instructions,
+			prescription.OverrideReason,
+			prescription.CreatedBy,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
+
+const prescriptionColumns = `rx.id, rx.organisation_id, rx.patient_id, pt.first_name || ' ' || pt.last_name,
+	rx.prescriber_id, u.first_name || ' ' || u.last_name, rx.rxcui, rx.drug, rx.strength, rx.dose, rx.route,
+	rx.frequency, rx.duration_days, rx.quantity, rx.refills, rx.refills_remaining, rx.instructions, rx.override_reason, rx.status,
+	rx.status_reason, rx.status_changed_at, rx.created_by, rx.created_at, rx.updated_at, p.user_id, pt.user_id`
+
+const prescriptionJoins = `prescriptions rx
+	JOIN practitioners p ON p.id = rx.prescriber_id
+	JOIN users u ON u.id = p.user_id
+	JOIN patients pt ON pt.id = rx.patient_id`
+
+func scanPrescription(row rowScanner) (*model.Prescription, error) {
+	var rx model.Prescription
+	if err := row.Scan(
+		&rx.ID,
+		&rx.OrganisationID,
+		&rx.PatientID,
+		&rx.PatientName,
+		&rx.PrescriberID,
+		&rx.PrescriberName,
+		&rx.RxCUI,
+		&rx.Drug,
+		&rx.Strength,
+		&rx.Dose,
+		&rx.Route,
+		&rx.Frequency,
+		&rx.DurationDays,
+		&rx.Quantity,
+		&rx.Refills,
+		&rx.RefillsRemaining,
+		&rx.Instructions,
+		&rx.OverrideReason,
+		&rx.Status,
+		&rx.StatusReason,
+		&rx.StatusChangedAt,
+		&rx.CreatedBy,
+		&rx.CreatedAt,
+		&rx.UpdatedAt,
+		&rx.PrescriberUserID,
+		&rx.PatientUserID,
+	); err != nil {
+		return nil, err
+	}
+	return &rx, nil
+}
+
+func (r *PrescriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Prescription, error) {
+	q := `SELECT ` + prescriptionColumns + ` FROM ` + prescriptionJoins + ` WHERE rx.id = $1 AND ` + tenantOrganisationOf("rx")
+
+	var rx *model.Prescription
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		rx, err = scanPrescription(db.QueryRowContext(ctx, q, id))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rx, nil
+}
+
+// prescriptionFilterConds builds the WHERE conditions for filter, limited to
+// the request's organisation.
+func prescriptionFilterConds(filter model.PrescriptionFilter) ([]string, []any) {
+	conds := []string{tenantOrganisationOf("rx")}
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.PatientID != nil {
+		conds = append(conds, "rx.patient_id = "+arg(*filter.PatientID))
+	}
+	if filter.PrescriberID != nil {
+		conds = append(conds, "rx.prescriber_id = "+arg(*filter.PrescriberID))
+	}
+	if filter.Status != "" {
+		conds = append(conds, "rx.status = "+arg(filter.Status))
+	}
+	if filter.ParticipantID != nil {
+		p := arg(*filter.ParticipantID)
+		conds = append(conds, fmt.Sprintf("(p.user_id = %s OR pt.user_id = %s)", p, p))
+	}
+
+	return conds, args
+}
+
+// List returns a page of prescriptions, newest first.
+func (r *PrescriptionRepo) List(ctx context.Context, filter model.PrescriptionFilter, limit, offset int) ([]model.Prescription, error) {
+	conds, args := prescriptionFilterConds(filter)
+	q := fmt.Sprintf(`SELECT %s FROM %s %s ORDER BY rx.created_at DESC, rx.id DESC LIMIT $%d OFFSET $%d`,
+		prescriptionColumns, prescriptionJoins, whereClause(conds), len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	var prescriptions []model.Prescription
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rx, err := scanPrescription(rows)
+			if err != nil {
+				return err
+			}
+			prescriptions = append(prescriptions, *rx)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prescriptions, nil
+}
+
+func (r *PrescriptionRepo) Count(ctx context.Context, filter model.PrescriptionFilter) (int, error) {
+	conds, args := prescriptionFilterConds(filter)
+	q := `SELECT COUNT(*) FROM ` + prescriptionJoins + ` ` + whereClause(conds)
+
+	var count int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, args...).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListCurrent returns a patient's prescriptions that are active or on hold,
+// which new prescriptions are checked against.
+func (r *PrescriptionRepo) ListCurrent(ctx context.Context, patientID uuid.UUID) ([]model.Prescription, error) {
+	q := `SELECT ` + prescriptionColumns + ` FROM ` + prescriptionJoins + `
+		WHERE rx.patient_id = $1 AND rx.status IN ('active', 'on_hold') AND ` + tenantOrganisationOf("rx") + `
+		ORDER BY rx.created_at, rx.id`
+
+	var prescriptions []model.Prescription
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, patientID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rx, err := scanPrescription(rows)
+			if err != nil {
+				return err
+			}
+			prescriptions = append(prescriptions, *rx)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prescriptions, nil
+}
+
+// SetStatus moves a prescription from one status to another. A prescription
+// whose status is no longer from is ErrConflict.
+func (r *PrescriptionRepo) SetStatus(ctx context.Context, id uuid.UUID, from, to string, reason *string) error {
+	q := `UPDATE prescriptions SET status = $3, status_reason = $4, status_changed_at = now()
+		WHERE id = $1 AND status = $2 AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, id, from, to, reason)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrConflict
+		}
+		return nil
+	})
+}
+
+// CreateRefill records a refill request. The prescription must be active
+// with refills remaining and no other request pending, or it is
+// ErrConflict.
+func (r *PrescriptionRepo) CreateRefill(ctx context.Context, refill model.RefillRequest) error {
+	q := `INSERT INTO prescription_refills(id, organisation_id, prescription_id, note, requested_by)
+		SELECT $1, organisation_id, id, $3, $4 FROM prescriptions
+		WHERE id = $2 AND status = 'active' AND refills_remaining > 0 AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, refill.ID, refill.PrescriptionID, refill.Note, refill.RequestedBy)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return model.ErrConflict
+			}
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrConflict
+		}
+		return nil
+	})
+}
+
+const refillColumns = `id, prescription_id, status, note, requested_by, decided_by, decision_note, created_at, decided_at`
+
+func scanRefill(row rowScanner) (*model.RefillRequest, error) {
+	var rf model.RefillRequest
+	if err := row.Scan(
+		&rf.ID,
+		&rf.PrescriptionID,
+		&rf.Status,
+		&rf.Note,
+		&rf.RequestedBy,
+		&rf.DecidedBy,
+		&rf.DecisionNote,
+		&rf.CreatedAt,
+		&rf.DecidedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &rf, nil
+}
+
+func (r *PrescriptionRepo) GetRefill(ctx context.Context, prescriptionID, refillID uuid.UUID) (*model.RefillRequest, error) {
+	q := `SELECT ` + refillColumns + ` FROM prescription_refills
+		WHERE id = $1 AND prescription_id = $2 AND ` + tenantOrganisation
+
+	var rf *model.RefillRequest
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		rf, err = scanRefill(db.QueryRowContext(ctx, q, refillID, prescriptionID))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// ListRefills returns the refill requests of a prescription, newest first.
+func (r *PrescriptionRepo) ListRefills(ctx context.Context, prescriptionID uuid.UUID) ([]model.RefillRequest, error) {
+	q := `SELECT ` + refillColumns + ` FROM prescription_refills
+		WHERE prescription_id = $1 AND ` + tenantOrganisation + `
+		ORDER BY created_at DESC, id DESC`
+
+	var refills []model.RefillRequest
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, prescriptionID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rf, err := scanRefill(rows)
+			if err != nil {
+				return err
+			}
+			refills = append(refills, *rf)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refills, nil
+}
+
+// DecideRefill approves or denies a pending refill request. Approving uses
+// up one of the prescription's refills, so the prescription must still be
+// active with refills remaining. A request that is no longer pending, or a
+// prescription that cannot be refilled, is ErrConflict.
+func (r *PrescriptionRepo) DecideRefill(ctx context.Context, prescriptionID, refillID uuid.UUID, approve bool, decidedBy uuid.UUID, note *string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	status := model.RefillDenied
+	if approve {
+		status = model.RefillApproved
+	}
+
+	q := `UPDATE prescription_refills SET status = $3, decided_by = $4, decision_note = $5, decided_at = now()
+		WHERE id = $1 AND prescription_id = $2 AND status = 'pending' AND ` + tenantOrganisation
+	res, err := tx.ExecContext(ctx, q, refillID, prescriptionID, status, decidedBy, note)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrConflict
+	}
+
+	if approve {
+		q = `UPDATE prescriptions SET refills_remaining = refills_remaining - 1
+			WHERE id = $1 AND status = 'active' AND refills_remaining > 0 AND ` + tenantOrganisation
+		res, err := tx.ExecContext(ctx, q, prescriptionID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrConflict
+		}
+	}
+
+	return tx.Commit()
+}
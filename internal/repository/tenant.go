@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+// TenantFromContext returns the organisation a request is scoped to, or nil
+// when it may see every organisation, as for super admins and background jobs.
+func TenantFromContext(ctx context.Context) *uuid.UUID {
+	if id, ok := ctx.Value("organisation_id").(uuid.UUID); ok && id != uuid.Nil {
+		return &id
+	}
+	return nil
+}
+
+// WithTenant returns a copy of ctx scoped to orgID.
+func WithTenant(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, "organisation_id", orgID)
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// setTenant sets app.organisation_id for the rest of tx, which is what the
+// row-level security policies and tenantUsers read. It does nothing for
+// unscoped requests.
+func setTenant(ctx context.Context, tx *sql.Tx) error {
+	orgID := TenantFromContext(ctx)
+	if orgID == nil {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.organisation_id', $1, true)`, orgID.String())
+	return err
+}
+
+// inTenant runs fn against db. Scoped requests run in a transaction with
+// app.organisation_id set, since the setting only lasts for a transaction.
+func inTenant(ctx context.Context, db *sql.DB, fn func(q querier) error) error {
+	if TenantFromContext(ctx) == nil {
+		return fn(db)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockShared locks a user's row for the rest of tx and reports whether a
+// scoped request must leave the account alone because it also belongs to
+// other organisations. It must run before setTenant, while the policies still
+// show every membership; holding the lock keeps memberships from being added
+// until tx ends. Unscoped requests may change any account.
+func lockShared(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (bool, error) {
+	orgID := TenantFromContext(ctx)
+	if orgID == nil {
+		return false, nil
+	}
+
+	q := `SELECT EXISTS (SELECT 1 FROM organisation_members m WHERE m.user_id = users.id AND m.organisation_id <> $2)
+		FROM users WHERE id = $1 FOR UPDATE`
+
+	var shared bool
+	if err := tx.QueryRowContext(ctx, q, userID, *orgID).Scan(&shared); err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	return shared, nil
+}
+
+// errSharedUser is returned when an organisation's admins try to delete,
+// suspend or erase an account that other organisations share.
+var errSharedUser = fmt.Errorf("the account also belongs to other organisations: %w", model.ErrForbidden)
+
+// tenantUsers restricts a query on users to members of the organisation set
+// by setTenant. It repeats the row-level security policy so scoping also
+// holds when the application connects as a role that bypasses policies.
+const tenantUsers = `(app_organisation_id() IS NULL OR EXISTS (
+	SELECT 1 FROM organisation_members m WHERE m.user_id = users.id AND m.organisation_id = app_organisation_id()))`
+
+// tenantOrganisation does the same for tables with an organisation_id column.
+const tenantOrganisation = `(app_organisation_id() IS NULL OR organisation_id = app_organisation_id())`
+
+// tenantOrganisationOf is tenantOrganisation for a table referred to by
+// alias, for joins where organisation_id would be ambiguous.
+func tenantOrganisationOf(alias string) string {
+	return `(app_organisation_id() IS NULL OR ` + alias + `.organisation_id = app_organisation_id())`
+}
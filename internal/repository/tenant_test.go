@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// testDB connects to the migrated database named by TEST_DATABASE_URL. It
+// must be reached as an ordinary role, not a superuser, for the row-level
+// security policies to apply. Without it the test is skipped.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testOrganisation creates an organisation, deleted when t ends.
+func testOrganisation(t *testing.T, db *sql.DB) uuid.UUID {
+	t.Helper()
+	id, _ := uuid.NewV7()
+	if _, err := db.Exec(`INSERT INTO organisations(id, name) VALUES($1, $2)`, id, "test-"+id.String()); err != nil {
+		t.Fatalf("create organisation: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM organisations WHERE id = $1`, id) })
+	return id
+}
+
+// testUser creates a user who is a member of orgs, deleted when t ends.
+func testUser(t *testing.T, db *sql.DB, orgs ...uuid.UUID) uuid.UUID {
+	t.Helper()
+	id, _ := uuid.NewV7()
+	q := `INSERT INTO users(id, first_name, last_name, email, password) VALUES($1, 'Test', 'User', $2, 'x')`
+	if _, err := db.Exec(q, id, id.String()+"@example.com"); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, id) })
+	for _, org := range orgs {
+		if _, err := db.Exec(`INSERT INTO organisation_members(organisation_id, user_id) VALUES($1, $2)`, org, id); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+	}
+	return id
+}
+
+func TestImportRepo_UpsertBatch(t *testing.T) {
+	db := testDB(t)
+	repo := NewImportRepository(db)
+
+	tests := []struct {
+		name         string
+		scoped       bool
+		existing     bool
+		elsewhere    bool
+		passwordHash string
+		expect       string
+		expectMember bool
+	}{
+		{name: "new user in an organisation", scoped: true, passwordHash: "x", expect: model.ImportCreated, expectMember: true},
+		{name: "new user without an organisation", passwordHash: "x", expect: model.ImportCreated},
+		{name: "member", scoped: true, existing: true, passwordHash: "x", expect: model.ImportUpdated, expectMember: true},
+		{name: "member without a password", scoped: true, existing: true, expect: model.ImportUpdated, expectMember: true},
+		{name: "user of another organisation", scoped: true, existing: true, elsewhere: true, passwordHash: "x", expect: model.ImportSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgID := testOrganisation(t, db)
+			ctx := context.Background()
+			if tt.scoped {
+				ctx = WithTenant(ctx, orgID)
+			}
+
+			id, _ := uuid.NewV7()
+			email := id.String() + "@example.com"
+			if tt.existing {
+				memberOf := orgID
+				if tt.elsewhere {
+					memberOf = testOrganisation(t, db)
+				}
+				id = testUser(t, db, memberOf)
+				email = id.String() + "@example.com"
+			} else {
+				t.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, id) })
+			}
+
+			outcomes, err := repo.UpsertBatch(ctx, []model.ImportUser{{ID: id, FirstName: "Jane", LastName: "Doe", Email: email, PasswordHash: tt.passwordHash}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if outcomes[0] != tt.expect {
+				t.Errorf("outcome %s, want %s", outcomes[0], tt.expect)
+			}
+
+			var member bool
+			q := `SELECT EXISTS (SELECT 1 FROM organisation_members WHERE organisation_id = $1 AND user_id = $2)`
+			if err := db.QueryRow(q, orgID, id).Scan(&member); err != nil {
+				t.Fatalf("membership: %v", err)
+			}
+			if member != tt.expectMember {
+				t.Errorf("member = %v, want %v", member, tt.expectMember)
+			}
+		})
+	}
+}
+
+func TestRepo_SharedUserWrites(t *testing.T) {
+	db := testDB(t)
+	users := NewUserRepository(db)
+	erasure := NewErasureRepository(db)
+
+	del := func(ctx context.Context, id uuid.UUID) error {
+		return users.DeleteByID(ctx, id, model.Precondition{Any: true})
+	}
+	suspend := func(ctx context.Context, id uuid.UUID) error {
+		_, err := users.SetStatus(ctx, id, model.ChangeStatus{Status: model.StatusSuspended}, id)
+		return err
+	}
+	erase := func(ctx context.Context, id uuid.UUID) error {
+		certID, _ := uuid.NewV7()
+		_, _, err := erasure.Erase(ctx, model.ErasureCertificate{ID: certID, UserID: id, Pseudonym: "x", RequestedBy: id}, "erased@erased.invalid")
+		return err
+	}
+
+	tests := []struct {
+		name      string
+		write     func(ctx context.Context, id uuid.UUID) error
+		shared    bool
+		scoped    bool
+		expectErr error
+	}{
+		{name: "delete a member", write: del, scoped: true},
+		{name: "delete a shared account", write: del, shared: true, scoped: true, expectErr: model.ErrForbidden},
+		{name: "delete a shared account unscoped", write: del, shared: true},
+		{name: "suspend a member", write: suspend, scoped: true},
+		{name: "suspend a shared account", write: suspend, shared: true, scoped: true, expectErr: model.ErrForbidden},
+		{name: "erase a shared account", write: erase, shared: true, scoped: true, expectErr: model.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgID := testOrganisation(t, db)
+			orgs := []uuid.UUID{orgID}
+			if tt.shared {
+				orgs = append(orgs, testOrganisation(t, db))
+			}
+			id := testUser(t, db, orgs...)
+			ctx := context.Background()
+			if tt.scoped {
+				ctx = WithTenant(ctx, orgID)
+			}
+
+			err := tt.write(ctx, id)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestUserRepo_TagFollowsLapsedStatus(t *testing.T) {
+	db := testDB(t)
+	repo := NewUserRepository(db)
+
+	tests := []struct {
+		name         string
+		until        string
+		expectStatus string
+		expectLapsed bool
+	}{
+		{name: "suspended", until: "1 hour", expectStatus: model.StatusSuspended},
+		{name: "suspension lapsed", until: "-1 hour", expectStatus: model.StatusActive, expectLapsed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := testUser(t, db)
+			ctx := context.Background()
+
+			var version int64
+			q := `UPDATE users SET status = 'suspended', status_until = now() + $2::interval WHERE id = $1 RETURNING version`
+			if err := db.QueryRow(q, id, tt.until).Scan(&version); err != nil {
+				t.Fatalf("suspend: %v", err)
+			}
+
+			user, err := repo.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if user.Status != tt.expectStatus {
+				t.Errorf("status %s, want %s", user.Status, tt.expectStatus)
+			}
+			if lapsed := user.Version != version*2; lapsed != tt.expectLapsed {
+				t.Errorf("tag %d for version %d, lapsed = %v, want %v", user.Version, version, lapsed, tt.expectLapsed)
+			}
+
+			stale := model.Precondition{Versions: []int64{version * 2}}
+			if tt.expectLapsed {
+				err := repo.DeleteByID(ctx, id, stale)
+				if !errors.Is(err, model.ErrPreconditionFailed) {
+					t.Fatalf("expected %v, got %v", model.ErrPreconditionFailed, err)
+				}
+			}
+			if err := repo.DeleteByID(ctx, id, model.Precondition{Versions: []int64{user.Version}}); err != nil {
+				t.Fatalf("delete with the read tag: %v", err)
+			}
+		})
+	}
+}
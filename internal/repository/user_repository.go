@@ -3,17 +3,31 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/PranavJoshi2893/med-portal/internal/model"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type UserRepository interface {
-	GetAll(ctx context.Context, limit, offset int) ([]model.GetAll, error)
-	GetCount(ctx context.Context) (int, error)
-	DeleteByID(ctx context.Context, id uuid.UUID) error
+	GetAll(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error)
+	GetCount(ctx context.Context, filter model.UserFilter) (int, error)
+	GetPage(ctx context.Context, filter model.UserFilter, cursor *model.Cursor, limit int) ([]model.GetAll, error)
+	DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.GetByID, error)
-	UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error
+	UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error)
+	GetDeleted(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.DeletedUser, error)
+	RestoreByID(ctx context.Context, id uuid.UUID) error
+	PurgeDeleted(ctx context.Context, cutoff time.Time, mode string, limit int) ([]model.PurgedUser, error)
+	SetAvatar(ctx context.Context, id uuid.UUID, contentType string) error
+	GetAvatar(ctx context.Context, id uuid.UUID) (*model.Avatar, error)
+	ClearAvatar(ctx context.Context, id uuid.UUID) error
+	GetStatus(ctx context.Context, id uuid.UUID) (*model.AccountStatus, error)
+	SetStatus(ctx context.Context, id uuid.UUID, change model.ChangeStatus, actorID uuid.UUID) (string, error)
+	StreamUsers(ctx context.Context, filter model.UserFilter, fields []string, fn func(values []any) error) error
 }
 
 type UserRepo struct {
@@ -26,74 +40,244 @@ func NewUserRepository(db *sql.DB) *UserRepo {
 	}
 }
 
-func (r *UserRepo) GetAll(ctx context.Context, limit, offset int) ([]model.GetAll, error) {
-	q := `SELECT id, first_name, last_name, email FROM users WHERE is_deleted = false ORDER BY id LIMIT $1 OFFSET $2`
+// userSortColumns maps the sortable API fields to their columns.
+var userSortColumns = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"role":       effectiveRole,
+	"created_at": "created_at",
+	"deleted_at": "deleted_at",
+}
 
-	data, err := r.db.QueryContext(ctx, q, limit, offset)
-	if err != nil {
-		return nil, err
+// userFilterConds builds the WHERE conditions for filter, limited to the
+// request's organisation. Placeholders are numbered from 1 and the matching
+// arguments are returned alongside.
+func userFilterConds(filter model.UserFilter) ([]string, []any) {
+	conds := []string{tenantUsers}
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
-	defer data.Close()
 
-	var users []model.GetAll
+	switch filter.Deleted {
+	case model.DeletedOnly:
+		conds = append(conds, "is_deleted = true", "purged_at IS NULL")
+	case model.DeletedInclude:
+		conds = append(conds, "purged_at IS NULL")
+	default:
+		conds = append(conds, "is_deleted = false")
+	}
 
-	for data.Next() {
-		var user model.GetAll
+	if filter.Search != "" {
+		p := arg("%" + escapeLike(filter.Search) + "%")
+		conds = append(conds, fmt.Sprintf("((first_name || ' ' || last_name) ILIKE %s OR email ILIKE %s)", p, p))
+	}
 
-		if err := data.Scan(
-			&user.ID,
-			&user.FirstName,
-			&user.LastName,
-			&user.Email,
-		); err != nil {
-			return nil, err
+	if filter.Role != "" {
+		conds = append(conds, effectiveRole+" = "+arg(filter.Role))
+	}
+
+	if filter.Status != "" {
+		conds = append(conds, effectiveStatus+" = "+arg(filter.Status))
+	}
+
+	if filter.CreatedFrom != nil {
+		conds = append(conds, "created_at >= "+arg(*filter.CreatedFrom))
+	}
+
+	if filter.CreatedTo != nil {
+		conds = append(conds, "created_at <= "+arg(*filter.CreatedTo))
+	}
+
+	return conds, args
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
+
+// userOrderClause builds the ORDER BY clause for sort. id is always the last
+// key so that pages are stable when the requested keys tie.
+func userOrderClause(sort []model.SortField) string {
+	var keys []string
+	for _, s := range sort {
+		col, ok := userSortColumns[s.Field]
+		if !ok {
+			continue
 		}
+		if s.Desc {
+			col += " DESC"
+		}
+		keys = append(keys, col)
+	}
+	keys = append(keys, "id")
+	return "ORDER BY " + strings.Join(keys, ", ")
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *UserRepo) GetAll(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error) {
+	conds, args := userFilterConds(filter)
+	q := fmt.Sprintf(
+		`SELECT id, first_name, last_name, email FROM users %s %s LIMIT $%d OFFSET $%d`,
+		whereClause(conds), userOrderClause(filter.Sort), len(args)+1, len(args)+2,
+	)
+	args = append(args, limit, offset)
+
+	return r.queryUsers(ctx, q, args...)
+}
 
-		users = append(users, user)
+// GetPage returns up to limit users after (or, for a backward cursor, before)
+// cursor in id order. Backward pages are returned in descending id order.
+func (r *UserRepo) GetPage(ctx context.Context, filter model.UserFilter, cursor *model.Cursor, limit int) ([]model.GetAll, error) {
+	conds, args := userFilterConds(filter)
+	order := "ORDER BY id"
+
+	if cursor != nil {
+		args = append(args, cursor.ID)
+		op := ">"
+		if cursor.Before {
+			op = "<"
+			order = "ORDER BY id DESC"
+		}
+		if cursor.Inclusive {
+			op += "="
+		}
+		conds = append(conds, fmt.Sprintf("id %s $%d", op, len(args)))
 	}
 
-	if err := data.Err(); err != nil {
+	q := fmt.Sprintf(
+		`SELECT id, first_name, last_name, email FROM users %s %s LIMIT $%d`,
+		whereClause(conds), order, len(args)+1,
+	)
+	args = append(args, limit)
+
+	return r.queryUsers(ctx, q, args...)
+}
+
+func (r *UserRepo) queryUsers(ctx context.Context, q string, args ...any) ([]model.GetAll, error) {
+	var users []model.GetAll
+
+	err := inTenant(ctx, r.db, func(db querier) error {
+		data, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer data.Close()
+
+		for data.Next() {
+			var user model.GetAll
+
+			if err := data.Scan(
+				&user.ID,
+				&user.FirstName,
+				&user.LastName,
+				&user.Email,
+			); err != nil {
+				return err
+			}
+
+			users = append(users, user)
+		}
+
+		return data.Err()
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return users, nil
 }
 
-func (r *UserRepo) GetCount(ctx context.Context) (int, error) {
-	q := `SELECT COUNT(*) FROM users WHERE is_deleted = false`
+func (r *UserRepo) GetCount(ctx context.Context, filter model.UserFilter) (int, error) {
+	conds, args := userFilterConds(filter)
+	q := `SELECT COUNT(*) FROM users ` + whereClause(conds)
 	var count int
-	if err := r.db.QueryRowContext(ctx, q).Scan(&count); err != nil {
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, args...).Scan(&count)
+	})
+	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
 func (r *UserRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.GetByID, error) {
-	q := `SELECT id, first_name, last_name, email FROM users WHERE id = $1 AND is_deleted = false`
+	q := `SELECT id, first_name, last_name, email, ` + effectiveStatus + `, phone, date_of_birth, sex, gender, preferred_language,
+		address_line1, address_line2, address_city, address_region, address_postal_code, address_country,
+		avatar_updated_at, ` + userTag + `
+	FROM users WHERE id = $1 AND is_deleted = false AND ` + tenantUsers
 
 	var user model.GetByID
-	if err := r.db.QueryRowContext(ctx, q, id).Scan(
-		&user.ID,
-		&user.FirstName,
-		&user.LastName,
-		&user.Email,
-	); err != nil {
+	var dob sql.NullTime
+	var line1, line2, city, region, postalCode, country sql.NullString
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, id).Scan(
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&user.Email,
+			&user.Status,
+			&user.Phone,
+			&dob,
+			&user.Sex,
+			&user.Gender,
+			&user.PreferredLanguage,
+			&line1,
+			&line2,
+			&city,
+			&region,
+			&postalCode,
+			&country,
+			&user.AvatarUpdatedAt,
+			&user.Version,
+		)
+	})
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, model.ErrNotFound
 		}
 		return nil, err
 	}
 
+	if dob.Valid {
+		d := dob.Time.Format(model.DateLayout)
+		user.DateOfBirth = &d
+	}
+
+	addr := model.Address{
+		Line1:      line1.String,
+		Line2:      line2.String,
+		City:       city.String,
+		Region:     region.String,
+		PostalCode: postalCode.String,
+		Country:    country.String,
+	}
+	if !addr.IsZero() {
+		user.Address = &addr
+	}
+
 	return &user, nil
 
 }
 
-func (r *UserRepo) DeleteByID(ctx context.Context, id uuid.UUID) error {
-
-	q := `SELECT is_deleted FROM users WHERE id = $1`
+// lockForWrite locks a live user row for the rest of tx and checks cond
+// against its tag. Users outside the request's organisation are not found.
+func lockForWrite(ctx context.Context, tx *sql.Tx, id uuid.UUID, cond model.Precondition) error {
+	q := `SELECT is_deleted, ` + userTag + ` FROM users WHERE id = $1 AND ` + tenantUsers + ` FOR UPDATE`
 
 	var isDeleted bool
-	if err := r.db.QueryRowContext(ctx, q, id).Scan(&isDeleted); err != nil {
+	var version int64
+	if err := tx.QueryRowContext(ctx, q, id).Scan(&isDeleted, &version); err != nil {
 		if err == sql.ErrNoRows {
 			return model.ErrNotFound
 		}
@@ -103,38 +287,566 @@ func (r *UserRepo) DeleteByID(ctx context.Context, id uuid.UUID) error {
 	if isDeleted {
 		return model.ErrAlreadyDeleted
 	}
+	if !cond.Matches(version) {
+		return model.ErrPreconditionFailed
+	}
+	return nil
+}
+
+// DeleteByID marks a user deleted if cond matches the current tag.
+// Within an organisation, accounts other organisations share are forbidden.
+func (r *UserRepo) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	q = `UPDATE users SET is_deleted = true WHERE id = $1`
+	shared, err := lockShared(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
 
-	if _, err := r.db.ExecContext(ctx, q, id); err != nil {
+	if err := lockForWrite(ctx, tx, id, cond); err != nil {
 		return err
 	}
+	if shared {
+		return errSharedUser
+	}
 
-	return nil
+	q := `UPDATE users SET is_deleted = true, deleted_at = now() WHERE id = $1`
+
+	if _, err := tx.ExecContext(ctx, q, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *UserRepo) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error {
-	if data == nil || data.FirstName == nil || data.LastName == nil {
-		return model.ErrBadRequest
+// UpdateByID applies a merge patch if cond matches the current tag and
+// returns the new one.
+func (r *UserRepo) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+	if data == nil {
+		return 0, model.ErrBadRequest
 	}
 
-	q := `SELECT is_deleted FROM users WHERE id=$1`
-	var isDeleted bool
-	if err := r.db.QueryRowContext(ctx, q, id).Scan(&isDeleted); err != nil {
+	sets, args := patchSets(data)
+	if len(sets) == 0 {
+		return 0, model.ErrBadRequest
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	if err := lockForWrite(ctx, tx, id, cond); err != nil {
+		return 0, err
+	}
+
+	args = append(args, id)
+	q := fmt.Sprintf(`UPDATE users SET %s WHERE id = $%d RETURNING `+userTag, strings.Join(sets, ", "), len(args))
+
+	var version int64
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(&version); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// clearedProfileColumns nulls the optional personal data columns. It is used
+// when an account is anonymised or erased.
+const clearedProfileColumns = `phone = NULL,
+			date_of_birth = NULL,
+			sex = NULL,
+			gender = NULL,
+			preferred_language = NULL,
+			address_line1 = NULL,
+			address_line2 = NULL,
+			address_city = NULL,
+			address_region = NULL,
+			address_postal_code = NULL,
+			address_country = NULL,
+			avatar_content_type = NULL,
+			avatar_updated_at = NULL`
+
+// patchSets builds the SET assignments for the members present in a merge
+// patch. Null members are stored as NULL.
+func patchSets(data *model.UpdateUser) ([]string, []any) {
+	var sets []string
+	var args []any
+
+	set := func(col string, o model.Optional[string]) {
+		if !o.Set {
+			return
+		}
+		if o.Null {
+			sets = append(sets, col+" = NULL")
+			return
+		}
+		args = append(args, o.Value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", col, len(args)))
+	}
+
+	set("first_name", data.FirstName)
+	set("last_name", data.LastName)
+	set("phone", data.Phone)
+	set("date_of_birth", data.DateOfBirth)
+	set("sex", data.Sex)
+	set("gender", data.Gender)
+	set("preferred_language", data.PreferredLanguage)
+
+	if data.Address.Null {
+		for _, col := range addressColumns {
+			sets = append(sets, col+" = NULL")
+		}
+	} else if data.Address.Set {
+		a := data.Address.Value
+		set("address_line1", a.Line1)
+		set("address_line2", a.Line2)
+		set("address_city", a.City)
+		set("address_region", a.Region)
+		set("address_postal_code", a.PostalCode)
+		set("address_country", a.Country)
+	}
+
+	return sets, args
+}
+
+var addressColumns = []string{
+	"address_line1", "address_line2", "address_city", "address_region", "address_postal_code", "address_country",
+}
+
+// SetAvatar records that a new avatar of contentType was stored for the user.
+func (r *UserRepo) SetAvatar(ctx context.Context, id uuid.UUID, contentType string) error {
+	q := `UPDATE users SET avatar_content_type = $1, avatar_updated_at = now()
+		WHERE id = $2 AND is_deleted = false AND ` + tenantUsers
+
+	return r.execUser(ctx, q, contentType, id)
+}
+
+// GetAvatar returns the avatar metadata of a user, or ErrNotFound if the
+// user does not exist or has no avatar.
+func (r *UserRepo) GetAvatar(ctx context.Context, id uuid.UUID) (*model.Avatar, error) {
+	q := `SELECT avatar_content_type, avatar_updated_at FROM users
+		WHERE id = $1 AND is_deleted = false AND avatar_content_type IS NOT NULL AND ` + tenantUsers
+
+	var a model.Avatar
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, id).Scan(&a.ContentType, &a.UpdatedAt)
+	})
+	if err != nil {
 		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ClearAvatar removes the avatar metadata of a user.
+func (r *UserRepo) ClearAvatar(ctx context.Context, id uuid.UUID) error {
+	q := `UPDATE users SET avatar_content_type = NULL, avatar_updated_at = NULL
+		WHERE id = $1 AND is_deleted = false AND avatar_content_type IS NOT NULL AND ` + tenantUsers
+
+	return r.execUser(ctx, q, id)
+}
+
+// execUser runs an update of a single user within the request's organisation
+// and returns ErrNotFound if no row matched.
+func (r *UserRepo) execUser(ctx context.Context, q string, args ...any) error {
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
 			return model.ErrNotFound
 		}
-		return err
+		return nil
+	})
+}
+
+func (r *UserRepo) GetDeleted(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.DeletedUser, error) {
+	filter.Deleted = model.DeletedOnly
+	conds, args := userFilterConds(filter)
+	q := fmt.Sprintf(
+		`SELECT id, first_name, last_name, email, deleted_at FROM users %s %s LIMIT $%d OFFSET $%d`,
+		whereClause(conds), userOrderClause(filter.Sort), len(args)+1, len(args)+2,
+	)
+	args = append(args, limit, offset)
+
+	var users []model.DeletedUser
+
+	err := inTenant(ctx, r.db, func(db querier) error {
+		data, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer data.Close()
+
+		for data.Next() {
+			var user model.DeletedUser
+
+			if err := data.Scan(
+				&user.ID,
+				&user.FirstName,
+				&user.LastName,
+				&user.Email,
+				&user.DeletedAt,
+			); err != nil {
+				return err
+			}
+
+			users = append(users, user)
+		}
+
+		return data.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *UserRepo) RestoreByID(ctx context.Context, id uuid.UUID) error {
+	return inTenant(ctx, r.db, func(db querier) error {
+		q := `SELECT is_deleted, purged_at IS NOT NULL FROM users WHERE id = $1 AND ` + tenantUsers
+
+		var isDeleted, isPurged bool
+		if err := db.QueryRowContext(ctx, q, id).Scan(&isDeleted, &isPurged); err != nil {
+			if err == sql.ErrNoRows {
+				return model.ErrNotFound
+			}
+			return err
+		}
+
+		if isPurged {
+			return model.ErrAlreadyDeleted
+		}
+
+		if !isDeleted {
+			return model.ErrConflict
+		}
+
+		q = `UPDATE users SET is_deleted = false, deleted_at = NULL
+			WHERE id = $1 AND is_deleted = true AND purged_at IS NULL AND ` + tenantUsers
+
+		res, err := db.ExecContext(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		rows, _ := res.RowsAffected()
+		if rows == 0 {
+			return model.ErrConflict
+		}
+
+		return nil
+	})
+}
+
+// PurgeDeleted purges up to limit accounts soft-deleted before cutoff and
+// returns them with the mode each was purged with. It is run by the purge job
+// across all organisations. Refresh tokens and email change requests
+// reference users, so they are removed in the same transaction before the
+// user rows are deleted or anonymised. Anonymised rows keep their id and are
+// marked with purged_at so they are never selected again. In delete mode,
+// accounts with a practitioner record are anonymised instead, since their
+// appointments and prescriptions must outlive the account.
+func (r *UserRepo) PurgeDeleted(ctx context.Context, cutoff time.Time, mode string, limit int) ([]model.PurgedUser, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := `SELECT id FROM users
+		WHERE is_deleted = true AND purged_at IS NULL AND deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.QueryContext(ctx, q, cutoff, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	var strIDs []string
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		strIDs = append(strIDs, id.String())
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = ANY($1::uuid[])`, pq.Array(strIDs)); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_change_requests WHERE user_id = ANY($1::uuid[])`, pq.Array(strIDs)); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM invitations WHERE accepted_user_id = ANY($1::uuid[])`, pq.Array(strIDs)); err != nil {
+		return nil, err
+	}
+
+	q = `UPDATE users SET
+			first_name = 'Deleted',
+			last_name = 'User',
+			email = 'deleted-' || id || '@invalid.invalid',
+			password = '',
+			` + clearedProfileColumns + `,
+			purged_at = now()
+		WHERE id = ANY($1::uuid[])`
+	if mode == model.PurgeModeDelete {
+		q += ` AND EXISTS (SELECT 1 FROM practitioners p WHERE p.user_id = users.id)`
+	}
+
+	anonymised := map[uuid.UUID]bool{}
+	rows, err = tx.QueryContext(ctx, q+` RETURNING id`, pq.Array(strIDs))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		anonymised[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if mode == model.PurgeModeDelete {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ANY($1::uuid[]) AND purged_at IS NULL`, pq.Array(strIDs)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	purged := make([]model.PurgedUser, len(ids))
+	for i, id := range ids {
+		purged[i] = model.PurgedUser{ID: id, Mode: model.PurgeModeDelete}
+		if anonymised[id] {
+			purged[i].Mode = model.PurgeModeAnonymise
+		}
+	}
+	return purged, nil
+}
+
+// effectiveStatus is the SQL expression for a user's status with lapsed
+// suspensions and locks read as active.
+const effectiveStatus = `(CASE WHEN status IN ('suspended', 'locked') AND status_until <= now() THEN 'active' ELSE status::text END)`
+
+// userTag is the SQL expression for the version a user's ETag carries. The
+// row version is doubled and the low bit set once a suspension or lock has
+// lapsed, so the tag changes when effectiveStatus does.
+const userTag = `(version * 2 + CASE WHEN status IN ('suspended', 'locked') AND status_until <= now() THEN 1 ELSE 0 END)`
+
+// effectiveRole is the SQL expression for the role a user holds in the
+// request's organisation: admins of it read as admin, or admins of any
+// organisation for unscoped requests.
+const effectiveRole = `(CASE
+		WHEN users.role = 'super_admin' THEN 'super_admin'
+		WHEN EXISTS (SELECT 1 FROM organisation_members m WHERE m.user_id = users.id AND m.role = 'admin'
+			AND (app_organisation_id() IS NULL OR m.organisation_id = app_organisation_id())) THEN 'admin'
+		ELSE 'user'
+	END)`
+
+func (r *UserRepo) GetStatus(ctx context.Context, id uuid.UUID) (*model.AccountStatus, error) {
+	q := `SELECT ` + effectiveStatus + `, status_reason, status_until, status_changed_at, status_changed_by
+	FROM users WHERE id = $1 AND is_deleted = false AND ` + tenantUsers
+
+	var s model.AccountStatus
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, id).Scan(&s.Status, &s.Reason, &s.Until, &s.ChangedAt, &s.ChangedBy)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if s.Status == model.StatusActive {
+		s.Until = nil
+	}
+	return &s, nil
+}
+
+// SetStatus changes the status of a live user and returns the previous
+// effective status. Leaving the active state revokes all refresh tokens in the
+// same transaction, so no session outlives the change. Within an
+// organisation, accounts other organisations share are forbidden.
+func (r *UserRepo) SetStatus(ctx context.Context, id uuid.UUID, change model.ChangeStatus, actorID uuid.UUID) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	shared, err := lockShared(ctx, tx, id)
+	if err != nil {
+		return "", err
+	}
+	if err := setTenant(ctx, tx); err != nil {
+		return "", err
+	}
+
+	q := `SELECT is_deleted, ` + effectiveStatus + ` FROM users WHERE id = $1 AND ` + tenantUsers + ` FOR UPDATE`
+
+	var isDeleted bool
+	var previous string
+	if err := tx.QueryRowContext(ctx, q, id).Scan(&isDeleted, &previous); err != nil {
+		if err == sql.ErrNoRows {
+			return "", model.ErrNotFound
+		}
+		return "", err
 	}
 
 	if isDeleted {
-		return model.ErrAlreadyDeleted
+		return "", model.ErrAlreadyDeleted
+	}
+	if shared {
+		return "", errSharedUser
 	}
 
-	q = `UPDATE users SET first_name=$1, last_name=$2 WHERE id = $3 AND is_deleted=false`
-	if _, err := r.db.ExecContext(ctx, q, *data.FirstName, *data.LastName, id); err != nil {
-		return err
+	var reason any
+	if change.Reason != "" {
+		reason = change.Reason
 	}
 
-	return nil
+	q = `UPDATE users SET
+		status = $1,
+		status_reason = $2,
+		status_until = $3,
+		status_changed_at = now(),
+		status_changed_by = $4
+	WHERE id = $5`
+	if _, err := tx.ExecContext(ctx, q, change.Status, reason, change.Until, actorID, id); err != nil {
+		return "", err
+	}
+
+	if change.Status != model.StatusActive {
+		q = `UPDATE refresh_tokens SET revoked = true WHERE user_id = $1 AND revoked = false`
+		if _, err := tx.ExecContext(ctx, q, id); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return previous, nil
+}
+
+// userExportColumns maps the exportable fields to expressions yielding text
+// or timestamps, so rows can be scanned without knowing the column types.
+var userExportColumns = map[string]string{
+	"id":                  "id::text",
+	"first_name":          "first_name",
+	"last_name":           "last_name",
+	"email":               "email",
+	"role":                effectiveRole,
+	"status":              effectiveStatus + "::text",
+	"organisation_id":     "organisation_id::text",
+	"phone":               "phone",
+	"date_of_birth":       "date_of_birth::text",
+	"sex":                 "sex",
+	"gender":              "gender",
+	"preferred_language":  "preferred_language",
+	"address_line1":       "address_line1",
+	"address_line2":       "address_line2",
+	"address_city":        "address_city",
+	"address_region":      "address_region",
+	"address_postal_code": "address_postal_code",
+	"address_country":     "address_country",
+	"created_at":          "created_at",
+	"updated_at":          "updated_at",
+	"deleted_at":          "deleted_at",
+}
+
+// StreamUsers calls fn with the requested fields of every user matching
+// filter, one row at a time as they are read from the database. Values are
+// a string, a time.Time or nil. Returning an error from fn stops the scan.
+func (r *UserRepo) StreamUsers(ctx context.Context, filter model.UserFilter, fields []string, fn func(values []any) error) error {
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		col, ok := userExportColumns[f]
+		if !ok {
+			return fmt.Errorf("unknown export field %q", f)
+		}
+		cols[i] = col
+	}
+
+	conds, args := userFilterConds(filter)
+	q := fmt.Sprintf(`SELECT %s FROM users %s %s`, strings.Join(cols, ", "), whereClause(conds), userOrderClause(filter.Sort))
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		values := make([]any, len(fields))
+		dest := make([]any, len(fields))
+		for i := range dest {
+			dest[i] = &values[i]
+		}
+
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				return err
+			}
+			for i, v := range values {
+				if b, ok := v.([]byte); ok {
+					values[i] = string(b)
+				}
+			}
+			if err := fn(values); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	})
 }
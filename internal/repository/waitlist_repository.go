@@ -0,0 +1,502 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type WaitlistRepository interface {
+	Create(ctx context.Context, entry model.WaitlistEntry) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.WaitlistEntry, error)
+	List(ctx context.Context, filter model.WaitlistFilter, limit, offset int) ([]model.WaitlistEntry, error)
+	Count(ctx context.Context, filter model.WaitlistFilter) (int, error)
+	Cancel(ctx context.Context, id uuid.UUID) error
+	NextEligible(ctx context.Context, slot model.FreedSlot, day string) (*model.WaitlistEntry, error)
+	CreateOffer(ctx context.Context, offer model.WaitlistOffer) error
+	GetOffer(ctx context.Context, id uuid.UUID) (*model.WaitlistOffer, error)
+	ResolveOffer(ctx context.Context, id uuid.UUID, status string, appointmentID *uuid.UUID, at time.Time) error
+	AcceptOffer(ctx context.Context, id uuid.UUID, appointment model.Appointment, at time.Time) error
+	ListHolds(ctx context.Context, practitionerID uuid.UUID, from, to, now time.Time) ([]model.TimeRange, error)
+	ListLapsed(ctx context.Context, now time.Time, limit int) ([]model.WaitlistOffer, error)
+}
+
+type WaitlistRepo struct {
+	db *sql.DB
+}
+
+func NewWaitlistRepository(db *sql.DB) *WaitlistRepo {
+	return &WaitlistRepo{
+		db: db,
+	}
+}
+
+// Create puts an entry on the waitlist. The practitioner and the patient must
+// be live and belong to the same organisation, or it is ErrNotFound.
+func (r *WaitlistRepo) Create(ctx context.Context, entry model.WaitlistEntry) error {
+	q := `INSERT INTO waitlist_entries(id, organisation_id, practitioner_id, patient_id, service, from_date, to_date, created_by, created_at)
+		SELECT $1, p.organisation_id, p.id, pt.id, $4, $5, $6, $7, $8
+		FROM practitioners p JOIN patients pt ON pt.organisation_id = p.organisation_id
+		WHERE p.id = $2 AND pt.id = $3 AND p.is_deleted = false AND pt.is_deleted = false AND ` + tenantOrganisationOf("p")
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q,
+			entry.ID,
+			entry.PractitionerID,
+			entry.PatientID,
+			entry.Service,
+			entry.From,
+			entry.To,
+			entry.CreatedBy,
+			entry.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
+
+const waitlistColumns = `e.id, e.organisation_id, e.practitioner_id, u.first_name || ' ' || u.last_name,
+	e.patient_id, pt.first_name || ' ' || pt.last_name, e.service, to_char(e.from_date, 'YYYY-MM-DD'),
+	to_char(e.to_date, 'YYYY-MM-DD'), e.status, e.created_by, e.created_at, e.updated_at, p.user_id, pt.user_id,
+	o.id, o.starts_at, o.ends_at, o.expires_at, o.created_at`
+
+// waitlistJoins brings in the pending offer of an entry, of which there is at
+// most one.
+const waitlistJoins = `waitlist_entries e
+	JOIN practitioners p ON p.id = e.practitioner_id
+	JOIN users u ON u.id = p.user_id
+	JOIN patients pt ON pt.id = e.patient_id
+	LEFT JOIN waitlist_offers o ON o.entry_id = e.id AND o.status = 'pending'`
+
+func scanWaitlistEntry(row rowScanner) (*model.WaitlistEntry, error) {
+	var e model.WaitlistEntry
+	var offerID *uuid.UUID
+	var startsAt, endsAt, expiresAt, offeredAt *time.Time
+	if err := row.Scan(
+		&e.ID,
+		&e.OrganisationID,
+		&e.PractitionerID,
+		&e.PractitionerName,
+		&e.PatientID,
+		&e.PatientName,
+		&e.Service,
+		&e.From,
+		&e.To,
+		&e.Status,
+		&e.CreatedBy,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+		&e.PractitionerUserID,
+		&e.PatientUserID,
+		&offerID,
+		&startsAt,
+		&endsAt,
+		&expiresAt,
+		&offeredAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if offerID != nil {
+		e.Offer = &model.WaitlistOffer{
+			ID:             *offerID,
+			OrganisationID: e.OrganisationID,
+			EntryID:        e.ID,
+			PractitionerID: e.PractitionerID,
+			PatientID:      e.PatientID,
+			StartsAt:       *startsAt,
+			EndsAt:         *endsAt,
+			Status:         model.OfferPending,
+			ExpiresAt:      *expiresAt,
+			CreatedAt:      *offeredAt,
+			PatientUserID:  e.PatientUserID,
+		}
+	}
+	return &e, nil
+}
+
+func (r *WaitlistRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.WaitlistEntry, error) {
+	q := `SELECT ` + waitlistColumns + ` FROM ` + waitlistJoins + ` WHERE e.id = $1 AND ` + tenantOrganisationOf("e")
+
+	var e *model.WaitlistEntry
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		e, err = scanWaitlistEntry(db.QueryRowContext(ctx, q, id))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// waitlistFilterConds builds the WHERE conditions for filter, limited to the
+// request's organisation.
+func waitlistFilterConds(filter model.WaitlistFilter) ([]string, []any) {
+	conds := []string{tenantOrganisationOf("e")}
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.PractitionerID != nil {
+		conds = append(conds, "e.practitioner_id = "+arg(*filter.PractitionerID))
+	}
+	if filter.PatientID != nil {
+		conds = append(conds, "e.patient_id = "+arg(*filter.PatientID))
+	}
+	if filter.Status != "" {
+		conds = append(conds, "e.status = "+arg(filter.Status))
+	}
+	if filter.ParticipantID != nil {
+		p := arg(*filter.ParticipantID)
+		conds = append(conds, fmt.Sprintf("(p.user_id = %s OR pt.user_id = %s)", p, p))
+	}
+
+	return conds, args
+}
+
+// List returns a page of the waitlist in the order entries are offered slots.
+func (r *WaitlistRepo) List(ctx context.Context, filter model.WaitlistFilter, limit, offset int) ([]model.WaitlistEntry, error) {
+	conds, args := waitlistFilterConds(filter)
+	q := fmt.Sprintf(`SELECT %s FROM %s %s ORDER BY e.created_at, e.id LIMIT $%d OFFSET $%d`,
+		waitlistColumns, waitlistJoins, whereClause(conds), len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	var entries []model.WaitlistEntry
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			e, err := scanWaitlistEntry(rows)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, *e)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *WaitlistRepo) Count(ctx context.Context, filter model.WaitlistFilter) (int, error) {
+	conds, args := waitlistFilterConds(filter)
+	q := `SELECT COUNT(*) FROM ` + waitlistJoins + ` ` + whereClause(conds)
+
+	var count int
+	err := inTenant(ctx, r.db, func(db querier) error {
+		return db.QueryRowContext(ctx, q, args...).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Cancel takes an entry that is still waiting off the waitlist. Any other
+// entry is ErrConflict.
+func (r *WaitlistRepo) Cancel(ctx context.Context, id uuid.UUID) error {
+	q := `UPDATE waitlist_entries SET status = 'cancelled' WHERE id = $1 AND status = 'waiting' AND ` + tenantOrganisation
+
+	return inTenant(ctx, r.db, func(db querier) error {
+		res, err := db.ExecContext(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return model.ErrConflict
+		}
+		return nil
+	})
+}
+
+// NextEligible returns the entry that joined first among those waiting for
+// the practitioner on day, a date of the practitioner's time zone, that want
+// the slot's service or any service and have not been offered the slot
+// before. It is ErrNotFound when there is none.
+func (r *WaitlistRepo) NextEligible(ctx context.Context, slot model.FreedSlot, day string) (*model.WaitlistEntry, error) {
+	q := `SELECT ` + waitlistColumns + ` FROM ` + waitlistJoins + `
+		WHERE e.practitioner_id = $1 AND e.status = 'waiting' AND $2::date BETWEEN e.from_date AND e.to_date
+			AND (e.service IS NULL OR e.service = $3)
+			AND pt.is_deleted = false
+			AND NOT EXISTS (SELECT 1 FROM waitlist_offers x WHERE x.entry_id = e.id AND x.starts_at = $4)
+			AND ` + tenantOrganisationOf("e") + `
+		ORDER BY e.created_at, e.id
+		LIMIT 1`
+
+	var e *model.WaitlistEntry
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		e, err = scanWaitlistEntry(db.QueryRowContext(ctx, q, slot.PractitionerID, day, slot.Service, slot.StartsAt))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// CreateOffer offers a slot to a waiting entry. An entry that is no longer
+// waiting, or a slot already held by another offer, is ErrConflict.
+func (r *WaitlistRepo) CreateOffer(ctx context.Context, offer model.WaitlistOffer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	q := `UPDATE waitlist_entries SET status = 'offered' WHERE id = $1 AND status = 'waiting' AND ` + tenantOrganisation
+	res, err := tx.ExecContext(ctx, q, offer.EntryID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return model.ErrConflict
+	}
+
+	q = `INSERT INTO waitlist_offers(id, organisation_id, entry_id, practitioner_id, starts_at, ends_at, service, expires_at, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err = tx.ExecContext(ctx, q,
+		offer.ID,
+		offer.OrganisationID,
+		offer.EntryID,
+		offer.PractitionerID,
+		offer.StartsAt,
+		offer.EndsAt,
+		offer.Service,
+		offer.ExpiresAt,
+		offer.CreatedAt,
+	)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return model.ErrConflict
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const offerColumns = `o.id, o.organisation_id, o.entry_id, o.practitioner_id, e.patient_id, o.starts_at, o.ends_at,
+	o.service, o.status, o.expires_at, o.appointment_id, o.created_at, o.responded_at, pt.user_id`
+
+const offerJoins = `waitlist_offers o
+	JOIN waitlist_entries e ON e.id = o.entry_id
+	JOIN patients pt ON pt.id = e.patient_id`
+
+func scanOffer(row rowScanner) (*model.WaitlistOffer, error) {
+	var o model.WaitlistOffer
+	if err := row.Scan(
+		&o.ID,
+		&o.OrganisationID,
+		&o.EntryID,
+		&o.PractitionerID,
+		&o.PatientID,
+		&o.StartsAt,
+		&o.EndsAt,
+		&o.Service,
+		&o.Status,
+		&o.ExpiresAt,
+		&o.AppointmentID,
+		&o.CreatedAt,
+		&o.RespondedAt,
+		&o.PatientUserID,
+	); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *WaitlistRepo) GetOffer(ctx context.Context, id uuid.UUID) (*model.WaitlistOffer, error) {
+	q := `SELECT ` + offerColumns + ` FROM ` + offerJoins + ` WHERE o.id = $1 AND ` + tenantOrganisationOf("o")
+
+	var o *model.WaitlistOffer
+	err := inTenant(ctx, r.db, func(db querier) (err error) {
+		o, err = scanOffer(db.QueryRowContext(ctx, q, id))
+		if err == sql.ErrNoRows {
+			return model.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// ResolveOffer closes a pending offer with status at the given time. An
+// accepted offer books its entry; a declined or lapsed one puts it back in
+// the queue. An offer that is no longer pending is ErrConflict.
+func (r *WaitlistRepo) ResolveOffer(ctx context.Context, id uuid.UUID, status string, appointmentID *uuid.UUID, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	q := `UPDATE waitlist_offers SET status = $2, appointment_id = $3, responded_at = $4
+		WHERE id = $1 AND status = 'pending' AND ` + tenantOrganisation + `
+		RETURNING entry_id`
+	var entryID uuid.UUID
+	if err := tx.QueryRowContext(ctx, q, id, status, appointmentID, at).Scan(&entryID); err != nil {
+		if err == sql.ErrNoRows {
+			return model.ErrConflict
+		}
+		return err
+	}
+
+	entryStatus := model.WaitlistWaiting
+	if status == model.OfferAccepted {
+		entryStatus = model.WaitlistBooked
+	}
+	q = `UPDATE waitlist_entries SET status = $2 WHERE id = $1 AND status = 'offered'`
+	if _, err := tx.ExecContext(ctx, q, entryID, entryStatus); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AcceptOffer books appointment for an offer that is pending and unexpired
+// at the given time, and closes the offer as accepted, in one transaction.
+// The offer row is locked first, so the lapse job or a second accept waits
+// and then finds it closed. An offer that can no longer be taken up, or a
+// slot that overlaps another appointment, is ErrConflict; nothing is booked
+// then.
+func (r *WaitlistRepo) AcceptOffer(ctx context.Context, id uuid.UUID, appointment model.Appointment, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	q := `SELECT entry_id FROM waitlist_offers
+		WHERE id = $1 AND status = 'pending' AND expires_at > $2 AND ` + tenantOrganisation + `
+		FOR UPDATE`
+	var entryID uuid.UUID
+	if err := tx.QueryRowContext(ctx, q, id, at).Scan(&entryID); err != nil {
+		if err == sql.ErrNoRows {
+			return model.ErrConflict
+		}
+		return err
+	}
+
+	if err := insertAppointment(ctx, tx, appointment); err != nil {
+		return err
+	}
+
+	q = `UPDATE waitlist_offers SET status = 'accepted', appointment_id = $2, responded_at = $3 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, q, id, appointment.ID, at); err != nil {
+		return err
+	}
+
+	q = `UPDATE waitlist_entries SET status = 'booked' WHERE id = $1 AND status = 'offered'`
+	if _, err := tx.ExecContext(ctx, q, entryID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListHolds returns the times of the practitioner's slots, overlapping
+// [from, to), that are held by offers pending at now.
+func (r *WaitlistRepo) ListHolds(ctx context.Context, practitionerID uuid.UUID, from, to, now time.Time) ([]model.TimeRange, error) {
+	q := `SELECT starts_at, ends_at FROM waitlist_offers
+		WHERE practitioner_id = $1 AND status = 'pending' AND expires_at > $4
+			AND tstzrange(starts_at, ends_at) && tstzrange($2, $3) AND ` + tenantOrganisation + `
+		ORDER BY starts_at`
+
+	var holds []model.TimeRange
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, practitionerID, from, to, now)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var h model.TimeRange
+			if err := rows.Scan(&h.Start, &h.End); err != nil {
+				return err
+			}
+			holds = append(holds, h)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+// ListLapsed returns up to limit pending offers that expired by now, oldest
+// first.
+func (r *WaitlistRepo) ListLapsed(ctx context.Context, now time.Time, limit int) ([]model.WaitlistOffer, error) {
+	q := `SELECT ` + offerColumns + ` FROM ` + offerJoins + `
+		WHERE o.status = 'pending' AND o.expires_at <= $1 AND ` + tenantOrganisationOf("o") + `
+		ORDER BY o.expires_at, o.id
+		LIMIT $2`
+
+	var offers []model.WaitlistOffer
+	err := inTenant(ctx, r.db, func(db querier) error {
+		rows, err := db.QueryContext(ctx, q, now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			o, err := scanOffer(rows)
+			if err != nil {
+				return err
+			}
+			offers = append(offers, *o)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return offers, nil
+}
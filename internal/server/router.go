@@ -12,7 +12,29 @@ import (
 	"github.com/go-chi/cors"
 )
 
-func Routes(authHandler *handler.AuthHandler, userHandler *handler.UserHandler, cfg *config.Config) http.Handler {
+// Handlers holds the HTTP handlers Routes mounts.
+type Handlers struct {
+	Auth         *handler.AuthHandler
+	User         *handler.UserHandler
+	Erasure      *handler.ErasureHandler
+	Export       *handler.ExportHandler
+	EmailChange  *handler.EmailChangeHandler
+	Invitation   *handler.InvitationHandler
+	Organisation *handler.OrganisationHandler
+	Import       *handler.ImportHandler
+	Patient      *handler.PatientHandler
+	Practitioner *handler.PractitionerHandler
+	Appointment  *handler.AppointmentHandler
+	Waitlist     *handler.WaitlistHandler
+	Calendar     *handler.CalendarHandler
+	Medication   *handler.MedicationHandler
+	Prescription *handler.PrescriptionHandler
+	Allergy      *handler.AllergyHandler
+	Lab          *handler.LabHandler
+	FHIR         *handler.FHIRHandler
+}
+
+func Routes(h Handlers, accounts appMiddleware.AccountChecker, cfg *config.Config) http.Handler {
 
 	r := chi.NewRouter()
 
@@ -23,8 +45,8 @@ func Routes(authHandler *handler.AuthHandler, userHandler *handler.UserHandler,
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:4200"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag", "Location"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -36,20 +58,182 @@ func Routes(authHandler *handler.AuthHandler, userHandler *handler.UserHandler,
 		w.Write([]byte(`{"status":"ok"}`))
 	})
 
+	r.Route("/fhir/R4", func(r chi.Router) {
+		r.NotFound(h.FHIR.NotFound)
+		r.MethodNotAllowed(h.FHIR.MethodNotAllowed)
+		r.Get("/metadata", h.FHIR.Metadata)
+
+		r.Group(func(r chi.Router) {
+			r.Use(appMiddleware.FHIRAccessTokenMiddleware(cfg, accounts))
+			r.Get("/Patient", h.FHIR.SearchPatients)
+			r.Post("/Patient", h.FHIR.CreatePatient)
+			r.Get("/Patient/{id}", h.FHIR.ReadPatient)
+			r.Get("/Practitioner", h.FHIR.SearchPractitioners)
+			r.Post("/Practitioner", h.FHIR.CreatePractitioner)
+			r.Get("/Practitioner/{id}", h.FHIR.ReadPractitioner)
+			r.Get("/Appointment", h.FHIR.SearchAppointments)
+			r.Post("/Appointment", h.FHIR.CreateAppointment)
+			r.Get("/Appointment/{id}", h.FHIR.ReadAppointment)
+		})
+	})
+
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Route("/auth", func(r chi.Router) {
-			r.Post("/register", authHandler.Register)
-			r.Post("/login", authHandler.Login)
-			r.With(appMiddleware.RefreshTokenMiddleware(cfg)).Post("/logout", authHandler.Logout)
-			r.With(appMiddleware.RefreshTokenMiddleware(cfg)).Post("/refresh", authHandler.Refresh)
+			r.Post("/register", h.Auth.Register)
+			r.Post("/login", h.Auth.Login)
+			r.With(appMiddleware.RefreshTokenMiddleware(cfg)).Post("/logout", h.Auth.Logout)
+			r.With(appMiddleware.RefreshTokenMiddleware(cfg)).Post("/refresh", h.Auth.Refresh)
+			r.Post("/email/confirm", h.EmailChange.Confirm)
+			r.Post("/email/revert", h.EmailChange.Revert)
+			r.Post("/invitations/accept", h.Invitation.Accept)
 		})
 
 		r.Route("/users", func(r chi.Router) {
-			r.Use(appMiddleware.AccessTokenMiddleware(cfg))
-			r.Get("/", userHandler.GetAll)
-			r.Delete("/{id}", userHandler.DeleteByID)
-			r.Get("/{id}", userHandler.GetByID)
-			r.Patch("/{id}", userHandler.UpdateByID)
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Get("/", h.User.GetAll)
+			r.Get("/deleted", h.User.GetDeleted)
+			r.Post("/import", h.Import.Import)
+			r.Get("/export", h.User.Export)
+			r.Post("/{id}/restore", h.User.RestoreByID)
+			r.Get("/{id}/status", h.User.GetStatus)
+			r.Put("/{id}/status", h.User.SetStatus)
+			r.Post("/{id}/erasure", h.Erasure.Erase)
+			r.Get("/{id}/erasure", h.Erasure.GetCertificate)
+			r.Post("/{id}/email", h.EmailChange.Request)
+			r.Put("/{id}/avatar", h.User.UploadAvatar)
+			r.Get("/{id}/avatar", h.User.GetAvatar)
+			r.Delete("/{id}/avatar", h.User.DeleteAvatar)
+			r.Delete("/{id}", h.User.DeleteByID)
+			r.Get("/{id}", h.User.GetByID)
+			r.Patch("/{id}", h.User.UpdateByID)
+		})
+
+		r.Route("/invitations", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/", h.Invitation.Create)
+			r.Get("/", h.Invitation.List)
+			r.Get("/{id}", h.Invitation.GetByID)
+			r.Post("/{id}/resend", h.Invitation.Resend)
+			r.Post("/{id}/revoke", h.Invitation.Revoke)
+		})
+
+		r.Route("/organisations", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/", h.Organisation.Create)
+			r.Get("/", h.Organisation.List)
+			r.Get("/{id}/members", h.Organisation.ListMembers)
+			r.Put("/{id}/members/{userID}", h.Organisation.SetMember)
+			r.Delete("/{id}/members/{userID}", h.Organisation.RemoveMember)
+		})
+
+		r.Route("/patients", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/", h.Patient.Create)
+			r.Get("/", h.Patient.List)
+			r.Get("/me", h.Patient.ListOwn)
+			r.Get("/{id}", h.Patient.GetByID)
+			r.Patch("/{id}", h.Patient.UpdateByID)
+			r.Delete("/{id}", h.Patient.DeleteByID)
+			r.Post("/{id}/identifiers", h.Patient.AddIdentifier)
+			r.Delete("/{id}/identifiers/{identifierID}", h.Patient.RemoveIdentifier)
+			r.Put("/{id}/user", h.Patient.LinkUser)
+			r.Delete("/{id}/user", h.Patient.UnlinkUser)
+			r.Post("/{id}/allergies", h.Allergy.Create)
+			r.Get("/{id}/allergies", h.Allergy.List)
+			r.Patch("/{id}/allergies/{allergyID}", h.Allergy.UpdateByID)
+			r.Get("/{id}/observations", h.Lab.ListObservations)
+		})
+
+		r.Route("/facilities", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/", h.Practitioner.CreateFacility)
+			r.Get("/", h.Practitioner.ListFacilities)
+		})
+
+		r.Route("/practitioners", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/", h.Practitioner.Create)
+			r.Get("/", h.Practitioner.List)
+			r.Get("/{id}", h.Practitioner.GetByID)
+			r.Patch("/{id}", h.Practitioner.UpdateByID)
+			r.Delete("/{id}", h.Practitioner.DeleteByID)
+			r.Post("/{id}/licences", h.Practitioner.AddLicence)
+			r.Delete("/{id}/licences/{licenceID}", h.Practitioner.RemoveLicence)
+			r.Get("/{id}/schedule", h.Appointment.GetSchedule)
+			r.Put("/{id}/schedule", h.Appointment.SetSchedule)
+			r.Get("/{id}/exceptions", h.Appointment.ListExceptions)
+			r.Post("/{id}/exceptions", h.Appointment.AddException)
+			r.Delete("/{id}/exceptions/{exceptionID}", h.Appointment.RemoveException)
+			r.Get("/{id}/slots", h.Appointment.Slots)
+		})
+
+		r.Route("/appointments", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/", h.Appointment.Book)
+			r.Get("/", h.Appointment.List)
+			r.Get("/{id}", h.Appointment.GetByID)
+			r.Post("/{id}/reschedule", h.Appointment.Reschedule)
+			r.Put("/{id}/status", h.Appointment.SetStatus)
+		})
+
+		r.Route("/waitlist", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/", h.Waitlist.Join)
+			r.Get("/", h.Waitlist.List)
+			r.Get("/{id}", h.Waitlist.GetByID)
+			r.Delete("/{id}", h.Waitlist.Leave)
+			r.Post("/offers/{id}/accept", h.Waitlist.AcceptOffer)
+			r.Post("/offers/{id}/decline", h.Waitlist.DeclineOffer)
+		})
+
+		r.Route("/medications", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Get("/", h.Medication.List)
+			r.Get("/{rxcui}", h.Medication.GetByRxCUI)
+		})
+
+		r.Route("/prescriptions", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/", h.Prescription.Create)
+			r.Get("/", h.Prescription.List)
+			r.Post("/check", h.Prescription.Check)
+			r.Get("/{id}", h.Prescription.GetByID)
+			r.Put("/{id}/status", h.Prescription.SetStatus)
+			r.Post("/{id}/refills", h.Prescription.RequestRefill)
+			r.Get("/{id}/refills", h.Prescription.ListRefills)
+			r.Put("/{id}/refills/{refillID}", h.Prescription.DecideRefill)
+		})
+
+		r.Route("/lab", func(r chi.Router) {
+			r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+			r.Post("/messages", h.Lab.Receive)
+			r.Get("/reconciliation", h.Lab.ListReconciliation)
+			r.Post("/reconciliation/{id}/resolve", h.Lab.Resolve)
+			r.Post("/reconciliation/{id}/discard", h.Lab.Discard)
+			r.Get("/critical", h.Lab.ListCritical)
+			r.Post("/observations/{id}/acknowledge", h.Lab.AcknowledgeCritical)
+		})
+
+		r.Route("/exports", func(r chi.Router) {
+			r.Get("/{id}/download", h.Export.Download)
+
+			r.Group(func(r chi.Router) {
+				r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+				r.Post("/", h.Export.Request)
+				r.Get("/", h.Export.List)
+				r.Get("/{id}", h.Export.GetByID)
+			})
+		})
+
+		r.Route("/calendar", func(r chi.Router) {
+			r.Get("/{token}", h.Calendar.Feed)
+
+			r.Group(func(r chi.Router) {
+				r.Use(appMiddleware.AccessTokenMiddleware(cfg, accounts))
+				r.Post("/feed", h.Calendar.CreateFeed)
+				r.Get("/feed", h.Calendar.GetFeed)
+				r.Delete("/feed", h.Calendar.RevokeFeed)
+			})
 		})
 	})
 
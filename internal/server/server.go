@@ -3,23 +3,30 @@ package server
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/PranavJoshi2893/med-portal/internal/config"
+	"github.com/PranavJoshi2893/med-portal/pkg/mllp"
 )
 
 type Server struct {
 	httpServer *http.Server
+	mllpServer *mllp.Server
 	db         *sql.DB
 }
 
-func NewServer(cfg *config.Config, db *sql.DB, handler http.Handler) *Server {
-	return &Server{
+// NewServer returns a server for handler. When cfg.MLLPAddr is set and
+// lab is not nil, lab messages are also received over MLLP.
+func NewServer(cfg *config.Config, db *sql.DB, handler http.Handler, lab mllp.Handler) *Server {
+	s := &Server{
 		httpServer: &http.Server{
 			Addr:              cfg.ServerPort,
 			Handler:           handler,
@@ -30,10 +37,18 @@ func NewServer(cfg *config.Config, db *sql.DB, handler http.Handler) *Server {
 		},
 		db: db,
 	}
+	if cfg.MLLPAddr != "" && lab != nil {
+		s.mllpServer = &mllp.Server{
+			Addr:        cfg.MLLPAddr,
+			Handler:     lab,
+			IdleTimeout: cfg.MLLPIdleTimeout,
+		}
+	}
+	return s
 }
 
 func (s *Server) Run() error {
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 2)
 
 	go func() {
 		err := s.httpServer.ListenAndServe()
@@ -42,6 +57,16 @@ func (s *Server) Run() error {
 		}
 	}()
 
+	if s.mllpServer != nil {
+		log.Println("mllp listener is running on", s.mllpServer.Addr)
+		go func() {
+			err := s.mllpServer.ListenAndServe()
+			if err != nil && !errors.Is(err, mllp.ErrServerClosed) {
+				errChan <- fmt.Errorf("mllp: %w", err)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -52,7 +77,24 @@ func (s *Server) Run() error {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
 
+		// Both listeners drain at the same time, so an MLLP connection
+		// waiting on its acknowledgment gets the same grace period as an
+		// HTTP request.
+		var wg sync.WaitGroup
+		var mllpErr error
+		if s.mllpServer != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				mllpErr = s.mllpServer.Shutdown(ctx)
+			}()
+		}
+
 		err := s.httpServer.Shutdown(ctx)
+		wg.Wait()
+		if err == nil {
+			err = mllpErr
+		}
 		if err != nil {
 			return fmt.Errorf("forced shutdown: %v", err)
 		}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+type AllergyService struct {
+	repo          repository.AllergyRepository
+	patients      repository.PatientRepository
+	practitioners repository.PractitionerRepository
+	medications   repository.MedicationRepository
+	audit         repository.AuditRepository
+}
+
+func NewAllergyService(repo repository.AllergyRepository, patients repository.PatientRepository, practitioners repository.PractitionerRepository, medications repository.MedicationRepository, audit repository.AuditRepository) *AllergyService {
+	return &AllergyService{
+		repo:          repo,
+		patients:      patients,
+		practitioners: practitioners,
+		medications:   medications,
+		audit:         audit,
+	}
+}
+
+// allergyError adds context to the repository errors of a single allergy.
+func allergyError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("allergy %w", err)
+	}
+	return err
+}
+
+// Create records an allergy of a patient. Admins and practitioners may
+// record allergies.
+func (s *AllergyService) Create(ctx context.Context, patientID uuid.UUID, data *model.CreateAllergy, callerID uuid.UUID, callerRole string) (*model.Allergy, error) {
+	clinician, err := clinicalAccess(ctx, s.patients, s.practitioners, patientID, callerID, callerRole)
+	if err != nil {
+		return nil, err
+	}
+	if !clinician {
+		return nil, fmt.Errorf("only clinicians can record allergies: %w", model.ErrForbidden)
+	}
+
+	allergen := data.Allergen
+	if data.RxCUI != "" {
+		medication, err := s.medications.GetByRxCUI(ctx, data.RxCUI)
+		if err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return nil, model.ValidationErrors{model.FieldError{Field: "rxcui", Message: "rxcui is not in the medication catalogue"}}
+			}
+			return nil, err
+		}
+		if allergen == "" {
+			allergen = medication.Name
+		}
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	err = s.repo.Create(ctx, model.Allergy{
+		ID:           id,
+		PatientID:    patientID,
+		Allergen:     allergen,
+		RxCUI:        optionalString(data.RxCUI),
+		Reaction:     optionalString(data.Reaction),
+		Severity:     data.Severity,
+		Verification: data.Verification,
+		RecordedBy:   &callerID,
+	})
+	if err != nil {
+		return nil, patientError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditAllergyRecorded, "allergy", id, map[string]any{
+		"patient_id":   patientID,
+		"allergen":     allergen,
+		"severity":     data.Severity,
+		"verification": data.Verification,
+	})
+
+	allergy, err := s.repo.GetByID(ctx, patientID, id)
+	if err != nil {
+		return nil, allergyError(err)
+	}
+	return allergy, nil
+}
+
+// List returns every allergy recorded for a patient, to clinicians and to
+// the patient.
+func (s *AllergyService) List(ctx context.Context, patientID uuid.UUID, callerID uuid.UUID, callerRole string) ([]model.Allergy, error) {
+	if _, err := clinicalAccess(ctx, s.patients, s.practitioners, patientID, callerID, callerRole); err != nil {
+		return nil, err
+	}
+
+	allergies, err := s.repo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if allergies == nil {
+		allergies = []model.Allergy{}
+	}
+	return allergies, nil
+}
+
+// UpdateByID applies a merge patch to an allergy, such as confirming or
+// refuting it. Admins and practitioners may update allergies.
+func (s *AllergyService) UpdateByID(ctx context.Context, patientID, id uuid.UUID, data *model.UpdateAllergy, callerID uuid.UUID, callerRole string) (*model.Allergy, error) {
+	clinician, err := clinicalAccess(ctx, s.patients, s.practitioners, patientID, callerID, callerRole)
+	if err != nil {
+		return nil, err
+	}
+	if !clinician {
+		return nil, fmt.Errorf("only clinicians can update allergies: %w", model.ErrForbidden)
+	}
+
+	current, err := s.repo.GetByID(ctx, patientID, id)
+	if err != nil {
+		return nil, allergyError(err)
+	}
+	if data.IsEmpty() {
+		return current, nil
+	}
+
+	if err := s.repo.UpdateByID(ctx, patientID, id, data); err != nil {
+		return nil, allergyError(err)
+	}
+
+	details := map[string]any{"patient_id": patientID}
+	if data.Severity.Set {
+		details["severity"] = data.Severity.Value
+	}
+	if data.Verification.Set {
+		details["verification"] = data.Verification.Value
+	}
+	recordAudit(ctx, s.audit, &callerID, model.AuditAllergyUpdated, "allergy", id, details)
+
+	allergy, err := s.repo.GetByID(ctx, patientID, id)
+	if err != nil {
+		return nil, allergyError(err)
+	}
+	return allergy, nil
+}
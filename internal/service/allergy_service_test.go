@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+// mockAllergyRepo keeps allergies in memory. The xxxFunc fields, when set,
+// replace it.
+type mockAllergyRepo struct {
+	allergies []*model.Allergy
+
+	createFunc        func(ctx context.Context, allergy model.Allergy) error
+	listByPatientFunc func(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error)
+	updateByIDFunc    func(ctx context.Context, patientID, id uuid.UUID, data *model.UpdateAllergy) error
+}
+
+func (m *mockAllergyRepo) Create(ctx context.Context, allergy model.Allergy) error {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, allergy)
+	}
+	m.allergies = append(m.allergies, &allergy)
+	return nil
+}
+
+func (m *mockAllergyRepo) GetByID(ctx context.Context, patientID, id uuid.UUID) (*model.Allergy, error) {
+	for _, a := range m.allergies {
+		if a.ID == id && a.PatientID == patientID {
+			copy := *a
+			return &copy, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockAllergyRepo) ListByPatient(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error) {
+	if m.listByPatientFunc != nil {
+		return m.listByPatientFunc(ctx, patientID)
+	}
+	var list []model.Allergy
+	for _, a := range m.allergies {
+		if a.PatientID == patientID {
+			list = append(list, *a)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockAllergyRepo) UpdateByID(ctx context.Context, patientID, id uuid.UUID, data *model.UpdateAllergy) error {
+	if m.updateByIDFunc != nil {
+		return m.updateByIDFunc(ctx, patientID, id, data)
+	}
+	for _, a := range m.allergies {
+		if a.ID == id && a.PatientID == patientID {
+			if data.Reaction.Set {
+				a.Reaction = nil
+				if !data.Reaction.Null {
+					a.Reaction = &data.Reaction.Value
+				}
+			}
+			if data.Severity.Set {
+				a.Severity = data.Severity.Value
+			}
+			if data.Verification.Set {
+				a.Verification = data.Verification.Value
+			}
+			return nil
+		}
+	}
+	return model.ErrNotFound
+}
+
+func TestAllergyService_Create(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	nurseID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	strangerID, _ := uuid.NewV7()
+
+	patient := model.Patient{ID: patientID, UserID: &patientUserID}
+	penicillin := model.Medication{RxCUI: "7980", Name: "penicillin G", TermType: "IN"}
+
+	tests := []struct {
+		name           string
+		data           model.CreateAllergy
+		createFunc     func(ctx context.Context, allergy model.Allergy) error
+		callerID       uuid.UUID
+		unscoped       bool
+		expectErr      error
+		expectField    string
+		expectAllergen string
+	}{
+		{
+			name:           "success - catalogue allergen",
+			data:           model.CreateAllergy{RxCUI: "7980", Reaction: "hives", Severity: "severe"},
+			callerID:       nurseID,
+			expectAllergen: "penicillin G",
+		},
+		{
+			name:           "success - named allergen",
+			data:           model.CreateAllergy{Allergen: "latex", Severity: "mild"},
+			callerID:       nurseID,
+			expectAllergen: "latex",
+		},
+		{
+			name:        "unknown rxcui",
+			data:        model.CreateAllergy{RxCUI: "1", Severity: "mild"},
+			callerID:    nurseID,
+			expectField: "rxcui",
+		},
+		{
+			name:      "patient cannot record",
+			data:      model.CreateAllergy{Allergen: "latex", Severity: "mild"},
+			callerID:  patientUserID,
+			expectErr: model.ErrForbidden,
+		},
+		{
+			name:      "stranger",
+			data:      model.CreateAllergy{Allergen: "latex", Severity: "mild"},
+			callerID:  strangerID,
+			expectErr: model.ErrNotFound,
+		},
+		{
+			name:      "nurse outside an organisation",
+			data:      model.CreateAllergy{Allergen: "latex", Severity: "mild"},
+			callerID:  nurseID,
+			unscoped:  true,
+			expectErr: model.ErrNotFound,
+		},
+		{
+			name: "repo error",
+			data: model.CreateAllergy{Allergen: "latex", Severity: "mild"},
+			createFunc: func(ctx context.Context, allergy model.Allergy) error {
+				return errRepo
+			},
+			callerID:  nurseID,
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &mockAuditRepo{}
+			service := NewAllergyService(
+				&mockAllergyRepo{createFunc: tt.createFunc},
+				&mockPatientRepo{getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+					return &patient, nil
+				}},
+				&mockPractitionerRepo{practitionerUsers: map[uuid.UUID]bool{nurseID: true}},
+				&mockMedicationRepo{catalogue: map[string]model.Medication{penicillin.RxCUI: penicillin}},
+				audit,
+			)
+			if err := tt.data.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			if tt.unscoped {
+				ctx = context.Background()
+			}
+			allergy, err := service.Create(ctx, patientID, &tt.data, tt.callerID, "user")
+
+			if tt.expectField != "" {
+				var verrs model.ValidationErrors
+				if !errors.As(err, &verrs) || verrs[0].Field != tt.expectField {
+					t.Errorf("expected a validation error on %s, got %v", tt.expectField, err)
+				}
+				return
+			}
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("audited %+v, want nothing", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if allergy.Allergen != tt.expectAllergen || allergy.Verification != model.AllergyUnconfirmed {
+				t.Errorf("allergy = %+v, want an unconfirmed allergy to %s", allergy, tt.expectAllergen)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditAllergyRecorded {
+				t.Errorf("expected the allergy to be audited, got %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestAllergyService_List(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	nurseID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	strangerID, _ := uuid.NewV7()
+	allergyID, _ := uuid.NewV7()
+
+	patient := model.Patient{ID: patientID, UserID: &patientUserID}
+	allergies := []model.Allergy{{ID: allergyID, PatientID: patientID, Allergen: "latex", Severity: "mild", Verification: model.AllergyConfirmed}}
+
+	tests := []struct {
+		name        string
+		mockFunc    func(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error)
+		callerID    uuid.UUID
+		expectErr   error
+		expectItems int
+	}{
+		{
+			name: "success - patient",
+			mockFunc: func(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error) {
+				return allergies, nil
+			},
+			callerID:    patientUserID,
+			expectItems: 1,
+		},
+		{
+			name: "success - nurse",
+			mockFunc: func(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error) {
+				return allergies, nil
+			},
+			callerID:    nurseID,
+			expectItems: 1,
+		},
+		{
+			name: "stranger",
+			mockFunc: func(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error) {
+				return allergies, nil
+			},
+			callerID:  strangerID,
+			expectErr: model.ErrNotFound,
+		},
+		{
+			name: "nil slice treated as empty",
+			mockFunc: func(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error) {
+				return nil, nil
+			},
+			callerID: nurseID,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, patientID uuid.UUID) ([]model.Allergy, error) {
+				return nil, errRepo
+			},
+			callerID:  nurseID,
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewAllergyService(
+				&mockAllergyRepo{listByPatientFunc: tt.mockFunc},
+				&mockPatientRepo{getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+					return &patient, nil
+				}},
+				&mockPractitionerRepo{practitionerUsers: map[uuid.UUID]bool{nurseID: true}},
+				&mockMedicationRepo{},
+				&mockAuditRepo{},
+			)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			list, err := service.List(ctx, patientID, tt.callerID, "user")
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if list == nil || len(list) != tt.expectItems {
+				t.Errorf("listed %+v, want %d allergies", list, tt.expectItems)
+			}
+		})
+	}
+}
+
+func TestAllergyService_UpdateByID(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	nurseID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	allergyID, _ := uuid.NewV7()
+	unknownID, _ := uuid.NewV7()
+
+	patient := model.Patient{ID: patientID, UserID: &patientUserID}
+	refute := model.UpdateAllergy{Verification: model.Optional[string]{Value: model.AllergyRefuted, Set: true}}
+
+	tests := []struct {
+		name               string
+		mockFunc           func(ctx context.Context, patientID, id uuid.UUID, data *model.UpdateAllergy) error
+		allergyID          uuid.UUID
+		callerID           uuid.UUID
+		callerRole         string
+		expectErr          error
+		expectVerification string
+	}{
+		{
+			name:               "success - admin refutes",
+			allergyID:          allergyID,
+			callerID:           adminID,
+			callerRole:         "admin",
+			expectVerification: model.AllergyRefuted,
+		},
+		{
+			name:               "success - nurse refutes",
+			allergyID:          allergyID,
+			callerID:           nurseID,
+			callerRole:         "user",
+			expectVerification: model.AllergyRefuted,
+		},
+		{
+			name:               "patient cannot update",
+			allergyID:          allergyID,
+			callerID:           patientUserID,
+			callerRole:         "user",
+			expectErr:          model.ErrForbidden,
+			expectVerification: model.AllergyUnconfirmed,
+		},
+		{
+			name:               "unknown allergy",
+			allergyID:          unknownID,
+			callerID:           nurseID,
+			callerRole:         "user",
+			expectErr:          model.ErrNotFound,
+			expectVerification: model.AllergyUnconfirmed,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, patientID, id uuid.UUID, data *model.UpdateAllergy) error {
+				return errRepo
+			},
+			allergyID:          allergyID,
+			callerID:           nurseID,
+			callerRole:         "user",
+			expectErr:          errRepo,
+			expectVerification: model.AllergyUnconfirmed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := &model.Allergy{ID: allergyID, PatientID: patientID, Allergen: "penicillin G", Severity: "severe", Verification: model.AllergyUnconfirmed}
+			service := NewAllergyService(
+				&mockAllergyRepo{allergies: []*model.Allergy{stored}, updateByIDFunc: tt.mockFunc},
+				&mockPatientRepo{getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+					return &patient, nil
+				}},
+				&mockPractitionerRepo{practitionerUsers: map[uuid.UUID]bool{nurseID: true}},
+				&mockMedicationRepo{},
+				&mockAuditRepo{},
+			)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			allergy, err := service.UpdateByID(ctx, patientID, tt.allergyID, &refute, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if allergy.Verification != tt.expectVerification || allergy.Active() {
+					t.Errorf("allergy = %+v, want it refuted", allergy)
+				}
+			}
+			if stored.Verification != tt.expectVerification {
+				t.Errorf("stored allergy is %s, want %s", stored.Verification, tt.expectVerification)
+			}
+		})
+	}
+}
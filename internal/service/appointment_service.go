@@ -0,0 +1,541 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/ical"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+// SlotWaitlist is told about slots that appointments give up, and holds the
+// slots it has offered to patients.
+type SlotWaitlist interface {
+	SlotFreed(ctx context.Context, slot model.FreedSlot)
+	Holds(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.TimeRange, error)
+}
+
+type AppointmentService struct {
+	repo          repository.AppointmentRepository
+	patients      repository.PatientRepository
+	practitioners repository.PractitionerRepository
+	audit         repository.AuditRepository
+	mailer        mailer.Mailer
+	waitlist      SlotWaitlist
+}
+
+// NewAppointmentService returns an AppointmentService. waitlist may be nil,
+// in which case freed slots are not offered to anyone.
+func NewAppointmentService(repo repository.AppointmentRepository, patients repository.PatientRepository, practitioners repository.PractitionerRepository, audit repository.AuditRepository, mailer mailer.Mailer, waitlist SlotWaitlist) *AppointmentService {
+	return &AppointmentService{
+		repo:          repo,
+		patients:      patients,
+		practitioners: practitioners,
+		audit:         audit,
+		mailer:        mailer,
+		waitlist:      waitlist,
+	}
+}
+
+// holds returns the times of a practitioner's slots held for the waitlist.
+func (s *AppointmentService) holds(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.TimeRange, error) {
+	if s.waitlist == nil {
+		return nil, nil
+	}
+	return s.waitlist.Holds(ctx, practitionerID, from, to)
+}
+
+// slotFreed passes a slot an appointment has given up to the waitlist.
+func (s *AppointmentService) slotFreed(ctx context.Context, a *model.Appointment) {
+	if s.waitlist == nil {
+		return
+	}
+	s.waitlist.SlotFreed(ctx, model.FreedSlot{
+		PractitionerID: a.PractitionerID,
+		StartsAt:       a.StartsAt,
+		EndsAt:         a.EndsAt,
+		Service:        a.Service,
+	})
+}
+
+// appointmentError adds context to the repository errors of a single
+// appointment.
+func appointmentError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("appointment %w", err)
+	}
+	return err
+}
+
+// canManageSchedule returns nil if the caller may change the schedule of a
+// practitioner: admins may change any, and practitioners their own.
+func (s *AppointmentService) canManageSchedule(ctx context.Context, practitionerID, callerID uuid.UUID, callerRole string) error {
+	if isAdmin(callerRole) {
+		return nil
+	}
+	if !inOrganisation(ctx, callerRole) {
+		return model.ErrForbidden
+	}
+
+	practitioner, err := s.practitioners.GetByID(ctx, practitionerID)
+	if err != nil {
+		return practitionerError(err)
+	}
+	if practitioner.UserID != callerID {
+		return model.ErrForbidden
+	}
+	return nil
+}
+
+func (s *AppointmentService) GetSchedule(ctx context.Context, practitionerID uuid.UUID, callerRole string) (*model.Schedule, error) {
+	if !inOrganisation(ctx, callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	schedule, err := s.repo.GetSchedule(ctx, practitionerID)
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+	return schedule, nil
+}
+
+// SetSchedule replaces the weekly schedule of a practitioner.
+func (s *AppointmentService) SetSchedule(ctx context.Context, practitionerID uuid.UUID, data *model.SetSchedule, callerID uuid.UUID, callerRole string) (*model.Schedule, error) {
+	if err := s.canManageSchedule(ctx, practitionerID, callerID, callerRole); err != nil {
+		return nil, err
+	}
+
+	windows := data.Windows
+	if windows == nil {
+		windows = []model.ScheduleWindow{}
+	}
+
+	err := s.repo.SetSchedule(ctx, model.Schedule{
+		PractitionerID: practitionerID,
+		Timezone:       data.Timezone,
+		SlotMinutes:    data.SlotMinutes,
+		Windows:        windows,
+	})
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditScheduleSet, "practitioner", practitionerID, map[string]any{
+		"timezone":     data.Timezone,
+		"slot_minutes": data.SlotMinutes,
+		"windows":      len(windows),
+	})
+
+	schedule, err := s.repo.GetSchedule(ctx, practitionerID)
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+	return schedule, nil
+}
+
+// ListExceptions returns the exceptions of a practitioner that have not
+// ended yet.
+func (s *AppointmentService) ListExceptions(ctx context.Context, practitionerID uuid.UUID, callerRole string) ([]model.AvailabilityException, error) {
+	if !inOrganisation(ctx, callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	// The schedule lookup checks that the practitioner exists.
+	if _, err := s.repo.GetSchedule(ctx, practitionerID); err != nil {
+		return nil, practitionerError(err)
+	}
+
+	now := time.Now()
+	exceptions, err := s.repo.ListExceptions(ctx, practitionerID, now, now.AddDate(100, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	if exceptions == nil {
+		exceptions = []model.AvailabilityException{}
+	}
+	return exceptions, nil
+}
+
+// AddException takes a practitioner out of their schedule. Appointments
+// already booked in that time are kept, so the front desk can reschedule
+// them.
+func (s *AppointmentService) AddException(ctx context.Context, practitionerID uuid.UUID, data *model.CreateAvailabilityException, callerID uuid.UUID, callerRole string) (*model.AvailabilityException, error) {
+	if err := s.canManageSchedule(ctx, practitionerID, callerID, callerRole); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	exception, err := s.repo.AddException(ctx, model.AvailabilityException{
+		ID:             id,
+		PractitionerID: practitionerID,
+		StartsAt:       data.StartsAt,
+		EndsAt:         data.EndsAt,
+		Reason:         data.Reason,
+		CreatedBy:      &callerID,
+	})
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditAvailabilityExceptionAdded, "practitioner", practitionerID, map[string]any{
+		"exception_id": exception.ID,
+		"starts_at":    exception.StartsAt,
+		"ends_at":      exception.EndsAt,
+	})
+
+	return exception, nil
+}
+
+func (s *AppointmentService) RemoveException(ctx context.Context, practitionerID, exceptionID uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	if err := s.canManageSchedule(ctx, practitionerID, callerID, callerRole); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveException(ctx, practitionerID, exceptionID); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("exception %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditAvailabilityExceptionRemoved, "practitioner", practitionerID, map[string]any{
+		"exception_id": exceptionID,
+	})
+
+	return nil
+}
+
+// exceptionRanges returns the times exceptions take out of a schedule.
+func exceptionRanges(exceptions []model.AvailabilityException) []model.TimeRange {
+	ranges := make([]model.TimeRange, len(exceptions))
+	for i, e := range exceptions {
+		ranges[i] = model.TimeRange{Start: e.StartsAt, End: e.EndsAt}
+	}
+	return ranges
+}
+
+// Slots returns the open slots of a practitioner between two dates of their
+// time zone: the slots of their schedule that have not passed, fall outside
+// their exceptions and are neither booked nor held for the waitlist.
+func (s *AppointmentService) Slots(ctx context.Context, practitionerID uuid.UUID, rng model.SlotRange, callerRole string) ([]model.Slot, error) {
+	if !inOrganisation(ctx, callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	schedule, err := s.repo.GetSchedule(ctx, practitionerID)
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+	if schedule.Timezone == "" {
+		return []model.Slot{}, nil
+	}
+
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	fromDate, _ := time.Parse(model.DateLayout, rng.From)
+	toDate, _ := time.Parse(model.DateLayout, rng.To)
+	from := time.Date(fromDate.Year(), fromDate.Month(), fromDate.Day(), 0, 0, 0, 0, loc)
+	to := time.Date(toDate.Year(), toDate.Month(), toDate.Day()+1, 0, 0, 0, 0, loc)
+
+	exceptions, err := s.repo.ListExceptions(ctx, practitionerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	booked, err := s.repo.ListBusy(ctx, practitionerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	held, err := s.holds(ctx, practitionerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := append(append(exceptionRanges(exceptions), booked...), held...)
+	return schedule.Slots(from, to, time.Now(), busy)
+}
+
+// openSlot returns the slot of a practitioner's schedule that starts at
+// start, unless it has passed or falls in an exception. A slot held for the
+// waitlist is ErrConflict. Whether it is booked is left to the database,
+// which refuses overlapping appointments.
+func (s *AppointmentService) openSlot(ctx context.Context, practitionerID uuid.UUID, start time.Time) (*model.Slot, error) {
+	schedule, err := s.repo.GetSchedule(ctx, practitionerID)
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+
+	if schedule.Timezone != "" {
+		end := start.Add(time.Duration(schedule.SlotMinutes) * time.Minute)
+		exceptions, err := s.repo.ListExceptions(ctx, practitionerID, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		slots, err := schedule.Slots(start, start.Add(time.Nanosecond), time.Now(), exceptionRanges(exceptions))
+		if err != nil {
+			return nil, err
+		}
+		for _, slot := range slots {
+			if !slot.StartsAt.Equal(start) {
+				continue
+			}
+			held, err := s.holds(ctx, practitionerID, slot.StartsAt, slot.EndsAt)
+			if err != nil {
+				return nil, err
+			}
+			if len(held) > 0 {
+				return nil, fmt.Errorf("slot is held for a patient on the waitlist: %w", model.ErrConflict)
+			}
+			return &slot, nil
+		}
+	}
+
+	return nil, model.ValidationErrors{model.FieldError{Field: "starts_at", Message: "starts_at is not an open slot of the practitioner"}}
+}
+
+// Book books a patient into an open slot. Admins may book any patient of
+// their organisation; other users may book the patient linked to their
+// login.
+func (s *AppointmentService) Book(ctx context.Context, data *model.CreateAppointment, callerID uuid.UUID, callerRole string) (*model.Appointment, error) {
+	if !isAdmin(callerRole) {
+		if !inOrganisation(ctx, callerRole) {
+			return nil, model.ErrForbidden
+		}
+		patient, err := s.patients.GetByID(ctx, data.PatientID)
+		if err != nil {
+			return nil, patientError(err)
+		}
+		if patient.UserID == nil || *patient.UserID != callerID {
+			return nil, fmt.Errorf("patient %w", model.ErrNotFound)
+		}
+	}
+
+	slot, err := s.openSlot(ctx, data.PractitionerID, data.StartsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	err = s.repo.Create(ctx, model.Appointment{
+		ID:             id,
+		PractitionerID: data.PractitionerID,
+		PatientID:      data.PatientID,
+		StartsAt:       slot.StartsAt,
+		EndsAt:         slot.EndsAt,
+		Service:        optionalString(data.Service),
+		Reason:         optionalString(data.Reason),
+		CreatedBy:      &callerID,
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return nil, fmt.Errorf("slot is already booked: %w", err)
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("practitioner or patient %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditAppointmentBooked, "appointment", id, map[string]any{
+		"practitioner_id": data.PractitionerID,
+		"patient_id":      data.PatientID,
+		"starts_at":       slot.StartsAt,
+	})
+
+	appointment, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, appointmentError(err)
+	}
+
+	s.sendConfirmation(ctx, appointment)
+	return appointment, nil
+}
+
+// sendConfirmation emails the patient, if they have an email address, a
+// confirmation of the booking with the appointment attached as iCalendar.
+// The booking stands either way, so failures are logged rather than
+// returned.
+func (s *AppointmentService) sendConfirmation(ctx context.Context, a *model.Appointment) {
+	patient, err := s.patients.GetByID(ctx, a.PatientID)
+	if err != nil {
+		log.Printf("appointment %s: confirmation: %v", a.ID, err)
+		return
+	}
+	if patient.Email == nil || *patient.Email == "" {
+		return
+	}
+
+	event := appointmentEvent(*a, false, time.Now())
+	cal := ical.Calendar{ProdID: calendarProdID, Method: "PUBLISH", Events: []ical.Event{event}}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      *patient.Email,
+		Subject: "Your appointment is booked",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nYour appointment with %s is booked for %s.\n\nThe attached file adds it to your calendar.",
+			patient.FirstName, a.PractitionerName, event.Start.Format("Monday 2 January 2006 at 15:04 MST"),
+		),
+		Attachments: []mailer.Attachment{{
+			Filename:    "appointment.ics",
+			ContentType: ical.ContentType + "; method=PUBLISH",
+			Data:        cal.Bytes(),
+		}},
+	})
+	if err != nil {
+		log.Printf("appointment %s: confirmation: %v", a.ID, err)
+	}
+}
+
+// List returns a page of appointments. Admins see every appointment of
+// their organisation; other users see those they are the practitioner or
+// the patient of.
+func (s *AppointmentService) List(ctx context.Context, filter model.AppointmentFilter, params model.PaginationParams, callerID uuid.UUID, callerRole string) (*model.PaginatedAppointmentsResponse, error) {
+	if !isAdmin(callerRole) {
+		filter.ParticipantID = &callerID
+	}
+
+	appointments, err := s.repo.List(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointments == nil {
+		appointments = []model.Appointment{}
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedAppointmentsResponse{
+		Items: appointments,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// isParticipant reports whether the caller is the practitioner or the
+// patient of an appointment.
+func isParticipant(a *model.Appointment, callerID uuid.UUID) bool {
+	return a.PractitionerUserID == callerID || (a.PatientUserID != nil && *a.PatientUserID == callerID)
+}
+
+// GetByID returns an appointment to admins and to its practitioner and
+// patient. Other callers are told it does not exist.
+func (s *AppointmentService) GetByID(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.Appointment, error) {
+	appointment, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, appointmentError(err)
+	}
+
+	if !isAdmin(callerRole) && !isParticipant(appointment, callerID) {
+		return nil, fmt.Errorf("appointment %w", model.ErrNotFound)
+	}
+
+	return appointment, nil
+}
+
+// Reschedule moves a booked appointment to another open slot of the same
+// practitioner.
+func (s *AppointmentService) Reschedule(ctx context.Context, id uuid.UUID, data *model.RescheduleAppointment, callerID uuid.UUID, callerRole string) (*model.Appointment, error) {
+	current, err := s.GetByID(ctx, id, callerID, callerRole)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status != model.AppointmentBooked {
+		return nil, fmt.Errorf("only booked appointments can be rescheduled: %w", model.ErrConflict)
+	}
+
+	slot, err := s.openSlot(ctx, current.PractitionerID, data.StartsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Reschedule(ctx, id, *slot, data.Reason); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return nil, fmt.Errorf("slot is already booked or appointment has changed: %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditAppointmentRescheduled, "appointment", id, map[string]any{
+		"from":   current.StartsAt,
+		"to":     slot.StartsAt,
+		"reason": data.Reason,
+	})
+
+	s.slotFreed(ctx, current)
+
+	appointment, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, appointmentError(err)
+	}
+	return appointment, nil
+}
+
+// SetStatus moves an appointment on. Admins and the practitioner may make
+// any allowed change; the patient may only cancel.
+func (s *AppointmentService) SetStatus(ctx context.Context, id uuid.UUID, data *model.ChangeAppointmentStatus, callerID uuid.UUID, callerRole string) (*model.Appointment, error) {
+	current, err := s.GetByID(ctx, id, callerID, callerRole)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Status != model.AppointmentCancelled && !isAdmin(callerRole) && current.PractitionerUserID != callerID {
+		return nil, model.ErrForbidden
+	}
+
+	if !model.CanTransition(current.Status, data.Status) {
+		return nil, fmt.Errorf("cannot move appointment from %s to %s: %w", current.Status, data.Status, model.ErrConflict)
+	}
+
+	if err := s.repo.SetStatus(ctx, id, current.Status, data.Status, optionalString(data.Reason)); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return nil, fmt.Errorf("appointment has changed: %w", err)
+		}
+		return nil, err
+	}
+
+	details := map[string]any{
+		"from": current.Status,
+		"to":   data.Status,
+	}
+	if data.Reason != "" {
+		details["reason"] = data.Reason
+	}
+	recordAudit(ctx, s.audit, &callerID, model.AuditAppointmentStatusChanged, "appointment", id, details)
+
+	if data.Status == model.AppointmentCancelled {
+		s.slotFreed(ctx, current)
+	}
+
+	appointment, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, appointmentError(err)
+	}
+	return appointment, nil
+}
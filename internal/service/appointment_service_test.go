@@ -0,0 +1,446 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+type mockAppointmentRepo struct {
+	schedule       *model.Schedule
+	exceptions     []model.AvailabilityException
+	busy           []model.TimeRange
+	appointment    *model.Appointment
+	createErr      error
+	setStatusErr   error
+	created        *model.Appointment
+	rescheduled    *model.Slot
+	statusChange   []string
+	setScheduleArg *model.Schedule
+	listFilter     model.AppointmentFilter
+	listed         []model.Appointment
+}
+
+func (m *mockAppointmentRepo) GetSchedule(ctx context.Context, practitionerID uuid.UUID) (*model.Schedule, error) {
+	if m.schedule == nil {
+		return nil, model.ErrNotFound
+	}
+	return m.schedule, nil
+}
+
+func (m *mockAppointmentRepo) SetSchedule(ctx context.Context, schedule model.Schedule) error {
+	m.setScheduleArg = &schedule
+	return nil
+}
+
+func (m *mockAppointmentRepo) ListExceptions(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.AvailabilityException, error) {
+	return m.exceptions, nil
+}
+
+func (m *mockAppointmentRepo) AddException(ctx context.Context, exception model.AvailabilityException) (*model.AvailabilityException, error) {
+	return &exception, nil
+}
+
+func (m *mockAppointmentRepo) RemoveException(ctx context.Context, practitionerID, exceptionID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockAppointmentRepo) ListBusy(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.TimeRange, error) {
+	return m.busy, nil
+}
+
+func (m *mockAppointmentRepo) Create(ctx context.Context, appointment model.Appointment) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	appointment.Status = model.AppointmentBooked
+	m.created = &appointment
+	m.appointment = &appointment
+	return nil
+}
+
+func (m *mockAppointmentRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Appointment, error) {
+	if m.appointment == nil || m.appointment.ID != id {
+		return nil, model.ErrNotFound
+	}
+	a := *m.appointment
+	return &a, nil
+}
+
+func (m *mockAppointmentRepo) List(ctx context.Context, filter model.AppointmentFilter, limit, offset int) ([]model.Appointment, error) {
+	m.listFilter = filter
+	return m.listed, nil
+}
+
+func (m *mockAppointmentRepo) Count(ctx context.Context, filter model.AppointmentFilter) (int, error) {
+	return 0, nil
+}
+
+func (m *mockAppointmentRepo) Reschedule(ctx context.Context, id uuid.UUID, slot model.Slot, reason string) error {
+	m.rescheduled = &slot
+	m.appointment.StartsAt = slot.StartsAt
+	m.appointment.EndsAt = slot.EndsAt
+	return nil
+}
+
+func (m *mockAppointmentRepo) SetStatus(ctx context.Context, id uuid.UUID, from, to string, reason *string) error {
+	if m.setStatusErr != nil {
+		return m.setStatusErr
+	}
+	m.statusChange = []string{from, to}
+	m.appointment.Status = to
+	return nil
+}
+
+// openSchedule is open every day around the clock in 30 minute slots.
+func openSchedule(practitionerID uuid.UUID) *model.Schedule {
+	s := &model.Schedule{PractitionerID: practitionerID, Timezone: "UTC", SlotMinutes: 30}
+	for d := 0; d < 7; d++ {
+		s.Windows = append(s.Windows, model.ScheduleWindow{Weekday: d, Start: "00:00", End: "23:30"})
+	}
+	return s
+}
+
+// nextSlot returns the start of a slot of openSchedule two days ahead.
+func nextSlot() time.Time {
+	start := time.Now().UTC().Truncate(time.Hour).Add(48 * time.Hour)
+	if start.Hour() == 23 {
+		start = start.Add(-time.Hour)
+	}
+	return start
+}
+
+func TestAppointmentService_Book(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	otherUserID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	start := nextSlot()
+
+	tests := []struct {
+		name       string
+		callerID   uuid.UUID
+		callerRole string
+		startsAt   time.Time
+		exceptions []model.AvailabilityException
+		createErr  error
+		expectErr  error
+	}{
+		{name: "admin books any patient", callerID: adminID, callerRole: "admin", startsAt: start},
+		{name: "patient books for themselves", callerID: patientUserID, callerRole: "user", startsAt: start},
+		{name: "user books another patient", callerID: otherUserID, callerRole: "user", startsAt: start, expectErr: model.ErrNotFound},
+		{name: "start between slots", callerID: adminID, callerRole: "admin", startsAt: start.Add(10 * time.Minute), expectErr: model.ErrValidationFailed},
+		{name: "start in the past", callerID: adminID, callerRole: "admin", startsAt: start.Add(-72 * time.Hour), expectErr: model.ErrValidationFailed},
+		{
+			name:       "practitioner on leave",
+			callerID:   adminID,
+			callerRole: "admin",
+			startsAt:   start,
+			exceptions: []model.AvailabilityException{{StartsAt: start.Add(-time.Hour), EndsAt: start.Add(time.Hour), Reason: "leave"}},
+			expectErr:  model.ErrValidationFailed,
+		},
+		{name: "slot already booked", callerID: adminID, callerRole: "admin", startsAt: start, createErr: model.ErrConflict, expectErr: model.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockAppointmentRepo{schedule: openSchedule(practitionerID), exceptions: tt.exceptions, createErr: tt.createErr}
+			patients := &mockPatientRepo{
+				getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+					return &model.Patient{ID: id, UserID: &patientUserID}, nil
+				},
+			}
+			audit := &mockAuditRepo{}
+			service := NewAppointmentService(repo, patients, &mockPractitionerRepo{}, audit, &mockMailer{}, nil)
+			ctx := repository.WithTenant(context.Background(), orgID)
+
+			data := &model.CreateAppointment{PractitionerID: practitionerID, PatientID: patientID, StartsAt: tt.startsAt}
+			appointment, err := service.Book(ctx, data, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				var verrs model.ValidationErrors
+				if tt.expectErr == model.ErrValidationFailed && errors.As(err, &verrs) {
+					return
+				}
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !appointment.StartsAt.Equal(start) || appointment.EndsAt.Sub(appointment.StartsAt) != 30*time.Minute {
+				t.Errorf("appointment = %v-%v, want a 30 minute slot at %v", appointment.StartsAt, appointment.EndsAt, start)
+			}
+			if repo.created.CreatedBy == nil || *repo.created.CreatedBy != tt.callerID {
+				t.Errorf("created by = %v, want %v", repo.created.CreatedBy, tt.callerID)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditAppointmentBooked {
+				t.Errorf("audit entries = %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestAppointmentService_Book_Confirmation(t *testing.T) {
+	practitionerID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	email := "jane@example.com"
+	start := nextSlot()
+
+	repo := &mockAppointmentRepo{schedule: openSchedule(practitionerID)}
+	patients := &mockPatientRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+			return &model.Patient{ID: id, FirstName: "Jane", Email: &email}, nil
+		},
+	}
+	mail := &mockMailer{}
+	service := NewAppointmentService(repo, patients, &mockPractitionerRepo{}, &mockAuditRepo{}, mail, nil)
+
+	patientID, _ := uuid.NewV7()
+	data := &model.CreateAppointment{PractitionerID: practitionerID, PatientID: patientID, StartsAt: start}
+	if _, err := service.Book(context.Background(), data, adminID, "super_admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(mail.sent) != 1 || mail.sent[0].To != email {
+		t.Fatalf("sent = %+v, want one confirmation to %s", mail.sent, email)
+	}
+	attachments := mail.sent[0].Attachments
+	if len(attachments) != 1 || attachments[0].Filename != "appointment.ics" {
+		t.Fatalf("attachments = %+v, want appointment.ics", attachments)
+	}
+	ics := string(attachments[0].Data)
+	for _, want := range []string{"METHOD:PUBLISH\r\n", "DTSTART:" + start.Format("20060102T150405Z") + "\r\n", "STATUS:CONFIRMED\r\n"} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("attachment missing %q:\n%s", want, ics)
+		}
+	}
+}
+
+func TestAppointmentService_Slots(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	start := nextSlot()
+	day := start.Format(model.DateLayout)
+
+	repo := &mockAppointmentRepo{
+		schedule: openSchedule(practitionerID),
+		busy:     []model.TimeRange{{Start: start, End: start.Add(30 * time.Minute)}},
+	}
+	service := NewAppointmentService(repo, &mockPatientRepo{}, &mockPractitionerRepo{}, &mockAuditRepo{}, &mockMailer{}, nil)
+	ctx := repository.WithTenant(context.Background(), orgID)
+
+	slots, err := service.Slots(ctx, practitionerID, model.SlotRange{From: day, To: day}, "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slots) != 46 {
+		t.Errorf("got %d slots, want 46", len(slots))
+	}
+	for _, slot := range slots {
+		if slot.StartsAt.Equal(start) {
+			t.Errorf("booked slot %v listed as open", start)
+		}
+	}
+
+	repo.schedule = &model.Schedule{PractitionerID: practitionerID, Windows: []model.ScheduleWindow{}}
+	slots, err = service.Slots(ctx, practitionerID, model.SlotRange{From: day, To: day}, "user")
+	if err != nil || len(slots) != 0 {
+		t.Fatalf("expected no slots without a schedule, got %v, %v", slots, err)
+	}
+
+	if _, err := service.Slots(context.Background(), practitionerID, model.SlotRange{From: day, To: day}, "user"); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden without an organisation, got %v", err)
+	}
+}
+
+func TestAppointmentService_SetSchedule(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	practitionerUserID, _ := uuid.NewV7()
+	otherUserID, _ := uuid.NewV7()
+
+	repo := &mockAppointmentRepo{schedule: openSchedule(practitionerID)}
+	practitioners := &mockPractitionerRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Practitioner, error) {
+			return &model.Practitioner{ID: id, UserID: practitionerUserID}, nil
+		},
+	}
+	audit := &mockAuditRepo{}
+	service := NewAppointmentService(repo, &mockPatientRepo{}, practitioners, audit, &mockMailer{}, nil)
+	ctx := repository.WithTenant(context.Background(), orgID)
+	data := &model.SetSchedule{Timezone: "UTC", SlotMinutes: 20}
+
+	if _, err := service.SetSchedule(ctx, practitionerID, data, practitionerUserID, "user"); err != nil {
+		t.Fatalf("practitioner: unexpected error: %v", err)
+	}
+	if repo.setScheduleArg == nil || repo.setScheduleArg.SlotMinutes != 20 || repo.setScheduleArg.Windows == nil {
+		t.Errorf("stored schedule = %+v", repo.setScheduleArg)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditScheduleSet {
+		t.Errorf("audit entries = %+v", audit.entries)
+	}
+
+	if _, err := service.SetSchedule(ctx, practitionerID, data, otherUserID, "user"); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden for another user, got %v", err)
+	}
+	if _, err := service.SetSchedule(ctx, practitionerID, data, otherUserID, "admin"); err != nil {
+		t.Fatalf("admin: unexpected error: %v", err)
+	}
+
+	exception := &model.CreateAvailabilityException{StartsAt: time.Now(), EndsAt: time.Now().Add(24 * time.Hour), Reason: "leave"}
+	if _, err := service.AddException(ctx, practitionerID, exception, otherUserID, "user"); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden exception for another user, got %v", err)
+	}
+	if _, err := service.AddException(ctx, practitionerID, exception, practitionerUserID, "user"); err != nil {
+		t.Fatalf("practitioner exception: unexpected error: %v", err)
+	}
+}
+
+func TestAppointmentService_List(t *testing.T) {
+	callerID, _ := uuid.NewV7()
+	repo := &mockAppointmentRepo{}
+	service := NewAppointmentService(repo, &mockPatientRepo{}, &mockPractitionerRepo{}, &mockAuditRepo{}, &mockMailer{}, nil)
+	params := model.PaginationParams{Page: 1, Limit: 10}
+
+	resp, err := service.List(context.Background(), model.AppointmentFilter{}, params, callerID, "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Items == nil {
+		t.Error("expected an empty list, got nil")
+	}
+	if repo.listFilter.ParticipantID == nil || *repo.listFilter.ParticipantID != callerID {
+		t.Errorf("participant = %v, want %v", repo.listFilter.ParticipantID, callerID)
+	}
+
+	if _, err := service.List(context.Background(), model.AppointmentFilter{}, params, callerID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.listFilter.ParticipantID != nil {
+		t.Errorf("admin list limited to participant %v", repo.listFilter.ParticipantID)
+	}
+}
+
+func TestAppointmentService_SetStatus(t *testing.T) {
+	appointmentID, _ := uuid.NewV7()
+	practitionerUserID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	otherUserID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		current    string
+		status     string
+		callerID   uuid.UUID
+		callerRole string
+		repoErr    error
+		expectErr  error
+	}{
+		{name: "practitioner checks in", current: model.AppointmentBooked, status: model.AppointmentCheckedIn, callerID: practitionerUserID, callerRole: "user"},
+		{name: "admin completes", current: model.AppointmentCheckedIn, status: model.AppointmentCompleted, callerID: otherUserID, callerRole: "admin"},
+		{name: "patient cancels", current: model.AppointmentBooked, status: model.AppointmentCancelled, callerID: patientUserID, callerRole: "user"},
+		{name: "patient cannot check in", current: model.AppointmentBooked, status: model.AppointmentCheckedIn, callerID: patientUserID, callerRole: "user", expectErr: model.ErrForbidden},
+		{name: "stranger cannot see it", current: model.AppointmentBooked, status: model.AppointmentCancelled, callerID: otherUserID, callerRole: "user", expectErr: model.ErrNotFound},
+		{name: "completed cannot be cancelled", current: model.AppointmentCompleted, status: model.AppointmentCancelled, callerID: otherUserID, callerRole: "admin", expectErr: model.ErrConflict},
+		{name: "booked cannot be completed", current: model.AppointmentBooked, status: model.AppointmentCompleted, callerID: practitionerUserID, callerRole: "user", expectErr: model.ErrConflict},
+		{name: "changed meanwhile", current: model.AppointmentBooked, status: model.AppointmentNoShow, callerID: otherUserID, callerRole: "admin", repoErr: model.ErrConflict, expectErr: model.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockAppointmentRepo{
+				appointment: &model.Appointment{
+					ID:                 appointmentID,
+					Status:             tt.current,
+					PractitionerUserID: practitionerUserID,
+					PatientUserID:      &patientUserID,
+				},
+				setStatusErr: tt.repoErr,
+			}
+			audit := &mockAuditRepo{}
+			service := NewAppointmentService(repo, &mockPatientRepo{}, &mockPractitionerRepo{}, audit, &mockMailer{}, nil)
+
+			data := &model.ChangeAppointmentStatus{Status: tt.status, Reason: "patient unwell"}
+			appointment, err := service.SetStatus(context.Background(), appointmentID, data, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("audit entries = %+v, want none", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if appointment.Status != tt.status {
+				t.Errorf("status = %s, want %s", appointment.Status, tt.status)
+			}
+			if repo.statusChange[0] != tt.current {
+				t.Errorf("changed from %s, want %s", repo.statusChange[0], tt.current)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditAppointmentStatusChanged {
+				t.Errorf("audit entries = %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestAppointmentService_Reschedule(t *testing.T) {
+	appointmentID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	start := nextSlot()
+
+	repo := &mockAppointmentRepo{
+		schedule: openSchedule(practitionerID),
+		appointment: &model.Appointment{
+			ID:             appointmentID,
+			PractitionerID: practitionerID,
+			Status:         model.AppointmentBooked,
+			StartsAt:       start,
+			EndsAt:         start.Add(30 * time.Minute),
+			PatientUserID:  &patientUserID,
+		},
+	}
+	audit := &mockAuditRepo{}
+	service := NewAppointmentService(repo, &mockPatientRepo{}, &mockPractitionerRepo{}, audit, &mockMailer{}, nil)
+	ctx := context.Background()
+
+	later := start.Add(time.Hour)
+	appointment, err := service.Reschedule(ctx, appointmentID, &model.RescheduleAppointment{StartsAt: later, Reason: "clash"}, patientUserID, "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !appointment.StartsAt.Equal(later) || !repo.rescheduled.EndsAt.Equal(later.Add(30*time.Minute)) {
+		t.Errorf("rescheduled to %v-%v, want %v", appointment.StartsAt, repo.rescheduled.EndsAt, later)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditAppointmentRescheduled {
+		t.Errorf("audit entries = %+v", audit.entries)
+	}
+
+	var verrs model.ValidationErrors
+	if _, err := service.Reschedule(ctx, appointmentID, &model.RescheduleAppointment{StartsAt: later.Add(5 * time.Minute), Reason: "clash"}, patientUserID, "user"); !errors.As(err, &verrs) {
+		t.Fatalf("expected validation errors off the slot grid, got %v", err)
+	}
+
+	repo.appointment.Status = model.AppointmentCheckedIn
+	if _, err := service.Reschedule(ctx, appointmentID, &model.RescheduleAppointment{StartsAt: later, Reason: "clash"}, patientUserID, "user"); !errors.Is(err, model.ErrConflict) {
+		t.Fatalf("expected conflict for a checked in appointment, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+// recordAudit writes an audit entry about a subject. The audited action has
+// already happened by the time it is recorded, so a failure is logged rather
+// than returned. actorID is nil for actions taken by the system.
+func recordAudit(ctx context.Context, audit repository.AuditRepository, actorID *uuid.UUID, action, subjectType string, subjectID uuid.UUID, details map[string]any) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		log.Printf("audit %s %s: failed to generate uuid: %v", action, subjectID, err)
+		return
+	}
+
+	err = audit.Record(ctx, model.AuditEntry{
+		ID:          id,
+		ActorID:     actorID,
+		Action:      action,
+		SubjectType: subjectType,
+		SubjectID:   &subjectID,
+		Details:     details,
+	})
+	if err != nil {
+		log.Printf("audit %s %s: %v", action, subjectID, err)
+	}
+}
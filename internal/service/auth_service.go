@@ -66,9 +66,10 @@ func (s *AuthService) Register(ctx context.Context, user *model.CreateUser) erro
 // Steps:
 //   1. Retrieves the user by email from the repository.
 //   2. Verifies the provided password against the stored hash.
-//   3. Generates an access token and a refresh token if authentication is successful.
-//   4. Stores the refresh token in the database for session management.
-//   5. Returns the generated access and refresh tokens to the caller.
+//   3. Resolves the organisation to sign in to and the role held there.
+//   4. Generates an access token and a refresh token if authentication is successful.
+//   5. Stores the refresh token in the database for session management.
+//   6. Returns the generated access and refresh tokens to the caller.
 
 func (s *AuthService) Login(ctx context.Context, user *model.LoginUser) (*model.LoginResponse, error) {
 	data, err := s.repo.Login(ctx, user.Email)
@@ -83,14 +84,32 @@ func (s *AuthService) Login(ctx context.Context, user *model.LoginUser) (*model.
 		return nil, model.ErrUnauthorized
 	}
 
+	// Checked only after the password so the status of an account is not
+	// revealed to someone who does not know it.
+	if err := statusError(data.Status); err != nil {
+		return nil, err
+	}
+
+	orgID := user.OrganisationID
+	if orgID == nil && data.Role != "super_admin" {
+		if orgID, err = s.repo.DefaultOrganisation(ctx, data.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	role, err := s.CheckAccount(ctx, data.ID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
 	var access_token string
 	var refresh_token string
 
-	if access_token, err = auth.GenerateAccessToken(s.accessTokenKey, data.ID, data.Role); err != nil {
+	if access_token, err = auth.GenerateAccessToken(s.accessTokenKey, data.ID, role, orgID); err != nil {
 		return nil, fmt.Errorf("internal server error")
 	}
 
-	if refresh_token, err = auth.GenerateRefreshToken(s.refreshTokenKey, data.ID, data.Role); err != nil {
+	if refresh_token, err = auth.GenerateRefreshToken(s.refreshTokenKey, data.ID, role, orgID); err != nil {
 		return nil, fmt.Errorf("internal server error")
 	}
 
@@ -125,15 +144,23 @@ func (s *AuthService) Logout(ctx context.Context, token string) error {
 	return nil
 }
 
-func (s *AuthService) Refresh(ctx context.Context) (*model.LoginResponse, error) {
+// Refresh rotates the refresh token in ctx. The new tokens are issued for
+// switchTo if it is given and otherwise for the organisation of the old token,
+// with the role the user now holds there.
+func (s *AuthService) Refresh(ctx context.Context, switchTo *uuid.UUID) (*model.LoginResponse, error) {
 	userID, ok := ctx.Value("user_id").(uuid.UUID)
 	if !ok {
 		return nil, fmt.Errorf("internal server error")
 	}
 
-	role, _ := ctx.Value("role").(string)
-	if role == "" {
-		role = "user"
+	orgID := switchTo
+	if orgID == nil {
+		orgID = repository.TenantFromContext(ctx)
+	}
+
+	role, err := s.CheckAccount(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
 	}
 
 	oldToken, _ := ctx.Value("refresh_token").(string)
@@ -147,12 +174,12 @@ func (s *AuthService) Refresh(ctx context.Context) (*model.LoginResponse, error)
 		}
 	}
 
-	accessToken, err := auth.GenerateAccessToken(s.accessTokenKey, userID, role)
+	accessToken, err := auth.GenerateAccessToken(s.accessTokenKey, userID, role, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("internal server error")
 	}
 
-	refreshToken, err := auth.GenerateRefreshToken(s.refreshTokenKey, userID, role)
+	refreshToken, err := auth.GenerateRefreshToken(s.refreshTokenKey, userID, role, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("internal server error")
 	}
@@ -173,3 +200,48 @@ func (s *AuthService) Refresh(ctx context.Context) (*model.LoginResponse, error)
 		RefreshToken: refreshToken,
 	}, nil
 }
+
+// CheckAccount reports whether userID may still act on the tokens it holds
+// for orgID and returns the role it now holds there. Deleted accounts are
+// unauthorized, and accounts that are not active or no longer belong to orgID
+// are forbidden. Super admins hold their role in every organisation, and
+// without an organisation everyone else is a plain user.
+func (s *AuthService) CheckAccount(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (string, error) {
+	access, err := s.repo.GetAccess(ctx, userID, orgID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return "", model.ErrUnauthorized
+		}
+		return "", err
+	}
+
+	if err := statusError(access.Status); err != nil {
+		return "", err
+	}
+
+	if access.Role == "super_admin" {
+		if orgID != nil && !access.OrganisationExists {
+			return "", fmt.Errorf("organisation %w", model.ErrNotFound)
+		}
+		return "super_admin", nil
+	}
+
+	if orgID == nil {
+		return "user", nil
+	}
+
+	switch access.MemberRole {
+	case model.MemberRoleAdmin:
+		return "admin", nil
+	case model.MemberRoleMember:
+		return "user", nil
+	}
+	return "", fmt.Errorf("not a member of this organisation: %w", model.ErrForbidden)
+}
+
+func statusError(status string) error {
+	if status == model.StatusActive {
+		return nil
+	}
+	return fmt.Errorf("account is %s: %w", status, model.ErrForbidden)
+}
@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/auth"
 	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
 	"github.com/google/uuid"
 )
@@ -15,6 +18,8 @@ type mockAuthRepo struct {
 	loginFunc         func(ctx context.Context, email string) (*model.GetByEmail, error)
 	storeRefreshFunc  func(ctx context.Context, id uuid.UUID, userID uuid.UUID, token string, expiresAt time.Time) error
 	revokeRefreshFunc func(ctx context.Context, token string) error
+	getAccessFunc     func(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error)
+	defaultOrgFunc    func(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error)
 }
 
 func (m *mockAuthRepo) Register(ctx context.Context, user model.User) error {
@@ -45,6 +50,25 @@ func (m *mockAuthRepo) RevokeRefreshToken(ctx context.Context, token string) err
 	return nil
 }
 
+func (m *mockAuthRepo) GetAccess(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error) {
+	if m.getAccessFunc != nil {
+		return m.getAccessFunc(ctx, userID, orgID)
+	}
+	access := &model.Access{Status: model.StatusActive, Role: "user"}
+	if orgID != nil {
+		access.MemberRole = model.MemberRoleMember
+		access.OrganisationExists = true
+	}
+	return access, nil
+}
+
+func (m *mockAuthRepo) DefaultOrganisation(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	if m.defaultOrgFunc != nil {
+		return m.defaultOrgFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
 func TestAuthService_Register(t *testing.T) {
 
 	tests := []struct {
@@ -134,6 +158,7 @@ func TestAuthService_Login(t *testing.T) {
 					ID:       testID,
 					Password: hashedPassword,
 					Role:     "user",
+					Status:   model.StatusActive,
 				}, nil
 			},
 			expectErr: false,
@@ -160,6 +185,7 @@ func TestAuthService_Login(t *testing.T) {
 					ID:       testID,
 					Password: hashedPassword,
 					Role:     "user",
+					Status:   model.StatusActive,
 				}, nil
 			},
 			expectErr: true,
@@ -175,6 +201,7 @@ func TestAuthService_Login(t *testing.T) {
 					ID:       testID,
 					Password: hashedPassword,
 					Role:     "user",
+					Status:   model.StatusActive,
 				}, nil
 			},
 			storeRefreshFunc: func(ctx context.Context, id uuid.UUID, userID uuid.UUID, token string, expiresAt time.Time) error {
@@ -182,6 +209,22 @@ func TestAuthService_Login(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "suspended account",
+			loginData: &model.LoginUser{
+				Email:    "johndoe@test.com",
+				Password: "password123",
+			},
+			mockFunc: func(ctx context.Context, email string) (*model.GetByEmail, error) {
+				return &model.GetByEmail{
+					ID:       testID,
+					Password: hashedPassword,
+					Role:     "user",
+					Status:   model.StatusSuspended,
+				}, nil
+			},
+			expectErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -298,6 +341,7 @@ func TestAuthService_Refresh(t *testing.T) {
 		name              string
 		ctx               context.Context
 		revokeRefreshFunc func(ctx context.Context, token string) error
+		getAccessFunc     func(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error)
 		expectErr         bool
 	}{
 		{
@@ -333,14 +377,26 @@ func TestAuthService_Refresh(t *testing.T) {
 			ctx:       context.Background(),
 			expectErr: true,
 		},
+		{
+			name: "locked account",
+			ctx:  context.WithValue(context.WithValue(context.Background(), "user_id", testID), "refresh_token", "old-token"),
+			getAccessFunc: func(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error) {
+				return &model.Access{Status: model.StatusLocked, Role: "user"}, nil
+			},
+			revokeRefreshFunc: func(ctx context.Context, token string) error {
+				t.Error("refresh token of a locked account must not be rotated")
+				return nil
+			},
+			expectErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := &mockAuthRepo{revokeRefreshFunc: tt.revokeRefreshFunc}
+			mockRepo := &mockAuthRepo{revokeRefreshFunc: tt.revokeRefreshFunc, getAccessFunc: tt.getAccessFunc}
 			service := NewAuthService(mockRepo, "test-pepper", "test-access-key", "test-refresh-key")
 
-			resp, err := service.Refresh(tt.ctx)
+			resp, err := service.Refresh(tt.ctx, nil)
 
 			if tt.expectErr {
 				if err == nil {
@@ -362,3 +418,184 @@ func TestAuthService_Refresh(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_Refresh_SwitchOrganisation(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	fromOrg, _ := uuid.NewV7()
+	toOrg, _ := uuid.NewV7()
+
+	ctx := context.WithValue(context.Background(), "user_id", testID)
+	ctx = context.WithValue(ctx, "refresh_token", "old-token")
+	ctx = repository.WithTenant(ctx, fromOrg)
+
+	mockRepo := &mockAuthRepo{getAccessFunc: func(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error) {
+		if orgID == nil || *orgID != toOrg {
+			t.Errorf("access checked for %v, want %v", orgID, toOrg)
+		}
+		return &model.Access{Status: model.StatusActive, Role: "user", MemberRole: model.MemberRoleAdmin, OrganisationExists: true}, nil
+	}}
+	service := NewAuthService(mockRepo, "test-pepper", "test-access-key", "test-refresh-key")
+
+	resp, err := service.Refresh(ctx, &toOrg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims, err := auth.VerifyAccessToken("test-access-key", resp.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken: %v", err)
+	}
+	if claims.OrganisationID == nil || *claims.OrganisationID != toOrg {
+		t.Errorf("organisation: got %v want %v", claims.OrganisationID, toOrg)
+	}
+	if claims.Role != "admin" {
+		t.Errorf("role: got %q want admin", claims.Role)
+	}
+}
+
+func TestAuthService_Login_Organisation(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	defaultOrg, _ := uuid.NewV7()
+	otherOrg, _ := uuid.NewV7()
+	hasher := encrypt.NewPasswordHasher("test-pepper")
+	hashedPassword, _ := hasher.HashPassword("password123")
+
+	tests := []struct {
+		name       string
+		role       string
+		orgID      *uuid.UUID
+		memberRole string
+		expectOrg  *uuid.UUID
+		expectRole string
+		expectErr  error
+	}{
+		{name: "default organisation", role: "user", memberRole: model.MemberRoleAdmin, expectOrg: &defaultOrg, expectRole: "admin"},
+		{name: "chosen organisation", role: "user", orgID: &otherOrg, memberRole: model.MemberRoleMember, expectOrg: &otherOrg, expectRole: "user"},
+		{name: "not a member", role: "user", orgID: &otherOrg, expectErr: model.ErrForbidden},
+		{name: "super admin across organisations", role: "super_admin", expectRole: "super_admin"},
+		{name: "super admin in an organisation", role: "super_admin", orgID: &otherOrg, expectOrg: &otherOrg, expectRole: "super_admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockAuthRepo{
+				loginFunc: func(ctx context.Context, email string) (*model.GetByEmail, error) {
+					return &model.GetByEmail{ID: testID, Password: hashedPassword, Role: tt.role, Status: model.StatusActive}, nil
+				},
+				defaultOrgFunc: func(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+					return &defaultOrg, nil
+				},
+				getAccessFunc: func(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error) {
+					return &model.Access{Status: model.StatusActive, Role: tt.role, MemberRole: tt.memberRole, OrganisationExists: true}, nil
+				},
+			}
+			service := NewAuthService(mockRepo, "test-pepper", "test-access-key", "test-refresh-key")
+
+			resp, err := service.Login(context.Background(), &model.LoginUser{
+				Email:          "johndoe@test.com",
+				Password:       "password123",
+				OrganisationID: tt.orgID,
+			})
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			claims, err := auth.VerifyAccessToken("test-access-key", resp.AccessToken)
+			if err != nil {
+				t.Fatalf("VerifyAccessToken: %v", err)
+			}
+			if claims.Role != tt.expectRole {
+				t.Errorf("role: got %q want %q", claims.Role, tt.expectRole)
+			}
+			switch {
+			case tt.expectOrg == nil && claims.OrganisationID != nil:
+				t.Errorf("organisation: got %v want none", claims.OrganisationID)
+			case tt.expectOrg != nil && (claims.OrganisationID == nil || *claims.OrganisationID != *tt.expectOrg):
+				t.Errorf("organisation: got %v want %v", claims.OrganisationID, tt.expectOrg)
+			}
+		})
+	}
+}
+
+func TestAuthService_CheckAccount(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	orgID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		access     model.Access
+		orgID      *uuid.UUID
+		repoErr    error
+		expectRole string
+		expectErr  error
+	}{
+		{name: "active", access: model.Access{Status: model.StatusActive, Role: "user"}, expectRole: "user"},
+		{name: "suspended", access: model.Access{Status: model.StatusSuspended, Role: "user"}, expectErr: model.ErrForbidden},
+		{name: "locked", access: model.Access{Status: model.StatusLocked, Role: "user"}, expectErr: model.ErrForbidden},
+		{name: "pending", access: model.Access{Status: model.StatusPending, Role: "user"}, expectErr: model.ErrForbidden},
+		{name: "deleted", repoErr: model.ErrNotFound, expectErr: model.ErrUnauthorized},
+		{name: "repo error", repoErr: errRepo, expectErr: errRepo},
+		{
+			name:       "organisation admin",
+			orgID:      &orgID,
+			access:     model.Access{Status: model.StatusActive, Role: "user", MemberRole: model.MemberRoleAdmin, OrganisationExists: true},
+			expectRole: "admin",
+		},
+		{
+			name:       "organisation member",
+			orgID:      &orgID,
+			access:     model.Access{Status: model.StatusActive, Role: "user", MemberRole: model.MemberRoleMember, OrganisationExists: true},
+			expectRole: "user",
+		},
+		{
+			name:      "removed from organisation",
+			orgID:     &orgID,
+			access:    model.Access{Status: model.StatusActive, Role: "user", OrganisationExists: true},
+			expectErr: model.ErrForbidden,
+		},
+		{
+			name:       "super admin without membership",
+			orgID:      &orgID,
+			access:     model.Access{Status: model.StatusActive, Role: "super_admin", OrganisationExists: true},
+			expectRole: "super_admin",
+		},
+		{
+			name:      "super admin in unknown organisation",
+			orgID:     &orgID,
+			access:    model.Access{Status: model.StatusActive, Role: "super_admin"},
+			expectErr: model.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockAuthRepo{getAccessFunc: func(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*model.Access, error) {
+				if tt.repoErr != nil {
+					return nil, tt.repoErr
+				}
+				return &tt.access, nil
+			}}
+			service := NewAuthService(mockRepo, "test-pepper", "test-access-key", "test-refresh-key")
+
+			role, err := service.CheckAccount(context.Background(), testID, tt.orgID)
+			if tt.expectErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if role != tt.expectRole {
+					t.Errorf("role: got %q want %q", role, tt.expectRole)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("expected %v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+// SetAvatar stores the image read from r as the avatar of user id, replacing
+// any previous one. The content type is sniffed from the data itself, and
+// images larger than the configured maximum are rejected without touching
+// the stored avatar.
+func (s *UserService) SetAvatar(ctx context.Context, id uuid.UUID, r io.Reader, callerID uuid.UUID, callerRole string) error {
+	if id != callerID && !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("user %w", err)
+		}
+		return err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return fmt.Errorf("avatar is empty: %w", model.ErrBadRequest)
+		}
+		return err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !model.AvatarContentTypes[contentType] {
+		return fmt.Errorf("avatar must be a JPEG, PNG or WebP image: %w", model.ErrUnsupportedMedia)
+	}
+
+	body := &sizeLimitedReader{r: io.MultiReader(bytes.NewReader(head), r), max: s.avatarMaxBytes}
+	if err := s.avatars.Put(ctx, model.AvatarKey(id), body); err != nil {
+		return err
+	}
+
+	if err := s.repo.SetAvatar(ctx, id, contentType); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("user %w", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetAvatar opens the avatar of user id. The caller must close the reader.
+func (s *UserService) GetAvatar(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.Avatar, io.ReadCloser, error) {
+	if id != callerID && !isAdmin(callerRole) {
+		return nil, nil, model.ErrForbidden
+	}
+
+	avatar, err := s.repo.GetAvatar(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, nil, fmt.Errorf("avatar %w", err)
+		}
+		return nil, nil, err
+	}
+
+	rc, err := s.avatars.Get(ctx, model.AvatarKey(id))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return avatar, rc, nil
+}
+
+func (s *UserService) DeleteAvatar(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	if id != callerID && !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	if err := s.repo.ClearAvatar(ctx, id); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("avatar %w", err)
+		}
+		return err
+	}
+
+	return s.avatars.Delete(ctx, model.AvatarKey(id))
+}
+
+// avatarURL is the address the avatar of a user is served from. The version
+// parameter changes with every upload so clients can cache it indefinitely.
+func avatarURL(user *model.GetByID) *string {
+	if user.AvatarUpdatedAt == nil {
+		return nil
+	}
+	u := fmt.Sprintf("/api/v1/users/%s/avatar?v=%d", user.ID, user.AvatarUpdatedAt.Unix())
+	return &u
+}
+
+// sizeLimitedReader fails with ErrPayloadTooLarge once more than max bytes
+// have been read.
+type sizeLimitedReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if remaining := l.max - l.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, fmt.Errorf("avatar must be at most %d bytes: %w", l.max, model.ErrPayloadTooLarge)
+	}
+	return n, err
+}
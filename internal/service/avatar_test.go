@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+// pngHeader is enough of a PNG file for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestUserService_SetAvatar(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	otherID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		callerID   uuid.UUID
+		callerRole string
+		body       []byte
+		expectErr  error
+	}{
+		{name: "own png", callerID: userID, callerRole: "user", body: pngHeader},
+		{name: "admin for other user", callerID: otherID, callerRole: "admin", body: pngHeader},
+		{name: "other user", callerID: otherID, callerRole: "user", body: pngHeader, expectErr: model.ErrForbidden},
+		{name: "not an image", callerID: userID, callerRole: "user", body: []byte("<html><script>alert(1)</script>"), expectErr: model.ErrUnsupportedMedia},
+		{name: "empty", callerID: userID, callerRole: "user", body: nil, expectErr: model.ErrBadRequest},
+		{name: "too large", callerID: userID, callerRole: "user", body: append(bytes.Clone(pngHeader), make([]byte, testAvatarMaxBytes)...), expectErr: model.ErrPayloadTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockUserRepo{}
+			store := newMockBlobStore()
+			service := NewUserService(repo, &mockAuditRepo{}, store, testAvatarMaxBytes)
+
+			err := service.SetAvatar(context.Background(), userID, bytes.NewReader(tt.body), tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if repo.avatar != nil {
+					t.Error("avatar metadata must not be set on failure")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if repo.avatar == nil || repo.avatar.ContentType != "image/png" {
+				t.Fatalf("expected image/png avatar, got %+v", repo.avatar)
+			}
+			if !bytes.Equal(store.blobs[model.AvatarKey(userID)], tt.body) {
+				t.Error("stored avatar differs from upload")
+			}
+		})
+	}
+}
+
+func TestUserService_GetAvatarAndURL(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	repo := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.GetByID, error) {
+			return &model.GetByID{ID: id}, nil
+		},
+	}
+	store := newMockBlobStore()
+	service := NewUserService(repo, &mockAuditRepo{}, store, testAvatarMaxBytes)
+	ctx := context.Background()
+
+	if _, _, err := service.GetAvatar(ctx, userID, userID, "user"); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("expected not found before upload, got %v", err)
+	}
+
+	if err := service.SetAvatar(ctx, userID, bytes.NewReader(pngHeader), userID, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	avatar, rc, err := service.GetAvatar(ctx, userID, userID, "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if avatar.ContentType != "image/png" || !bytes.Equal(b, pngHeader) {
+		t.Errorf("unexpected avatar %s, %d bytes", avatar.ContentType, len(b))
+	}
+
+	updatedAt := time.Unix(1700000000, 0)
+	repo.getByIDFunc = func(ctx context.Context, id uuid.UUID) (*model.GetByID, error) {
+		return &model.GetByID{ID: id, AvatarUpdatedAt: &updatedAt}, nil
+	}
+	user, err := service.GetByID(ctx, userID, userID, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.AvatarURL == nil || !strings.HasSuffix(*user.AvatarURL, "/users/"+userID.String()+"/avatar?v=1700000000") {
+		t.Errorf("unexpected avatar url %v", user.AvatarURL)
+	}
+
+	if err := service.DeleteAvatar(ctx, userID, userID, "user"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := store.blobs[model.AvatarKey(userID)]; ok {
+		t.Error("expected avatar blob to be deleted")
+	}
+	if err := service.DeleteAvatar(ctx, userID, userID, "user"); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("expected not found on second delete, got %v", err)
+	}
+}
+
+func TestUserService_PurgeDeletedRemovesAvatars(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	store := newMockBlobStore()
+	store.blobs[model.AvatarKey(userID)] = pngHeader
+
+	repo := &mockUserRepo{
+		purgeFunc: func(ctx context.Context, cutoff time.Time, mode string, limit int) ([]model.PurgedUser, error) {
+			return []model.PurgedUser{{ID: userID, Mode: mode}}, nil
+		},
+	}
+	service := NewUserService(repo, &mockAuditRepo{}, store, testAvatarMaxBytes)
+
+	if _, err := service.PurgeDeleted(context.Background(), time.Hour, model.PurgeModeAnonymise, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.blobs) != 0 {
+		t.Error("expected purged user's avatar to be deleted")
+	}
+}
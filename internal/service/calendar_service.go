@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/PranavJoshi2893/med-portal/pkg/ical"
+	"github.com/google/uuid"
+)
+
+const (
+	calendarProdID = "-//med-portal//Appointments//EN"
+
+	// A feed lists appointments from a day ago up to a year ahead.
+	calendarFeedPast  = 24 * time.Hour
+	calendarFeedAhead = 365 * 24 * time.Hour
+	calendarFeedLimit = 1000
+	calendarUIDSuffix = "@med-portal"
+)
+
+type CalendarService struct {
+	repo         repository.CalendarRepository
+	appointments repository.AppointmentRepository
+	audit        repository.AuditRepository
+}
+
+func NewCalendarService(repo repository.CalendarRepository, appointments repository.AppointmentRepository, audit repository.AuditRepository) *CalendarService {
+	return &CalendarService{
+		repo:         repo,
+		appointments: appointments,
+		audit:        audit,
+	}
+}
+
+// CreateFeed gives the caller a new feed URL, replacing any they had.
+func (s *CalendarService) CreateFeed(ctx context.Context, callerID uuid.UUID) (*model.CalendarFeedCreated, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	token, err := encrypt.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	feed := model.CalendarFeed{
+		ID:        id,
+		UserID:    callerID,
+		CreatedAt: time.Now(),
+		TokenHash: encrypt.HashToken(token),
+	}
+	if err := s.repo.CreateFeed(ctx, feed); err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditCalendarFeedCreated, "user", callerID, map[string]any{"feed_id": id})
+
+	return &model.CalendarFeedCreated{
+		Feed: feed,
+		URL:  fmt.Sprintf("/api/v1/calendar/%s.ics", token),
+	}, nil
+}
+
+func (s *CalendarService) GetFeed(ctx context.Context, callerID uuid.UUID) (*model.CalendarFeed, error) {
+	feed, err := s.repo.GetFeed(ctx, callerID)
+	if err != nil {
+		return nil, calendarFeedError(err)
+	}
+	return feed, nil
+}
+
+// RevokeFeed stops the caller's feed URL from working.
+func (s *CalendarService) RevokeFeed(ctx context.Context, callerID uuid.UUID) error {
+	if err := s.repo.RevokeFeed(ctx, callerID); err != nil {
+		return calendarFeedError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditCalendarFeedRevoked, "user", callerID, nil)
+	return nil
+}
+
+// Feed renders the calendar behind a feed token: the appointments, in every
+// organisation, that the feed's owner is the practitioner or the patient of.
+// Feeds of accounts that may not sign in are not found.
+func (s *CalendarService) Feed(ctx context.Context, token string) ([]byte, error) {
+	feed, err := s.repo.GetFeedByTokenHash(ctx, encrypt.HashToken(token))
+	if err != nil {
+		return nil, calendarFeedError(err)
+	}
+	if statusError(feed.OwnerStatus) != nil {
+		return nil, fmt.Errorf("calendar feed %w", model.ErrNotFound)
+	}
+
+	now := time.Now()
+	from, to := now.Add(-calendarFeedPast), now.Add(calendarFeedAhead)
+	appointments, err := s.appointments.List(ctx, model.AppointmentFilter{
+		From:          &from,
+		To:            &to,
+		ParticipantID: &feed.UserID,
+	}, calendarFeedLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.TouchFeed(ctx, feed.ID); err != nil {
+		return nil, err
+	}
+
+	cal := ical.Calendar{ProdID: calendarProdID, Name: "Appointments"}
+	for _, a := range appointments {
+		cal.Events = append(cal.Events, appointmentEvent(a, a.PractitionerUserID == feed.UserID, a.UpdatedAt))
+	}
+	return cal.Bytes(), nil
+}
+
+// appointmentEvent describes a as a calendar event for its practitioner, or
+// otherwise for its patient, naming the other party. The reason for the
+// visit is left out, as calendars are often shared or synced elsewhere.
+func appointmentEvent(a model.Appointment, forPractitioner bool, stamp time.Time) ical.Event {
+	loc, err := time.LoadLocation(a.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	summary := "Appointment with " + a.PractitionerName
+	if forPractitioner {
+		summary = "Appointment with " + a.PatientName
+	}
+
+	status := ical.StatusConfirmed
+	if a.Status == model.AppointmentCancelled {
+		status = ical.StatusCancelled
+	}
+
+	return ical.Event{
+		UID:         a.ID.String() + calendarUIDSuffix,
+		Stamp:       stamp,
+		Start:       a.StartsAt.In(loc),
+		End:         a.EndsAt.In(loc),
+		Summary:     summary,
+		Description: fmt.Sprintf("Practitioner: %s\nPatient: %s", a.PractitionerName, a.PatientName),
+		Status:      status,
+	}
+}
+
+func calendarFeedError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("calendar feed %w", err)
+	}
+	return err
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/google/uuid"
+)
+
+type mockCalendarRepo struct {
+	feed           *model.CalendarFeed
+	touched        bool
+	createFeedFunc func(ctx context.Context, feed model.CalendarFeed) error
+	touchFeedFunc  func(ctx context.Context, id uuid.UUID) error
+}
+
+func (m *mockCalendarRepo) CreateFeed(ctx context.Context, feed model.CalendarFeed) error {
+	if m.createFeedFunc != nil {
+		return m.createFeedFunc(ctx, feed)
+	}
+	feed.OwnerStatus = model.StatusActive
+	m.feed = &feed
+	return nil
+}
+
+func (m *mockCalendarRepo) GetFeed(ctx context.Context, userID uuid.UUID) (*model.CalendarFeed, error) {
+	if m.feed == nil || m.feed.UserID != userID {
+		return nil, model.ErrNotFound
+	}
+	return m.feed, nil
+}
+
+func (m *mockCalendarRepo) RevokeFeed(ctx context.Context, userID uuid.UUID) error {
+	if m.feed == nil || m.feed.UserID != userID {
+		return model.ErrNotFound
+	}
+	m.feed = nil
+	return nil
+}
+
+func (m *mockCalendarRepo) GetFeedByTokenHash(ctx context.Context, tokenHash string) (*model.CalendarFeed, error) {
+	if m.feed == nil || m.feed.TokenHash != tokenHash {
+		return nil, model.ErrNotFound
+	}
+	return m.feed, nil
+}
+
+func (m *mockCalendarRepo) TouchFeed(ctx context.Context, id uuid.UUID) error {
+	if m.touchFeedFunc != nil {
+		return m.touchFeedFunc(ctx, id)
+	}
+	m.touched = true
+	return nil
+}
+
+// feedToken extracts the token from a feed URL.
+func feedToken(t *testing.T, url string) string {
+	t.Helper()
+	token, ok := strings.CutPrefix(url, "/api/v1/calendar/")
+	if !ok || !strings.HasSuffix(token, ".ics") {
+		t.Fatalf("unexpected feed URL %q", url)
+	}
+	return strings.TrimSuffix(token, ".ics")
+}
+
+func TestCalendarService_CreateFeed(t *testing.T) {
+	userID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name           string
+		createFeedFunc func(ctx context.Context, feed model.CalendarFeed) error
+		expectErr      error
+	}{
+		{
+			name: "success",
+		},
+		{
+			name: "repo error",
+			createFeedFunc: func(ctx context.Context, feed model.CalendarFeed) error {
+				return errRepo
+			},
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockCalendarRepo{createFeedFunc: tt.createFeedFunc}
+			audit := &mockAuditRepo{}
+			service := NewCalendarService(repo, &mockAppointmentRepo{}, audit)
+
+			created, err := service.CreateFeed(context.Background(), userID)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("audited %+v, want nothing", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token := feedToken(t, created.URL); repo.feed.TokenHash != encrypt.HashToken(token) || repo.feed.UserID != userID {
+				t.Errorf("stored %+v, want the caller's feed with the token hashed", repo.feed)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditCalendarFeedCreated {
+				t.Errorf("audit entries = %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestCalendarService_Feed(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+
+	practitionerUserID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	booked, _ := uuid.NewV7()
+	cancelled, _ := uuid.NewV7()
+	listed := []model.Appointment{
+		{
+			ID: booked, StartsAt: start, EndsAt: start.Add(30 * time.Minute), Status: model.AppointmentBooked,
+			PractitionerName: "Greg House", PatientName: "Jane Doe", Timezone: "Europe/London",
+			PractitionerUserID: practitionerUserID, PatientUserID: &patientUserID,
+		},
+		{
+			ID: cancelled, StartsAt: start.Add(time.Hour), EndsAt: start.Add(90 * time.Minute), Status: model.AppointmentCancelled,
+			PractitionerName: "Greg House", PatientName: "John Roe", Timezone: "Europe/London",
+			PractitionerUserID: practitionerUserID,
+		},
+	}
+
+	tests := []struct {
+		name          string
+		wrongToken    bool
+		ownerStatus   string
+		revoked       bool
+		touchFeedFunc func(ctx context.Context, id uuid.UUID) error
+		expectErr     error
+		expectICS     []string
+	}{
+		{
+			name: "success",
+			expectICS: []string{
+				"BEGIN:VTIMEZONE\r\nTZID:Europe/London\r\n",
+				"UID:" + booked.String() + "@med-portal\r\n",
+				"DTSTART;TZID=Europe/London:" + start.In(london).Format("20060102T150405") + "\r\n",
+				"SUMMARY:Appointment with Jane Doe\r\n",
+				"SUMMARY:Appointment with John Roe\r\n",
+				"STATUS:CANCELLED\r\n",
+			},
+		},
+		{
+			name:       "wrong token",
+			wrongToken: true,
+			expectErr:  model.ErrNotFound,
+		},
+		{
+			name:        "suspended owner",
+			ownerStatus: model.StatusSuspended,
+			expectErr:   model.ErrNotFound,
+		},
+		{
+			name:      "revoked",
+			revoked:   true,
+			expectErr: model.ErrNotFound,
+		},
+		{
+			name: "repo error",
+			touchFeedFunc: func(ctx context.Context, id uuid.UUID) error {
+				return errRepo
+			},
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appointments := &mockAppointmentRepo{listed: listed}
+			repo := &mockCalendarRepo{touchFeedFunc: tt.touchFeedFunc}
+			service := NewCalendarService(repo, appointments, &mockAuditRepo{})
+
+			created, err := service.CreateFeed(context.Background(), practitionerUserID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			token := feedToken(t, created.URL)
+			if tt.wrongToken {
+				token = "wrong"
+			}
+			if tt.ownerStatus != "" {
+				repo.feed.OwnerStatus = tt.ownerStatus
+			}
+			if tt.revoked {
+				if err := service.RevokeFeed(context.Background(), practitionerUserID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			got, err := service.Feed(context.Background(), token)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.expectICS {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("feed missing %q:\n%s", want, got)
+				}
+			}
+			if f := appointments.listFilter; f.ParticipantID == nil || *f.ParticipantID != practitionerUserID || f.From == nil || f.To == nil {
+				t.Errorf("list filter = %+v, want the feed owner's upcoming appointments", f)
+			}
+			if !repo.touched {
+				t.Error("expected the feed's last use to be recorded")
+			}
+		})
+	}
+}
+
+func TestCalendarService_RevokeFeed(t *testing.T) {
+	userID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name      string
+		noFeed    bool
+		expectErr error
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:      "no feed",
+			noFeed:    true,
+			expectErr: model.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockCalendarRepo{}
+			audit := &mockAuditRepo{}
+			service := NewCalendarService(repo, &mockAppointmentRepo{}, audit)
+			if !tt.noFeed {
+				if _, err := service.CreateFeed(context.Background(), userID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			audit.entries = nil
+
+			err := service.RevokeFeed(context.Background(), userID)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("audited %+v, want nothing", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if repo.feed != nil {
+				t.Errorf("feed %+v is still stored", repo.feed)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditCalendarFeedRevoked {
+				t.Errorf("audit entries = %+v", audit.entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+type EmailChangeService struct {
+	repo         repository.EmailChangeRepository
+	audit        repository.AuditRepository
+	hasher       *encrypt.PasswordHasher
+	mailer       mailer.Mailer
+	baseURL      string
+	tokenTTL     time.Duration
+	revertWindow time.Duration
+}
+
+func NewEmailChangeService(repo repository.EmailChangeRepository, audit repository.AuditRepository, pepper string, mailer mailer.Mailer, baseURL string, tokenTTL, revertWindow time.Duration) *EmailChangeService {
+	return &EmailChangeService{
+		repo:         repo,
+		audit:        audit,
+		hasher:       encrypt.NewPasswordHasher(pepper),
+		mailer:       mailer,
+		baseURL:      baseURL,
+		tokenTTL:     tokenTTL,
+		revertWindow: revertWindow,
+	}
+}
+
+// Request starts an email change for the caller after checking their
+// password. A confirmation link goes to the new address and a notice to the
+// current one. Users may only change their own address.
+func (s *EmailChangeService) Request(ctx context.Context, id uuid.UUID, data *model.ChangeEmail, callerID uuid.UUID) error {
+	if id != callerID {
+		return model.ErrForbidden
+	}
+
+	creds, err := s.repo.GetCredentials(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("user %w", err)
+		}
+		return err
+	}
+
+	if !s.hasher.VerifyPassword(data.Password, creds.Password) {
+		return model.ErrUnauthorized
+	}
+
+	if data.NewEmail == creds.Email {
+		return model.ValidationErrors{model.FieldError{Field: "new_email", Message: "new email must differ from the current email"}}
+	}
+
+	exists, err := s.repo.EmailExists(ctx, data.NewEmail)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("email %w", model.ErrAlreadyExists)
+	}
+
+	reqID, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	token, err := encrypt.GenerateToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	err = s.repo.Create(ctx, model.EmailChangeRequest{
+		ID:        reqID,
+		UserID:    id,
+		OldEmail:  creds.Email,
+		NewEmail:  data.NewEmail,
+		TokenHash: encrypt.HashToken(token),
+		ExpiresAt: time.Now().Add(s.tokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditEmailChangeRequested, "user", id, nil)
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      data.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Confirm that you want to use this address for your Med Portal account:\n\n%s/confirm-email?token=%s\n\nThe link expires in %s.",
+			s.baseURL, token, s.tokenTTL,
+		),
+	}); err != nil {
+		return err
+	}
+
+	// The notice is informational; the change can go ahead without it.
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      creds.Email,
+		Subject: "Email change requested",
+		Body: fmt.Sprintf(
+			"A change of your Med Portal email address to %s was requested. If this was not you, change your password. You will be able to revert the change from this address once it is confirmed.",
+			data.NewEmail,
+		),
+	}); err != nil {
+		log.Printf("email change %s: notice to old address: %v", reqID, err)
+	}
+
+	return nil
+}
+
+// Confirm applies the change named by token and sends the old address a
+// link that can revert it for the revert window.
+func (s *EmailChangeService) Confirm(ctx context.Context, token string) error {
+	req, err := s.repo.GetByTokenHash(ctx, encrypt.HashToken(token))
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("token %w", err)
+		}
+		return err
+	}
+
+	if req.ConfirmedAt != nil {
+		return fmt.Errorf("email change already confirmed: %w", model.ErrConflict)
+	}
+	if time.Now().After(req.ExpiresAt) {
+		return fmt.Errorf("token has expired: %w", model.ErrAlreadyDeleted)
+	}
+
+	revertToken, err := encrypt.GenerateToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err := s.repo.Confirm(ctx, req.ID, encrypt.HashToken(revertToken), time.Now().Add(s.revertWindow)); err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return fmt.Errorf("email %w", err)
+		}
+		if errors.Is(err, model.ErrConflict) {
+			return fmt.Errorf("email has changed since the request: %w", err)
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("token %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &req.UserID, model.AuditEmailChanged, "user", req.UserID, nil)
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      req.OldEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf(
+			"The email address of your Med Portal account was changed to %s. If this was not you, revert the change and sign out all sessions:\n\n%s/revert-email?token=%s\n\nThe link expires in %s.",
+			req.NewEmail, s.baseURL, revertToken, s.revertWindow,
+		),
+	}); err != nil {
+		log.Printf("email change %s: revert link to old address: %v", req.ID, err)
+	}
+
+	return nil
+}
+
+// Revert restores the old address named by a revert token.
+func (s *EmailChangeService) Revert(ctx context.Context, token string) error {
+	req, err := s.repo.GetByRevertTokenHash(ctx, encrypt.HashToken(token))
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("token %w", err)
+		}
+		return err
+	}
+
+	if req.RevertedAt != nil {
+		return fmt.Errorf("email change already reverted: %w", model.ErrConflict)
+	}
+	if req.RevertExpiresAt == nil || time.Now().After(*req.RevertExpiresAt) {
+		return fmt.Errorf("token has expired: %w", model.ErrAlreadyDeleted)
+	}
+
+	if err := s.repo.Revert(ctx, req.ID); err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return fmt.Errorf("email %w", err)
+		}
+		if errors.Is(err, model.ErrConflict) {
+			return fmt.Errorf("email has changed again since the change: %w", err)
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("token %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, nil, model.AuditEmailReverted, "user", req.UserID, nil)
+
+	return nil
+}
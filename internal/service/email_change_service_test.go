@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+// mockEmailChangeRepo keeps a single user and their change requests in memory.
+type mockEmailChangeRepo struct {
+	creds    model.EmailCredentials
+	taken    map[string]bool
+	requests map[uuid.UUID]*model.EmailChangeRequest
+}
+
+func newMockEmailChangeRepo(email, passwordHash string) *mockEmailChangeRepo {
+	return &mockEmailChangeRepo{
+		creds:    model.EmailCredentials{Email: email, Password: passwordHash},
+		taken:    map[string]bool{},
+		requests: map[uuid.UUID]*model.EmailChangeRequest{},
+	}
+}
+
+func (m *mockEmailChangeRepo) GetCredentials(ctx context.Context, userID uuid.UUID) (*model.EmailCredentials, error) {
+	c := m.creds
+	return &c, nil
+}
+
+func (m *mockEmailChangeRepo) EmailExists(ctx context.Context, email string) (bool, error) {
+	return m.taken[email], nil
+}
+
+func (m *mockEmailChangeRepo) Create(ctx context.Context, req model.EmailChangeRequest) error {
+	m.requests[req.ID] = &req
+	return nil
+}
+
+func (m *mockEmailChangeRepo) find(match func(*model.EmailChangeRequest) bool) (*model.EmailChangeRequest, error) {
+	for _, r := range m.requests {
+		if match(r) {
+			c := *r
+			return &c, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockEmailChangeRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error) {
+	return m.find(func(r *model.EmailChangeRequest) bool { return r.TokenHash == tokenHash })
+}
+
+func (m *mockEmailChangeRepo) GetByRevertTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error) {
+	return m.find(func(r *model.EmailChangeRequest) bool {
+		return r.RevertTokenHash != "" && r.RevertTokenHash == tokenHash
+	})
+}
+
+func (m *mockEmailChangeRepo) Confirm(ctx context.Context, id uuid.UUID, revertTokenHash string, revertExpiresAt time.Time) error {
+	r := m.requests[id]
+	now := time.Now()
+	r.ConfirmedAt = &now
+	r.RevertTokenHash = revertTokenHash
+	r.RevertExpiresAt = &revertExpiresAt
+	m.creds.Email = r.NewEmail
+	return nil
+}
+
+func (m *mockEmailChangeRepo) Revert(ctx context.Context, id uuid.UUID) error {
+	r := m.requests[id]
+	now := time.Now()
+	r.RevertedAt = &now
+	m.creds.Email = r.OldEmail
+	return nil
+}
+
+type mockMailer struct {
+	sent    []mailer.Message
+	sendErr error
+}
+
+func (m *mockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.sendErr != nil {
+		return m.sendErr
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// linkToken extracts the token query parameter from a mailed link.
+func linkToken(t *testing.T, body string) string {
+	t.Helper()
+	i := strings.Index(body, "token=")
+	if i < 0 {
+		t.Fatalf("no token in message body: %q", body)
+	}
+	return strings.Fields(body[i+len("token="):])[0]
+}
+
+func newTestEmailChangeService(t *testing.T) (*EmailChangeService, *mockEmailChangeRepo, *mockMailer, *mockAuditRepo) {
+	t.Helper()
+	hash, err := encrypt.NewPasswordHasher("test-pepper").HashPassword("Password1!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := newMockEmailChangeRepo("old@example.com", hash)
+	mail := &mockMailer{}
+	audit := &mockAuditRepo{}
+	svc := NewEmailChangeService(repo, audit, "test-pepper", mail, "http://app.test", time.Hour, 24*time.Hour)
+	return svc, repo, mail, audit
+}
+
+func TestEmailChangeService_Request(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	otherID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name      string
+		callerID  uuid.UUID
+		data      model.ChangeEmail
+		taken     bool
+		expectErr error
+	}{
+		{name: "success", callerID: userID, data: model.ChangeEmail{NewEmail: "new@example.com", Password: "Password1!"}},
+		{name: "other user", callerID: otherID, data: model.ChangeEmail{NewEmail: "new@example.com", Password: "Password1!"}, expectErr: model.ErrForbidden},
+		{name: "wrong password", callerID: userID, data: model.ChangeEmail{NewEmail: "new@example.com", Password: "wrong"}, expectErr: model.ErrUnauthorized},
+		{name: "email taken", callerID: userID, data: model.ChangeEmail{NewEmail: "new@example.com", Password: "Password1!"}, taken: true, expectErr: model.ErrAlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, mail, _ := newTestEmailChangeService(t)
+			repo.taken["new@example.com"] = tt.taken
+
+			err := svc.Request(context.Background(), userID, &tt.data, tt.callerID)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(mail.sent) != 0 {
+					t.Errorf("expected no mail, got %d", len(mail.sent))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(mail.sent) != 2 || mail.sent[0].To != "new@example.com" || mail.sent[1].To != "old@example.com" {
+				t.Fatalf("expected confirmation to new and notice to old address, got %+v", mail.sent)
+			}
+			if strings.Contains(mail.sent[1].Body, "token=") {
+				t.Error("notice to the old address must not contain the confirmation token")
+			}
+		})
+	}
+
+	t.Run("same email", func(t *testing.T) {
+		svc, _, _, _ := newTestEmailChangeService(t)
+		err := svc.Request(context.Background(), userID, &model.ChangeEmail{NewEmail: "old@example.com", Password: "Password1!"}, userID)
+		var verrs model.ValidationErrors
+		if !errors.As(err, &verrs) {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+	})
+}
+
+func TestEmailChangeService_ConfirmAndRevert(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	svc, repo, mail, audit := newTestEmailChangeService(t)
+	ctx := context.Background()
+
+	if err := svc.Request(ctx, userID, &model.ChangeEmail{NewEmail: "new@example.com", Password: "Password1!"}, userID); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	confirmToken := linkToken(t, mail.sent[0].Body)
+
+	if err := svc.Confirm(ctx, confirmToken); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if repo.creds.Email != "new@example.com" {
+		t.Fatalf("expected email to change, got %s", repo.creds.Email)
+	}
+	if err := svc.Confirm(ctx, confirmToken); !errors.Is(err, model.ErrConflict) {
+		t.Fatalf("expected conflict on second confirm, got %v", err)
+	}
+
+	last := mail.sent[len(mail.sent)-1]
+	if last.To != "old@example.com" {
+		t.Fatalf("expected revert link to old address, got %s", last.To)
+	}
+	revertToken := linkToken(t, last.Body)
+	if revertToken == confirmToken {
+		t.Fatal("revert token must differ from confirmation token")
+	}
+
+	if err := svc.Revert(ctx, revertToken); err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if repo.creds.Email != "old@example.com" {
+		t.Fatalf("expected email to be reverted, got %s", repo.creds.Email)
+	}
+	if err := svc.Revert(ctx, revertToken); !errors.Is(err, model.ErrConflict) {
+		t.Fatalf("expected conflict on second revert, got %v", err)
+	}
+
+	want := []string{model.AuditEmailChangeRequested, model.AuditEmailChanged, model.AuditEmailReverted}
+	if len(audit.entries) != len(want) {
+		t.Fatalf("expected %d audit entries, got %d", len(want), len(audit.entries))
+	}
+	for i, a := range want {
+		if audit.entries[i].Action != a {
+			t.Errorf("audit entry %d: expected %s, got %s", i, a, audit.entries[i].Action)
+		}
+	}
+}
+
+func TestEmailChangeService_Expired(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	svc, repo, _, _ := newTestEmailChangeService(t)
+	ctx := context.Background()
+
+	if err := svc.Confirm(ctx, "unknown"); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	token := "expired-token"
+	repo.requests[userID] = &model.EmailChangeRequest{
+		ID:        userID,
+		UserID:    userID,
+		OldEmail:  "old@example.com",
+		NewEmail:  "new@example.com",
+		TokenHash: encrypt.HashToken(token),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	if err := svc.Confirm(ctx, token); !errors.Is(err, model.ErrAlreadyDeleted) {
+		t.Fatalf("expected expired error, got %v", err)
+	}
+	if repo.creds.Email != "old@example.com" {
+		t.Fatal("expired token must not change the email")
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/blob"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/google/uuid"
+)
+
+type ErasureService struct {
+	repo       repository.ErasureRepository
+	audit      repository.AuditRepository
+	avatars    blob.Store
+	signingKey string
+}
+
+func NewErasureService(repo repository.ErasureRepository, audit repository.AuditRepository, avatars blob.Store, signingKey string) *ErasureService {
+	return &ErasureService{
+		repo:       repo,
+		audit:      audit,
+		avatars:    avatars,
+		signingKey: signingKey,
+	}
+}
+
+// pseudonym derives the replacement identifier for a user. It is keyed so it
+// cannot be linked back to the user id without the signing key, and
+// deterministic so a re-run writes exactly the same values.
+func (s *ErasureService) pseudonym(userID uuid.UUID) string {
+	return encrypt.Sign(s.signingKey, "pseudonym|"+userID.String())[:32]
+}
+
+// Erase irreversibly replaces the personal data of a user, and of the patient
+// records linked to them, with pseudonyms and returns the signed certificate.
+// Erasing an already erased user returns the original certificate; created
+// reports whether this call did the erasure.
+func (s *ErasureService) Erase(ctx context.Context, userID uuid.UUID, callerID uuid.UUID, callerRole string) (*model.ErasureResult, bool, error) {
+	if !isAdmin(callerRole) {
+		return nil, false, model.ErrForbidden
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	patientIDs, err := s.repo.LinkedPatients(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	erasedFields := slices.Clone(model.ErasedUserFields)
+	if len(patientIDs) > 0 {
+		erasedFields = append(erasedFields, model.ErasedPatientFields...)
+	}
+
+	pseudonym := s.pseudonym(userID)
+	cert := model.ErasureCertificate{
+		ID:           id,
+		UserID:       userID,
+		Pseudonym:    pseudonym,
+		ErasedFields: erasedFields,
+		RequestedBy:  callerID,
+		// Postgres stores microseconds; truncate so the signed payload
+		// matches what is read back.
+		ErasedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	cert.Signature = encrypt.Sign(s.signingKey, cert.SigningPayload())
+
+	stored, created, err := s.repo.Erase(ctx, cert, "erased-"+pseudonym+"@erased.invalid")
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, false, fmt.Errorf("user %w", err)
+		}
+		return nil, false, err
+	}
+
+	// The avatar and export archives live outside the database. Deleting
+	// them on every call means a retry after a failure here finishes the job.
+	if err := s.avatars.Delete(ctx, model.AvatarKey(userID)); err != nil {
+		return nil, false, fmt.Errorf("delete avatar: %w", err)
+	}
+	if err := s.deleteExports(ctx, userID); err != nil {
+		return nil, false, fmt.Errorf("delete exports: %w", err)
+	}
+
+	if created {
+		details := map[string]any{"certificate_id": stored.ID}
+		if len(patientIDs) > 0 {
+			details["patient_ids"] = patientIDs
+		}
+		recordAudit(ctx, s.audit, &callerID, model.AuditUserErase, "user", userID, details)
+	}
+
+	return s.result(stored), created, nil
+}
+
+// deleteExports deletes the archives of the user's data exports, which the
+// erasure expired.
+func (s *ErasureService) deleteExports(ctx context.Context, userID uuid.UUID) error {
+	paths, err := s.repo.ExportFiles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return s.repo.ClearExportFiles(ctx, userID)
+}
+
+func (s *ErasureService) GetCertificate(ctx context.Context, userID uuid.UUID, callerRole string) (*model.ErasureResult, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	cert, err := s.repo.GetCertificate(ctx, userID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("erasure certificate %w", err)
+		}
+		return nil, err
+	}
+
+	return s.result(cert), nil
+}
+
+func (s *ErasureService) result(cert *model.ErasureCertificate) *model.ErasureResult {
+	return &model.ErasureResult{
+		Certificate: *cert,
+		Valid:       encrypt.VerifySignature(s.signingKey, cert.SigningPayload(), cert.Signature),
+	}
+}
@@ -0,0 +1,396 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+type mockErasureRepo struct {
+	eraseFunc          func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error)
+	getCertificateFunc func(ctx context.Context, userID uuid.UUID) (*model.ErasureCertificate, error)
+	linkedPatientsFunc func(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	exportFilesFunc    func(ctx context.Context, userID uuid.UUID) ([]string, error)
+	clearExportsFunc   func(ctx context.Context, userID uuid.UUID) error
+}
+
+func (m *mockErasureRepo) Erase(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+	if m.eraseFunc != nil {
+		return m.eraseFunc(ctx, cert, email)
+	}
+	return &cert, true, nil
+}
+
+func (m *mockErasureRepo) LinkedPatients(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	if m.linkedPatientsFunc != nil {
+		return m.linkedPatientsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockErasureRepo) GetCertificate(ctx context.Context, userID uuid.UUID) (*model.ErasureCertificate, error) {
+	if m.getCertificateFunc != nil {
+		return m.getCertificateFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockErasureRepo) ExportFiles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if m.exportFilesFunc != nil {
+		return m.exportFilesFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockErasureRepo) ClearExportFiles(ctx context.Context, userID uuid.UUID) error {
+	if m.clearExportsFunc != nil {
+		return m.clearExportsFunc(ctx, userID)
+	}
+	return nil
+}
+
+func TestErasureService_Erase(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name          string
+		callerRole    string
+		eraseFunc     func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error)
+		expectErr     error
+		expectCreated bool
+		expectAudit   int
+	}{
+		{
+			name:          "success - admin",
+			callerRole:    "admin",
+			expectCreated: true,
+			expectAudit:   1,
+		},
+		{
+			name:       "already erased returns existing certificate",
+			callerRole: "admin",
+			eraseFunc: func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+				return &cert, false, nil
+			},
+			expectCreated: false,
+			expectAudit:   0,
+		},
+		{
+			name:       "forbidden - regular user",
+			callerRole: "user",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name:       "user not found",
+			callerRole: "admin",
+			eraseFunc: func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+				return nil, false, model.ErrNotFound
+			},
+			expectErr: model.ErrNotFound,
+		},
+		{
+			name:       "repo error",
+			callerRole: "admin",
+			eraseFunc: func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+				return nil, false, errRepo
+			},
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &mockAuditRepo{}
+			service := NewErasureService(&mockErasureRepo{eraseFunc: tt.eraseFunc}, audit, newMockBlobStore(), "test-erasure-key")
+
+			result, created, err := service.Erase(context.Background(), userID, adminID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if created != tt.expectCreated {
+				t.Errorf("got created %v want %v", created, tt.expectCreated)
+			}
+			if !result.Valid {
+				t.Error("expected certificate signature to verify")
+			}
+			if result.Certificate.UserID != userID || result.Certificate.RequestedBy != adminID {
+				t.Errorf("unexpected certificate %+v", result.Certificate)
+			}
+			if len(audit.entries) != tt.expectAudit {
+				t.Errorf("got %d audit entries want %d", len(audit.entries), tt.expectAudit)
+			}
+		})
+	}
+}
+
+func TestErasureService_Erase_Deterministic(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+
+	var pseudonyms, emails []string
+	repo := &mockErasureRepo{
+		eraseFunc: func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+			pseudonyms = append(pseudonyms, cert.Pseudonym)
+			emails = append(emails, email)
+			return &cert, true, nil
+		},
+	}
+	service := NewErasureService(repo, &mockAuditRepo{}, newMockBlobStore(), "test-erasure-key")
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := service.Erase(context.Background(), userID, adminID, "admin"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if pseudonyms[0] != pseudonyms[1] || emails[0] != emails[1] {
+		t.Errorf("expected identical pseudonyms across runs, got %v and %v", pseudonyms, emails)
+	}
+	if strings.Contains(pseudonyms[0], userID.String()) {
+		t.Error("pseudonym must not contain the user id")
+	}
+
+	other := NewErasureService(repo, &mockAuditRepo{}, newMockBlobStore(), "other-key")
+	if _, _, err := other.Erase(context.Background(), userID, adminID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pseudonyms[2] == pseudonyms[0] {
+		t.Error("pseudonym should depend on the signing key")
+	}
+}
+
+func TestErasureService_Erase_LinkedPatients(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name               string
+		linkedPatientsFunc func(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+		expectErr          error
+		expectPatient      bool
+	}{
+		{
+			name: "no linked patient",
+		},
+		{
+			name: "linked patient",
+			linkedPatientsFunc: func(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+				return []uuid.UUID{patientID}, nil
+			},
+			expectPatient: true,
+		},
+		{
+			name: "repo error",
+			linkedPatientsFunc: func(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+				return nil, errRepo
+			},
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			erased := false
+			repo := &mockErasureRepo{
+				linkedPatientsFunc: tt.linkedPatientsFunc,
+				eraseFunc: func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+					erased = true
+					return &cert, true, nil
+				},
+			}
+			audit := &mockAuditRepo{}
+			service := NewErasureService(repo, audit, newMockBlobStore(), "test-erasure-key")
+
+			result, _, err := service.Erase(context.Background(), userID, adminID, "admin")
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if erased {
+					t.Error("nothing should be erased when the lookup fails")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.Valid {
+				t.Error("expected certificate signature to verify")
+			}
+			fields := result.Certificate.ErasedFields
+			for _, f := range model.ErasedPatientFields {
+				if slices.Contains(fields, f) != tt.expectPatient {
+					t.Errorf("erased fields %v: %s listed = %v, want %v", fields, f, !tt.expectPatient, tt.expectPatient)
+				}
+			}
+			_, logged := audit.entries[0].Details["patient_ids"]
+			if logged != tt.expectPatient {
+				t.Errorf("audit details %v: patient_ids logged = %v, want %v", audit.entries[0].Details, logged, tt.expectPatient)
+			}
+		})
+	}
+}
+
+func TestErasureService_Erase_Exports(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name            string
+		created         bool
+		missing         bool
+		exportFilesErr  error
+		clearExportsErr error
+		expectErr       error
+		expectRemoved   bool
+		expectCleared   bool
+	}{
+		{name: "archive deleted", created: true, expectRemoved: true, expectCleared: true},
+		// A retry finishes deleting archives a failed call left behind.
+		{name: "archive left by an earlier call", expectRemoved: true, expectCleared: true},
+		{name: "archive already gone", created: true, missing: true, expectCleared: true},
+		{name: "repo error listing archives", created: true, exportFilesErr: errRepo, expectErr: errRepo},
+		{name: "repo error clearing archives", created: true, clearExportsErr: errRepo, expectErr: errRepo, expectRemoved: true, expectCleared: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export.zip")
+			if !tt.missing {
+				if err := os.WriteFile(path, []byte("archive"), 0o600); err != nil {
+					t.Fatalf("write archive: %v", err)
+				}
+			}
+
+			cleared := false
+			repo := &mockErasureRepo{
+				eraseFunc: func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+					return &cert, tt.created, nil
+				},
+				exportFilesFunc: func(ctx context.Context, id uuid.UUID) ([]string, error) {
+					if id != userID {
+						t.Errorf("listed the archives of %s, want %s", id, userID)
+					}
+					return []string{path}, tt.exportFilesErr
+				},
+				clearExportsFunc: func(ctx context.Context, id uuid.UUID) error {
+					cleared = true
+					return tt.clearExportsErr
+				},
+			}
+			service := NewErasureService(repo, &mockAuditRepo{}, newMockBlobStore(), "test-erasure-key")
+
+			_, _, err := service.Erase(context.Background(), userID, adminID, "admin")
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := os.Stat(path); tt.expectRemoved && !errors.Is(err, os.ErrNotExist) {
+				t.Error("expected the archive to be deleted")
+			}
+			if cleared != tt.expectCleared {
+				t.Errorf("cleared = %v, want %v", cleared, tt.expectCleared)
+			}
+		})
+	}
+}
+
+func TestErasureService_GetCertificate(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+
+	var stored model.ErasureCertificate
+	repo := &mockErasureRepo{
+		eraseFunc: func(ctx context.Context, cert model.ErasureCertificate, email string) (*model.ErasureCertificate, bool, error) {
+			stored = cert
+			return &cert, true, nil
+		},
+	}
+	service := NewErasureService(repo, &mockAuditRepo{}, newMockBlobStore(), "test-erasure-key")
+	if _, _, err := service.Erase(context.Background(), userID, adminID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tampered := stored
+	tampered.ErasedFields = []string{"first_name"}
+
+	tests := []struct {
+		name        string
+		callerRole  string
+		cert        *model.ErasureCertificate
+		certErr     error
+		expectErr   error
+		expectValid bool
+	}{
+		{
+			name:        "valid certificate",
+			callerRole:  "admin",
+			cert:        &stored,
+			expectValid: true,
+		},
+		{
+			name:        "tampered certificate",
+			callerRole:  "admin",
+			cert:        &tampered,
+			expectValid: false,
+		},
+		{
+			name:       "forbidden - regular user",
+			callerRole: "user",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name:       "not erased",
+			callerRole: "admin",
+			certErr:    model.ErrNotFound,
+			expectErr:  model.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.getCertificateFunc = func(ctx context.Context, id uuid.UUID) (*model.ErasureCertificate, error) {
+				return tt.cert, tt.certErr
+			}
+
+			result, err := service.GetCertificate(context.Background(), userID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Valid != tt.expectValid {
+				t.Errorf("got valid %v want %v", result.Valid, tt.expectValid)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/google/uuid"
+)
+
+const (
+	// exportRateWindow is the window over which ExportService.limit applies.
+	exportRateWindow = 24 * time.Hour
+	// exportStaleAfter is how long a running export may take before another
+	// worker picks it up again.
+	exportStaleAfter = 15 * time.Minute
+)
+
+type ExportService struct {
+	repo    repository.ExportRepository
+	dir     string
+	linkTTL time.Duration
+	limit   int
+}
+
+func NewExportService(repo repository.ExportRepository, dir string, linkTTL time.Duration, limit int) *ExportService {
+	return &ExportService{
+		repo:    repo,
+		dir:     dir,
+		linkTTL: linkTTL,
+		limit:   limit,
+	}
+}
+
+// Request queues an export of the caller's data. At most limit exports may
+// be requested per 24 hours. The download link is returned straight away and
+// works once a worker has built the archive.
+func (s *ExportService) Request(ctx context.Context, callerID uuid.UUID) (*model.DataExportRequested, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	token, err := encrypt.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	export := model.DataExport{
+		ID:        id,
+		UserID:    callerID,
+		Status:    model.ExportPending,
+		CreatedAt: time.Now(),
+		TokenHash: encrypt.HashToken(token),
+	}
+
+	if err := s.repo.CreateWithinLimit(ctx, export, time.Now().Add(-exportRateWindow), s.limit); err != nil {
+		if errors.Is(err, model.ErrTooManyRequests) {
+			return nil, fmt.Errorf("export limit of %d per day reached: %w", s.limit, err)
+		}
+		return nil, err
+	}
+
+	return &model.DataExportRequested{
+		Export:      export,
+		DownloadURL: fmt.Sprintf("/api/v1/exports/%s/download?token=%s", id, token),
+	}, nil
+}
+
+func (s *ExportService) List(ctx context.Context, callerID uuid.UUID) ([]model.DataExport, error) {
+	exports, err := s.repo.ListByUser(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+	if exports == nil {
+		exports = []model.DataExport{}
+	}
+	return exports, nil
+}
+
+// GetByID returns one of the caller's exports. Exports belonging to someone
+// else are reported as not found.
+func (s *ExportService) GetByID(ctx context.Context, id uuid.UUID, callerID uuid.UUID) (*model.DataExport, error) {
+	export, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("export %w", err)
+		}
+		return nil, err
+	}
+	if export.UserID != callerID {
+		return nil, fmt.Errorf("export %w", model.ErrNotFound)
+	}
+	return export, nil
+}
+
+// Open checks token against the export's download token and opens the
+// archive. The caller must close the returned reader.
+func (s *ExportService) Open(ctx context.Context, id uuid.UUID, token string) (io.ReadCloser, *model.DataExport, error) {
+	export, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, nil, fmt.Errorf("export %w", err)
+		}
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(encrypt.HashToken(token)), []byte(export.TokenHash)) != 1 {
+		return nil, nil, fmt.Errorf("export %w", model.ErrNotFound)
+	}
+
+	switch export.Status {
+	case model.ExportReady:
+	case model.ExportExpired:
+		return nil, nil, fmt.Errorf("export link has expired: %w", model.ErrAlreadyDeleted)
+	case model.ExportFailed:
+		return nil, nil, fmt.Errorf("export failed: %w", model.ErrNotFound)
+	default:
+		return nil, nil, fmt.Errorf("export is not ready yet: %w", model.ErrConflict)
+	}
+
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		return nil, nil, fmt.Errorf("export link has expired: %w", model.ErrAlreadyDeleted)
+	}
+
+	f, err := os.Open(export.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, export, nil
+}
+
+// ProcessPending builds archives for queued exports until none are left and
+// returns how many were completed.
+func (s *ExportService) ProcessPending(ctx context.Context) (int, error) {
+	done := 0
+	for ctx.Err() == nil {
+		export, err := s.repo.ClaimPending(ctx, exportStaleAfter)
+		if err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return done, nil
+			}
+			return done, err
+		}
+
+		path, err := s.build(ctx, export)
+		if err != nil {
+			log.Printf("export %s: %v", export.ID, err)
+			if err := s.repo.MarkFailed(ctx, export.ID, err.Error()); err != nil {
+				return done, err
+			}
+			continue
+		}
+
+		if err := s.repo.MarkReady(ctx, export.ID, path, time.Now().Add(s.linkTTL)); err != nil {
+			if !errors.Is(err, model.ErrNotFound) {
+				return done, err
+			}
+			// The export was expired while it was built, as when its user
+			// is erased, so the archive must not stay behind.
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return done, err
+			}
+			continue
+		}
+		done++
+	}
+	return done, ctx.Err()
+}
+
+// CleanupExpired deletes the archives of exports whose link has expired.
+func (s *ExportService) CleanupExpired(ctx context.Context) (int, error) {
+	exports, err := s.repo.ListExpired(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range exports {
+		if err := os.Remove(e.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+		if err := s.repo.MarkExpired(ctx, e.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(exports), nil
+}
+
+// build writes the archive for export to a temporary file and renames it into
+// place, so a half-written archive is never served.
+func (s *ExportService) build(ctx context.Context, export *model.DataExport) (string, error) {
+	data, err := s.repo.GetPersonalData(ctx, export.UserID)
+	if err != nil {
+		return "", err
+	}
+	data.GeneratedAt = time.Now().UTC()
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, export.ID.String()+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeExportArchive(tmp, data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(s.dir, export.ID.String()+".zip")
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// writeExportArchive writes data as a ZIP with a JSON file per section and a
+// CSV alongside each tabular section.
+func writeExportArchive(w io.Writer, data *model.PersonalData) error {
+	zw := zip.NewWriter(w)
+
+	if err := writeZipJSON(zw, "profile.json", data.Profile); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "sessions.json", data.Sessions); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "audit_log.json", data.AuditLog); err != nil {
+		return err
+	}
+
+	p := data.Profile
+	if err := writeZipCSV(zw, "profile.csv",
+		[]string{
+			"id", "first_name", "last_name", "email", "phone", "date_of_birth", "sex", "gender", "preferred_language",
+			"address_line1", "address_line2", "address_city", "address_region", "address_postal_code", "address_country",
+			"role", "created_at", "updated_at",
+		},
+		[][]string{{
+			p.ID.String(), p.FirstName, p.LastName, p.Email, p.Phone, p.DateOfBirth, p.Sex, p.Gender, p.PreferredLanguage,
+			p.Address.Line1, p.Address.Line2, p.Address.City, p.Address.Region, p.Address.PostalCode, p.Address.Country,
+			p.Role, formatTime(p.CreatedAt), formatTime(p.UpdatedAt),
+		}},
+	); err != nil {
+		return err
+	}
+
+	sessions := make([][]string, 0, len(data.Sessions))
+	for _, s := range data.Sessions {
+		sessions = append(sessions, []string{s.ID.String(), formatTime(s.CreatedAt), formatTime(s.ExpiresAt), strconv.FormatBool(s.Revoked)})
+	}
+	if err := writeZipCSV(zw, "sessions.csv", []string{"id", "created_at", "expires_at", "revoked"}, sessions); err != nil {
+		return err
+	}
+
+	audit := make([][]string, 0, len(data.AuditLog))
+	for _, e := range data.AuditLog {
+		details, err := json.Marshal(e.Details)
+		if err != nil {
+			return err
+		}
+		audit = append(audit, []string{e.ID.String(), uuidOrEmpty(e.ActorID), e.Action, e.SubjectType, uuidOrEmpty(e.SubjectID), string(details), formatTime(e.CreatedAt)})
+	}
+	if err := writeZipCSV(zw, "audit_log.csv", []string{"id", "actor_id", "action", "subject_type", "subject_id", "details", "created_at"}, audit); err != nil {
+		return err
+	}
+
+	if err := writeZipJSON(zw, "patients.json", data.Patients); err != nil {
+		return err
+	}
+	if err := writePatientCSVs(zw, data.Patients); err != nil {
+		return err
+	}
+
+	if err := writeZipJSON(zw, "manifest.json", map[string]any{
+		"user_id":      data.Profile.ID,
+		"generated_at": data.GeneratedAt,
+	}); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writePatientCSVs writes a CSV per clinical section of the linked patient
+// records, with a patient_id column to tell the records apart.
+func writePatientCSVs(zw *zip.Writer, patients []model.ExportPatient) error {
+	var appointments, prescriptions, allergies, labResults [][]string
+	for _, p := range patients {
+		patientID := p.Patient.ID.String()
+		for _, a := range p.Appointments {
+			appointments = append(appointments, []string{
+				a.ID.String(), patientID, a.PractitionerName, formatTime(a.StartsAt), formatTime(a.EndsAt), a.Status,
+				stringOrEmpty(a.Service), stringOrEmpty(a.Reason),
+			})
+		}
+		for _, rx := range p.Prescriptions {
+			prescriptions = append(prescriptions, []string{
+				rx.ID.String(), patientID, rx.PrescriberName, rx.Drug, stringOrEmpty(rx.Strength), rx.Dose, rx.Route, rx.Frequency,
+				strconv.Itoa(rx.Quantity), strconv.Itoa(rx.RefillsRemaining), rx.Status, formatTime(rx.CreatedAt),
+			})
+		}
+		for _, a := range p.Allergies {
+			allergies = append(allergies, []string{
+				a.ID.String(), patientID, a.Allergen, stringOrEmpty(a.Reaction), a.Severity, a.Verification, formatTime(a.CreatedAt),
+			})
+		}
+		for _, o := range p.LabResults {
+			observedAt := ""
+			if o.ObservedAt != nil {
+				observedAt = formatTime(*o.ObservedAt)
+			}
+			labResults = append(labResults, []string{
+				o.ID.String(), patientID, o.Code, o.Name, stringOrEmpty(o.Value), stringOrEmpty(o.Units),
+				stringOrEmpty(o.ReferenceRange), stringOrEmpty(o.Interpretation), o.ResultStatus, observedAt,
+			})
+		}
+	}
+
+	if err := writeZipCSV(zw, "appointments.csv",
+		[]string{"id", "patient_id", "practitioner", "starts_at", "ends_at", "status", "service", "reason"}, appointments); err != nil {
+		return err
+	}
+	if err := writeZipCSV(zw, "prescriptions.csv",
+		[]string{"id", "patient_id", "prescriber", "drug", "strength", "dose", "route", "frequency", "quantity", "refills_remaining", "status", "created_at"},
+		prescriptions); err != nil {
+		return err
+	}
+	if err := writeZipCSV(zw, "allergies.csv",
+		[]string{"id", "patient_id", "allergen", "reaction", "severity", "verification", "created_at"}, allergies); err != nil {
+		return err
+	}
+	return writeZipCSV(zw, "lab_results.csv",
+		[]string{"id", "patient_id", "code", "name", "value", "units", "reference_range", "interpretation", "result_status", "observed_at"},
+		labResults)
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeZipCSV(zw *zip.Writer, name string, header []string, rows [][]string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func uuidOrEmpty(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
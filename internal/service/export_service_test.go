@@ -0,0 +1,500 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/google/uuid"
+)
+
+type mockExportRepo struct {
+	createWithinLimitFunc func(ctx context.Context, export model.DataExport, since time.Time, limit int) error
+	getByIDFunc           func(ctx context.Context, id uuid.UUID) (*model.DataExport, error)
+	listByUserFunc        func(ctx context.Context, userID uuid.UUID) ([]model.DataExport, error)
+	claimPendingFunc      func(ctx context.Context, staleAfter time.Duration) (*model.DataExport, error)
+	markReadyFunc         func(ctx context.Context, id uuid.UUID, filePath string, expiresAt time.Time) error
+	markFailedFunc        func(ctx context.Context, id uuid.UUID, reason string) error
+	listExpiredFunc       func(ctx context.Context, now time.Time) ([]model.DataExport, error)
+	markExpiredFunc       func(ctx context.Context, id uuid.UUID) error
+	getPersonalDataFunc   func(ctx context.Context, userID uuid.UUID) (*model.PersonalData, error)
+}
+
+func (m *mockExportRepo) CreateWithinLimit(ctx context.Context, export model.DataExport, since time.Time, limit int) error {
+	if m.createWithinLimitFunc != nil {
+		return m.createWithinLimitFunc(ctx, export, since, limit)
+	}
+	return nil
+}
+
+func (m *mockExportRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.DataExport, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(ctx, id)
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockExportRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.DataExport, error) {
+	if m.listByUserFunc != nil {
+		return m.listByUserFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockExportRepo) ClaimPending(ctx context.Context, staleAfter time.Duration) (*model.DataExport, error) {
+	if m.claimPendingFunc != nil {
+		return m.claimPendingFunc(ctx, staleAfter)
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockExportRepo) MarkReady(ctx context.Context, id uuid.UUID, filePath string, expiresAt time.Time) error {
+	if m.markReadyFunc != nil {
+		return m.markReadyFunc(ctx, id, filePath, expiresAt)
+	}
+	return nil
+}
+
+func (m *mockExportRepo) MarkFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	if m.markFailedFunc != nil {
+		return m.markFailedFunc(ctx, id, reason)
+	}
+	return nil
+}
+
+func (m *mockExportRepo) ListExpired(ctx context.Context, now time.Time) ([]model.DataExport, error) {
+	if m.listExpiredFunc != nil {
+		return m.listExpiredFunc(ctx, now)
+	}
+	return nil, nil
+}
+
+func (m *mockExportRepo) MarkExpired(ctx context.Context, id uuid.UUID) error {
+	if m.markExpiredFunc != nil {
+		return m.markExpiredFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockExportRepo) GetPersonalData(ctx context.Context, userID uuid.UUID) (*model.PersonalData, error) {
+	if m.getPersonalDataFunc != nil {
+		return m.getPersonalDataFunc(ctx, userID)
+	}
+	return nil, model.ErrNotFound
+}
+
+func TestExportService_Request(t *testing.T) {
+	userID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name      string
+		count     int
+		createErr error
+		expectErr error
+	}{
+		{
+			name:  "success",
+			count: 0,
+		},
+		{
+			name:      "rate limited",
+			count:     3,
+			expectErr: model.ErrTooManyRequests,
+		},
+		{
+			name:      "repo error",
+			createErr: errRepo,
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created model.DataExport
+			repo := &mockExportRepo{
+				createWithinLimitFunc: func(ctx context.Context, export model.DataExport, since time.Time, limit int) error {
+					if limit != 3 || time.Since(since) < 23*time.Hour {
+						t.Errorf("limited to %d since %v, want 3 in the last day", limit, since)
+					}
+					if tt.count >= limit {
+						return model.ErrTooManyRequests
+					}
+					created = export
+					return tt.createErr
+				},
+			}
+			service := NewExportService(repo, t.TempDir(), time.Hour, 3)
+
+			result, err := service.Request(context.Background(), userID)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if created.UserID != userID || created.Status != model.ExportPending {
+				t.Errorf("unexpected export %+v", created)
+			}
+
+			u, err := url.Parse(result.DownloadURL)
+			if err != nil {
+				t.Fatalf("invalid download url %q: %v", result.DownloadURL, err)
+			}
+			token := u.Query().Get("token")
+			if token == "" || encrypt.HashToken(token) != created.TokenHash {
+				t.Error("download token does not match the stored hash")
+			}
+		})
+	}
+}
+
+func TestExportService_GetByID(t *testing.T) {
+	ownerID, _ := uuid.NewV7()
+	otherID, _ := uuid.NewV7()
+	exportID, _ := uuid.NewV7()
+
+	repo := &mockExportRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.DataExport, error) {
+			return &model.DataExport{ID: exportID, UserID: ownerID, Status: model.ExportPending}, nil
+		},
+	}
+	service := NewExportService(repo, t.TempDir(), time.Hour, 3)
+
+	if _, err := service.GetByID(context.Background(), exportID, ownerID); err != nil {
+		t.Fatalf("owner: unexpected error: %v", err)
+	}
+	if _, err := service.GetByID(context.Background(), exportID, otherID); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("other user: expected not found, got %v", err)
+	}
+}
+
+func TestExportService_Open(t *testing.T) {
+	exportID, _ := uuid.NewV7()
+	path := filepath.Join(t.TempDir(), "export.zip")
+	if err := os.WriteFile(path, []byte("zip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	const token = "download-token"
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		export    model.DataExport
+		token     string
+		expectErr error
+	}{
+		{
+			name:   "ready",
+			export: model.DataExport{Status: model.ExportReady, ExpiresAt: &future, FilePath: path},
+			token:  token,
+		},
+		{
+			name:      "wrong token",
+			export:    model.DataExport{Status: model.ExportReady, ExpiresAt: &future, FilePath: path},
+			token:     "guess",
+			expectErr: model.ErrNotFound,
+		},
+		{
+			name:      "not ready",
+			export:    model.DataExport{Status: model.ExportPending},
+			token:     token,
+			expectErr: model.ErrConflict,
+		},
+		{
+			name:      "link expired",
+			export:    model.DataExport{Status: model.ExportReady, ExpiresAt: &past, FilePath: path},
+			token:     token,
+			expectErr: model.ErrAlreadyDeleted,
+		},
+		{
+			name:      "archive removed",
+			export:    model.DataExport{Status: model.ExportExpired},
+			token:     token,
+			expectErr: model.ErrAlreadyDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := tt.export
+			export.ID = exportID
+			export.TokenHash = encrypt.HashToken(token)
+			repo := &mockExportRepo{
+				getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.DataExport, error) {
+					return &export, nil
+				},
+			}
+			service := NewExportService(repo, t.TempDir(), time.Hour, 3)
+
+			rc, _, err := service.Open(context.Background(), exportID, tt.token)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer rc.Close()
+			b, _ := io.ReadAll(rc)
+			if string(b) != "zip" {
+				t.Errorf("got %q want %q", b, "zip")
+			}
+		})
+	}
+}
+
+func TestExportService_ProcessPending(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	exportID, _ := uuid.NewV7()
+	sessionID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	dir := t.TempDir()
+
+	data := &model.PersonalData{
+		Profile: model.ExportProfile{ID: userID, FirstName: "John", LastName: "Doe", Email: "johndoe@test.com", Role: "user"},
+		Sessions: []model.ExportSession{
+			{ID: sessionID, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)},
+		},
+		AuditLog: []model.AuditEntry{
+			{ID: exportID, Action: model.AuditUserRestore, SubjectType: "user", SubjectID: &userID, Details: map[string]any{"k": "v"}},
+		},
+		Patients: []model.ExportPatient{{
+			Patient: model.Patient{
+				ID: patientID, MRN: "MRN-000001", FirstName: "John", LastName: "Doe", DateOfBirth: "1980-04-12", UserID: &userID,
+				Identifiers: []model.PatientIdentifier{{System: "https://fhir.nhs.uk/Id/nhs-number", Value: "9434765919"}},
+			},
+			Appointments:  []model.Appointment{{ID: exportID, PatientID: patientID, Status: model.AppointmentBooked}},
+			Prescriptions: []model.Prescription{{ID: exportID, PatientID: patientID, Drug: "Amoxicillin"}},
+			Allergies:     []model.Allergy{{ID: exportID, PatientID: patientID, Allergen: "Penicillin"}},
+			LabResults:    []model.LabObservation{{ID: exportID, PatientID: &patientID, Code: "2345-7", Name: "Glucose"}},
+		}},
+	}
+
+	claimed := false
+	var readyPath string
+	repo := &mockExportRepo{
+		claimPendingFunc: func(ctx context.Context, staleAfter time.Duration) (*model.DataExport, error) {
+			if claimed {
+				return nil, model.ErrNotFound
+			}
+			claimed = true
+			return &model.DataExport{ID: exportID, UserID: userID, Status: model.ExportRunning}, nil
+		},
+		getPersonalDataFunc: func(ctx context.Context, id uuid.UUID) (*model.PersonalData, error) {
+			return data, nil
+		},
+		markReadyFunc: func(ctx context.Context, id uuid.UUID, filePath string, expiresAt time.Time) error {
+			readyPath = filePath
+			return nil
+		},
+	}
+	service := NewExportService(repo, dir, time.Hour, 3)
+
+	n, err := service.ProcessPending(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("got %d completed want 1", n)
+	}
+
+	zr, err := zip.OpenReader(readyPath)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer zr.Close()
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{
+		"profile.json", "profile.csv", "sessions.json", "sessions.csv", "audit_log.json", "audit_log.csv",
+		"patients.json", "appointments.csv", "prescriptions.csv", "allergies.csv", "lab_results.csv", "manifest.json",
+	} {
+		if files[name] == nil {
+			t.Errorf("archive is missing %s", name)
+		}
+	}
+
+	rc, _ := files["profile.json"].Open()
+	var profile model.ExportProfile
+	if err := json.NewDecoder(rc).Decode(&profile); err != nil {
+		t.Fatalf("decode profile.json: %v", err)
+	}
+	rc.Close()
+	if profile.Email != "johndoe@test.com" {
+		t.Errorf("got email %q", profile.Email)
+	}
+
+	rc, _ = files["sessions.csv"].Open()
+	records, err := csv.NewReader(rc).ReadAll()
+	rc.Close()
+	if err != nil {
+		t.Fatalf("read sessions.csv: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != sessionID.String() {
+		t.Errorf("unexpected sessions.csv %v", records)
+	}
+
+	rc, _ = files["patients.json"].Open()
+	var patients []model.ExportPatient
+	if err := json.NewDecoder(rc).Decode(&patients); err != nil {
+		t.Fatalf("decode patients.json: %v", err)
+	}
+	rc.Close()
+	if len(patients) != 1 || patients[0].Patient.MRN != "MRN-000001" || len(patients[0].Patient.Identifiers) != 1 {
+		t.Fatalf("unexpected patients.json %+v", patients)
+	}
+	if p := patients[0]; len(p.Appointments) != 1 || len(p.Prescriptions) != 1 || len(p.Allergies) != 1 || len(p.LabResults) != 1 {
+		t.Errorf("clinical records missing from patients.json: %+v", p)
+	}
+
+	for _, name := range []string{"appointments.csv", "prescriptions.csv", "allergies.csv", "lab_results.csv"} {
+		rc, _ = files[name].Open()
+		records, err := csv.NewReader(rc).ReadAll()
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if len(records) != 2 || records[1][1] != patientID.String() {
+			t.Errorf("unexpected %s %v", name, records)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+func TestExportService_ProcessPending_Failure(t *testing.T) {
+	exportID, _ := uuid.NewV7()
+
+	claimed := false
+	var failed uuid.UUID
+	repo := &mockExportRepo{
+		claimPendingFunc: func(ctx context.Context, staleAfter time.Duration) (*model.DataExport, error) {
+			if claimed {
+				return nil, model.ErrNotFound
+			}
+			claimed = true
+			return &model.DataExport{ID: exportID, Status: model.ExportRunning}, nil
+		},
+		getPersonalDataFunc: func(ctx context.Context, id uuid.UUID) (*model.PersonalData, error) {
+			return nil, errRepo
+		},
+		markFailedFunc: func(ctx context.Context, id uuid.UUID, reason string) error {
+			failed = id
+			return nil
+		},
+	}
+	service := NewExportService(repo, t.TempDir(), time.Hour, 3)
+
+	n, err := service.ProcessPending(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("got %d completed want 0", n)
+	}
+	if failed != exportID {
+		t.Error("expected export to be marked failed")
+	}
+}
+
+func TestExportService_ProcessPending_Expired(t *testing.T) {
+	exportID, _ := uuid.NewV7()
+	userID, _ := uuid.NewV7()
+	dir := t.TempDir()
+
+	claimed := false
+	var readyPath string
+	repo := &mockExportRepo{
+		claimPendingFunc: func(ctx context.Context, staleAfter time.Duration) (*model.DataExport, error) {
+			if claimed {
+				return nil, model.ErrNotFound
+			}
+			claimed = true
+			return &model.DataExport{ID: exportID, UserID: userID, Status: model.ExportRunning}, nil
+		},
+		getPersonalDataFunc: func(ctx context.Context, id uuid.UUID) (*model.PersonalData, error) {
+			return &model.PersonalData{Profile: model.ExportProfile{ID: userID}}, nil
+		},
+		// The user was erased while the archive was built.
+		markReadyFunc: func(ctx context.Context, id uuid.UUID, filePath string, expiresAt time.Time) error {
+			readyPath = filePath
+			return model.ErrNotFound
+		},
+	}
+	service := NewExportService(repo, dir, time.Hour, 3)
+
+	n, err := service.ProcessPending(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("got %d completed want 0", n)
+	}
+	if readyPath == "" {
+		t.Fatal("expected an archive to be built")
+	}
+	if _, err := os.Stat(readyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Error("expected the archive of the expired export to be deleted")
+	}
+}
+
+func TestExportService_CleanupExpired(t *testing.T) {
+	exportID, _ := uuid.NewV7()
+	path := filepath.Join(t.TempDir(), "export.zip")
+	if err := os.WriteFile(path, []byte("zip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var expired uuid.UUID
+	repo := &mockExportRepo{
+		listExpiredFunc: func(ctx context.Context, now time.Time) ([]model.DataExport, error) {
+			return []model.DataExport{{ID: exportID, FilePath: path}}, nil
+		},
+		markExpiredFunc: func(ctx context.Context, id uuid.UUID) error {
+			expired = id
+			return nil
+		},
+	}
+	service := NewExportService(repo, t.TempDir(), time.Hour, 3)
+
+	n, err := service.CleanupExpired(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 || expired != exportID {
+		t.Errorf("expected export to be marked expired")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("expected archive to be removed")
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/google/uuid"
+)
+
+type ImportService struct {
+	repo      repository.ImportRepository
+	audit     repository.AuditRepository
+	hasher    *encrypt.PasswordHasher
+	batchSize int
+	maxRows   int
+}
+
+// NewImportService returns a service that writes imports in transactions of
+// batchSize rows. maxRows caps the rows of an import made through the API; the
+// command line import has no cap.
+func NewImportService(repo repository.ImportRepository, audit repository.AuditRepository, pepper string, batchSize, maxRows int) *ImportService {
+	return &ImportService{
+		repo:      repo,
+		audit:     audit,
+		hasher:    encrypt.NewPasswordHasher(pepper),
+		batchSize: batchSize,
+		maxRows:   maxRows,
+	}
+}
+
+// Import imports users from a CSV file on behalf of an admin.
+func (s *ImportService) Import(ctx context.Context, r io.Reader, dryRun bool, callerID uuid.UUID, callerRole string) (*model.ImportReport, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	rows, err := model.ParseImportCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	if s.maxRows > 0 && len(rows) > s.maxRows {
+		return nil, fmt.Errorf("import is limited to %d rows, use the command line import for larger files: %w", s.maxRows, model.ErrPayloadTooLarge)
+	}
+
+	return s.importRows(ctx, rows, dryRun, &callerID)
+}
+
+// ImportFile imports users from a CSV file as the system, without a row
+// limit. It backs the command line import.
+func (s *ImportService) ImportFile(ctx context.Context, r io.Reader, dryRun bool) (*model.ImportReport, error) {
+	rows, err := model.ParseImportCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.importRows(ctx, rows, dryRun, nil)
+}
+
+// importRows validates every row before writing any of them. Valid rows are
+// upserted by email: new addresses are created and existing accounts have
+// their names updated but keep their password, so running the same file
+// twice changes nothing the second time. Batches are committed one by one;
+// if one fails, the earlier ones stay written and the import can be rerun.
+func (s *ImportService) importRows(ctx context.Context, rows []model.ImportRow, dryRun bool, actorID *uuid.UUID) (*model.ImportReport, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	report := &model.ImportReport{
+		ID:     id,
+		DryRun: dryRun,
+		Total:  len(rows),
+		Rows:   make([]model.ImportRowResult, len(rows)),
+	}
+
+	seen := map[string]int{}
+	var emails []string
+	for i := range rows {
+		row := &rows[i]
+		result := &report.Rows[i]
+		result.Line = row.Line
+
+		errs := model.ValidateImportRow(&row.User)
+		result.Email = row.User.Email
+		if first, dup := seen[row.User.Email]; dup && row.User.Email != "" {
+			errs = append(errs, model.FieldError{Field: "email", Message: fmt.Sprintf("duplicate of line %d", first)})
+		}
+		if len(errs) > 0 {
+			result.Outcome = model.ImportInvalid
+			result.Errors = errs
+			continue
+		}
+
+		seen[row.User.Email] = row.Line
+		emails = append(emails, row.User.Email)
+	}
+
+	existing := map[string]bool{}
+	if len(emails) > 0 {
+		if existing, err = s.repo.ExistingEmails(ctx, emails); err != nil {
+			return nil, err
+		}
+	}
+
+	// pending holds the indexes of the rows to write.
+	var pending []int
+	users := make([]model.ImportUser, len(rows))
+	for i, row := range rows {
+		result := &report.Rows[i]
+		if result.Outcome == model.ImportInvalid {
+			continue
+		}
+
+		skip, exists := existing[row.User.Email]
+		switch {
+		case exists && skip:
+			result.Outcome = model.ImportSkipped
+			continue
+		case exists:
+			result.Outcome = model.ImportUpdated
+		default:
+			result.Outcome = model.ImportCreated
+		}
+
+		users[i] = model.ImportUser{
+			FirstName: row.User.FirstName,
+			LastName:  row.User.LastName,
+			Email:     row.User.Email,
+		}
+		pending = append(pending, i)
+	}
+
+	if !dryRun {
+		if err := s.hashNewPasswords(rows, users, report.Rows, pending); err != nil {
+			return nil, err
+		}
+
+		var written model.ImportReport
+		for start := 0; start < len(pending); start += s.batchSize {
+			batch := pending[start:min(start+s.batchSize, len(pending))]
+
+			batchUsers := make([]model.ImportUser, len(batch))
+			for j, i := range batch {
+				batchUsers[j] = users[i]
+			}
+
+			outcomes, err := s.repo.UpsertBatch(ctx, batchUsers)
+			if err != nil {
+				s.recordImport(ctx, report.ID, report.Total, &written, actorID)
+				return nil, fmt.Errorf("import stopped at line %d; earlier rows were imported: %w", rows[batch[0]].Line, err)
+			}
+			for j, i := range batch {
+				report.Rows[i].Outcome = outcomes[j]
+				written.Count(outcomes[j])
+			}
+		}
+
+		s.recordImport(ctx, report.ID, report.Total, &written, actorID)
+	}
+
+	for _, result := range report.Rows {
+		report.Count(result.Outcome)
+	}
+
+	return report, nil
+}
+
+// hashNewPasswords hashes the passwords of the rows to be created. Hashing
+// is deliberately slow, so it is spread over all CPUs.
+func (s *ImportService) hashNewPasswords(rows []model.ImportRow, users []model.ImportUser, results []model.ImportRowResult, pending []int) error {
+	var toHash []int
+	for _, i := range pending {
+		if results[i].Outcome == model.ImportCreated {
+			toHash = append(toHash, i)
+		}
+	}
+
+	jobs := make(chan int)
+	errs := make(chan error, len(toHash))
+	var wg sync.WaitGroup
+
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hash, err := s.hasher.HashPassword(rows[i].User.Password)
+				if err != nil {
+					errs <- fmt.Errorf("failed to hash password: %w", err)
+					continue
+				}
+				id, err := uuid.NewV7()
+				if err != nil {
+					errs <- fmt.Errorf("failed to generate uuid: %w", err)
+					continue
+				}
+				users[i].ID = id
+				users[i].PasswordHash = hash
+			}
+		}()
+	}
+
+	for _, i := range toHash {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+func (s *ImportService) recordImport(ctx context.Context, id uuid.UUID, total int, written *model.ImportReport, actorID *uuid.UUID) {
+	recordAudit(ctx, s.audit, actorID, model.AuditUserImport, "user_import", id, map[string]any{
+		"total":   total,
+		"created": written.Created,
+		"updated": written.Updated,
+		"skipped": written.Skipped,
+	})
+}
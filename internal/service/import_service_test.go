@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+// mockImportRepo holds users by email, mapped to whether they are deleted.
+type mockImportRepo struct {
+	users     map[string]bool
+	names     map[string]string
+	batches   [][]model.ImportUser
+	failBatch int // 1-based index of a batch that fails, 0 for none
+}
+
+func newMockImportRepo() *mockImportRepo {
+	return &mockImportRepo{users: map[string]bool{}, names: map[string]string{}}
+}
+
+func (m *mockImportRepo) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	existing := map[string]bool{}
+	for _, e := range emails {
+		if deleted, ok := m.users[e]; ok {
+			existing[e] = deleted
+		}
+	}
+	return existing, nil
+}
+
+func (m *mockImportRepo) UpsertBatch(ctx context.Context, users []model.ImportUser) ([]string, error) {
+	m.batches = append(m.batches, users)
+	if len(m.batches) == m.failBatch {
+		return nil, errRepo
+	}
+
+	outcomes := make([]string, len(users))
+	for i, u := range users {
+		deleted, exists := m.users[u.Email]
+		switch {
+		case exists && deleted:
+			outcomes[i] = model.ImportSkipped
+		case exists:
+			m.names[u.Email] = u.FirstName
+			outcomes[i] = model.ImportUpdated
+		case u.PasswordHash == "":
+			outcomes[i] = model.ImportSkipped
+		default:
+			m.users[u.Email] = false
+			m.names[u.Email] = u.FirstName
+			outcomes[i] = model.ImportCreated
+		}
+	}
+	return outcomes, nil
+}
+
+const importCSV = `first_name,last_name,email,password
+Jane,Doe,jane@example.com,Password1!
+John,Doe,john@example.com,Password1!
+Bad1,Doe,bad@example.com,Password1!
+Gone,Doe,gone@example.com,Password1!
+Jo,Doe,JANE@example.com,Password1!
+Ann,Lee,ann@example.com,Password1!
+`
+
+func TestImportService_Import(t *testing.T) {
+	adminID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name      string
+		dryRun    bool
+		role      string
+		maxRows   int
+		failBatch int
+		expectErr error
+	}{
+		{name: "dry run", dryRun: true, role: "admin"},
+		{name: "import", role: "admin"},
+		{name: "user cannot import", role: "user", expectErr: model.ErrForbidden},
+		{name: "too many rows", role: "admin", maxRows: 5, expectErr: model.ErrPayloadTooLarge},
+		{name: "batch fails", role: "admin", failBatch: 2, expectErr: errRepo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockImportRepo()
+			repo.users["john@example.com"] = false
+			repo.users["gone@example.com"] = true
+			repo.failBatch = tt.failBatch
+			audit := &mockAuditRepo{}
+			svc := NewImportService(repo, audit, "test-pepper", 1, tt.maxRows)
+
+			report, err := svc.Import(context.Background(), strings.NewReader(importCSV), tt.dryRun, adminID, tt.role)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if tt.failBatch > 0 {
+					if !strings.Contains(err.Error(), "line 3") {
+						t.Errorf("expected error to name line 3, got %v", err)
+					}
+					if len(audit.entries) != 1 || audit.entries[0].Details["created"] != 1 {
+						t.Errorf("expected the written batch to be audited, got %+v", audit.entries)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := []struct {
+				line    int
+				outcome string
+				field   string
+			}{
+				{2, model.ImportCreated, ""},
+				{3, model.ImportUpdated, ""},
+				{4, model.ImportInvalid, "first_name"},
+				{5, model.ImportSkipped, ""},
+				{6, model.ImportInvalid, "email"},
+				{7, model.ImportCreated, ""},
+			}
+			if len(report.Rows) != len(want) {
+				t.Fatalf("expected %d rows, got %d", len(want), len(report.Rows))
+			}
+			for i, w := range want {
+				got := report.Rows[i]
+				if got.Line != w.line || got.Outcome != w.outcome {
+					t.Errorf("row %d: expected line %d %s, got line %d %s", i, w.line, w.outcome, got.Line, got.Outcome)
+				}
+				if w.field != "" && (len(got.Errors) == 0 || got.Errors[0].Field != w.field) {
+					t.Errorf("row %d: expected %s error, got %+v", i, w.field, got.Errors)
+				}
+			}
+			if report.Total != 6 || report.Created != 2 || report.Updated != 1 || report.Skipped != 1 || report.Invalid != 2 {
+				t.Errorf("unexpected totals: %+v", report)
+			}
+
+			if tt.dryRun {
+				if len(repo.batches) != 0 || len(audit.entries) != 0 {
+					t.Errorf("dry run wrote %d batches and %d audit entries", len(repo.batches), len(audit.entries))
+				}
+				return
+			}
+
+			if len(repo.batches) != 3 {
+				t.Errorf("expected 3 batches of one row, got %d", len(repo.batches))
+			}
+			for _, batch := range repo.batches {
+				u := batch[0]
+				if u.Email == "john@example.com" && u.PasswordHash != "" {
+					t.Error("existing user's password should not be rehashed")
+				}
+				if u.Email != "john@example.com" && (u.PasswordHash == "" || u.ID == uuid.Nil) {
+					t.Errorf("new user %s is missing an id or password hash", u.Email)
+				}
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditUserImport {
+				t.Errorf("expected a user.import audit entry, got %+v", audit.entries)
+			}
+
+			// Running the same file again only updates.
+			again, err := svc.Import(context.Background(), strings.NewReader(importCSV), false, adminID, tt.role)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Created != 0 || again.Updated != 3 {
+				t.Errorf("expected rerun to update 3 rows and create none, got %+v", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+type InteractionService struct {
+	repo      repository.InteractionRepository
+	audit     repository.AuditRepository
+	batchSize int
+}
+
+// NewInteractionService returns a service that loads the interaction
+// dataset in statements of batchSize entries.
+func NewInteractionService(repo repository.InteractionRepository, audit repository.AuditRepository, batchSize int) *InteractionService {
+	return &InteractionService{
+		repo:      repo,
+		audit:     audit,
+		batchSize: batchSize,
+	}
+}
+
+// LoadDataset loads the interaction dataset from a CSV file as the system.
+// The whole file is checked before anything is written, so a bad row
+// loads nothing. Entries are upserted by their pair of substances, so
+// loading a newer release updates the dataset and loading the same file
+// again changes nothing; pairs left out of the file are kept.
+func (s *InteractionService) LoadDataset(ctx context.Context, r io.Reader) (*model.InteractionReport, error) {
+	rows, err := model.ParseInteractionCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	interactions := make([]model.Interaction, len(rows))
+	lines := map[[2]string]int{}
+	for i, row := range rows {
+		in := row.Interaction
+		if err := in.Normalize(); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", model.ErrBadRequest, row.Line, err)
+		}
+		pair := [2]string{in.SubstanceA, in.SubstanceB}
+		if first, dup := lines[pair]; dup {
+			return nil, fmt.Errorf("%w: line %d: %s and %s are already paired on line %d", model.ErrBadRequest, row.Line, in.SubstanceA, in.SubstanceB, first)
+		}
+		lines[pair] = row.Line
+		interactions[i] = in
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+	report := &model.InteractionReport{ID: id, Read: len(rows)}
+
+	for start := 0; start < len(interactions); start += s.batchSize {
+		batch := interactions[start:min(start+s.batchSize, len(interactions))]
+		n, err := s.repo.UpsertBatch(ctx, batch)
+		if err != nil {
+			return nil, fmt.Errorf("load stopped at line %d; earlier entries were loaded: %w", rows[start].Line, err)
+		}
+		report.Loaded += n
+		report.Unchanged += len(batch) - n
+	}
+
+	recordAudit(ctx, s.audit, nil, model.AuditInteractionsLoaded, "interaction_dataset", report.ID, map[string]any{
+		"read":      report.Read,
+		"loaded":    report.Loaded,
+		"unchanged": report.Unchanged,
+	})
+
+	return report, nil
+}
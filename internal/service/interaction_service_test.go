@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+func TestInteractionService_LoadDataset(t *testing.T) {
+	file := "substance_a,substance_b,severity,description\n" +
+		"warfarin,aspirin,high,increased risk of bleeding\n" +
+		"Amoxicillin,methotrexate,Moderate,reduced methotrexate clearance\n" +
+		"simvastatin,clarithromycin,high,risk of myopathy\n"
+
+	repo := &mockInteractionRepo{}
+	audit := &mockAuditRepo{}
+	service := NewInteractionService(repo, audit, 2)
+
+	report, err := service.LoadDataset(context.Background(), strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Read != 3 || report.Loaded != 3 || report.Unchanged != 0 {
+		t.Errorf("report = %+v, want 3 read and loaded", report)
+	}
+	if in := repo.interactions[0]; in.SubstanceA != "aspirin" || in.SubstanceB != "warfarin" {
+		t.Errorf("interaction = %+v, want the substances in name order", in)
+	}
+	if in := repo.interactions[1]; in.SubstanceA != "amoxicillin" || in.Severity != model.SeverityModerate {
+		t.Errorf("interaction = %+v, want it lower-cased", in)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditInteractionsLoaded {
+		t.Errorf("expected the load to be audited, got %+v", audit.entries)
+	}
+
+	// Loading the same file again changes nothing.
+	report, err = service.LoadDataset(context.Background(), strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Loaded != 0 || report.Unchanged != 3 {
+		t.Errorf("report = %+v, want 3 unchanged on the second load", report)
+	}
+}
+
+func TestInteractionService_LoadDataset_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{name: "missing column", file: "substance_a,substance_b,severity\nwarfarin,aspirin,high\n", want: "missing column description"},
+		{name: "bad severity", file: "substance_a,substance_b,severity,description\nwarfarin,aspirin,high,bleeding\nwarfarin,ibuprofen,severe,bleeding\n", want: "line 3"},
+		{name: "repeated pair", file: "substance_a,substance_b,severity,description\nwarfarin,aspirin,high,bleeding\nAspirin,warfarin,low,bleeding\n", want: "already paired on line 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockInteractionRepo{}
+			_, err := NewInteractionService(repo, &mockAuditRepo{}, 100).LoadDataset(context.Background(), strings.NewReader(tt.file))
+
+			var verrs model.ValidationErrors
+			message := ""
+			switch {
+			case errors.As(err, &verrs):
+				message = verrs[0].Message
+			case errors.Is(err, model.ErrBadRequest):
+				message = err.Error()
+			default:
+				t.Fatalf("expected a bad request, got %v", err)
+			}
+			if !strings.Contains(message, tt.want) {
+				t.Errorf("error = %q, want it to mention %q", message, tt.want)
+			}
+			if len(repo.interactions) != 0 {
+				t.Errorf("loaded %+v, want nothing", repo.interactions)
+			}
+		})
+	}
+}
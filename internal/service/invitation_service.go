@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+type InvitationService struct {
+	repo     repository.InvitationRepository
+	audit    repository.AuditRepository
+	hasher   *encrypt.PasswordHasher
+	mailer   mailer.Mailer
+	baseURL  string
+	tokenTTL time.Duration
+}
+
+func NewInvitationService(repo repository.InvitationRepository, audit repository.AuditRepository, pepper string, mailer mailer.Mailer, baseURL string, tokenTTL time.Duration) *InvitationService {
+	return &InvitationService{
+		repo:     repo,
+		audit:    audit,
+		hasher:   encrypt.NewPasswordHasher(pepper),
+		mailer:   mailer,
+		baseURL:  baseURL,
+		tokenTTL: tokenTTL,
+	}
+}
+
+// canInvite reports whether callerRole may invite someone as role. Admins
+// can invite users and admins; only super admins can invite super admins.
+func canInvite(callerRole, role string) bool {
+	if !isAdmin(callerRole) {
+		return false
+	}
+	return role != "super_admin" || callerRole == "super_admin"
+}
+
+// Create invites data.Email and mails them a link to set their password. If
+// the mail cannot be sent the invitation is revoked, so it can simply be
+// created again.
+func (s *InvitationService) Create(ctx context.Context, data *model.CreateInvitation, callerID uuid.UUID, callerRole string) (*model.Invitation, error) {
+	if !canInvite(callerRole, data.Role) {
+		return nil, model.ErrForbidden
+	}
+
+	// Organisation admins only invite to the organisation they are signed in to.
+	if orgID := repository.TenantFromContext(ctx); orgID != nil && *orgID != data.OrganisationID {
+		return nil, fmt.Errorf("cannot invite to another organisation: %w", model.ErrForbidden)
+	}
+
+	registered, err := s.repo.EmailRegistered(ctx, data.Email)
+	if err != nil {
+		return nil, err
+	}
+	if registered {
+		return nil, fmt.Errorf("email %w", model.ErrAlreadyExists)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	token, err := encrypt.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	inv, err := s.repo.Create(ctx, model.Invitation{
+		ID:             id,
+		Email:          data.Email,
+		FirstName:      data.FirstName,
+		LastName:       data.LastName,
+		Role:           data.Role,
+		OrganisationID: data.OrganisationID,
+		InvitedBy:      &callerID,
+		ExpiresAt:      time.Now().Add(s.tokenTTL),
+		TokenHash:      encrypt.HashToken(token),
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return nil, fmt.Errorf("pending invitation for this email %w", err)
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, model.ValidationErrors{model.FieldError{Field: "organisation_id", Message: "unknown organisation"}}
+		}
+		return nil, err
+	}
+
+	if err := s.send(ctx, inv, token); err != nil {
+		// The link never reached the invitee, and an open invitation would
+		// stop the address being invited again.
+		if err := s.repo.Revoke(ctx, inv.ID); err != nil {
+			log.Printf("revoke unsent invitation %s: %v", inv.ID, err)
+		}
+		return nil, fmt.Errorf("send invitation: %w", err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditInvitationCreated, "invitation", inv.ID, map[string]any{
+		"role":            inv.Role,
+		"organisation_id": inv.OrganisationID,
+	})
+
+	return inv, nil
+}
+
+func (s *InvitationService) send(ctx context.Context, inv *model.Invitation, token string) error {
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      inv.Email,
+		Subject: "You have been invited to Med Portal",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nYou have been invited to join Med Portal. Choose a password to activate your account:\n\n%s/accept-invitation?token=%s\n\nThe link expires in %s.",
+			inv.FirstName, s.baseURL, token, s.tokenTTL,
+		),
+	})
+}
+
+func (s *InvitationService) List(ctx context.Context, callerRole string, filter model.InvitationFilter, params model.PaginationParams) (*model.PaginatedInvitationsResponse, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	invs, err := s.repo.List(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if invs == nil {
+		invs = []model.Invitation{}
+	}
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedInvitationsResponse{
+		Items: invs,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+func (s *InvitationService) GetByID(ctx context.Context, id uuid.UUID, callerRole string) (*model.Invitation, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	inv, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("invitation %w", err)
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Resend mails a new link for a pending or expired invitation. The previous
+// link stops working and the expiry starts again.
+func (s *InvitationService) Resend(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.Invitation, error) {
+	inv, err := s.GetByID(ctx, id, callerRole)
+	if err != nil {
+		return nil, err
+	}
+	if !canInvite(callerRole, inv.Role) {
+		return nil, model.ErrForbidden
+	}
+	if inv.Status != model.InvitationPending && inv.Status != model.InvitationExpired {
+		return nil, fmt.Errorf("invitation is %s: %w", inv.Status, model.ErrConflict)
+	}
+
+	token, err := encrypt.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	inv, err = s.repo.Resend(ctx, id, encrypt.HashToken(token), time.Now().Add(s.tokenTTL))
+	if err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return nil, fmt.Errorf("invitation is no longer pending: %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditInvitationResent, "invitation", id, nil)
+
+	if err := s.send(ctx, inv, token); err != nil {
+		return nil, err
+	}
+
+	return inv, nil
+}
+
+// Revoke withdraws a pending or expired invitation so its link can no
+// longer be used.
+func (s *InvitationService) Revoke(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	inv, err := s.GetByID(ctx, id, callerRole)
+	if err != nil {
+		return err
+	}
+	if !canInvite(callerRole, inv.Role) {
+		return model.ErrForbidden
+	}
+	if inv.Status != model.InvitationPending && inv.Status != model.InvitationExpired {
+		return fmt.Errorf("invitation is %s: %w", inv.Status, model.ErrConflict)
+	}
+
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return fmt.Errorf("invitation is no longer pending: %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditInvitationRevoked, "invitation", id, nil)
+
+	return nil
+}
+
+// Accept creates the invited account with the chosen password. The token
+// can be used once.
+func (s *InvitationService) Accept(ctx context.Context, data *model.AcceptInvitation) error {
+	inv, err := s.repo.GetByTokenHash(ctx, encrypt.HashToken(data.Token))
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("token %w", err)
+		}
+		return err
+	}
+
+	switch inv.Status {
+	case model.InvitationAccepted:
+		return fmt.Errorf("invitation already accepted: %w", model.ErrConflict)
+	case model.InvitationRevoked:
+		return fmt.Errorf("invitation has been revoked: %w", model.ErrAlreadyDeleted)
+	case model.InvitationExpired:
+		return fmt.Errorf("token has expired: %w", model.ErrAlreadyDeleted)
+	}
+
+	userID, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	hashedPassword, err := s.hasher.HashPassword(data.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.repo.Accept(ctx, inv.ID, userID, hashedPassword); err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return fmt.Errorf("email %w", err)
+		}
+		if errors.Is(err, model.ErrConflict) {
+			return fmt.Errorf("invitation is no longer pending: %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &userID, model.AuditInvitationAccepted, "invitation", inv.ID, map[string]any{"user_id": userID})
+
+	return nil
+}
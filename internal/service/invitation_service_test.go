@@ -0,0 +1,396 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/encrypt"
+	"github.com/google/uuid"
+)
+
+// mockInvitationRepo keeps invitations and registered emails in memory and
+// derives the status the way the repository's SQL does.
+type mockInvitationRepo struct {
+	registered  map[string]bool
+	invitations map[uuid.UUID]*model.Invitation
+	passwords   map[string]string
+}
+
+func newMockInvitationRepo() *mockInvitationRepo {
+	return &mockInvitationRepo{
+		registered:  map[string]bool{},
+		invitations: map[uuid.UUID]*model.Invitation{},
+		passwords:   map[string]string{},
+	}
+}
+
+func invitationStatusOf(inv *model.Invitation) string {
+	switch {
+	case inv.AcceptedAt != nil:
+		return model.InvitationAccepted
+	case inv.RevokedAt != nil:
+		return model.InvitationRevoked
+	case !inv.ExpiresAt.After(time.Now()):
+		return model.InvitationExpired
+	}
+	return model.InvitationPending
+}
+
+func (m *mockInvitationRepo) get(inv *model.Invitation) *model.Invitation {
+	c := *inv
+	c.Status = invitationStatusOf(inv)
+	return &c
+}
+
+func (m *mockInvitationRepo) EmailRegistered(ctx context.Context, email string) (bool, error) {
+	return m.registered[email], nil
+}
+
+func (m *mockInvitationRepo) Create(ctx context.Context, inv model.Invitation) (*model.Invitation, error) {
+	for _, other := range m.invitations {
+		if other.Email == inv.Email && invitationStatusOf(other) == model.InvitationPending {
+			return nil, model.ErrAlreadyExists
+		}
+	}
+	inv.SentAt = time.Now()
+	inv.CreatedAt = inv.SentAt
+	m.invitations[inv.ID] = &inv
+	return m.get(&inv), nil
+}
+
+func (m *mockInvitationRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Invitation, error) {
+	inv, ok := m.invitations[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return m.get(inv), nil
+}
+
+func (m *mockInvitationRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	for _, inv := range m.invitations {
+		if inv.TokenHash == tokenHash {
+			return m.get(inv), nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockInvitationRepo) List(ctx context.Context, filter model.InvitationFilter, limit, offset int) ([]model.Invitation, error) {
+	var invs []model.Invitation
+	for _, inv := range m.invitations {
+		if filter.Status == "" || invitationStatusOf(inv) == filter.Status {
+			invs = append(invs, *m.get(inv))
+		}
+	}
+	return invs, nil
+}
+
+func (m *mockInvitationRepo) Count(ctx context.Context, filter model.InvitationFilter) (int, error) {
+	invs, _ := m.List(ctx, filter, 0, 0)
+	return len(invs), nil
+}
+
+func (m *mockInvitationRepo) Resend(ctx context.Context, id uuid.UUID, tokenHash string, expiresAt time.Time) (*model.Invitation, error) {
+	inv := m.invitations[id]
+	inv.TokenHash = tokenHash
+	inv.ExpiresAt = expiresAt
+	inv.SentAt = time.Now()
+	return m.get(inv), nil
+}
+
+func (m *mockInvitationRepo) Revoke(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
+	m.invitations[id].RevokedAt = &now
+	return nil
+}
+
+func (m *mockInvitationRepo) Accept(ctx context.Context, id uuid.UUID, userID uuid.UUID, passwordHash string) error {
+	inv := m.invitations[id]
+	if m.registered[inv.Email] {
+		return model.ErrAlreadyExists
+	}
+	now := time.Now()
+	inv.AcceptedAt = &now
+	inv.AcceptedUserID = &userID
+	m.registered[inv.Email] = true
+	m.passwords[inv.Email] = passwordHash
+	return nil
+}
+
+func newTestInvitationService() (*InvitationService, *mockInvitationRepo, *mockMailer, *mockAuditRepo) {
+	repo := newMockInvitationRepo()
+	mail := &mockMailer{}
+	audit := &mockAuditRepo{}
+	svc := NewInvitationService(repo, audit, "test-pepper", mail, "http://app.test", time.Hour)
+	return svc, repo, mail, audit
+}
+
+func testInvitation(orgID uuid.UUID, role string) *model.CreateInvitation {
+	return &model.CreateInvitation{
+		Email:          "staff@example.com",
+		FirstName:      "Jane",
+		LastName:       "Doe",
+		Role:           role,
+		OrganisationID: orgID,
+	}
+}
+
+func TestInvitationService_Create(t *testing.T) {
+	adminID, _ := uuid.NewV7()
+	orgID, _ := uuid.NewV7()
+	otherOrgID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		callerRole string
+		role       string
+		tenant     uuid.UUID
+		registered bool
+		existing   bool
+		mailErr    error
+		expectErr  error
+	}{
+		{name: "admin invites user", callerRole: "admin", role: "user"},
+		{name: "admin invites to own organisation", callerRole: "admin", role: "user", tenant: orgID},
+		{name: "admin cannot invite to another organisation", callerRole: "admin", role: "user", tenant: otherOrgID, expectErr: model.ErrForbidden},
+		{name: "admin invites admin", callerRole: "admin", role: "admin"},
+		{name: "super admin invites super admin", callerRole: "super_admin", role: "super_admin"},
+		{name: "admin cannot invite super admin", callerRole: "admin", role: "super_admin", expectErr: model.ErrForbidden},
+		{name: "user cannot invite", callerRole: "user", role: "user", expectErr: model.ErrForbidden},
+		{name: "email registered", callerRole: "admin", role: "user", registered: true, expectErr: model.ErrAlreadyExists},
+		{name: "pending invitation", callerRole: "admin", role: "user", existing: true, expectErr: model.ErrAlreadyExists},
+		{name: "mail fails", callerRole: "admin", role: "user", mailErr: errMail, expectErr: errMail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, mail, audit := newTestInvitationService()
+			repo.registered["staff@example.com"] = tt.registered
+			if tt.existing {
+				if _, err := svc.Create(context.Background(), testInvitation(orgID, "user"), adminID, "admin"); err != nil {
+					t.Fatal(err)
+				}
+				mail.sent = nil
+				audit.entries = nil
+			}
+
+			ctx := context.Background()
+			if tt.tenant != uuid.Nil {
+				ctx = repository.WithTenant(ctx, tt.tenant)
+			}
+
+			mail.sendErr = tt.mailErr
+			inv, err := svc.Create(ctx, testInvitation(orgID, tt.role), adminID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(mail.sent) != 0 {
+					t.Errorf("expected no mail, got %d", len(mail.sent))
+				}
+				if tt.mailErr != nil {
+					for _, inv := range repo.invitations {
+						if invitationStatusOf(inv) != model.InvitationRevoked {
+							t.Errorf("unsent invitation is %s, want revoked", invitationStatusOf(inv))
+						}
+					}
+					if len(audit.entries) != 0 {
+						t.Errorf("expected no audit entry, got %+v", audit.entries)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if inv.Status != model.InvitationPending || inv.Role != tt.role || inv.OrganisationID != orgID {
+				t.Errorf("unexpected invitation: %+v", inv)
+			}
+			if inv.InvitedBy == nil || *inv.InvitedBy != adminID {
+				t.Errorf("expected invited_by %s, got %v", adminID, inv.InvitedBy)
+			}
+			if len(mail.sent) != 1 || mail.sent[0].To != "staff@example.com" {
+				t.Fatalf("expected one mail to the invitee, got %+v", mail.sent)
+			}
+			if token := linkToken(t, mail.sent[0].Body); encrypt.HashToken(token) != inv.TokenHash {
+				t.Error("mailed token does not match the stored hash")
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditInvitationCreated {
+				t.Errorf("expected invitation.created audit entry, got %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestInvitationService_Accept(t *testing.T) {
+	adminID, _ := uuid.NewV7()
+	orgID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name      string
+		prepare   func(repo *mockInvitationRepo, inv *model.Invitation)
+		token     func(token string) string
+		expectErr error
+	}{
+		{name: "success"},
+		{name: "unknown token", token: func(string) string { return "wrong" }, expectErr: model.ErrNotFound},
+		{
+			name: "expired",
+			prepare: func(repo *mockInvitationRepo, inv *model.Invitation) {
+				repo.invitations[inv.ID].ExpiresAt = time.Now().Add(-time.Minute)
+			},
+			expectErr: model.ErrAlreadyDeleted,
+		},
+		{
+			name: "revoked",
+			prepare: func(repo *mockInvitationRepo, inv *model.Invitation) {
+				now := time.Now()
+				repo.invitations[inv.ID].RevokedAt = &now
+			},
+			expectErr: model.ErrAlreadyDeleted,
+		},
+		{
+			name: "email registered since",
+			prepare: func(repo *mockInvitationRepo, inv *model.Invitation) {
+				repo.registered[inv.Email] = true
+			},
+			expectErr: model.ErrAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, mail, audit := newTestInvitationService()
+
+			inv, err := svc.Create(context.Background(), testInvitation(orgID, "admin"), adminID, "admin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			token := linkToken(t, mail.sent[0].Body)
+			if tt.prepare != nil {
+				tt.prepare(repo, inv)
+			}
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+			audit.entries = nil
+
+			err = svc.Accept(context.Background(), &model.AcceptInvitation{Token: token, Password: "Password1!"})
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			hash := repo.passwords[inv.Email]
+			if !encrypt.NewPasswordHasher("test-pepper").VerifyPassword("Password1!", hash) {
+				t.Error("stored password does not verify")
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditInvitationAccepted {
+				t.Errorf("expected invitation.accepted audit entry, got %+v", audit.entries)
+			}
+
+			// The token is single use.
+			err = svc.Accept(context.Background(), &model.AcceptInvitation{Token: token, Password: "Password1!"})
+			if !errors.Is(err, model.ErrConflict) {
+				t.Errorf("expected second accept to conflict, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInvitationService_Resend(t *testing.T) {
+	adminID, _ := uuid.NewV7()
+	orgID, _ := uuid.NewV7()
+
+	svc, repo, mail, _ := newTestInvitationService()
+	inv, err := svc.Create(context.Background(), testInvitation(orgID, "user"), adminID, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken := linkToken(t, mail.sent[0].Body)
+	repo.invitations[inv.ID].ExpiresAt = time.Now().Add(-time.Minute)
+
+	resent, err := svc.Resend(context.Background(), inv.ID, adminID, "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resent.Status != model.InvitationPending {
+		t.Errorf("expected resent invitation to be pending, got %s", resent.Status)
+	}
+	if len(mail.sent) != 2 {
+		t.Fatalf("expected a second mail, got %d", len(mail.sent))
+	}
+
+	newToken := linkToken(t, mail.sent[1].Body)
+	err = svc.Accept(context.Background(), &model.AcceptInvitation{Token: oldToken, Password: "Password1!"})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected the old link to stop working, got %v", err)
+	}
+	if err := svc.Accept(context.Background(), &model.AcceptInvitation{Token: newToken, Password: "Password1!"}); err != nil {
+		t.Errorf("expected the new link to work, got %v", err)
+	}
+
+	if _, err := svc.Resend(context.Background(), inv.ID, adminID, "admin"); !errors.Is(err, model.ErrConflict) {
+		t.Errorf("expected resending an accepted invitation to conflict, got %v", err)
+	}
+}
+
+func TestInvitationService_Revoke(t *testing.T) {
+	adminID, _ := uuid.NewV7()
+	orgID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		callerRole string
+		role       string
+		expectErr  error
+	}{
+		{name: "admin revokes", callerRole: "admin", role: "user"},
+		{name: "user cannot revoke", callerRole: "user", role: "user", expectErr: model.ErrForbidden},
+		{name: "admin cannot revoke super admin invitation", callerRole: "admin", role: "super_admin", expectErr: model.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, mail, _ := newTestInvitationService()
+			inv, err := svc.Create(context.Background(), testInvitation(orgID, tt.role), adminID, "super_admin")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = svc.Revoke(context.Background(), inv.ID, adminID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			token := linkToken(t, mail.sent[0].Body)
+			err = svc.Accept(context.Background(), &model.AcceptInvitation{Token: token, Password: "Password1!"})
+			if !errors.Is(err, model.ErrAlreadyDeleted) {
+				t.Errorf("expected revoked invitation to be gone, got %v", err)
+			}
+
+			if err := svc.Revoke(context.Background(), inv.ID, adminID, tt.callerRole); !errors.Is(err, model.ErrConflict) {
+				t.Errorf("expected revoking twice to conflict, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,862 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/hl7"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/PranavJoshi2893/med-portal/pkg/mllp"
+	"github.com/google/uuid"
+)
+
+type LabService struct {
+	repo          repository.LabRepository
+	patients      repository.PatientRepository
+	practitioners repository.PractitionerRepository
+	audit         repository.AuditRepository
+	mailer        mailer.Mailer
+	ranges        *ReferenceRanges
+	loc           *time.Location
+}
+
+// NewLabService returns a service that files lab results and interprets
+// them against ranges. Times in messages without a UTC offset are read in
+// loc, the lab's time zone.
+func NewLabService(repo repository.LabRepository, patients repository.PatientRepository, practitioners repository.PractitionerRepository, audit repository.AuditRepository, mailer mailer.Mailer, ranges *ReferenceRanges, loc *time.Location) *LabService {
+	return &LabService{
+		repo:          repo,
+		patients:      patients,
+		practitioners: practitioners,
+		audit:         audit,
+		mailer:        mailer,
+		ranges:        ranges,
+		loc:           loc,
+	}
+}
+
+// reconciliationError adds context to the repository errors of a single
+// reconciliation item.
+func reconciliationError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("reconciliation item %w", err)
+	}
+	return err
+}
+
+// observationError adds context to the repository errors of a single lab
+// result.
+func observationError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("lab result %w", err)
+	}
+	return err
+}
+
+// Receive files an HL7 v2 ORU^R01 message submitted by an admin in the
+// organisation they are signed in to, and returns its acknowledgment.
+func (s *LabService) Receive(ctx context.Context, raw []byte, callerID uuid.UUID, callerRole string) ([]byte, error) {
+	if !isAdmin(callerRole) {
+		return nil, fmt.Errorf("only admins can submit lab results: %w", model.ErrForbidden)
+	}
+	if repository.TenantFromContext(ctx) == nil {
+		return nil, fmt.Errorf("sign in to an organisation to submit lab results: %w", model.ErrForbidden)
+	}
+	return s.Ingest(ctx, raw, &callerID)
+}
+
+// Ingest files an HL7 v2 ORU^R01 message for the request's organisation and
+// returns its acknowledgment. Data that is not an HL7 message at all is
+// ErrBadRequest, since it cannot be acknowledged. Otherwise every message is
+// acknowledged: AA when its results were filed, or when it was filed before;
+// AR when it is not a lab result message; AE when its content is invalid or
+// it could not be stored. Results for a patient who cannot be matched are
+// filed in the reconciliation queue.
+func (s *LabService) Ingest(ctx context.Context, raw []byte, actorID *uuid.UUID) ([]byte, error) {
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrBadRequest, err)
+	}
+	return s.acknowledge(ctx, msg, s.file(ctx, msg, string(raw), actorID), actorID)
+}
+
+// acknowledge builds the acknowledgment of msg, auditing it unless it
+// accepts the message.
+func (s *LabService) acknowledge(ctx context.Context, msg *hl7.Message, ack hl7.Ack, actorID *uuid.UUID) ([]byte, error) {
+	ackID, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	if ack.Code != hl7.AckAccept {
+		h := msg.Segments[0]
+		recordAudit(ctx, s.audit, actorID, model.AuditLabMessageRejected, "lab_message", ackID, map[string]any{
+			"control_id":       msg.ControlID(),
+			"sending_facility": h.Value(4, 1),
+			"ack_code":         ack.Code,
+			"error_code":       ack.ErrorCode,
+			"error":            ack.Text,
+		})
+	}
+	return ack.Acknowledge(msg, ackID.String(), time.Now()).Bytes(), nil
+}
+
+// reject acknowledges data that could not be read or parsed whole with ack,
+// when its MSH segment can be parsed. Without one there is no sender to
+// answer, and it returns nil.
+func (s *LabService) reject(ctx context.Context, data []byte, ack hl7.Ack) []byte {
+	msg, err := hl7.ParseHeader(data)
+	if err != nil {
+		return nil
+	}
+	reply, err := s.acknowledge(ctx, msg, ack, nil)
+	if err != nil {
+		log.Printf("lab: failed to reject message: %v", err)
+		return nil
+	}
+	return reply
+}
+
+// MLLPHandler files messages received over MLLP for organisation orgID. The
+// listener has no signed-in user, so the messages are audited without an
+// actor. Messages that cannot be parsed are answered with AE, and those the
+// listener could not read whole, such as ones over its size limit, with AR,
+// whenever their MSH segment can be parsed; otherwise the connection is
+// closed without an answer.
+func (s *LabService) MLLPHandler(orgID uuid.UUID) mllp.Handler {
+	return labMLLPHandler{service: s, orgID: orgID}
+}
+
+// labMLLPHandler is the mllp.Handler and mllp.Rejecter of MLLPHandler.
+type labMLLPHandler struct {
+	service *LabService
+	orgID   uuid.UUID
+}
+
+func (h labMLLPHandler) ServeMLLP(ctx context.Context, msg []byte) ([]byte, error) {
+	ctx = repository.WithTenant(ctx, h.orgID)
+	if _, err := hl7.Parse(msg); err != nil {
+		if nak := h.service.reject(ctx, msg, hl7.Ack{Code: hl7.AckError, ErrorCode: hl7.ErrSegmentSequence, Text: err.Error()}); nak != nil {
+			return nak, nil
+		}
+	}
+	return h.service.Ingest(ctx, msg, nil)
+}
+
+func (h labMLLPHandler) RejectMLLP(ctx context.Context, partial []byte, err error) []byte {
+	ctx = repository.WithTenant(ctx, h.orgID)
+	return h.service.reject(ctx, partial, hl7.Ack{Code: hl7.AckReject, ErrorCode: hl7.ErrApplicationInternal, Text: err.Error()})
+}
+
+// file stores a parsed message and reports how to acknowledge it.
+func (s *LabService) file(ctx context.Context, msg *hl7.Message, raw string, actorID *uuid.UUID) hl7.Ack {
+	orgID := repository.TenantFromContext(ctx)
+	if orgID == nil {
+		return hl7.Ack{Code: hl7.AckError, ErrorCode: hl7.ErrApplicationInternal, Text: "no organisation to file results for"}
+	}
+
+	code, event := msg.Type()
+	if code != "ORU" {
+		return hl7.Ack{Code: hl7.AckReject, ErrorCode: hl7.ErrUnsupportedMessageType, Text: fmt.Sprintf("unsupported message type %q; only ORU^R01 is accepted", code)}
+	}
+	if event != "R01" {
+		return hl7.Ack{Code: hl7.AckReject, ErrorCode: hl7.ErrUnsupportedEventCode, Text: fmt.Sprintf("unsupported trigger event %q; only ORU^R01 is accepted", event)}
+	}
+	if msg.ControlID() == "" {
+		return hl7.Ack{Code: hl7.AckReject, ErrorCode: hl7.ErrRequiredFieldMissing, Text: "MSH-10 message control ID is required"}
+	}
+
+	results, ackErr := s.parseResults(msg)
+	if ackErr != nil {
+		return *ackErr
+	}
+
+	h := msg.Segments[0]
+	messageID, err := uuid.NewV7()
+	if err != nil {
+		return internalAck(msg, err)
+	}
+	record := model.LabMessage{
+		ID:                 messageID,
+		OrganisationID:     *orgID,
+		ControlID:          msg.ControlID(),
+		SendingApplication: h.Value(3, 1),
+		SendingFacility:    h.Value(4, 1),
+		MessageType:        "ORU^R01",
+		Raw:                raw,
+		ReceivedBy:         actorID,
+	}
+
+	var items []model.LabReconciliation
+	var observations []model.LabObservation
+	orderedBy := map[string]*uuid.UUID{}
+	now := time.Now()
+	for _, result := range results {
+		patient, reason, err := s.match(ctx, result.patient)
+		if err != nil {
+			return internalAck(msg, err)
+		}
+
+		var patientID, itemID *uuid.UUID
+		if patient != nil {
+			patientID = &patient.ID
+		} else {
+			id, err := uuid.NewV7()
+			if err != nil {
+				return internalAck(msg, err)
+			}
+			itemID = &id
+			items = append(items, model.LabReconciliation{
+				ID:             id,
+				OrganisationID: *orgID,
+				MessageID:      messageID,
+				Patient:        result.patient,
+				Reason:         reason,
+			})
+		}
+
+		for _, o := range result.observations {
+			id, err := uuid.NewV7()
+			if err != nil {
+				return internalAck(msg, err)
+			}
+			o.ID = id
+			o.OrganisationID = *orgID
+			o.MessageID = messageID
+			o.PatientID = patientID
+			o.ReconciliationID = itemID
+			if patient != nil {
+				o.Interpretation = s.ranges.Interpret(&o, patient.Sex, patient.DateOfBirth, now)
+			}
+			if o.OrderingProvider != nil {
+				if _, ok := orderedBy[*o.OrderingProvider]; !ok {
+					orderedBy[*o.OrderingProvider], err = s.orderingPractitioner(ctx, *o.OrderingProvider)
+					if err != nil {
+						return internalAck(msg, err)
+					}
+				}
+				o.OrderingPractitionerID = orderedBy[*o.OrderingProvider]
+			}
+			observations = append(observations, o)
+		}
+	}
+
+	if err := s.repo.Store(ctx, record, items, observations); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return hl7.Ack{Code: hl7.AckAccept, Text: "duplicate of a message already accepted"}
+		}
+		return internalAck(msg, err)
+	}
+
+	recordAudit(ctx, s.audit, actorID, model.AuditLabMessageAccepted, "lab_message", messageID, map[string]any{
+		"control_id":       record.ControlID,
+		"sending_facility": record.SendingFacility,
+		"observations":     len(observations),
+		"unmatched":        len(items),
+	})
+	s.alertCritical(ctx, observations, actorID)
+	return hl7.Ack{Code: hl7.AckAccept}
+}
+
+// orderingPractitioner finds the practitioner an order names by NPI in
+// OBR-16, or returns nil if none matches.
+func (s *LabService) orderingPractitioner(ctx context.Context, npi string) (*uuid.UUID, error) {
+	p, err := s.practitioners.FindByNPI(ctx, npi)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p.ID, nil
+}
+
+// alertCritical audits the critical results among observations filed under
+// a patient and emails each to the practitioner who ordered it. Failures to
+// notify are logged rather than returned, since the results are filed
+// either way, and the results stay on the critical list until acknowledged.
+func (s *LabService) alertCritical(ctx context.Context, observations []model.LabObservation, actorID *uuid.UUID) {
+	practitioners := map[uuid.UUID]*model.Practitioner{}
+	for _, o := range observations {
+		if o.PatientID == nil || o.Interpretation == nil || *o.Interpretation != model.InterpretationCritical {
+			continue
+		}
+
+		var practitioner *model.Practitioner
+		if id := o.OrderingPractitionerID; id != nil {
+			if _, ok := practitioners[*id]; !ok {
+				p, err := s.practitioners.GetByID(ctx, *id)
+				if err != nil {
+					log.Printf("lab result %s: critical result alert: %v", o.ID, err)
+				}
+				practitioners[*id] = p
+			}
+			practitioner = practitioners[*id]
+		}
+
+		recordAudit(ctx, s.audit, actorID, model.AuditLabCriticalResult, "lab_observation", o.ID, map[string]any{
+			"patient_id":               o.PatientID,
+			"code":                     o.Code,
+			"value":                    o.Value,
+			"units":                    o.Units,
+			"ordering_practitioner_id": o.OrderingPractitionerID,
+			"notified":                 practitioner != nil,
+		})
+		if practitioner == nil {
+			continue
+		}
+
+		value := ""
+		if o.Value != nil {
+			value = *o.Value
+		}
+		if o.Units != nil {
+			value += " " + *o.Units
+		}
+		err := s.mailer.Send(ctx, mailer.Message{
+			To:      practitioner.Email,
+			Subject: "Critical lab result",
+			Body: fmt.Sprintf(
+				"Hello %s,\n\nA critical result was filed for a test you ordered: %s %s. Please review it and acknowledge it in the portal.",
+				practitioner.FirstName, o.Name, value,
+			),
+		})
+		if err != nil {
+			log.Printf("lab result %s: critical result alert: %v", o.ID, err)
+		}
+	}
+}
+
+// internalAck logs an error that kept a message from being filed and
+// returns an AE acknowledgment that does not reveal it.
+func internalAck(msg *hl7.Message, err error) hl7.Ack {
+	log.Printf("lab message %s: %v", msg.ControlID(), err)
+	return hl7.Ack{Code: hl7.AckError, ErrorCode: hl7.ErrApplicationInternal, Text: "the message could not be filed; send it again later"}
+}
+
+// labResult is the results of one patient in a message.
+type labResult struct {
+	patient      model.LabPatient
+	observations []model.LabObservation
+}
+
+// resultStatuses are the OBX-11 observation result statuses (HL7 table
+// 0085).
+const resultStatuses = "CDFINOPRSUWX"
+
+// parseResults reads the patients and results of an ORU^R01 message: each
+// PID segment starts a patient, each OBR an order of that patient, and each
+// OBX a result of that order. An NTE after an OBX is a note on the result;
+// other segments are skipped.
+func (s *LabService) parseResults(msg *hl7.Message) ([]labResult, *hl7.Ack) {
+	var results []labResult
+	var order hl7.Segment
+	var inOrder, afterOBX bool
+
+	fail := func(n int, code, format string, args ...any) *hl7.Ack {
+		return &hl7.Ack{Code: hl7.AckError, ErrorCode: code, Text: fmt.Sprintf("segment %d: ", n+1) + fmt.Sprintf(format, args...)}
+	}
+
+	for n, seg := range msg.Segments {
+		switch seg.Name {
+		case "PID":
+			patient, err := s.parsePatient(seg)
+			if err != nil {
+				return nil, fail(n, hl7.ErrDataType, "%v", err)
+			}
+			results = append(results, labResult{patient: patient})
+			inOrder, afterOBX = false, false
+
+		case "OBR":
+			if len(results) == 0 {
+				return nil, fail(n, hl7.ErrSegmentSequence, "OBR before any PID segment")
+			}
+			if seg.Value(4, 1) == "" {
+				return nil, fail(n, hl7.ErrRequiredFieldMissing, "OBR-4 universal service identifier is required")
+			}
+			order, inOrder, afterOBX = seg, true, false
+
+		case "OBX":
+			if !inOrder {
+				return nil, fail(n, hl7.ErrSegmentSequence, "OBX outside an OBR order")
+			}
+			o, code, err := s.parseObservation(order, seg)
+			if err != nil {
+				return nil, fail(n, code, "%v", err)
+			}
+			r := &results[len(results)-1]
+			r.observations = append(r.observations, o)
+			afterOBX = true
+			continue
+
+		case "NTE":
+			if afterOBX {
+				r := &results[len(results)-1]
+				o := &r.observations[len(r.observations)-1]
+				note := seg.Field(3).String()
+				if o.Note != nil {
+					note = *o.Note + "\n" + note
+				}
+				o.Note = &note
+				continue
+			}
+		}
+		afterOBX = false
+	}
+
+	if len(results) == 0 {
+		return nil, &hl7.Ack{Code: hl7.AckError, ErrorCode: hl7.ErrSegmentSequence, Text: "the message has no PID segment"}
+	}
+	count := 0
+	for _, r := range results {
+		count += len(r.observations)
+	}
+	if count == 0 {
+		return nil, &hl7.Ack{Code: hl7.AckError, ErrorCode: hl7.ErrSegmentSequence, Text: "the message has no OBX results"}
+	}
+	return results, nil
+}
+
+// parsePatient reads the identifiers (PID-3), name (PID-5) and date of
+// birth (PID-7) of a PID segment.
+func (s *LabService) parsePatient(pid hl7.Segment) (model.LabPatient, error) {
+	patient := model.LabPatient{
+		Identifiers: []model.LabIdentifier{},
+		FamilyName:  strings.TrimSpace(pid.Value(5, 1)),
+		GivenName:   strings.TrimSpace(pid.Value(5, 2)),
+	}
+
+	for _, rep := range pid.Field(3) {
+		value := strings.TrimSpace(rep.Component(1))
+		if value == "" {
+			continue
+		}
+		patient.Identifiers = append(patient.Identifiers, model.LabIdentifier{
+			Value:     value,
+			Authority: strings.TrimSpace(rep.Component(4)),
+			Type:      strings.TrimSpace(rep.Component(5)),
+		})
+	}
+
+	if v := pid.Value(7, 1); v != "" {
+		dob, err := hl7.ParseTime(v, s.loc)
+		if err != nil {
+			return patient, fmt.Errorf("PID-7 date of birth: %v", err)
+		}
+		date := dob.Format(model.DateLayout)
+		patient.DateOfBirth = &date
+	}
+	return patient, nil
+}
+
+// parseObservation reads an OBX result reported under an OBR order. On
+// failure it returns the HL7 error code that describes the problem.
+func (s *LabService) parseObservation(obr, obx hl7.Segment) (model.LabObservation, string, error) {
+	o := model.LabObservation{
+		PlacerOrderNumber: optionalString(obr.Value(2, 1)),
+		FillerOrderNumber: optionalString(obr.Value(3, 1)),
+		OrderingProvider:  optionalString(strings.TrimSpace(obr.Value(16, 1))),
+		ServiceCode:       obr.Value(4, 1),
+		ServiceName:       optionalString(obr.Value(4, 2)),
+		ValueType:         obx.Value(2, 1),
+		Code:              obx.Value(3, 1),
+		CodeSystem:        optionalString(obx.Value(3, 3)),
+		Name:              obx.Value(3, 2),
+		ReferenceRange:    optionalString(obx.Value(7, 1)),
+		AbnormalFlags:     []string{},
+		ResultStatus:      obx.Value(11, 1),
+	}
+
+	if o.Code == "" {
+		return o, hl7.ErrRequiredFieldMissing, errors.New("OBX-3 observation identifier is required")
+	}
+	if o.Name == "" {
+		o.Name = o.Code
+	}
+	if o.ResultStatus == "" {
+		return o, hl7.ErrRequiredFieldMissing, errors.New("OBX-11 observation result status is required")
+	}
+	if len(o.ResultStatus) != 1 || !strings.Contains(resultStatuses, o.ResultStatus) {
+		return o, hl7.ErrTableValueNotFound, fmt.Errorf("OBX-11 observation result status %q is not in table 0085", o.ResultStatus)
+	}
+
+	value, err := observationValue(o.ValueType, obx.Field(5))
+	if err != nil {
+		return o, hl7.ErrDataType, err
+	}
+	o.Value = optionalString(value)
+
+	units := obx.Value(6, 1)
+	if units == "" {
+		units = obx.Value(6, 2)
+	}
+	o.Units = optionalString(units)
+
+	for _, rep := range obx.Field(8) {
+		if flag := strings.TrimSpace(rep.Component(1)); flag != "" {
+			o.AbnormalFlags = append(o.AbnormalFlags, flag)
+		}
+	}
+
+	observed := obx.Value(14, 1)
+	if observed == "" {
+		observed = obr.Value(7, 1)
+	}
+	if observed != "" {
+		t, err := hl7.ParseTime(observed, s.loc)
+		if err != nil {
+			return o, hl7.ErrDataType, fmt.Errorf("observation date/time: %v", err)
+		}
+		o.ObservedAt = &t
+	}
+
+	return o, "", nil
+}
+
+// observationValue renders OBX-5 as text according to its value type in
+// OBX-2. Numeric values must be numbers, coded values are shown by their
+// text and other values that repeat are put on separate lines.
+func observationValue(valueType string, f hl7.Field) (string, error) {
+	switch valueType {
+	case "NM":
+		v := strings.TrimSpace(f.String())
+		if v == "" {
+			return "", nil
+		}
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "", fmt.Errorf("OBX-5 %q is not a number", v)
+		}
+		return v, nil
+
+	case "SN":
+		// A structured numeric such as <^5 or ^10^-^20 reads as its
+		// components run together.
+		if len(f) == 0 {
+			return "", nil
+		}
+		var b strings.Builder
+		for i := 1; i <= len(f[0]); i++ {
+			b.WriteString(f[0].Component(i))
+		}
+		return b.String(), nil
+
+	case "CE", "CWE", "CNE":
+		if v := f.Component(2); v != "" {
+			return v, nil
+		}
+		return f.Component(1), nil
+	}
+
+	lines := make([]string, 0, len(f))
+	for _, rep := range f {
+		lines = append(lines, rep.Component(1))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// match finds the patient record a message names. Identifiers of type MR
+// are looked up as MRNs and identifiers with an assigning authority as
+// patient identifiers of that system. The match must be unique, and the
+// patient's date of birth and family name must agree with the message;
+// otherwise the reason it failed is returned.
+func (s *LabService) match(ctx context.Context, patient model.LabPatient) (*model.PatientSummary, string, error) {
+	var mrns []string
+	var identifiers []model.CreatePatientIdentifier
+	for _, id := range patient.Identifiers {
+		if id.Type == "MR" {
+			mrns = append(mrns, id.Value)
+		}
+		if id.Authority != "" {
+			identifiers = append(identifiers, model.CreatePatientIdentifier{System: id.Authority, Value: id.Value})
+		}
+	}
+	if len(mrns) == 0 && len(identifiers) == 0 {
+		return nil, model.ReconcileNoIdentifiers, nil
+	}
+
+	candidates, err := s.patients.FindByIdentifiers(ctx, mrns, identifiers)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case len(candidates) == 0:
+		return nil, model.ReconcileNoMatch, nil
+	case len(candidates) > 1:
+		return nil, model.ReconcileSeveral, nil
+	}
+
+	c := candidates[0]
+	if patient.DateOfBirth == nil || *patient.DateOfBirth != c.DateOfBirth || !strings.EqualFold(patient.FamilyName, c.LastName) {
+		return nil, model.ReconcileDemographics, nil
+	}
+	return &c, "", nil
+}
+
+// ListObservations returns a patient's lab results to clinicians and to
+// the patient, most recently observed first.
+func (s *LabService) ListObservations(ctx context.Context, patientID uuid.UUID, params model.PaginationParams, callerID uuid.UUID, callerRole string) (*model.PaginatedLabObservationsResponse, error) {
+	if _, err := clinicalAccess(ctx, s.patients, s.practitioners, patientID, callerID, callerRole); err != nil {
+		return nil, err
+	}
+
+	observations, err := s.repo.ListObservations(ctx, patientID, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.CountObservations(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if observations == nil {
+		observations = []model.LabObservation{}
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedLabObservationsResponse{
+		Items: observations,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// ListReconciliation returns the reconciliation queue to admins, oldest
+// first.
+func (s *LabService) ListReconciliation(ctx context.Context, filter model.ReconciliationFilter, params model.PaginationParams, callerRole string) (*model.PaginatedLabReconciliationResponse, error) {
+	if !isAdmin(callerRole) {
+		return nil, fmt.Errorf("only admins can reconcile lab results: %w", model.ErrForbidden)
+	}
+
+	items, err := s.repo.ListReconciliation(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.CountReconciliation(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if items == nil {
+		items = []model.LabReconciliation{}
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedLabReconciliationResponse{
+		Items: items,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// pendingReconciliation returns a reconciliation item that is still
+// pending, for an admin.
+func (s *LabService) pendingReconciliation(ctx context.Context, id uuid.UUID, callerRole string) (*model.LabReconciliation, error) {
+	if !isAdmin(callerRole) {
+		return nil, fmt.Errorf("only admins can reconcile lab results: %w", model.ErrForbidden)
+	}
+
+	item, err := s.repo.GetReconciliation(ctx, id)
+	if err != nil {
+		return nil, reconciliationError(err)
+	}
+	if item.Status != model.ReconciliationPending {
+		return nil, fmt.Errorf("reconciliation item is already %s: %w", item.Status, model.ErrConflict)
+	}
+	return item, nil
+}
+
+// Resolve files the results of a pending reconciliation item under the
+// patient they belong to.
+func (s *LabService) Resolve(ctx context.Context, id uuid.UUID, data *model.ResolveReconciliation, callerID uuid.UUID, callerRole string) (*model.LabReconciliation, error) {
+	item, err := s.pendingReconciliation(ctx, id, callerRole)
+	if err != nil {
+		return nil, err
+	}
+
+	patient, err := s.patients.GetByID(ctx, data.PatientID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, model.ValidationErrors{model.FieldError{Field: "patient_id", Message: "patient not found"}}
+		}
+		return nil, err
+	}
+
+	// The results are interpreted now that the patient's sex and age are
+	// known.
+	observations, err := s.repo.ReconciliationObservations(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	interpretations := map[uuid.UUID]string{}
+	now := time.Now()
+	for i := range observations {
+		o := &observations[i]
+		o.PatientID = &patient.ID
+		o.Interpretation = s.ranges.Interpret(o, patient.Sex, patient.DateOfBirth, now)
+		if o.Interpretation != nil {
+			interpretations[o.ID] = *o.Interpretation
+		}
+	}
+
+	if err := s.repo.Resolve(ctx, id, data.PatientID, callerID, optionalString(data.Note), interpretations); err != nil {
+		return nil, reconciliationError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditLabReconciliationResolved, "lab_reconciliation", id, map[string]any{
+		"message_id":   item.MessageID,
+		"patient_id":   data.PatientID,
+		"observations": item.Observations,
+	})
+	s.alertCritical(ctx, observations, &callerID)
+
+	item, err = s.repo.GetReconciliation(ctx, id)
+	if err != nil {
+		return nil, reconciliationError(err)
+	}
+	return item, nil
+}
+
+// Discard closes a pending reconciliation item without filing its results.
+func (s *LabService) Discard(ctx context.Context, id uuid.UUID, data *model.DiscardReconciliation, callerID uuid.UUID, callerRole string) (*model.LabReconciliation, error) {
+	item, err := s.pendingReconciliation(ctx, id, callerRole)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Discard(ctx, id, callerID, data.Reason); err != nil {
+		return nil, reconciliationError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditLabReconciliationDiscarded, "lab_reconciliation", id, map[string]any{
+		"message_id":   item.MessageID,
+		"reason":       data.Reason,
+		"observations": item.Observations,
+	})
+
+	item, err = s.repo.GetReconciliation(ctx, id)
+	if err != nil {
+		return nil, reconciliationError(err)
+	}
+	return item, nil
+}
+
+// ListCritical returns the critical results nobody has acknowledged, oldest
+// first: all of them to admins, and to a practitioner those they ordered.
+func (s *LabService) ListCritical(ctx context.Context, params model.PaginationParams, callerID uuid.UUID, callerRole string) (*model.PaginatedLabObservationsResponse, error) {
+	var orderedBy *uuid.UUID
+	if !isAdmin(callerRole) {
+		ok, err := s.practitioners.IsPractitioner(ctx, callerID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("only practitioners and admins can list critical lab results: %w", model.ErrForbidden)
+		}
+		orderedBy = &callerID
+	}
+
+	observations, err := s.repo.ListCritical(ctx, orderedBy, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.CountCritical(ctx, orderedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if observations == nil {
+		observations = []model.LabObservation{}
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedLabObservationsResponse{
+		Items: observations,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// AcknowledgeCritical records that the practitioner who ordered a critical
+// result, or an admin, has seen it, taking it off the critical list.
+func (s *LabService) AcknowledgeCritical(ctx context.Context, id, callerID uuid.UUID, callerRole string) (*model.LabObservation, error) {
+	o, err := s.repo.GetObservation(ctx, id)
+	if err != nil {
+		return nil, observationError(err)
+	}
+
+	if !isAdmin(callerRole) {
+		allowed := false
+		if o.OrderingPractitionerID != nil {
+			p, err := s.practitioners.GetByID(ctx, *o.OrderingPractitionerID)
+			if err != nil && !errors.Is(err, model.ErrNotFound) {
+				return nil, err
+			}
+			allowed = p != nil && p.UserID == callerID
+		}
+		if !allowed {
+			// Only clinicians, who can see every result, learn that the
+			// result exists.
+			clinician, err := s.practitioners.IsPractitioner(ctx, callerID)
+			if err != nil {
+				return nil, err
+			}
+			if !clinician {
+				return nil, fmt.Errorf("lab result %w", model.ErrNotFound)
+			}
+			return nil, fmt.Errorf("only the practitioner who ordered a critical result or an admin can acknowledge it: %w", model.ErrForbidden)
+		}
+	}
+
+	if o.PatientID == nil || o.Interpretation == nil || *o.Interpretation != model.InterpretationCritical {
+		return nil, fmt.Errorf("lab result is not critical: %w", model.ErrConflict)
+	}
+	if o.AcknowledgedAt != nil {
+		return nil, fmt.Errorf("critical lab result is already acknowledged: %w", model.ErrConflict)
+	}
+
+	if err := s.repo.AcknowledgeCritical(ctx, id, callerID); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("critical lab result is already acknowledged: %w", model.ErrConflict)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditLabCriticalAcknowledged, "lab_observation", id, map[string]any{
+		"patient_id": o.PatientID,
+		"code":       o.Code,
+	})
+
+	o, err = s.repo.GetObservation(ctx, id)
+	if err != nil {
+		return nil, observationError(err)
+	}
+	return o, nil
+}
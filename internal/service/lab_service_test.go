@@ -0,0 +1,1333 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/hl7"
+	"github.com/PranavJoshi2893/med-portal/pkg/mllp"
+	"github.com/google/uuid"
+)
+
+// mockLabRepo keeps lab messages, reconciliation items and results in
+// memory.
+type mockLabRepo struct {
+	messages     []model.LabMessage
+	items        []*model.LabReconciliation
+	observations []*model.LabObservation
+
+	// practitionerUsers maps practitioner IDs to their logins.
+	practitionerUsers map[uuid.UUID]uuid.UUID
+
+	storeFunc               func(ctx context.Context, msg model.LabMessage, items []model.LabReconciliation, observations []model.LabObservation) error
+	resolveFunc             func(ctx context.Context, id, patientID, resolvedBy uuid.UUID, note *string, interpretations map[uuid.UUID]string) error
+	discardFunc             func(ctx context.Context, id, resolvedBy uuid.UUID, reason string) error
+	acknowledgeCriticalFunc func(ctx context.Context, id, acknowledgedBy uuid.UUID) error
+}
+
+func (m *mockLabRepo) Store(ctx context.Context, msg model.LabMessage, items []model.LabReconciliation, observations []model.LabObservation) error {
+	if m.storeFunc != nil {
+		return m.storeFunc(ctx, msg, items, observations)
+	}
+	for _, existing := range m.messages {
+		if existing.SendingApplication == msg.SendingApplication && existing.SendingFacility == msg.SendingFacility &&
+			existing.ControlID == msg.ControlID {
+			return model.ErrConflict
+		}
+	}
+	m.messages = append(m.messages, msg)
+	for _, item := range items {
+		item.Status = model.ReconciliationPending
+		m.items = append(m.items, &item)
+	}
+	for _, o := range observations {
+		m.observations = append(m.observations, &o)
+	}
+	return nil
+}
+
+func (m *mockLabRepo) ListObservations(ctx context.Context, patientID uuid.UUID, limit, offset int) ([]model.LabObservation, error) {
+	var list []model.LabObservation
+	for _, o := range m.observations {
+		if o.PatientID != nil && *o.PatientID == patientID {
+			list = append(list, *o)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockLabRepo) CountObservations(ctx context.Context, patientID uuid.UUID) (int, error) {
+	list, _ := m.ListObservations(ctx, patientID, 0, 0)
+	return len(list), nil
+}
+
+func (m *mockLabRepo) GetReconciliation(ctx context.Context, id uuid.UUID) (*model.LabReconciliation, error) {
+	for _, item := range m.items {
+		if item.ID == id {
+			copy := *item
+			for _, o := range m.observations {
+				if o.ReconciliationID != nil && *o.ReconciliationID == id {
+					copy.Observations++
+				}
+			}
+			return &copy, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockLabRepo) ListReconciliation(ctx context.Context, filter model.ReconciliationFilter, limit, offset int) ([]model.LabReconciliation, error) {
+	var list []model.LabReconciliation
+	for _, item := range m.items {
+		if item.Status == filter.Status {
+			list = append(list, *item)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockLabRepo) CountReconciliation(ctx context.Context, filter model.ReconciliationFilter) (int, error) {
+	list, _ := m.ListReconciliation(ctx, filter, 0, 0)
+	return len(list), nil
+}
+
+func (m *mockLabRepo) ReconciliationObservations(ctx context.Context, id uuid.UUID) ([]model.LabObservation, error) {
+	var list []model.LabObservation
+	for _, o := range m.observations {
+		if o.ReconciliationID != nil && *o.ReconciliationID == id {
+			list = append(list, *o)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockLabRepo) Resolve(ctx context.Context, id, patientID, resolvedBy uuid.UUID, note *string, interpretations map[uuid.UUID]string) error {
+	if m.resolveFunc != nil {
+		return m.resolveFunc(ctx, id, patientID, resolvedBy, note, interpretations)
+	}
+	for _, item := range m.items {
+		if item.ID == id && item.Status == model.ReconciliationPending {
+			item.Status = model.ReconciliationResolved
+			item.PatientID = &patientID
+			item.ResolvedBy = &resolvedBy
+			item.Note = note
+			for _, o := range m.observations {
+				if o.ReconciliationID != nil && *o.ReconciliationID == id {
+					o.PatientID = &patientID
+					if interpretation, ok := interpretations[o.ID]; ok {
+						o.Interpretation = &interpretation
+					}
+				}
+			}
+			return nil
+		}
+	}
+	return model.ErrNotFound
+}
+
+func (m *mockLabRepo) Discard(ctx context.Context, id, resolvedBy uuid.UUID, reason string) error {
+	if m.discardFunc != nil {
+		return m.discardFunc(ctx, id, resolvedBy, reason)
+	}
+	for _, item := range m.items {
+		if item.ID == id && item.Status == model.ReconciliationPending {
+			item.Status = model.ReconciliationDiscarded
+			item.ResolvedBy = &resolvedBy
+			item.Note = &reason
+			return nil
+		}
+	}
+	return model.ErrNotFound
+}
+
+func (m *mockLabRepo) GetObservation(ctx context.Context, id uuid.UUID) (*model.LabObservation, error) {
+	for _, o := range m.observations {
+		if o.ID == id {
+			copy := *o
+			return &copy, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockLabRepo) ListCritical(ctx context.Context, orderedBy *uuid.UUID, limit, offset int) ([]model.LabObservation, error) {
+	var list []model.LabObservation
+	for _, o := range m.observations {
+		if o.PatientID == nil || o.Interpretation == nil || *o.Interpretation != model.InterpretationCritical || o.AcknowledgedAt != nil {
+			continue
+		}
+		if orderedBy != nil && (o.OrderingPractitionerID == nil || m.practitionerUsers[*o.OrderingPractitionerID] != *orderedBy) {
+			continue
+		}
+		list = append(list, *o)
+	}
+	return list, nil
+}
+
+func (m *mockLabRepo) CountCritical(ctx context.Context, orderedBy *uuid.UUID) (int, error) {
+	list, _ := m.ListCritical(ctx, orderedBy, 0, 0)
+	return len(list), nil
+}
+
+func (m *mockLabRepo) AcknowledgeCritical(ctx context.Context, id, acknowledgedBy uuid.UUID) error {
+	if m.acknowledgeCriticalFunc != nil {
+		return m.acknowledgeCriticalFunc(ctx, id, acknowledgedBy)
+	}
+	for _, o := range m.observations {
+		if o.ID == id && o.Interpretation != nil && *o.Interpretation == model.InterpretationCritical && o.AcknowledgedAt == nil {
+			now := time.Now()
+			o.AcknowledgedBy = &acknowledgedBy
+			o.AcknowledgedAt = &now
+			return nil
+		}
+	}
+	return model.ErrNotFound
+}
+
+// oruMessage builds an ORU^R01 message with one result for the patient in
+// pid.
+func oruMessage(controlID, pid, obx string) string {
+	return "MSH|^~\\&|LAB|ACME LAB|MEDPORTAL|CLINIC|20261019083000||ORU^R01^ORU_R01|" + controlID + "|P|2.5.1\r" +
+		pid + "\r" +
+		"ORC|RE|ORD123\r" +
+		"OBR|1|ORD123|FIL456|24331-1^Lipid panel^LN|||20261018073000\r" +
+		obx + "\r" +
+		"NTE|1||Fasting sample\r"
+}
+
+const (
+	labPID = "PID|1||MRN-000001^^^CLINIC^MR~NHS-42^^^https://nhs.uk^NH||Doe^Jane||19800214|F"
+	labOBX = "OBX|1|NM|2093-3^Cholesterol^LN||212|mg/dL|<200|H~A|||F|||20261018071500-0400"
+)
+
+// labUnmatchedPID names a patient the clinic does not know, so the results
+// are held for reconciliation.
+const labUnmatchedPID = "PID|1||MRN-999999^^^CLINIC^MR||Doe^Jane||19800214|F"
+
+// ackOf parses an acknowledgment and returns its MSA segment.
+func ackOf(t *testing.T, raw []byte) hl7.Segment {
+	t.Helper()
+	ack, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("invalid acknowledgment %q: %v", raw, err)
+	}
+	msa, ok := ack.Segment("MSA")
+	if !ok {
+		t.Fatalf("acknowledgment %q has no MSA segment", raw)
+	}
+	return msa
+}
+
+// labOrderedOBR is the OBR of oruMessage with Dr House, by NPI, as the
+// ordering provider in OBR-16.
+const labOrderedOBR = "OBR|1|ORD123|FIL456|24331-1^Lipid panel^LN|||20261018073000|||||||||1234567890^House^Gregory"
+
+// labPotassiumOBX is a critically high potassium result.
+const labPotassiumOBX = "OBX|1|NM|2823-3^Potassium^LN||7.2|mmol/L|3.5-5.1|HH|||F"
+
+// orderedMessage is oruMessage with the order placed by Dr House.
+func orderedMessage(controlID, pid, obx string) string {
+	return strings.Replace(oruMessage(controlID, pid, obx), "OBR|1|ORD123|FIL456|24331-1^Lipid panel^LN|||20261018073000", labOrderedOBR, 1)
+}
+
+// newLabPatientRepo returns a patient repository that knows only Jane Doe,
+// the patient in labPID, under patientID.
+func newLabPatientRepo(patientID uuid.UUID) *mockPatientRepo {
+	jane := model.PatientSummary{ID: patientID, MRN: "MRN-000001", FirstName: "Jane", LastName: "Doe", DateOfBirth: "1980-02-14", Sex: "female"}
+	return &mockPatientRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+			if id != patientID {
+				return nil, model.ErrNotFound
+			}
+			return &model.Patient{ID: id, MRN: jane.MRN, DateOfBirth: jane.DateOfBirth, Sex: jane.Sex}, nil
+		},
+		findFunc: func(ctx context.Context, mrns []string, identifiers []model.CreatePatientIdentifier) ([]model.PatientSummary, error) {
+			for _, mrn := range mrns {
+				if mrn == jane.MRN {
+					return []model.PatientSummary{jane}, nil
+				}
+			}
+			for _, id := range identifiers {
+				if id.System == "https://nhs.uk" && id.Value == "NHS-42" {
+					return []model.PatientSummary{jane}, nil
+				}
+			}
+			return nil, nil
+		},
+	}
+}
+
+// newLabPractitionerRepo returns a practitioner repository with Dr House,
+// who orders the results in orderedMessage, under practitionerID and the
+// login doctorID, and other practitioners with the logins in otherIDs.
+func newLabPractitionerRepo(practitionerID, doctorID uuid.UUID, otherIDs ...uuid.UUID) *mockPractitionerRepo {
+	house := &model.Practitioner{ID: practitionerID, UserID: doctorID, FirstName: "Gregory", LastName: "House", Email: "house@example.com"}
+	users := map[uuid.UUID]bool{doctorID: true}
+	for _, id := range otherIDs {
+		users[id] = true
+	}
+	return &mockPractitionerRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Practitioner, error) {
+			if id != practitionerID {
+				return nil, model.ErrNotFound
+			}
+			return house, nil
+		},
+		findByNPIFunc: func(ctx context.Context, npi string) (*model.Practitioner, error) {
+			if npi != "1234567890" {
+				return nil, model.ErrNotFound
+			}
+			return house, nil
+		},
+		practitionerUsers: users,
+	}
+}
+
+// newLabRanges returns reference ranges for adult cholesterol and for
+// potassium, with critical limits.
+func newLabRanges(t *testing.T) *ReferenceRanges {
+	t.Helper()
+	limit := func(v float64) *float64 { return &v }
+	adult, _ := model.ParseAge("18y")
+	ranges, err := NewReferenceRanges([]model.ReferenceRange{
+		{Code: "2093-3", Units: "mg/dL", MinAge: &adult, High: limit(200)},
+		{Code: "2823-3", Units: "mmol/L", Low: limit(3.5), High: limit(5.1), CriticalLow: limit(2.5), CriticalHigh: limit(6.5)},
+	})
+	if err != nil {
+		t.Fatalf("invalid reference ranges: %v", err)
+	}
+	return ranges
+}
+
+func TestLabService_Ingest(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+
+	repo := &mockLabRepo{}
+	audit := &mockAuditRepo{}
+	service := NewLabService(repo, newLabPatientRepo(patientID), &mockPractitionerRepo{}, audit, &mockMailer{}, newLabRanges(t), time.UTC)
+	ctx := repository.WithTenant(context.Background(), orgID)
+
+	ack, err := service.Receive(ctx, []byte(oruMessage("MSG1", labPID, labOBX)), adminID, "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msa := ackOf(t, ack); msa.Value(1, 1) != hl7.AckAccept || msa.Value(2, 1) != "MSG1" {
+		t.Errorf("MSA = %+v, want AA for MSG1", msa)
+	}
+
+	if len(repo.messages) != 1 || len(repo.items) != 0 || len(repo.observations) != 1 {
+		t.Fatalf("stored %d messages, %d items and %d results, want one matched result",
+			len(repo.messages), len(repo.items), len(repo.observations))
+	}
+	o := repo.observations[0]
+	if o.PatientID == nil || *o.PatientID != patientID {
+		t.Errorf("result filed under %v, want the patient", o.PatientID)
+	}
+	if o.Code != "2093-3" || o.Name != "Cholesterol" || o.Value == nil || *o.Value != "212" || *o.Units != "mg/dL" ||
+		o.ResultStatus != "F" || o.ServiceCode != "24331-1" || *o.PlacerOrderNumber != "ORD123" {
+		t.Errorf("result = %+v", o)
+	}
+	if len(o.AbnormalFlags) != 2 || o.AbnormalFlags[0] != "H" || o.Note == nil || *o.Note != "Fasting sample" {
+		t.Errorf("flags %v and note %v, want H, A and the NTE", o.AbnormalFlags, o.Note)
+	}
+	if o.Interpretation == nil || *o.Interpretation != model.InterpretationHigh {
+		t.Errorf("interpretation %v, want high against the configured range", o.Interpretation)
+	}
+	if want := time.Date(2026, 10, 18, 11, 15, 0, 0, time.UTC); o.ObservedAt == nil || !o.ObservedAt.Equal(want) {
+		t.Errorf("observed at %v, want %v from OBX-14", o.ObservedAt, want)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditLabMessageAccepted {
+		t.Errorf("expected the message to be audited, got %+v", audit.entries)
+	}
+}
+
+func TestLabService_Ingest_Rejected(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name      string
+		message   string
+		code      string
+		errorCode string
+	}{
+		{
+			name:      "message type",
+			message:   strings.Replace(oruMessage("MSG2", labPID, labOBX), "ORU^R01^ORU_R01", "ADT^A01^ADT_A01", 1),
+			code:      hl7.AckReject,
+			errorCode: hl7.ErrUnsupportedMessageType,
+		},
+		{
+			name:      "trigger event",
+			message:   strings.Replace(oruMessage("MSG2", labPID, labOBX), "ORU^R01^ORU_R01", "ORU^R30", 1),
+			code:      hl7.AckReject,
+			errorCode: hl7.ErrUnsupportedEventCode,
+		},
+		{
+			name:      "control id",
+			message:   oruMessage("", labPID, labOBX),
+			code:      hl7.AckReject,
+			errorCode: hl7.ErrRequiredFieldMissing,
+		},
+		{
+			name:      "numeric value",
+			message:   oruMessage("MSG2", labPID, "OBX|1|NM|2093-3^Cholesterol^LN||high|mg/dL|||||F"),
+			code:      hl7.AckError,
+			errorCode: hl7.ErrDataType,
+		},
+		{
+			name:      "result status",
+			message:   oruMessage("MSG2", labPID, "OBX|1|NM|2093-3^Cholesterol^LN||212|mg/dL|||||Q"),
+			code:      hl7.AckError,
+			errorCode: hl7.ErrTableValueNotFound,
+		},
+		{
+			name:      "observation identifier",
+			message:   oruMessage("MSG2", labPID, "OBX|1|NM|||212|mg/dL|||||F"),
+			code:      hl7.AckError,
+			errorCode: hl7.ErrRequiredFieldMissing,
+		},
+		{
+			name:      "result before an order",
+			message:   "MSH|^~\\&|LAB|ACME LAB|||20261019||ORU^R01|MSG2|P|2.5.1\r" + labPID + "\r" + labOBX + "\r",
+			code:      hl7.AckError,
+			errorCode: hl7.ErrSegmentSequence,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockLabRepo{}
+			audit := &mockAuditRepo{}
+			service := NewLabService(repo, newLabPatientRepo(patientID), &mockPractitionerRepo{}, audit, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			ack, err := service.Receive(ctx, []byte(tc.message), adminID, "admin")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			parsed, _ := hl7.Parse(ack)
+			msa, _ := parsed.Segment("MSA")
+			errSeg, ok := parsed.Segment("ERR")
+			if msa.Value(1, 1) != tc.code || !ok || errSeg.Value(3, 1) != tc.errorCode {
+				t.Errorf("acknowledged %q, want %s with error %s", ack, tc.code, tc.errorCode)
+			}
+			if len(repo.messages) != 0 {
+				t.Error("expected nothing to be stored")
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditLabMessageRejected {
+				t.Errorf("expected the rejection to be audited, got %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestLabService_Receive(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	userID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name            string
+		message         string
+		storeFunc       func(ctx context.Context, msg model.LabMessage, items []model.LabReconciliation, observations []model.LabObservation) error
+		callerID        uuid.UUID
+		callerRole      string
+		unscoped        bool
+		expectErr       error
+		expectCode      string
+		expectErrorCode string
+		expectStored    int
+		expectAudit     string
+	}{
+		{
+			name:         "accepted",
+			message:      oruMessage("MSG3", labPID, labOBX),
+			callerID:     adminID,
+			callerRole:   "admin",
+			expectCode:   hl7.AckAccept,
+			expectStored: 1,
+			expectAudit:  model.AuditLabMessageAccepted,
+		},
+		{
+			// The lab sends a message again, e.g. after losing the first
+			// acknowledgment.
+			name:    "duplicate",
+			message: oruMessage("MSG3", labPID, labOBX),
+			storeFunc: func(ctx context.Context, msg model.LabMessage, items []model.LabReconciliation, observations []model.LabObservation) error {
+				return model.ErrConflict
+			},
+			callerID:   adminID,
+			callerRole: "admin",
+			expectCode: hl7.AckAccept,
+		},
+		{
+			name:    "repo error",
+			message: oruMessage("MSG3", labPID, labOBX),
+			storeFunc: func(ctx context.Context, msg model.LabMessage, items []model.LabReconciliation, observations []model.LabObservation) error {
+				return errRepo
+			},
+			callerID:        adminID,
+			callerRole:      "admin",
+			expectCode:      hl7.AckError,
+			expectErrorCode: hl7.ErrApplicationInternal,
+			expectAudit:     model.AuditLabMessageRejected,
+		},
+		{
+			name:       "not a message",
+			message:    "not a message",
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  model.ErrBadRequest,
+		},
+		{
+			name:       "user",
+			message:    oruMessage("MSG3", labPID, labOBX),
+			callerID:   userID,
+			callerRole: "user",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name:       "no organisation",
+			message:    oruMessage("MSG3", labPID, labOBX),
+			callerID:   adminID,
+			callerRole: "admin",
+			unscoped:   true,
+			expectErr:  model.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockLabRepo{storeFunc: tt.storeFunc}
+			audit := &mockAuditRepo{}
+			service := NewLabService(repo, newLabPatientRepo(patientID), &mockPractitionerRepo{}, audit, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			if tt.unscoped {
+				ctx = context.Background()
+			}
+			ack, err := service.Receive(ctx, []byte(tt.message), tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msa := ackOf(t, ack); msa.Value(1, 1) != tt.expectCode || msa.Value(2, 1) != "MSG3" {
+				t.Errorf("MSA = %+v, want %s for MSG3", msa, tt.expectCode)
+			}
+			if tt.expectErrorCode != "" {
+				parsed, _ := hl7.Parse(ack)
+				if errSeg, ok := parsed.Segment("ERR"); !ok || errSeg.Value(3, 1) != tt.expectErrorCode {
+					t.Errorf("acknowledged %q, want error %s", ack, tt.expectErrorCode)
+				}
+			}
+			if len(repo.messages) != tt.expectStored {
+				t.Errorf("stored %d messages, want %d", len(repo.messages), tt.expectStored)
+			}
+			switch {
+			case tt.expectAudit == "" && len(audit.entries) != 0:
+				t.Errorf("audited %+v, want nothing", audit.entries)
+			case tt.expectAudit != "" && (len(audit.entries) != 1 || audit.entries[0].Action != tt.expectAudit):
+				t.Errorf("audited %+v, want %s", audit.entries, tt.expectAudit)
+			}
+		})
+	}
+}
+
+func TestLabService_ListObservations(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	userID, _ := uuid.NewV7()
+	observationID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name        string
+		callerID    uuid.UUID
+		callerRole  string
+		expectErr   error
+		expectTotal int
+	}{
+		{name: "admin", callerID: adminID, callerRole: "admin", expectTotal: 1},
+		{name: "practitioner", callerID: doctorID, callerRole: "user", expectTotal: 1},
+		{name: "stranger", callerID: userID, callerRole: "user", expectErr: model.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockLabRepo{observations: []*model.LabObservation{{ID: observationID, PatientID: &patientID, Code: "2093-3"}}}
+			service := NewLabService(repo, newLabPatientRepo(patientID), newLabPractitionerRepo(practitionerID, doctorID), &mockAuditRepo{}, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			page, err := service.ListObservations(ctx, patientID, model.PaginationParams{Page: 1, Limit: 20}, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if page.Meta.Total != tt.expectTotal || len(page.Items) != tt.expectTotal {
+				t.Errorf("listed %+v, want %d results", page, tt.expectTotal)
+			}
+		})
+	}
+}
+
+func TestLabService_Reconciliation(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name   string
+		pid    string
+		reason string
+	}{
+		{"unknown mrn", labUnmatchedPID, model.ReconcileNoMatch},
+		{"no identifiers", "PID|1||||Doe^Jane||19800214|F", model.ReconcileNoIdentifiers},
+		{"date of birth", "PID|1||MRN-000001^^^CLINIC^MR||Doe^Jane||19800215|F", model.ReconcileDemographics},
+		{"family name", "PID|1||NHS-42^^^https://nhs.uk^NH||Roe^Jane||19800214|F", model.ReconcileDemographics},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockLabRepo{}
+			service := NewLabService(repo, newLabPatientRepo(patientID), &mockPractitionerRepo{}, &mockAuditRepo{}, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			ack, err := service.Receive(ctx, []byte(oruMessage("MSG4", tc.pid, labOBX)), adminID, "admin")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msa := ackOf(t, ack); msa.Value(1, 1) != hl7.AckAccept {
+				t.Errorf("MSA = %+v, want AA", msa)
+			}
+			if len(repo.items) != 1 || repo.items[0].Reason != tc.reason {
+				t.Fatalf("queued %+v, want one item because %s", repo.items, tc.reason)
+			}
+			if o := repo.observations[0]; o.PatientID != nil || o.ReconciliationID == nil || *o.ReconciliationID != repo.items[0].ID {
+				t.Errorf("result = %+v, want it held for reconciliation", o)
+			}
+		})
+	}
+}
+
+func TestLabService_ListReconciliation(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	itemID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name        string
+		status      string
+		callerRole  string
+		expectErr   error
+		expectTotal int
+	}{
+		{name: "pending", status: model.ReconciliationPending, callerRole: "admin", expectTotal: 1},
+		{name: "resolved", status: model.ReconciliationResolved, callerRole: "admin", expectTotal: 0},
+		{name: "practitioner", status: model.ReconciliationPending, callerRole: "user", expectErr: model.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockLabRepo{items: []*model.LabReconciliation{{ID: itemID, Status: model.ReconciliationPending, Reason: model.ReconcileNoMatch}}}
+			service := NewLabService(repo, &mockPatientRepo{}, &mockPractitionerRepo{practitionerUsers: map[uuid.UUID]bool{doctorID: true}}, &mockAuditRepo{}, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			page, err := service.ListReconciliation(ctx, model.ReconciliationFilter{Status: tt.status}, model.PaginationParams{Page: 1, Limit: 20}, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if page.Meta.Total != tt.expectTotal || len(page.Items) != tt.expectTotal {
+				t.Errorf("listed %+v, want %d items", page, tt.expectTotal)
+			}
+		})
+	}
+}
+
+func TestLabService_Resolve(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	unknownID, _ := uuid.NewV7()
+	itemID, _ := uuid.NewV7()
+	observationID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name        string
+		mockFunc    func(ctx context.Context, id, patientID, resolvedBy uuid.UUID, note *string, interpretations map[uuid.UUID]string) error
+		itemID      uuid.UUID
+		status      string
+		patientID   uuid.UUID
+		callerID    uuid.UUID
+		callerRole  string
+		expectErr   error
+		expectField string
+	}{
+		{
+			name:       "resolved",
+			itemID:     itemID,
+			status:     model.ReconciliationPending,
+			patientID:  patientID,
+			callerID:   adminID,
+			callerRole: "admin",
+		},
+		{
+			name:        "unknown patient",
+			itemID:      itemID,
+			status:      model.ReconciliationPending,
+			patientID:   unknownID,
+			callerID:    adminID,
+			callerRole:  "admin",
+			expectField: "patient_id",
+		},
+		{
+			name:       "unknown item",
+			itemID:     unknownID,
+			status:     model.ReconciliationPending,
+			patientID:  patientID,
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  model.ErrNotFound,
+		},
+		{
+			name:       "already resolved",
+			itemID:     itemID,
+			status:     model.ReconciliationResolved,
+			patientID:  patientID,
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  model.ErrConflict,
+		},
+		{
+			name:       "already discarded",
+			itemID:     itemID,
+			status:     model.ReconciliationDiscarded,
+			patientID:  patientID,
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  model.ErrConflict,
+		},
+		{
+			name:       "practitioner",
+			itemID:     itemID,
+			status:     model.ReconciliationPending,
+			patientID:  patientID,
+			callerID:   doctorID,
+			callerRole: "user",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name: "resolved meanwhile",
+			mockFunc: func(ctx context.Context, id, patientID, resolvedBy uuid.UUID, note *string, interpretations map[uuid.UUID]string) error {
+				return model.ErrNotFound
+			},
+			itemID:     itemID,
+			status:     model.ReconciliationPending,
+			patientID:  patientID,
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  model.ErrNotFound,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, id, patientID, resolvedBy uuid.UUID, note *string, interpretations map[uuid.UUID]string) error {
+				return errRepo
+			},
+			itemID:     itemID,
+			status:     model.ReconciliationPending,
+			patientID:  patientID,
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockLabRepo{
+				items:        []*model.LabReconciliation{{ID: itemID, Status: tt.status, Reason: model.ReconcileNoMatch}},
+				observations: []*model.LabObservation{{ID: observationID, ReconciliationID: &itemID, Code: "2093-3"}},
+				resolveFunc:  tt.mockFunc,
+			}
+			audit := &mockAuditRepo{}
+			service := NewLabService(repo, newLabPatientRepo(patientID), &mockPractitionerRepo{practitionerUsers: map[uuid.UUID]bool{doctorID: true}}, audit, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			item, err := service.Resolve(ctx, tt.itemID, &model.ResolveReconciliation{PatientID: tt.patientID, Note: "MRN typo"}, tt.callerID, tt.callerRole)
+
+			if tt.expectField != "" {
+				var verrs model.ValidationErrors
+				if !errors.As(err, &verrs) || verrs[0].Field != tt.expectField {
+					t.Fatalf("expected a %s validation error, got %v", tt.expectField, err)
+				}
+				return
+			}
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("audited %+v, want nothing", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if item.Status != model.ReconciliationResolved || item.PatientID == nil || *item.PatientID != patientID {
+				t.Errorf("item = %+v, want it resolved to the patient", item)
+			}
+			page, _ := service.ListObservations(ctx, patientID, model.PaginationParams{Page: 1, Limit: 20}, adminID, "admin")
+			if page.Meta.Total != 1 {
+				t.Errorf("patient has %d results, want the reconciled one", page.Meta.Total)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditLabReconciliationResolved {
+				t.Errorf("audited %+v, want the resolution", audit.entries)
+			}
+		})
+	}
+}
+
+func TestLabService_Discard(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	unknownID, _ := uuid.NewV7()
+	itemID, _ := uuid.NewV7()
+	observationID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		mockFunc   func(ctx context.Context, id, resolvedBy uuid.UUID, reason string) error
+		itemID     uuid.UUID
+		status     string
+		callerID   uuid.UUID
+		callerRole string
+		expectErr  error
+	}{
+		{
+			name:       "discarded",
+			itemID:     itemID,
+			status:     model.ReconciliationPending,
+			callerID:   adminID,
+			callerRole: "admin",
+		},
+		{
+			name:       "unknown item",
+			itemID:     unknownID,
+			status:     model.ReconciliationPending,
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  model.ErrNotFound,
+		},
+		{
+			name:       "already resolved",
+			itemID:     itemID,
+			status:     model.ReconciliationResolved,
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  model.ErrConflict,
+		},
+		{
+			name:       "practitioner",
+			itemID:     itemID,
+			status:     model.ReconciliationPending,
+			callerID:   doctorID,
+			callerRole: "user",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, id, resolvedBy uuid.UUID, reason string) error {
+				return errRepo
+			},
+			itemID:     itemID,
+			status:     model.ReconciliationPending,
+			callerID:   adminID,
+			callerRole: "admin",
+			expectErr:  errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockLabRepo{
+				items:        []*model.LabReconciliation{{ID: itemID, Status: tt.status, Reason: model.ReconcileNoMatch}},
+				observations: []*model.LabObservation{{ID: observationID, ReconciliationID: &itemID, Code: "2093-3"}},
+				discardFunc:  tt.mockFunc,
+			}
+			audit := &mockAuditRepo{}
+			service := NewLabService(repo, newLabPatientRepo(patientID), &mockPractitionerRepo{practitionerUsers: map[uuid.UUID]bool{doctorID: true}}, audit, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			item, err := service.Discard(ctx, tt.itemID, &model.DiscardReconciliation{Reason: "sent to the wrong clinic"}, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("audited %+v, want nothing", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if item.Status != model.ReconciliationDiscarded || item.PatientID != nil {
+				t.Errorf("item = %+v, want it discarded", item)
+			}
+			page, _ := service.ListObservations(ctx, patientID, model.PaginationParams{Page: 1, Limit: 20}, adminID, "admin")
+			if page.Meta.Total != 0 {
+				t.Errorf("patient has %d results, want discarded results left out", page.Meta.Total)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditLabReconciliationDiscarded {
+				t.Errorf("audited %+v, want the discard", audit.entries)
+			}
+		})
+	}
+}
+
+func TestLabService_MLLPHandler(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+
+	repo := &mockLabRepo{}
+	audit := &mockAuditRepo{}
+	service := NewLabService(repo, newLabPatientRepo(patientID), &mockPractitionerRepo{}, audit, &mockMailer{}, newLabRanges(t), time.UTC)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &mllp.Server{Handler: service.MLLPHandler(orgID)}
+	go srv.Serve(ln)
+	defer srv.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	for _, controlID := range []string{"MSG1", "MSG2"} {
+		if err := mllp.WriteMessage(conn, []byte(oruMessage(controlID, labPID, labOBX))); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		ack, err := mllp.ReadMessage(r, mllp.DefaultMaxMessageBytes)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if msa := ackOf(t, ack); msa.Value(1, 1) != hl7.AckAccept || msa.Value(2, 1) != controlID {
+			t.Errorf("MSA = %+v, want AA for %s", msa, controlID)
+		}
+	}
+
+	if len(repo.messages) != 2 || repo.messages[0].OrganisationID != orgID || repo.messages[0].ReceivedBy != nil {
+		t.Errorf("stored %+v, want both messages for the organisation with no user", repo.messages)
+	}
+	if len(audit.entries) != 2 || audit.entries[0].ActorID != nil {
+		t.Errorf("audited %+v, want both messages without an actor", audit.entries)
+	}
+}
+
+func TestLabService_MLLPHandler_Rejected(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name      string
+		message   string
+		code      string
+		controlID string
+		errorCode string
+		stayOpen  bool
+	}{
+		{
+			name:      "too large",
+			message:   oruMessage("MSG3", labPID, labOBX) + strings.Repeat("NTE|2||padding\r", 100),
+			code:      hl7.AckReject,
+			controlID: "MSG3",
+			errorCode: hl7.ErrApplicationInternal,
+		},
+		{
+			name:      "malformed",
+			message:   oruMessage("MSG4", labPID, "OB!|1"),
+			code:      hl7.AckError,
+			controlID: "MSG4",
+			errorCode: hl7.ErrSegmentSequence,
+			stayOpen:  true,
+		},
+		{
+			name:    "no header",
+			message: "PID|1||MRN-000001\r",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockLabRepo{}
+			audit := &mockAuditRepo{}
+			service := NewLabService(repo, newLabPatientRepo(patientID), &mockPractitionerRepo{}, audit, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			srv := &mllp.Server{Handler: service.MLLPHandler(orgID), MaxMessageBytes: 1024}
+			go srv.Serve(ln)
+			defer srv.Shutdown(context.Background())
+
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			r := bufio.NewReader(conn)
+
+			if err := mllp.WriteMessage(conn, []byte(tc.message)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			ack, err := mllp.ReadMessage(r, mllp.DefaultMaxMessageBytes)
+			if tc.code == "" {
+				if err == nil {
+					t.Errorf("got %q, want the connection closed without an answer", ack)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+
+			parsed, err := hl7.Parse(ack)
+			if err != nil {
+				t.Fatalf("invalid acknowledgment %q: %v", ack, err)
+			}
+			msa, _ := parsed.Segment("MSA")
+			errSegment, _ := parsed.Segment("ERR")
+			if msa.Value(1, 1) != tc.code || msa.Value(2, 1) != tc.controlID || errSegment.Value(3, 1) != tc.errorCode {
+				t.Errorf("acknowledged %q, want %s for %s with error %s", ack, tc.code, tc.controlID, tc.errorCode)
+			}
+			if len(repo.messages) != 0 || len(audit.entries) != 1 || audit.entries[0].Action != model.AuditLabMessageRejected {
+				t.Errorf("stored %d messages and audited %+v, want only the rejection audited", len(repo.messages), audit.entries)
+			}
+
+			// A malformed message leaves the stream in step, so the
+			// connection stays open; one that could not be read closes it
+			// once it is answered.
+			if tc.stayOpen {
+				if err := mllp.WriteMessage(conn, []byte(oruMessage("MSG5", labPID, labOBX))); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+				if ack, err := mllp.ReadMessage(r, mllp.DefaultMaxMessageBytes); err != nil || ackOf(t, ack).Value(1, 1) != hl7.AckAccept {
+					t.Errorf("got %q, %v, want the next message accepted", ack, err)
+				}
+			} else if _, err := r.ReadByte(); err == nil {
+				t.Error("expected the connection to be closed")
+			}
+		})
+	}
+}
+
+func TestLabService_CriticalResults(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+
+	repo := &mockLabRepo{}
+	audit := &mockAuditRepo{}
+	mailer := &mockMailer{}
+	service := NewLabService(repo, newLabPatientRepo(patientID), newLabPractitionerRepo(practitionerID, doctorID), audit, mailer, newLabRanges(t), time.UTC)
+	ctx := repository.WithTenant(context.Background(), orgID)
+
+	ack, err := service.Receive(ctx, []byte(orderedMessage("MSG7", labPID, labPotassiumOBX)), adminID, "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msa := ackOf(t, ack); msa.Value(1, 1) != hl7.AckAccept {
+		t.Fatalf("MSA = %+v, want AA", msa)
+	}
+
+	o := repo.observations[0]
+	if o.Interpretation == nil || *o.Interpretation != model.InterpretationCritical {
+		t.Errorf("interpretation %v, want critical", o.Interpretation)
+	}
+	if o.OrderingProvider == nil || *o.OrderingProvider != "1234567890" || o.OrderingPractitionerID == nil || *o.OrderingPractitionerID != practitionerID {
+		t.Errorf("ordered by %v (%v), want Dr House", o.OrderingProvider, o.OrderingPractitionerID)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "house@example.com" || !strings.Contains(mailer.sent[0].Body, "Potassium 7.2 mmol/L") {
+		t.Errorf("sent %+v, want an alert to the ordering practitioner", mailer.sent)
+	}
+	if last := audit.entries[len(audit.entries)-1]; last.Action != model.AuditLabCriticalResult {
+		t.Errorf("audited %+v, want the critical result", last)
+	}
+
+	// A result that is only high raises no alert.
+	if _, err := service.Receive(ctx, []byte(orderedMessage("MSG8", labPID, labOBX)), adminID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mailer.sent) != 1 {
+		t.Errorf("sent %d alerts, want none for a high result", len(mailer.sent)-1)
+	}
+}
+
+func TestLabService_CriticalResults_Reconciled(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+
+	repo := &mockLabRepo{}
+	mailer := &mockMailer{}
+	service := NewLabService(repo, newLabPatientRepo(patientID), newLabPractitionerRepo(practitionerID, doctorID), &mockAuditRepo{}, mailer, newLabRanges(t), time.UTC)
+	ctx := repository.WithTenant(context.Background(), orgID)
+
+	// Without a patient there is no sex or age to pick a range by, so the
+	// result is interpreted when it is reconciled.
+	if _, err := service.Receive(ctx, []byte(orderedMessage("MSG9", labUnmatchedPID, labPotassiumOBX)), adminID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o := repo.observations[0]; o.Interpretation != nil || len(mailer.sent) != 0 {
+		t.Errorf("unmatched result interpreted as %v with %d alerts, want neither", o.Interpretation, len(mailer.sent))
+	}
+
+	if _, err := service.Resolve(ctx, repo.items[0].ID, &model.ResolveReconciliation{PatientID: patientID}, adminID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o := repo.observations[0]; o.Interpretation == nil || *o.Interpretation != model.InterpretationCritical {
+		t.Errorf("interpretation %v, want critical once reconciled", o.Interpretation)
+	}
+	if len(mailer.sent) != 1 {
+		t.Errorf("sent %d alerts, want one once reconciled", len(mailer.sent))
+	}
+}
+
+func TestLabService_ListCritical(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	otherDoctorID, _ := uuid.NewV7()
+	userID, _ := uuid.NewV7()
+	potassiumID, _ := uuid.NewV7()
+	cholesterolID, _ := uuid.NewV7()
+
+	critical, high := model.InterpretationCritical, model.InterpretationHigh
+
+	tests := []struct {
+		name        string
+		callerID    uuid.UUID
+		callerRole  string
+		expectErr   error
+		expectTotal int
+	}{
+		{name: "admin", callerID: adminID, callerRole: "admin", expectTotal: 1},
+		{name: "ordering practitioner", callerID: doctorID, callerRole: "user", expectTotal: 1},
+		// Another practitioner sees no results they did not order.
+		{name: "other practitioner", callerID: otherDoctorID, callerRole: "user", expectTotal: 0},
+		{name: "user", callerID: userID, callerRole: "user", expectErr: model.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockLabRepo{
+				observations: []*model.LabObservation{
+					{ID: potassiumID, PatientID: &patientID, OrderingPractitionerID: &practitionerID, Code: "2823-3", Interpretation: &critical},
+					{ID: cholesterolID, PatientID: &patientID, OrderingPractitionerID: &practitionerID, Code: "2093-3", Interpretation: &high},
+				},
+				practitionerUsers: map[uuid.UUID]uuid.UUID{practitionerID: doctorID},
+			}
+			service := NewLabService(repo, newLabPatientRepo(patientID), newLabPractitionerRepo(practitionerID, doctorID, otherDoctorID), &mockAuditRepo{}, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			page, err := service.ListCritical(ctx, model.PaginationParams{Page: 1, Limit: 20}, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if page.Meta.Total != tt.expectTotal || len(page.Items) != tt.expectTotal {
+				t.Errorf("listed %+v, want %d results", page, tt.expectTotal)
+			}
+		})
+	}
+}
+
+func TestLabService_AcknowledgeCritical(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	otherDoctorID, _ := uuid.NewV7()
+	userID, _ := uuid.NewV7()
+	potassiumID, _ := uuid.NewV7()
+	cholesterolID, _ := uuid.NewV7()
+
+	critical, high := model.InterpretationCritical, model.InterpretationHigh
+
+	tests := []struct {
+		name          string
+		mockFunc      func(ctx context.Context, id, acknowledgedBy uuid.UUID) error
+		observationID uuid.UUID
+		acknowledged  bool
+		callerID      uuid.UUID
+		callerRole    string
+		expectErr     error
+	}{
+		{
+			name:          "ordering practitioner",
+			observationID: potassiumID,
+			callerID:      doctorID,
+			callerRole:    "user",
+		},
+		{
+			name:          "admin",
+			observationID: potassiumID,
+			callerID:      adminID,
+			callerRole:    "admin",
+		},
+		{
+			// Another practitioner cannot acknowledge a result they did
+			// not order; anyone else cannot tell the result exists.
+			name:          "other practitioner",
+			observationID: potassiumID,
+			callerID:      otherDoctorID,
+			callerRole:    "user",
+			expectErr:     model.ErrForbidden,
+		},
+		{
+			name:          "user",
+			observationID: potassiumID,
+			callerID:      userID,
+			callerRole:    "user",
+			expectErr:     model.ErrNotFound,
+		},
+		{
+			name:          "not critical",
+			observationID: cholesterolID,
+			callerID:      doctorID,
+			callerRole:    "user",
+			expectErr:     model.ErrConflict,
+		},
+		{
+			name:          "already acknowledged",
+			observationID: potassiumID,
+			acknowledged:  true,
+			callerID:      doctorID,
+			callerRole:    "user",
+			expectErr:     model.ErrConflict,
+		},
+		{
+			name: "acknowledged meanwhile",
+			mockFunc: func(ctx context.Context, id, acknowledgedBy uuid.UUID) error {
+				return model.ErrNotFound
+			},
+			observationID: potassiumID,
+			callerID:      doctorID,
+			callerRole:    "user",
+			expectErr:     model.ErrConflict,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, id, acknowledgedBy uuid.UUID) error {
+				return errRepo
+			},
+			observationID: potassiumID,
+			callerID:      doctorID,
+			callerRole:    "user",
+			expectErr:     errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			potassium := &model.LabObservation{ID: potassiumID, PatientID: &patientID, OrderingPractitionerID: &practitionerID, Code: "2823-3", Interpretation: &critical}
+			if tt.acknowledged {
+				now := time.Now()
+				potassium.AcknowledgedBy, potassium.AcknowledgedAt = &adminID, &now
+			}
+			repo := &mockLabRepo{
+				observations: []*model.LabObservation{
+					potassium,
+					{ID: cholesterolID, PatientID: &patientID, OrderingPractitionerID: &practitionerID, Code: "2093-3", Interpretation: &high},
+				},
+				practitionerUsers:       map[uuid.UUID]uuid.UUID{practitionerID: doctorID},
+				acknowledgeCriticalFunc: tt.mockFunc,
+			}
+			audit := &mockAuditRepo{}
+			service := NewLabService(repo, newLabPatientRepo(patientID), newLabPractitionerRepo(practitionerID, doctorID, otherDoctorID), audit, &mockMailer{}, newLabRanges(t), time.UTC)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			acked, err := service.AcknowledgeCritical(ctx, tt.observationID, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("audited %+v, want nothing", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if acked.AcknowledgedBy == nil || *acked.AcknowledgedBy != tt.callerID || acked.AcknowledgedAt == nil {
+				t.Errorf("acknowledged %+v, want it acknowledged by the caller", acked)
+			}
+			if page, _ := service.ListCritical(ctx, model.PaginationParams{Page: 1, Limit: 20}, adminID, "admin"); page.Meta.Total != 0 {
+				t.Errorf("%d critical results left, want none after acknowledgment", page.Meta.Total)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditLabCriticalAcknowledged {
+				t.Errorf("audited %+v, want the acknowledgment", audit.entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/rxnorm"
+	"github.com/google/uuid"
+)
+
+type MedicationService struct {
+	repo      repository.MedicationRepository
+	audit     repository.AuditRepository
+	batchSize int
+}
+
+// NewMedicationService returns a service that loads the catalogue in
+// statements of batchSize entries.
+func NewMedicationService(repo repository.MedicationRepository, audit repository.AuditRepository, batchSize int) *MedicationService {
+	return &MedicationService{
+		repo:      repo,
+		audit:     audit,
+		batchSize: batchSize,
+	}
+}
+
+// medicationError adds context to the repository errors of a single
+// catalogue entry.
+func medicationError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("medication %w", err)
+	}
+	return err
+}
+
+// List searches the catalogue. It is open to every signed-in user.
+func (s *MedicationService) List(ctx context.Context, filter model.MedicationFilter, params model.PaginationParams) (*model.PaginatedMedicationsResponse, error) {
+	medications, err := s.repo.List(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if medications == nil {
+		medications = []model.Medication{}
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedMedicationsResponse{
+		Items: medications,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+func (s *MedicationService) GetByRxCUI(ctx context.Context, rxcui string) (*model.Medication, error) {
+	medication, err := s.repo.GetByRxCUI(ctx, rxcui)
+	if err != nil {
+		return nil, medicationError(err)
+	}
+	return medication, nil
+}
+
+// LoadCatalogue loads the catalogue from an RxNorm concept file as the
+// system. It keeps the current English RxNorm names of ingredients and of
+// drugs that can be prescribed, one per concept; entries are upserted, so
+// loading a newer release updates the catalogue and loading the same file
+// again changes nothing. Batches are written as the file is read; if one
+// fails, the earlier ones stay written and the load can be rerun.
+func (s *MedicationService) LoadCatalogue(ctx context.Context, r io.Reader) (*model.CatalogueReport, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+	report := &model.CatalogueReport{ID: id}
+
+	seen := map[string]bool{}
+	batch := make([]model.Medication, 0, s.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := s.repo.UpsertBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		report.Loaded += n
+		report.Unchanged += len(batch) - n
+		batch = batch[:0]
+		return nil
+	}
+
+	reader := rxnorm.NewReader(r)
+	for {
+		concept, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", model.ErrBadRequest, err)
+		}
+		report.Read++
+
+		if concept.Source != model.CatalogueSource || concept.Language != "ENG" || !concept.Current() ||
+			!model.InCatalogue(concept.TermType) || concept.Name == "" || seen[concept.RXCUI] {
+			report.Skipped++
+			continue
+		}
+		seen[concept.RXCUI] = true
+
+		batch = append(batch, model.Medication{RxCUI: concept.RXCUI, Name: concept.Name, TermType: concept.TermType})
+		if len(batch) == s.batchSize {
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("load stopped at line %d; earlier entries were loaded: %w", reader.Line(), err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, fmt.Errorf("load stopped at the end of the file; earlier entries were loaded: %w", err)
+	}
+
+	recordAudit(ctx, s.audit, nil, model.AuditMedicationCatalogueLoaded, "medication_catalogue", report.ID, map[string]any{
+		"read":      report.Read,
+		"loaded":    report.Loaded,
+		"unchanged": report.Unchanged,
+		"skipped":   report.Skipped,
+	})
+
+	return report, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+// mockMedicationRepo keeps the catalogue in memory. upsertBatchFunc, when
+// set, replaces writing to it.
+type mockMedicationRepo struct {
+	catalogue       map[string]model.Medication
+	batches         int
+	upsertBatchFunc func(ctx context.Context, medications []model.Medication) (int, error)
+}
+
+func (m *mockMedicationRepo) UpsertBatch(ctx context.Context, medications []model.Medication) (int, error) {
+	if m.upsertBatchFunc != nil {
+		return m.upsertBatchFunc(ctx, medications)
+	}
+	if m.catalogue == nil {
+		m.catalogue = map[string]model.Medication{}
+	}
+	m.batches++
+
+	changed := 0
+	for _, med := range medications {
+		if old, ok := m.catalogue[med.RxCUI]; ok && old.Name == med.Name && old.TermType == med.TermType {
+			continue
+		}
+		m.catalogue[med.RxCUI] = med
+		changed++
+	}
+	return changed, nil
+}
+
+func (m *mockMedicationRepo) GetByRxCUI(ctx context.Context, rxcui string) (*model.Medication, error) {
+	med, ok := m.catalogue[rxcui]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &med, nil
+}
+
+func (m *mockMedicationRepo) List(ctx context.Context, filter model.MedicationFilter, limit, offset int) ([]model.Medication, error) {
+	return nil, nil
+}
+
+func (m *mockMedicationRepo) Count(ctx context.Context, filter model.MedicationFilter) (int, error) {
+	return 0, nil
+}
+
+// rrfLine builds a line of RXNCONSO.RRF.
+func rrfLine(rxcui, source, termType, name, suppress string) string {
+	fields := make([]string, 19)
+	fields[0] = rxcui
+	fields[1] = "ENG"
+	fields[11] = source
+	fields[12] = termType
+	fields[14] = name
+	fields[16] = suppress
+	return strings.Join(fields, "|") + "\n"
+}
+
+func TestMedicationService_LoadCatalogue(t *testing.T) {
+	file := rrfLine("723", "RXNORM", "IN", "amoxicillin", "N") +
+		rrfLine("308191", "RXNORM", "SCD", "amoxicillin 500 MG Oral Capsule", "N") +
+		rrfLine("308191", "RXNORM", "SCD", "amoxicillin 500 MG Oral Capsule", "N") + // repeated concept
+		rrfLine("308191", "RXNORM", "SY", "amoxicillin 500mg capsule", "N") + // synonym
+		rrfLine("308191", "MTHSPL", "SU", "Amoxicillin 500mg", "N") + // other source
+		rrfLine("199999", "RXNORM", "SCD", "withdrawn 1 MG Oral Tablet", "O") + // obsolete
+		rrfLine("392151", "RXNORM", "SBD", "Amoxil 500 MG Oral Capsule", "N")
+	loaded := map[string]model.Medication{
+		"723":    {RxCUI: "723", Name: "amoxicillin", TermType: "IN"},
+		"308191": {RxCUI: "308191", Name: "amoxicillin 500 MG Oral Capsule", TermType: "SCD"},
+		"392151": {RxCUI: "392151", Name: "Amoxil 500 MG Oral Capsule", TermType: "SBD"},
+	}
+
+	tests := []struct {
+		name            string
+		file            string
+		catalogue       map[string]model.Medication
+		upsertBatchFunc func(ctx context.Context, medications []model.Medication) (int, error)
+		expectErr       error
+		expectMessage   string
+		expectReport    model.CatalogueReport
+		expectBatches   int
+	}{
+		{
+			name:          "success - new catalogue",
+			file:          file,
+			expectReport:  model.CatalogueReport{Read: 7, Loaded: 3, Skipped: 4},
+			expectBatches: 2,
+		},
+		{
+			name:          "same file again changes nothing",
+			file:          file,
+			catalogue:     loaded,
+			expectReport:  model.CatalogueReport{Read: 7, Unchanged: 3, Skipped: 4},
+			expectBatches: 2,
+		},
+		{
+			name:          "malformed line",
+			file:          rrfLine("723", "RXNORM", "IN", "amoxicillin", "N") + "not|a|concept\n",
+			expectErr:     model.ErrBadRequest,
+			expectMessage: "line 2",
+		},
+		{
+			name: "repo error",
+			file: file,
+			upsertBatchFunc: func(ctx context.Context, medications []model.Medication) (int, error) {
+				return 0, errRepo
+			},
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockMedicationRepo{catalogue: map[string]model.Medication{}, upsertBatchFunc: tt.upsertBatchFunc}
+			for rxcui, med := range tt.catalogue {
+				repo.catalogue[rxcui] = med
+			}
+			audit := &mockAuditRepo{}
+			service := NewMedicationService(repo, audit, 2)
+
+			report, err := service.LoadCatalogue(context.Background(), strings.NewReader(tt.file))
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) || !strings.Contains(err.Error(), tt.expectMessage) {
+					t.Errorf("expected %v (%q), got %v", tt.expectErr, tt.expectMessage, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("audited %+v, want nothing", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			report.ID = uuid.Nil
+			if *report != tt.expectReport {
+				t.Errorf("report = %+v, want %+v", *report, tt.expectReport)
+			}
+			if repo.batches != tt.expectBatches {
+				t.Errorf("wrote %d batches, want %d", repo.batches, tt.expectBatches)
+			}
+			if med := repo.catalogue["308191"]; med.Name != "amoxicillin 500 MG Oral Capsule" || med.TermType != "SCD" {
+				t.Errorf("catalogue entry = %+v, want the clinical drug", med)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditMedicationCatalogueLoaded {
+				t.Errorf("expected the load to be audited, got %+v", audit.entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+type OrganisationService struct {
+	repo  repository.OrganisationRepository
+	audit repository.AuditRepository
+}
+
+func NewOrganisationService(repo repository.OrganisationRepository, audit repository.AuditRepository) *OrganisationService {
+	return &OrganisationService{
+		repo:  repo,
+		audit: audit,
+	}
+}
+
+// Create adds an organisation. Only super admins may create organisations.
+func (s *OrganisationService) Create(ctx context.Context, data *model.CreateOrganisation, callerID uuid.UUID, callerRole string) (*model.Organisation, error) {
+	if callerRole != "super_admin" {
+		return nil, model.ErrForbidden
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	org, err := s.repo.Create(ctx, model.Organisation{ID: id, Name: data.Name})
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return nil, fmt.Errorf("organisation %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditOrganisationCreated, "organisation", org.ID, map[string]any{"name": org.Name})
+
+	return org, nil
+}
+
+// List returns every organisation to super admins and the organisations the
+// caller belongs to to everyone else.
+func (s *OrganisationService) List(ctx context.Context, callerID uuid.UUID, callerRole string) ([]model.Organisation, error) {
+	var memberID *uuid.UUID
+	if callerRole != "super_admin" {
+		memberID = &callerID
+	}
+
+	orgs, err := s.repo.List(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if orgs == nil {
+		orgs = []model.Organisation{}
+	}
+	return orgs, nil
+}
+
+// canManage reports whether the caller may manage the members of orgID:
+// super admins may manage any organisation and admins the one they are
+// signed in to.
+func canManage(ctx context.Context, orgID uuid.UUID, callerRole string) bool {
+	if callerRole == "super_admin" {
+		return true
+	}
+	tenant := repository.TenantFromContext(ctx)
+	return callerRole == "admin" && tenant != nil && *tenant == orgID
+}
+
+// targetOrganisation returns the organisation a new record belongs to: the
+// requested one, which must be the organisation the caller is signed in to
+// if they are signed in to one, or else that organisation.
+func targetOrganisation(ctx context.Context, requested *uuid.UUID) (uuid.UUID, error) {
+	tenant := repository.TenantFromContext(ctx)
+	switch {
+	case requested == nil && tenant == nil:
+		return uuid.Nil, model.ValidationErrors{model.FieldError{Field: "organisation_id", Message: "organisation is required"}}
+	case requested == nil:
+		return *tenant, nil
+	case tenant != nil && *tenant != *requested:
+		return uuid.Nil, fmt.Errorf("cannot act in another organisation: %w", model.ErrForbidden)
+	}
+	return *requested, nil
+}
+
+// inOrganisation reports whether the caller's reads are limited to an
+// organisation, or whether they are a super admin who may read across all of
+// them. Other callers have no organisation records to read.
+func inOrganisation(ctx context.Context, callerRole string) bool {
+	return callerRole == "super_admin" || repository.TenantFromContext(ctx) != nil
+}
+
+func (s *OrganisationService) ListMembers(ctx context.Context, orgID uuid.UUID, callerRole string) ([]model.Member, error) {
+	if !canManage(ctx, orgID, callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	members, err := s.repo.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if members == nil {
+		members = []model.Member{}
+	}
+	return members, nil
+}
+
+// SetMember gives userID the role data.Role in orgID. Super admins may add
+// any user; organisation admins may only change the role of existing members
+// other than themselves, since new members join by invitation.
+func (s *OrganisationService) SetMember(ctx context.Context, orgID, userID uuid.UUID, data *model.SetMember, callerID uuid.UUID, callerRole string) (*model.Member, error) {
+	if !canManage(ctx, orgID, callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	if callerRole != "super_admin" {
+		if userID == callerID {
+			return nil, fmt.Errorf("cannot change your own membership: %w", model.ErrForbidden)
+		}
+		if _, err := s.repo.GetMember(ctx, orgID, userID); err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return nil, fmt.Errorf("member %w", err)
+			}
+			return nil, err
+		}
+	}
+
+	if err := s.repo.SetMember(ctx, orgID, userID, data.Role); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("organisation or user %w", err)
+		}
+		return nil, err
+	}
+
+	member, err := s.repo.GetMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditMemberSet, "user", userID, map[string]any{
+		"organisation_id": orgID,
+		"role":            data.Role,
+	})
+
+	return member, nil
+}
+
+// RemoveMember removes userID from orgID. Organisation admins cannot remove
+// themselves.
+func (s *OrganisationService) RemoveMember(ctx context.Context, orgID, userID uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	if !canManage(ctx, orgID, callerRole) {
+		return model.ErrForbidden
+	}
+
+	if callerRole != "super_admin" && userID == callerID {
+		return fmt.Errorf("cannot change your own membership: %w", model.ErrForbidden)
+	}
+
+	if err := s.repo.RemoveMember(ctx, orgID, userID); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("member %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditMemberRemoved, "user", userID, map[string]any{
+		"organisation_id": orgID,
+	})
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+// mockOrganisationRepo keeps memberships in memory, keyed by organisation and
+// then user.
+type mockOrganisationRepo struct {
+	orgs    []model.Organisation
+	members map[uuid.UUID]map[uuid.UUID]string
+}
+
+func newMockOrganisationRepo() *mockOrganisationRepo {
+	return &mockOrganisationRepo{members: map[uuid.UUID]map[uuid.UUID]string{}}
+}
+
+func (m *mockOrganisationRepo) Create(ctx context.Context, org model.Organisation) (*model.Organisation, error) {
+	m.orgs = append(m.orgs, org)
+	return &org, nil
+}
+
+func (m *mockOrganisationRepo) List(ctx context.Context, memberID *uuid.UUID) ([]model.Organisation, error) {
+	var orgs []model.Organisation
+	for _, org := range m.orgs {
+		if memberID != nil {
+			if _, ok := m.members[org.ID][*memberID]; !ok {
+				continue
+			}
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+func (m *mockOrganisationRepo) ListMembers(ctx context.Context, orgID uuid.UUID) ([]model.Member, error) {
+	var members []model.Member
+	for userID, role := range m.members[orgID] {
+		members = append(members, model.Member{UserID: userID, Role: role})
+	}
+	return members, nil
+}
+
+func (m *mockOrganisationRepo) GetMember(ctx context.Context, orgID, userID uuid.UUID) (*model.Member, error) {
+	role, ok := m.members[orgID][userID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &model.Member{UserID: userID, Role: role}, nil
+}
+
+func (m *mockOrganisationRepo) SetMember(ctx context.Context, orgID, userID uuid.UUID, role string) error {
+	if m.members[orgID] == nil {
+		m.members[orgID] = map[uuid.UUID]string{}
+	}
+	m.members[orgID][userID] = role
+	return nil
+}
+
+func (m *mockOrganisationRepo) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	if _, ok := m.members[orgID][userID]; !ok {
+		return model.ErrNotFound
+	}
+	delete(m.members[orgID], userID)
+	return nil
+}
+
+func TestOrganisationService_List(t *testing.T) {
+	callerID, _ := uuid.NewV7()
+	orgA := model.Organisation{ID: uuid.New(), Name: "A"}
+	orgB := model.Organisation{ID: uuid.New(), Name: "B"}
+
+	repo := newMockOrganisationRepo()
+	repo.orgs = []model.Organisation{orgA, orgB}
+	repo.members[orgA.ID] = map[uuid.UUID]string{callerID: model.MemberRoleAdmin}
+	svc := NewOrganisationService(repo, &mockAuditRepo{})
+
+	orgs, err := svc.List(context.Background(), callerID, "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orgs) != 1 || orgs[0].ID != orgA.ID {
+		t.Errorf("admin should see only their organisations, got %+v", orgs)
+	}
+
+	orgs, err = svc.List(context.Background(), callerID, "super_admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orgs) != 2 {
+		t.Errorf("super admin should see every organisation, got %+v", orgs)
+	}
+}
+
+func TestOrganisationService_SetMember(t *testing.T) {
+	callerID, _ := uuid.NewV7()
+	memberID, _ := uuid.NewV7()
+	outsiderID, _ := uuid.NewV7()
+	orgID, _ := uuid.NewV7()
+	otherOrgID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		callerRole string
+		tenant     uuid.UUID
+		orgID      uuid.UUID
+		userID     uuid.UUID
+		expectErr  error
+	}{
+		{name: "admin changes member", callerRole: "admin", tenant: orgID, orgID: orgID, userID: memberID},
+		{name: "admin cannot add outsider", callerRole: "admin", tenant: orgID, orgID: orgID, userID: outsiderID, expectErr: model.ErrNotFound},
+		{name: "admin cannot change self", callerRole: "admin", tenant: orgID, orgID: orgID, userID: callerID, expectErr: model.ErrForbidden},
+		{name: "admin of another organisation", callerRole: "admin", tenant: otherOrgID, orgID: orgID, userID: memberID, expectErr: model.ErrForbidden},
+		{name: "user cannot manage", callerRole: "user", tenant: orgID, orgID: orgID, userID: memberID, expectErr: model.ErrForbidden},
+		{name: "super admin adds outsider", callerRole: "super_admin", orgID: orgID, userID: outsiderID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockOrganisationRepo()
+			repo.members[orgID] = map[uuid.UUID]string{
+				callerID: model.MemberRoleAdmin,
+				memberID: model.MemberRoleMember,
+			}
+			audit := &mockAuditRepo{}
+			svc := NewOrganisationService(repo, audit)
+
+			ctx := context.Background()
+			if tt.tenant != uuid.Nil {
+				ctx = repository.WithTenant(ctx, tt.tenant)
+			}
+
+			member, err := svc.SetMember(ctx, tt.orgID, tt.userID, &model.SetMember{Role: model.MemberRoleAdmin}, callerID, tt.callerRole)
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("expected no audit entry, got %+v", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if member.Role != model.MemberRoleAdmin || repo.members[tt.orgID][tt.userID] != model.MemberRoleAdmin {
+				t.Errorf("role not changed: %+v", member)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditMemberSet {
+				t.Errorf("expected organisation.member_set audit entry, got %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestOrganisationService_RemoveMember(t *testing.T) {
+	callerID, _ := uuid.NewV7()
+	memberID, _ := uuid.NewV7()
+	orgID, _ := uuid.NewV7()
+
+	repo := newMockOrganisationRepo()
+	repo.members[orgID] = map[uuid.UUID]string{callerID: model.MemberRoleAdmin, memberID: model.MemberRoleMember}
+	svc := NewOrganisationService(repo, &mockAuditRepo{})
+	ctx := repository.WithTenant(context.Background(), orgID)
+
+	if err := svc.RemoveMember(ctx, orgID, callerID, callerID, "admin"); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("removing self: expected forbidden, got %v", err)
+	}
+	if err := svc.RemoveMember(ctx, orgID, memberID, callerID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := repo.members[orgID][memberID]; ok {
+		t.Error("member was not removed")
+	}
+	if err := svc.RemoveMember(ctx, orgID, memberID, callerID, "admin"); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("removing again: expected not found, got %v", err)
+	}
+}
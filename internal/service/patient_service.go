@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+type PatientService struct {
+	repo          repository.PatientRepository
+	practitioners repository.PractitionerRepository
+	audit         repository.AuditRepository
+}
+
+func NewPatientService(repo repository.PatientRepository, practitioners repository.PractitionerRepository, audit repository.AuditRepository) *PatientService {
+	return &PatientService{
+		repo:          repo,
+		practitioners: practitioners,
+		audit:         audit,
+	}
+}
+
+// patientError adds context to the repository errors of a single patient.
+func patientError(err error) error {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return fmt.Errorf("patient %w", err)
+	case errors.Is(err, model.ErrPreconditionFailed):
+		return fmt.Errorf("patient has been modified: %w", err)
+	}
+	return err
+}
+
+// clinicalAccess checks that the caller may see a patient's clinical
+// records, such as allergies and lab results, and reports whether they are a
+// clinician: an admin, or a practitioner of the organisation they are signed
+// in to. Clinicians may see any patient's records; others only those of the
+// patient linked to their login, and are told other patients do not exist.
+func clinicalAccess(ctx context.Context, patients repository.PatientRepository, practitioners repository.PractitionerRepository, patientID uuid.UUID, callerID uuid.UUID, callerRole string) (bool, error) {
+	patient, err := patients.GetByID(ctx, patientID)
+	if err != nil {
+		return false, patientError(err)
+	}
+
+	clinician, err := isClinician(ctx, practitioners, callerID, callerRole)
+	if err != nil {
+		return false, err
+	}
+	if !clinician && (patient.UserID == nil || *patient.UserID != callerID) {
+		return false, fmt.Errorf("patient %w", model.ErrNotFound)
+	}
+	return clinician, nil
+}
+
+// isClinician reports whether the caller is an admin or a practitioner of
+// the organisation they are signed in to.
+func isClinician(ctx context.Context, practitioners repository.PractitionerRepository, callerID uuid.UUID, callerRole string) (bool, error) {
+	if isAdmin(callerRole) {
+		return true, nil
+	}
+	if repository.TenantFromContext(ctx) == nil {
+		return false, nil
+	}
+	return practitioners.IsPractitioner(ctx, callerID)
+}
+
+// optionalString stores an empty optional field as NULL.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Create registers a patient in the organisation the admin is signed in to,
+// or, for super admins, the one given in the request. The MRN is assigned by
+// the repository.
+func (s *PatientService) Create(ctx context.Context, data *model.CreatePatient, callerID uuid.UUID, callerRole string) (*model.Patient, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	orgID, err := targetOrganisation(ctx, data.OrganisationID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	identifiers := make([]model.PatientIdentifier, len(data.Identifiers))
+	for i, identifier := range data.Identifiers {
+		identifierID, err := uuid.NewV7()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate uuid: %w", err)
+		}
+		identifiers[i] = model.PatientIdentifier{ID: identifierID, System: identifier.System, Value: identifier.Value}
+	}
+
+	patient, err := s.repo.Create(ctx, model.Patient{
+		ID:                id,
+		OrganisationID:    orgID,
+		FirstName:         data.FirstName,
+		LastName:          data.LastName,
+		DateOfBirth:       data.DateOfBirth,
+		Sex:               data.Sex,
+		Gender:            optionalString(data.Gender),
+		Phone:             optionalString(data.Phone),
+		Email:             optionalString(data.Email),
+		PreferredLanguage: optionalString(data.PreferredLanguage),
+		Address:           data.Address,
+		UserID:            data.UserID,
+		Identifiers:       identifiers,
+		CreatedBy:         &callerID,
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return nil, fmt.Errorf("patient identifier or user link %w", err)
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("organisation or user %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPatientCreated, "patient", patient.ID, map[string]any{
+		"organisation_id": patient.OrganisationID,
+		"mrn":             patient.MRN,
+	})
+
+	return patient, nil
+}
+
+// List returns a page of the patients of the organisation the caller is
+// signed in to, or of every organisation for super admins. Only clinicians
+// may list patients.
+func (s *PatientService) List(ctx context.Context, callerID uuid.UUID, callerRole string, filter model.PatientFilter, params model.PaginationParams) (*model.PaginatedPatientsResponse, error) {
+	clinician, err := isClinician(ctx, s.practitioners, callerID, callerRole)
+	if err != nil {
+		return nil, err
+	}
+	if !clinician {
+		return nil, model.ErrForbidden
+	}
+
+	patients, err := s.repo.List(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if patients == nil {
+		patients = []model.PatientSummary{}
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedPatientsResponse{
+		Items: patients,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// ListOwn returns the patient records linked to the caller's login.
+func (s *PatientService) ListOwn(ctx context.Context, callerID uuid.UUID) ([]model.PatientSummary, error) {
+	patients, err := s.repo.ListByUser(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+	if patients == nil {
+		patients = []model.PatientSummary{}
+	}
+	return patients, nil
+}
+
+// GetByID returns a patient to clinicians and to the user linked to it.
+// Other callers are told it does not exist.
+func (s *PatientService) GetByID(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.Patient, error) {
+	patient, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, patientError(err)
+	}
+
+	if patient.UserID == nil || *patient.UserID != callerID {
+		clinician, err := isClinician(ctx, s.practitioners, callerID, callerRole)
+		if err != nil {
+			return nil, err
+		}
+		if !clinician {
+			return nil, fmt.Errorf("patient %w", model.ErrNotFound)
+		}
+	}
+
+	return patient, nil
+}
+
+// UpdateByID applies a merge patch to a patient if cond matches the current
+// version, and returns the new version. As for users, an empty patch returns
+// the current version and an address patch is merged before it is checked.
+func (s *PatientService) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdatePatient, cond model.Precondition, callerID uuid.UUID, callerRole string) (int64, error) {
+	if !isAdmin(callerRole) {
+		return 0, model.ErrForbidden
+	}
+
+	if data.IsEmpty() || data.Address.HasValue() {
+		current, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return 0, patientError(err)
+		}
+
+		if !cond.Matches(current.Version) {
+			return 0, patientError(model.ErrPreconditionFailed)
+		}
+
+		if data.IsEmpty() {
+			return current.Version, nil
+		}
+
+		if err := data.Address.Value.Apply(current.Address).Validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	version, err := s.repo.UpdateByID(ctx, id, data, cond)
+	if err != nil {
+		return 0, patientError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPatientUpdated, "patient", id, nil)
+
+	return version, nil
+}
+
+// DeleteByID soft-deletes a patient if cond matches the current version.
+func (s *PatientService) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition, callerID uuid.UUID, callerRole string) error {
+	if !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	if err := s.repo.DeleteByID(ctx, id, cond); err != nil {
+		return patientError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPatientDeleted, "patient", id, nil)
+
+	return nil
+}
+
+func (s *PatientService) AddIdentifier(ctx context.Context, patientID uuid.UUID, data *model.CreatePatientIdentifier, callerID uuid.UUID, callerRole string) (*model.PatientIdentifier, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	identifier, err := s.repo.AddIdentifier(ctx, patientID, model.PatientIdentifier{ID: id, System: data.System, Value: data.Value})
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return nil, fmt.Errorf("identifier %w", err)
+		}
+		return nil, patientError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPatientIdentifierAdded, "patient", patientID, map[string]any{
+		"system": identifier.System,
+	})
+
+	return identifier, nil
+}
+
+func (s *PatientService) RemoveIdentifier(ctx context.Context, patientID, identifierID uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	if !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	if err := s.repo.RemoveIdentifier(ctx, patientID, identifierID); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("patient or identifier %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPatientIdentifierRemoved, "patient", patientID, map[string]any{
+		"identifier_id": identifierID,
+	})
+
+	return nil
+}
+
+// LinkUser links a patient to the login of a member of its organisation, so
+// they can see their own record.
+func (s *PatientService) LinkUser(ctx context.Context, id uuid.UUID, data *model.LinkPatientUser, callerID uuid.UUID, callerRole string) error {
+	if !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	if err := s.repo.SetUser(ctx, id, &data.UserID); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("patient or member %w", err)
+		}
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return fmt.Errorf("user is already linked to another patient: %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPatientUserLinked, "patient", id, map[string]any{
+		"user_id": data.UserID,
+	})
+
+	return nil
+}
+
+func (s *PatientService) UnlinkUser(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	if !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	if err := s.repo.SetUser(ctx, id, nil); err != nil {
+		return patientError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPatientUserUnlinked, "patient", id, nil)
+
+	return nil
+}
@@ -0,0 +1,553 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+type mockPatientRepo struct {
+	createFunc     func(ctx context.Context, patient model.Patient) (*model.Patient, error)
+	getByIDFunc    func(ctx context.Context, id uuid.UUID) (*model.Patient, error)
+	listByUserFunc func(ctx context.Context, userID uuid.UUID) ([]model.PatientSummary, error)
+	findFunc       func(ctx context.Context, mrns []string, identifiers []model.CreatePatientIdentifier) ([]model.PatientSummary, error)
+	listFunc       func(ctx context.Context, filter model.PatientFilter, limit, offset int) ([]model.PatientSummary, error)
+	countFunc      func(ctx context.Context, filter model.PatientFilter) (int, error)
+	updateByIDFunc func(ctx context.Context, id uuid.UUID, data *model.UpdatePatient, cond model.Precondition) (int64, error)
+	deleteByIDFunc func(ctx context.Context, id uuid.UUID, cond model.Precondition) error
+	setUserFunc    func(ctx context.Context, id uuid.UUID, userID *uuid.UUID) error
+	identifierErr  error
+}
+
+func (m *mockPatientRepo) Create(ctx context.Context, patient model.Patient) (*model.Patient, error) {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, patient)
+	}
+	patient.MRN = model.FormatMRN(1)
+	return &patient, nil
+}
+
+func (m *mockPatientRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(ctx, id)
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockPatientRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.PatientSummary, error) {
+	if m.listByUserFunc != nil {
+		return m.listByUserFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockPatientRepo) FindByIdentifiers(ctx context.Context, mrns []string, identifiers []model.CreatePatientIdentifier) ([]model.PatientSummary, error) {
+	if m.findFunc != nil {
+		return m.findFunc(ctx, mrns, identifiers)
+	}
+	return nil, nil
+}
+
+func (m *mockPatientRepo) List(ctx context.Context, filter model.PatientFilter, limit, offset int) ([]model.PatientSummary, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, filter, limit, offset)
+	}
+	return nil, nil
+}
+
+func (m *mockPatientRepo) Count(ctx context.Context, filter model.PatientFilter) (int, error) {
+	if m.countFunc != nil {
+		return m.countFunc(ctx, filter)
+	}
+	return 0, nil
+}
+
+func (m *mockPatientRepo) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdatePatient, cond model.Precondition) (int64, error) {
+	if m.updateByIDFunc != nil {
+		return m.updateByIDFunc(ctx, id, data, cond)
+	}
+	return 0, nil
+}
+
+func (m *mockPatientRepo) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
+	if m.deleteByIDFunc != nil {
+		return m.deleteByIDFunc(ctx, id, cond)
+	}
+	return nil
+}
+
+func (m *mockPatientRepo) AddIdentifier(ctx context.Context, patientID uuid.UUID, identifier model.PatientIdentifier) (*model.PatientIdentifier, error) {
+	if m.identifierErr != nil {
+		return nil, m.identifierErr
+	}
+	return &identifier, nil
+}
+
+func (m *mockPatientRepo) RemoveIdentifier(ctx context.Context, patientID, identifierID uuid.UUID) error {
+	return m.identifierErr
+}
+
+func (m *mockPatientRepo) SetUser(ctx context.Context, id uuid.UUID, userID *uuid.UUID) error {
+	if m.setUserFunc != nil {
+		return m.setUserFunc(ctx, id, userID)
+	}
+	return nil
+}
+
+func TestPatientService_Create(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	otherOrgID, _ := uuid.NewV7()
+	callerID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		tenant     *uuid.UUID
+		orgID      *uuid.UUID
+		callerRole string
+		repoErr    error
+		expectErr  error
+		expectOrg  uuid.UUID
+	}{
+		{
+			name:       "admin registers in the organisation they are signed in to",
+			tenant:     &orgID,
+			callerRole: "admin",
+			expectOrg:  orgID,
+		},
+		{
+			name:       "super admin names the organisation",
+			orgID:      &otherOrgID,
+			callerRole: "super_admin",
+			expectOrg:  otherOrgID,
+		},
+		{
+			name:       "super admin without organisation",
+			callerRole: "super_admin",
+			expectErr:  model.ErrValidationFailed,
+		},
+		{
+			name:       "admin registers in another organisation",
+			tenant:     &orgID,
+			orgID:      &otherOrgID,
+			callerRole: "admin",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name:       "user cannot register patients",
+			tenant:     &orgID,
+			callerRole: "user",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name:       "identifier taken",
+			tenant:     &orgID,
+			callerRole: "admin",
+			repoErr:    model.ErrAlreadyExists,
+			expectErr:  model.ErrAlreadyExists,
+		},
+		{
+			name:       "unknown linked user",
+			tenant:     &orgID,
+			callerRole: "admin",
+			repoErr:    model.ErrNotFound,
+			expectErr:  model.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored model.Patient
+			mock := &mockPatientRepo{
+				createFunc: func(ctx context.Context, patient model.Patient) (*model.Patient, error) {
+					if tt.repoErr != nil {
+						return nil, tt.repoErr
+					}
+					stored = patient
+					patient.MRN = model.FormatMRN(1)
+					return &patient, nil
+				},
+			}
+			audit := &mockAuditRepo{}
+			service := NewPatientService(mock, &mockPractitionerRepo{}, audit)
+
+			ctx := context.Background()
+			if tt.tenant != nil {
+				ctx = repository.WithTenant(ctx, *tt.tenant)
+			}
+
+			data := &model.CreatePatient{
+				OrganisationID: tt.orgID,
+				FirstName:      "Jane",
+				LastName:       "Doe",
+				DateOfBirth:    "1990-04-01",
+				Sex:            "female",
+				Identifiers:    []model.CreatePatientIdentifier{{System: "urn:nhs", Value: "9434765919"}},
+			}
+			patient, err := service.Create(ctx, data, callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				var verrs model.ValidationErrors
+				if tt.expectErr == model.ErrValidationFailed && errors.As(err, &verrs) {
+					return
+				}
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if stored.OrganisationID != tt.expectOrg {
+				t.Errorf("organisation = %v, want %v", stored.OrganisationID, tt.expectOrg)
+			}
+			if stored.CreatedBy == nil || *stored.CreatedBy != callerID {
+				t.Errorf("created_by = %v, want %v", stored.CreatedBy, callerID)
+			}
+			if stored.Gender != nil || stored.Phone != nil {
+				t.Errorf("empty optional fields should be nil, got %v %v", stored.Gender, stored.Phone)
+			}
+			if len(stored.Identifiers) != 1 || stored.Identifiers[0].ID == uuid.Nil {
+				t.Errorf("identifiers = %+v, want one with an id", stored.Identifiers)
+			}
+			if !model.ValidMRN(patient.MRN) {
+				t.Errorf("invalid mrn %q", patient.MRN)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditPatientCreated {
+				t.Errorf("audit entries = %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestPatientService_List(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	patients := []model.PatientSummary{{ID: testID, MRN: model.FormatMRN(1), FirstName: "Jane", LastName: "Doe"}}
+
+	mock := &mockPatientRepo{
+		listFunc: func(ctx context.Context, filter model.PatientFilter, limit, offset int) ([]model.PatientSummary, error) {
+			if limit != 5 || offset != 5 {
+				t.Errorf("limit, offset = %d, %d, want 5, 5", limit, offset)
+			}
+			return patients, nil
+		},
+		countFunc: func(ctx context.Context, filter model.PatientFilter) (int, error) {
+			return 11, nil
+		},
+	}
+	callerID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	practitioners := &mockPractitionerRepo{practitionerUsers: map[uuid.UUID]bool{practitionerID: true}}
+	service := NewPatientService(mock, practitioners, &mockAuditRepo{})
+	params := model.PaginationParams{Page: 2, Limit: 5}
+
+	resp, err := service.List(context.Background(), callerID, "admin", model.PatientFilter{}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := model.PaginationMeta{Page: 2, Limit: 5, Total: 11, TotalPages: 3}
+	if resp.Meta != want {
+		t.Errorf("meta = %+v, want %+v", resp.Meta, want)
+	}
+	if !reflect.DeepEqual(resp.Items, patients) {
+		t.Errorf("items = %+v, want %+v", resp.Items, patients)
+	}
+
+	ctx := repository.WithTenant(context.Background(), testID)
+	if _, err := service.List(ctx, practitionerID, "user", model.PatientFilter{}, params); err != nil {
+		t.Fatalf("expected a practitioner to list patients, got %v", err)
+	}
+	if _, err := service.List(ctx, callerID, "user", model.PatientFilter{}, params); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden for user, got %v", err)
+	}
+	if _, err := service.List(context.Background(), practitionerID, "user", model.PatientFilter{}, params); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden for a practitioner outside an organisation, got %v", err)
+	}
+}
+
+func TestPatientService_ListOwn(t *testing.T) {
+	callerID, _ := uuid.NewV7()
+	service := NewPatientService(&mockPatientRepo{}, &mockPractitionerRepo{}, &mockAuditRepo{})
+
+	patients, err := service.ListOwn(context.Background(), callerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patients == nil || len(patients) != 0 {
+		t.Errorf("expected an empty list, got %#v", patients)
+	}
+}
+
+func TestPatientService_GetByID(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	linkedID, _ := uuid.NewV7()
+	otherID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+
+	patient := model.Patient{ID: testID, FirstName: "Jane", LastName: "Doe", UserID: &linkedID}
+
+	tests := []struct {
+		name       string
+		mockFunc   func(ctx context.Context, id uuid.UUID) (*model.Patient, error)
+		callerID   uuid.UUID
+		callerRole string
+		expectErr  error
+	}{
+		{
+			name: "success - admin",
+			mockFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+				return &patient, nil
+			},
+			callerID:   otherID,
+			callerRole: "admin",
+		},
+		{
+			name: "success - linked user",
+			mockFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+				return &patient, nil
+			},
+			callerID:   linkedID,
+			callerRole: "user",
+		},
+		{
+			name: "success - practitioner",
+			mockFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+				return &patient, nil
+			},
+			callerID:   practitionerID,
+			callerRole: "user",
+		},
+		{
+			name: "hidden from other users",
+			mockFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+				return &patient, nil
+			},
+			callerID:   otherID,
+			callerRole: "user",
+			expectErr:  model.ErrNotFound,
+		},
+		{
+			name: "patient not found",
+			mockFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+				return nil, model.ErrNotFound
+			},
+			callerID:   otherID,
+			callerRole: "admin",
+			expectErr:  model.ErrNotFound,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+				return nil, errRepo
+			},
+			callerID:   otherID,
+			callerRole: "admin",
+			expectErr:  errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			practitioners := &mockPractitionerRepo{practitionerUsers: map[uuid.UUID]bool{practitionerID: true}}
+			service := NewPatientService(&mockPatientRepo{getByIDFunc: tt.mockFunc}, practitioners, &mockAuditRepo{})
+
+			ctx := repository.WithTenant(context.Background(), testID)
+			resp, err := service.GetByID(ctx, testID, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(resp, &patient) {
+				t.Errorf("got %+v want %+v", resp, &patient)
+			}
+		})
+	}
+}
+
+func TestPatientService_UpdateByID(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	callerID, _ := uuid.NewV7()
+	current := &model.Patient{
+		ID:      testID,
+		Address: &model.Address{Line1: "1 High Street", City: "London", Country: "GB"},
+		Version: 4,
+	}
+
+	var gotCond model.Precondition
+	mock := &mockPatientRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+			return current, nil
+		},
+		updateByIDFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdatePatient, cond model.Precondition) (int64, error) {
+			gotCond = cond
+			if !cond.Matches(4) {
+				return 0, model.ErrPreconditionFailed
+			}
+			return 5, nil
+		},
+	}
+	audit := &mockAuditRepo{}
+	service := NewPatientService(mock, &mockPractitionerRepo{}, audit)
+	ctx := context.Background()
+	cond := model.Precondition{Versions: []int64{4}}
+	rename := &model.UpdatePatient{FirstName: model.Optional[string]{Value: "Janet", Set: true}}
+
+	version, err := service.UpdateByID(ctx, testID, rename, cond, callerID, "admin")
+	if err != nil || version != 5 {
+		t.Fatalf("expected version 5, got %d, %v", version, err)
+	}
+	if !reflect.DeepEqual(gotCond, cond) {
+		t.Errorf("precondition = %+v, want %+v", gotCond, cond)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditPatientUpdated {
+		t.Errorf("audit entries = %+v", audit.entries)
+	}
+
+	if _, err := service.UpdateByID(ctx, testID, rename, model.Precondition{Versions: []int64{3}}, callerID, "admin"); !errors.Is(err, model.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failed, got %v", err)
+	}
+
+	version, err = service.UpdateByID(ctx, testID, &model.UpdatePatient{}, cond, callerID, "admin")
+	if err != nil || version != 4 {
+		t.Fatalf("empty patch: expected current version 4, got %d, %v", version, err)
+	}
+
+	clearCity := &model.UpdatePatient{Address: model.Optional[model.AddressPatch]{
+		Set:   true,
+		Value: model.AddressPatch{City: model.Optional[string]{Set: true, Null: true}},
+	}}
+	var verrs model.ValidationErrors
+	if _, err := service.UpdateByID(ctx, testID, clearCity, cond, callerID, "admin"); !errors.As(err, &verrs) {
+		t.Fatalf("expected validation errors for an incomplete address, got %v", err)
+	}
+
+	if _, err := service.UpdateByID(ctx, testID, rename, cond, current.ID, "user"); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden for user, got %v", err)
+	}
+}
+
+func TestPatientService_DeleteByID(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	callerID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		repoErr    error
+		callerRole string
+		expectErr  error
+	}{
+		{name: "success", callerRole: "admin"},
+		{name: "forbidden for user", callerRole: "user", expectErr: model.ErrForbidden},
+		{name: "not found", repoErr: model.ErrNotFound, callerRole: "admin", expectErr: model.ErrNotFound},
+		{name: "modified", repoErr: model.ErrPreconditionFailed, callerRole: "admin", expectErr: model.ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockPatientRepo{
+				deleteByIDFunc: func(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
+					return tt.repoErr
+				},
+			}
+			audit := &mockAuditRepo{}
+			service := NewPatientService(mock, &mockPractitionerRepo{}, audit)
+
+			err := service.DeleteByID(context.Background(), testID, model.Precondition{Any: true}, callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Errorf("expected no audit entry, got %+v", audit.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditPatientDeleted {
+				t.Errorf("audit entries = %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestPatientService_AddIdentifier(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	callerID, _ := uuid.NewV7()
+	data := &model.CreatePatientIdentifier{System: "urn:insurer", Value: "A-1"}
+
+	service := NewPatientService(&mockPatientRepo{}, &mockPractitionerRepo{}, &mockAuditRepo{})
+	identifier, err := service.AddIdentifier(context.Background(), testID, data, callerID, "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identifier.ID == uuid.Nil || identifier.System != data.System || identifier.Value != data.Value {
+		t.Errorf("identifier = %+v", identifier)
+	}
+
+	service = NewPatientService(&mockPatientRepo{identifierErr: model.ErrAlreadyExists}, &mockPractitionerRepo{}, &mockAuditRepo{})
+	if _, err := service.AddIdentifier(context.Background(), testID, data, callerID, "admin"); !errors.Is(err, model.ErrAlreadyExists) {
+		t.Fatalf("expected already exists, got %v", err)
+	}
+
+	if _, err := service.AddIdentifier(context.Background(), testID, data, callerID, "user"); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden for user, got %v", err)
+	}
+}
+
+func TestPatientService_LinkUser(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	callerID, _ := uuid.NewV7()
+	userID, _ := uuid.NewV7()
+
+	var linked *uuid.UUID
+	var setErr error
+	mock := &mockPatientRepo{
+		setUserFunc: func(ctx context.Context, id uuid.UUID, u *uuid.UUID) error {
+			if setErr != nil {
+				return setErr
+			}
+			linked = u
+			return nil
+		},
+	}
+	service := NewPatientService(mock, &mockPractitionerRepo{}, &mockAuditRepo{})
+	ctx := context.Background()
+
+	if err := service.LinkUser(ctx, testID, &model.LinkPatientUser{UserID: userID}, callerID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if linked == nil || *linked != userID {
+		t.Errorf("linked = %v, want %v", linked, userID)
+	}
+
+	if err := service.UnlinkUser(ctx, testID, callerID, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if linked != nil {
+		t.Errorf("expected the link to be removed, got %v", linked)
+	}
+
+	setErr = model.ErrAlreadyExists
+	if err := service.LinkUser(ctx, testID, &model.LinkPatientUser{UserID: userID}, callerID, "admin"); !errors.Is(err, model.ErrAlreadyExists) {
+		t.Fatalf("expected already exists, got %v", err)
+	}
+
+	if err := service.LinkUser(ctx, testID, &model.LinkPatientUser{UserID: userID}, callerID, "user"); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden for user, got %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+type PractitionerService struct {
+	repo   repository.PractitionerRepository
+	audit  repository.AuditRepository
+	mailer mailer.Mailer
+}
+
+func NewPractitionerService(repo repository.PractitionerRepository, audit repository.AuditRepository, mail mailer.Mailer) *PractitionerService {
+	return &PractitionerService{
+		repo:   repo,
+		audit:  audit,
+		mailer: mail,
+	}
+}
+
+// practitionerError adds context to the repository errors of a single
+// practitioner.
+func practitionerError(err error) error {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return fmt.Errorf("practitioner %w", err)
+	case errors.Is(err, model.ErrPreconditionFailed):
+		return fmt.Errorf("practitioner has been modified: %w", err)
+	}
+	return err
+}
+
+// CreateFacility adds a facility to the organisation the admin is signed in
+// to, or, for super admins, the one given in the request.
+func (s *PractitionerService) CreateFacility(ctx context.Context, data *model.CreateFacility, callerID uuid.UUID, callerRole string) (*model.Facility, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	orgID, err := targetOrganisation(ctx, data.OrganisationID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	facility, err := s.repo.CreateFacility(ctx, model.Facility{
+		ID:             id,
+		OrganisationID: orgID,
+		Name:           data.Name,
+		Address:        data.Address,
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return nil, fmt.Errorf("facility %w", err)
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("organisation %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditFacilityCreated, "facility", facility.ID, map[string]any{
+		"organisation_id": facility.OrganisationID,
+		"name":            facility.Name,
+	})
+
+	return facility, nil
+}
+
+// ListFacilities returns the facilities of the caller's organisation.
+func (s *PractitionerService) ListFacilities(ctx context.Context, callerRole string) ([]model.Facility, error) {
+	if !inOrganisation(ctx, callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	facilities, err := s.repo.ListFacilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if facilities == nil {
+		facilities = []model.Facility{}
+	}
+	return facilities, nil
+}
+
+// Create registers a member of the organisation as a practitioner.
+func (s *PractitionerService) Create(ctx context.Context, data *model.CreatePractitioner, callerID uuid.UUID, callerRole string) (*model.Practitioner, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	orgID, err := targetOrganisation(ctx, data.OrganisationID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	licences := make([]model.Licence, len(data.Licences))
+	for i, l := range data.Licences {
+		licenceID, err := uuid.NewV7()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate uuid: %w", err)
+		}
+		licences[i] = model.Licence{ID: licenceID, Number: l.Number, Jurisdiction: l.Jurisdiction, ExpiresOn: l.ExpiresOn}
+	}
+
+	err = s.repo.Create(ctx, model.Practitioner{
+		ID:             id,
+		OrganisationID: orgID,
+		UserID:         data.UserID,
+		Profession:     data.Profession,
+		NPI:            optionalString(data.NPI),
+		Specialties:    data.Specialties,
+		Licences:       licences,
+	}, data.FacilityIDs)
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return nil, fmt.Errorf("practitioner, npi or licence %w", err)
+		}
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("member or facility %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPractitionerCreated, "practitioner", id, map[string]any{
+		"organisation_id": orgID,
+		"user_id":         data.UserID,
+	})
+
+	practitioner, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+	return practitioner, nil
+}
+
+// List returns a page of the practitioner directory of the caller's
+// organisation. Every member may search the directory.
+func (s *PractitionerService) List(ctx context.Context, callerRole string, filter model.PractitionerFilter, params model.PaginationParams) (*model.PaginatedPractitionersResponse, error) {
+	if !inOrganisation(ctx, callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	practitioners, err := s.repo.List(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if practitioners == nil {
+		practitioners = []model.PractitionerSummary{}
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedPractitionersResponse{
+		Items: practitioners,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+func (s *PractitionerService) GetByID(ctx context.Context, id uuid.UUID, callerRole string) (*model.Practitioner, error) {
+	if !inOrganisation(ctx, callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	practitioner, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+	return practitioner, nil
+}
+
+// UpdateByID applies a merge patch to a practitioner if cond matches the
+// current version, and returns the new version. An empty patch returns the
+// current version.
+func (s *PractitionerService) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdatePractitioner, cond model.Precondition, callerID uuid.UUID, callerRole string) (int64, error) {
+	if !isAdmin(callerRole) {
+		return 0, model.ErrForbidden
+	}
+
+	if data.IsEmpty() {
+		current, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return 0, practitionerError(err)
+		}
+		if !cond.Matches(current.Version) {
+			return 0, practitionerError(model.ErrPreconditionFailed)
+		}
+		return current.Version, nil
+	}
+
+	version, err := s.repo.UpdateByID(ctx, id, data, cond)
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return 0, fmt.Errorf("npi %w", err)
+		}
+		if errors.Is(err, model.ErrNotFound) && data.FacilityIDs.HasValue() {
+			return 0, fmt.Errorf("practitioner or facility %w", err)
+		}
+		return 0, practitionerError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPractitionerUpdated, "practitioner", id, nil)
+
+	return version, nil
+}
+
+// DeleteByID soft-deletes a practitioner if cond matches the current
+// version.
+func (s *PractitionerService) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition, callerID uuid.UUID, callerRole string) error {
+	if !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	if err := s.repo.DeleteByID(ctx, id, cond); err != nil {
+		return practitionerError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPractitionerDeleted, "practitioner", id, nil)
+
+	return nil
+}
+
+func (s *PractitionerService) AddLicence(ctx context.Context, practitionerID uuid.UUID, data *model.CreateLicence, callerID uuid.UUID, callerRole string) (*model.Licence, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	licence, err := s.repo.AddLicence(ctx, practitionerID, model.Licence{
+		ID:           id,
+		Number:       data.Number,
+		Jurisdiction: data.Jurisdiction,
+		ExpiresOn:    data.ExpiresOn,
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return nil, fmt.Errorf("licence %w", err)
+		}
+		return nil, practitionerError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditLicenceAdded, "practitioner", practitionerID, map[string]any{
+		"licence_id":   licence.ID,
+		"jurisdiction": licence.Jurisdiction,
+		"expires_on":   licence.ExpiresOn,
+	})
+
+	return licence, nil
+}
+
+func (s *PractitionerService) RemoveLicence(ctx context.Context, practitionerID, licenceID uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	if !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	if err := s.repo.RemoveLicence(ctx, practitionerID, licenceID); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("practitioner or licence %w", err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditLicenceRemoved, "practitioner", practitionerID, map[string]any{
+		"licence_id": licenceID,
+	})
+
+	return nil
+}
+
+// FlagExpiringLicences flags the licences that expire within the given time
+// and emails their practitioners. It returns the number flagged. A licence is
+// flagged once, so a failed email is logged rather than retried.
+func (s *PractitionerService) FlagExpiringLicences(ctx context.Context, within time.Duration) (int, error) {
+	licences, err := s.repo.FlagExpiringLicences(ctx, time.Now().Add(within))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, l := range licences {
+		recordAudit(ctx, s.audit, nil, model.AuditLicenceExpiryFlagged, "practitioner", l.PractitionerID, map[string]any{
+			"licence_id":      l.LicenceID,
+			"organisation_id": l.OrganisationID,
+			"expires_on":      l.ExpiresOn,
+		})
+
+		err := s.mailer.Send(ctx, mailer.Message{
+			To:      l.Email,
+			Subject: "Your licence to practise is about to expire",
+			Body: fmt.Sprintf(
+				"Hello %s,\n\nYour licence %s (%s) expires on %s. Please renew it and send the new licence to your administrator.",
+				l.FirstName, l.Number, l.Jurisdiction, l.ExpiresOn,
+			),
+		})
+		if err != nil {
+			log.Printf("licence %s: failed to send expiry notice: %v", l.LicenceID, err)
+		}
+	}
+
+	return len(licences), nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+type mockPractitionerRepo struct {
+	createFunc     func(ctx context.Context, practitioner model.Practitioner, facilityIDs []uuid.UUID) error
+	getByIDFunc    func(ctx context.Context, id uuid.UUID) (*model.Practitioner, error)
+	listFunc       func(ctx context.Context, filter model.PractitionerFilter, limit, offset int) ([]model.PractitionerSummary, error)
+	updateByIDFunc func(ctx context.Context, id uuid.UUID, data *model.UpdatePractitioner, cond model.Precondition) (int64, error)
+	flagFunc       func(ctx context.Context, cutoff time.Time) ([]model.ExpiringLicence, error)
+	findByNPIFunc  func(ctx context.Context, npi string) (*model.Practitioner, error)
+
+	// practitionerUsers are the logins with a practitioner record.
+	practitionerUsers map[uuid.UUID]bool
+}
+
+func (m *mockPractitionerRepo) IsPractitioner(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return m.practitionerUsers[userID], nil
+}
+
+func (m *mockPractitionerRepo) FindByNPI(ctx context.Context, npi string) (*model.Practitioner, error) {
+	if m.findByNPIFunc != nil {
+		return m.findByNPIFunc(ctx, npi)
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockPractitionerRepo) CreateFacility(ctx context.Context, facility model.Facility) (*model.Facility, error) {
+	return &facility, nil
+}
+
+func (m *mockPractitionerRepo) ListFacilities(ctx context.Context) ([]model.Facility, error) {
+	return nil, nil
+}
+
+func (m *mockPractitionerRepo) Create(ctx context.Context, practitioner model.Practitioner, facilityIDs []uuid.UUID) error {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, practitioner, facilityIDs)
+	}
+	return nil
+}
+
+func (m *mockPractitionerRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Practitioner, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(ctx, id)
+	}
+	return &model.Practitioner{ID: id, Version: 1}, nil
+}
+
+func (m *mockPractitionerRepo) List(ctx context.Context, filter model.PractitionerFilter, limit, offset int) ([]model.PractitionerSummary, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, filter, limit, offset)
+	}
+	return nil, nil
+}
+
+func (m *mockPractitionerRepo) Count(ctx context.Context, filter model.PractitionerFilter) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPractitionerRepo) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdatePractitioner, cond model.Precondition) (int64, error) {
+	if m.updateByIDFunc != nil {
+		return m.updateByIDFunc(ctx, id, data, cond)
+	}
+	return 0, nil
+}
+
+func (m *mockPractitionerRepo) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
+	return nil
+}
+
+func (m *mockPractitionerRepo) AddLicence(ctx context.Context, practitionerID uuid.UUID, licence model.Licence) (*model.Licence, error) {
+	return &licence, nil
+}
+
+func (m *mockPractitionerRepo) RemoveLicence(ctx context.Context, practitionerID, licenceID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockPractitionerRepo) FlagExpiringLicences(ctx context.Context, cutoff time.Time) ([]model.ExpiringLicence, error) {
+	if m.flagFunc != nil {
+		return m.flagFunc(ctx, cutoff)
+	}
+	return nil, nil
+}
+
+func TestPractitionerService_Create(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	otherOrgID, _ := uuid.NewV7()
+	callerID, _ := uuid.NewV7()
+	userID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name       string
+		tenant     *uuid.UUID
+		orgID      *uuid.UUID
+		callerRole string
+		repoErr    error
+		expectErr  error
+	}{
+		{name: "admin registers in their organisation", tenant: &orgID, callerRole: "admin"},
+		{name: "admin registers in another organisation", tenant: &orgID, orgID: &otherOrgID, callerRole: "admin", expectErr: model.ErrForbidden},
+		{name: "user cannot register practitioners", tenant: &orgID, callerRole: "user", expectErr: model.ErrForbidden},
+		{name: "already registered", tenant: &orgID, callerRole: "admin", repoErr: model.ErrAlreadyExists, expectErr: model.ErrAlreadyExists},
+		{name: "unknown facility", tenant: &orgID, callerRole: "admin", repoErr: model.ErrNotFound, expectErr: model.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored model.Practitioner
+			mock := &mockPractitionerRepo{
+				createFunc: func(ctx context.Context, practitioner model.Practitioner, facilityIDs []uuid.UUID) error {
+					stored = practitioner
+					return tt.repoErr
+				},
+			}
+			audit := &mockAuditRepo{}
+			service := NewPractitionerService(mock, audit, &mockMailer{})
+
+			ctx := context.Background()
+			if tt.tenant != nil {
+				ctx = repository.WithTenant(ctx, *tt.tenant)
+			}
+
+			data := &model.CreatePractitioner{
+				OrganisationID: tt.orgID,
+				UserID:         userID,
+				Profession:     "doctor",
+				Licences:       []model.CreateLicence{{Number: "GMC123", Jurisdiction: "GB", ExpiresOn: "2099-01-01"}},
+			}
+			_, err := service.Create(ctx, data, callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if stored.OrganisationID != orgID {
+				t.Errorf("organisation = %v, want %v", stored.OrganisationID, orgID)
+			}
+			if len(stored.Licences) != 1 || stored.Licences[0].ID == uuid.Nil {
+				t.Errorf("licences = %+v, want one with an id", stored.Licences)
+			}
+			if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditPractitionerCreated {
+				t.Errorf("audit entries = %+v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestPractitionerService_List(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	service := NewPractitionerService(&mockPractitionerRepo{}, &mockAuditRepo{}, &mockMailer{})
+	params := model.PaginationParams{Page: 1, Limit: 10}
+
+	ctx := repository.WithTenant(context.Background(), orgID)
+	resp, err := service.List(ctx, "user", model.PractitionerFilter{}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Items == nil {
+		t.Error("expected an empty list, got nil")
+	}
+
+	if _, err := service.List(context.Background(), "user", model.PractitionerFilter{}, params); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden without an organisation, got %v", err)
+	}
+	if _, err := service.List(context.Background(), "super_admin", model.PractitionerFilter{}, params); err != nil {
+		t.Fatalf("unexpected error for super admin: %v", err)
+	}
+}
+
+func TestPractitionerService_UpdateByID(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	callerID, _ := uuid.NewV7()
+
+	mock := &mockPractitionerRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Practitioner, error) {
+			return &model.Practitioner{ID: id, Version: 4}, nil
+		},
+		updateByIDFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdatePractitioner, cond model.Precondition) (int64, error) {
+			if !cond.Matches(4) {
+				return 0, model.ErrPreconditionFailed
+			}
+			return 5, nil
+		},
+	}
+	audit := &mockAuditRepo{}
+	service := NewPractitionerService(mock, audit, &mockMailer{})
+	ctx := context.Background()
+	cond := model.Precondition{Versions: []int64{4}}
+	patch := &model.UpdatePractitioner{Specialties: model.Optional[[]string]{Value: []string{"Oncology"}, Set: true}}
+
+	version, err := service.UpdateByID(ctx, testID, patch, cond, callerID, "admin")
+	if err != nil || version != 5 {
+		t.Fatalf("expected version 5, got %d, %v", version, err)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditPractitionerUpdated {
+		t.Errorf("audit entries = %+v", audit.entries)
+	}
+
+	if _, err := service.UpdateByID(ctx, testID, patch, model.Precondition{Versions: []int64{3}}, callerID, "admin"); !errors.Is(err, model.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failed, got %v", err)
+	}
+
+	version, err = service.UpdateByID(ctx, testID, &model.UpdatePractitioner{}, cond, callerID, "admin")
+	if err != nil || version != 4 {
+		t.Fatalf("empty patch: expected current version 4, got %d, %v", version, err)
+	}
+
+	if _, err := service.UpdateByID(ctx, testID, patch, cond, callerID, "user"); !errors.Is(err, model.ErrForbidden) {
+		t.Fatalf("expected forbidden for user, got %v", err)
+	}
+}
+
+func TestPractitionerService_FlagExpiringLicences(t *testing.T) {
+	licenceID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+
+	var gotCutoff time.Time
+	mock := &mockPractitionerRepo{
+		flagFunc: func(ctx context.Context, cutoff time.Time) ([]model.ExpiringLicence, error) {
+			gotCutoff = cutoff
+			return []model.ExpiringLicence{{
+				LicenceID:      licenceID,
+				PractitionerID: practitionerID,
+				Number:         "GMC123",
+				Jurisdiction:   "GB",
+				ExpiresOn:      "2026-11-01",
+				FirstName:      "Ann",
+				Email:          "ann@example.com",
+			}}, nil
+		},
+	}
+	audit := &mockAuditRepo{}
+	mail := &mockMailer{}
+	service := NewPractitionerService(mock, audit, mail)
+
+	within := 30 * 24 * time.Hour
+	n, err := service.FlagExpiringLicences(context.Background(), within)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 flagged, got %d, %v", n, err)
+	}
+
+	if d := time.Until(gotCutoff) - within; d > time.Minute || d < -time.Minute {
+		t.Errorf("cutoff = %v, want about %v from now", gotCutoff, within)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != model.AuditLicenceExpiryFlagged || audit.entries[0].ActorID != nil {
+		t.Errorf("audit entries = %+v", audit.entries)
+	}
+	if len(mail.sent) != 1 || mail.sent[0].To != "ann@example.com" {
+		t.Errorf("sent = %+v", mail.sent)
+	}
+}
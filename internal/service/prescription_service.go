@@ -0,0 +1,578 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+type PrescriptionService struct {
+	repo         repository.PrescriptionRepository
+	medications  repository.MedicationRepository
+	interactions repository.InteractionRepository
+	allergies    repository.AllergyRepository
+	patients     repository.PatientRepository
+	audit        repository.AuditRepository
+	mailer       mailer.Mailer
+}
+
+func NewPrescriptionService(repo repository.PrescriptionRepository, medications repository.MedicationRepository, interactions repository.InteractionRepository, allergies repository.AllergyRepository, patients repository.PatientRepository, audit repository.AuditRepository, mailer mailer.Mailer) *PrescriptionService {
+	return &PrescriptionService{
+		repo:         repo,
+		medications:  medications,
+		interactions: interactions,
+		allergies:    allergies,
+		patients:     patients,
+		audit:        audit,
+		mailer:       mailer,
+	}
+}
+
+// prescriptionError adds context to the repository errors of a single
+// prescription.
+func prescriptionError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("prescription %w", err)
+	}
+	return err
+}
+
+// prescriber returns the practitioner record of a caller who may prescribe:
+// a practitioner of the organisation they are signed in to, in a
+// prescribing profession, with a licence that has not expired. Account
+// roles do not matter; an admin who is not a prescriber cannot prescribe.
+func (s *PrescriptionService) prescriber(ctx context.Context, callerID uuid.UUID) (*model.Prescriber, error) {
+	if repository.TenantFromContext(ctx) == nil {
+		return nil, fmt.Errorf("sign in to an organisation to prescribe: %w", model.ErrForbidden)
+	}
+
+	p, err := s.repo.GetPrescriber(ctx, callerID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("only prescribers can do this: %w", model.ErrForbidden)
+		}
+		return nil, err
+	}
+	if !model.CanPrescribe(p.Profession) {
+		return nil, fmt.Errorf("only prescribers can do this: %w", model.ErrForbidden)
+	}
+	if p.LicensedUntil == nil || *p.LicensedUntil < time.Now().Format(model.DateLayout) {
+		return nil, fmt.Errorf("a current licence is needed to prescribe: %w", model.ErrForbidden)
+	}
+	return p, nil
+}
+
+// isPrescriber reports whether the caller may prescribe.
+func (s *PrescriptionService) isPrescriber(ctx context.Context, callerID uuid.UUID) (bool, error) {
+	_, err := s.prescriber(ctx, callerID)
+	if errors.Is(err, model.ErrForbidden) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// drug returns the name of the drug to prescribe, drug if given or else
+// the catalogue name of rxcui, which must be in the catalogue; and the term
+// conflict checks match it by.
+func (s *PrescriptionService) drug(ctx context.Context, rxcui, drug string) (string, model.DrugTerm, error) {
+	if rxcui == "" {
+		return drug, model.DrugTerm{Name: drug}, nil
+	}
+
+	medication, err := s.medications.GetByRxCUI(ctx, rxcui)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return "", model.DrugTerm{}, model.ValidationErrors{model.FieldError{Field: "rxcui", Message: "rxcui is not in the medication catalogue"}}
+		}
+		return "", model.DrugTerm{}, err
+	}
+	term := model.DrugTerm{RxCUI: rxcui, Name: medication.Name}
+	if drug == "" {
+		return medication.Name, term, nil
+	}
+	return drug, term, nil
+}
+
+// drugTerm returns the term conflict checks match a recorded drug or
+// allergen by: a coded one by its catalogue name, and an uncoded one, or one
+// whose RxCUI has left the catalogue, by the name it was recorded with.
+// Catalogue names are looked up once per check, through names.
+func (s *PrescriptionService) drugTerm(ctx context.Context, names map[string]string, rxcui *string, name string) (model.DrugTerm, error) {
+	if rxcui == nil || *rxcui == "" {
+		return model.DrugTerm{Name: name}, nil
+	}
+
+	catalogueName, ok := names[*rxcui]
+	if !ok {
+		medication, err := s.medications.GetByRxCUI(ctx, *rxcui)
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			return model.DrugTerm{}, err
+		}
+		if medication != nil {
+			catalogueName = medication.Name
+		}
+		names[*rxcui] = catalogueName
+	}
+	if catalogueName == "" {
+		return model.DrugTerm{RxCUI: *rxcui, Name: name}, nil
+	}
+	return model.DrugTerm{RxCUI: *rxcui, Name: catalogueName}, nil
+}
+
+// conflicts finds what prescribing drug to a patient conflicts with, most
+// severe first: the patient's active and held prescriptions that the
+// interaction dataset pairs with it, and their allergies to it or to a
+// substance the dataset pairs with it. Drugs and allergens are matched as
+// model.DrugTerm describes. Refuted allergies and those entered in error
+// are ignored. Each prescription or allergy is reported once, at its most
+// severe.
+func (s *PrescriptionService) conflicts(ctx context.Context, patientID uuid.UUID, drug model.DrugTerm) ([]model.Conflict, error) {
+	current, err := s.repo.ListCurrent(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	allergies, err := s.allergies.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	interactions, err := s.interactions.ListMentioned(ctx, drug.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	currentTerms := make([]model.DrugTerm, len(current))
+	for i, rx := range current {
+		if currentTerms[i], err = s.drugTerm(ctx, names, rx.RxCUI, rx.Drug); err != nil {
+			return nil, err
+		}
+	}
+	allergyTerms := make([]model.DrugTerm, len(allergies))
+	for i, a := range allergies {
+		if allergyTerms[i], err = s.drugTerm(ctx, names, a.RxCUI, a.Allergen); err != nil {
+			return nil, err
+		}
+	}
+
+	conflicts := []model.Conflict{}
+	add := func(c model.Conflict) {
+		for i, seen := range conflicts {
+			if (c.PrescriptionID != nil && seen.PrescriptionID != nil && *c.PrescriptionID == *seen.PrescriptionID) ||
+				(c.AllergyID != nil && seen.AllergyID != nil && *c.AllergyID == *seen.AllergyID) {
+				if model.MoreSevere(c.Severity, seen.Severity) {
+					conflicts[i] = c
+				}
+				return
+			}
+		}
+		conflicts = append(conflicts, c)
+	}
+
+	for i, a := range allergies {
+		allergen := allergyTerms[i]
+		if a.Active() && (drug.Same(allergen) || drug.Mentions(allergen.Name) || allergen.Mentions(drug.Name)) {
+			description := "patient is allergic to " + a.Allergen
+			if a.Reaction != nil {
+				description += " (" + *a.Reaction + ")"
+			}
+			add(model.Conflict{Type: model.ConflictDrugAllergy, Severity: a.ConflictSeverity(), With: a.Allergen, AllergyID: &a.ID, Description: description})
+		}
+	}
+
+	for _, in := range interactions {
+		for _, pair := range [][2]string{{in.SubstanceA, in.SubstanceB}, {in.SubstanceB, in.SubstanceA}} {
+			if !drug.Mentions(pair[0]) {
+				continue
+			}
+			for i, rx := range current {
+				if currentTerms[i].Mentions(pair[1]) {
+					add(model.Conflict{Type: model.ConflictDrugDrug, Severity: in.Severity, With: rx.Drug, PrescriptionID: &rx.ID, Description: in.Description})
+				}
+			}
+			for i, a := range allergies {
+				if a.Active() && allergyTerms[i].Mentions(pair[1]) {
+					add(model.Conflict{Type: model.ConflictDrugAllergy, Severity: in.Severity, With: a.Allergen, AllergyID: &a.ID, Description: in.Description})
+				}
+			}
+		}
+	}
+
+	model.SortConflicts(conflicts)
+	return conflicts, nil
+}
+
+// Check returns the conflicts prescribing a drug to a patient would raise,
+// for prescribers to review before prescribing.
+func (s *PrescriptionService) Check(ctx context.Context, data *model.CheckPrescription, callerID uuid.UUID, callerRole string) ([]model.Conflict, error) {
+	if _, err := s.prescriber(ctx, callerID); err != nil {
+		return nil, err
+	}
+	if _, err := s.patients.GetByID(ctx, data.PatientID); err != nil {
+		return nil, patientError(err)
+	}
+
+	_, term, err := s.drug(ctx, data.RxCUI, data.Drug)
+	if err != nil {
+		return nil, err
+	}
+	return s.conflicts(ctx, data.PatientID, term)
+}
+
+// Create prescribes a medication to a patient of the prescriber's
+// organisation. The caller is the prescriber. The drug is checked against
+// the patient's prescriptions and allergies first; conflicts are returned
+// with the prescription, and high-severity ones refuse it unless the
+// prescriber gives a reason to override them, which is audited.
+func (s *PrescriptionService) Create(ctx context.Context, data *model.CreatePrescription, callerID uuid.UUID, callerRole string) (*model.Prescription, error) {
+	prescriber, err := s.prescriber(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	drug, term, err := s.drug(ctx, data.RxCUI, data.Drug)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts, err := s.conflicts(ctx, data.PatientID, term)
+	if err != nil {
+		return nil, err
+	}
+	var high []model.Conflict
+	var refusals model.ValidationErrors
+	for _, c := range conflicts {
+		if c.Severity != model.SeverityHigh {
+			continue
+		}
+		high = append(high, c)
+		what := "interaction with "
+		if c.Type == model.ConflictDrugAllergy {
+			what = "allergy conflict with "
+		}
+		refusals = append(refusals, model.FieldError{
+			Field:   "override_reason",
+			Message: "high-severity " + what + c.With + ": " + c.Description + "; give an override_reason to prescribe anyway",
+		})
+	}
+	if len(high) > 0 && data.OverrideReason == "" {
+		return nil, refusals
+	}
+	var overrideReason *string
+	if len(high) > 0 {
+		overrideReason = &data.OverrideReason
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	err = s.repo.Create(ctx, model.Prescription{
+		ID:             id,
+		PatientID:      data.PatientID,
+		PrescriberID:   prescriber.PractitionerID,
+		RxCUI:          optionalString(data.RxCUI),
+		Drug:           drug,
+		Strength:       optionalString(data.Strength),
+		Dose:           data.Dose,
+		Route:          data.Route,
+		Frequency:      data.Frequency,
+		DurationDays:   data.DurationDays,
+		Quantity:       data.Quantity,
+		Refills:        data.Refills,
+		Instructions:   optionalString(data.Instructions),
+		OverrideReason: overrideReason,
+		CreatedBy:      &callerID,
+	})
+	if err != nil {
+		return nil, patientError(err)
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditPrescriptionCreated, "prescription", id, map[string]any{
+		"patient_id":    data.PatientID,
+		"prescriber_id": prescriber.PractitionerID,
+		"rxcui":         data.RxCUI,
+		"drug":          drug,
+		"refills":       data.Refills,
+		"conflicts":     len(conflicts),
+	})
+	if overrideReason != nil {
+		recordAudit(ctx, s.audit, &callerID, model.AuditPrescriptionOverridden, "prescription", id, map[string]any{
+			"patient_id": data.PatientID,
+			"reason":     *overrideReason,
+			"conflicts":  high,
+		})
+	}
+
+	prescription, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, prescriptionError(err)
+	}
+	prescription.Conflicts = conflicts
+	return prescription, nil
+}
+
+// List returns a page of prescriptions. Admins and prescribers see every
+// prescription of their organisation; other users see those they are the
+// prescriber or the patient of.
+func (s *PrescriptionService) List(ctx context.Context, filter model.PrescriptionFilter, params model.PaginationParams, callerID uuid.UUID, callerRole string) (*model.PaginatedPrescriptionsResponse, error) {
+	if !isAdmin(callerRole) {
+		prescriber, err := s.isPrescriber(ctx, callerID)
+		if err != nil {
+			return nil, err
+		}
+		if !prescriber {
+			filter.ParticipantID = &callerID
+		}
+	}
+
+	prescriptions, err := s.repo.List(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if prescriptions == nil {
+		prescriptions = []model.Prescription{}
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedPrescriptionsResponse{
+		Items: prescriptions,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// GetByID returns a prescription to admins, prescribers, and its patient.
+// Other callers are told it does not exist.
+func (s *PrescriptionService) GetByID(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.Prescription, error) {
+	prescription, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, prescriptionError(err)
+	}
+
+	if isAdmin(callerRole) || prescription.PrescriberUserID == callerID ||
+		(prescription.PatientUserID != nil && *prescription.PatientUserID == callerID) {
+		return prescription, nil
+	}
+
+	prescriber, err := s.isPrescriber(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+	if !prescriber {
+		return nil, fmt.Errorf("prescription %w", model.ErrNotFound)
+	}
+	return prescription, nil
+}
+
+// SetStatus moves a prescription on. Any prescriber of the organisation may
+// do so, so that colleagues can act on each other's prescriptions.
+func (s *PrescriptionService) SetStatus(ctx context.Context, id uuid.UUID, data *model.ChangePrescriptionStatus, callerID uuid.UUID, callerRole string) (*model.Prescription, error) {
+	if _, err := s.prescriber(ctx, callerID); err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, prescriptionError(err)
+	}
+
+	if !model.CanTransitionPrescription(current.Status, data.Status) {
+		return nil, fmt.Errorf("cannot move prescription from %s to %s: %w", current.Status, data.Status, model.ErrConflict)
+	}
+
+	if err := s.repo.SetStatus(ctx, id, current.Status, data.Status, optionalString(data.Reason)); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return nil, fmt.Errorf("prescription has changed: %w", err)
+		}
+		return nil, err
+	}
+
+	details := map[string]any{
+		"from": current.Status,
+		"to":   data.Status,
+	}
+	if data.Reason != "" {
+		details["reason"] = data.Reason
+	}
+	recordAudit(ctx, s.audit, &callerID, model.AuditPrescriptionStatusChanged, "prescription", id, details)
+
+	prescription, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, prescriptionError(err)
+	}
+	return prescription, nil
+}
+
+// RequestRefill asks for a refill of an active prescription with refills
+// remaining. The patient asks through their login; admins may ask on their
+// behalf.
+func (s *PrescriptionService) RequestRefill(ctx context.Context, id uuid.UUID, data *model.CreateRefillRequest, callerID uuid.UUID, callerRole string) (*model.RefillRequest, error) {
+	prescription, err := s.GetByID(ctx, id, callerID, callerRole)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin(callerRole) && (prescription.PatientUserID == nil || *prescription.PatientUserID != callerID) {
+		return nil, fmt.Errorf("only the patient can request a refill: %w", model.ErrForbidden)
+	}
+
+	if prescription.Status != model.PrescriptionActive {
+		return nil, fmt.Errorf("only active prescriptions can be refilled: %w", model.ErrConflict)
+	}
+	if prescription.RefillsRemaining == 0 {
+		return nil, fmt.Errorf("no refills remain: %w", model.ErrConflict)
+	}
+
+	refillID, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	err = s.repo.CreateRefill(ctx, model.RefillRequest{
+		ID:             refillID,
+		PrescriptionID: id,
+		Note:           optionalString(data.Note),
+		RequestedBy:    &callerID,
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return nil, fmt.Errorf("a refill request is already pending or the prescription has changed: %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditRefillRequested, "prescription", id, map[string]any{
+		"refill_id": refillID,
+	})
+
+	refill, err := s.repo.GetRefill(ctx, id, refillID)
+	if err != nil {
+		return nil, refillError(err)
+	}
+	return refill, nil
+}
+
+// refillError adds context to the repository errors of a single refill
+// request.
+func refillError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("refill request %w", err)
+	}
+	return err
+}
+
+// ListRefills returns the refill requests of a prescription to those who
+// may see it.
+func (s *PrescriptionService) ListRefills(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) ([]model.RefillRequest, error) {
+	if _, err := s.GetByID(ctx, id, callerID, callerRole); err != nil {
+		return nil, err
+	}
+
+	refills, err := s.repo.ListRefills(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if refills == nil {
+		refills = []model.RefillRequest{}
+	}
+	return refills, nil
+}
+
+// DecideRefill approves or denies a pending refill request. Approving uses
+// up one refill of the prescription. The patient is emailed the decision.
+func (s *PrescriptionService) DecideRefill(ctx context.Context, id, refillID uuid.UUID, data *model.DecideRefillRequest, callerID uuid.UUID, callerRole string) (*model.RefillRequest, error) {
+	prescriber, err := s.prescriber(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	prescription, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, prescriptionError(err)
+	}
+	refill, err := s.repo.GetRefill(ctx, id, refillID)
+	if err != nil {
+		return nil, refillError(err)
+	}
+	if refill.Status != model.RefillPending {
+		return nil, fmt.Errorf("refill request is already %s: %w", refill.Status, model.ErrConflict)
+	}
+
+	approve := *data.Approve
+	if approve && (prescription.Status != model.PrescriptionActive || prescription.RefillsRemaining == 0) {
+		return nil, fmt.Errorf("prescription is %s with %d refills remaining: %w", prescription.Status, prescription.RefillsRemaining, model.ErrConflict)
+	}
+
+	if err := s.repo.DecideRefill(ctx, id, refillID, approve, prescriber.PractitionerID, optionalString(data.Note)); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return nil, fmt.Errorf("refill request or prescription has changed: %w", err)
+		}
+		return nil, err
+	}
+
+	action := model.AuditRefillDenied
+	if approve {
+		action = model.AuditRefillApproved
+	}
+	recordAudit(ctx, s.audit, &callerID, action, "prescription", id, map[string]any{
+		"refill_id": refillID,
+	})
+
+	s.notifyRefill(ctx, prescription, approve, data.Note)
+
+	refill, err = s.repo.GetRefill(ctx, id, refillID)
+	if err != nil {
+		return nil, refillError(err)
+	}
+	return refill, nil
+}
+
+// notifyRefill emails the patient, if they have an email address, the
+// decision on their refill request. Failures are logged rather than
+// returned, since the decision stands either way.
+func (s *PrescriptionService) notifyRefill(ctx context.Context, prescription *model.Prescription, approved bool, note string) {
+	patient, err := s.patients.GetByID(ctx, prescription.PatientID)
+	if err != nil {
+		log.Printf("prescription %s: refill notification: %v", prescription.ID, err)
+		return
+	}
+	if patient.Email == nil || *patient.Email == "" {
+		return
+	}
+
+	msg := mailer.Message{
+		To:      *patient.Email,
+		Subject: "Your refill request was approved",
+		Body:    fmt.Sprintf("Hello %s,\n\nYour request for a refill of %s was approved.", patient.FirstName, prescription.Drug),
+	}
+	if !approved {
+		msg.Subject = "Your refill request was not approved"
+		msg.Body = fmt.Sprintf("Hello %s,\n\nYour request for a refill of %s was not approved:\n\n%s", patient.FirstName, prescription.Drug, note)
+	}
+	if note != "" && approved {
+		msg.Body += "\n\n" + note
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("prescription %s: refill notification: %v", prescription.ID, err)
+	}
+}
@@ -0,0 +1,1062 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/google/uuid"
+)
+
+// mockPrescriptionRepo keeps prescriptions and refill requests in memory and
+// follows the rules of the database. The xxxFunc fields, when set, replace
+// those rules.
+type mockPrescriptionRepo struct {
+	// prescribers maps a login to its practitioner record.
+	prescribers   map[uuid.UUID]*model.Prescriber
+	prescriptions map[uuid.UUID]*model.Prescription
+	refills       []*model.RefillRequest
+
+	// patientUsers maps a patient to their login.
+	patientUsers map[uuid.UUID]uuid.UUID
+
+	createFunc       func(ctx context.Context, rx model.Prescription) error
+	listCurrentFunc  func(ctx context.Context, patientID uuid.UUID) ([]model.Prescription, error)
+	setStatusFunc    func(ctx context.Context, id uuid.UUID, from, to string, reason *string) error
+	createRefillFunc func(ctx context.Context, refill model.RefillRequest) error
+	decideRefillFunc func(ctx context.Context, prescriptionID, refillID uuid.UUID, approve bool, decidedBy uuid.UUID, note *string) error
+}
+
+func (m *mockPrescriptionRepo) GetPrescriber(ctx context.Context, userID uuid.UUID) (*model.Prescriber, error) {
+	p, ok := m.prescribers[userID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return p, nil
+}
+
+func (m *mockPrescriptionRepo) Create(ctx context.Context, rx model.Prescription) error {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, rx)
+	}
+	userID, ok := m.patientUsers[rx.PatientID]
+	if !ok {
+		return model.ErrNotFound
+	}
+	for u, p := range m.prescribers {
+		if p.PractitionerID == rx.PrescriberID {
+			rx.PrescriberUserID = u
+		}
+	}
+	rx.PatientUserID = &userID
+	rx.Status = model.PrescriptionActive
+	rx.RefillsRemaining = rx.Refills
+	if m.prescriptions == nil {
+		m.prescriptions = map[uuid.UUID]*model.Prescription{}
+	}
+	m.prescriptions[rx.ID] = &rx
+	return nil
+}
+
+func (m *mockPrescriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Prescription, error) {
+	rx, ok := m.prescriptions[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	copy := *rx
+	return &copy, nil
+}
+
+func (m *mockPrescriptionRepo) List(ctx context.Context, filter model.PrescriptionFilter, limit, offset int) ([]model.Prescription, error) {
+	var list []model.Prescription
+	for _, rx := range m.prescriptions {
+		if filter.ParticipantID != nil && rx.PrescriberUserID != *filter.ParticipantID &&
+			(rx.PatientUserID == nil || *rx.PatientUserID != *filter.ParticipantID) {
+			continue
+		}
+		list = append(list, *rx)
+	}
+	return list, nil
+}
+
+func (m *mockPrescriptionRepo) Count(ctx context.Context, filter model.PrescriptionFilter) (int, error) {
+	list, _ := m.List(ctx, filter, 0, 0)
+	return len(list), nil
+}
+
+func (m *mockPrescriptionRepo) ListCurrent(ctx context.Context, patientID uuid.UUID) ([]model.Prescription, error) {
+	if m.listCurrentFunc != nil {
+		return m.listCurrentFunc(ctx, patientID)
+	}
+	var list []model.Prescription
+	for _, rx := range m.prescriptions {
+		if rx.PatientID == patientID && (rx.Status == model.PrescriptionActive || rx.Status == model.PrescriptionOnHold) {
+			list = append(list, *rx)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockPrescriptionRepo) SetStatus(ctx context.Context, id uuid.UUID, from, to string, reason *string) error {
+	if m.setStatusFunc != nil {
+		return m.setStatusFunc(ctx, id, from, to, reason)
+	}
+	rx, ok := m.prescriptions[id]
+	if !ok || rx.Status != from {
+		return model.ErrConflict
+	}
+	rx.Status = to
+	rx.StatusReason = reason
+	return nil
+}
+
+func (m *mockPrescriptionRepo) pending(prescriptionID uuid.UUID) *model.RefillRequest {
+	for _, rf := range m.refills {
+		if rf.PrescriptionID == prescriptionID && rf.Status == model.RefillPending {
+			return rf
+		}
+	}
+	return nil
+}
+
+func (m *mockPrescriptionRepo) CreateRefill(ctx context.Context, refill model.RefillRequest) error {
+	if m.createRefillFunc != nil {
+		return m.createRefillFunc(ctx, refill)
+	}
+	rx, ok := m.prescriptions[refill.PrescriptionID]
+	if !ok || rx.Status != model.PrescriptionActive || rx.RefillsRemaining == 0 || m.pending(rx.ID) != nil {
+		return model.ErrConflict
+	}
+	refill.Status = model.RefillPending
+	m.refills = append(m.refills, &refill)
+	return nil
+}
+
+func (m *mockPrescriptionRepo) GetRefill(ctx context.Context, prescriptionID, refillID uuid.UUID) (*model.RefillRequest, error) {
+	for _, rf := range m.refills {
+		if rf.ID == refillID && rf.PrescriptionID == prescriptionID {
+			copy := *rf
+			return &copy, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockPrescriptionRepo) ListRefills(ctx context.Context, prescriptionID uuid.UUID) ([]model.RefillRequest, error) {
+	var refills []model.RefillRequest
+	for _, rf := range m.refills {
+		if rf.PrescriptionID == prescriptionID {
+			refills = append(refills, *rf)
+		}
+	}
+	return refills, nil
+}
+
+func (m *mockPrescriptionRepo) DecideRefill(ctx context.Context, prescriptionID, refillID uuid.UUID, approve bool, decidedBy uuid.UUID, note *string) error {
+	if m.decideRefillFunc != nil {
+		return m.decideRefillFunc(ctx, prescriptionID, refillID, approve, decidedBy, note)
+	}
+	rf := m.pending(prescriptionID)
+	if rf == nil || rf.ID != refillID {
+		return model.ErrConflict
+	}
+	rx := m.prescriptions[prescriptionID]
+	if approve {
+		if rx.Status != model.PrescriptionActive || rx.RefillsRemaining == 0 {
+			return model.ErrConflict
+		}
+		rx.RefillsRemaining--
+		rf.Status = model.RefillApproved
+	} else {
+		rf.Status = model.RefillDenied
+	}
+	rf.DecidedBy = &decidedBy
+	rf.DecisionNote = note
+	return nil
+}
+
+// mockInteractionRepo holds the interaction dataset in memory.
+type mockInteractionRepo struct {
+	interactions []model.Interaction
+}
+
+func (m *mockInteractionRepo) UpsertBatch(ctx context.Context, interactions []model.Interaction) (int, error) {
+	changed := 0
+	for _, in := range interactions {
+		found := false
+		for i, old := range m.interactions {
+			if old.SubstanceA == in.SubstanceA && old.SubstanceB == in.SubstanceB {
+				found = true
+				if old != in {
+					m.interactions[i] = in
+					changed++
+				}
+			}
+		}
+		if !found {
+			m.interactions = append(m.interactions, in)
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (m *mockInteractionRepo) ListMentioned(ctx context.Context, drug string) ([]model.Interaction, error) {
+	var list []model.Interaction
+	for _, in := range m.interactions {
+		if strings.Contains(strings.ToLower(drug), in.SubstanceA) || strings.Contains(strings.ToLower(drug), in.SubstanceB) {
+			list = append(list, in)
+		}
+	}
+	return list, nil
+}
+
+func TestPrescriptionService_Create(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	otherPatientID, _ := uuid.NewV7()
+
+	licensedUntil := time.Now().AddDate(1, 0, 0).Format(model.DateLayout)
+	doctor := model.Prescriber{PractitionerID: practitionerID, Profession: "doctor", LicensedUntil: &licensedUntil}
+	amoxicillin := model.Medication{RxCUI: "308191", Name: "amoxicillin 500 MG Oral Capsule", TermType: "SCD"}
+
+	tests := []struct {
+		name        string
+		data        model.CreatePrescription
+		mockFunc    func(ctx context.Context, rx model.Prescription) error
+		expectErr   error
+		expectField string
+		expectDrug  string
+	}{
+		{
+			name:       "success - catalogue drug",
+			data:       model.CreatePrescription{PatientID: patientID, RxCUI: "308191", Refills: 2},
+			expectDrug: "amoxicillin 500 MG Oral Capsule",
+		},
+		{
+			name:       "success - named drug",
+			data:       model.CreatePrescription{PatientID: patientID, Drug: "paracetamol", Refills: 2},
+			expectDrug: "paracetamol",
+		},
+		{
+			name:        "unknown rxcui",
+			data:        model.CreatePrescription{PatientID: patientID, RxCUI: "1"},
+			expectField: "rxcui",
+		},
+		{
+			name:      "unknown patient",
+			data:      model.CreatePrescription{PatientID: otherPatientID, Drug: "paracetamol"},
+			expectErr: model.ErrNotFound,
+		},
+		{
+			name: "repo error",
+			data: model.CreatePrescription{PatientID: patientID, Drug: "paracetamol"},
+			mockFunc: func(ctx context.Context, rx model.Prescription) error {
+				return errRepo
+			},
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockPrescriptionRepo{
+				prescribers:  map[uuid.UUID]*model.Prescriber{doctorID: &doctor},
+				patientUsers: map[uuid.UUID]uuid.UUID{patientID: patientUserID},
+				createFunc:   tt.mockFunc,
+			}
+			medications := &mockMedicationRepo{catalogue: map[string]model.Medication{amoxicillin.RxCUI: amoxicillin}}
+			service := NewPrescriptionService(repo, medications, &mockInteractionRepo{}, &mockAllergyRepo{}, &mockPatientRepo{}, &mockAuditRepo{}, &mockMailer{})
+
+			data := tt.data
+			data.Dose, data.Route, data.Frequency, data.Quantity = "1 capsule", "oral", "three times daily", 21
+			ctx := repository.WithTenant(context.Background(), orgID)
+			rx, err := service.Create(ctx, &data, doctorID, "user")
+
+			if tt.expectField != "" {
+				var verrs model.ValidationErrors
+				if !errors.As(err, &verrs) || verrs[0].Field != tt.expectField {
+					t.Errorf("expected a validation error on %s, got %v", tt.expectField, err)
+				}
+				return
+			}
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rx.Drug != tt.expectDrug || (data.RxCUI != "" && (rx.RxCUI == nil || *rx.RxCUI != data.RxCUI)) {
+				t.Errorf("prescription = %+v, want %s", rx, tt.expectDrug)
+			}
+			if rx.Status != model.PrescriptionActive || rx.RefillsRemaining != 2 {
+				t.Errorf("prescription is %s with %d refills remaining, want active with 2", rx.Status, rx.RefillsRemaining)
+			}
+		})
+	}
+}
+
+func TestPrescriptionService_Create_Prescribers(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	callerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+
+	expired := time.Now().AddDate(0, 0, -1).Format(model.DateLayout)
+	current := time.Now().AddDate(1, 0, 0).Format(model.DateLayout)
+
+	tests := []struct {
+		name       string
+		prescriber *model.Prescriber
+		callerRole string
+		unscoped   bool
+		expectErr  error
+	}{
+		{name: "licensed nurse", prescriber: &model.Prescriber{Profession: "nurse", LicensedUntil: &current}, callerRole: "user"},
+		{name: "admin who is a doctor", prescriber: &model.Prescriber{Profession: "doctor", LicensedUntil: &current}, callerRole: "admin"},
+		{name: "user who is not a practitioner", callerRole: "user", expectErr: model.ErrForbidden},
+		{name: "admin who is not a practitioner", callerRole: "admin", expectErr: model.ErrForbidden},
+		{name: "therapist", prescriber: &model.Prescriber{Profession: "therapist", LicensedUntil: &current}, callerRole: "user", expectErr: model.ErrForbidden},
+		{name: "expired licence", prescriber: &model.Prescriber{Profession: "doctor", LicensedUntil: &expired}, callerRole: "user", expectErr: model.ErrForbidden},
+		{name: "no licence", prescriber: &model.Prescriber{Profession: "doctor"}, callerRole: "user", expectErr: model.ErrForbidden},
+		{name: "not signed in to an organisation", prescriber: &model.Prescriber{Profession: "doctor", LicensedUntil: &current}, callerRole: "super_admin", unscoped: true, expectErr: model.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockPrescriptionRepo{
+				prescribers:  map[uuid.UUID]*model.Prescriber{},
+				patientUsers: map[uuid.UUID]uuid.UUID{patientID: patientUserID},
+			}
+			if tt.prescriber != nil {
+				repo.prescribers[callerID] = tt.prescriber
+			}
+			service := NewPrescriptionService(repo, &mockMedicationRepo{}, &mockInteractionRepo{}, &mockAllergyRepo{}, &mockPatientRepo{}, &mockAuditRepo{}, &mockMailer{})
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			if tt.unscoped {
+				ctx = context.Background()
+			}
+			data := &model.CreatePrescription{PatientID: patientID, Drug: "paracetamol", Dose: "1 g", Route: "oral", Frequency: "four times daily", Quantity: 32}
+			_, err := service.Create(ctx, data, callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPrescriptionService_Visibility(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	colleagueID, _ := uuid.NewV7()
+	strangerID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	rxID, _ := uuid.NewV7()
+
+	licensedUntil := time.Now().AddDate(1, 0, 0).Format(model.DateLayout)
+	doctor := model.Prescriber{PractitionerID: practitionerID, Profession: "doctor", LicensedUntil: &licensedUntil}
+	rx := model.Prescription{ID: rxID, PatientID: patientID, PrescriberID: practitionerID, PrescriberUserID: doctorID, PatientUserID: &patientUserID, Drug: "paracetamol", Status: model.PrescriptionActive}
+
+	tests := []struct {
+		name         string
+		callerID     uuid.UUID
+		expectGetErr error
+		expectListed int
+	}{
+		{name: "patient", callerID: patientUserID, expectListed: 1},
+		{name: "prescriber", callerID: doctorID, expectListed: 1},
+		{name: "colleague in the organisation", callerID: colleagueID, expectListed: 1},
+		{name: "stranger", callerID: strangerID, expectGetErr: model.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockPrescriptionRepo{
+				prescribers:   map[uuid.UUID]*model.Prescriber{doctorID: &doctor, colleagueID: &doctor},
+				prescriptions: map[uuid.UUID]*model.Prescription{rxID: &rx},
+			}
+			service := NewPrescriptionService(repo, &mockMedicationRepo{}, &mockInteractionRepo{}, &mockAllergyRepo{}, &mockPatientRepo{}, &mockAuditRepo{}, &mockMailer{})
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			_, err := service.GetByID(ctx, rxID, tt.callerID, "user")
+			if tt.expectGetErr != nil {
+				if !errors.Is(err, tt.expectGetErr) {
+					t.Errorf("expected %v, got %v", tt.expectGetErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			list, err := service.List(ctx, model.PrescriptionFilter{}, model.PaginationParams{Page: 1, Limit: 10}, tt.callerID, "user")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(list.Items) != tt.expectListed {
+				t.Errorf("listed %d prescriptions, want %d", len(list.Items), tt.expectListed)
+			}
+		})
+	}
+}
+
+func TestPrescriptionService_SetStatus(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	rxID, _ := uuid.NewV7()
+
+	licensedUntil := time.Now().AddDate(1, 0, 0).Format(model.DateLayout)
+	doctor := model.Prescriber{PractitionerID: practitionerID, Profession: "doctor", LicensedUntil: &licensedUntil}
+	hold := model.ChangePrescriptionStatus{Status: model.PrescriptionOnHold, Reason: "awaiting blood results"}
+	complete := model.ChangePrescriptionStatus{Status: model.PrescriptionCompleted}
+
+	tests := []struct {
+		name         string
+		mockFunc     func(ctx context.Context, id uuid.UUID, from, to string, reason *string) error
+		status       string
+		data         model.ChangePrescriptionStatus
+		callerID     uuid.UUID
+		expectErr    error
+		expectStatus string
+	}{
+		{
+			name:         "success - put on hold",
+			status:       model.PrescriptionActive,
+			data:         hold,
+			callerID:     doctorID,
+			expectStatus: model.PrescriptionOnHold,
+		},
+		{
+			name:         "patient cannot change it",
+			status:       model.PrescriptionActive,
+			data:         hold,
+			callerID:     patientUserID,
+			expectErr:    model.ErrForbidden,
+			expectStatus: model.PrescriptionActive,
+		},
+		{
+			name:         "completing a prescription on hold",
+			status:       model.PrescriptionOnHold,
+			data:         complete,
+			callerID:     doctorID,
+			expectErr:    model.ErrConflict,
+			expectStatus: model.PrescriptionOnHold,
+		},
+		{
+			name: "changed meanwhile",
+			mockFunc: func(ctx context.Context, id uuid.UUID, from, to string, reason *string) error {
+				return model.ErrConflict
+			},
+			status:       model.PrescriptionActive,
+			data:         hold,
+			callerID:     doctorID,
+			expectErr:    model.ErrConflict,
+			expectStatus: model.PrescriptionActive,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, id uuid.UUID, from, to string, reason *string) error {
+				return errRepo
+			},
+			status:       model.PrescriptionActive,
+			data:         hold,
+			callerID:     doctorID,
+			expectErr:    errRepo,
+			expectStatus: model.PrescriptionActive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rx := &model.Prescription{ID: rxID, PatientID: patientID, PrescriberID: practitionerID, PrescriberUserID: doctorID, PatientUserID: &patientUserID, Drug: "paracetamol", Status: tt.status}
+			repo := &mockPrescriptionRepo{
+				prescribers:   map[uuid.UUID]*model.Prescriber{doctorID: &doctor},
+				prescriptions: map[uuid.UUID]*model.Prescription{rxID: rx},
+				setStatusFunc: tt.mockFunc,
+			}
+			service := NewPrescriptionService(repo, &mockMedicationRepo{}, &mockInteractionRepo{}, &mockAllergyRepo{}, &mockPatientRepo{}, &mockAuditRepo{}, &mockMailer{})
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			got, err := service.SetStatus(ctx, rxID, &tt.data, tt.callerID, "user")
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got.StatusReason == nil || *got.StatusReason != tt.data.Reason {
+					t.Errorf("prescription = %+v, want the reason", got)
+				}
+			}
+			if rx.Status != tt.expectStatus {
+				t.Errorf("prescription is %s, want %s", rx.Status, tt.expectStatus)
+			}
+		})
+	}
+}
+
+func TestPrescriptionService_RequestRefill(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	rxID, _ := uuid.NewV7()
+	refillID, _ := uuid.NewV7()
+
+	licensedUntil := time.Now().AddDate(1, 0, 0).Format(model.DateLayout)
+	doctor := model.Prescriber{PractitionerID: practitionerID, Profession: "doctor", LicensedUntil: &licensedUntil}
+
+	tests := []struct {
+		name      string
+		mockFunc  func(ctx context.Context, refill model.RefillRequest) error
+		remaining int
+		pending   bool
+		callerID  uuid.UUID
+		expectErr error
+	}{
+		{
+			name:      "success - patient asks",
+			remaining: 1,
+			callerID:  patientUserID,
+		},
+		{
+			name:      "prescriber cannot ask",
+			remaining: 1,
+			callerID:  doctorID,
+			expectErr: model.ErrForbidden,
+		},
+		{
+			name:      "request already pending",
+			remaining: 1,
+			pending:   true,
+			callerID:  patientUserID,
+			expectErr: model.ErrConflict,
+		},
+		{
+			name:      "no refills remaining",
+			remaining: 0,
+			callerID:  patientUserID,
+			expectErr: model.ErrConflict,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, refill model.RefillRequest) error {
+				return errRepo
+			},
+			remaining: 1,
+			callerID:  patientUserID,
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rx := &model.Prescription{ID: rxID, PatientID: patientID, PrescriberID: practitionerID, PrescriberUserID: doctorID, PatientUserID: &patientUserID, Drug: "paracetamol",
+				Status: model.PrescriptionActive, Refills: 1, RefillsRemaining: tt.remaining}
+			repo := &mockPrescriptionRepo{
+				prescribers:      map[uuid.UUID]*model.Prescriber{doctorID: &doctor},
+				prescriptions:    map[uuid.UUID]*model.Prescription{rxID: rx},
+				createRefillFunc: tt.mockFunc,
+			}
+			if tt.pending {
+				repo.refills = []*model.RefillRequest{{ID: refillID, PrescriptionID: rxID, Status: model.RefillPending}}
+			}
+			service := NewPrescriptionService(repo, &mockMedicationRepo{}, &mockInteractionRepo{}, &mockAllergyRepo{}, &mockPatientRepo{}, &mockAuditRepo{}, &mockMailer{})
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			refill, err := service.RequestRefill(ctx, rxID, &model.CreateRefillRequest{Note: "running low"}, tt.callerID, "user")
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if refill.Status != model.RefillPending || refill.Note == nil || *refill.Note != "running low" {
+				t.Errorf("refill = %+v, want pending with the note", refill)
+			}
+		})
+	}
+}
+
+func TestPrescriptionService_DecideRefill(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	rxID, _ := uuid.NewV7()
+	refillID, _ := uuid.NewV7()
+
+	yes, no := true, false
+	licensedUntil := time.Now().AddDate(1, 0, 0).Format(model.DateLayout)
+	doctor := model.Prescriber{PractitionerID: practitionerID, Profession: "doctor", LicensedUntil: &licensedUntil}
+	email := "patient@example.com"
+	getPatient := func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+		return &model.Patient{ID: id, FirstName: "Pat", Email: &email}, nil
+	}
+
+	tests := []struct {
+		name            string
+		mockFunc        func(ctx context.Context, prescriptionID, refillID uuid.UUID, approve bool, decidedBy uuid.UUID, note *string) error
+		refillStatus    string
+		remaining       int
+		data            model.DecideRefillRequest
+		callerID        uuid.UUID
+		callerRole      string
+		expectErr       error
+		expectStatus    string
+		expectRemaining int
+		expectMail      string
+		expectAskAgain  error
+	}{
+		{
+			name:            "success - approved",
+			refillStatus:    model.RefillPending,
+			remaining:       1,
+			data:            model.DecideRefillRequest{Approve: &yes},
+			callerID:        doctorID,
+			callerRole:      "user",
+			expectStatus:    model.RefillApproved,
+			expectRemaining: 0,
+			expectMail:      "approved",
+			expectAskAgain:  model.ErrConflict,
+		},
+		{
+			name:            "success - denied",
+			refillStatus:    model.RefillPending,
+			remaining:       1,
+			data:            model.DecideRefillRequest{Approve: &no, Note: "please book a review first"},
+			callerID:        doctorID,
+			callerRole:      "user",
+			expectStatus:    model.RefillDenied,
+			expectRemaining: 1,
+			expectMail:      "please book a review first",
+		},
+		{
+			name:            "patient cannot decide",
+			refillStatus:    model.RefillPending,
+			remaining:       1,
+			data:            model.DecideRefillRequest{Approve: &yes},
+			callerID:        patientUserID,
+			callerRole:      "user",
+			expectErr:       model.ErrForbidden,
+			expectStatus:    model.RefillPending,
+			expectRemaining: 1,
+		},
+		{
+			name:            "admin who is not a prescriber",
+			refillStatus:    model.RefillPending,
+			remaining:       1,
+			data:            model.DecideRefillRequest{Approve: &yes},
+			callerID:        adminID,
+			callerRole:      "admin",
+			expectErr:       model.ErrForbidden,
+			expectStatus:    model.RefillPending,
+			expectRemaining: 1,
+		},
+		{
+			name:            "decided twice",
+			refillStatus:    model.RefillApproved,
+			remaining:       0,
+			data:            model.DecideRefillRequest{Approve: &no, Note: "too late"},
+			callerID:        doctorID,
+			callerRole:      "user",
+			expectErr:       model.ErrConflict,
+			expectStatus:    model.RefillApproved,
+			expectRemaining: 0,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, prescriptionID, refillID uuid.UUID, approve bool, decidedBy uuid.UUID, note *string) error {
+				return errRepo
+			},
+			refillStatus:    model.RefillPending,
+			remaining:       1,
+			data:            model.DecideRefillRequest{Approve: &yes},
+			callerID:        doctorID,
+			callerRole:      "user",
+			expectErr:       errRepo,
+			expectStatus:    model.RefillPending,
+			expectRemaining: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rx := &model.Prescription{ID: rxID, PatientID: patientID, PrescriberID: practitionerID, PrescriberUserID: doctorID, PatientUserID: &patientUserID, Drug: "paracetamol",
+				Status: model.PrescriptionActive, Refills: 1, RefillsRemaining: tt.remaining}
+			refill := &model.RefillRequest{ID: refillID, PrescriptionID: rxID, Status: tt.refillStatus, RequestedBy: &patientUserID}
+			repo := &mockPrescriptionRepo{
+				prescribers:      map[uuid.UUID]*model.Prescriber{doctorID: &doctor},
+				prescriptions:    map[uuid.UUID]*model.Prescription{rxID: rx},
+				refills:          []*model.RefillRequest{refill},
+				decideRefillFunc: tt.mockFunc,
+			}
+			mail := &mockMailer{}
+			service := NewPrescriptionService(repo, &mockMedicationRepo{}, &mockInteractionRepo{}, &mockAllergyRepo{}, &mockPatientRepo{getByIDFunc: getPatient}, &mockAuditRepo{}, mail)
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			got, err := service.DecideRefill(ctx, rxID, refillID, &tt.data, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got.DecidedBy == nil || *got.DecidedBy != practitionerID {
+					t.Errorf("refill = %+v, want it decided by the doctor", got)
+				}
+			}
+
+			if refill.Status != tt.expectStatus {
+				t.Errorf("refill is %s, want %s", refill.Status, tt.expectStatus)
+			}
+			if rx.RefillsRemaining != tt.expectRemaining {
+				t.Errorf("%d refills remaining, want %d", rx.RefillsRemaining, tt.expectRemaining)
+			}
+			if tt.expectMail == "" {
+				if len(mail.sent) != 0 {
+					t.Errorf("sent %+v, want no mail", mail.sent)
+				}
+				return
+			}
+			if len(mail.sent) != 1 || !strings.Contains(mail.sent[0].Subject+mail.sent[0].Body, tt.expectMail) {
+				t.Errorf("sent %+v, want the patient told %q", mail.sent, tt.expectMail)
+			}
+			if _, err := service.RequestRefill(ctx, rxID, &model.CreateRefillRequest{}, patientUserID, "user"); !errors.Is(err, tt.expectAskAgain) {
+				t.Errorf("asking again: expected %v, got %v", tt.expectAskAgain, err)
+			}
+		})
+	}
+}
+
+func TestPrescriptionService_Conflicts(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+
+	licensedUntil := time.Now().AddDate(1, 0, 0).Format(model.DateLayout)
+	doctor := model.Prescriber{PractitionerID: practitionerID, Profession: "doctor", LicensedUntil: &licensedUntil}
+	amoxicillin := model.Medication{RxCUI: "308191", Name: "amoxicillin 500 MG Oral Capsule", TermType: "SCD"}
+	interactions := []model.Interaction{
+		{SubstanceA: "Warfarin", SubstanceB: "aspirin", Severity: "high", Description: "increased risk of bleeding"},
+		{SubstanceA: "amoxicillin", SubstanceB: "penicillin", Severity: "moderate", Description: "cross-sensitivity between penicillins"},
+	}
+	for i := range interactions {
+		if err := interactions[i].Normalize(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	getPatient := func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+		return &model.Patient{ID: id}, nil
+	}
+	allergy := func(allergen, severity, verification string) model.Allergy {
+		return model.Allergy{Allergen: allergen, Severity: severity, Verification: verification}
+	}
+	anaphylaxis := "anaphylaxis"
+
+	tests := []struct {
+		name            string
+		mockFunc        func(ctx context.Context, patientID uuid.UUID) ([]model.Prescription, error)
+		current         []string
+		allergies       []model.Allergy
+		rxcui           string
+		drug            string
+		create          bool
+		overrideReason  string
+		callerID        uuid.UUID
+		expectErr       error
+		expectField     string
+		expectConflicts []model.Conflict
+		expectDetail    string
+	}{
+		{
+			name:            "no conflicts",
+			drug:            "warfarin 5 MG Oral Tablet",
+			callerID:        doctorID,
+			expectConflicts: []model.Conflict{},
+		},
+		{
+			name:            "interaction with a current prescription",
+			current:         []string{"warfarin 5 MG Oral Tablet"},
+			drug:            "aspirin 75 MG Oral Tablet",
+			callerID:        doctorID,
+			expectConflicts: []model.Conflict{{Type: model.ConflictDrugDrug, Severity: model.SeverityHigh, With: "warfarin 5 MG Oral Tablet"}},
+		},
+		{
+			name:      "patient cannot check",
+			current:   []string{"warfarin 5 MG Oral Tablet"},
+			drug:      "aspirin 75 MG Oral Tablet",
+			callerID:  patientUserID,
+			expectErr: model.ErrForbidden,
+		},
+		{
+			name:         "high severity needs an override",
+			current:      []string{"warfarin 5 MG Oral Tablet"},
+			drug:         "aspirin 75 MG Oral Tablet",
+			create:       true,
+			callerID:     doctorID,
+			expectField:  "override_reason",
+			expectDetail: "warfarin",
+		},
+		{
+			name:            "high severity overridden",
+			current:         []string{"warfarin 5 MG Oral Tablet"},
+			drug:            "aspirin 75 MG Oral Tablet",
+			create:          true,
+			overrideReason:  "INR monitored weekly by the anticoagulation clinic",
+			callerID:        doctorID,
+			expectConflicts: []model.Conflict{{Type: model.ConflictDrugDrug, Severity: model.SeverityHigh, With: "warfarin 5 MG Oral Tablet"}},
+		},
+		{
+			name:            "cross-sensitive allergy",
+			allergies:       []model.Allergy{allergy("penicillin", model.AllergyMild, model.AllergyConfirmed)},
+			rxcui:           "308191",
+			create:          true,
+			callerID:        doctorID,
+			expectConflicts: []model.Conflict{{Type: model.ConflictDrugAllergy, Severity: model.SeverityModerate, With: "penicillin"}},
+		},
+		{
+			name:            "refuted allergy ignored",
+			allergies:       []model.Allergy{allergy("aspirin", model.AllergySevere, model.AllergyRefuted)},
+			drug:            "aspirin 75 MG Oral Tablet",
+			callerID:        doctorID,
+			expectConflicts: []model.Conflict{},
+		},
+		{
+			name: "severe allergy to the drug first",
+			allergies: []model.Allergy{
+				allergy("penicillin", model.AllergyMild, model.AllergyConfirmed),
+				{Allergen: "Amoxicillin", Reaction: &anaphylaxis, Severity: model.AllergySevere, Verification: model.AllergyUnconfirmed},
+			},
+			rxcui:    "308191",
+			callerID: doctorID,
+			expectConflicts: []model.Conflict{
+				{Type: model.ConflictDrugAllergy, Severity: model.SeverityHigh, With: "Amoxicillin"},
+				{Type: model.ConflictDrugAllergy, Severity: model.SeverityModerate, With: "penicillin"},
+			},
+			expectDetail: "anaphylaxis",
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, patientID uuid.UUID) ([]model.Prescription, error) {
+				return nil, errRepo
+			},
+			drug:      "aspirin 75 MG Oral Tablet",
+			callerID:  doctorID,
+			expectErr: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockPrescriptionRepo{
+				prescribers:     map[uuid.UUID]*model.Prescriber{doctorID: &doctor},
+				prescriptions:   map[uuid.UUID]*model.Prescription{},
+				patientUsers:    map[uuid.UUID]uuid.UUID{patientID: patientUserID},
+				listCurrentFunc: tt.mockFunc,
+			}
+			for _, drug := range tt.current {
+				id, _ := uuid.NewV7()
+				repo.prescriptions[id] = &model.Prescription{ID: id, PatientID: patientID, PrescriberID: practitionerID, PrescriberUserID: doctorID, Drug: drug, Status: model.PrescriptionActive}
+			}
+			allergies := &mockAllergyRepo{}
+			for _, a := range tt.allergies {
+				a.ID, _ = uuid.NewV7()
+				a.PatientID = patientID
+				allergies.allergies = append(allergies.allergies, &a)
+			}
+			medications := &mockMedicationRepo{catalogue: map[string]model.Medication{amoxicillin.RxCUI: amoxicillin}}
+			audit := &mockAuditRepo{}
+			service := NewPrescriptionService(repo, medications, &mockInteractionRepo{interactions: interactions}, allergies, &mockPatientRepo{getByIDFunc: getPatient}, audit, &mockMailer{})
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			var conflicts []model.Conflict
+			var err error
+			if tt.create {
+				var rx *model.Prescription
+				data := &model.CreatePrescription{PatientID: patientID, RxCUI: tt.rxcui, Drug: tt.drug, Dose: "1 tablet", Route: "oral", Frequency: "daily", Quantity: 28, OverrideReason: tt.overrideReason}
+				rx, err = service.Create(ctx, data, tt.callerID, "user")
+				if err == nil {
+					conflicts = rx.Conflicts
+				}
+			} else {
+				conflicts, err = service.Check(ctx, &model.CheckPrescription{PatientID: patientID, RxCUI: tt.rxcui, Drug: tt.drug}, tt.callerID, "user")
+			}
+
+			if tt.expectField != "" {
+				var verrs model.ValidationErrors
+				if !errors.As(err, &verrs) || verrs[0].Field != tt.expectField || !strings.Contains(verrs[0].Message, tt.expectDetail) {
+					t.Errorf("expected a validation error on %s naming %s, got %v", tt.expectField, tt.expectDetail, err)
+				}
+				return
+			}
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(conflicts) != len(tt.expectConflicts) {
+				t.Fatalf("conflicts = %+v, want %+v", conflicts, tt.expectConflicts)
+			}
+			for i, want := range tt.expectConflicts {
+				if c := conflicts[i]; c.Type != want.Type || c.Severity != want.Severity || c.With != want.With ||
+					(c.PrescriptionID == nil) == (c.AllergyID == nil) {
+					t.Errorf("conflict %d = %+v, want %+v", i, c, want)
+				}
+			}
+			if tt.expectDetail != "" && !strings.Contains(conflicts[0].Description, tt.expectDetail) {
+				t.Errorf("conflict %+v, want it to mention %s", conflicts[0], tt.expectDetail)
+			}
+
+			var overridden *model.AuditEntry
+			for i, e := range audit.entries {
+				if e.Action == model.AuditPrescriptionOverridden {
+					overridden = &audit.entries[i]
+				}
+			}
+			if tt.overrideReason != "" && (overridden == nil || overridden.Details["reason"] != tt.overrideReason) {
+				t.Errorf("expected the override to be audited with its reason, got %+v", audit.entries)
+			}
+			if tt.overrideReason == "" && overridden != nil {
+				t.Errorf("audited an override, %+v", overridden)
+			}
+		})
+	}
+}
+
+func TestPrescriptionService_Conflicts_Coded(t *testing.T) {
+	orgID, _ := uuid.NewV7()
+	doctorID, _ := uuid.NewV7()
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	rxID, _ := uuid.NewV7()
+	allergyID, _ := uuid.NewV7()
+
+	licensedUntil := time.Now().AddDate(1, 0, 0).Format(model.DateLayout)
+	doctor := model.Prescriber{PractitionerID: practitionerID, Profession: "doctor", LicensedUntil: &licensedUntil}
+	brand, methotrexate := "617296", "105585"
+	catalogue := map[string]model.Medication{
+		"308191":     {RxCUI: "308191", Name: "amoxicillin 500 MG Oral Capsule", TermType: "SCD"},
+		brand:        {RxCUI: brand, Name: "amoxicillin 500 MG / clavulanate 125 MG Oral Tablet [Augmentin]", TermType: "SBD"},
+		methotrexate: {RxCUI: methotrexate, Name: "methotrexate 2.5 MG Oral Tablet [Trexall]", TermType: "SBD"},
+	}
+	getPatient := func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+		return &model.Patient{ID: id}, nil
+	}
+	in := model.Interaction{SubstanceA: "amoxicillin", SubstanceB: "methotrexate", Severity: "moderate", Description: "reduced methotrexate clearance"}
+	if err := in.Normalize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		check      model.CheckPrescription
+		current    *model.Prescription
+		allergy    *model.Allergy
+		expectType string
+	}{
+		{
+			name:       "brand name matched by its ingredients",
+			check:      model.CheckPrescription{PatientID: patientID, RxCUI: brand, Drug: "Augmentin 625"},
+			current:    &model.Prescription{ID: rxID, PatientID: patientID, Drug: "methotrexate 2.5 MG Oral Tablet", Status: model.PrescriptionActive},
+			expectType: model.ConflictDrugDrug,
+		},
+		{
+			name:       "current prescription matched by its ingredients",
+			check:      model.CheckPrescription{PatientID: patientID, Drug: "amoxicillin 500 MG Oral Capsule"},
+			current:    &model.Prescription{ID: rxID, PatientID: patientID, RxCUI: &methotrexate, Drug: "Trexall", Status: model.PrescriptionActive},
+			expectType: model.ConflictDrugDrug,
+		},
+		{
+			name:       "allergy matched by RxCUI",
+			check:      model.CheckPrescription{PatientID: patientID, RxCUI: brand, Drug: "Co-amoxiclav"},
+			allergy:    &model.Allergy{ID: allergyID, PatientID: patientID, RxCUI: &brand, Allergen: "Augmentin", Severity: model.AllergyModerate, Verification: model.AllergyConfirmed},
+			expectType: model.ConflictDrugAllergy,
+		},
+		{
+			name:       "allergy matched by the drug's ingredients",
+			check:      model.CheckPrescription{PatientID: patientID, RxCUI: brand, Drug: "Augmentin 625"},
+			allergy:    &model.Allergy{ID: allergyID, PatientID: patientID, Allergen: "clavulanate", Severity: model.AllergyModerate, Verification: model.AllergyConfirmed},
+			expectType: model.ConflictDrugAllergy,
+		},
+		{
+			name:    "uncoded names only match by name",
+			check:   model.CheckPrescription{PatientID: patientID, Drug: "Augmentin 625"},
+			allergy: &model.Allergy{ID: allergyID, PatientID: patientID, Allergen: "amoxicillin", Severity: model.AllergyModerate, Verification: model.AllergyConfirmed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockPrescriptionRepo{
+				prescribers:   map[uuid.UUID]*model.Prescriber{doctorID: &doctor},
+				prescriptions: map[uuid.UUID]*model.Prescription{},
+			}
+			if tt.current != nil {
+				repo.prescriptions[tt.current.ID] = tt.current
+			}
+			allergies := &mockAllergyRepo{}
+			if tt.allergy != nil {
+				allergies.allergies = []*model.Allergy{tt.allergy}
+			}
+			service := NewPrescriptionService(repo, &mockMedicationRepo{catalogue: catalogue}, &mockInteractionRepo{interactions: []model.Interaction{in}}, allergies, &mockPatientRepo{getByIDFunc: getPatient}, &mockAuditRepo{}, &mockMailer{})
+
+			ctx := repository.WithTenant(context.Background(), orgID)
+			conflicts, err := service.Check(ctx, &tt.check, doctorID, "user")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectType == "" {
+				if len(conflicts) != 0 {
+					t.Errorf("conflicts = %+v, want none", conflicts)
+				}
+				return
+			}
+			if len(conflicts) != 1 || conflicts[0].Type != tt.expectType {
+				t.Errorf("conflicts = %+v, want one %s conflict", conflicts, tt.expectType)
+			}
+		})
+	}
+}
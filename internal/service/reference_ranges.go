@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+// ReferenceRanges holds the configured reference ranges of lab tests by
+// code.
+type ReferenceRanges struct {
+	byCode map[string][]model.ReferenceRange
+}
+
+// NewReferenceRanges validates ranges and indexes them by test code.
+func NewReferenceRanges(ranges []model.ReferenceRange) (*ReferenceRanges, error) {
+	r := &ReferenceRanges{byCode: map[string][]model.ReferenceRange{}}
+	for i := range ranges {
+		if err := ranges[i].Validate(); err != nil {
+			return nil, fmt.Errorf("reference range %d: %w", i+1, describeValidation(err))
+		}
+		r.byCode[ranges[i].Code] = append(r.byCode[ranges[i].Code], ranges[i])
+	}
+	return r, nil
+}
+
+// LoadReferenceRanges reads reference ranges from a JSON file holding an
+// array of ranges. An empty path configures none.
+func LoadReferenceRanges(path string) (*ReferenceRanges, error) {
+	if path == "" {
+		return NewReferenceRanges(nil)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reference ranges: %w", err)
+	}
+	defer f.Close()
+
+	var ranges []model.ReferenceRange
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ranges); err != nil {
+		return nil, fmt.Errorf("failed to read reference ranges from %s: %w", path, err)
+	}
+	return NewReferenceRanges(ranges)
+}
+
+// describeValidation spells out the fields of a validation error, which
+// otherwise reads only "validation failed".
+func describeValidation(err error) error {
+	var verrs model.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	msgs := make([]string, len(verrs))
+	for i, e := range verrs {
+		msgs[i] = e.Message
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// Interpret compares a numeric result with the range for its code that
+// applies to a patient of sex born on dob (a date, or empty if unknown),
+// preferring a range for the patient's sex over one for any sex. It
+// returns nil when the result is not a number or no range applies.
+func (r *ReferenceRanges) Interpret(o *model.LabObservation, sex, dob string, now time.Time) *string {
+	if r == nil || o.ValueType != "NM" || o.Value == nil {
+		return nil
+	}
+	v, err := strconv.ParseFloat(*o.Value, 64)
+	if err != nil {
+		return nil
+	}
+
+	var born *time.Time
+	if t, err := time.Parse(model.DateLayout, dob); err == nil {
+		born = &t
+	}
+	at := now
+	if o.ObservedAt != nil {
+		at = *o.ObservedAt
+	}
+
+	var best *model.ReferenceRange
+	for i := range r.byCode[o.Code] {
+		c := &r.byCode[o.Code][i]
+		if c.System != "" && (o.CodeSystem == nil || !strings.EqualFold(c.System, *o.CodeSystem)) {
+			continue
+		}
+		if c.Units != "" && o.Units != nil && !strings.EqualFold(c.Units, *o.Units) {
+			continue
+		}
+		if !c.Applies(sex, born, at) {
+			continue
+		}
+		if best == nil || (best.Sex == "" && c.Sex != "") {
+			best = c
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	interpretation := best.Interpret(v)
+	return &interpretation
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+const referenceRangesJSON = `[
+	{"code": "718-7", "system": "LN", "units": "g/dL", "sex": "female", "min_age": "18y", "low": 12.0, "high": 15.5, "critical_low": 7.0},
+	{"code": "718-7", "system": "LN", "units": "g/dL", "sex": "male", "min_age": "18y", "low": 13.5, "high": 17.5, "critical_low": 7.0},
+	{"code": "718-7", "system": "LN", "units": "g/dL", "max_age": "28d", "low": 14.0, "high": 24.0},
+	{"code": "2823-3", "units": "mmol/L", "low": 3.5, "high": 5.1, "critical_low": 2.5, "critical_high": 6.5},
+	{"code": "2823-3", "units": "mmol/L", "sex": "female", "low": 3.4, "high": 5.0}
+]`
+
+func writeRanges(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ranges.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write ranges: %v", err)
+	}
+	return path
+}
+
+func TestLoadReferenceRanges(t *testing.T) {
+	ranges, err := LoadReferenceRanges(writeRanges(t, referenceRangesJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(ranges.byCode["718-7"]); n != 3 {
+		t.Errorf("loaded %d haemoglobin ranges, want 3", n)
+	}
+
+	if ranges, err := LoadReferenceRanges(""); err != nil || len(ranges.byCode) != 0 {
+		t.Errorf("no file: got %+v, %v, want no ranges", ranges, err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not json", `{`, "failed to read"},
+		{"unknown field", `[{"code": "718-7", "lo": 12}]`, "failed to read"},
+		{"invalid range", `[{"code": "718-7", "low": 12}, {"code": "718-7", "low": 15, "high": 12}]`, "reference range 2: high must not be below low"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadReferenceRanges(writeRanges(t, tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceRanges_Interpret(t *testing.T) {
+	ranges, err := LoadReferenceRanges(writeRanges(t, referenceRangesJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	observedAt := time.Date(2026, time.October, 18, 7, 15, 0, 0, time.UTC)
+	result := func(code, value, units string) *model.LabObservation {
+		system := "LN"
+		return &model.LabObservation{Code: code, CodeSystem: &system, ValueType: "NM", Value: &value, Units: &units, ObservedAt: &observedAt}
+	}
+
+	tests := []struct {
+		name string
+		o    *model.LabObservation
+		sex  string
+		dob  string
+		want string
+	}{
+		{"adult woman", result("718-7", "13.0", "g/dL"), "female", "1980-02-14", model.InterpretationNormal},
+		{"adult man", result("718-7", "13.0", "g/dL"), "male", "1980-02-14", model.InterpretationLow},
+		{"critical", result("718-7", "6.1", "g/dL"), "male", "1980-02-14", model.InterpretationCritical},
+		{"newborn", result("718-7", "13.0", "g/dL"), "male", "2026-10-01", model.InterpretationLow},
+		{"sex specific range preferred", result("2823-3", "5.05", "mmol/L"), "female", "1980-02-14", model.InterpretationHigh},
+		{"range for any sex", result("2823-3", "5.05", "mmol/L"), "male", "1980-02-14", model.InterpretationNormal},
+		{"range for any age without a date of birth", result("2823-3", "7.0", "mmol/L"), "male", "", model.InterpretationCritical},
+		{"units differ", result("2823-3", "7.0", "mEq/L"), "male", "1980-02-14", ""},
+		{"no range between newborn and adult", result("718-7", "13.0", "g/dL"), "male", "2016-01-01", ""},
+		{"no range for the code", result("2093-3", "212", "mg/dL"), "female", "1980-02-14", ""},
+		{"not a number", result("2823-3", ">10", "mmol/L"), "male", "1980-02-14", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ranges.Interpret(tt.o, tt.sex, tt.dob, time.Now())
+			if (got == nil) != (tt.want == "") || (got != nil && *got != tt.want) {
+				t.Errorf("Interpret() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+
+	structured := result("2823-3", "7.0", "mmol/L")
+	structured.ValueType = "SN"
+	if got := ranges.Interpret(structured, "male", "1980-02-14", time.Now()); got != nil {
+		t.Errorf("Interpret() = %v for a structured numeric, want nil", *got)
+	}
+}
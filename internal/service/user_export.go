@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+// ExportUsers writes every user matching filter to w as CSV with a header
+// row or as newline-delimited JSON objects. Rows are encoded as they are read
+// from the database, so memory use does not grow with the number of users.
+// Nothing is written if the caller may not export.
+func (s *UserService) ExportUsers(ctx context.Context, w io.Writer, format string, fields []string, filter model.UserFilter, callerRole string) error {
+	if !isAdmin(callerRole) {
+		return model.ErrForbidden
+	}
+
+	buf := bufio.NewWriterSize(w, 64<<10)
+
+	var cw *csv.Writer
+	var writeRow func(values []any) error
+	switch format {
+	case model.UserExportCSV:
+		cw = csv.NewWriter(buf)
+		if err := cw.Write(fields); err != nil {
+			return err
+		}
+		record := make([]string, len(fields))
+		writeRow = func(values []any) error {
+			for i, v := range values {
+				record[i] = exportText(fields[i], v)
+			}
+			return cw.Write(record)
+		}
+	case model.UserExportNDJSON:
+		writeRow = func(values []any) error {
+			return writeJSONObject(buf, fields, values)
+		}
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+
+	if err := s.repo.StreamUsers(ctx, filter, fields, writeRow); err != nil {
+		return err
+	}
+
+	if cw != nil {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// exportText formats a value of field for CSV. Timestamps are written in UTC
+// as RFC 3339. Text that a spreadsheet would read as a formula is prefixed
+// with a single quote, so opening an export cannot run anything a user typed
+// into their profile. Valid phone numbers are left as they are: E.164 numbers
+// start with a plus but hold nothing but digits.
+func exportText(field string, v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if field == "phone" && model.ValidPhone(v) {
+			return v
+		}
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeJSONObject writes fields and values as one JSON object on its own
+// line, keeping the field order.
+func writeJSONObject(w *bufio.Writer, fields []string, values []any) error {
+	w.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		key, _ := json.Marshal(f)
+		w.Write(key)
+		w.WriteByte(':')
+
+		v := values[i]
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC().Format(time.RFC3339)
+		}
+		val, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		w.Write(val)
+	}
+	w.WriteByte('}')
+	_, err := w.Write([]byte{'\n'})
+	return err
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+func TestUserService_ExportUsers(t *testing.T) {
+	created := time.Date(2026, 10, 1, 9, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	rows := [][]any{
+		{"0192f5b4-0000-7000-8000-000000000001", "Jane", "O'Neil, MD", created, nil},
+		{"0192f5b4-0000-7000-8000-000000000002", "John", "Doe \"JD\"", created, "+4915112345678"},
+		{"0192f5b4-0000-7000-8000-000000000003", "=HYPERLINK(\"http://evil.test\")", "@SUM(A1)", created, "-1"},
+	}
+	fields := []string{"id", "first_name", "last_name", "created_at", "phone"}
+
+	tests := []struct {
+		name       string
+		format     string
+		callerRole string
+		streamErr  error
+		expected   string
+		expectErr  error
+	}{
+		{
+			name:       "csv",
+			format:     model.UserExportCSV,
+			callerRole: "admin",
+			expected: "id,first_name,last_name,created_at,phone\n" +
+				"0192f5b4-0000-7000-8000-000000000001,Jane,\"O'Neil, MD\",2026-10-01T07:30:00Z,\n" +
+				"0192f5b4-0000-7000-8000-000000000002,John,\"Doe \"\"JD\"\"\",2026-10-01T07:30:00Z,+4915112345678\n" +
+				"0192f5b4-0000-7000-8000-000000000003,\"'=HYPERLINK(\"\"http://evil.test\"\")\",'@SUM(A1),2026-10-01T07:30:00Z,'-1\n",
+		},
+		{
+			name:       "ndjson",
+			format:     model.UserExportNDJSON,
+			callerRole: "super_admin",
+			expected: `{"id":"0192f5b4-0000-7000-8000-000000000001","first_name":"Jane","last_name":"O'Neil, MD","created_at":"2026-10-01T07:30:00Z","phone":null}` + "\n" +
+				`{"id":"0192f5b4-0000-7000-8000-000000000002","first_name":"John","last_name":"Doe \"JD\"","created_at":"2026-10-01T07:30:00Z","phone":"+4915112345678"}` + "\n" +
+				`{"id":"0192f5b4-0000-7000-8000-000000000003","first_name":"=HYPERLINK(\"http://evil.test\")","last_name":"@SUM(A1)","created_at":"2026-10-01T07:30:00Z","phone":"-1"}` + "\n",
+		},
+		{
+			name:       "forbidden writes nothing",
+			format:     model.UserExportCSV,
+			callerRole: "user",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name:       "stream error",
+			format:     model.UserExportNDJSON,
+			callerRole: "admin",
+			streamErr:  errRepo,
+			expectErr:  errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockUserRepo{streamRows: rows, streamErr: tt.streamErr}
+			service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
+
+			var out bytes.Buffer
+			err := service.ExportUsers(context.Background(), &out, tt.format, fields, model.UserFilter{}, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if tt.expectErr == model.ErrForbidden && out.Len() != 0 {
+					t.Errorf("expected no output, got %q", out.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if out.String() != tt.expected {
+				t.Errorf("unexpected output:\n%s\nexpected:\n%s", out.String(), tt.expected)
+			}
+		})
+	}
+}
@@ -4,19 +4,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"time"
 
 	"github.com/PranavJoshi2893/med-portal/internal/model"
 	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/blob"
 	"github.com/google/uuid"
 )
 
 type UserService struct {
-	repo repository.UserRepository
+	repo           repository.UserRepository
+	audit          repository.AuditRepository
+	avatars        blob.Store
+	avatarMaxBytes int64
 }
 
-func NewUserService(repo repository.UserRepository) *UserService {
+func NewUserService(repo repository.UserRepository, audit repository.AuditRepository, avatars blob.Store, avatarMaxBytes int64) *UserService {
 	return &UserService{
-		repo: repo,
+		repo:           repo,
+		audit:          audit,
+		avatars:        avatars,
+		avatarMaxBytes: avatarMaxBytes,
 	}
 }
 
@@ -24,13 +34,13 @@ func isAdmin(role string) bool {
 	return role == "admin" || role == "super_admin"
 }
 
-func (s *UserService) GetAll(ctx context.Context, callerID uuid.UUID, callerRole string, params model.PaginationParams) (*model.PaginatedUsersResponse, error) {
+func (s *UserService) GetAll(ctx context.Context, callerID uuid.UUID, callerRole string, filter model.UserFilter, params model.PaginationParams) (*model.PaginatedUsersResponse, error) {
 	if isAdmin(callerRole) {
-		users, err := s.repo.GetAll(ctx, params.Limit, (params.Page-1)*params.Limit)
+		users, err := s.repo.GetAll(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
 		if err != nil {
 			return nil, err
 		}
-		total, err := s.repo.GetCount(ctx)
+		total, err := s.repo.GetCount(ctx, filter)
 		if err != nil {
 			return nil, err
 		}
@@ -71,15 +81,86 @@ func (s *UserService) GetAll(ctx context.Context, callerID uuid.UUID, callerRole
 	}, nil
 }
 
-func (s *UserService) DeleteByID(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) error {
+// GetAllByCursor lists users with keyset pagination in id order. Rows are
+// fetched one past the limit to tell whether another page exists in the
+// direction of travel.
+func (s *UserService) GetAllByCursor(ctx context.Context, callerID uuid.UUID, callerRole string, filter model.UserFilter, params model.CursorParams) (*model.CursorUsersResponse, error) {
+	if !isAdmin(callerRole) {
+		user, err := s.repo.GetByID(ctx, callerID)
+		if err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return nil, fmt.Errorf("user %w", err)
+			}
+			return nil, err
+		}
+		return &model.CursorUsersResponse{
+			Items: []model.GetAll{{
+				ID:        user.ID,
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+				Email:     user.Email,
+			}},
+			Meta: model.CursorMeta{Limit: params.Limit},
+		}, nil
+	}
+
+	users, err := s.repo.GetPage(ctx, filter, params.Cursor, params.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(users) > params.Limit
+	if hasMore {
+		users = users[:params.Limit]
+	}
+
+	backward := params.Cursor != nil && params.Cursor.Before
+	if backward {
+		slices.Reverse(users)
+	}
+
+	if users == nil {
+		users = []model.GetAll{}
+	}
+
+	meta := model.CursorMeta{Limit: params.Limit}
+	if len(users) > 0 {
+		first, last := users[0].ID, users[len(users)-1].ID
+		if hasMore || backward {
+			meta.NextCursor = model.Cursor{ID: last}.Encode()
+		}
+		if (hasMore && backward) || (!backward && params.Cursor != nil) {
+			meta.PrevCursor = model.Cursor{ID: first, Before: true}.Encode()
+		}
+	}
+	if len(users) == 0 && params.Cursor != nil {
+		// Past either end the cursor's own id is on the adjoining page.
+		if backward {
+			meta.NextCursor = model.Cursor{ID: params.Cursor.ID, Inclusive: true}.Encode()
+		} else {
+			meta.PrevCursor = model.Cursor{ID: params.Cursor.ID, Before: true, Inclusive: true}.Encode()
+		}
+	}
+
+	return &model.CursorUsersResponse{Items: users, Meta: meta}, nil
+}
+
+// DeleteByID soft-deletes a user if cond matches the current version.
+func (s *UserService) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition, callerID uuid.UUID, callerRole string) error {
 	if id != callerID && !isAdmin(callerRole) {
 		return model.ErrForbidden
 	}
-	err := s.repo.DeleteByID(ctx, id)
+	err := s.repo.DeleteByID(ctx, id, cond)
 	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("user %w", err)
+		}
 		if errors.Is(err, model.ErrAlreadyDeleted) {
 			return fmt.Errorf("user %w", err)
 		}
+		if errors.Is(err, model.ErrPreconditionFailed) {
+			return fmt.Errorf("user has been modified: %w", err)
+		}
 		return err
 	}
 	return nil
@@ -96,22 +177,183 @@ func (s *UserService) GetByID(ctx context.Context, id uuid.UUID, callerID uuid.U
 		}
 		return nil, err
 	}
+	user.AvatarURL = avatarURL(user)
 	return user, nil
 }
 
-func (s *UserService) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdateUser, callerID uuid.UUID, callerRole string) error {
+// UpdateByID applies a merge patch to a user if cond matches the current
+// version, and returns the new version. An empty patch changes nothing and
+// returns the current version. An address patch is merged with the stored
+// address before checking that the result is complete.
+func (s *UserService) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition, callerID uuid.UUID, callerRole string) (int64, error) {
 	if id != callerID && !isAdmin(callerRole) {
+		return 0, model.ErrForbidden
+	}
+
+	if data.IsEmpty() || data.Address.HasValue() {
+		current, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return 0, fmt.Errorf("user %w", err)
+			}
+			return 0, err
+		}
+
+		if !cond.Matches(current.Version) {
+			return 0, fmt.Errorf("user has been modified: %w", model.ErrPreconditionFailed)
+		}
+
+		if data.IsEmpty() {
+			return current.Version, nil
+		}
+
+		if err := data.Address.Value.Apply(current.Address).Validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	version, err := s.repo.UpdateByID(ctx, id, data, cond)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return 0, fmt.Errorf("user %w", err)
+		}
+		if errors.Is(err, model.ErrAlreadyDeleted) {
+			return 0, fmt.Errorf("user %w", err)
+		}
+		if errors.Is(err, model.ErrPreconditionFailed) {
+			return 0, fmt.Errorf("user has been modified: %w", err)
+		}
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *UserService) GetDeleted(ctx context.Context, callerRole string, filter model.UserFilter, params model.PaginationParams) (*model.PaginatedDeletedUsersResponse, error) {
+	if !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	filter.Deleted = model.DeletedOnly
+	if len(filter.Sort) == 0 {
+		filter.Sort = []model.SortField{{Field: "deleted_at", Desc: true}}
+	}
+
+	users, err := s.repo.GetDeleted(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []model.DeletedUser{}
+	}
+
+	total, err := s.repo.GetCount(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	totalPages := total / params.Limit
+	if total%params.Limit > 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedDeletedUsersResponse{
+		Items: users,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+func (s *UserService) RestoreByID(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	if !isAdmin(callerRole) {
 		return model.ErrForbidden
 	}
-	err := s.repo.UpdateByID(ctx, id, data)
+	err := s.repo.RestoreByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, model.ErrNotFound) {
 			return fmt.Errorf("user %w", err)
 		}
 		if errors.Is(err, model.ErrAlreadyDeleted) {
-			return fmt.Errorf("user %w", err)
+			return fmt.Errorf("user has been purged: %w", err)
+		}
+		if errors.Is(err, model.ErrConflict) {
+			return fmt.Errorf("user is not deleted: %w", err)
 		}
 		return err
 	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditUserRestore, "user", id, nil)
 	return nil
 }
+
+// PurgeDeleted purges accounts that have been soft-deleted for longer than
+// retention, in batches of batchSize, and returns how many were purged.
+func (s *UserService) PurgeDeleted(ctx context.Context, retention time.Duration, mode string, batchSize int) (int, error) {
+	cutoff := time.Now().Add(-retention)
+	purged := 0
+
+	for {
+		users, err := s.repo.PurgeDeleted(ctx, cutoff, mode, batchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, u := range users {
+			if err := s.avatars.Delete(ctx, model.AvatarKey(u.ID)); err != nil {
+				log.Printf("purge user %s: delete avatar: %v", u.ID, err)
+			}
+			recordAudit(ctx, s.audit, nil, model.AuditUserPurge, "user", u.ID, map[string]any{"mode": u.Mode})
+		}
+		purged += len(users)
+
+		if len(users) < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// GetStatus returns the account status of a user. Users may read their own.
+func (s *UserService) GetStatus(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.AccountStatus, error) {
+	if id != callerID && !isAdmin(callerRole) {
+		return nil, model.ErrForbidden
+	}
+
+	status, err := s.repo.GetStatus(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("user %w", err)
+		}
+		return nil, err
+	}
+	return status, nil
+}
+
+// SetStatus changes the account status of a user. Only admins may do so, and
+// not for their own account, so an admin cannot lock themselves out or lift
+// their own suspension.
+func (s *UserService) SetStatus(ctx context.Context, id uuid.UUID, change model.ChangeStatus, callerID uuid.UUID, callerRole string) (*model.AccountStatus, error) {
+	if !isAdmin(callerRole) || id == callerID {
+		return nil, model.ErrForbidden
+	}
+
+	previous, err := s.repo.SetStatus(ctx, id, change, callerID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) || errors.Is(err, model.ErrAlreadyDeleted) {
+			return nil, fmt.Errorf("user %w", err)
+		}
+		return nil, err
+	}
+
+	details := map[string]any{"from": previous, "to": change.Status}
+	if change.Reason != "" {
+		details["reason"] = change.Reason
+	}
+	if change.Until != nil {
+		details["until"] = change.Until.UTC()
+	}
+	recordAudit(ctx, s.audit, &callerID, model.AuditUserStatus, "user", id, details)
+
+	return s.repo.GetStatus(ctx, id)
+}
@@ -1,41 +1,61 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"reflect"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/pkg/blob"
 	"github.com/google/uuid"
 )
 
 type mockUserRepo struct {
-	getAllFunc     func(ctx context.Context, limit, offset int) ([]model.GetAll, error)
-	getCountFunc   func(ctx context.Context) (int, error)
-	deleteByIDFunc func(ctx context.Context, id uuid.UUID) error
+	getAllFunc     func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error)
+	getCountFunc   func(ctx context.Context, filter model.UserFilter) (int, error)
+	getPageFunc    func(ctx context.Context, filter model.UserFilter, cursor *model.Cursor, limit int) ([]model.GetAll, error)
+	deleteByIDFunc func(ctx context.Context, id uuid.UUID, cond model.Precondition) error
 	getByIDFunc    func(ctx context.Context, id uuid.UUID) (*model.GetByID, error)
-	updateByIDFunc func(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error
+	updateByIDFunc func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error)
+	getDeletedFunc func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.DeletedUser, error)
+	restoreFunc    func(ctx context.Context, id uuid.UUID) error
+	purgeFunc      func(ctx context.Context, cutoff time.Time, mode string, limit int) ([]model.PurgedUser, error)
+	setStatusFunc  func(ctx context.Context, id uuid.UUID, change model.ChangeStatus, actorID uuid.UUID) (string, error)
+	streamRows     [][]any
+	streamErr      error
+	avatar         *model.Avatar
+	status         *model.AccountStatus
 }
 
-func (m *mockUserRepo) GetAll(ctx context.Context, limit, offset int) ([]model.GetAll, error) {
+func (m *mockUserRepo) GetAll(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error) {
 	if m.getAllFunc != nil {
-		return m.getAllFunc(ctx, limit, offset)
+		return m.getAllFunc(ctx, filter, limit, offset)
 	}
 	return nil, nil
 }
 
-func (m *mockUserRepo) GetCount(ctx context.Context) (int, error) {
+func (m *mockUserRepo) GetCount(ctx context.Context, filter model.UserFilter) (int, error) {
 	if m.getCountFunc != nil {
-		return m.getCountFunc(ctx)
+		return m.getCountFunc(ctx, filter)
 	}
 	return 0, nil
 }
 
-func (m *mockUserRepo) DeleteByID(ctx context.Context, id uuid.UUID) error {
+func (m *mockUserRepo) GetPage(ctx context.Context, filter model.UserFilter, cursor *model.Cursor, limit int) ([]model.GetAll, error) {
+	if m.getPageFunc != nil {
+		return m.getPageFunc(ctx, filter, cursor, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepo) DeleteByID(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
 	if m.deleteByIDFunc != nil {
-		return m.deleteByIDFunc(ctx, id)
+		return m.deleteByIDFunc(ctx, id, cond)
 	}
 	return nil
 }
@@ -47,14 +67,129 @@ func (m *mockUserRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.GetByI
 	return nil, nil
 }
 
-func (m *mockUserRepo) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error {
+func (m *mockUserRepo) UpdateByID(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
 	if m.updateByIDFunc != nil {
-		return m.updateByIDFunc(ctx, id, data)
+		return m.updateByIDFunc(ctx, id, data, cond)
+	}
+	return 0, nil
+}
+
+func (m *mockUserRepo) GetDeleted(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.DeletedUser, error) {
+	if m.getDeletedFunc != nil {
+		return m.getDeletedFunc(ctx, filter, limit, offset)
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepo) RestoreByID(ctx context.Context, id uuid.UUID) error {
+	if m.restoreFunc != nil {
+		return m.restoreFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockUserRepo) PurgeDeleted(ctx context.Context, cutoff time.Time, mode string, limit int) ([]model.PurgedUser, error) {
+	if m.purgeFunc != nil {
+		return m.purgeFunc(ctx, cutoff, mode, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepo) SetAvatar(ctx context.Context, id uuid.UUID, contentType string) error {
+	m.avatar = &model.Avatar{ContentType: contentType, UpdatedAt: time.Now()}
+	return nil
+}
+
+func (m *mockUserRepo) GetAvatar(ctx context.Context, id uuid.UUID) (*model.Avatar, error) {
+	if m.avatar == nil {
+		return nil, model.ErrNotFound
+	}
+	return m.avatar, nil
+}
+
+func (m *mockUserRepo) ClearAvatar(ctx context.Context, id uuid.UUID) error {
+	if m.avatar == nil {
+		return model.ErrNotFound
+	}
+	m.avatar = nil
+	return nil
+}
+
+func (m *mockUserRepo) GetStatus(ctx context.Context, id uuid.UUID) (*model.AccountStatus, error) {
+	if m.status == nil {
+		return &model.AccountStatus{Status: model.StatusActive}, nil
+	}
+	return m.status, nil
+}
+
+func (m *mockUserRepo) SetStatus(ctx context.Context, id uuid.UUID, change model.ChangeStatus, actorID uuid.UUID) (string, error) {
+	if m.setStatusFunc != nil {
+		return m.setStatusFunc(ctx, id, change, actorID)
+	}
+	previous := model.StatusActive
+	if m.status != nil {
+		previous = m.status.Status
+	}
+	m.status = &model.AccountStatus{Status: change.Status, Until: change.Until, ChangedBy: &actorID}
+	if change.Reason != "" {
+		m.status.Reason = &change.Reason
+	}
+	return previous, nil
+}
+
+func (m *mockUserRepo) StreamUsers(ctx context.Context, filter model.UserFilter, fields []string, fn func(values []any) error) error {
+	for _, row := range m.streamRows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return m.streamErr
+}
+
+const testAvatarMaxBytes = 1024
+
+// mockBlobStore is an in-memory blob.Store.
+type mockBlobStore struct {
+	blobs map[string][]byte
+}
+
+func newMockBlobStore() *mockBlobStore {
+	return &mockBlobStore{blobs: map[string][]byte{}}
+}
+
+func (m *mockBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.blobs[key] = b
+	return nil
+}
+
+func (m *mockBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, ok := m.blobs[key]
+	if !ok {
+		return nil, blob.ErrNotFound
 	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *mockBlobStore) Delete(ctx context.Context, key string) error {
+	delete(m.blobs, key)
+	return nil
+}
+
+type mockAuditRepo struct {
+	entries []model.AuditEntry
+}
+
+func (m *mockAuditRepo) Record(ctx context.Context, entry model.AuditEntry) error {
+	m.entries = append(m.entries, entry)
 	return nil
 }
 
 var errRepo = errors.New("repo error")
+var errMail = errors.New("mail error")
 
 func TestUserService_GetAll(t *testing.T) {
 
@@ -67,11 +202,12 @@ func TestUserService_GetAll(t *testing.T) {
 	}
 
 	params := model.PaginationParams{Page: 1, Limit: 10}
+	filter := model.UserFilter{Search: "doe", Role: "user", Deleted: model.DeletedExclude}
 
 	tests := []struct {
 		name         string
-		mockFunc     func(ctx context.Context, limit, offset int) ([]model.GetAll, error)
-		getCountFunc func(ctx context.Context) (int, error)
+		mockFunc     func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error)
+		getCountFunc func(ctx context.Context, filter model.UserFilter) (int, error)
 		getByIDFunc  func(ctx context.Context, id uuid.UUID) (*model.GetByID, error)
 		callerID     *uuid.UUID
 		callerRole   string
@@ -81,19 +217,39 @@ func TestUserService_GetAll(t *testing.T) {
 	}{
 		{
 			name: "success - admin gets all",
-			mockFunc: func(ctx context.Context, limit, offset int) ([]model.GetAll, error) {
+			mockFunc: func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error) {
 				return users, nil
 			},
-			getCountFunc: func(ctx context.Context) (int, error) { return 2, nil },
+			getCountFunc: func(ctx context.Context, filter model.UserFilter) (int, error) { return 2, nil },
 			callerID:     &testID_1,
 			callerRole:   "admin",
 			expectErr:    false,
 			expectItems:  users,
 			expectTotal:  2,
 		},
+		{
+			name: "filter applied to items and count",
+			mockFunc: func(ctx context.Context, f model.UserFilter, limit, offset int) ([]model.GetAll, error) {
+				if !reflect.DeepEqual(f, filter) {
+					return nil, errRepo
+				}
+				return users[:1], nil
+			},
+			getCountFunc: func(ctx context.Context, f model.UserFilter) (int, error) {
+				if !reflect.DeepEqual(f, filter) {
+					return 0, errRepo
+				}
+				return 1, nil
+			},
+			callerID:    &testID_1,
+			callerRole:  "admin",
+			expectErr:   false,
+			expectItems: users[:1],
+			expectTotal: 1,
+		},
 		{
 			name: "repo error",
-			mockFunc: func(ctx context.Context, limit, offset int) ([]model.GetAll, error) {
+			mockFunc: func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error) {
 				return nil, errRepo
 			},
 			callerID:   &testID_1,
@@ -102,10 +258,10 @@ func TestUserService_GetAll(t *testing.T) {
 		},
 		{
 			name: "nil slice treated as empty",
-			mockFunc: func(ctx context.Context, limit, offset int) ([]model.GetAll, error) {
+			mockFunc: func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error) {
 				return nil, nil
 			},
-			getCountFunc: func(ctx context.Context) (int, error) { return 0, nil },
+			getCountFunc: func(ctx context.Context, filter model.UserFilter) (int, error) { return 0, nil },
 			callerID:     &testID_1,
 			callerRole:   "admin",
 			expectErr:    false,
@@ -114,7 +270,7 @@ func TestUserService_GetAll(t *testing.T) {
 		},
 		{
 			name: "regular user gets only self",
-			mockFunc: func(ctx context.Context, limit, offset int) ([]model.GetAll, error) {
+			mockFunc: func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.GetAll, error) {
 				return nil, nil
 			},
 			getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.GetByID, error) {
@@ -135,7 +291,7 @@ func TestUserService_GetAll(t *testing.T) {
 				getCountFunc: tt.getCountFunc,
 				getByIDFunc:  tt.getByIDFunc,
 			}
-			service := NewUserService(mock)
+			service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
 			callerID := testID_1
 			if tt.callerID != nil {
 				callerID = *tt.callerID
@@ -145,7 +301,7 @@ func TestUserService_GetAll(t *testing.T) {
 				callerRole = "user"
 			}
 
-			resp, err := service.GetAll(context.Background(), callerID, callerRole, params)
+			resp, err := service.GetAll(context.Background(), callerID, callerRole, filter, params)
 
 			if tt.expectErr {
 				if err == nil {
@@ -169,6 +325,180 @@ func TestUserService_GetAll(t *testing.T) {
 
 }
 
+func TestUserService_GetAllByCursor(t *testing.T) {
+	var users []model.GetAll
+	for i := 0; i < 5; i++ {
+		id, _ := uuid.NewV7()
+		users = append(users, model.GetAll{ID: id, FirstName: "John", LastName: "Doe", Email: "johndoe@test.com"})
+	}
+
+	// page simulates the repository: ids ascending after the cursor, or
+	// descending before it.
+	page := func(ctx context.Context, filter model.UserFilter, cursor *model.Cursor, limit int) ([]model.GetAll, error) {
+		var out []model.GetAll
+		switch {
+		case cursor == nil:
+			out = slices.Clone(users)
+		case cursor.Before:
+			for i := len(users) - 1; i >= 0; i-- {
+				if users[i].ID.String() < cursor.ID.String() || cursor.Inclusive && users[i].ID == cursor.ID {
+					out = append(out, users[i])
+				}
+			}
+		default:
+			for _, u := range users {
+				if u.ID.String() > cursor.ID.String() || cursor.Inclusive && u.ID == cursor.ID {
+					out = append(out, u)
+				}
+			}
+		}
+		if len(out) > limit {
+			out = out[:limit]
+		}
+		return out, nil
+	}
+
+	tests := []struct {
+		name        string
+		callerRole  string
+		cursor      *model.Cursor
+		getPageFunc func(ctx context.Context, filter model.UserFilter, cursor *model.Cursor, limit int) ([]model.GetAll, error)
+		expectErr   bool
+		expectItems []model.GetAll
+		expectNext  *model.Cursor
+		expectPrev  *model.Cursor
+	}{
+		{
+			name:        "first page",
+			callerRole:  "admin",
+			getPageFunc: page,
+			expectItems: users[:2],
+			expectNext:  &model.Cursor{ID: users[1].ID},
+		},
+		{
+			name:        "middle page",
+			callerRole:  "admin",
+			cursor:      &model.Cursor{ID: users[1].ID},
+			getPageFunc: page,
+			expectItems: users[2:4],
+			expectNext:  &model.Cursor{ID: users[3].ID},
+			expectPrev:  &model.Cursor{ID: users[2].ID, Before: true},
+		},
+		{
+			name:        "last page",
+			callerRole:  "admin",
+			cursor:      &model.Cursor{ID: users[3].ID},
+			getPageFunc: page,
+			expectItems: users[4:],
+			expectPrev:  &model.Cursor{ID: users[4].ID, Before: true},
+		},
+		{
+			name:        "backward page",
+			callerRole:  "admin",
+			cursor:      &model.Cursor{ID: users[4].ID, Before: true},
+			getPageFunc: page,
+			expectItems: users[2:4],
+			expectNext:  &model.Cursor{ID: users[3].ID},
+			expectPrev:  &model.Cursor{ID: users[2].ID, Before: true},
+		},
+		{
+			name:        "backward to first page",
+			callerRole:  "admin",
+			cursor:      &model.Cursor{ID: users[2].ID, Before: true},
+			getPageFunc: page,
+			expectItems: users[:2],
+			expectNext:  &model.Cursor{ID: users[1].ID},
+		},
+		{
+			name:        "past the last page",
+			callerRole:  "admin",
+			cursor:      &model.Cursor{ID: users[4].ID},
+			getPageFunc: page,
+			expectItems: []model.GetAll{},
+			expectPrev:  &model.Cursor{ID: users[4].ID, Before: true, Inclusive: true},
+		},
+		{
+			name:        "back from past the last page",
+			callerRole:  "admin",
+			cursor:      &model.Cursor{ID: users[4].ID, Before: true, Inclusive: true},
+			getPageFunc: page,
+			expectItems: users[3:],
+			expectNext:  &model.Cursor{ID: users[4].ID},
+			expectPrev:  &model.Cursor{ID: users[3].ID, Before: true},
+		},
+		{
+			name:        "before the first page",
+			callerRole:  "admin",
+			cursor:      &model.Cursor{ID: users[0].ID, Before: true},
+			getPageFunc: page,
+			expectItems: []model.GetAll{},
+			expectNext:  &model.Cursor{ID: users[0].ID, Inclusive: true},
+		},
+		{
+			name:       "repo error",
+			callerRole: "admin",
+			getPageFunc: func(ctx context.Context, filter model.UserFilter, cursor *model.Cursor, limit int) ([]model.GetAll, error) {
+				return nil, errRepo
+			},
+			expectErr: true,
+		},
+		{
+			name:        "regular user gets only self",
+			callerRole:  "user",
+			expectItems: []model.GetAll{users[0]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockUserRepo{
+				getPageFunc: tt.getPageFunc,
+				getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.GetByID, error) {
+					u := users[0]
+					return &model.GetByID{ID: u.ID, FirstName: u.FirstName, LastName: u.LastName, Email: u.Email}, nil
+				},
+			}
+			service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
+
+			params := model.CursorParams{Cursor: tt.cursor, Limit: 2}
+			resp, err := service.GetAllByCursor(context.Background(), users[0].ID, tt.callerRole, model.UserFilter{}, params)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(resp.Items, tt.expectItems) {
+				t.Errorf("got items %v want %v", resp.Items, tt.expectItems)
+			}
+
+			checkCursor := func(label, got string, want *model.Cursor) {
+				if want == nil {
+					if got != "" {
+						t.Errorf("%s: expected no cursor, got %q", label, got)
+					}
+					return
+				}
+				c, err := model.DecodeCursor(got)
+				if err != nil {
+					t.Fatalf("%s: decode %q: %v", label, got, err)
+				}
+				if *c != *want {
+					t.Errorf("%s: got %+v want %+v", label, *c, *want)
+				}
+			}
+			checkCursor("next", resp.Meta.NextCursor, tt.expectNext)
+			checkCursor("prev", resp.Meta.PrevCursor, tt.expectPrev)
+		})
+	}
+}
+
 func TestUserService_GetByID(t *testing.T) {
 	testID, _ := uuid.NewV7()
 
@@ -239,7 +569,7 @@ func TestUserService_GetByID(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := &mockUserRepo{getByIDFunc: tt.mockFunc}
-			service := NewUserService(mock)
+			service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
 
 			resp, err := service.GetByID(context.Background(), testID, tt.callerID, tt.callerRole)
 
@@ -267,14 +597,14 @@ func TestUserService_DeleteByID(t *testing.T) {
 
 	tests := []struct {
 		name       string
-		mockFunc   func(ctx context.Context, id uuid.UUID) error
+		mockFunc   func(ctx context.Context, id uuid.UUID, cond model.Precondition) error
 		callerID   uuid.UUID
 		callerRole string
 		expectErr  bool
 	}{
 		{
 			name: "success - same user",
-			mockFunc: func(ctx context.Context, id uuid.UUID) error {
+			mockFunc: func(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
 				return nil
 			},
 			callerID:   testID,
@@ -283,7 +613,7 @@ func TestUserService_DeleteByID(t *testing.T) {
 		},
 		{
 			name: "success - admin deleting other user",
-			mockFunc: func(ctx context.Context, id uuid.UUID) error {
+			mockFunc: func(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
 				return nil
 			},
 			callerID:   uuid.MustParse("00000000-0000-0000-0000-000000000001"),
@@ -292,7 +622,7 @@ func TestUserService_DeleteByID(t *testing.T) {
 		},
 		{
 			name: "forbidden - user deleting other user",
-			mockFunc: func(ctx context.Context, id uuid.UUID) error {
+			mockFunc: func(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
 				return nil
 			},
 			callerID:   uuid.MustParse("00000000-0000-0000-0000-000000000001"),
@@ -301,16 +631,25 @@ func TestUserService_DeleteByID(t *testing.T) {
 		},
 		{
 			name: "repo error",
-			mockFunc: func(ctx context.Context, id uuid.UUID) error {
+			mockFunc: func(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
 				return errRepo
 			},
 			callerID:   testID,
 			callerRole: "user",
 			expectErr:  true,
 		},
+		{
+			name: "version mismatch",
+			mockFunc: func(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
+				return model.ErrPreconditionFailed
+			},
+			callerID:   testID,
+			callerRole: "user",
+			expectErr:  true,
+		},
 		{
 			name: "user already deleted",
-			mockFunc: func(ctx context.Context, id uuid.UUID) error {
+			mockFunc: func(ctx context.Context, id uuid.UUID, cond model.Precondition) error {
 				return model.ErrAlreadyDeleted
 			},
 			callerID:   testID,
@@ -322,9 +661,9 @@ func TestUserService_DeleteByID(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := &mockUserRepo{deleteByIDFunc: tt.mockFunc}
-			service := NewUserService(mock)
+			service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
 
-			err := service.DeleteByID(context.Background(), testID, tt.callerID, tt.callerRole)
+			err := service.DeleteByID(context.Background(), testID, model.Precondition{Any: true}, tt.callerID, tt.callerRole)
 
 			if tt.expectErr {
 				if err == nil {
@@ -343,24 +682,22 @@ func TestUserService_DeleteByID(t *testing.T) {
 func TestUserService_UpdateByID(t *testing.T) {
 	testID, _ := uuid.NewV7()
 
-	firstName := "John"
-	lastName := "Doe"
 	updateData := &model.UpdateUser{
-		FirstName: &firstName,
-		LastName:  &lastName,
+		FirstName: model.Optional[string]{Value: "John", Set: true},
+		LastName:  model.Optional[string]{Value: "Doe", Set: true},
 	}
 
 	tests := []struct {
 		name       string
-		mockFunc   func(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error
+		mockFunc   func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error)
 		callerID   uuid.UUID
 		callerRole string
 		expectErr  bool
 	}{
 		{
 			name: "success - same user",
-			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error {
-				return nil
+			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+				return 2, nil
 			},
 			callerID:   testID,
 			callerRole: "user",
@@ -368,8 +705,8 @@ func TestUserService_UpdateByID(t *testing.T) {
 		},
 		{
 			name: "forbidden - user updating other user",
-			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error {
-				return nil
+			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+				return 2, nil
 			},
 			callerID:   uuid.MustParse("00000000-0000-0000-0000-000000000001"),
 			callerRole: "user",
@@ -377,8 +714,8 @@ func TestUserService_UpdateByID(t *testing.T) {
 		},
 		{
 			name: "repo error",
-			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error {
-				return errRepo
+			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+				return 0, errRepo
 			},
 			callerID:   testID,
 			callerRole: "user",
@@ -386,8 +723,8 @@ func TestUserService_UpdateByID(t *testing.T) {
 		},
 		{
 			name: "user not found",
-			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error {
-				return model.ErrNotFound
+			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+				return 0, model.ErrNotFound
 			},
 			callerID:   testID,
 			callerRole: "user",
@@ -395,8 +732,8 @@ func TestUserService_UpdateByID(t *testing.T) {
 		},
 		{
 			name: "user already deleted",
-			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error {
-				return model.ErrAlreadyDeleted
+			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+				return 0, model.ErrAlreadyDeleted
 			},
 			callerID:   testID,
 			callerRole: "user",
@@ -404,8 +741,8 @@ func TestUserService_UpdateByID(t *testing.T) {
 		},
 		{
 			name: "bad request - nil fields",
-			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser) error {
-				return model.ErrBadRequest
+			mockFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+				return 0, model.ErrBadRequest
 			},
 			callerID:   testID,
 			callerRole: "user",
@@ -416,9 +753,191 @@ func TestUserService_UpdateByID(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := &mockUserRepo{updateByIDFunc: tt.mockFunc}
-			service := NewUserService(mock)
+			service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
+
+			_, err := service.UpdateByID(context.Background(), testID, updateData, model.Precondition{Any: true}, tt.callerID, tt.callerRole)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestUserService_UpdateByIDMergesAddress(t *testing.T) {
+	testID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name      string
+		current   *model.Address
+		patch     model.AddressPatch
+		expectErr bool
+	}{
+		{
+			name:    "partial patch completes existing address",
+			current: &model.Address{Line1: "1 High St", City: "London", Country: "GB"},
+			patch:   model.AddressPatch{PostalCode: model.Optional[string]{Value: "SW1A 1AA", Set: true}},
+		},
+		{
+			name:      "partial patch without existing address",
+			patch:     model.AddressPatch{PostalCode: model.Optional[string]{Value: "SW1A 1AA", Set: true}},
+			expectErr: true,
+		},
+		{
+			name:      "patch removes required member",
+			current:   &model.Address{Line1: "1 High St", City: "London", Country: "GB"},
+			patch:     model.AddressPatch{City: model.Optional[string]{Set: true, Null: true}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := false
+			mock := &mockUserRepo{
+				getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.GetByID, error) {
+					return &model.GetByID{ID: id, Address: tt.current}, nil
+				},
+				updateByIDFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+					updated = true
+					return 2, nil
+				},
+			}
+			service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
+
+			patch := &model.UpdateUser{Address: model.Optional[model.AddressPatch]{Value: tt.patch, Set: true}}
+			_, err := service.UpdateByID(context.Background(), testID, patch, model.Precondition{Any: true}, testID, "user")
+
+			if tt.expectErr {
+				var verrs model.ValidationErrors
+				if !errors.As(err, &verrs) {
+					t.Fatalf("expected validation error, got %v", err)
+				}
+				if updated {
+					t.Error("repository must not be called for an invalid merged address")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !updated {
+				t.Error("expected repository update")
+			}
+		})
+	}
+}
+
+func TestUserService_UpdateByIDEmptyPatch(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	mock := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.GetByID, error) {
+			return nil, model.ErrNotFound
+		},
+		updateByIDFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+			t.Error("empty patch must not reach the repository update")
+			return 0, nil
+		},
+	}
+	service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
+
+	_, err := service.UpdateByID(context.Background(), testID, &model.UpdateUser{}, model.Precondition{Any: true}, testID, "user")
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("expected not found for missing user, got %v", err)
+	}
+}
+
+func TestUserService_UpdateByIDPrecondition(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	mock := &mockUserRepo{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.GetByID, error) {
+			return &model.GetByID{ID: id, Version: 4}, nil
+		},
+		updateByIDFunc: func(ctx context.Context, id uuid.UUID, data *model.UpdateUser, cond model.Precondition) (int64, error) {
+			if !cond.Matches(4) {
+				return 0, model.ErrPreconditionFailed
+			}
+			return 5, nil
+		},
+	}
+	service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
+	ctx := context.Background()
+	rename := &model.UpdateUser{FirstName: model.Optional[string]{Value: "Jane", Set: true}}
+
+	version, err := service.UpdateByID(ctx, testID, rename, model.Precondition{Versions: []int64{4}}, testID, "user")
+	if err != nil || version != 5 {
+		t.Fatalf("expected version 5, got %d, %v", version, err)
+	}
+
+	if _, err := service.UpdateByID(ctx, testID, rename, model.Precondition{Versions: []int64{3}}, testID, "user"); !errors.Is(err, model.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failed, got %v", err)
+	}
+
+	version, err = service.UpdateByID(ctx, testID, &model.UpdateUser{}, model.Precondition{Versions: []int64{4}}, testID, "user")
+	if err != nil || version != 4 {
+		t.Fatalf("empty patch: expected current version 4, got %d, %v", version, err)
+	}
+
+	if _, err := service.UpdateByID(ctx, testID, &model.UpdateUser{}, model.Precondition{Versions: []int64{3}}, testID, "user"); !errors.Is(err, model.ErrPreconditionFailed) {
+		t.Fatalf("empty patch: expected precondition failed, got %v", err)
+	}
+}
+
+func TestUserService_GetDeleted(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	deleted := []model.DeletedUser{{ID: testID, FirstName: "John", LastName: "Doe", Email: "johndoe@test.com", DeletedAt: time.Now()}}
+
+	tests := []struct {
+		name       string
+		callerRole string
+		mockFunc   func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.DeletedUser, error)
+		expectErr  bool
+	}{
+		{
+			name:       "success - admin",
+			callerRole: "admin",
+			mockFunc: func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.DeletedUser, error) {
+				if filter.Deleted != model.DeletedOnly {
+					return nil, errRepo
+				}
+				if len(filter.Sort) != 1 || filter.Sort[0].Field != "deleted_at" || !filter.Sort[0].Desc {
+					return nil, errRepo
+				}
+				return deleted, nil
+			},
+			expectErr: false,
+		},
+		{
+			name:       "forbidden - regular user",
+			callerRole: "user",
+			expectErr:  true,
+		},
+		{
+			name:       "repo error",
+			callerRole: "admin",
+			mockFunc: func(ctx context.Context, filter model.UserFilter, limit, offset int) ([]model.DeletedUser, error) {
+				return nil, errRepo
+			},
+			expectErr: true,
+		},
+	}
 
-			err := service.UpdateByID(context.Background(), testID, updateData, tt.callerID, tt.callerRole)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockUserRepo{
+				getDeletedFunc: tt.mockFunc,
+				getCountFunc:   func(ctx context.Context, filter model.UserFilter) (int, error) { return 1, nil },
+			}
+			service := NewUserService(mock, &mockAuditRepo{}, newMockBlobStore(), testAvatarMaxBytes)
+
+			resp, err := service.GetDeleted(context.Background(), tt.callerRole, model.UserFilter{}, model.PaginationParams{Page: 1, Limit: 10})
 
 			if tt.expectErr {
 				if err == nil {
@@ -430,6 +949,266 @@ func TestUserService_UpdateByID(t *testing.T) {
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
+
+			if !reflect.DeepEqual(resp.Items, deleted) {
+				t.Errorf("got items %v want %v", resp.Items, deleted)
+			}
+			if resp.Meta.Total != 1 {
+				t.Errorf("got total %d want 1", resp.Meta.Total)
+			}
+		})
+	}
+}
+
+func TestUserService_RestoreByID(t *testing.T) {
+	testID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+
+	tests := []struct {
+		name        string
+		mockFunc    func(ctx context.Context, id uuid.UUID) error
+		callerRole  string
+		expectErr   error
+		expectAudit bool
+	}{
+		{
+			name:        "success - admin",
+			mockFunc:    func(ctx context.Context, id uuid.UUID) error { return nil },
+			callerRole:  "admin",
+			expectAudit: true,
+		},
+		{
+			name:       "forbidden - regular user",
+			callerRole: "user",
+			expectErr:  model.ErrForbidden,
+		},
+		{
+			name:       "not found",
+			mockFunc:   func(ctx context.Context, id uuid.UUID) error { return model.ErrNotFound },
+			callerRole: "admin",
+			expectErr:  model.ErrNotFound,
+		},
+		{
+			name:       "not deleted",
+			mockFunc:   func(ctx context.Context, id uuid.UUID) error { return model.ErrConflict },
+			callerRole: "admin",
+			expectErr:  model.ErrConflict,
+		},
+		{
+			name:       "already purged",
+			mockFunc:   func(ctx context.Context, id uuid.UUID) error { return model.ErrAlreadyDeleted },
+			callerRole: "admin",
+			expectErr:  model.ErrAlreadyDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &mockAuditRepo{}
+			service := NewUserService(&mockUserRepo{restoreFunc: tt.mockFunc}, audit, newMockBlobStore(), testAvatarMaxBytes)
+
+			err := service.RestoreByID(context.Background(), testID, adminID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectAudit {
+				if len(audit.entries) != 1 {
+					t.Fatalf("expected 1 audit entry, got %d", len(audit.entries))
+				}
+				e := audit.entries[0]
+				if e.Action != model.AuditUserRestore || *e.SubjectID != testID || *e.ActorID != adminID {
+					t.Errorf("unexpected audit entry %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestUserService_PurgeDeleted(t *testing.T) {
+	batch := func(n int) []model.PurgedUser {
+		users := make([]model.PurgedUser, n)
+		for i := range users {
+			users[i].ID, _ = uuid.NewV7()
+			users[i].Mode = model.PurgeModeAnonymise
+		}
+		return users
+	}
+
+	tests := []struct {
+		name        string
+		batches     [][]model.PurgedUser
+		batchErr    error
+		expectCount int
+		expectErr   bool
+	}{
+		{
+			name:        "nothing to purge",
+			batches:     [][]model.PurgedUser{nil},
+			expectCount: 0,
+		},
+		{
+			name:        "multiple batches",
+			batches:     [][]model.PurgedUser{batch(2), batch(2), batch(1)},
+			expectCount: 5,
+		},
+		{
+			name:      "repo error",
+			batchErr:  errRepo,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var gotCutoff time.Time
+			mock := &mockUserRepo{
+				purgeFunc: func(ctx context.Context, cutoff time.Time, mode string, limit int) ([]model.PurgedUser, error) {
+					gotCutoff = cutoff
+					if tt.batchErr != nil {
+						return nil, tt.batchErr
+					}
+					users := tt.batches[calls]
+					calls++
+					return users, nil
+				},
+			}
+			audit := &mockAuditRepo{}
+			service := NewUserService(mock, audit, newMockBlobStore(), testAvatarMaxBytes)
+
+			before := time.Now()
+			n, err := service.PurgeDeleted(context.Background(), 24*time.Hour, model.PurgeModeAnonymise, 2)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != tt.expectCount {
+				t.Errorf("got %d purged want %d", n, tt.expectCount)
+			}
+			if len(audit.entries) != tt.expectCount {
+				t.Errorf("got %d audit entries want %d", len(audit.entries), tt.expectCount)
+			}
+			if gotCutoff.Before(before.Add(-24*time.Hour)) || gotCutoff.After(time.Now().Add(-24*time.Hour)) {
+				t.Errorf("cutoff %v is not 24h before now", gotCutoff)
+			}
+		})
+	}
+}
+
+// In delete mode the accounts of practitioners are anonymised rather than
+// deleted, so their clinical records survive, and the audit log says so.
+func TestUserService_PurgeDeleted_KeepsPractitioners(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	practitionerUserID, _ := uuid.NewV7()
+
+	var gotMode string
+	mock := &mockUserRepo{
+		purgeFunc: func(ctx context.Context, cutoff time.Time, mode string, limit int) ([]model.PurgedUser, error) {
+			gotMode = mode
+			return []model.PurgedUser{
+				{ID: userID, Mode: model.PurgeModeDelete},
+				{ID: practitionerUserID, Mode: model.PurgeModeAnonymise},
+			}, nil
+		},
+	}
+	audit := &mockAuditRepo{}
+	service := NewUserService(mock, audit, newMockBlobStore(), testAvatarMaxBytes)
+
+	n, err := service.PurgeDeleted(context.Background(), 24*time.Hour, model.PurgeModeDelete, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 || gotMode != model.PurgeModeDelete {
+		t.Fatalf("purged %d in mode %q, want 2 in delete mode", n, gotMode)
+	}
+
+	modes := map[uuid.UUID]any{}
+	for _, e := range audit.entries {
+		modes[*e.SubjectID] = e.Details["mode"]
+	}
+	if modes[userID] != model.PurgeModeDelete {
+		t.Errorf("user audited as %v, want delete", modes[userID])
+	}
+	if modes[practitionerUserID] != model.PurgeModeAnonymise {
+		t.Errorf("practitioner audited as %v, want anonymise", modes[practitionerUserID])
+	}
+}
+
+func TestUserService_SetStatus(t *testing.T) {
+	userID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	until := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		callerID   uuid.UUID
+		callerRole string
+		repoErr    error
+		expectErr  error
+	}{
+		{name: "admin suspends user", callerID: adminID, callerRole: "admin"},
+		{name: "user cannot change status", callerID: userID, callerRole: "user", expectErr: model.ErrForbidden},
+		{name: "admin cannot change own status", callerID: userID, callerRole: "admin", expectErr: model.ErrForbidden},
+		{name: "user not found", callerID: adminID, callerRole: "admin", repoErr: model.ErrNotFound, expectErr: model.ErrNotFound},
+		{name: "user deleted", callerID: adminID, callerRole: "super_admin", repoErr: model.ErrAlreadyDeleted, expectErr: model.ErrAlreadyDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockUserRepo{}
+			if tt.repoErr != nil {
+				mock.setStatusFunc = func(ctx context.Context, id uuid.UUID, change model.ChangeStatus, actorID uuid.UUID) (string, error) {
+					return "", tt.repoErr
+				}
+			}
+			audit := &mockAuditRepo{}
+			service := NewUserService(mock, audit, newMockBlobStore(), testAvatarMaxBytes)
+
+			change := model.ChangeStatus{Status: model.StatusSuspended, Reason: "unpaid invoice", Until: &until}
+			status, err := service.SetStatus(context.Background(), userID, change, tt.callerID, tt.callerRole)
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("expected %v, got %v", tt.expectErr, err)
+				}
+				if len(audit.entries) != 0 {
+					t.Error("failed change must not be audited")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status.Status != model.StatusSuspended {
+				t.Errorf("expected suspended, got %s", status.Status)
+			}
+
+			if len(audit.entries) != 1 {
+				t.Fatalf("expected 1 audit entry, got %d", len(audit.entries))
+			}
+			e := audit.entries[0]
+			if e.Action != model.AuditUserStatus || *e.ActorID != adminID || *e.SubjectID != userID {
+				t.Errorf("unexpected audit entry %+v", e)
+			}
+			if e.Details["from"] != model.StatusActive || e.Details["to"] != model.StatusSuspended || e.Details["reason"] != "unpaid invoice" {
+				t.Errorf("unexpected audit details %v", e.Details)
+			}
 		})
 	}
 }
@@ -0,0 +1,466 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/PranavJoshi2893/med-portal/internal/repository"
+	"github.com/PranavJoshi2893/med-portal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+// lapsedOfferBatch is how many lapsed offers LapseOffers passes on per query.
+const lapsedOfferBatch = 100
+
+// WaitlistService keeps patients waiting for a practitioner and offers them
+// slots that free up. Offers go to waiting entries in the order they joined
+// and hold the slot for hold, after which they pass to the next entry. All
+// times come from now, so tests can control the clock.
+type WaitlistService struct {
+	repo         repository.WaitlistRepository
+	appointments repository.AppointmentRepository
+	patients     repository.PatientRepository
+	audit        repository.AuditRepository
+	mailer       mailer.Mailer
+	baseURL      string
+	hold         time.Duration
+	now          func() time.Time
+}
+
+func NewWaitlistService(repo repository.WaitlistRepository, appointments repository.AppointmentRepository, patients repository.PatientRepository, audit repository.AuditRepository, mailer mailer.Mailer, baseURL string, hold time.Duration, now func() time.Time) *WaitlistService {
+	return &WaitlistService{
+		repo:         repo,
+		appointments: appointments,
+		patients:     patients,
+		audit:        audit,
+		mailer:       mailer,
+		baseURL:      baseURL,
+		hold:         hold,
+		now:          now,
+	}
+}
+
+func waitlistError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("waitlist entry %w", err)
+	}
+	return err
+}
+
+func offerError(err error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return fmt.Errorf("offer %w", err)
+	}
+	return err
+}
+
+// scheduleLocation returns the time zone of a practitioner's schedule, or UTC
+// if they have none.
+func (s *WaitlistService) scheduleLocation(ctx context.Context, practitionerID uuid.UUID) (*time.Location, error) {
+	schedule, err := s.appointments.GetSchedule(ctx, practitionerID)
+	if err != nil {
+		return nil, practitionerError(err)
+	}
+	if schedule.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(schedule.Timezone)
+}
+
+// Join puts a patient on a practitioner's waitlist. Admins add any patient;
+// other users only the patient linked to their login.
+func (s *WaitlistService) Join(ctx context.Context, data *model.JoinWaitlist, callerID uuid.UUID, callerRole string) (*model.WaitlistEntry, error) {
+	if !isAdmin(callerRole) {
+		if !inOrganisation(ctx, callerRole) {
+			return nil, model.ErrForbidden
+		}
+		patient, err := s.patients.GetByID(ctx, data.PatientID)
+		if err != nil {
+			return nil, patientError(err)
+		}
+		if patient.UserID == nil || *patient.UserID != callerID {
+			return nil, fmt.Errorf("patient %w", model.ErrNotFound)
+		}
+	}
+
+	loc, err := s.scheduleLocation(ctx, data.PractitionerID)
+	if err != nil {
+		return nil, err
+	}
+	if data.To < s.now().In(loc).Format(model.DateLayout) {
+		return nil, model.ValidationErrors{model.FieldError{Field: "to", Message: "to must not be in the past"}}
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	err = s.repo.Create(ctx, model.WaitlistEntry{
+		ID:             id,
+		PractitionerID: data.PractitionerID,
+		PatientID:      data.PatientID,
+		Service:        optionalString(data.Service),
+		From:           data.From,
+		To:             data.To,
+		CreatedBy:      &callerID,
+		CreatedAt:      s.now(),
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("practitioner or patient %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditWaitlistJoined, "waitlist_entry", id, map[string]any{
+		"practitioner_id": data.PractitionerID,
+		"patient_id":      data.PatientID,
+		"from":            data.From,
+		"to":              data.To,
+	})
+
+	entry, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, waitlistError(err)
+	}
+	return entry, nil
+}
+
+// List returns a page of the waitlist. Admins see the whole waitlist of their
+// organisation; other users see the entries they are the practitioner or
+// the patient of.
+func (s *WaitlistService) List(ctx context.Context, filter model.WaitlistFilter, params model.PaginationParams, callerID uuid.UUID, callerRole string) (*model.PaginatedWaitlistResponse, error) {
+	if !isAdmin(callerRole) {
+		filter.ParticipantID = &callerID
+	}
+
+	entries, err := s.repo.List(ctx, filter, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if entries == nil {
+		entries = []model.WaitlistEntry{}
+	}
+
+	totalPages := total / params.Limit
+	if total%params.Limit != 0 {
+		totalPages++
+	}
+
+	return &model.PaginatedWaitlistResponse{
+		Items: entries,
+		Meta: model.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// GetByID returns an entry to admins and to its practitioner and patient.
+// Other callers are told it does not exist.
+func (s *WaitlistService) GetByID(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.WaitlistEntry, error) {
+	entry, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, waitlistError(err)
+	}
+
+	participant := entry.PractitionerUserID == callerID || (entry.PatientUserID != nil && *entry.PatientUserID == callerID)
+	if !isAdmin(callerRole) && !participant {
+		return nil, fmt.Errorf("waitlist entry %w", model.ErrNotFound)
+	}
+
+	return entry, nil
+}
+
+// Leave takes an entry off the waitlist. A slot it is being offered is
+// declined and passed on.
+func (s *WaitlistService) Leave(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	entry, err := s.GetByID(ctx, id, callerID, callerRole)
+	if err != nil {
+		return err
+	}
+
+	if entry.Offer != nil {
+		if err := s.resolve(ctx, entry.Offer, model.OfferDeclined, &callerID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.Cancel(ctx, id); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return fmt.Errorf("waitlist entry is already %s: %w", entry.Status, err)
+		}
+		return err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditWaitlistLeft, "waitlist_entry", id, nil)
+	return nil
+}
+
+// offer returns an offer to admins and to the patient it was made to.
+func (s *WaitlistService) offer(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.WaitlistOffer, error) {
+	offer, err := s.repo.GetOffer(ctx, id)
+	if err != nil {
+		return nil, offerError(err)
+	}
+	if !isAdmin(callerRole) && (offer.PatientUserID == nil || *offer.PatientUserID != callerID) {
+		return nil, fmt.Errorf("offer %w", model.ErrNotFound)
+	}
+	return offer, nil
+}
+
+// openOffer returns an offer that can still be taken up.
+func (s *WaitlistService) openOffer(ctx context.Context, id uuid.UUID, callerID uuid.UUID, callerRole string) (*model.WaitlistOffer, error) {
+	offer, err := s.offer(ctx, id, callerID, callerRole)
+	if err != nil {
+		return nil, err
+	}
+	if offer.Status != model.OfferPending {
+		return nil, fmt.Errorf("offer is already %s: %w", offer.Status, model.ErrConflict)
+	}
+	if !s.now().Before(offer.ExpiresAt) {
+		return nil, fmt.Errorf("offer has lapsed: %w", model.ErrConflict)
+	}
+	return offer, nil
+}
+
+// Accept books the offered slot for the patient. The booking and closing the
+// offer happen together, so an offer that lapses or is declined in the
+// meantime leaves no appointment behind.
+func (s *WaitlistService) Accept(ctx context.Context, offerID uuid.UUID, callerID uuid.UUID, callerRole string) (*model.Appointment, error) {
+	offer, err := s.openOffer(ctx, offerID, callerID, callerRole)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	err = s.repo.AcceptOffer(ctx, offer.ID, model.Appointment{
+		ID:             id,
+		PractitionerID: offer.PractitionerID,
+		PatientID:      offer.PatientID,
+		StartsAt:       offer.StartsAt,
+		EndsAt:         offer.EndsAt,
+		Service:        offer.Service,
+		CreatedBy:      &callerID,
+	}, s.now())
+	if err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			// Either the offer closed since it was read or the slot was
+			// booked another way; the offer tells which.
+			if _, openErr := s.openOffer(ctx, offerID, callerID, callerRole); openErr != nil {
+				return nil, openErr
+			}
+			return nil, fmt.Errorf("slot overlaps another appointment: %w", err)
+		}
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, &callerID, model.AuditWaitlistOfferAccepted, "waitlist_entry", offer.EntryID, map[string]any{
+		"offer_id":       offer.ID,
+		"appointment_id": id,
+	})
+	recordAudit(ctx, s.audit, &callerID, model.AuditAppointmentBooked, "appointment", id, map[string]any{
+		"practitioner_id": offer.PractitionerID,
+		"patient_id":      offer.PatientID,
+		"starts_at":       offer.StartsAt,
+		"offer_id":        offer.ID,
+	})
+
+	appointment, err := s.appointments.GetByID(ctx, id)
+	if err != nil {
+		return nil, appointmentError(err)
+	}
+	return appointment, nil
+}
+
+// Decline turns an offer down and passes the slot on. The entry stays on the
+// waitlist for other slots.
+func (s *WaitlistService) Decline(ctx context.Context, offerID uuid.UUID, callerID uuid.UUID, callerRole string) error {
+	offer, err := s.openOffer(ctx, offerID, callerID, callerRole)
+	if err != nil {
+		return err
+	}
+	return s.resolve(ctx, offer, model.OfferDeclined, &callerID)
+}
+
+// LapseOffers closes the offers whose hold has expired and passes their
+// slots on. It returns how many offers lapsed.
+func (s *WaitlistService) LapseOffers(ctx context.Context) (int, error) {
+	lapsed := 0
+	for ctx.Err() == nil {
+		offers, err := s.repo.ListLapsed(ctx, s.now(), lapsedOfferBatch)
+		if err != nil {
+			return lapsed, err
+		}
+		if len(offers) == 0 {
+			return lapsed, nil
+		}
+
+		for _, offer := range offers {
+			if err := s.resolve(ctx, &offer, model.OfferLapsed, nil); err != nil {
+				if errors.Is(err, model.ErrConflict) {
+					continue
+				}
+				return lapsed, err
+			}
+			lapsed++
+		}
+	}
+	return lapsed, ctx.Err()
+}
+
+// resolve closes a pending offer other than by accepting it, and offers the
+// slot to the next entry.
+func (s *WaitlistService) resolve(ctx context.Context, offer *model.WaitlistOffer, status string, actorID *uuid.UUID) error {
+	if err := s.repo.ResolveOffer(ctx, offer.ID, status, nil, s.now()); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return fmt.Errorf("offer has changed: %w", err)
+		}
+		return err
+	}
+
+	action := model.AuditWaitlistOfferDeclined
+	if status == model.OfferLapsed {
+		action = model.AuditWaitlistOfferLapsed
+	}
+	recordAudit(ctx, s.audit, actorID, action, "waitlist_entry", offer.EntryID, map[string]any{"offer_id": offer.ID})
+
+	s.SlotFreed(ctx, model.FreedSlot{
+		PractitionerID: offer.PractitionerID,
+		StartsAt:       offer.StartsAt,
+		EndsAt:         offer.EndsAt,
+		Service:        offer.Service,
+	})
+	return nil
+}
+
+// Holds returns the times of the practitioner's slots, overlapping
+// [from, to), that are held for patients on the waitlist.
+func (s *WaitlistService) Holds(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.TimeRange, error) {
+	return s.repo.ListHolds(ctx, practitionerID, from, to, s.now())
+}
+
+// SlotFreed offers a slot that has been given up to the waitlist. The change
+// that freed it stands either way, so failures are logged rather than
+// returned.
+func (s *WaitlistService) SlotFreed(ctx context.Context, slot model.FreedSlot) {
+	if err := s.offerSlot(ctx, slot); err != nil {
+		log.Printf("waitlist: offering %s at %s: %v", slot.PractitionerID, slot.StartsAt.Format(time.RFC3339), err)
+	}
+}
+
+// offerSlot offers slot to the first eligible entry, if the slot is still to
+// come and free: not booked, held or taken out of the schedule.
+func (s *WaitlistService) offerSlot(ctx context.Context, slot model.FreedSlot) error {
+	now := s.now()
+	if !slot.StartsAt.After(now) {
+		return nil
+	}
+
+	busy, err := s.appointments.ListBusy(ctx, slot.PractitionerID, slot.StartsAt, slot.EndsAt)
+	if err != nil {
+		return err
+	}
+	exceptions, err := s.appointments.ListExceptions(ctx, slot.PractitionerID, slot.StartsAt, slot.EndsAt)
+	if err != nil {
+		return err
+	}
+	holds, err := s.repo.ListHolds(ctx, slot.PractitionerID, slot.StartsAt, slot.EndsAt, now)
+	if err != nil {
+		return err
+	}
+	if len(busy) > 0 || len(exceptions) > 0 || len(holds) > 0 {
+		return nil
+	}
+
+	loc, err := s.scheduleLocation(ctx, slot.PractitionerID)
+	if err != nil {
+		return err
+	}
+
+	entry, err := s.repo.NextEligible(ctx, slot, slot.StartsAt.In(loc).Format(model.DateLayout))
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate uuid: %w", err)
+	}
+
+	// The hold never outlasts the slot itself.
+	expiresAt := now.Add(s.hold)
+	if expiresAt.After(slot.StartsAt) {
+		expiresAt = slot.StartsAt
+	}
+
+	offer := model.WaitlistOffer{
+		ID:             id,
+		OrganisationID: entry.OrganisationID,
+		EntryID:        entry.ID,
+		PractitionerID: slot.PractitionerID,
+		PatientID:      entry.PatientID,
+		StartsAt:       slot.StartsAt,
+		EndsAt:         slot.EndsAt,
+		Service:        slot.Service,
+		Status:         model.OfferPending,
+		ExpiresAt:      expiresAt,
+		CreatedAt:      now,
+	}
+	if err := s.repo.CreateOffer(ctx, offer); err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.audit, nil, model.AuditWaitlistOffered, "waitlist_entry", entry.ID, map[string]any{
+		"offer_id":   id,
+		"starts_at":  slot.StartsAt,
+		"expires_at": expiresAt,
+	})
+
+	s.notify(ctx, entry, &offer, loc)
+	return nil
+}
+
+// notify emails the patient, if they have an email address, about an offer.
+func (s *WaitlistService) notify(ctx context.Context, entry *model.WaitlistEntry, offer *model.WaitlistOffer, loc *time.Location) {
+	patient, err := s.patients.GetByID(ctx, entry.PatientID)
+	if err != nil {
+		log.Printf("waitlist offer %s: %v", offer.ID, err)
+		return
+	}
+	if patient.Email == nil || *patient.Email == "" {
+		return
+	}
+
+	const layout = "Monday 2 January 2006 at 15:04 MST"
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      *patient.Email,
+		Subject: "An appointment is available",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nAn appointment with %s is available on %s. It is held for you until %s. Accept or decline it here:\n\n%s/waitlist/offers/%s",
+			patient.FirstName, entry.PractitionerName, offer.StartsAt.In(loc).Format(layout), offer.ExpiresAt.In(loc).Format(layout), s.baseURL, offer.ID,
+		),
+	})
+	if err != nil {
+		log.Printf("waitlist offer %s: %v", offer.ID, err)
+	}
+}
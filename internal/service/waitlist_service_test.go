@@ -0,0 +1,710 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+// mockWaitlistRepo keeps the waitlist in memory and follows the rules of the
+// database: entries are offered slots in the order they joined, an entry is
+// offered a slot at most once and a slot is held by one offer at a time.
+// The xxxFunc fields, when set, replace those rules.
+type mockWaitlistRepo struct {
+	entries []*model.WaitlistEntry
+	offers  []*model.WaitlistOffer
+
+	// appointments receives the bookings of accepted offers.
+	appointments *mockAppointmentRepo
+
+	cancelFunc      func(ctx context.Context, id uuid.UUID) error
+	createOfferFunc func(ctx context.Context, offer model.WaitlistOffer) error
+	acceptOfferFunc func(ctx context.Context, id uuid.UUID, appointment model.Appointment, at time.Time) error
+}
+
+func (m *mockWaitlistRepo) entry(id uuid.UUID) *model.WaitlistEntry {
+	for _, e := range m.entries {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func (m *mockWaitlistRepo) Create(ctx context.Context, entry model.WaitlistEntry) error {
+	entry.Status = model.WaitlistWaiting
+	m.entries = append(m.entries, &entry)
+	return nil
+}
+
+func (m *mockWaitlistRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.WaitlistEntry, error) {
+	e := m.entry(id)
+	if e == nil {
+		return nil, model.ErrNotFound
+	}
+	entry := *e
+	for _, o := range m.offers {
+		if o.EntryID == id && o.Status == model.OfferPending {
+			offer := *o
+			entry.Offer = &offer
+		}
+	}
+	return &entry, nil
+}
+
+func (m *mockWaitlistRepo) List(ctx context.Context, filter model.WaitlistFilter, limit, offset int) ([]model.WaitlistEntry, error) {
+	return nil, nil
+}
+
+func (m *mockWaitlistRepo) Count(ctx context.Context, filter model.WaitlistFilter) (int, error) {
+	return 0, nil
+}
+
+func (m *mockWaitlistRepo) Cancel(ctx context.Context, id uuid.UUID) error {
+	if m.cancelFunc != nil {
+		return m.cancelFunc(ctx, id)
+	}
+	e := m.entry(id)
+	if e == nil || e.Status != model.WaitlistWaiting {
+		return model.ErrConflict
+	}
+	e.Status = model.WaitlistCancelled
+	return nil
+}
+
+func (m *mockWaitlistRepo) NextEligible(ctx context.Context, slot model.FreedSlot, day string) (*model.WaitlistEntry, error) {
+	queue := append([]*model.WaitlistEntry(nil), m.entries...)
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].CreatedAt.Before(queue[j].CreatedAt) })
+
+	for _, e := range queue {
+		if e.PractitionerID != slot.PractitionerID || e.Status != model.WaitlistWaiting || day < e.From || day > e.To {
+			continue
+		}
+		if e.Service != nil && (slot.Service == nil || *e.Service != *slot.Service) {
+			continue
+		}
+		offered := false
+		for _, o := range m.offers {
+			if o.EntryID == e.ID && o.StartsAt.Equal(slot.StartsAt) {
+				offered = true
+			}
+		}
+		if !offered {
+			entry := *e
+			return &entry, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockWaitlistRepo) CreateOffer(ctx context.Context, offer model.WaitlistOffer) error {
+	if m.createOfferFunc != nil {
+		return m.createOfferFunc(ctx, offer)
+	}
+	e := m.entry(offer.EntryID)
+	if e == nil || e.Status != model.WaitlistWaiting {
+		return model.ErrConflict
+	}
+	for _, o := range m.offers {
+		if o.Status == model.OfferPending && o.PractitionerID == offer.PractitionerID && o.StartsAt.Equal(offer.StartsAt) {
+			return model.ErrConflict
+		}
+	}
+	e.Status = model.WaitlistOffered
+	offer.PatientUserID = e.PatientUserID
+	m.offers = append(m.offers, &offer)
+	return nil
+}
+
+func (m *mockWaitlistRepo) GetOffer(ctx context.Context, id uuid.UUID) (*model.WaitlistOffer, error) {
+	for _, o := range m.offers {
+		if o.ID == id {
+			offer := *o
+			return &offer, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockWaitlistRepo) ResolveOffer(ctx context.Context, id uuid.UUID, status string, appointmentID *uuid.UUID, at time.Time) error {
+	for _, o := range m.offers {
+		if o.ID != id {
+			continue
+		}
+		if o.Status != model.OfferPending {
+			return model.ErrConflict
+		}
+		o.Status = status
+		o.AppointmentID = appointmentID
+		o.RespondedAt = &at
+
+		e := m.entry(o.EntryID)
+		e.Status = model.WaitlistWaiting
+		if status == model.OfferAccepted {
+			e.Status = model.WaitlistBooked
+		}
+		return nil
+	}
+	return model.ErrConflict
+}
+
+func (m *mockWaitlistRepo) AcceptOffer(ctx context.Context, id uuid.UUID, appointment model.Appointment, at time.Time) error {
+	if m.acceptOfferFunc != nil {
+		return m.acceptOfferFunc(ctx, id, appointment, at)
+	}
+	for _, o := range m.offers {
+		if o.ID != id {
+			continue
+		}
+		if o.Status != model.OfferPending || !o.ExpiresAt.After(at) {
+			return model.ErrConflict
+		}
+		if err := m.appointments.Create(ctx, appointment); err != nil {
+			return err
+		}
+		o.Status = model.OfferAccepted
+		o.AppointmentID = &appointment.ID
+		o.RespondedAt = &at
+		m.entry(o.EntryID).Status = model.WaitlistBooked
+		return nil
+	}
+	return model.ErrConflict
+}
+
+func (m *mockWaitlistRepo) ListHolds(ctx context.Context, practitionerID uuid.UUID, from, to, now time.Time) ([]model.TimeRange, error) {
+	var holds []model.TimeRange
+	for _, o := range m.offers {
+		r := model.TimeRange{Start: o.StartsAt, End: o.EndsAt}
+		if o.PractitionerID == practitionerID && o.Status == model.OfferPending && o.ExpiresAt.After(now) && r.Overlaps(model.TimeRange{Start: from, End: to}) {
+			holds = append(holds, r)
+		}
+	}
+	return holds, nil
+}
+
+func (m *mockWaitlistRepo) ListLapsed(ctx context.Context, now time.Time, limit int) ([]model.WaitlistOffer, error) {
+	var lapsed []model.WaitlistOffer
+	for _, o := range m.offers {
+		if o.Status == model.OfferPending && !o.ExpiresAt.After(now) && len(lapsed) < limit {
+			lapsed = append(lapsed, *o)
+		}
+	}
+	return lapsed, nil
+}
+
+// pending returns the pending offers.
+func (m *mockWaitlistRepo) pending() []*model.WaitlistOffer {
+	var offers []*model.WaitlistOffer
+	for _, o := range m.offers {
+		if o.Status == model.OfferPending {
+			offers = append(offers, o)
+		}
+	}
+	return offers
+}
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestWaitlistService_OfferCascade(t *testing.T) {
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	start := time.Date(2026, time.November, 3, 10, 0, 0, 0, time.UTC)
+	slot := model.FreedSlot{PractitionerID: practitionerID, StartsAt: start, EndsAt: start.Add(30 * time.Minute)}
+	joined := time.Date(2026, time.November, 1, 9, 0, 0, 0, time.UTC)
+	dermatology := "dermatology"
+
+	email := "patient@example.com"
+	patients := &mockPatientRepo{getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+		return &model.Patient{ID: id, FirstName: "Pat", Email: &email}, nil
+	}}
+
+	var entries []*model.WaitlistEntry
+	for i, e := range []struct {
+		from, to string
+		service  *string
+	}{
+		{"2026-11-01", "2026-11-10", nil},
+		{"2026-11-01", "2026-11-10", &dermatology}, // wants another service
+		{"2026-11-04", "2026-11-10", nil},          // not waiting for that day
+		{"2026-11-03", "2026-11-03", nil},
+	} {
+		id, _ := uuid.NewV7()
+		userID, _ := uuid.NewV7()
+		entries = append(entries, &model.WaitlistEntry{ID: id, PractitionerID: practitionerID, PatientID: patientID, Service: e.service, From: e.from, To: e.to,
+			Status: model.WaitlistWaiting, CreatedAt: joined.Add(time.Duration(i) * time.Minute), PatientUserID: &userID})
+	}
+	first, last := entries[0], entries[3]
+
+	appointments := &mockAppointmentRepo{schedule: openSchedule(practitionerID)}
+	repo := &mockWaitlistRepo{entries: entries, appointments: appointments}
+	mail := &mockMailer{}
+	clock := &fakeClock{t: time.Date(2026, time.November, 2, 10, 0, 0, 0, time.UTC)}
+	service := NewWaitlistService(repo, appointments, patients, &mockAuditRepo{}, mail, "http://app.test", 2*time.Hour, clock.now)
+
+	service.SlotFreed(context.Background(), slot)
+
+	pending := repo.pending()
+	if len(pending) != 1 || pending[0].EntryID != first.ID {
+		t.Fatalf("pending offers = %+v, want one to the first entry", pending)
+	}
+	if want := clock.now().Add(2 * time.Hour); !pending[0].ExpiresAt.Equal(want) {
+		t.Errorf("offer expires at %v, want %v", pending[0].ExpiresAt, want)
+	}
+	if len(mail.sent) != 1 {
+		t.Errorf("expected the patient to be told about the offer, sent %d mails", len(mail.sent))
+	}
+
+	holds, _ := service.Holds(context.Background(), practitionerID, slot.StartsAt, slot.EndsAt)
+	if len(holds) != 1 {
+		t.Errorf("expected the slot to be held, got %+v", holds)
+	}
+
+	// Nothing lapses before the hold expires.
+	clock.advance(time.Hour)
+	if n, err := service.LapseOffers(context.Background()); err != nil || n != 0 {
+		t.Fatalf("LapseOffers = %d, %v before expiry", n, err)
+	}
+
+	clock.advance(time.Hour)
+	n, err := service.LapseOffers(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("LapseOffers = %d, %v, want 1", n, err)
+	}
+	if first.Status != model.WaitlistWaiting {
+		t.Errorf("first entry is %s after its offer lapsed, want waiting", first.Status)
+	}
+	pending = repo.pending()
+	if len(pending) != 1 || pending[0].EntryID != last.ID {
+		t.Fatalf("pending offers = %+v, want one to the last eligible entry", pending)
+	}
+
+	// Declining leaves nobody who has not had the slot.
+	if err := service.Decline(context.Background(), pending[0].ID, *last.PatientUserID, "user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending := repo.pending(); len(pending) != 0 {
+		t.Errorf("expected no more offers, got %+v", pending)
+	}
+	if len(repo.offers) != 2 {
+		t.Errorf("expected two offers in all, got %d", len(repo.offers))
+	}
+}
+
+func TestWaitlistService_Accept(t *testing.T) {
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	nextUserID, _ := uuid.NewV7()
+	otherID, _ := uuid.NewV7()
+	entryID, _ := uuid.NewV7()
+	nextID, _ := uuid.NewV7()
+	offerID, _ := uuid.NewV7()
+
+	start := time.Date(2026, time.November, 3, 10, 0, 0, 0, time.UTC)
+	offeredAt := time.Date(2026, time.November, 2, 10, 0, 0, 0, time.UTC)
+	expiresAt := offeredAt.Add(2 * time.Hour)
+
+	email := "patient@example.com"
+	patients := &mockPatientRepo{getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+		return &model.Patient{ID: id, FirstName: "Pat", Email: &email}, nil
+	}}
+
+	tests := []struct {
+		name      string
+		mockFunc  func(ctx context.Context, id uuid.UUID, appointment model.Appointment, at time.Time) error
+		createErr error
+		// lapse has the lapse job close the offer after Accept reads it
+		// and before it books the slot.
+		lapse         bool
+		now           time.Time
+		callerID      uuid.UUID
+		expectErr     error
+		expectMessage string
+		expectOffer   string
+		expectEntry   string
+		expectNext    bool
+	}{
+		{
+			name:        "success - patient accepts",
+			now:         offeredAt,
+			callerID:    patientUserID,
+			expectOffer: model.OfferAccepted,
+			expectEntry: model.WaitlistBooked,
+		},
+		{
+			name:        "someone else's offer",
+			now:         offeredAt,
+			callerID:    otherID,
+			expectErr:   model.ErrNotFound,
+			expectOffer: model.OfferPending,
+			expectEntry: model.WaitlistOffered,
+		},
+		{
+			name:        "hold over before the lapse job runs",
+			now:         expiresAt,
+			callerID:    patientUserID,
+			expectErr:   model.ErrConflict,
+			expectOffer: model.OfferPending,
+			expectEntry: model.WaitlistOffered,
+		},
+		{
+			name:        "offer lapses while accepting",
+			lapse:       true,
+			now:         expiresAt.Add(-time.Second),
+			callerID:    patientUserID,
+			expectErr:   model.ErrConflict,
+			expectOffer: model.OfferLapsed,
+			expectEntry: model.WaitlistWaiting,
+			expectNext:  true,
+		},
+		{
+			name:          "slot booked another way",
+			createErr:     model.ErrConflict,
+			now:           offeredAt,
+			callerID:      patientUserID,
+			expectErr:     model.ErrConflict,
+			expectMessage: "overlaps",
+			expectOffer:   model.OfferPending,
+			expectEntry:   model.WaitlistOffered,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, id uuid.UUID, appointment model.Appointment, at time.Time) error {
+				return errRepo
+			},
+			now:         offeredAt,
+			callerID:    patientUserID,
+			expectErr:   errRepo,
+			expectOffer: model.OfferPending,
+			expectEntry: model.WaitlistOffered,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &model.WaitlistEntry{ID: entryID, PractitionerID: practitionerID, PatientID: patientID, From: "2026-11-01", To: "2026-11-10",
+				Status: model.WaitlistOffered, CreatedAt: offeredAt.Add(-time.Hour), PatientUserID: &patientUserID}
+			next := &model.WaitlistEntry{ID: nextID, PractitionerID: practitionerID, PatientID: patientID, From: "2026-11-01", To: "2026-11-10",
+				Status: model.WaitlistWaiting, CreatedAt: offeredAt.Add(-time.Minute), PatientUserID: &nextUserID}
+			offer := &model.WaitlistOffer{ID: offerID, EntryID: entryID, PractitionerID: practitionerID, PatientID: patientID, StartsAt: start, EndsAt: start.Add(30 * time.Minute),
+				Status: model.OfferPending, ExpiresAt: expiresAt, CreatedAt: offeredAt, PatientUserID: &patientUserID}
+
+			appointments := &mockAppointmentRepo{schedule: openSchedule(practitionerID), createErr: tt.createErr}
+			repo := &mockWaitlistRepo{
+				entries:         []*model.WaitlistEntry{entry, next},
+				offers:          []*model.WaitlistOffer{offer},
+				appointments:    appointments,
+				acceptOfferFunc: tt.mockFunc,
+			}
+			clock := &fakeClock{t: tt.now}
+			service := NewWaitlistService(repo, appointments, patients, &mockAuditRepo{}, &mockMailer{}, "http://app.test", 2*time.Hour, clock.now)
+			if tt.lapse {
+				repo.acceptOfferFunc = func(ctx context.Context, id uuid.UUID, appointment model.Appointment, at time.Time) error {
+					repo.acceptOfferFunc = nil
+					clock.advance(time.Second)
+					if n, err := service.LapseOffers(ctx); err != nil || n != 1 {
+						t.Fatalf("LapseOffers = %d, %v, want 1", n, err)
+					}
+					return repo.AcceptOffer(ctx, id, appointment, at)
+				}
+			}
+
+			appointment, err := service.Accept(context.Background(), offerID, tt.callerID, "user")
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) || !strings.Contains(err.Error(), tt.expectMessage) {
+					t.Errorf("expected %v (%q), got %v", tt.expectErr, tt.expectMessage, err)
+				}
+				if appointments.created != nil {
+					t.Errorf("booked %+v, want nothing booked", appointments.created)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !appointment.StartsAt.Equal(start) || appointment.PatientID != patientID {
+					t.Errorf("appointment = %+v, want the offered slot for the patient", appointment)
+				}
+				if offer.AppointmentID == nil || *offer.AppointmentID != appointment.ID {
+					t.Errorf("offer = %+v, want it to hold the appointment", offer)
+				}
+				if _, err := service.Accept(context.Background(), offerID, tt.callerID, "user"); !errors.Is(err, model.ErrConflict) {
+					t.Errorf("expected a conflict accepting twice, got %v", err)
+				}
+			}
+
+			if offer.Status != tt.expectOffer || entry.Status != tt.expectEntry {
+				t.Errorf("offer is %s and entry %s, want %s and %s", offer.Status, entry.Status, tt.expectOffer, tt.expectEntry)
+			}
+			if pending := repo.pending(); tt.expectNext && (len(pending) != 1 || pending[0].EntryID != nextID) {
+				t.Errorf("pending offers = %+v, want the slot passed to the next entry", pending)
+			}
+		})
+	}
+}
+
+func TestWaitlistService_SlotFreed(t *testing.T) {
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	entryID, _ := uuid.NewV7()
+
+	start := time.Date(2026, time.November, 3, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2026, time.November, 2, 10, 0, 0, 0, time.UTC)
+	physio := "physiotherapy"
+
+	email := "patient@example.com"
+	patients := &mockPatientRepo{getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+		return &model.Patient{ID: id, FirstName: "Pat", Email: &email}, nil
+	}}
+
+	tests := []struct {
+		name         string
+		mockFunc     func(ctx context.Context, offer model.WaitlistOffer) error
+		entryService *string
+		slotService  *string
+		now          time.Time
+		busy         bool
+		expectOffer  bool
+		expectExpiry time.Time
+	}{
+		{
+			name:         "success - held for the hold period",
+			now:          now,
+			expectOffer:  true,
+			expectExpiry: now.Add(2 * time.Hour),
+		},
+		{
+			name:         "hold ends when the slot starts",
+			now:          start.Add(-30 * time.Minute),
+			expectOffer:  true,
+			expectExpiry: start,
+		},
+		{
+			name: "past slots are not offered",
+			now:  start,
+		},
+		{
+			name: "slots taken again are not offered",
+			now:  now,
+			busy: true,
+		},
+		{
+			name:         "service must match",
+			entryService: &physio,
+			now:          now,
+		},
+		{
+			name:         "slot with the service",
+			entryService: &physio,
+			slotService:  &physio,
+			now:          now,
+			expectOffer:  true,
+			expectExpiry: now.Add(2 * time.Hour),
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, offer model.WaitlistOffer) error {
+				return errRepo
+			},
+			now: now,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &model.WaitlistEntry{ID: entryID, PractitionerID: practitionerID, PatientID: patientID, Service: tt.entryService, From: "2026-11-01", To: "2026-11-10",
+				Status: model.WaitlistWaiting, CreatedAt: now.Add(-time.Hour), PatientUserID: &patientUserID}
+			appointments := &mockAppointmentRepo{schedule: openSchedule(practitionerID)}
+			if tt.busy {
+				appointments.busy = []model.TimeRange{{Start: start, End: start.Add(30 * time.Minute)}}
+			}
+			repo := &mockWaitlistRepo{entries: []*model.WaitlistEntry{entry}, appointments: appointments, createOfferFunc: tt.mockFunc}
+			mail := &mockMailer{}
+			clock := &fakeClock{t: tt.now}
+			service := NewWaitlistService(repo, appointments, patients, &mockAuditRepo{}, mail, "http://app.test", 2*time.Hour, clock.now)
+
+			service.SlotFreed(context.Background(), model.FreedSlot{PractitionerID: practitionerID, StartsAt: start, EndsAt: start.Add(30 * time.Minute), Service: tt.slotService})
+
+			pending := repo.pending()
+			if !tt.expectOffer {
+				if len(pending) != 0 || len(mail.sent) != 0 {
+					t.Errorf("offered %+v and sent %d mails, want no offer", pending, len(mail.sent))
+				}
+				return
+			}
+			if len(pending) != 1 || pending[0].EntryID != entryID {
+				t.Fatalf("pending offers = %+v, want one to the entry", pending)
+			}
+			if !pending[0].ExpiresAt.Equal(tt.expectExpiry) {
+				t.Errorf("offer expires at %v, want %v", pending[0].ExpiresAt, tt.expectExpiry)
+			}
+			if len(mail.sent) != 1 {
+				t.Errorf("expected the patient to be told about the offer, sent %d mails", len(mail.sent))
+			}
+		})
+	}
+}
+
+func TestWaitlistService_Leave(t *testing.T) {
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	patientUserID, _ := uuid.NewV7()
+	secondUserID, _ := uuid.NewV7()
+	otherID, _ := uuid.NewV7()
+	firstID, _ := uuid.NewV7()
+	secondID, _ := uuid.NewV7()
+	offerID, _ := uuid.NewV7()
+
+	start := time.Date(2026, time.November, 3, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2026, time.November, 2, 10, 0, 0, 0, time.UTC)
+
+	email := "patient@example.com"
+	patients := &mockPatientRepo{getByIDFunc: func(ctx context.Context, id uuid.UUID) (*model.Patient, error) {
+		return &model.Patient{ID: id, FirstName: "Pat", Email: &email}, nil
+	}}
+
+	tests := []struct {
+		name        string
+		mockFunc    func(ctx context.Context, id uuid.UUID) error
+		callerID    uuid.UUID
+		expectErr   error
+		expectEntry string
+		expectNext  bool
+	}{
+		{
+			name:        "success - offer passed on",
+			callerID:    patientUserID,
+			expectEntry: model.WaitlistCancelled,
+			expectNext:  true,
+		},
+		{
+			name:        "someone else's entry",
+			callerID:    otherID,
+			expectErr:   model.ErrNotFound,
+			expectEntry: model.WaitlistOffered,
+		},
+		{
+			name: "already left",
+			mockFunc: func(ctx context.Context, id uuid.UUID) error {
+				return model.ErrConflict
+			},
+			callerID:    patientUserID,
+			expectErr:   model.ErrConflict,
+			expectEntry: model.WaitlistWaiting,
+		},
+		{
+			name: "repo error",
+			mockFunc: func(ctx context.Context, id uuid.UUID) error {
+				return errRepo
+			},
+			callerID:    patientUserID,
+			expectErr:   errRepo,
+			expectEntry: model.WaitlistWaiting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &model.WaitlistEntry{ID: firstID, PractitionerID: practitionerID, PatientID: patientID, From: "2026-11-01", To: "2026-11-10",
+				Status: model.WaitlistOffered, CreatedAt: now.Add(-time.Hour), PatientUserID: &patientUserID}
+			second := &model.WaitlistEntry{ID: secondID, PractitionerID: practitionerID, PatientID: patientID, From: "2026-11-01", To: "2026-11-10",
+				Status: model.WaitlistWaiting, CreatedAt: now.Add(-time.Minute), PatientUserID: &secondUserID}
+			offer := &model.WaitlistOffer{ID: offerID, EntryID: firstID, PractitionerID: practitionerID, PatientID: patientID, StartsAt: start, EndsAt: start.Add(30 * time.Minute),
+				Status: model.OfferPending, ExpiresAt: now.Add(2 * time.Hour), CreatedAt: now, PatientUserID: &patientUserID}
+
+			appointments := &mockAppointmentRepo{schedule: openSchedule(practitionerID)}
+			repo := &mockWaitlistRepo{
+				entries:      []*model.WaitlistEntry{first, second},
+				offers:       []*model.WaitlistOffer{offer},
+				appointments: appointments,
+				cancelFunc:   tt.mockFunc,
+			}
+			clock := &fakeClock{t: now}
+			service := NewWaitlistService(repo, appointments, patients, &mockAuditRepo{}, &mockMailer{}, "http://app.test", 2*time.Hour, clock.now)
+
+			err := service.Leave(context.Background(), firstID, tt.callerID, "user")
+
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("expected %v, got %v", tt.expectErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if first.Status != tt.expectEntry {
+				t.Errorf("entry is %s, want %s", first.Status, tt.expectEntry)
+			}
+			if pending := repo.pending(); tt.expectNext && (len(pending) != 1 || pending[0].EntryID != secondID) {
+				t.Errorf("pending offers = %+v, want the slot passed to the second entry", pending)
+			}
+		})
+	}
+}
+
+// recordingWaitlist records the slots an AppointmentService frees and holds
+// the slots it is given.
+type recordingWaitlist struct {
+	freed []model.FreedSlot
+	held  []model.TimeRange
+}
+
+func (w *recordingWaitlist) SlotFreed(ctx context.Context, slot model.FreedSlot) {
+	w.freed = append(w.freed, slot)
+}
+
+func (w *recordingWaitlist) Holds(ctx context.Context, practitionerID uuid.UUID, from, to time.Time) ([]model.TimeRange, error) {
+	return w.held, nil
+}
+
+func TestAppointmentService_Waitlist(t *testing.T) {
+	practitionerID, _ := uuid.NewV7()
+	patientID, _ := uuid.NewV7()
+	adminID, _ := uuid.NewV7()
+	start := nextSlot()
+
+	t.Run("cancelling frees the slot", func(t *testing.T) {
+		id, _ := uuid.NewV7()
+		repo := &mockAppointmentRepo{appointment: &model.Appointment{
+			ID: id, PractitionerID: practitionerID, PatientID: patientID, StartsAt: start, EndsAt: start.Add(30 * time.Minute), Status: model.AppointmentBooked,
+		}}
+		waitlist := &recordingWaitlist{}
+		service := NewAppointmentService(repo, &mockPatientRepo{}, &mockPractitionerRepo{}, &mockAuditRepo{}, &mockMailer{}, waitlist)
+
+		data := &model.ChangeAppointmentStatus{Status: model.AppointmentCancelled, Reason: "unwell"}
+		if _, err := service.SetStatus(context.Background(), id, data, adminID, "super_admin"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(waitlist.freed) != 1 || !waitlist.freed[0].StartsAt.Equal(start) {
+			t.Errorf("freed = %+v, want the cancelled slot", waitlist.freed)
+		}
+	})
+
+	t.Run("held slots cannot be booked", func(t *testing.T) {
+		repo := &mockAppointmentRepo{schedule: openSchedule(practitionerID)}
+		waitlist := &recordingWaitlist{held: []model.TimeRange{{Start: start, End: start.Add(30 * time.Minute)}}}
+		service := NewAppointmentService(repo, &mockPatientRepo{}, &mockPractitionerRepo{}, &mockAuditRepo{}, &mockMailer{}, waitlist)
+
+		data := &model.CreateAppointment{PractitionerID: practitionerID, PatientID: patientID, StartsAt: start}
+		if _, err := service.Book(context.Background(), data, adminID, "super_admin"); !errors.Is(err, model.ErrConflict) {
+			t.Errorf("expected a conflict, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN purged_at TIMESTAMPTZ;
+
+UPDATE users SET deleted_at = updated_at WHERE is_deleted = true;
+
+CREATE INDEX idx_users_deleted_at ON users (deleted_at) WHERE is_deleted = true AND purged_at IS NULL;
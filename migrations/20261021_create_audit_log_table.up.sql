@@ -0,0 +1,13 @@
+-- actor_id and subject_id carry no foreign keys so entries outlive purged users.
+CREATE TABLE audit_log(
+    id UUID PRIMARY KEY,
+    actor_id UUID,
+    action VARCHAR(100) NOT NULL,
+    subject_type VARCHAR(50) NOT NULL,
+    subject_id UUID,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_log_subject ON audit_log (subject_type, subject_id, created_at);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_id, created_at);
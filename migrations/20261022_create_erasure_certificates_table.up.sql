@@ -0,0 +1,9 @@
+CREATE TABLE erasure_certificates(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id),
+    pseudonym VARCHAR(64) NOT NULL,
+    erased_fields TEXT[] NOT NULL,
+    requested_by UUID NOT NULL,
+    erased_at TIMESTAMPTZ NOT NULL,
+    signature TEXT NOT NULL
+);
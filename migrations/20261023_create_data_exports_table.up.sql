@@ -0,0 +1,15 @@
+CREATE TABLE data_exports(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    token_hash TEXT NOT NULL UNIQUE,
+    file_path TEXT,
+    error TEXT,
+    expires_at TIMESTAMPTZ,
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_data_exports_user_created ON data_exports (user_id, created_at);
+CREATE INDEX idx_data_exports_status ON data_exports (status);
@@ -0,0 +1,16 @@
+CREATE TABLE email_change_requests(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email VARCHAR(150) NOT NULL,
+    new_email VARCHAR(150) NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    confirmed_at TIMESTAMPTZ,
+    revert_token_hash TEXT UNIQUE,
+    revert_expires_at TIMESTAMPTZ,
+    reverted_at TIMESTAMPTZ,
+    cancelled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_email_change_requests_user ON email_change_requests (user_id);
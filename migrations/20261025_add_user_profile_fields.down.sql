@@ -0,0 +1,17 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_sex_check;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_phone_e164;
+
+ALTER TABLE users
+    DROP COLUMN avatar_updated_at,
+    DROP COLUMN avatar_content_type,
+    DROP COLUMN address_country,
+    DROP COLUMN address_postal_code,
+    DROP COLUMN address_region,
+    DROP COLUMN address_city,
+    DROP COLUMN address_line2,
+    DROP COLUMN address_line1,
+    DROP COLUMN preferred_language,
+    DROP COLUMN gender,
+    DROP COLUMN sex,
+    DROP COLUMN date_of_birth,
+    DROP COLUMN phone;
@@ -0,0 +1,17 @@
+ALTER TABLE users
+    ADD COLUMN phone VARCHAR(16),
+    ADD COLUMN date_of_birth DATE,
+    ADD COLUMN sex VARCHAR(10),
+    ADD COLUMN gender VARCHAR(50),
+    ADD COLUMN preferred_language VARCHAR(35),
+    ADD COLUMN address_line1 VARCHAR(100),
+    ADD COLUMN address_line2 VARCHAR(100),
+    ADD COLUMN address_city VARCHAR(100),
+    ADD COLUMN address_region VARCHAR(100),
+    ADD COLUMN address_postal_code VARCHAR(20),
+    ADD COLUMN address_country CHAR(2),
+    ADD COLUMN avatar_content_type VARCHAR(50),
+    ADD COLUMN avatar_updated_at TIMESTAMPTZ;
+
+ALTER TABLE users ADD CONSTRAINT users_phone_e164 CHECK (phone ~ '^\+[1-9][0-9]{1,14}$');
+ALTER TABLE users ADD CONSTRAINT users_sex_check CHECK (sex IN ('male', 'female', 'other', 'unknown'));
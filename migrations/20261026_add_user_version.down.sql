@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_users_version ON users;
+DROP FUNCTION IF EXISTS users_bump_version();
+
+ALTER TABLE users DROP COLUMN version;
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+-- Every change to a user row yields a new version, so the ETag derived from
+-- it changes whichever code path updated the row.
+CREATE OR REPLACE FUNCTION users_bump_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_users_version
+BEFORE UPDATE ON users
+FOR EACH ROW
+EXECUTE FUNCTION users_bump_version();
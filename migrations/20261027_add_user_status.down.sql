@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_users_status;
+
+ALTER TABLE users
+    DROP COLUMN status_changed_by,
+    DROP COLUMN status_changed_at,
+    DROP COLUMN status_until,
+    DROP COLUMN status_reason,
+    DROP COLUMN status;
+
+DROP TYPE account_status;
@@ -0,0 +1,10 @@
+CREATE TYPE account_status AS ENUM('active', 'suspended', 'locked', 'pending');
+
+ALTER TABLE users
+    ADD COLUMN status account_status NOT NULL DEFAULT 'active',
+    ADD COLUMN status_reason VARCHAR(500),
+    ADD COLUMN status_until TIMESTAMPTZ,
+    ADD COLUMN status_changed_at TIMESTAMPTZ,
+    ADD COLUMN status_changed_by UUID;
+
+CREATE INDEX idx_users_status ON users (status) WHERE status <> 'active';
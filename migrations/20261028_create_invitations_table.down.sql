@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS invitations;
+
+ALTER TABLE users DROP COLUMN IF EXISTS organisation_id;
+
+DROP TABLE IF EXISTS organisations;
@@ -0,0 +1,32 @@
+CREATE TABLE organisations(
+    id UUID PRIMARY KEY,
+    name VARCHAR(150) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE users ADD COLUMN organisation_id UUID REFERENCES organisations(id) ON DELETE SET NULL;
+
+-- An invitation holds the invitee's details until they accept it, when the
+-- user row is created. accepted_user_id cascades so purging the user removes
+-- the copy of their name and email kept here.
+CREATE TABLE invitations(
+    id UUID PRIMARY KEY,
+    email VARCHAR(150) NOT NULL,
+    first_name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    role role NOT NULL DEFAULT 'user',
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    accepted_at TIMESTAMPTZ,
+    accepted_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- At most one open invitation per address.
+CREATE UNIQUE INDEX idx_invitations_open_email ON invitations (email)
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
+CREATE INDEX idx_invitations_created ON invitations (created_at DESC);
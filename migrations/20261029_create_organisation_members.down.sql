@@ -0,0 +1,19 @@
+DROP POLICY IF EXISTS invitations_tenant ON invitations;
+ALTER TABLE invitations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE invitations DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS users_insert ON users;
+DROP POLICY IF EXISTS users_tenant_select ON users;
+DROP POLICY IF EXISTS users_tenant_update ON users;
+DROP POLICY IF EXISTS users_tenant_delete ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+DROP FUNCTION IF EXISTS app_organisation_id();
+
+UPDATE users SET role = 'admin'
+    WHERE role = 'user' AND id IN (SELECT user_id FROM organisation_members WHERE role = 'admin');
+
+DROP TABLE IF EXISTS organisation_members;
+
+DROP TYPE IF EXISTS membership_role;
@@ -0,0 +1,77 @@
+CREATE TYPE membership_role AS ENUM('admin', 'member');
+
+CREATE TABLE organisation_members(
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role membership_role NOT NULL DEFAULT 'member',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (organisation_id, user_id)
+);
+
+CREATE INDEX idx_organisation_members_user ON organisation_members (user_id);
+
+-- Accounts from before organisations existed are moved into a default
+-- organisation so their admins keep seeing them.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE organisation_id IS NULL AND role <> 'super_admin') THEN
+        INSERT INTO organisations(id, name) VALUES (gen_random_uuid(), 'Default')
+            ON CONFLICT (name) DO NOTHING;
+        UPDATE users SET organisation_id = (SELECT id FROM organisations WHERE name = 'Default')
+            WHERE organisation_id IS NULL AND role <> 'super_admin';
+    END IF;
+END $$;
+
+-- users.organisation_id becomes the organisation selected at login. Global
+-- admins become admins of that organisation; only super_admin stays global.
+INSERT INTO organisation_members(organisation_id, user_id, role)
+    SELECT organisation_id, id, CASE WHEN role = 'admin' THEN 'admin'::membership_role ELSE 'member' END
+    FROM users WHERE organisation_id IS NOT NULL;
+
+UPDATE users SET role = 'user' WHERE role = 'admin';
+
+-- Row-level security. The application sets app.organisation_id for the
+-- transactions of requests made within an organisation; without it, as for
+-- super admins, background jobs and sign-in, every row is visible. Policies
+-- do not apply to superusers, so the application must connect as an
+-- ordinary role for them to take effect.
+CREATE FUNCTION app_organisation_id() RETURNS UUID
+    LANGUAGE sql STABLE
+    AS $$ SELECT NULLIF(current_setting('app.organisation_id', true), '')::uuid $$;
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+
+-- New accounts have no membership until later in the same transaction, so
+-- plain inserts are not restricted. An insert with RETURNING or ON CONFLICT DO
+-- UPDATE also checks the new row against users_tenant_select, which fails in
+-- an organisation until the membership exists, so such statements cannot
+-- create users within one.
+CREATE POLICY users_insert ON users FOR INSERT WITH CHECK (true);
+CREATE POLICY users_tenant_select ON users FOR SELECT USING (
+    app_organisation_id() IS NULL OR EXISTS (
+        SELECT 1 FROM organisation_members m WHERE m.user_id = users.id AND m.organisation_id = app_organisation_id()
+    )
+);
+CREATE POLICY users_tenant_update ON users FOR UPDATE USING (
+    app_organisation_id() IS NULL OR EXISTS (
+        SELECT 1 FROM organisation_members m WHERE m.user_id = users.id AND m.organisation_id = app_organisation_id()
+    )
+);
+CREATE POLICY users_tenant_delete ON users FOR DELETE USING (
+    app_organisation_id() IS NULL OR EXISTS (
+        SELECT 1 FROM organisation_members m WHERE m.user_id = users.id AND m.organisation_id = app_organisation_id()
+    )
+);
+
+ALTER TABLE organisation_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE organisation_members FORCE ROW LEVEL SECURITY;
+CREATE POLICY organisation_members_tenant ON organisation_members USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE invitations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invitations FORCE ROW LEVEL SECURITY;
+CREATE POLICY invitations_tenant ON invitations USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
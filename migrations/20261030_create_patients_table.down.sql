@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS patient_identifiers;
+DROP TABLE IF EXISTS patients;
+DROP TABLE IF EXISTS patient_mrn_sequences;
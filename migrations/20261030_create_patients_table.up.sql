@@ -0,0 +1,80 @@
+-- MRNs are numbered per organisation. The counter row is locked by the
+-- upsert that takes the next value, so concurrent registrations never share
+-- a number.
+CREATE TABLE patient_mrn_sequences(
+    organisation_id UUID PRIMARY KEY REFERENCES organisations(id) ON DELETE CASCADE,
+    last_value BIGINT NOT NULL
+);
+
+CREATE TABLE patients(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    mrn VARCHAR(20) NOT NULL,
+    first_name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    date_of_birth DATE NOT NULL,
+    sex VARCHAR(10) NOT NULL,
+    gender VARCHAR(50),
+    phone VARCHAR(16),
+    email VARCHAR(150),
+    preferred_language VARCHAR(35),
+    address_line1 VARCHAR(100),
+    address_line2 VARCHAR(100),
+    address_city VARCHAR(100),
+    address_region VARCHAR(100),
+    address_postal_code VARCHAR(20),
+    address_country CHAR(2),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    is_deleted BOOLEAN NOT NULL DEFAULT false,
+    deleted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    version BIGINT NOT NULL DEFAULT 1,
+    UNIQUE (organisation_id, mrn),
+    CONSTRAINT patients_phone_e164 CHECK (phone ~ '^\+[1-9][0-9]{1,14}$'),
+    CONSTRAINT patients_sex_check CHECK (sex IN ('male', 'female', 'other', 'unknown'))
+);
+
+-- A login is linked to at most one patient record per organisation.
+CREATE UNIQUE INDEX idx_patients_user ON patients (organisation_id, user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_patients_name ON patients (organisation_id, last_name, first_name);
+
+CREATE TRIGGER trg_patients_updated_at
+BEFORE UPDATE ON patients
+FOR EACH ROW
+EXECUTE FUNCTION users_set_updated_at();
+
+CREATE TRIGGER trg_patients_version
+BEFORE UPDATE ON patients
+FOR EACH ROW
+EXECUTE FUNCTION users_bump_version();
+
+CREATE TABLE patient_identifiers(
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    system VARCHAR(100) NOT NULL,
+    value VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (organisation_id, system, value),
+    UNIQUE (patient_id, system)
+);
+
+ALTER TABLE patient_mrn_sequences ENABLE ROW LEVEL SECURITY;
+ALTER TABLE patient_mrn_sequences FORCE ROW LEVEL SECURITY;
+CREATE POLICY patient_mrn_sequences_tenant ON patient_mrn_sequences USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE patients ENABLE ROW LEVEL SECURITY;
+ALTER TABLE patients FORCE ROW LEVEL SECURITY;
+CREATE POLICY patients_tenant ON patients USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE patient_identifiers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE patient_identifiers FORCE ROW LEVEL SECURITY;
+CREATE POLICY patient_identifiers_tenant ON patient_identifiers USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS practitioner_licences;
+DROP TABLE IF EXISTS practitioner_facilities;
+DROP TABLE IF EXISTS practitioners;
+DROP TABLE IF EXISTS facilities;
@@ -0,0 +1,92 @@
+CREATE TABLE facilities(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    address_line1 VARCHAR(100) NOT NULL,
+    address_line2 VARCHAR(100),
+    address_city VARCHAR(100) NOT NULL,
+    address_region VARCHAR(100),
+    address_postal_code VARCHAR(20),
+    address_country CHAR(2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (organisation_id, name)
+);
+
+CREATE TABLE practitioners(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    profession VARCHAR(20) NOT NULL,
+    npi CHAR(10),
+    specialties TEXT[] NOT NULL DEFAULT '{}',
+    is_deleted BOOLEAN NOT NULL DEFAULT false,
+    deleted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    version BIGINT NOT NULL DEFAULT 1,
+    CONSTRAINT practitioners_profession_check CHECK (profession IN ('doctor', 'nurse', 'midwife', 'pharmacist', 'therapist', 'other'))
+);
+
+-- A member is registered once per organisation, and an NPI belongs to one
+-- practitioner. Deleted records do not count.
+CREATE UNIQUE INDEX idx_practitioners_user ON practitioners (organisation_id, user_id) WHERE is_deleted = false;
+CREATE UNIQUE INDEX idx_practitioners_npi ON practitioners (organisation_id, npi) WHERE is_deleted = false AND npi IS NOT NULL;
+CREATE INDEX idx_practitioners_specialties ON practitioners USING GIN (specialties);
+
+CREATE TRIGGER trg_practitioners_updated_at
+BEFORE UPDATE ON practitioners
+FOR EACH ROW
+EXECUTE FUNCTION users_set_updated_at();
+
+CREATE TRIGGER trg_practitioners_version
+BEFORE UPDATE ON practitioners
+FOR EACH ROW
+EXECUTE FUNCTION users_bump_version();
+
+CREATE TABLE practitioner_facilities(
+    practitioner_id UUID NOT NULL REFERENCES practitioners(id) ON DELETE CASCADE,
+    facility_id UUID NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    PRIMARY KEY (practitioner_id, facility_id)
+);
+
+CREATE INDEX idx_practitioner_facilities_facility ON practitioner_facilities (facility_id);
+
+CREATE TABLE practitioner_licences(
+    id UUID PRIMARY KEY,
+    practitioner_id UUID NOT NULL REFERENCES practitioners(id) ON DELETE CASCADE,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    number VARCHAR(50) NOT NULL,
+    jurisdiction VARCHAR(6) NOT NULL,
+    expires_on DATE NOT NULL,
+    expiry_flagged_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (organisation_id, jurisdiction, number)
+);
+
+-- The expiry check looks for licences that have not been flagged yet.
+CREATE INDEX idx_practitioner_licences_expiry ON practitioner_licences (expires_on) WHERE expiry_flagged_at IS NULL;
+
+ALTER TABLE facilities ENABLE ROW LEVEL SECURITY;
+ALTER TABLE facilities FORCE ROW LEVEL SECURITY;
+CREATE POLICY facilities_tenant ON facilities USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE practitioners ENABLE ROW LEVEL SECURITY;
+ALTER TABLE practitioners FORCE ROW LEVEL SECURITY;
+CREATE POLICY practitioners_tenant ON practitioners USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE practitioner_facilities ENABLE ROW LEVEL SECURITY;
+ALTER TABLE practitioner_facilities FORCE ROW LEVEL SECURITY;
+CREATE POLICY practitioner_facilities_tenant ON practitioner_facilities USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE practitioner_licences ENABLE ROW LEVEL SECURITY;
+ALTER TABLE practitioner_licences FORCE ROW LEVEL SECURITY;
+CREATE POLICY practitioner_licences_tenant ON practitioner_licences USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS appointments;
+DROP TABLE IF EXISTS availability_exceptions;
+DROP TABLE IF EXISTS schedule_windows;
+DROP TABLE IF EXISTS practitioner_schedules;
@@ -0,0 +1,101 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- The weekly availability of a practitioner: the hours they see patients on
+-- each weekday, in their time zone, cut into slots of slot_minutes.
+CREATE TABLE practitioner_schedules(
+    practitioner_id UUID PRIMARY KEY REFERENCES practitioners(id) ON DELETE CASCADE,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    timezone VARCHAR(64) NOT NULL,
+    slot_minutes INT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT practitioner_schedules_slot_check CHECK (slot_minutes BETWEEN 5 AND 480)
+);
+
+CREATE TABLE schedule_windows(
+    practitioner_id UUID NOT NULL REFERENCES practitioner_schedules(practitioner_id) ON DELETE CASCADE,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    PRIMARY KEY (practitioner_id, weekday, start_time),
+    CONSTRAINT schedule_windows_weekday_check CHECK (weekday BETWEEN 0 AND 6),
+    CONSTRAINT schedule_windows_time_check CHECK (end_time > start_time)
+);
+
+-- Exceptions take a practitioner out of their schedule, e.g. for holidays or
+-- leave.
+CREATE TABLE availability_exceptions(
+    id UUID PRIMARY KEY,
+    practitioner_id UUID NOT NULL REFERENCES practitioners(id) ON DELETE CASCADE,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason VARCHAR(200) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT availability_exceptions_time_check CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_availability_exceptions_range ON availability_exceptions USING gist (practitioner_id, tstzrange(starts_at, ends_at));
+
+CREATE TABLE appointments(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    practitioner_id UUID NOT NULL REFERENCES practitioners(id) ON DELETE RESTRICT,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'booked',
+    reason VARCHAR(500),
+    cancel_reason VARCHAR(500),
+    cancelled_at TIMESTAMPTZ,
+    reschedule_reason VARCHAR(500),
+    rescheduled_at TIMESTAMPTZ,
+    checked_in_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT appointments_status_check CHECK (status IN ('booked', 'checked_in', 'completed', 'no_show', 'cancelled')),
+    CONSTRAINT appointments_time_check CHECK (ends_at > starts_at),
+    -- Neither a practitioner nor a patient can be in two appointments at
+    -- once. Cancelled appointments free their time.
+    CONSTRAINT appointments_practitioner_overlap EXCLUDE USING gist (
+        practitioner_id WITH =, tstzrange(starts_at, ends_at) WITH &&
+    ) WHERE (status <> 'cancelled'),
+    CONSTRAINT appointments_patient_overlap EXCLUDE USING gist (
+        patient_id WITH =, tstzrange(starts_at, ends_at) WITH &&
+    ) WHERE (status <> 'cancelled')
+);
+
+CREATE INDEX idx_appointments_org_starts_at ON appointments (organisation_id, starts_at);
+CREATE INDEX idx_appointments_patient ON appointments (patient_id, starts_at);
+
+CREATE TRIGGER trg_appointments_updated_at
+BEFORE UPDATE ON appointments
+FOR EACH ROW
+EXECUTE FUNCTION users_set_updated_at();
+
+ALTER TABLE practitioner_schedules ENABLE ROW LEVEL SECURITY;
+ALTER TABLE practitioner_schedules FORCE ROW LEVEL SECURITY;
+CREATE POLICY practitioner_schedules_tenant ON practitioner_schedules USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE schedule_windows ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schedule_windows FORCE ROW LEVEL SECURITY;
+CREATE POLICY schedule_windows_tenant ON schedule_windows USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE availability_exceptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE availability_exceptions FORCE ROW LEVEL SECURITY;
+CREATE POLICY availability_exceptions_tenant ON availability_exceptions USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE appointments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE appointments FORCE ROW LEVEL SECURITY;
+CREATE POLICY appointments_tenant ON appointments USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
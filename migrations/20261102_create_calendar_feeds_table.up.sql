@@ -0,0 +1,11 @@
+CREATE TABLE calendar_feeds(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A user has at most one working feed URL; creating a new one revokes the old.
+CREATE UNIQUE INDEX idx_calendar_feeds_user_active ON calendar_feeds (user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,67 @@
+-- The kind of visit an appointment is for, e.g. "physiotherapy", which
+-- waitlist entries can ask for.
+ALTER TABLE appointments ADD COLUMN service VARCHAR(100);
+
+-- Patients waiting for a slot with a practitioner between two dates of the
+-- practitioner's time zone, optionally for one service.
+CREATE TABLE waitlist_entries(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    practitioner_id UUID NOT NULL REFERENCES practitioners(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    service VARCHAR(100),
+    from_date DATE NOT NULL,
+    to_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT waitlist_entries_status_check CHECK (status IN ('waiting', 'offered', 'booked', 'cancelled')),
+    CONSTRAINT waitlist_entries_date_check CHECK (to_date >= from_date)
+);
+
+-- Offers go to the waiting entries of a practitioner in the order they joined.
+CREATE INDEX idx_waitlist_entries_queue ON waitlist_entries (practitioner_id, created_at, id) WHERE status = 'waiting';
+CREATE INDEX idx_waitlist_entries_patient ON waitlist_entries (patient_id);
+
+-- A freed slot offered to a waitlist entry. The slot is held for the patient
+-- until expires_at.
+CREATE TABLE waitlist_offers(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    entry_id UUID NOT NULL REFERENCES waitlist_entries(id) ON DELETE CASCADE,
+    practitioner_id UUID NOT NULL REFERENCES practitioners(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    service VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMPTZ NOT NULL,
+    appointment_id UUID REFERENCES appointments(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    responded_at TIMESTAMPTZ,
+    CONSTRAINT waitlist_offers_status_check CHECK (status IN ('pending', 'accepted', 'declined', 'lapsed')),
+    CONSTRAINT waitlist_offers_time_check CHECK (ends_at > starts_at),
+    -- An entry is offered a slot at most once.
+    CONSTRAINT waitlist_offers_entry_slot UNIQUE (entry_id, starts_at)
+);
+
+-- A slot is held by at most one offer at a time.
+CREATE UNIQUE INDEX idx_waitlist_offers_pending_slot ON waitlist_offers (practitioner_id, starts_at) WHERE status = 'pending';
+CREATE INDEX idx_waitlist_offers_pending_expiry ON waitlist_offers (expires_at) WHERE status = 'pending';
+
+CREATE TRIGGER trg_waitlist_entries_updated_at
+BEFORE UPDATE ON waitlist_entries
+FOR EACH ROW
+EXECUTE FUNCTION users_set_updated_at();
+
+ALTER TABLE waitlist_entries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE waitlist_entries FORCE ROW LEVEL SECURITY;
+CREATE POLICY waitlist_entries_tenant ON waitlist_entries USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE waitlist_offers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE waitlist_offers FORCE ROW LEVEL SECURITY;
+CREATE POLICY waitlist_offers_tenant ON waitlist_offers USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
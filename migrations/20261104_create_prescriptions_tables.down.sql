@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS prescription_refills;
+DROP TABLE IF EXISTS prescriptions;
+DROP TABLE IF EXISTS medications;
@@ -0,0 +1,78 @@
+-- The medication catalogue, loaded from an RxNorm concept file. It is shared
+-- by every organisation.
+CREATE TABLE medications(
+    rxcui VARCHAR(20) PRIMARY KEY,
+    name TEXT NOT NULL,
+    term_type VARCHAR(20) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_medications_name_trgm ON medications USING gin (name gin_trgm_ops);
+
+CREATE TABLE prescriptions(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    prescriber_id UUID NOT NULL REFERENCES practitioners(id) ON DELETE RESTRICT,
+    rxcui VARCHAR(20) REFERENCES medications(rxcui),
+    drug TEXT NOT NULL,
+    strength VARCHAR(50),
+    dose VARCHAR(100) NOT NULL,
+    route VARCHAR(20) NOT NULL,
+    frequency VARCHAR(100) NOT NULL,
+    duration_days INT,
+    quantity INT NOT NULL,
+    refills INT NOT NULL DEFAULT 0,
+    refills_remaining INT NOT NULL DEFAULT 0,
+    instructions VARCHAR(500),
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    status_reason VARCHAR(500),
+    status_changed_at TIMESTAMPTZ,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT prescriptions_status_check CHECK (status IN ('active', 'on_hold', 'completed', 'cancelled')),
+    CONSTRAINT prescriptions_quantity_check CHECK (quantity > 0),
+    CONSTRAINT prescriptions_duration_check CHECK (duration_days IS NULL OR duration_days > 0),
+    CONSTRAINT prescriptions_refills_check CHECK (refills_remaining BETWEEN 0 AND refills)
+);
+
+CREATE INDEX idx_prescriptions_patient ON prescriptions (patient_id, created_at);
+CREATE INDEX idx_prescriptions_prescriber ON prescriptions (prescriber_id, created_at);
+
+-- A patient's request for a refill of a prescription, approved or denied by
+-- a prescriber.
+CREATE TABLE prescription_refills(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    prescription_id UUID NOT NULL REFERENCES prescriptions(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    note VARCHAR(500),
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_by UUID REFERENCES practitioners(id) ON DELETE RESTRICT,
+    decision_note VARCHAR(500),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    decided_at TIMESTAMPTZ,
+    CONSTRAINT prescription_refills_status_check CHECK (status IN ('pending', 'approved', 'denied'))
+);
+
+-- A prescription has at most one pending refill request.
+CREATE UNIQUE INDEX idx_prescription_refills_pending ON prescription_refills (prescription_id) WHERE status = 'pending';
+CREATE INDEX idx_prescription_refills_prescription ON prescription_refills (prescription_id, created_at);
+
+CREATE TRIGGER trg_prescriptions_updated_at
+BEFORE UPDATE ON prescriptions
+FOR EACH ROW
+EXECUTE FUNCTION users_set_updated_at();
+
+ALTER TABLE prescriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE prescriptions FORCE ROW LEVEL SECURITY;
+CREATE POLICY prescriptions_tenant ON prescriptions USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE prescription_refills ENABLE ROW LEVEL SECURITY;
+ALTER TABLE prescription_refills FORCE ROW LEVEL SECURITY;
+CREATE POLICY prescription_refills_tenant ON prescription_refills USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE prescriptions DROP COLUMN IF EXISTS override_reason;
+DROP TABLE IF EXISTS drug_interactions;
+DROP TABLE IF EXISTS patient_allergies;
@@ -0,0 +1,49 @@
+-- A patient's recorded allergies. Entries are never deleted; one recorded
+-- by mistake is marked entered_in_error.
+CREATE TABLE patient_allergies(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    allergen VARCHAR(200) NOT NULL,
+    rxcui VARCHAR(20) REFERENCES medications(rxcui),
+    reaction VARCHAR(500),
+    severity VARCHAR(10) NOT NULL,
+    verification VARCHAR(20) NOT NULL DEFAULT 'unconfirmed',
+    recorded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT patient_allergies_severity_check CHECK (severity IN ('mild', 'moderate', 'severe')),
+    CONSTRAINT patient_allergies_verification_check CHECK (verification IN ('unconfirmed', 'confirmed', 'refuted', 'entered_in_error'))
+);
+
+CREATE INDEX idx_patient_allergies_patient ON patient_allergies (patient_id, created_at);
+
+CREATE TRIGGER trg_patient_allergies_updated_at
+BEFORE UPDATE ON patient_allergies
+FOR EACH ROW
+EXECUTE FUNCTION users_set_updated_at();
+
+ALTER TABLE patient_allergies ENABLE ROW LEVEL SECURITY;
+ALTER TABLE patient_allergies FORCE ROW LEVEL SECURITY;
+CREATE POLICY patient_allergies_tenant ON patient_allergies USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+-- The interaction dataset, loaded from a CSV file and shared by every
+-- organisation. Each pair of substances is stored once, in name order.
+CREATE TABLE drug_interactions(
+    substance_a VARCHAR(100) NOT NULL,
+    substance_b VARCHAR(100) NOT NULL,
+    severity VARCHAR(10) NOT NULL,
+    description VARCHAR(500) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (substance_a, substance_b),
+    CONSTRAINT drug_interactions_order_check CHECK (substance_a < substance_b),
+    CONSTRAINT drug_interactions_severity_check CHECK (severity IN ('low', 'moderate', 'high'))
+);
+
+CREATE INDEX idx_drug_interactions_b ON drug_interactions (substance_b);
+
+-- The reason a prescriber gave for prescribing despite a high-severity
+-- conflict.
+ALTER TABLE prescriptions ADD COLUMN override_reason VARCHAR(500);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lab_observations;
+DROP TABLE IF EXISTS lab_reconciliation;
+DROP TABLE IF EXISTS lab_messages;
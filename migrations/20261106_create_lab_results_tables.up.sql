@@ -0,0 +1,86 @@
+-- Lab result messages (HL7 v2 ORU^R01) accepted from a lab system, kept as
+-- received. A sender's control ID identifies a message, so a message sent
+-- again is recognised and acknowledged without being stored twice.
+CREATE TABLE lab_messages(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    control_id VARCHAR(199) NOT NULL,
+    sending_application VARCHAR(227) NOT NULL DEFAULT '',
+    sending_facility VARCHAR(227) NOT NULL DEFAULT '',
+    message_type VARCHAR(20) NOT NULL,
+    raw TEXT NOT NULL,
+    received_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (organisation_id, sending_application, sending_facility, control_id)
+);
+
+-- A patient named in a message who could not be matched to a patient record.
+-- Their results wait here until someone picks the patient or discards them.
+CREATE TABLE lab_reconciliation(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    message_id UUID NOT NULL REFERENCES lab_messages(id) ON DELETE CASCADE,
+    identifiers JSONB NOT NULL DEFAULT '[]',
+    family_name VARCHAR(100) NOT NULL DEFAULT '',
+    given_name VARCHAR(100) NOT NULL DEFAULT '',
+    date_of_birth DATE,
+    reason VARCHAR(200) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    patient_id UUID REFERENCES patients(id) ON DELETE SET NULL,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ,
+    note VARCHAR(500),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT lab_reconciliation_status_check CHECK (status IN ('pending', 'resolved', 'discarded'))
+);
+
+CREATE INDEX idx_lab_reconciliation_status ON lab_reconciliation (organisation_id, status, created_at);
+
+-- One OBX result of a message, with the OBR order it was reported under.
+-- Results of an unmatched patient have no patient until their
+-- reconciliation item is resolved.
+CREATE TABLE lab_observations(
+    id UUID PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    message_id UUID NOT NULL REFERENCES lab_messages(id) ON DELETE CASCADE,
+    patient_id UUID REFERENCES patients(id) ON DELETE CASCADE,
+    reconciliation_id UUID REFERENCES lab_reconciliation(id) ON DELETE CASCADE,
+    placer_order_number VARCHAR(199),
+    filler_order_number VARCHAR(199),
+    service_code VARCHAR(50) NOT NULL,
+    service_name TEXT,
+    code VARCHAR(50) NOT NULL,
+    code_system VARCHAR(50),
+    name TEXT NOT NULL,
+    value_type VARCHAR(3) NOT NULL DEFAULT '',
+    value TEXT,
+    units VARCHAR(50),
+    reference_range VARCHAR(100),
+    abnormal_flags TEXT[] NOT NULL DEFAULT '{}',
+    result_status VARCHAR(1) NOT NULL,
+    observed_at TIMESTAMPTZ,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT lab_observations_subject_check CHECK (patient_id IS NOT NULL OR reconciliation_id IS NOT NULL)
+);
+
+CREATE INDEX idx_lab_observations_patient ON lab_observations (patient_id, observed_at);
+CREATE INDEX idx_lab_observations_reconciliation ON lab_observations (reconciliation_id);
+
+ALTER TABLE lab_messages ENABLE ROW LEVEL SECURITY;
+ALTER TABLE lab_messages FORCE ROW LEVEL SECURITY;
+CREATE POLICY lab_messages_tenant ON lab_messages USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE lab_reconciliation ENABLE ROW LEVEL SECURITY;
+ALTER TABLE lab_reconciliation FORCE ROW LEVEL SECURITY;
+CREATE POLICY lab_reconciliation_tenant ON lab_reconciliation USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
+
+ALTER TABLE lab_observations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE lab_observations FORCE ROW LEVEL SECURITY;
+CREATE POLICY lab_observations_tenant ON lab_observations USING (
+    app_organisation_id() IS NULL OR organisation_id = app_organisation_id()
+);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_lab_observations_critical;
+
+ALTER TABLE lab_observations
+    DROP CONSTRAINT lab_observations_interpretation_check,
+    DROP COLUMN acknowledged_at,
+    DROP COLUMN acknowledged_by,
+    DROP COLUMN interpretation,
+    DROP COLUMN ordering_practitioner_id,
+    DROP COLUMN ordering_provider;
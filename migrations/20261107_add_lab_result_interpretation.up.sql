@@ -0,0 +1,13 @@
+-- How a result compares with the reference range for the patient's sex and
+-- age, and the practitioner who ordered it. Critical results stay on the
+-- ordering practitioner's list until someone acknowledges them.
+ALTER TABLE lab_observations
+    ADD COLUMN ordering_provider VARCHAR(199),
+    ADD COLUMN ordering_practitioner_id UUID REFERENCES practitioners(id) ON DELETE SET NULL,
+    ADD COLUMN interpretation VARCHAR(10),
+    ADD COLUMN acknowledged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN acknowledged_at TIMESTAMPTZ,
+    ADD CONSTRAINT lab_observations_interpretation_check CHECK (interpretation IN ('low', 'normal', 'high', 'critical'));
+
+CREATE INDEX idx_lab_observations_critical ON lab_observations (organisation_id, created_at)
+    WHERE interpretation = 'critical' AND acknowledged_at IS NULL;
@@ -8,24 +8,30 @@ import (
 	"github.com/google/uuid"
 )
 
+// AccessClaims identify the caller and the organisation the token was issued
+// for. OrganisationID is absent for users acting outside any organisation,
+// such as a super admin working across all of them.
 type AccessClaims struct {
 	UserID uuid.UUID `json:"user_id"`
 	jwt.RegisteredClaims
-	Role string `json:"role"`
+	Role           string     `json:"role"`
+	OrganisationID *uuid.UUID `json:"organisation_id,omitempty"`
 }
 
 type RefreshClaims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Role   string    `json:"role"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Role           string     `json:"role"`
+	OrganisationID *uuid.UUID `json:"organisation_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(key string, userID uuid.UUID, role string) (string, error) {
+func GenerateAccessToken(key string, userID uuid.UUID, role string, orgID *uuid.UUID) (string, error) {
 	accessTokenKey := []byte(key)
 
 	claims := AccessClaims{
-		UserID: userID,
-		Role:   role,
+		UserID:         userID,
+		Role:           role,
+		OrganisationID: orgID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -37,12 +43,13 @@ func GenerateAccessToken(key string, userID uuid.UUID, role string) (string, err
 
 }
 
-func GenerateRefreshToken(key string, userID uuid.UUID, role string) (string, error) {
+func GenerateRefreshToken(key string, userID uuid.UUID, role string, orgID *uuid.UUID) (string, error) {
 	refreshTokenKey := []byte(key)
 
 	claims := RefreshClaims{
-		UserID: userID,
-		Role:   role,
+		UserID:         userID,
+		Role:           role,
+		OrganisationID: orgID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 7)),
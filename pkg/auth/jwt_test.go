@@ -12,7 +12,7 @@ func TestGenerateAccessToken_VerifyAccessToken(t *testing.T) {
 	userID, _ := uuid.NewV7()
 	role := "user"
 
-	token, err := GenerateAccessToken(key, userID, role)
+	token, err := GenerateAccessToken(key, userID, role, nil)
 	if err != nil {
 		t.Fatalf("GenerateAccessToken: %v", err)
 	}
@@ -37,7 +37,7 @@ func TestGenerateRefreshToken_VerifyRefreshToken(t *testing.T) {
 	userID, _ := uuid.NewV7()
 	role := "admin"
 
-	token, err := GenerateRefreshToken(key, userID, role)
+	token, err := GenerateRefreshToken(key, userID, role, nil)
 	if err != nil {
 		t.Fatalf("GenerateRefreshToken: %v", err)
 	}
@@ -57,11 +57,38 @@ func TestGenerateRefreshToken_VerifyRefreshToken(t *testing.T) {
 	}
 }
 
+func TestAccessToken_Organisation(t *testing.T) {
+	key := "test-key"
+	userID, _ := uuid.NewV7()
+	orgID, _ := uuid.NewV7()
+
+	token, err := GenerateAccessToken(key, userID, "admin", &orgID)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	claims, err := VerifyAccessToken(key, token)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken: %v", err)
+	}
+	if claims.OrganisationID == nil || *claims.OrganisationID != orgID {
+		t.Errorf("OrganisationID: got %v want %v", claims.OrganisationID, orgID)
+	}
+
+	token, _ = GenerateAccessToken(key, userID, "super_admin", nil)
+	claims, err = VerifyAccessToken(key, token)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken: %v", err)
+	}
+	if claims.OrganisationID != nil {
+		t.Errorf("OrganisationID: got %v want nil", claims.OrganisationID)
+	}
+}
+
 func TestVerifyAccessToken_Invalid(t *testing.T) {
 	key := "test-key"
 	userID, _ := uuid.NewV7()
 
-	token, _ := GenerateAccessToken(key, userID, "user")
+	token, _ := GenerateAccessToken(key, userID, "user", nil)
 
 	tests := []struct {
 		name string
@@ -87,7 +114,7 @@ func TestVerifyRefreshToken_Invalid(t *testing.T) {
 	key := "test-key"
 	userID, _ := uuid.NewV7()
 
-	token, _ := GenerateRefreshToken(key, userID, "user")
+	token, _ := GenerateRefreshToken(key, userID, "user", nil)
 
 	tests := []struct {
 		name string
@@ -112,8 +139,8 @@ func TestRefreshToken_UniqueJti(t *testing.T) {
 	key := "test-key"
 	userID, _ := uuid.NewV7()
 
-	t1, _ := GenerateRefreshToken(key, userID, "user")
-	t2, _ := GenerateRefreshToken(key, userID, "user")
+	t1, _ := GenerateRefreshToken(key, userID, "user", nil)
+	t2, _ := GenerateRefreshToken(key, userID, "user", nil)
 
 	if t1 == t2 {
 		t.Error("refresh tokens should be unique (different jti)")
@@ -124,7 +151,7 @@ func TestAccessToken_Expiry(t *testing.T) {
 	key := "test-key"
 	userID, _ := uuid.NewV7()
 
-	token, err := GenerateAccessToken(key, userID, "user")
+	token, err := GenerateAccessToken(key, userID, "user", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
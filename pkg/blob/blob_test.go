@@ -0,0 +1,89 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore_PutGetDelete(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "avatars/a", strings.NewReader("first")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Put(ctx, "avatars/a", strings.NewReader("second")); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+
+	rc, err := store.Get(ctx, "avatars/a")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "second" {
+		t.Errorf("expected overwritten content, got %q", b)
+	}
+
+	if err := store.Delete(ctx, "avatars/a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := store.Delete(ctx, "avatars/a"); err != nil {
+		t.Fatalf("deleting a missing blob should succeed, got %v", err)
+	}
+	if _, err := store.Get(ctx, "avatars/a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLocalStore_FailedPutKeepsOldBlob(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewLocalStore(dir)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "k", strings.NewReader("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := io.MultiReader(strings.NewReader("partial"), errReader{})
+	if err := store.Put(ctx, "k", failing); err == nil {
+		t.Fatal("expected error from failing reader")
+	}
+
+	rc, err := store.Get(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "old" {
+		t.Errorf("expected old content to survive, got %q", b)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected temporary file to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestLocalStore_InvalidKey(t *testing.T) {
+	store, _ := NewLocalStore(t.TempDir())
+
+	for _, key := range []string{"", ".", "../escape", "/abs", "a/../../b", "a//b"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("key %q: expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
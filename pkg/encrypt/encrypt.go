@@ -77,6 +77,35 @@ func HashToken(token string) string {
 	return hex.EncodeToString(h[:])
 }
 
+// Sign returns the hex HMAC-SHA256 of message under key.
+func Sign(key, message string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(message))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifySignature reports whether signature is the HMAC-SHA256 of message
+// under key. The comparison is constant time.
+func VerifySignature(key, message, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(message))
+	return hmac.Equal(h.Sum(nil), expected)
+}
+
+// GenerateToken returns a random 256-bit URL-safe token for single-use links.
+// Store only its HashToken.
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // GeneratePepper generates a random pepper (run once, store securely)
 func GeneratePepper() (string, error) {
 	pepper := make([]byte, 32)
@@ -65,3 +65,41 @@ func TestVerifyPassword_Empty(t *testing.T) {
 		t.Error("empty hash should not verify")
 	}
 }
+
+func TestSign_VerifySignature(t *testing.T) {
+	sig := Sign("key", "message")
+
+	if sig != Sign("key", "message") {
+		t.Error("Sign should be deterministic")
+	}
+	if len(sig) != 64 {
+		t.Errorf("HMAC-SHA256 hex should be 64 chars, got %d", len(sig))
+	}
+	if !VerifySignature("key", "message", sig) {
+		t.Error("VerifySignature: valid signature should verify")
+	}
+	if VerifySignature("other-key", "message", sig) {
+		t.Error("VerifySignature: wrong key should not verify")
+	}
+	if VerifySignature("key", "tampered", sig) {
+		t.Error("VerifySignature: tampered message should not verify")
+	}
+	if VerifySignature("key", "message", "not-hex") {
+		t.Error("VerifySignature: malformed signature should not verify")
+	}
+}
+
+func TestGenerateToken(t *testing.T) {
+	t1, err := GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	t2, _ := GenerateToken()
+
+	if t1 == t2 {
+		t.Error("tokens should be random")
+	}
+	if len(t1) != 43 {
+		t.Errorf("expected 43 chars, got %d", len(t1))
+	}
+}
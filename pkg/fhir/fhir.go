@@ -0,0 +1,324 @@
+// Package fhir holds the FHIR R4 resources the API exposes, encoded as
+// JSON, and their mapping to and from the domain model.
+package fhir
+
+import "time"
+
+// ContentType is the media type of a FHIR resource encoded as JSON.
+const ContentType = "application/fhir+json"
+
+// Version is the FHIR release the resources conform to.
+const Version = "4.0.1"
+
+// Identifier systems and code systems used by the mapping.
+const (
+	// SystemURI identifies a value that is itself a URI, such as
+	// urn:uuid:... for the user account of a practitioner.
+	SystemURI = "urn:ietf:rfc:3986"
+	// SystemNPI is the US National Provider Identifier.
+	SystemNPI = "http://hl7.org/fhir/sid/us-npi"
+	// SystemIdentifierType is HL7 table 0203, whose MR code marks the
+	// medical record number.
+	SystemIdentifierType = "http://terminology.hl7.org/CodeSystem/v2-0203"
+	// SystemLanguage is BCP 47, the system of language tags.
+	SystemLanguage = "urn:ietf:bcp:47"
+	// SystemProfession is the code system of the professions a practitioner
+	// can be registered with.
+	SystemProfession = "https://github.com/PranavJoshi2893/med-portal/fhir/CodeSystem/profession"
+)
+
+// Meta is the metadata of a resource.
+type Meta struct {
+	VersionID   string     `json:"versionId,omitempty"`
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+// Narrative is the human-readable summary of a resource. It is accepted on
+// create but never written, since clients render resources themselves.
+type Narrative struct {
+	Status string `json:"status"`
+	Div    string `json:"div"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Code returns the code of the first coding of c in system, or "".
+func (c *CodeableConcept) Code(system string) string {
+	if c == nil {
+		return ""
+	}
+	for _, coding := range c.Coding {
+		if coding.System == system {
+			return coding.Code
+		}
+	}
+	return ""
+}
+
+// String returns the text of c, falling back to the display or code of its
+// first coding.
+func (c *CodeableConcept) String() string {
+	if c == nil {
+		return ""
+	}
+	if c.Text != "" {
+		return c.Text
+	}
+	for _, coding := range c.Coding {
+		if coding.Display != "" {
+			return coding.Display
+		}
+		if coding.Code != "" {
+			return coding.Code
+		}
+	}
+	return ""
+}
+
+type Identifier struct {
+	Use    string           `json:"use,omitempty"`
+	Type   *CodeableConcept `json:"type,omitempty"`
+	System string           `json:"system,omitempty"`
+	Value  string           `json:"value,omitempty"`
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+// ContactPoint systems.
+const (
+	ContactPhone = "phone"
+	ContactEmail = "email"
+)
+
+type ContactPoint struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+type Address struct {
+	Use        string   `json:"use,omitempty"`
+	Line       []string `json:"line,omitempty"`
+	City       string   `json:"city,omitempty"`
+	State      string   `json:"state,omitempty"`
+	PostalCode string   `json:"postalCode,omitempty"`
+	Country    string   `json:"country,omitempty"`
+}
+
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// Reference points to another resource as Type/id. A reference may carry
+// only a Display, for example for an organisation that is not a resource
+// of the API.
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+// Patient is the FHIR Patient resource.
+type Patient struct {
+	ResourceType  string                 `json:"resourceType"`
+	ID            string                 `json:"id,omitempty"`
+	Meta          *Meta                  `json:"meta,omitempty"`
+	Text          *Narrative             `json:"text,omitempty"`
+	Identifier    []Identifier           `json:"identifier,omitempty"`
+	Active        *bool                  `json:"active,omitempty"`
+	Name          []HumanName            `json:"name,omitempty"`
+	Telecom       []ContactPoint         `json:"telecom,omitempty"`
+	Gender        string                 `json:"gender,omitempty"`
+	BirthDate     string                 `json:"birthDate,omitempty"`
+	Address       []Address              `json:"address,omitempty"`
+	Communication []PatientCommunication `json:"communication,omitempty"`
+}
+
+type PatientCommunication struct {
+	Language  CodeableConcept `json:"language"`
+	Preferred bool            `json:"preferred,omitempty"`
+}
+
+// Practitioner is the FHIR Practitioner resource.
+type Practitioner struct {
+	ResourceType  string          `json:"resourceType"`
+	ID            string          `json:"id,omitempty"`
+	Meta          *Meta           `json:"meta,omitempty"`
+	Text          *Narrative      `json:"text,omitempty"`
+	Identifier    []Identifier    `json:"identifier,omitempty"`
+	Active        *bool           `json:"active,omitempty"`
+	Name          []HumanName     `json:"name,omitempty"`
+	Telecom       []ContactPoint  `json:"telecom,omitempty"`
+	Qualification []Qualification `json:"qualification,omitempty"`
+}
+
+type Qualification struct {
+	Identifier []Identifier    `json:"identifier,omitempty"`
+	Code       CodeableConcept `json:"code"`
+	Period     *Period         `json:"period,omitempty"`
+	Issuer     *Reference      `json:"issuer,omitempty"`
+}
+
+// Appointment statuses of the FHIR AppointmentStatus value set.
+const (
+	AppointmentProposed  = "proposed"
+	AppointmentPending   = "pending"
+	AppointmentBooked    = "booked"
+	AppointmentArrived   = "arrived"
+	AppointmentFulfilled = "fulfilled"
+	AppointmentCancelled = "cancelled"
+	AppointmentNoShow    = "noshow"
+	AppointmentCheckedIn = "checked-in"
+	AppointmentWaitlist  = "waitlist"
+)
+
+// Appointment is the FHIR Appointment resource.
+type Appointment struct {
+	ResourceType      string                   `json:"resourceType"`
+	ID                string                   `json:"id,omitempty"`
+	Meta              *Meta                    `json:"meta,omitempty"`
+	Text              *Narrative               `json:"text,omitempty"`
+	Status            string                   `json:"status"`
+	CancelationReason *CodeableConcept         `json:"cancelationReason,omitempty"`
+	ServiceType       []CodeableConcept        `json:"serviceType,omitempty"`
+	ReasonCode        []CodeableConcept        `json:"reasonCode,omitempty"`
+	Description       string                   `json:"description,omitempty"`
+	Start             *time.Time               `json:"start,omitempty"`
+	End               *time.Time               `json:"end,omitempty"`
+	MinutesDuration   int                      `json:"minutesDuration,omitempty"`
+	Created           *time.Time               `json:"created,omitempty"`
+	Participant       []AppointmentParticipant `json:"participant"`
+}
+
+type AppointmentParticipant struct {
+	Actor    *Reference `json:"actor,omitempty"`
+	Required string     `json:"required,omitempty"`
+	Status   string     `json:"status"`
+}
+
+// Bundle types.
+const (
+	BundleSearchset = "searchset"
+)
+
+// Bundle is a collection of resources, such as a page of search results.
+// Link relations are self, first, previous, next and last.
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Total        *int          `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type BundleEntry struct {
+	FullURL  string        `json:"fullUrl,omitempty"`
+	Resource any           `json:"resource"`
+	Search   *BundleSearch `json:"search,omitempty"`
+}
+
+type BundleSearch struct {
+	Mode string `json:"mode"`
+}
+
+// Issue severities and the codes of the IssueType value set used by the
+// API.
+const (
+	SeverityError       = "error"
+	SeverityInformation = "information"
+
+	IssueInvalid      = "invalid"
+	IssueStructure    = "structure"
+	IssueRequired     = "required"
+	IssueLogin        = "login"
+	IssueForbidden    = "forbidden"
+	IssueNotFound     = "not-found"
+	IssueDeleted      = "deleted"
+	IssueDuplicate    = "duplicate"
+	IssueConflict     = "conflict"
+	IssueThrottled    = "throttled"
+	IssueTooCostly    = "too-costly"
+	IssueNotSupported = "not-supported"
+	IssueException    = "exception"
+)
+
+// OperationOutcome reports the errors of a request.
+type OperationOutcome struct {
+	ResourceType string  `json:"resourceType"`
+	Issue        []Issue `json:"issue"`
+}
+
+type Issue struct {
+	Severity    string   `json:"severity"`
+	Code        string   `json:"code"`
+	Diagnostics string   `json:"diagnostics,omitempty"`
+	Expression  []string `json:"expression,omitempty"`
+}
+
+// CapabilityStatement describes what a FHIR server supports.
+type CapabilityStatement struct {
+	ResourceType   string                  `json:"resourceType"`
+	Status         string                  `json:"status"`
+	Date           string                  `json:"date"`
+	Kind           string                  `json:"kind"`
+	Software       *CapabilitySoftware     `json:"software,omitempty"`
+	Implementation *CapabilityImplementing `json:"implementation,omitempty"`
+	FHIRVersion    string                  `json:"fhirVersion"`
+	Format         []string                `json:"format"`
+	Rest           []CapabilityRest        `json:"rest"`
+}
+
+type CapabilitySoftware struct {
+	Name string `json:"name"`
+}
+
+type CapabilityImplementing struct {
+	Description string `json:"description"`
+	URL         string `json:"url,omitempty"`
+}
+
+type CapabilityRest struct {
+	Mode     string               `json:"mode"`
+	Security *CapabilitySecurity  `json:"security,omitempty"`
+	Resource []CapabilityResource `json:"resource"`
+}
+
+type CapabilitySecurity struct {
+	Service     []CodeableConcept `json:"service,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+type CapabilityResource struct {
+	Type        string                  `json:"type"`
+	Interaction []CapabilityInteraction `json:"interaction"`
+	SearchParam []CapabilitySearchParam `json:"searchParam,omitempty"`
+}
+
+type CapabilityInteraction struct {
+	Code string `json:"code"`
+}
+
+type CapabilitySearchParam struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Documentation string `json:"documentation,omitempty"`
+}
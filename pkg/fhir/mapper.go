@@ -0,0 +1,444 @@
+package fhir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+// PatientPaths locates the fields of model.CreatePatient in a Patient.
+var PatientPaths = map[string]string{
+	"first_name":          "Patient.name.given",
+	"last_name":           "Patient.name.family",
+	"date_of_birth":       "Patient.birthDate",
+	"sex":                 "Patient.gender",
+	"phone":               "Patient.telecom",
+	"email":               "Patient.telecom",
+	"preferred_language":  "Patient.communication.language",
+	"address":             "Patient.address",
+	"address.line1":       "Patient.address.line",
+	"address.line2":       "Patient.address.line",
+	"address.city":        "Patient.address.city",
+	"address.region":      "Patient.address.state",
+	"address.postal_code": "Patient.address.postalCode",
+	"address.country":     "Patient.address.country",
+	"identifiers":         "Patient.identifier",
+}
+
+// PractitionerPaths locates the fields of model.CreatePractitioner in a
+// Practitioner.
+var PractitionerPaths = map[string]string{
+	"user_id":    "Practitioner.identifier",
+	"npi":        "Practitioner.identifier",
+	"profession": "Practitioner.qualification.code",
+	"licences":   "Practitioner.qualification",
+}
+
+// AppointmentPaths locates the fields of model.CreateAppointment in an
+// Appointment.
+var AppointmentPaths = map[string]string{
+	"practitioner_id": "Appointment.participant.actor",
+	"patient_id":      "Appointment.participant.actor",
+	"starts_at":       "Appointment.start",
+	"service":         "Appointment.serviceType",
+	"reason":          "Appointment.reasonCode",
+}
+
+// licenceCode names the qualification of a licence to practise.
+const licenceCode = "Licence to practise"
+
+// appointmentStatuses maps the statuses of appointments to FHIR.
+var appointmentStatuses = map[string]string{
+	model.AppointmentBooked:    AppointmentBooked,
+	model.AppointmentCheckedIn: AppointmentCheckedIn,
+	model.AppointmentCompleted: AppointmentFulfilled,
+	model.AppointmentNoShow:    AppointmentNoShow,
+	model.AppointmentCancelled: AppointmentCancelled,
+}
+
+// AppointmentStatus returns the status of appointments matching a FHIR
+// status, or false if no appointment can have it.
+func AppointmentStatus(status string) (string, bool) {
+	for ours, theirs := range appointmentStatuses {
+		if theirs == status {
+			return ours, true
+		}
+	}
+	return "", false
+}
+
+// Ref returns the reference to a resource of type resourceType.
+func Ref(resourceType string, id uuid.UUID) string {
+	return resourceType + "/" + id.String()
+}
+
+// ParseRef parses a reference to a resource of type resourceType, given as
+// Type/id, or as a bare id where the type is implied, as in search
+// parameters.
+func ParseRef(resourceType, ref string) (uuid.UUID, bool) {
+	ref = strings.TrimPrefix(ref, resourceType+"/")
+	id, err := uuid.Parse(ref)
+	return id, err == nil && !strings.Contains(ref, "/")
+}
+
+func meta(version int64, updatedAt time.Time) *Meta {
+	m := &Meta{LastUpdated: &updatedAt}
+	if version > 0 {
+		m.VersionID = strconv.FormatInt(version, 10)
+	}
+	return m
+}
+
+func officialName(first, last string) []HumanName {
+	return []HumanName{{Use: "official", Family: last, Given: []string{first}}}
+}
+
+// pickName returns the official name, or the first name of names.
+func pickName(names []HumanName) (HumanName, bool) {
+	for _, n := range names {
+		if n.Use == "official" {
+			return n, true
+		}
+	}
+	if len(names) == 0 {
+		return HumanName{}, false
+	}
+	return names[0], true
+}
+
+func mrn(value string) Identifier {
+	return Identifier{
+		Use:   "usual",
+		Type:  &CodeableConcept{Coding: []Coding{{System: SystemIdentifierType, Code: "MR", Display: "Medical record number"}}},
+		Value: value,
+	}
+}
+
+// FromPatient maps a patient to a Patient resource.
+func FromPatient(p *model.Patient) *Patient {
+	active := true
+	r := &Patient{
+		ResourceType: "Patient",
+		ID:           p.ID.String(),
+		Meta:         meta(p.Version, p.UpdatedAt),
+		Identifier:   []Identifier{mrn(p.MRN)},
+		Active:       &active,
+		Name:         officialName(p.FirstName, p.LastName),
+		Gender:       p.Sex,
+		BirthDate:    p.DateOfBirth,
+	}
+	for _, id := range p.Identifiers {
+		r.Identifier = append(r.Identifier, Identifier{System: id.System, Value: id.Value})
+	}
+	if p.Phone != nil {
+		r.Telecom = append(r.Telecom, ContactPoint{System: ContactPhone, Value: *p.Phone})
+	}
+	if p.Email != nil {
+		r.Telecom = append(r.Telecom, ContactPoint{System: ContactEmail, Value: *p.Email})
+	}
+	if a := p.Address; a != nil {
+		lines := []string{a.Line1}
+		if a.Line2 != "" {
+			lines = append(lines, a.Line2)
+		}
+		r.Address = []Address{{
+			Use:        "home",
+			Line:       lines,
+			City:       a.City,
+			State:      a.Region,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+		}}
+	}
+	if p.PreferredLanguage != nil {
+		r.Communication = []PatientCommunication{{
+			Language:  CodeableConcept{Coding: []Coding{{System: SystemLanguage, Code: *p.PreferredLanguage}}},
+			Preferred: true,
+		}}
+	}
+	return r
+}
+
+// FromPatientSummary maps a patient as listed to a Patient resource.
+func FromPatientSummary(p model.PatientSummary) *Patient {
+	active := true
+	return &Patient{
+		ResourceType: "Patient",
+		ID:           p.ID.String(),
+		Identifier:   []Identifier{mrn(p.MRN)},
+		Active:       &active,
+		Name:         officialName(p.FirstName, p.LastName),
+		Gender:       p.Sex,
+		BirthDate:    p.DateOfBirth,
+	}
+}
+
+// CreatePatient maps a Patient resource to the patient to register. The
+// MRN is assigned by the server, so identifiers typed MR are ignored, as
+// are the ID and metadata. Only the first given name is kept, and only one
+// phone number, email address and address.
+func (p *Patient) CreatePatient() (*model.CreatePatient, error) {
+	var errs model.ValidationErrors
+	if p.ResourceType != "Patient" {
+		errs = append(errs, model.FieldError{Field: "Patient.resourceType", Message: "resourceType must be Patient"})
+	}
+
+	data := &model.CreatePatient{
+		DateOfBirth: p.BirthDate,
+		Sex:         p.Gender,
+	}
+
+	if name, ok := pickName(p.Name); ok {
+		data.LastName = name.Family
+		if len(name.Given) > 0 {
+			data.FirstName = name.Given[0]
+		}
+	}
+
+	for _, t := range p.Telecom {
+		switch {
+		case t.System == ContactPhone && data.Phone == "":
+			data.Phone = t.Value
+		case t.System == ContactEmail && data.Email == "":
+			data.Email = t.Value
+		}
+	}
+
+	if len(p.Address) > 0 {
+		a := p.Address[0]
+		if len(a.Line) > 2 {
+			errs = append(errs, model.FieldError{Field: "Patient.address.line", Message: "at most 2 address lines"})
+		}
+		data.Address = &model.Address{
+			City:       a.City,
+			Region:     a.State,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+		}
+		if len(a.Line) > 0 {
+			data.Address.Line1 = a.Line[0]
+		}
+		if len(a.Line) > 1 {
+			data.Address.Line2 = a.Line[1]
+		}
+	}
+
+	for _, c := range p.Communication {
+		if code := c.Language.Code(SystemLanguage); code != "" && (c.Preferred || data.PreferredLanguage == "") {
+			data.PreferredLanguage = code
+		}
+	}
+
+	for _, id := range p.Identifier {
+		if id.Type.Code(SystemIdentifierType) == "MR" {
+			continue
+		}
+		data.Identifiers = append(data.Identifiers, model.CreatePatientIdentifier{System: id.System, Value: id.Value})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return data, nil
+}
+
+func practitionerIdentifiers(userID uuid.UUID, npi *string) []Identifier {
+	ids := []Identifier{{Use: "secondary", System: SystemURI, Value: "urn:uuid:" + userID.String()}}
+	if npi != nil {
+		ids = append(ids, Identifier{Use: "official", System: SystemNPI, Value: *npi})
+	}
+	return ids
+}
+
+func profession(code string) Qualification {
+	return Qualification{Code: CodeableConcept{Coding: []Coding{{System: SystemProfession, Code: code}}, Text: code}}
+}
+
+// FromPractitioner maps a practitioner to a Practitioner resource. The user
+// account is identified by its URN, and the profession and licences are
+// qualifications. Specialties and facilities belong to PractitionerRole,
+// which the API does not expose.
+func FromPractitioner(p *model.Practitioner) *Practitioner {
+	active := true
+	r := &Practitioner{
+		ResourceType:  "Practitioner",
+		ID:            p.ID.String(),
+		Meta:          meta(p.Version, p.UpdatedAt),
+		Identifier:    practitionerIdentifiers(p.UserID, p.NPI),
+		Active:        &active,
+		Name:          officialName(p.FirstName, p.LastName),
+		Telecom:       []ContactPoint{{System: ContactEmail, Value: p.Email, Use: "work"}},
+		Qualification: []Qualification{profession(p.Profession)},
+	}
+	for _, l := range p.Licences {
+		r.Qualification = append(r.Qualification, Qualification{
+			Identifier: []Identifier{{Value: l.Number}},
+			Code:       CodeableConcept{Text: licenceCode},
+			Period:     &Period{End: l.ExpiresOn},
+			Issuer:     &Reference{Display: l.Jurisdiction},
+		})
+	}
+	return r
+}
+
+// FromPractitionerSummary maps a practitioner as listed to a Practitioner
+// resource.
+func FromPractitionerSummary(p model.PractitionerSummary) *Practitioner {
+	active := true
+	return &Practitioner{
+		ResourceType:  "Practitioner",
+		ID:            p.ID.String(),
+		Identifier:    practitionerIdentifiers(p.UserID, nil),
+		Active:        &active,
+		Name:          officialName(p.FirstName, p.LastName),
+		Qualification: []Qualification{profession(p.Profession)},
+	}
+}
+
+// CreatePractitioner maps a Practitioner resource to the practitioner to
+// register. The user account is required, as an identifier in the
+// urn:ietf:rfc:3986 system; its name and email are those of the account,
+// so the name and telecom of the resource are ignored. Qualifications with
+// an identifier are licences, numbered by the identifier and issued in the
+// jurisdiction named by the issuer's display.
+func (p *Practitioner) CreatePractitioner() (*model.CreatePractitioner, error) {
+	var errs model.ValidationErrors
+	if p.ResourceType != "Practitioner" {
+		errs = append(errs, model.FieldError{Field: "Practitioner.resourceType", Message: "resourceType must be Practitioner"})
+	}
+
+	data := &model.CreatePractitioner{}
+	for _, id := range p.Identifier {
+		switch id.System {
+		case SystemNPI:
+			data.NPI = id.Value
+		case SystemURI:
+			if raw, ok := strings.CutPrefix(id.Value, "urn:uuid:"); ok {
+				if userID, err := uuid.Parse(raw); err == nil {
+					data.UserID = userID
+				} else {
+					errs = append(errs, model.FieldError{Field: "Practitioner.identifier", Message: "invalid user id"})
+				}
+			}
+		}
+	}
+
+	for i, q := range p.Qualification {
+		if code := q.Code.Code(SystemProfession); code != "" {
+			data.Profession = code
+			continue
+		}
+		if len(q.Identifier) == 0 {
+			continue
+		}
+		licence := model.CreateLicence{Number: q.Identifier[0].Value}
+		if q.Issuer != nil {
+			licence.Jurisdiction = q.Issuer.Display
+		}
+		if q.Period != nil {
+			licence.ExpiresOn = q.Period.End
+		}
+		if licence.Jurisdiction == "" {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("Practitioner.qualification[%d].issuer", i), Message: "issuer must name the jurisdiction"})
+		}
+		data.Licences = append(data.Licences, licence)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return data, nil
+}
+
+// FromAppointment maps an appointment to an Appointment resource, with the
+// patient and the practitioner as participants.
+func FromAppointment(a *model.Appointment) *Appointment {
+	start, end, created := a.StartsAt, a.EndsAt, a.CreatedAt
+	r := &Appointment{
+		ResourceType:    "Appointment",
+		ID:              a.ID.String(),
+		Meta:            meta(0, a.UpdatedAt),
+		Status:          appointmentStatuses[a.Status],
+		Start:           &start,
+		End:             &end,
+		MinutesDuration: int(end.Sub(start) / time.Minute),
+		Created:         &created,
+		Participant: []AppointmentParticipant{
+			{Actor: &Reference{Reference: Ref("Patient", a.PatientID), Display: a.PatientName}, Required: "required", Status: "accepted"},
+			{Actor: &Reference{Reference: Ref("Practitioner", a.PractitionerID), Display: a.PractitionerName}, Required: "required", Status: "accepted"},
+		},
+	}
+	if a.Service != nil {
+		r.ServiceType = []CodeableConcept{{Text: *a.Service}}
+	}
+	if a.Reason != nil {
+		r.ReasonCode = []CodeableConcept{{Text: *a.Reason}}
+	}
+	if a.CancelReason != nil {
+		r.CancelationReason = &CodeableConcept{Text: *a.CancelReason}
+	}
+	return r
+}
+
+// CreateAppointment maps an Appointment resource to the appointment to
+// book. The resource must be booked, with a start, a Patient and a
+// Practitioner participant. Its end is set by the practitioner's slots.
+func (a *Appointment) CreateAppointment() (*model.CreateAppointment, error) {
+	var errs model.ValidationErrors
+	if a.ResourceType != "Appointment" {
+		errs = append(errs, model.FieldError{Field: "Appointment.resourceType", Message: "resourceType must be Appointment"})
+	}
+	if a.Status != AppointmentBooked {
+		errs = append(errs, model.FieldError{Field: "Appointment.status", Message: "only booked appointments can be created"})
+	}
+
+	data := &model.CreateAppointment{}
+	if a.Start != nil {
+		data.StartsAt = *a.Start
+	}
+	if len(a.ServiceType) > 0 {
+		data.Service = a.ServiceType[0].String()
+	}
+	if len(a.ReasonCode) > 0 {
+		data.Reason = a.ReasonCode[0].String()
+	} else {
+		data.Reason = a.Description
+	}
+
+	for i, p := range a.Participant {
+		if p.Actor == nil {
+			continue
+		}
+		field := fmt.Sprintf("Appointment.participant[%d].actor", i)
+		resourceType, _, _ := strings.Cut(p.Actor.Reference, "/")
+		var dst *uuid.UUID
+		switch resourceType {
+		case "Patient":
+			dst = &data.PatientID
+		case "Practitioner":
+			dst = &data.PractitionerID
+		default:
+			errs = append(errs, model.FieldError{Field: field, Message: "participants must be a Patient or a Practitioner"})
+			continue
+		}
+		id, ok := ParseRef(resourceType, p.Actor.Reference)
+		if !ok {
+			errs = append(errs, model.FieldError{Field: field, Message: "invalid reference"})
+			continue
+		}
+		if *dst != uuid.Nil {
+			errs = append(errs, model.FieldError{Field: field, Message: "only one " + resourceType + " participant"})
+			continue
+		}
+		*dst = id
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return data, nil
+}
@@ -0,0 +1,223 @@
+package fhir
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestFromPatient(t *testing.T) {
+	phone, email, lang := "+447700900123", "ada@example.com", "en-GB"
+	p := &model.Patient{
+		ID:                uuid.MustParse("0192f0a4-7c1e-7b3a-9c0e-111111111111"),
+		MRN:               "MRN-000042",
+		FirstName:         "Ada",
+		LastName:          "Lovelace",
+		DateOfBirth:       "1815-12-10",
+		Sex:               "female",
+		Phone:             &phone,
+		Email:             &email,
+		PreferredLanguage: &lang,
+		Address:           &model.Address{Line1: "12 St James's Square", City: "London", PostalCode: "SW1Y 4JH", Country: "GB"},
+		Identifiers:       []model.PatientIdentifier{{System: "https://fhir.nhs.uk/Id/nhs-number", Value: "9434765919"}},
+		UpdatedAt:         time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC),
+		Version:           3,
+	}
+
+	body, err := json.Marshal(FromPatient(p))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`"resourceType":"Patient"`,
+		`"id":"0192f0a4-7c1e-7b3a-9c0e-111111111111"`,
+		`"meta":{"versionId":"3","lastUpdated":"2026-10-19T09:00:00Z"}`,
+		`"type":{"coding":[{"system":"http://terminology.hl7.org/CodeSystem/v2-0203","code":"MR","display":"Medical record number"}]},"value":"MRN-000042"`,
+		`{"system":"https://fhir.nhs.uk/Id/nhs-number","value":"9434765919"}`,
+		`"name":[{"use":"official","family":"Lovelace","given":["Ada"]}]`,
+		`"telecom":[{"system":"phone","value":"+447700900123"},{"system":"email","value":"ada@example.com"}]`,
+		`"gender":"female","birthDate":"1815-12-10"`,
+		`"address":[{"use":"home","line":["12 St James's Square"],"city":"London","postalCode":"SW1Y 4JH","country":"GB"}]`,
+		`"communication":[{"language":{"coding":[{"system":"urn:ietf:bcp:47","code":"en-GB"}]},"preferred":true}]`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("missing %s in %s", want, body)
+		}
+	}
+}
+
+func TestPatient_CreatePatient(t *testing.T) {
+	var r Patient
+	if err := json.Unmarshal([]byte(`{
+		"resourceType": "Patient",
+		"identifier": [
+			{"type": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/v2-0203", "code": "MR"}]}, "value": "ignored"},
+			{"system": "https://fhir.nhs.uk/Id/nhs-number", "value": "9434765919"}
+		],
+		"name": [{"use": "nickname", "given": ["Addie"]}, {"use": "official", "family": "Lovelace", "given": ["Ada", "Augusta"]}],
+		"telecom": [{"system": "email", "value": "ada@example.com"}, {"system": "phone", "value": "+447700900123"}, {"system": "phone", "value": "+447700900999"}],
+		"gender": "female",
+		"birthDate": "1815-12-10",
+		"address": [{"line": ["12 St James's Square", "Flat 1"], "city": "London", "country": "GB"}],
+		"communication": [{"language": {"coding": [{"system": "urn:ietf:bcp:47", "code": "fr"}]}}, {"language": {"coding": [{"system": "urn:ietf:bcp:47", "code": "en-GB"}]}, "preferred": true}]
+	}`), &r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := r.CreatePatient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &model.CreatePatient{
+		FirstName:         "Ada",
+		LastName:          "Lovelace",
+		DateOfBirth:       "1815-12-10",
+		Sex:               "female",
+		Phone:             "+447700900123",
+		Email:             "ada@example.com",
+		PreferredLanguage: "en-GB",
+		Address:           &model.Address{Line1: "12 St James's Square", Line2: "Flat 1", City: "London", Country: "GB"},
+		Identifiers:       []model.CreatePatientIdentifier{{System: "https://fhir.nhs.uk/Id/nhs-number", Value: "9434765919"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CreatePatient() = %+v, want %+v", got, want)
+	}
+
+	r.ResourceType = "Practitioner"
+	r.Address[0].Line = append(r.Address[0].Line, "Third line")
+	_, err = r.CreatePatient()
+	var vErrs model.ValidationErrors
+	if !errors.As(err, &vErrs) || len(vErrs) != 2 {
+		t.Errorf("expected errors for the resource type and address lines, got %v", err)
+	}
+}
+
+func TestPractitioner_RoundTrip(t *testing.T) {
+	npi := "1234567893"
+	userID := uuid.MustParse("0192f0a4-7c1e-7b3a-9c0e-222222222222")
+	p := &model.Practitioner{
+		ID:         uuid.MustParse("0192f0a4-7c1e-7b3a-9c0e-333333333333"),
+		UserID:     userID,
+		FirstName:  "Gregory",
+		LastName:   "House",
+		Email:      "house@example.com",
+		Profession: "doctor",
+		NPI:        &npi,
+		Licences:   []model.Licence{{Number: "MD-12345", Jurisdiction: "US-NJ", ExpiresOn: "2027-06-30"}},
+	}
+
+	r := FromPractitioner(p)
+	got, err := r.CreatePractitioner()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &model.CreatePractitioner{
+		UserID:     userID,
+		Profession: "doctor",
+		NPI:        npi,
+		Licences:   []model.CreateLicence{{Number: "MD-12345", Jurisdiction: "US-NJ", ExpiresOn: "2027-06-30"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CreatePractitioner() = %+v, want %+v", got, want)
+	}
+
+	r.Identifier[0].Value = "urn:uuid:not-a-uuid"
+	r.Qualification[1].Issuer = nil
+	_, err = r.CreatePractitioner()
+	var vErrs model.ValidationErrors
+	if !errors.As(err, &vErrs) || len(vErrs) != 2 {
+		t.Errorf("expected errors for the user and the licence issuer, got %v", err)
+	}
+}
+
+func TestFromAppointment(t *testing.T) {
+	service, reason := "check-up", "annual review"
+	start := time.Date(2026, time.October, 20, 9, 0, 0, 0, time.UTC)
+	a := &model.Appointment{
+		ID:               uuid.MustParse("0192f0a4-7c1e-7b3a-9c0e-444444444444"),
+		PractitionerID:   uuid.MustParse("0192f0a4-7c1e-7b3a-9c0e-333333333333"),
+		PractitionerName: "Gregory House",
+		PatientID:        uuid.MustParse("0192f0a4-7c1e-7b3a-9c0e-111111111111"),
+		PatientName:      "Ada Lovelace",
+		StartsAt:         start,
+		EndsAt:           start.Add(30 * time.Minute),
+		Status:           model.AppointmentCompleted,
+		Service:          &service,
+		Reason:           &reason,
+	}
+
+	r := FromAppointment(a)
+	if r.Status != AppointmentFulfilled || r.MinutesDuration != 30 {
+		t.Errorf("status %q, %d minutes, want fulfilled, 30 minutes", r.Status, r.MinutesDuration)
+	}
+	if got := r.Participant[0].Actor.Reference; got != "Patient/0192f0a4-7c1e-7b3a-9c0e-111111111111" {
+		t.Errorf("patient reference = %q", got)
+	}
+
+	r.Status = AppointmentBooked
+	got, err := r.CreateAppointment()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &model.CreateAppointment{
+		PractitionerID: a.PractitionerID,
+		PatientID:      a.PatientID,
+		StartsAt:       start,
+		Service:        service,
+		Reason:         reason,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateAppointment() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAppointment_CreateAppointment_Invalid(t *testing.T) {
+	patient := "Patient/0192f0a4-7c1e-7b3a-9c0e-111111111111"
+	tests := []struct {
+		name      string
+		status    string
+		actors    []string
+		wantField string
+	}{
+		{"not booked", AppointmentProposed, []string{patient}, "Appointment.status"},
+		{"other participant", AppointmentBooked, []string{"Location/0192f0a4-7c1e-7b3a-9c0e-555555555555"}, "Appointment.participant[0].actor"},
+		{"invalid reference", AppointmentBooked, []string{"Patient/42"}, "Appointment.participant[0].actor"},
+		{"two patients", AppointmentBooked, []string{patient, patient}, "Appointment.participant[1].actor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Appointment{ResourceType: "Appointment", Status: tt.status}
+			for _, ref := range tt.actors {
+				r.Participant = append(r.Participant, AppointmentParticipant{Actor: &Reference{Reference: ref}})
+			}
+			_, err := r.CreateAppointment()
+			var vErrs model.ValidationErrors
+			if !errors.As(err, &vErrs) || len(vErrs) != 1 || vErrs[0].Field != tt.wantField {
+				t.Errorf("got %v, want an error for %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestAppointmentStatus(t *testing.T) {
+	for fhirStatus, want := range map[string]string{
+		AppointmentBooked:    model.AppointmentBooked,
+		AppointmentCheckedIn: model.AppointmentCheckedIn,
+		AppointmentFulfilled: model.AppointmentCompleted,
+		AppointmentNoShow:    model.AppointmentNoShow,
+		AppointmentCancelled: model.AppointmentCancelled,
+	} {
+		if got, ok := AppointmentStatus(fhirStatus); !ok || got != want {
+			t.Errorf("AppointmentStatus(%q) = %q, %v, want %q", fhirStatus, got, ok, want)
+		}
+	}
+	if _, ok := AppointmentStatus(AppointmentWaitlist); ok {
+		t.Error("expected no status for waitlist")
+	}
+}
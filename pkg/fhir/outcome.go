@@ -0,0 +1,78 @@
+package fhir
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+// NewOutcome returns an OperationOutcome with a single error issue.
+func NewOutcome(code, diagnostics string) *OperationOutcome {
+	return &OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []Issue{{Severity: SeverityError, Code: code, Diagnostics: diagnostics}},
+	}
+}
+
+// FromError maps an error of the domain to its HTTP status and an
+// OperationOutcome, as responses.FromModelError does for the JSON API.
+// Validation errors become one issue per field, located by the FHIRPath
+// expression paths gives for the field, or the field itself when it has
+// none. Unexpected errors are not disclosed.
+func FromError(err error, paths map[string]string) (int, *OperationOutcome) {
+	var vErrs model.ValidationErrors
+	if errors.As(err, &vErrs) {
+		outcome := &OperationOutcome{ResourceType: "OperationOutcome"}
+		for _, e := range vErrs {
+			outcome.Issue = append(outcome.Issue, Issue{
+				Severity:    SeverityError,
+				Code:        IssueInvalid,
+				Diagnostics: e.Message,
+				Expression:  []string{expression(e.Field, paths)},
+			})
+		}
+		return http.StatusUnprocessableEntity, outcome
+	}
+
+	for _, m := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{model.ErrAlreadyExists, http.StatusConflict, IssueDuplicate},
+		{model.ErrConflict, http.StatusConflict, IssueConflict},
+		{model.ErrNotFound, http.StatusNotFound, IssueNotFound},
+		{model.ErrAlreadyDeleted, http.StatusGone, IssueDeleted},
+		{model.ErrUnauthorized, http.StatusUnauthorized, IssueLogin},
+		{model.ErrBadRequest, http.StatusBadRequest, IssueInvalid},
+		{model.ErrForbidden, http.StatusForbidden, IssueForbidden},
+		{model.ErrTooManyRequests, http.StatusTooManyRequests, IssueThrottled},
+		{model.ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, IssueTooCostly},
+		{model.ErrUnsupportedMedia, http.StatusUnsupportedMediaType, IssueNotSupported},
+		{model.ErrPreconditionFailed, http.StatusPreconditionFailed, IssueConflict},
+		{model.ErrPreconditionRequired, http.StatusPreconditionRequired, IssueRequired},
+	} {
+		if errors.Is(err, m.err) {
+			return m.status, NewOutcome(m.code, err.Error())
+		}
+	}
+
+	return http.StatusInternalServerError, NewOutcome(IssueException, "Internal server error")
+}
+
+// expression returns the FHIRPath of a validation error's field. Fields of
+// list items, such as identifiers[0].system, fall back to the path of the
+// list.
+func expression(field string, paths map[string]string) string {
+	if p, ok := paths[field]; ok {
+		return p
+	}
+	if i := strings.IndexByte(field, '['); i > 0 {
+		if p, ok := paths[field[:i]]; ok {
+			return p
+		}
+	}
+	return field
+}
@@ -0,0 +1,67 @@
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantType string
+		wantDiag string
+	}{
+		{"not found", fmt.Errorf("patient %w", model.ErrNotFound), http.StatusNotFound, IssueNotFound, "patient not found"},
+		{"forbidden", model.ErrForbidden, http.StatusForbidden, IssueForbidden, model.ErrForbidden.Error()},
+		{"already exists", model.ErrAlreadyExists, http.StatusConflict, IssueDuplicate, model.ErrAlreadyExists.Error()},
+		{"conflict", model.ErrConflict, http.StatusConflict, IssueConflict, model.ErrConflict.Error()},
+		{"unauthorized", model.ErrUnauthorized, http.StatusUnauthorized, IssueLogin, model.ErrUnauthorized.Error()},
+		{"unknown error", errors.New("pq: connection refused"), http.StatusInternalServerError, IssueException, "Internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, outcome := FromError(tt.err, nil)
+			if code != tt.wantCode {
+				t.Errorf("code = %d, want %d", code, tt.wantCode)
+			}
+			if outcome.ResourceType != "OperationOutcome" || len(outcome.Issue) != 1 {
+				t.Fatalf("outcome = %+v", outcome)
+			}
+			if issue := outcome.Issue[0]; issue.Severity != SeverityError || issue.Code != tt.wantType || issue.Diagnostics != tt.wantDiag {
+				t.Errorf("issue = %+v, want %s %q", issue, tt.wantType, tt.wantDiag)
+			}
+		})
+	}
+}
+
+func TestFromError_Validation(t *testing.T) {
+	err := model.ValidationErrors{
+		{Field: "first_name", Message: "first name is required"},
+		{Field: "identifiers[1].system", Message: "system is required"},
+		{Field: "Patient.address.line", Message: "at most 2 address lines"},
+	}
+
+	code, outcome := FromError(err, PatientPaths)
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("code = %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	var got [][]string
+	for _, issue := range outcome.Issue {
+		if issue.Code != IssueInvalid {
+			t.Errorf("issue code = %q, want %q", issue.Code, IssueInvalid)
+		}
+		got = append(got, issue.Expression)
+	}
+	want := [][]string{{"Patient.name.given"}, {"Patient.identifier"}, {"Patient.address.line"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expressions = %v, want %v", got, want)
+	}
+}
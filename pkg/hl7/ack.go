@@ -0,0 +1,95 @@
+package hl7
+
+import "time"
+
+// Acknowledgment codes (HL7 table 0008).
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// Error codes of an ERR segment (HL7 table 0357).
+const (
+	ErrSegmentSequence         = "100"
+	ErrRequiredFieldMissing    = "101"
+	ErrDataType                = "102"
+	ErrTableValueNotFound      = "103"
+	ErrUnsupportedMessageType  = "200"
+	ErrUnsupportedEventCode    = "201"
+	ErrUnsupportedProcessingID = "202"
+	ErrUnsupportedVersionID    = "203"
+	ErrUnknownKeyIdentifier    = "204"
+	ErrDuplicateKeyIdentifier  = "205"
+	ErrApplicationRecordLocked = "206"
+	ErrApplicationInternal     = "207"
+)
+
+// errorText names the codes of table 0357 in ERR-3.
+var errorText = map[string]string{
+	ErrSegmentSequence:         "Segment sequence error",
+	ErrRequiredFieldMissing:    "Required field missing",
+	ErrDataType:                "Data type error",
+	ErrTableValueNotFound:      "Table value not found",
+	ErrUnsupportedMessageType:  "Unsupported message type",
+	ErrUnsupportedEventCode:    "Unsupported event code",
+	ErrUnsupportedProcessingID: "Unsupported processing id",
+	ErrUnsupportedVersionID:    "Unsupported version id",
+	ErrUnknownKeyIdentifier:    "Unknown key identifier",
+	ErrDuplicateKeyIdentifier:  "Duplicate key identifier",
+	ErrApplicationRecordLocked: "Application record locked",
+	ErrApplicationInternal:     "Application internal error",
+}
+
+// Ack is the acknowledgment of a message. Code is one of AckAccept,
+// AckError and AckReject; ErrorCode, from table 0357, and Text explain an
+// error or rejection.
+type Ack struct {
+	Code      string
+	ErrorCode string
+	Text      string
+}
+
+// Acknowledge builds the acknowledgment of msg, sent at now and identified
+// by controlID. The sending and receiving applications of msg are swapped,
+// and its processing and version IDs are kept.
+func (a Ack) Acknowledge(msg *Message, controlID string, now time.Time) *Message {
+	h := msg.header()
+	_, event := msg.Type()
+
+	ack := &Message{Delimiters: msg.Delimiters}
+	ack.Segments = append(ack.Segments, Segment{Name: "MSH", Fields: []Field{
+		Text("MSH"),
+		Text(string(msg.Delimiters.Field)),
+		Text(msg.Delimiters.encoding()),
+		h.Field(5),
+		h.Field(6),
+		h.Field(3),
+		h.Field(4),
+		Text(FormatTime(now)),
+		nil,
+		Components("ACK", event, "ACK"),
+		Text(controlID),
+		h.Field(11),
+		h.Field(12),
+	}})
+	ack.Segments = append(ack.Segments, NewSegment("MSA", Text(a.Code), Text(msg.ControlID()), Text(a.Text)))
+
+	if a.Code != AckAccept {
+		code := a.ErrorCode
+		if code == "" {
+			code = ErrApplicationInternal
+		}
+		ack.Segments = append(ack.Segments, NewSegment("ERR",
+			nil,
+			nil,
+			Components(code, errorText[code], "HL70357"),
+			Text("E"),
+			nil,
+			nil,
+			nil,
+			Text(a.Text),
+		))
+	}
+	return ack
+}
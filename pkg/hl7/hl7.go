@@ -0,0 +1,447 @@
+// Package hl7 reads and writes HL7 v2 messages in their pipe-delimited
+// (ER7) encoding. A message is a list of segments; a segment's fields hold
+// repetitions, which hold components, which hold subcomponents. Values are
+// unescaped when a message is parsed and escaped again when it is written.
+package hl7
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of an ER7-encoded message.
+const ContentType = "x-application/hl7-v2+er7"
+
+// ErrMalformed is returned for data that is not an HL7 v2 message.
+var ErrMalformed = errors.New("malformed HL7 message")
+
+// Delimiters are the separators and escape character of a message, declared
+// in its MSH segment.
+type Delimiters struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+// DefaultDelimiters are the delimiters recommended by the standard,
+// declared as MSH|^~\&.
+var DefaultDelimiters = Delimiters{Field: '|', Component: '^', Repetition: '~', Escape: '\\', Subcomponent: '&'}
+
+// encoding returns MSH-2, the encoding characters.
+func (d Delimiters) encoding() string {
+	return string([]byte{d.Component, d.Repetition, d.Escape, d.Subcomponent})
+}
+
+// Component is the subcomponents of a component.
+type Component []string
+
+// Repetition is the components of one occurrence of a field.
+type Repetition []Component
+
+// Field is the occurrences of a field. Most fields occur once.
+type Field []Repetition
+
+// Text returns a field holding a single value.
+func Text(s string) Field {
+	return Field{{{s}}}
+}
+
+// Components returns a field holding a single occurrence of the given
+// components.
+func Components(values ...string) Field {
+	rep := make(Repetition, len(values))
+	for i, v := range values {
+		rep[i] = Component{v}
+	}
+	return Field{rep}
+}
+
+// empty reports whether the field holds no values.
+func (f Field) empty() bool {
+	for _, rep := range f {
+		for _, c := range rep {
+			for _, v := range c {
+				if v != "" {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// String returns the first value of the field, or "" for an empty field.
+func (f Field) String() string {
+	return f.Component(1)
+}
+
+// Component returns the first subcomponent of component n, counted from 1,
+// of the field's first occurrence.
+func (f Field) Component(n int) string {
+	if len(f) == 0 {
+		return ""
+	}
+	return f[0].Component(n)
+}
+
+// Component returns the first subcomponent of component n, counted from 1.
+func (r Repetition) Component(n int) string {
+	if n < 1 || n > len(r) || len(r[n-1]) == 0 {
+		return ""
+	}
+	return r[n-1][0]
+}
+
+// Segment is a segment of a message. Fields are numbered as in the
+// standard: Fields[0] is the segment name, and in MSH Fields[1] is the
+// field separator and Fields[2] the encoding characters.
+type Segment struct {
+	Name   string
+	Fields []Field
+}
+
+// NewSegment returns a segment with the given fields, numbered from 1.
+func NewSegment(name string, fields ...Field) Segment {
+	return Segment{Name: name, Fields: append([]Field{Text(name)}, fields...)}
+}
+
+// Field returns field n, or nil when the segment is shorter.
+func (s Segment) Field(n int) Field {
+	if n < 1 || n >= len(s.Fields) {
+		return nil
+	}
+	return s.Fields[n]
+}
+
+// Value returns component c, counted from 1, of field n.
+func (s Segment) Value(n, c int) string {
+	return s.Field(n).Component(c)
+}
+
+// Message is a parsed message.
+type Message struct {
+	Delimiters Delimiters
+	Segments   []Segment
+}
+
+// Segment returns the first segment named name.
+func (m *Message) Segment(name string) (Segment, bool) {
+	for _, s := range m.Segments {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Segment{}, false
+}
+
+// header returns the MSH segment, which Parse guarantees comes first.
+func (m *Message) header() Segment {
+	if len(m.Segments) == 0 {
+		return Segment{}
+	}
+	return m.Segments[0]
+}
+
+// Type returns the message code and trigger event of MSH-9, e.g. ORU and
+// R01.
+func (m *Message) Type() (code, event string) {
+	h := m.header()
+	return h.Value(9, 1), h.Value(9, 2)
+}
+
+// ControlID returns MSH-10, which identifies the message to its sender.
+func (m *Message) ControlID() string {
+	return m.header().Value(10, 1)
+}
+
+// Parse parses a message. Segments may end in CR, LF or CRLF, and the
+// message must start with an MSH segment, which declares the delimiters.
+func Parse(data []byte) (*Message, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\r")
+	text = strings.ReplaceAll(text, "\n", "\r")
+
+	if len(text) < 8 || !strings.HasPrefix(text, "MSH") {
+		return nil, fmt.Errorf("%w: expected an MSH segment", ErrMalformed)
+	}
+	d := Delimiters{
+		Field:        text[3],
+		Component:    text[4],
+		Repetition:   text[5],
+		Escape:       text[6],
+		Subcomponent: text[7],
+	}
+	seen := map[byte]bool{'\r': true}
+	for _, c := range []byte{d.Field, d.Component, d.Repetition, d.Escape, d.Subcomponent} {
+		if seen[c] || c == ' ' || isAlphanumeric(c) {
+			return nil, fmt.Errorf("%w: invalid encoding characters", ErrMalformed)
+		}
+		seen[c] = true
+	}
+	if len(text) > 8 && text[8] != d.Field && text[8] != '\r' {
+		return nil, fmt.Errorf("%w: invalid encoding characters", ErrMalformed)
+	}
+
+	m := &Message{Delimiters: d}
+	for i, line := range strings.Split(text, "\r") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		s, err := d.parseSegment(line, i == 0)
+		if err != nil {
+			return nil, fmt.Errorf("%w: segment %d: %v", ErrMalformed, len(m.Segments)+1, err)
+		}
+		m.Segments = append(m.Segments, s)
+	}
+	return m, nil
+}
+
+// ParseHeader parses only the MSH segment that starts data, so that data
+// Parse rejects, such as a message malformed further on or one cut short,
+// can still be acknowledged. Fields missing from a cut-short MSH segment
+// are empty.
+func ParseHeader(data []byte) (*Message, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		data = data[:i]
+	}
+	return Parse(data)
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func (d Delimiters) parseSegment(line string, header bool) (Segment, error) {
+	values := strings.Split(line, string(d.Field))
+	name := values[0]
+	if len(name) != 3 || !isAlphanumeric(name[0]) || !isAlphanumeric(name[1]) || !isAlphanumeric(name[2]) {
+		return Segment{}, fmt.Errorf("invalid segment name %q", name)
+	}
+
+	s := Segment{Name: name, Fields: []Field{Text(name)}}
+	if header {
+		// MSH-1 is the field separator itself and MSH-2 the encoding
+		// characters, which are not split or unescaped.
+		s.Fields = append(s.Fields, Text(string(d.Field)), Text(values[1]))
+		values = values[2:]
+	} else {
+		if name == "MSH" {
+			return Segment{}, errors.New("unexpected MSH segment")
+		}
+		values = values[1:]
+	}
+
+	for _, v := range values {
+		s.Fields = append(s.Fields, d.parseField(v))
+	}
+	return s, nil
+}
+
+func (d Delimiters) parseField(v string) Field {
+	var f Field
+	for _, rv := range strings.Split(v, string(d.Repetition)) {
+		var rep Repetition
+		for _, cv := range strings.Split(rv, string(d.Component)) {
+			var c Component
+			for _, sv := range strings.Split(cv, string(d.Subcomponent)) {
+				c = append(c, d.UnescapeText(sv))
+			}
+			rep = append(rep, c)
+		}
+		f = append(f, rep)
+	}
+	return f
+}
+
+// UnescapeText replaces the escape sequences of s with the text they stand for.
+// \.br\ becomes a line feed, formatting sequences such as \H\ are dropped
+// and unknown sequences are kept as they are.
+func (d Delimiters) UnescapeText(s string) string {
+	esc := string(d.Escape)
+	if !strings.Contains(s, esc) {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, esc)
+		if start < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := strings.Index(s[start+1:], esc)
+		if end < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:start])
+		seq := s[start+1 : start+1+end]
+		s = s[start+end+2:]
+
+		switch {
+		case seq == "F":
+			b.WriteByte(d.Field)
+		case seq == "S":
+			b.WriteByte(d.Component)
+		case seq == "T":
+			b.WriteByte(d.Subcomponent)
+		case seq == "R":
+			b.WriteByte(d.Repetition)
+		case seq == "E":
+			b.WriteByte(d.Escape)
+		case seq == ".br":
+			b.WriteByte('\n')
+		case seq == "H" || seq == "N":
+		case strings.HasPrefix(seq, "X") && len(seq)%2 == 1 && len(seq) > 1:
+			raw, err := hexBytes(seq[1:])
+			if err != nil {
+				b.WriteString(esc + seq + esc)
+				continue
+			}
+			b.Write(raw)
+		default:
+			b.WriteString(esc + seq + esc)
+		}
+	}
+}
+
+func hexBytes(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s)/2)
+	for i := 0; i < len(s); i += 2 {
+		v, err := strconv.ParseUint(s[i:i+2], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, byte(v))
+	}
+	return out, nil
+}
+
+// EscapeText replaces the delimiters and line breaks in s with escape
+// sequences.
+func (d Delimiters) EscapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case d.Field:
+			b.WriteString(string(d.Escape) + "F" + string(d.Escape))
+		case d.Component:
+			b.WriteString(string(d.Escape) + "S" + string(d.Escape))
+		case d.Subcomponent:
+			b.WriteString(string(d.Escape) + "T" + string(d.Escape))
+		case d.Repetition:
+			b.WriteString(string(d.Escape) + "R" + string(d.Escape))
+		case d.Escape:
+			b.WriteString(string(d.Escape) + "E" + string(d.Escape))
+		case '\n':
+			b.WriteString(string(d.Escape) + ".br" + string(d.Escape))
+		case '\r':
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Bytes encodes the message, ending each segment with a CR. MSH-1 and MSH-2
+// are written from the message's delimiters, and empty trailing fields are
+// left out.
+func (m *Message) Bytes() []byte {
+	d := m.Delimiters
+	var b bytes.Buffer
+	for _, s := range m.Segments {
+		b.WriteString(s.Name)
+		first := 1
+		if s.Name == "MSH" {
+			b.WriteByte(d.Field)
+			b.WriteString(d.encoding())
+			first = 3
+		}
+		last := len(s.Fields) - 1
+		for last >= first && s.Fields[last].empty() {
+			last--
+		}
+		for n := first; n <= last; n++ {
+			b.WriteByte(d.Field)
+			d.writeField(&b, s.Fields[n])
+		}
+		b.WriteByte('\r')
+	}
+	return b.Bytes()
+}
+
+func (d Delimiters) writeField(b *bytes.Buffer, f Field) {
+	for i, rep := range f {
+		if i > 0 {
+			b.WriteByte(d.Repetition)
+		}
+		for j, c := range rep {
+			if j > 0 {
+				b.WriteByte(d.Component)
+			}
+			for k, v := range c {
+				if k > 0 {
+					b.WriteByte(d.Subcomponent)
+				}
+				b.WriteString(d.EscapeText(v))
+			}
+		}
+	}
+}
+
+// timeLayouts are the precisions of an HL7 date/time, from the most
+// precise.
+var timeLayouts = []string{
+	"20060102150405",
+	"200601021504",
+	"2006010215",
+	"20060102",
+	"200601",
+	"2006",
+}
+
+// ParseTime parses an HL7 date/time, YYYY[MM[DD[HH[MM[SS[.S...]]]]]][+/-ZZZZ].
+// Times without an offset are read in loc.
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	value, zone := s, ""
+	if i := strings.IndexAny(s, "+-"); i >= 0 {
+		value, zone = s[:i], s[i:]
+	}
+	frac := ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value, frac = value[:i], value[i:]
+	}
+
+	for _, layout := range timeLayouts {
+		if len(value) != len(layout) {
+			continue
+		}
+		if frac != "" && len(layout) != len("20060102150405") {
+			break
+		}
+		if zone != "" {
+			return time.Parse(layout+frac0(frac)+"-0700", value+frac+zone)
+		}
+		return time.ParseInLocation(layout+frac0(frac), value+frac, loc)
+	}
+	return time.Time{}, fmt.Errorf("invalid HL7 date/time %q", s)
+}
+
+// frac0 returns the layout of the fractional seconds frac.
+func frac0(frac string) string {
+	if frac == "" {
+		return ""
+	}
+	return "." + strings.Repeat("0", len(frac)-1)
+}
+
+// FormatTime formats t as an HL7 date/time to the second, with its offset.
+func FormatTime(t time.Time) string {
+	return t.Format("20060102150405-0700")
+}
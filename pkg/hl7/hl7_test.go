@@ -0,0 +1,193 @@
+package hl7
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const oru = "MSH|^~\\&|LAB|ACME LAB|MEDPORTAL|CLINIC|20261019083000-0400||ORU^R01^ORU_R01|MSG00001|P|2.5.1\r\n" +
+	"PID|1||100045^^^CLINIC^MR~999-88-7777^^^SSA^SS||Doe^Jane^Q||19800214|F\r\n" +
+	"OBR|1|ORD123|FIL456|24331-1^Lipid panel^LN|||20261018073000\r\n" +
+	"OBX|1|NM|2093-3^Cholesterol^LN||212|mg/dL|<200|H|||F\r\n" +
+	"NTE|1||Fasting \\T\\ hydrated\\.br\\repeat in 3 months \\F\\ \\S\\ \\R\\ \\E\\ \\X41\\\n"
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(oru))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Segments) != 5 {
+		t.Fatalf("got %d segments, want 5", len(m.Segments))
+	}
+	if m.Delimiters != DefaultDelimiters {
+		t.Errorf("delimiters = %+v", m.Delimiters)
+	}
+
+	if code, event := m.Type(); code != "ORU" || event != "R01" {
+		t.Errorf("type = %s^%s, want ORU^R01", code, event)
+	}
+	if m.ControlID() != "MSG00001" {
+		t.Errorf("control id = %q", m.ControlID())
+	}
+	h := m.Segments[0]
+	if h.Field(1).String() != "|" || h.Field(2).String() != "^~\\&" || h.Field(3).String() != "LAB" {
+		t.Errorf("MSH-1..3 = %q %q %q", h.Field(1), h.Field(2), h.Field(3))
+	}
+
+	pid, ok := m.Segment("PID")
+	if !ok {
+		t.Fatal("expected a PID segment")
+	}
+	ids := pid.Field(3)
+	if len(ids) != 2 || ids[0].Component(1) != "100045" || ids[0].Component(5) != "MR" || ids[1].Component(4) != "SSA" {
+		t.Errorf("PID-3 = %+v, want two identifiers", ids)
+	}
+	if pid.Value(5, 1) != "Doe" || pid.Value(5, 2) != "Jane" || pid.Value(7, 1) != "19800214" {
+		t.Errorf("PID = %+v", pid)
+	}
+	if pid.Field(30) != nil || pid.Value(5, 9) != "" {
+		t.Error("expected missing fields and components to be empty")
+	}
+
+	nte, _ := m.Segment("NTE")
+	want := "Fasting & hydrated\nrepeat in 3 months | ^ ~ \\ A"
+	if got := nte.Field(3).String(); got != want {
+		t.Errorf("NTE-3 = %q, want %q", got, want)
+	}
+}
+
+func TestParse_Delimiters(t *testing.T) {
+	m, err := Parse([]byte("MSH#:*!$#A#B\rOBX#1#ST#code:text#a*b#x!T!y$z"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obx, _ := m.Segment("OBX")
+	if obx.Value(3, 2) != "text" || len(obx.Field(4)) != 2 {
+		t.Errorf("OBX = %+v", obx)
+	}
+	if c := obx.Field(5)[0][0]; len(c) != 2 || c[0] != "x$y" || c[1] != "z" {
+		t.Errorf("OBX-5 = %+v, want subcomponents x$y and z", c)
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"no header", "PID|1||100045\r"},
+		{"short header", "MSH|^~"},
+		{"repeated delimiter", "MSH|^^\\&|LAB\r"},
+		{"bad segment name", "MSH|^~\\&|LAB\rP!D|1\r"},
+		{"second header", "MSH|^~\\&|LAB\rMSH|^~\\&|LAB\r"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tc.data)); !errors.Is(err, ErrMalformed) {
+				t.Errorf("expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		controlID string
+	}{
+		{"malformed further on", "MSH|^~\\&|LAB|ACME LAB|||||ORU^R01|MSG1|P|2.5.1\rP!D|1\r", "MSG1"},
+		{"cut short", "MSH|^~\\&|LAB|ACME LAB|||||ORU^R01|MSG1|P|2.", "MSG1"},
+		{"cut before the control id", "MSH|^~\\&|LAB|ACME", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := ParseHeader([]byte(tc.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(m.Segments) != 1 || m.ControlID() != tc.controlID {
+				t.Errorf("parsed %+v, want the MSH segment with control id %q", m.Segments, tc.controlID)
+			}
+		})
+	}
+
+	if _, err := ParseHeader([]byte("PID|1\rMSH|^~\\&|LAB\r")); !errors.Is(err, ErrMalformed) {
+		t.Errorf("no header: expected ErrMalformed, got %v", err)
+	}
+}
+
+func TestBytes_RoundTrip(t *testing.T) {
+	m, err := Parse([]byte(oru))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := Parse(m.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nte, _ := m.Segment("NTE")
+	nte2, _ := again.Segment("NTE")
+	if nte.Field(3).String() != nte2.Field(3).String() {
+		t.Errorf("NTE-3 = %q after a round trip, want %q", nte2.Field(3).String(), nte.Field(3).String())
+	}
+	if !strings.HasPrefix(string(m.Bytes()), "MSH|^~\\&|LAB|ACME LAB|") {
+		t.Errorf("encoded %q", m.Bytes())
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	m, err := Parse([]byte(oru))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC)
+
+	ack := Ack{Code: AckAccept}.Acknowledge(m, "ACK1", now)
+	want := "MSH|^~\\&|MEDPORTAL|CLINIC|LAB|ACME LAB|20261019123000+0000||ACK^R01^ACK|ACK1|P|2.5.1\rMSA|AA|MSG00001\r"
+	if got := string(ack.Bytes()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	ack = Ack{Code: AckReject, ErrorCode: ErrUnsupportedMessageType, Text: "only ORU^R01 is accepted"}.Acknowledge(m, "ACK2", now)
+	lines := strings.Split(strings.TrimSuffix(string(ack.Bytes()), "\r"), "\r")
+	if len(lines) != 3 {
+		t.Fatalf("got %q, want MSH, MSA and ERR", lines)
+	}
+	if lines[1] != "MSA|AR|MSG00001|only ORU\\S\\R01 is accepted" {
+		t.Errorf("MSA = %q", lines[1])
+	}
+	if lines[2] != "ERR|||200^Unsupported message type^HL70357|E||||only ORU\\S\\R01 is accepted" {
+		t.Errorf("ERR = %q", lines[2])
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2026", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"20261018", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"202610180730", time.Date(2026, 10, 18, 7, 30, 0, 0, time.UTC)},
+		{"20261018073015.25", time.Date(2026, 10, 18, 7, 30, 15, 250_000_000, time.UTC)},
+		{"20261018073000-0400", time.Date(2026, 10, 18, 11, 30, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		got, err := ParseTime(tc.in, time.UTC)
+		if err != nil || !got.Equal(tc.want) {
+			t.Errorf("ParseTime(%q) = %v, %v, want %v", tc.in, got, err, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "2026101", "20261318", "yesterday", "202610.5"} {
+		if _, err := ParseTime(in, time.UTC); err == nil {
+			t.Errorf("ParseTime(%q): expected an error", in)
+		}
+	}
+}
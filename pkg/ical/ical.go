@@ -0,0 +1,254 @@
+// Package ical writes iCalendar (RFC 5545) calendars of simple events.
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of an iCalendar object.
+const ContentType = "text/calendar; charset=utf-8"
+
+// Event statuses.
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event is a VEVENT. Start and End are written in their time zone, with a
+// VTIMEZONE for it, or as UTC when their location is UTC.
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+	Sequence    int
+}
+
+// Calendar is a VCALENDAR. Method is optional, e.g. PUBLISH for a calendar
+// sent as an email attachment. Name is shown by clients that subscribe to
+// the calendar.
+type Calendar struct {
+	ProdID string
+	Method string
+	Name   string
+	Events []Event
+}
+
+// Bytes renders the calendar with CRLF line endings and long lines folded.
+func (c *Calendar) Bytes() []byte {
+	w := &writer{}
+
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + c.ProdID)
+	w.line("CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		w.line("METHOD:" + c.Method)
+	}
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+
+	for _, tz := range c.timezones() {
+		writeTimezone(w, tz.loc, tz.from, tz.to)
+	}
+
+	for _, e := range c.Events {
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + escapeText(e.UID))
+		w.line("DTSTAMP:" + e.Stamp.UTC().Format(utcLayout))
+		w.line(dateTime("DTSTART", e.Start))
+		w.line(dateTime("DTEND", e.End))
+		if e.Sequence > 0 {
+			w.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		}
+		if e.Status != "" {
+			w.line("STATUS:" + e.Status)
+		}
+		w.line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			w.line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Location != "" {
+			w.line("LOCATION:" + escapeText(e.Location))
+		}
+		w.line("END:VEVENT")
+	}
+
+	w.line("END:VCALENDAR")
+	return []byte(w.b.String())
+}
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+)
+
+// dateTime renders a DATE-TIME property in UTC or with a TZID.
+func dateTime(name string, t time.Time) string {
+	if isUTC(t.Location()) {
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+	return name + ";TZID=" + t.Location().String() + ":" + t.Format(localLayout)
+}
+
+func isUTC(loc *time.Location) bool {
+	return loc == time.UTC || loc.String() == "UTC"
+}
+
+type zoneRange struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// timezones returns each time zone used by the events, with the span of time
+// its events cover, in order of name.
+func (c *Calendar) timezones() []zoneRange {
+	byName := map[string]*zoneRange{}
+	for _, e := range c.Events {
+		for _, t := range []time.Time{e.Start, e.End} {
+			loc := t.Location()
+			if isUTC(loc) {
+				continue
+			}
+			z, ok := byName[loc.String()]
+			if !ok {
+				byName[loc.String()] = &zoneRange{loc: loc, from: t, to: t}
+				continue
+			}
+			if t.Before(z.from) {
+				z.from = t
+			}
+			if t.After(z.to) {
+				z.to = t
+			}
+		}
+	}
+
+	zones := make([]zoneRange, 0, len(byName))
+	for _, z := range byName {
+		zones = append(zones, *z)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].loc.String() < zones[j].loc.String() })
+	return zones
+}
+
+// writeTimezone writes a VTIMEZONE for loc that covers from to to. Go does
+// not expose the rules of a zone, so each offset change in the span is
+// written as its own observance, after one for the offset in effect at the
+// start.
+func writeTimezone(w *writer, loc *time.Location, from, to time.Time) {
+	start := from.AddDate(0, 0, -1)
+	end := to.AddDate(0, 0, 1)
+
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+
+	_, offset := start.In(loc).Zone()
+	writeObservance(w, start.In(loc), offset)
+
+	for _, t := range transitions(loc, start, end) {
+		_, before := t.Add(-time.Second).In(loc).Zone()
+		writeObservance(w, t.In(loc), before)
+	}
+
+	w.line("END:VTIMEZONE")
+}
+
+// writeObservance writes the STANDARD or DAYLIGHT observance that takes
+// effect at t, changing the offset from offsetFrom.
+func writeObservance(w *writer, t time.Time, offsetFrom int) {
+	name, offsetTo := t.Zone()
+	kind := "STANDARD"
+	if t.IsDST() {
+		kind = "DAYLIGHT"
+	}
+
+	w.line("BEGIN:" + kind)
+	// DTSTART is the local time of the onset in the offset being replaced.
+	w.line("DTSTART:" + t.UTC().Add(time.Duration(offsetFrom)*time.Second).Format(localLayout))
+	w.line("TZOFFSETFROM:" + formatOffset(offsetFrom))
+	w.line("TZOFFSETTO:" + formatOffset(offsetTo))
+	w.line("TZNAME:" + escapeText(name))
+	w.line("END:" + kind)
+}
+
+// transitions returns the instants in [from, to) at which the UTC offset of
+// loc changes.
+func transitions(loc *time.Location, from, to time.Time) []time.Time {
+	const step = 12 * time.Hour
+
+	var out []time.Time
+	for t := from; t.Before(to); t = t.Add(step) {
+		next := t.Add(step)
+		_, a := t.In(loc).Zone()
+		_, b := next.In(loc).Zone()
+		if a == b {
+			continue
+		}
+
+		// Narrow down to the second the offset changes.
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+			if _, m := mid.In(loc).Zone(); m == a {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		out = append(out, hi)
+	}
+	return out
+}
+
+// formatOffset renders a UTC offset in seconds as +HHMM, or +HHMMSS when it
+// is not a whole number of minutes.
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	h, m, s := seconds/3600, seconds/60%60, seconds%60
+	if s != 0 {
+		return fmt.Sprintf("%s%02d%02d%02d", sign, h, m, s)
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, h, m)
+}
+
+// escapeText escapes a TEXT value (RFC 5545, section 3.3.11).
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+type writer struct {
+	b strings.Builder
+}
+
+// line writes a content line, folding it after 75 octets without splitting
+// a UTF-8 sequence (RFC 5545, section 3.1).
+func (w *writer) line(s string) {
+	const limit = 75
+
+	n := 0
+	for i, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			w.b.WriteString("\r\n ")
+			n = 1
+		}
+		w.b.WriteString(s[i : i+size])
+		n += size
+	}
+	w.b.WriteString("\r\n")
+}
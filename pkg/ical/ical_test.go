@@ -0,0 +1,122 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Bytes(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+
+	start := time.Date(2026, time.October, 19, 9, 0, 0, 0, london)
+	cal := Calendar{
+		ProdID: "-//med-portal//EN",
+		Method: "PUBLISH",
+		Name:   "Appointments",
+		Events: []Event{{
+			UID:     "a1@med-portal",
+			Stamp:   time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC),
+			Start:   start,
+			End:     start.Add(30 * time.Minute),
+			Summary: "Check-up, follow; up",
+			Status:  StatusConfirmed,
+		}},
+	}
+
+	got := string(cal.Bytes())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//med-portal//EN\r\n",
+		"METHOD:PUBLISH\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/London\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20261018T090000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0100\r\nTZNAME:BST\r\n",
+		"DTSTAMP:20261001T120000Z\r\n",
+		"DTSTART;TZID=Europe/London:20261019T090000\r\n",
+		"DTEND;TZID=Europe/London:20261019T093000\r\n",
+		"SUMMARY:Check-up\\, follow\\; up\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "BEGIN:VTIMEZONE") != 1 {
+		t.Errorf("expected one VTIMEZONE:\n%s", got)
+	}
+}
+
+func TestCalendar_Transitions(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+
+	// Events on either side of both 2026 clock changes.
+	first := time.Date(2026, time.March, 2, 9, 0, 0, 0, london)
+	last := time.Date(2026, time.November, 2, 9, 0, 0, 0, london)
+	cal := Calendar{ProdID: "-//test//EN", Events: []Event{
+		{UID: "1", Start: first, End: first.Add(time.Hour)},
+		{UID: "2", Start: last, End: last.Add(time.Hour)},
+	}}
+
+	got := string(cal.Bytes())
+
+	for _, want := range []string{
+		"BEGIN:STANDARD\r\nDTSTART:20260301T090000\r\nTZOFFSETFROM:+0000\r\nTZOFFSETTO:+0000\r\nTZNAME:GMT\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20260329T010000\r\nTZOFFSETFROM:+0000\r\nTZOFFSETTO:+0100\r\nTZNAME:BST\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20261025T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0000\r\nTZNAME:GMT\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCalendar_UTC(t *testing.T) {
+	start := time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)
+	cal := Calendar{ProdID: "-//test//EN", Events: []Event{{UID: "1", Start: start, End: start.Add(time.Hour)}}}
+
+	got := string(cal.Bytes())
+
+	if !strings.Contains(got, "DTSTART:20261019T090000Z\r\n") {
+		t.Errorf("expected a UTC start:\n%s", got)
+	}
+	if strings.Contains(got, "VTIMEZONE") {
+		t.Errorf("unexpected VTIMEZONE for UTC:\n%s", got)
+	}
+}
+
+func TestWriter_Line(t *testing.T) {
+	w := &writer{}
+	w.line("DESCRIPTION:" + strings.Repeat("é", 40))
+
+	lines := strings.Split(strings.TrimSuffix(w.b.String(), "\r\n"), "\r\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the line to be folded once, got %q", lines)
+	}
+	for i, l := range lines {
+		if len(l) > 75 {
+			t.Errorf("line %d is %d octets", i, len(l))
+		}
+		if !strings.HasPrefix(l, " ") && i > 0 {
+			t.Errorf("continuation line %d does not start with a space", i)
+		}
+	}
+	// "DESCRIPTION:" is 12 octets, leaving room for 31 two-octet runes.
+	if !strings.HasSuffix(lines[0], "é") || strings.Count(lines[0], "é") != 31 {
+		t.Errorf("fold split a character or fell short: %q", lines[0])
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	got := escapeText("a\\b;c,d\r\ne\nf")
+	want := `a\\b\;c\,d\ne\nf`
+	if got != want {
+		t.Errorf("escapeText = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email with optional attachments.
+type Message struct {
+	To          string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the log instead of sending them. It is used
+// when no SMTP server is configured, for example in development. Bodies carry
+// sign-in links, tokens and patient data, so only the recipient, subject and
+// attachment names are logged unless bodies is set.
+type LogMailer struct {
+	bodies bool
+}
+
+func NewLogMailer(bodies bool) *LogMailer {
+	return &LogMailer{bodies: bodies}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if m.bodies {
+		log.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	} else {
+		log.Printf("mail to=%s subject=%q body=%d bytes", msg.To, msg.Subject, len(msg.Body))
+	}
+	for _, a := range msg.Attachments {
+		log.Printf("mail attachment to=%s filename=%q type=%q size=%d", msg.To, a.Filename, a.ContentType, len(a.Data))
+	}
+	return nil
+}
+
+// SMTPMailer sends messages through an SMTP server with PLAIN auth.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	if err := smtp.SendMail(m.addr, auth, m.from, []string{msg.To}, buildMessage(m.from, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// buildMessage renders msg as an RFC 5322 message, as multipart/mixed when
+// it has attachments. Header values are stripped of line breaks so they
+// cannot inject extra headers.
+func buildMessage(from string, msg Message, date time.Time) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	b.WriteString("From: " + clean.Replace(from) + "\r\n")
+	b.WriteString("To: " + clean.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", clean.Replace(msg.Subject)) + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	if len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(body)
+		return []byte(b.String())
+	}
+
+	boundary := newBoundary()
+	b.WriteString(`Content-Type: multipart/mixed; boundary="` + boundary + `"` + "\r\n")
+	b.WriteString("\r\n")
+
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body + "\r\n")
+
+	for _, a := range msg.Attachments {
+		filename := mime.QEncoding.Encode("utf-8", clean.Replace(a.Filename))
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString("Content-Type: " + clean.Replace(a.ContentType) + "\r\n")
+		b.WriteString("Content-Transfer-Encoding: base64\r\n")
+		b.WriteString(`Content-Disposition: attachment; filename="` + strings.ReplaceAll(filename, `"`, "") + `"` + "\r\n")
+		b.WriteString("\r\n")
+		writeBase64(&b, a.Data)
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return []byte(b.String())
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters
+// (RFC 2045, section 6.8).
+func writeBase64(b *strings.Builder, data []byte) {
+	const width = 76
+
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > width {
+		b.WriteString(enc[:width] + "\r\n")
+		enc = enc[width:]
+	}
+	b.WriteString(enc + "\r\n")
+}
+
+// newBoundary returns a random multipart boundary, which cannot occur in
+// base64 data or, in practice, in a body.
+func newBoundary() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("=_%x", buf)
+}
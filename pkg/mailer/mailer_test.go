@@ -0,0 +1,114 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := Message{
+		To:      "john@example.com",
+		Subject: "Confirm your email",
+		Body:    "line one\nline two",
+	}
+
+	got := string(buildMessage("noreply@example.com", msg, date))
+
+	for _, want := range []string{
+		"From: noreply@example.com\r\n",
+		"To: john@example.com\r\n",
+		"Subject: Confirm your email\r\n",
+		"Date: Fri, 02 Jan 2026 03:04:05 +0000\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestBuildMessage_HeaderInjection(t *testing.T) {
+	msg := Message{
+		To:      "john@example.com\r\nBcc: attacker@example.com",
+		Subject: "Hello\r\nBcc: attacker@example.com",
+		Body:    "body",
+	}
+
+	got := string(buildMessage("noreply@example.com", msg, time.Now()))
+
+	if strings.Contains(got, "\r\nBcc:") {
+		t.Errorf("header injection not prevented:\n%s", got)
+	}
+}
+
+func TestBuildMessage_Attachments(t *testing.T) {
+	msg := Message{
+		To:      "john@example.com",
+		Subject: "Appointment booked",
+		Body:    "See attached.",
+		Attachments: []Attachment{{
+			Filename:    "appointment.ics",
+			ContentType: "text/calendar; charset=utf-8; method=PUBLISH",
+			Data:        []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"),
+		}},
+	}
+
+	got := string(buildMessage("noreply@example.com", msg, time.Now()))
+
+	for _, want := range []string{
+		"Content-Type: multipart/mixed; boundary=\"=_",
+		"Content-Type: text/plain; charset=utf-8\r\n\r\nSee attached.\r\n",
+		"Content-Type: text/calendar; charset=utf-8; method=PUBLISH\r\n",
+		"Content-Disposition: attachment; filename=\"appointment.ics\"\r\n",
+		"QkVHSU46VkNBTEVOREFSDQpFTkQ6VkNBTEVOREFSDQo=\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message missing %q:\n%s", want, got)
+		}
+	}
+	if !strings.HasSuffix(got, "--\r\n") {
+		t.Errorf("message does not close the multipart body:\n%s", got)
+	}
+}
+
+func TestLogMailer_Send(t *testing.T) {
+	msg := Message{
+		To:      "john@example.com",
+		Subject: "Confirm your email",
+		Body:    "https://app.example.com/confirm?token=secret-token",
+	}
+
+	tests := []struct {
+		name       string
+		bodies     bool
+		expectBody bool
+	}{
+		{name: "recipient and subject only", bodies: false, expectBody: false},
+		{name: "with bodies", bodies: true, expectBody: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			log.SetOutput(&buf)
+			defer log.SetOutput(os.Stderr)
+
+			if err := NewLogMailer(tt.bodies).Send(context.Background(), msg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := buf.String()
+			if !strings.Contains(got, "to=john@example.com") || !strings.Contains(got, `subject="Confirm your email"`) {
+				t.Errorf("logged %q, want the recipient and subject", got)
+			}
+			if strings.Contains(got, "secret-token") != tt.expectBody {
+				t.Errorf("logged %q, want the body logged: %v", got, tt.expectBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// Package mllp serves the Minimal Lower Layer Protocol, which carries HL7 v2
+// messages over TCP. Each message is framed by a start block (VT, 0x0B) and
+// an end block (FS CR, 0x1C 0x0D), and is answered on the same connection
+// before the next one is read.
+package mllp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Framing bytes.
+const (
+	StartBlock     = 0x0B
+	EndBlock       = 0x1C
+	CarriageReturn = 0x0D
+)
+
+// DefaultMaxMessageBytes caps the size of a message when a server sets no
+// limit of its own.
+const DefaultMaxMessageBytes = 1 << 20
+
+// ErrServerClosed is returned by Serve after Shutdown.
+var ErrServerClosed = errors.New("mllp: server closed")
+
+// ErrMessageTooLarge is returned by ReadMessage for a message over the limit.
+var ErrMessageTooLarge = errors.New("mllp: message too large")
+
+// ReadMessage reads the next framed message from r, without its framing.
+// Bytes before the start block, such as line breaks between messages, are
+// skipped. A message longer than max bytes is ErrMessageTooLarge, and the
+// rest of it is skipped. When a message cannot be read whole, what was read
+// of it, up to max bytes, is returned with the error, so that it can still
+// be answered.
+func ReadMessage(r *bufio.Reader, max int) ([]byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == StartBlock {
+			break
+		}
+	}
+
+	var msg []byte
+	tooLarge := false
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return msg, io.ErrUnexpectedEOF
+			}
+			return msg, err
+		}
+		if b == EndBlock {
+			next, err := r.ReadByte()
+			if err != nil {
+				if err == io.EOF {
+					return msg, io.ErrUnexpectedEOF
+				}
+				return msg, err
+			}
+			if next != CarriageReturn {
+				return msg, fmt.Errorf("mllp: expected CR after the end block, got 0x%02X", next)
+			}
+			if tooLarge {
+				return msg, ErrMessageTooLarge
+			}
+			return msg, nil
+		}
+		if b == StartBlock {
+			return msg, errors.New("mllp: start block inside a message")
+		}
+		if len(msg) >= max {
+			tooLarge = true
+			continue
+		}
+		msg = append(msg, b)
+	}
+}
+
+// WriteMessage writes msg to w in its framing.
+func WriteMessage(w io.Writer, msg []byte) error {
+	frame := make([]byte, 0, len(msg)+3)
+	frame = append(frame, StartBlock)
+	frame = append(frame, msg...)
+	frame = append(frame, EndBlock, CarriageReturn)
+	_, err := w.Write(frame)
+	return err
+}
+
+// Handler answers a message, typically with its HL7 acknowledgment. An
+// error closes the connection without an answer, for data that cannot be
+// acknowledged at all. Handlers that also implement Rejecter are asked to
+// answer messages that could not be read whole.
+type Handler interface {
+	ServeMLLP(ctx context.Context, msg []byte) ([]byte, error)
+}
+
+// HandlerFunc adapts a function to a Handler.
+type HandlerFunc func(ctx context.Context, msg []byte) ([]byte, error)
+
+func (f HandlerFunc) ServeMLLP(ctx context.Context, msg []byte) ([]byte, error) {
+	return f(ctx, msg)
+}
+
+// Rejecter is implemented by handlers that can answer a message the server
+// could not read whole, such as one over the size limit or one whose
+// framing is broken. partial is what was read of it and err why it could
+// not be read. The answer, if not nil, is sent before the connection is
+// closed.
+type Rejecter interface {
+	RejectMLLP(ctx context.Context, partial []byte, err error) []byte
+}
+
+// Server accepts MLLP connections and passes each message to Handler.
+// Connections are served concurrently; messages on one connection are
+// answered in turn.
+type Server struct {
+	Addr    string
+	Handler Handler
+
+	// IdleTimeout closes a connection that sends nothing for that long.
+	// Zero means no limit.
+	IdleTimeout time.Duration
+
+	// MaxMessageBytes caps the size of a message. Zero means
+	// DefaultMaxMessageBytes.
+	MaxMessageBytes int
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[*conn]struct{}
+	closing  bool
+	wg       sync.WaitGroup
+}
+
+// conn is a served connection. busy is set while a message is being
+// handled, so Shutdown lets it finish.
+type conn struct {
+	net.Conn
+	busy bool
+}
+
+// ListenAndServe listens on Addr and serves connections until Shutdown.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Shutdown, and then returns
+// ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		c := &conn{Conn: nc}
+		if !s.track(c) {
+			nc.Close()
+			continue
+		}
+		go s.serve(c)
+	}
+}
+
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// track registers a new connection, unless the server is shutting down.
+func (s *Server) track(c *conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = map[*conn]struct{}{}
+	}
+	s.conns[c] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(c *conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+// setBusy marks a connection busy or idle. It reports false when a
+// connection that has just read a message should close instead, because
+// the server is shutting down.
+func (s *Server) setBusy(c *conn, busy bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if busy && s.closing {
+		return false
+	}
+	c.busy = busy
+	return !s.closing
+}
+
+func (s *Server) serve(c *conn) {
+	defer s.untrack(c)
+	defer c.Close()
+
+	max := s.MaxMessageBytes
+	if max <= 0 {
+		max = DefaultMaxMessageBytes
+	}
+	r := bufio.NewReader(c)
+
+	for {
+		if s.IdleTimeout > 0 {
+			c.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		}
+		msg, err := ReadMessage(r, max)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !s.shuttingDown() {
+				log.Printf("mllp %s: %v", c.RemoteAddr(), err)
+				s.reject(c, msg, err)
+			}
+			return
+		}
+
+		if !s.setBusy(c, true) {
+			return
+		}
+		reply, err := s.Handler.ServeMLLP(context.Background(), msg)
+		if err != nil {
+			log.Printf("mllp %s: %v", c.RemoteAddr(), err)
+			return
+		}
+		c.SetWriteDeadline(time.Now().Add(30 * time.Second))
+		if err := WriteMessage(c, reply); err != nil {
+			log.Printf("mllp %s: %v", c.RemoteAddr(), err)
+			return
+		}
+		if !s.setBusy(c, false) {
+			return
+		}
+	}
+}
+
+// reject answers a message that could not be read whole, when something of
+// it was read and the handler is a Rejecter. The connection is closed
+// afterwards by serve.
+func (s *Server) reject(c *conn, partial []byte, err error) {
+	rejecter, ok := s.Handler.(Rejecter)
+	if !ok || len(partial) == 0 {
+		return
+	}
+	reply := rejecter.RejectMLLP(context.Background(), partial, err)
+	if reply == nil {
+		return
+	}
+	c.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	if err := WriteMessage(c, reply); err != nil {
+		log.Printf("mllp %s: %v", c.RemoteAddr(), err)
+	}
+}
+
+// Shutdown stops accepting connections, closes idle ones and waits for the
+// messages being handled to be answered. If ctx ends first, the remaining
+// connections are closed and ctx's error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for c := range s.conns {
+		if !c.busy {
+			c.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for c := range s.conns {
+			c.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
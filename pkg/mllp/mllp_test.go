@@ -0,0 +1,338 @@
+package mllp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadMessage(t *testing.T) {
+	stream := "\r\n\x0bMSH|first\x1c\r\x0bMSH|second\x1c\r"
+	r := bufio.NewReader(strings.NewReader(stream))
+
+	for _, want := range []string{"MSH|first", "MSH|second"} {
+		msg, err := ReadMessage(r, 100)
+		if err != nil || string(msg) != want {
+			t.Fatalf("read %q, %v, want %q", msg, err, want)
+		}
+	}
+	if _, err := ReadMessage(r, 100); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReadMessage_Malformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   error
+	}{
+		{"truncated", "\x0bMSH|first", io.ErrUnexpectedEOF},
+		{"no carriage return", "\x0bMSH|first\x1cX", nil},
+		{"nested start block", "\x0bMSH|\x0bfirst\x1c\r", nil},
+		{"too large", "\x0b" + strings.Repeat("x", 11) + "\x1c\r", ErrMessageTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := ReadMessage(bufio.NewReader(strings.NewReader(tc.stream)), 10)
+			if err == nil || (tc.want != nil && !errors.Is(err, tc.want)) {
+				t.Errorf("got %v, want %v", err, tc.want)
+			}
+			// What was read is returned, so the message can be answered.
+			if len(msg) == 0 {
+				t.Error("expected what was read of the message")
+			}
+		})
+	}
+}
+
+func TestReadMessage_SkipsTooLarge(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("\x0b" + strings.Repeat("x", 20) + "\x1c\r\x0bMSH|next\x1c\r"))
+
+	msg, err := ReadMessage(r, 10)
+	if !errors.Is(err, ErrMessageTooLarge) || string(msg) != strings.Repeat("x", 10) {
+		t.Fatalf("read %q, %v, want the first 10 bytes and ErrMessageTooLarge", msg, err)
+	}
+	if msg, err := ReadMessage(r, 10); err != nil || string(msg) != "MSH|next" {
+		t.Errorf("read %q, %v, want the next message", msg, err)
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteMessage(&b, []byte("MSA|AA")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.String() != "\x0bMSA|AA\x1c\r" {
+		t.Errorf("wrote %q", b.String())
+	}
+}
+
+// start serves handler on a local port and returns the server and its
+// address.
+func start(t *testing.T, handler Handler) (*Server, string) {
+	t.Helper()
+	s := &Server{Handler: handler, IdleTimeout: 5 * time.Second}
+	return s, serveLocal(t, s)
+}
+
+// serveLocal serves s on a local port until the test ends and returns its
+// address.
+func serveLocal(t *testing.T, s *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ln) }()
+	t.Cleanup(func() {
+		s.Shutdown(context.Background())
+		if err := <-served; !errors.Is(err, ErrServerClosed) {
+			t.Errorf("Serve returned %v, want ErrServerClosed", err)
+		}
+	})
+	return ln.Addr().String()
+}
+
+// client is an in-process MLLP client.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *client) send(msg string) (string, error) {
+	if err := WriteMessage(c.conn, []byte(msg)); err != nil {
+		return "", err
+	}
+	reply, err := ReadMessage(c.r, DefaultMaxMessageBytes)
+	return string(reply), err
+}
+
+// echo acknowledges a message with its text.
+var echo = HandlerFunc(func(ctx context.Context, msg []byte) ([]byte, error) {
+	if string(msg) == "garbage" {
+		return nil, errors.New("not an HL7 message")
+	}
+	return append([]byte("ACK "), msg...), nil
+})
+
+func TestServer(t *testing.T) {
+	_, addr := start(t, echo)
+
+	c := dial(t, addr)
+	for _, msg := range []string{"MSH|1", "MSH|2"} {
+		reply, err := c.send(msg)
+		if err != nil || reply != "ACK "+msg {
+			t.Errorf("sent %q, got %q, %v", msg, reply, err)
+		}
+	}
+
+	// Messages the handler cannot answer close the connection.
+	if _, err := c.send("garbage"); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+}
+
+// rejecter echoes messages and rejects those it could not read with what
+// was read of them.
+type rejecter struct{ Handler }
+
+func (rejecter) RejectMLLP(ctx context.Context, partial []byte, err error) []byte {
+	if !bytes.HasPrefix(partial, []byte("MSH")) {
+		return nil
+	}
+	return []byte("NAK " + string(partial) + ": " + err.Error())
+}
+
+func TestServer_Reject(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler Handler
+		frame   string
+		want    string
+	}{
+		{
+			name:    "too large",
+			handler: rejecter{echo},
+			frame:   "\x0bMSH|" + strings.Repeat("x", 20) + "\x1c\r",
+			want:    "NAK MSH|xxxxxx: " + ErrMessageTooLarge.Error(),
+		},
+		{
+			name:    "nested start block",
+			handler: rejecter{echo},
+			frame:   "\x0bMSH|1\x0bMSH|2\x1c\r",
+			want:    "NAK MSH|1: mllp: start block inside a message",
+		},
+		{
+			name:    "nothing to answer",
+			handler: rejecter{echo},
+			frame:   "\x0bxx\x0b\x1c\r",
+		},
+		{
+			name:    "handler cannot reject",
+			handler: echo,
+			frame:   "\x0bMSH|" + strings.Repeat("x", 20) + "\x1c\r",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := serveLocal(t, &Server{Handler: tc.handler, IdleTimeout: 5 * time.Second, MaxMessageBytes: 10})
+			c := dial(t, addr)
+
+			if reply, err := c.send("MSH|1"); err != nil || reply != "ACK MSH|1" {
+				t.Fatalf("sent MSH|1, got %q, %v", reply, err)
+			}
+			if _, err := c.conn.Write([]byte(tc.frame)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if tc.want != "" {
+				reply, err := ReadMessage(c.r, DefaultMaxMessageBytes)
+				if err != nil || string(reply) != tc.want {
+					t.Errorf("got %q, %v, want %q", reply, err, tc.want)
+				}
+			}
+			// The connection is closed once the rejection, if any, is sent.
+			if b, err := c.r.ReadByte(); err == nil {
+				t.Errorf("read 0x%02X, expected the connection to be closed", b)
+			}
+		})
+	}
+}
+
+func TestServer_Concurrent(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	waiting := 0
+	handler := HandlerFunc(func(ctx context.Context, msg []byte) ([]byte, error) {
+		mu.Lock()
+		waiting++
+		if waiting == 3 {
+			close(release)
+		}
+		mu.Unlock()
+		// Each message waits for the others, so the test only passes if
+		// the connections are served at the same time.
+		select {
+		case <-release:
+		case <-time.After(3 * time.Second):
+			return nil, errors.New("connections were not served concurrently")
+		}
+		return append([]byte("ACK "), msg...), nil
+	})
+	_, addr := start(t, handler)
+
+	var wg sync.WaitGroup
+	replies := make([]string, 3)
+	errs := make([]error, 3)
+	for i := range replies {
+		c := dial(t, addr)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			replies[i], errs[i] = c.send("MSH|" + string(rune('a'+i)))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range replies {
+		if want := "ACK MSH|" + string(rune('a'+i)); errs[i] != nil || replies[i] != want {
+			t.Errorf("connection %d got %q, %v, want %q", i, replies[i], errs[i], want)
+		}
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := HandlerFunc(func(ctx context.Context, msg []byte) ([]byte, error) {
+		if string(msg) == "slow" {
+			close(started)
+			<-release
+		}
+		return append([]byte("ACK "), msg...), nil
+	})
+	s, addr := start(t, handler)
+
+	idle := dial(t, addr)
+	if _, err := idle.send("fast"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	busy := dial(t, addr)
+	reply := make(chan string, 1)
+	go func() {
+		r, _ := busy.send("slow")
+		reply <- r
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+
+	// The idle connection is closed straight away, and new connections are
+	// refused.
+	if _, err := idle.r.ReadByte(); err == nil {
+		t.Error("expected the idle connection to be closed")
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v before the busy connection was answered", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Error("expected new connections to be refused")
+	}
+
+	// The message being handled is still answered.
+	close(release)
+	if r := <-reply; r != "ACK slow" {
+		t.Errorf("busy connection got %q, want its acknowledgment", r)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown returned %v", err)
+	}
+}
+
+func TestServer_Shutdown_Timeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := HandlerFunc(func(ctx context.Context, msg []byte) ([]byte, error) {
+		close(started)
+		<-release
+		return msg, nil
+	})
+	s, addr := start(t, handler)
+
+	c := dial(t, addr)
+	go c.send("stuck")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the drain to time out, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package responses
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+// ETag returns the strong entity tag for a resource version.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag writes the ETag header for version.
+func SetETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", ETag(version))
+}
+
+// ParseIfMatch parses the If-Match header of r. A missing header yields
+// ErrPreconditionRequired. Weak tags and tags that are not ours never match,
+// since If-Match uses strong comparison (RFC 9110, section 13.1.1).
+func ParseIfMatch(r *http.Request) (model.Precondition, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return model.Precondition{}, model.ErrPreconditionRequired
+	}
+	if header == "*" {
+		return model.Precondition{Any: true}, nil
+	}
+
+	var p model.Precondition
+	for _, tag := range strings.Split(header, ",") {
+		if v, ok := parseStrongETag(strings.TrimSpace(tag)); ok {
+			p.Versions = append(p.Versions, v)
+		}
+	}
+	return p, nil
+}
+
+// NoneMatch reports whether the If-None-Match header of r rules out a
+// resource at version, in which case a GET should answer 304 Not Modified.
+// Comparison is weak, so W/ tags match too.
+func NoneMatch(r *http.Request, version int64) bool {
+	header := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if v, ok := parseStrongETag(tag); ok && v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func parseStrongETag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
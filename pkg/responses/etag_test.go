@@ -0,0 +1,77 @@
+package responses
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PranavJoshi2893/med-portal/internal/model"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version int64
+		want    bool
+		wantErr error
+	}{
+		{name: "missing", header: "", wantErr: model.ErrPreconditionRequired},
+		{name: "match", header: `"3"`, version: 3, want: true},
+		{name: "mismatch", header: `"2"`, version: 3, want: false},
+		{name: "list", header: `"1", "3"`, version: 3, want: true},
+		{name: "any", header: "*", version: 7, want: true},
+		{name: "weak never matches", header: `W/"3"`, version: 3, want: false},
+		{name: "garbage", header: `abc`, version: 3, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			p, err := ParseIfMatch(r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := p.Matches(tt.version); got != tt.want {
+				t.Errorf("Matches(%d) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoneMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "missing", header: "", want: false},
+		{name: "current", header: ETag(5), want: true},
+		{name: "weak current", header: `W/"5"`, want: true},
+		{name: "stale", header: `"4"`, want: false},
+		{name: "list", header: `"4", "5"`, want: true},
+		{name: "any", header: "*", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-None-Match", tt.header)
+			}
+			if got := NoneMatch(r, 5); got != tt.want {
+				t.Errorf("NoneMatch = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package responses
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Link is a single RFC 8288 web link.
+type Link struct {
+	URL string
+	Rel string
+}
+
+// SetLinkHeader writes links as one RFC 8288 Link header. Nothing is written
+// when links is empty.
+func SetLinkHeader(w http.ResponseWriter, links ...Link) {
+	if len(links) == 0 {
+		return
+	}
+
+	parts := make([]string, 0, len(links))
+	for _, l := range links {
+		parts = append(parts, `<`+l.URL+`>; rel="`+l.Rel+`"`)
+	}
+
+	w.Header().Set("Link", strings.Join(parts, ", "))
+}
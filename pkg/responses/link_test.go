@@ -0,0 +1,42 @@
+package responses
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetLinkHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []Link
+		want  string
+	}{
+		{
+			name:  "no links",
+			links: nil,
+			want:  "",
+		},
+		{
+			name:  "single link",
+			links: []Link{{URL: "/api/v1/users/?cursor=abc", Rel: "next"}},
+			want:  `</api/v1/users/?cursor=abc>; rel="next"`,
+		},
+		{
+			name: "multiple links",
+			links: []Link{
+				{URL: "/users?cursor=a", Rel: "prev"},
+				{URL: "/users?cursor=b", Rel: "next"},
+			},
+			want: `</users?cursor=a>; rel="prev", </users?cursor=b>; rel="next"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			SetLinkHeader(rec, tt.links...)
+			if got := rec.Header().Get("Link"); got != tt.want {
+				t.Errorf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -26,6 +26,13 @@ func FromModelError(err error, message string) ErrorResponse {
 			Message: message,
 		}
 
+	case errors.Is(err, model.ErrConflict):
+		return ErrorResponse{
+			Code:    http.StatusConflict,
+			Status:  "CONFLICT",
+			Message: message,
+		}
+
 	case errors.Is(err, model.ErrNotFound):
 		return ErrorResponse{
 			Code:    http.StatusNotFound,
@@ -61,6 +68,41 @@ func FromModelError(err error, message string) ErrorResponse {
 			Message: message,
 		}
 
+	case errors.Is(err, model.ErrTooManyRequests):
+		return ErrorResponse{
+			Code:    http.StatusTooManyRequests,
+			Status:  "TOO_MANY_REQUESTS",
+			Message: message,
+		}
+
+	case errors.Is(err, model.ErrPayloadTooLarge):
+		return ErrorResponse{
+			Code:    http.StatusRequestEntityTooLarge,
+			Status:  "PAYLOAD_TOO_LARGE",
+			Message: message,
+		}
+
+	case errors.Is(err, model.ErrUnsupportedMedia):
+		return ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Status:  "UNSUPPORTED_MEDIA_TYPE",
+			Message: message,
+		}
+
+	case errors.Is(err, model.ErrPreconditionFailed):
+		return ErrorResponse{
+			Code:    http.StatusPreconditionFailed,
+			Status:  "PRECONDITION_FAILED",
+			Message: message,
+		}
+
+	case errors.Is(err, model.ErrPreconditionRequired):
+		return ErrorResponse{
+			Code:    http.StatusPreconditionRequired,
+			Status:  "PRECONDITION_REQUIRED",
+			Message: message,
+		}
+
 	default:
 		return ErrorResponse{
 			Code:    http.StatusInternalServerError,
@@ -30,6 +30,13 @@ func TestFromModelError(t *testing.T) {
 			wantCode: http.StatusConflict,
 			wantStat: "ALREADY_EXISTS",
 		},
+		{
+			name:     "conflict",
+			err:      model.ErrConflict,
+			message:  "user is not deleted",
+			wantCode: http.StatusConflict,
+			wantStat: "CONFLICT",
+		},
 		{
 			name:     "not found",
 			err:      model.ErrNotFound,
@@ -65,6 +72,41 @@ func TestFromModelError(t *testing.T) {
 			wantCode: http.StatusForbidden,
 			wantStat: "FORBIDDEN",
 		},
+		{
+			name:     "too many requests",
+			err:      model.ErrTooManyRequests,
+			message:  "export limit reached",
+			wantCode: http.StatusTooManyRequests,
+			wantStat: "TOO_MANY_REQUESTS",
+		},
+		{
+			name:     "payload too large",
+			err:      model.ErrPayloadTooLarge,
+			message:  "avatar too large",
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantStat: "PAYLOAD_TOO_LARGE",
+		},
+		{
+			name:     "unsupported media type",
+			err:      model.ErrUnsupportedMedia,
+			message:  "unsupported avatar type",
+			wantCode: http.StatusUnsupportedMediaType,
+			wantStat: "UNSUPPORTED_MEDIA_TYPE",
+		},
+		{
+			name:     "precondition failed",
+			err:      model.ErrPreconditionFailed,
+			message:  "user has been modified",
+			wantCode: http.StatusPreconditionFailed,
+			wantStat: "PRECONDITION_FAILED",
+		},
+		{
+			name:     "precondition required",
+			err:      model.ErrPreconditionRequired,
+			message:  "If-Match header is required",
+			wantCode: http.StatusPreconditionRequired,
+			wantStat: "PRECONDITION_REQUIRED",
+		},
 		{
 			name:     "unknown error",
 			err:      errors.New("unknown"),
@@ -0,0 +1,96 @@
+// Package rxnorm reads drug names from RxNorm's RXNCONSO.RRF, or any file
+// laid out like it: one name of a concept per line, in pipe-delimited
+// columns ending with a pipe.
+package rxnorm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Columns of RXNCONSO.RRF that are read.
+const (
+	colRXCUI    = 0
+	colLAT      = 1
+	colSAB      = 11
+	colTTY      = 12
+	colSTR      = 14
+	colSUPPRESS = 16
+
+	numColumns = 18
+)
+
+// maxLine caps the length of a line. RxNorm names are far shorter.
+const maxLine = 1 << 20
+
+// Concept is one name of an RxNorm concept. A concept has a name per source
+// and term type, such as the clinical drug (SCD) "amoxicillin 500 MG Oral
+// Capsule".
+type Concept struct {
+	RXCUI    string
+	Language string
+	Source   string
+	TermType string
+	Name     string
+	Suppress string
+}
+
+// Current reports whether the name is in use. Suppressed names are kept in
+// the file for history.
+func (c *Concept) Current() bool {
+	return c.Suppress == "" || c.Suppress == "N"
+}
+
+// Reader reads concepts from a file.
+type Reader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func NewReader(r io.Reader) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxLine)
+	return &Reader{s: s}
+}
+
+// Read returns the next concept, or io.EOF at the end of the file. Blank
+// lines are skipped.
+func (r *Reader) Read() (*Concept, error) {
+	for r.s.Scan() {
+		r.line++
+		text := strings.TrimSuffix(r.s.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		fields := strings.Split(text, "|")
+		if len(fields) < numColumns {
+			return nil, fmt.Errorf("line %d: expected %d columns, got %d", r.line, numColumns, len(fields))
+		}
+
+		c := &Concept{
+			RXCUI:    strings.TrimSpace(fields[colRXCUI]),
+			Language: fields[colLAT],
+			Source:   fields[colSAB],
+			TermType: fields[colTTY],
+			Name:     strings.TrimSpace(fields[colSTR]),
+			Suppress: fields[colSUPPRESS],
+		}
+		if c.RXCUI == "" {
+			return nil, fmt.Errorf("line %d: missing RXCUI", r.line)
+		}
+		return c, nil
+	}
+
+	if err := r.s.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", r.line+1, err)
+	}
+	return nil, io.EOF
+}
+
+// Line returns the line number of the concept last read.
+func (r *Reader) Line() int {
+	return r.line
+}
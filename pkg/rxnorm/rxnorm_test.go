@@ -0,0 +1,66 @@
+package rxnorm
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader_Read(t *testing.T) {
+	file := "723|ENG|S|L0000723|PF|S0000723|Y|A10334550||||RXNORM|IN|723|amoxicillin||N|4096|\r\n" +
+		"\r\n" +
+		"308191|ENG|S|L1|PF|S1|N|A1||||RXNORM|SCD|308191|amoxicillin 500 MG Oral Capsule||O||\n" +
+		"308191|ENG|S|L2|PF|S2|N|A2||||MTHSPL|SU|308191|Amoxicillin 500mg||N||\n"
+
+	r := NewReader(strings.NewReader(file))
+
+	c, err := r.Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Concept{RXCUI: "723", Language: "ENG", Source: "RXNORM", TermType: "IN", Name: "amoxicillin", Suppress: "N"}
+	if *c != want {
+		t.Errorf("got %+v, want %+v", *c, want)
+	}
+	if !c.Current() {
+		t.Error("expected an unsuppressed name to be current")
+	}
+
+	c, err = r.Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.TermType != "SCD" || c.Name != "amoxicillin 500 MG Oral Capsule" || r.Line() != 3 {
+		t.Errorf("got %+v on line %d, want the clinical drug on line 3", *c, r.Line())
+	}
+	if c.Current() {
+		t.Error("expected an obsolete name not to be current")
+	}
+
+	if c, err = r.Read(); err != nil || c.Source != "MTHSPL" {
+		t.Errorf("got %+v, %v, want the MTHSPL name", c, err)
+	}
+	if _, err := r.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReader_Read_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"too few columns", "723|ENG|S|amoxicillin|\n"},
+		{"missing rxcui", "|ENG|S|L1|PF|S1|Y|A1||||RXNORM|IN|723|amoxicillin||N||\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(tc.file)).Read()
+			if err == nil || !strings.HasPrefix(err.Error(), "line 1:") {
+				t.Errorf("expected an error on line 1, got %v", err)
+			}
+		})
+	}
+}
@@ -36,11 +36,18 @@ curl -s -X GET "$BASE_URL/users/" -H "Authorization: Bearer $TOKEN" | jq
 echo "=== Get user by id ==="
 curl -s -X GET "$BASE_URL/users/$USER_ID" -H "Authorization: Bearer $TOKEN" | jq
 
+# PATCH and DELETE need the current ETag in If-Match
+etag() {
+  curl -s -o /dev/null -D - "$BASE_URL/users/$USER_ID" -H "Authorization: Bearer $TOKEN" \
+    | grep -i '^etag:' | cut -d' ' -f2 | tr -d '\r'
+}
+
 echo "=== Update user ==="
 curl -s -X PATCH "$BASE_URL/users/$USER_ID" \
   -H "Authorization: Bearer $TOKEN" \
-  -H "Content-Type: application/json" \
-  -d '{"first_name":"Pranav","last_name":"Joshi-Updated"}' | jq
+  -H "Content-Type: application/merge-patch+json" \
+  -H "If-Match: $(etag)" \
+  -d '{"last_name":"Joshi-Updated"}' | jq
 
 # --- Refresh (uses cookie from login) ---
 echo "=== Refresh token ==="
@@ -52,4 +59,4 @@ curl -s -X POST "$BASE_URL/auth/logout" -b cookies.txt | jq
 
 # --- Delete (comment out to keep user for next run) ---
 echo "=== Delete user ==="
-curl -s -X DELETE "$BASE_URL/users/$USER_ID" -H "Authorization: Bearer $TOKEN" | jq
+curl -s -X DELETE "$BASE_URL/users/$USER_ID" -H "Authorization: Bearer $TOKEN" -H "If-Match: $(etag)" | jq